@@ -73,6 +73,26 @@ func init() {
 		return nil
 	})
 
+	R(&options.ElasticCacheIdOptions{}, "elastic-cache-start", "Start elastisc cache instance", func(s *mcclient.ClientSession, opts *options.ElasticCacheIdOptions) error {
+		result, err := modules.ElasticCache.PerformAction(s, opts.ID, "start", nil)
+		if err != nil {
+			return err
+		}
+
+		printObject(result)
+		return nil
+	})
+
+	R(&options.ElasticCacheIdOptions{}, "elastic-cache-stop", "Stop elastisc cache instance", func(s *mcclient.ClientSession, opts *options.ElasticCacheIdOptions) error {
+		result, err := modules.ElasticCache.PerformAction(s, opts.ID, "stop", nil)
+		if err != nil {
+			return err
+		}
+
+		printObject(result)
+		return nil
+	})
+
 	R(&options.ElasticCacheIdOptions{}, "elastic-cache-flush-instance", "Flush elastisc cache instance", func(s *mcclient.ClientSession, opts *options.ElasticCacheIdOptions) error {
 		result, err := modules.ElasticCache.PerformAction(s, opts.ID, "flush-instance", nil)
 		if err != nil {
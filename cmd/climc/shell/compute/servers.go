@@ -67,6 +67,7 @@ func init() {
 	cmd.PrintObjectYAML().Perform("migrate-forecast", new(options.ServerMigrateForecastOptions))
 	cmd.Perform("migrate", new(options.ServerMigrateOptions))
 	cmd.Perform("live-migrate", new(options.ServerLiveMigrateOptions))
+	cmd.Perform("cancel-live-migrate", new(options.ServerIdOptions))
 	cmd.Perform("modify-src-check", new(options.ServerModifySrcCheckOptions))
 	cmd.Perform("set-secgroup", new(options.ServerSecGroupsOptions))
 	cmd.Perform("add-secgroup", new(options.ServerSecGroupsOptions))
@@ -106,6 +107,8 @@ func init() {
 	cmd.Perform("calculate-record-checksum", &options.ServerIdOptions{})
 	cmd.Perform("set-class-metadata", &baseoptions.ResourceMetadataOptions{})
 	cmd.Perform("monitor", &options.ServerMonitorOptions{})
+	cmd.Perform("qga-ping", &options.ServerIdOptions{})
+	cmd.Perform("qga-set-password", &options.ServerQgaSetPasswordOptions{})
 	cmd.BatchPerform("enable-memclean", new(options.ServerIdsOptions))
 
 	cmd.Get("vnc", new(options.ServerIdOptions))
@@ -120,6 +123,7 @@ func init() {
 	cmd.Get("cpuset-cores", new(options.ServerIdOptions))
 	cmd.Get("sshport", new(options.ServerIdOptions))
 	cmd.Get("qemu-info", new(options.ServerIdOptions))
+	cmd.Get("qga-guest-info", new(options.ServerIdOptions))
 
 	cmd.GetProperty(&options.ServerStatusStatisticsOptions{})
 	cmd.GetProperty(&options.ServerProjectStatisticsOptions{})
@@ -12,29 +12,18 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package conditions
+package monitor
 
 import (
-	"time"
-
-	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/monitor"
+	options "yunion.io/x/onecloud/pkg/mcclient/options/monitor"
 )
 
-type suggestRuleReducer struct {
-	*queryReducer
-	duration time.Duration
-}
-
-func NewSuggestRuleReducer(t string, duration time.Duration) Reducer {
-	return &suggestRuleReducer{
-		queryReducer: &queryReducer{Type: t},
-		duration:     duration,
-	}
-}
-
-func (s *suggestRuleReducer) Reduce(series *tsdb.TimeSeries) (*float64, []string) {
-	/*if int(s.duration.Seconds()) > len(series.Points) {
-		return nil, nil
-	}*/
-	return s.queryReducer.Reduce(series)
+func init() {
+	cmd := NewResourceCmd(modules.RebalancePlanManager)
+	cmd.List(new(options.RebalancePlanListOptions))
+	cmd.Show(new(options.RebalancePlanShowOptions))
+	cmd.Delete(new(options.RebalancePlanShowOptions))
+	cmd.Perform("approve", new(options.RebalancePlanApproveOptions))
+	cmd.Perform("reject", new(options.RebalancePlanRejectOptions))
 }
@@ -118,6 +118,8 @@ type QuotaSetBaseOptions struct {
 	Project string `help:"Tenant name or ID to set quota" json:"tenant,omitempty"`
 	Domain  string `help:"Domain name or ID to set quota" json:"domain,omitempty"`
 	Action  string `help:"quota set action" choices:"add|sub|reset|replace|delete|update"`
+
+	WarningPercent *int `help:"notify when usage reaches the percent of quota, 0 to disable" json:"warning_percent,omitempty"`
 }
 
 func printQuotaList(result jsonutils.JSONObject) {
@@ -488,4 +490,87 @@ func init() {
 		}
 		return nil
 	})
+
+	type QuotaTrendOptions struct {
+		Scope   string `help:"scope" choices:"domain|project"`
+		Project string `help:"Tenant name or ID" json:"tenant"`
+		Domain  string `help:"Domain name or ID" json:"domain"`
+		Days    int    `help:"days of usage history to forecast, default 30" json:"days,omitzero"`
+	}
+	R(&QuotaTrendOptions{}, "quota-trend", "Show usage trend and forecast exhaustion of quota", func(s *mcclient.ClientSession, args *QuotaTrendOptions) error {
+		params := jsonutils.Marshal(args)
+		result, err := modules.Quotas.GetQuotaTrend(s, params)
+		if err != nil {
+			return err
+		}
+		printObjectRecursive(result)
+		return nil
+	})
+
+	type QuotaRequestCreateOptions struct {
+		Project string `help:"Tenant name or ID to request quota" json:"tenant,omitempty"`
+		Domain  string `help:"Domain name or ID to request quota" json:"domain,omitempty"`
+		Reason  string `help:"reason of the request" json:"reason,omitempty"`
+		ComputeQuotaOptions
+	}
+	R(&QuotaRequestCreateOptions{}, "quota-request-create", "Request to raise quota", func(s *mcclient.ClientSession, args *QuotaRequestCreateOptions) error {
+		params := jsonutils.Marshal(args)
+		result, err := modules.Quotas.CreateChangeRequest(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type QuotaRequestListOptions struct {
+		Status    string `help:"status of requests" choices:"pending|approved|rejected|apply_failed" json:"status,omitempty"`
+		DomainId  string `help:"domain id of requests" json:"domain_id,omitempty"`
+		ProjectId string `help:"project id of requests" json:"project_id,omitempty"`
+		Limit     int    `help:"page size" json:"limit,omitzero"`
+		Offset    int    `help:"page offset" json:"offset,omitzero"`
+	}
+	R(&QuotaRequestListOptions{}, "quota-request-list", "List quota change requests", func(s *mcclient.ClientSession, args *QuotaRequestListOptions) error {
+		params := jsonutils.Marshal(args)
+		result, err := modules.Quotas.ListChangeRequests(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, nil)
+		return nil
+	})
+
+	type QuotaRequestIdOptions struct {
+		ID string `help:"ID of quota change request"`
+	}
+	R(&QuotaRequestIdOptions{}, "quota-request-show", "Show quota change request", func(s *mcclient.ClientSession, args *QuotaRequestIdOptions) error {
+		result, err := modules.Quotas.GetChangeRequest(s, args.ID)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type QuotaRequestReviewOptions struct {
+		QuotaRequestIdOptions
+		Comment string `help:"review comment"`
+	}
+	R(&QuotaRequestReviewOptions{}, "quota-request-approve", "Approve quota change request and apply the quota", func(s *mcclient.ClientSession, args *QuotaRequestReviewOptions) error {
+		result, err := modules.Quotas.ApproveChangeRequest(s, args.ID, args.Comment)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	R(&QuotaRequestReviewOptions{}, "quota-request-reject", "Reject quota change request", func(s *mcclient.ClientSession, args *QuotaRequestReviewOptions) error {
+		result, err := modules.Quotas.RejectChangeRequest(s, args.ID, args.Comment)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
 }
@@ -73,13 +73,18 @@ func init() {
 	}
 
 	type CycleTimer struct {
-		CycleCycleType string `help:"Cycle type for cycle timer" json:"cycle_type" choices:"day|week|month"`
+		CycleCycleType string `help:"Cycle type for cycle timer" json:"cycle_type" choices:"day|week|month|cron"`
 		CycleMinute    int    `help:"Minute of cycle timer" json:"minute"`
 		CycleHour      int    `help:"Hour of cycle timer" json:"hour"`
 		CycleWeekdays  []int  `help:"Weekdays for cycle timer" json:"weekdays"`
 		CycleMonthDays []int  `help:"Month days for cycle timer" json:"month_days"`
 		CycleStartTime string `help:"Start time for cycle timer, format:'2006-01-02 15:04:05'" json:"start_time"`
 		CycleEndTime   string `help:"End time for cycle timer, format:'2006-01-02 15:04:05'" json:"end_time"`
+
+		CycleCronExpr        string `help:"Cron expression for 'cron' type cycle timer, e.g. '0 2 * * mon-fri'" json:"cron_expr"`
+		CycleTimeZone        string `help:"Time zone of the cron expression, e.g. Asia/Shanghai" json:"time_zone"`
+		CycleJitterSeconds   int    `help:"Delay every run by a random number of seconds less than it" json:"jitter_seconds"`
+		CycleMissedRunPolicy string `help:"Policy for missed runs" json:"missed_run_policy" choices:"skip|catch_up_once"`
 	}
 
 	type ScheduledTaskCreateOptions struct {
@@ -89,10 +94,20 @@ func init() {
 		Timer
 		CycleTimer
 
-		ResourceType string   `help:"resource type"`
-		Operation    string   `help:"operation"`
+		ResourceType string   `help:"resource type" choices:"server|cloudaccount|disk|scalinggroup|dbinstance|elasticcache"`
+		Operation    string   `help:"operation" choices:"start|stop|restart|sync|instance-snapshot|change-config|snapshot|backup|set-desired-capacity"`
 		LabelType    string   `help:"label type"`
 		Labels       []string `help:"labels"`
+
+		NamePrefix           string `help:"name prefix of snapshot or backup"`
+		WithMemory           bool   `help:"save memory for instance-snapshot"`
+		InstanceType         string `help:"instance type for change-config"`
+		VcpuCount            int    `help:"vcpu count for change-config"`
+		VmemSize             string `help:"memory size for change-config, e.g. 4G"`
+		AutoStart            bool   `help:"auto start server after change-config"`
+		DesireInstanceNumber int    `help:"desire instance number for set-desired-capacity"`
+		BackupStorageId      string `help:"backup storage id for backup"`
+		Incremental          bool   `help:"incremental backup"`
 	}
 	R(&ScheduledTaskCreateOptions{}, "scheduledtask-create", "Create Scheduled Task", func(s *mcclient.ClientSession, args *ScheduledTaskCreateOptions) error {
 		formatStr := "2006-01-02 15:04:05"
@@ -129,11 +144,27 @@ func init() {
 				MonthDays: args.CycleMonthDays,
 				StartTime: starttime,
 				EndTime:   endtime,
+
+				CronExpr:        args.CycleCronExpr,
+				TimeZone:        args.CycleTimeZone,
+				JitterSeconds:   args.CycleJitterSeconds,
+				MissedRunPolicy: args.CycleMissedRunPolicy,
 			},
 			ResourceType: args.ResourceType,
 			Operation:    args.Operation,
-			LabelType:    args.LabelType,
-			Labels:       args.Labels,
+			OperationParams: apis.ScheduledTaskOperationParams{
+				NamePrefix:           args.NamePrefix,
+				WithMemory:           args.WithMemory,
+				InstanceType:         args.InstanceType,
+				VcpuCount:            args.VcpuCount,
+				VmemSize:             args.VmemSize,
+				AutoStart:            args.AutoStart,
+				DesireInstanceNumber: args.DesireInstanceNumber,
+				BackupStorageId:      args.BackupStorageId,
+				Incremental:          args.Incremental,
+			},
+			LabelType: args.LabelType,
+			Labels:    args.Labels,
 		}
 		stCreateInput.Name = args.NAME
 		ret, err := modules.ScheduledTask.Create(s, jsonutils.Marshal(stCreateInput))
@@ -58,6 +58,20 @@ func init() {
 			return nil
 		})
 
+	R(&options.SchedulerSimulateOptions{}, "scheduler-simulate", "Simulate scheduling a batch of servers without reserving resources",
+		func(s *mcclient.ClientSession, args *options.SchedulerSimulateOptions) error {
+			specs, err := args.Params(s)
+			if err != nil {
+				return err
+			}
+			result, err := modules.SchedManager.DoSimulate(s, specs)
+			if err != nil {
+				return err
+			}
+			fmt.Println(result.YAMLString())
+			return nil
+		})
+
 	type SchedulerCandidateListOptions struct {
 		Type   string `help:"Sched type filter" choices:"baremetal|host"`
 		Region string `help:"Cloud region ID"`
@@ -0,0 +1,107 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webconsole
+
+import (
+	"fmt"
+	"os"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/cmd/climc/shell"
+	webconsole_api "yunion.io/x/onecloud/pkg/apis/webconsole"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/webconsole"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	type SessionRecordingListOptions struct {
+		options.BaseListOptions
+		SessionId   []string `help:"filter by webconsole session id"`
+		ObjId       []string `help:"filter by id of the accessed resource"`
+		ObjType     []string `help:"filter by type of the accessed resource, e.g. server, host, pod"`
+		SessionType []string `help:"filter by session type" choices:"ssh|kube|ipmi"`
+	}
+	R(&SessionRecordingListOptions{}, "webconsole-session-recording-list", "List webconsole session recordings", func(s *mcclient.ClientSession, args *SessionRecordingListOptions) error {
+		params, err := options.ListStructToParams(args)
+		if err != nil {
+			return err
+		}
+		ret, err := webconsole.SessionRecording.List(s, params)
+		if err != nil {
+			return err
+		}
+		shell.PrintList(ret, webconsole.SessionRecording.GetColumns(s))
+		return nil
+	})
+
+	type SessionRecordingIdOptions struct {
+		ID string `help:"ID of session recording"`
+	}
+	R(&SessionRecordingIdOptions{}, "webconsole-session-recording-show", "Show webconsole session recording", func(s *mcclient.ClientSession, args *SessionRecordingIdOptions) error {
+		ret, err := webconsole.SessionRecording.Get(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		shell.PrintObject(ret)
+		return nil
+	})
+
+	R(&SessionRecordingIdOptions{}, "webconsole-session-recording-delete", "Delete webconsole session recording", func(s *mcclient.ClientSession, args *SessionRecordingIdOptions) error {
+		ret, err := webconsole.SessionRecording.Delete(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		shell.PrintObject(ret)
+		return nil
+	})
+
+	type SessionRecordingDownloadOptions struct {
+		ID     string `help:"ID of session recording"`
+		OUTPUT string `help:"asciicast file to save the recording to, replay it with 'asciinema play'"`
+	}
+	R(&SessionRecordingDownloadOptions{}, "webconsole-session-recording-download", "Download webconsole session recording as asciicast v2 file", func(s *mcclient.ClientSession, args *SessionRecordingDownloadOptions) error {
+		f, err := os.Create(args.OUTPUT)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		offset := 0
+		for offset >= 0 {
+			query := jsonutils.NewDict()
+			query.Set("offset", jsonutils.NewInt(int64(offset)))
+			ret, err := webconsole.SessionRecording.GetSpecific(s, args.ID, "cast", query)
+			if err != nil {
+				return err
+			}
+			page := webconsole_api.SessionRecordingCastOutput{}
+			if err := ret.Unmarshal(&page); err != nil {
+				return fmt.Errorf("invalid response %s", ret)
+			}
+			lines := page.Events
+			if offset == 0 {
+				lines = append([]string{page.Header}, lines...)
+			}
+			for _, line := range lines {
+				if _, err := fmt.Fprintln(f, line); err != nil {
+					return err
+				}
+			}
+			offset = page.NextOffset
+		}
+		return nil
+	})
+}
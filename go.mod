@@ -12,6 +12,7 @@ require (
 	github.com/LeeEirc/terminalparser v0.0.0-20220328021224-de16b7643ea4
 	github.com/Masterminds/sprig v2.22.0+incompatible
 	github.com/Microsoft/azure-vhd-utils v0.0.0-20181115010904-44cbada2ece3
+	github.com/Shopify/sarama v1.20.0
 	github.com/aliyun/alibaba-cloud-sdk-go v1.61.684
 	github.com/aliyun/aliyun-oss-go-sdk v2.0.4+incompatible
 	github.com/anacrolix/torrent v0.0.0-20181129073333-cc531b8c4a80
@@ -73,6 +74,7 @@ require (
 	github.com/tencentyun/cos-go-sdk-v5 v0.7.24
 	github.com/tjfoc/gmsm v1.4.1
 	github.com/tredoe/osutil v0.0.0-20161130133508-7d3ee1afa71c
+	github.com/ugorji/go/codec v1.1.7
 	github.com/vishvananda/netlink v1.0.0
 	github.com/vishvananda/netns v0.0.0-20211101163701-50045581ed74
 	github.com/vmihailenco/msgpack v4.0.4+incompatible
@@ -123,7 +125,6 @@ require (
 	github.com/Masterminds/goutils v1.1.0 // indirect
 	github.com/Masterminds/semver v1.5.0 // indirect
 	github.com/RoaringBitmap/roaring v0.4.16 // indirect
-	github.com/Shopify/sarama v1.20.0 // indirect
 	github.com/Shopify/toxiproxy v2.1.4+incompatible // indirect
 	github.com/StackExchange/wmi v1.2.1 // indirect
 	github.com/VividCortex/ewma v1.1.1 // indirect
@@ -234,7 +235,6 @@ require (
 	github.com/tinylib/msgp v1.1.0 // indirect
 	github.com/tklauser/go-sysconf v0.3.10 // indirect
 	github.com/tklauser/numcpus v0.4.0 // indirect
-	github.com/ugorji/go/codec v1.1.7 // indirect
 	github.com/willf/bitset v1.1.9 // indirect
 	github.com/willf/bloom v2.0.3+incompatible // indirect
 	github.com/yusufpapurcu/wmi v1.2.2 // indirect
@@ -42,6 +42,11 @@ import (
 const (
 	TotpEnable  = '1'
 	TotpDisable = '0'
+
+	// bits of the MFA initialized flag, '1' is compatible with the cookie
+	// issued before webauthn is supported
+	mfaInitTotp     = 0x1
+	mfaInitWebAuthn = 0x2
 )
 
 var (
@@ -59,6 +64,8 @@ type SAuthToken struct {
 	initTotp   bool
 	isSsoLogin bool
 
+	initWebAuthn bool
+
 	retryCount     int    // 重试计数器
 	lockExpireTime uint32 // 锁定时间
 }
@@ -75,11 +82,14 @@ func (t SAuthToken) encodeBytes() []byte {
 	} else {
 		msg.WriteByte(TotpDisable)
 	}
+	initFlag := byte(0)
 	if t.initTotp {
-		msg.WriteByte(TotpEnable)
-	} else {
-		msg.WriteByte(TotpDisable)
+		initFlag |= mfaInitTotp
+	}
+	if t.initWebAuthn {
+		initFlag |= mfaInitWebAuthn
 	}
+	msg.WriteByte(TotpDisable + initFlag)
 	if t.isSsoLogin {
 		msg.WriteByte(TotpEnable)
 	} else {
@@ -135,11 +145,9 @@ func decodeBytes(tt []byte) (*SAuthToken, error) {
 	} else {
 		ret.enableTotp = false
 	}
-	if tt[2] == TotpEnable {
-		ret.initTotp = true
-	} else {
-		ret.initTotp = false
-	}
+	initFlag := tt[2] - TotpDisable
+	ret.initTotp = initFlag&mfaInitTotp != 0
+	ret.initWebAuthn = initFlag&mfaInitWebAuthn != 0
 	if tt[3] == TotpEnable {
 		ret.isSsoLogin = true
 	} else {
@@ -198,13 +206,22 @@ func (t SAuthToken) GetAuthCookie(token mcclient.TokenCredential) string {
 	info.Add(jsonutils.NewBool(t.enableTotp), "totp_on")                      // 用户totp 开启状态。 True（已开启）|False(未开启)
 	info.Add(jsonutils.NewBool(t.isSsoLogin), "is_sso")                       // 用户是否通过SSO登录
 	info.Add(jsonutils.NewBool(options.Options.EnableTotp), "system_totp_on") // 全局totp 开启状态。 True（已开启）|False(未开启)
+	info.Add(jsonutils.NewBool(t.initWebAuthn), "webauthn_init")              // 是否注册了WebAuthn安全密钥
+	info.Add(jsonutils.NewBool(IsWebAuthnEnabled()), "system_webauthn_on")
 	info.Add(jsonutils.NewString(token.GetUserId()), "user_id")
 	info.Add(jsonutils.NewString(token.GetUserName()), "user")
 	return info.String()
 }
 
+// IsMfaEnabled returns whether any second factor is enabled globally
+func IsMfaEnabled() bool {
+	return options.Options.EnableTotp || IsWebAuthnEnabled()
+}
+
+// IsTotpVerified returns whether the second factor is verified, by TOTP
+// passcode, WebAuthn assertion or recovery code
 func (t SAuthToken) IsTotpVerified() bool {
-	if !options.Options.EnableTotp {
+	if !IsMfaEnabled() {
 		return true
 	}
 	if !t.enableTotp {
@@ -225,6 +242,19 @@ func (t *SAuthToken) SetTotpInitialized() {
 	t.initTotp = true
 }
 
+func (t SAuthToken) IsWebAuthnInitialized() bool {
+	return t.initWebAuthn
+}
+
+func (t *SAuthToken) SetWebAuthnInitialized(init bool) {
+	t.initWebAuthn = init
+}
+
+// IsMfaInitialized returns whether user has enrolled any second factor
+func (t SAuthToken) IsMfaInitialized() bool {
+	return t.initTotp || t.initWebAuthn
+}
+
 func (t *SAuthToken) SetToken(tid string) {
 	t.token = tid
 }
@@ -239,6 +269,19 @@ func NewAuthToken(tid string, enableTotp bool, isTotpInit bool, isSsoLogin bool)
 	}
 }
 
+func (t *SAuthToken) checkLocked() error {
+	if t.lockExpireTime > uint32(time.Now().Unix()) {
+		return errors.Wrapf(httperrors.ErrResourceBusy, "locked, retry after %d seconds", t.lockExpireTime-uint32(time.Now().Unix()))
+	}
+	return nil
+}
+
+func (t *SAuthToken) setVerified() {
+	t.verifyTotp = true
+	t.lockExpireTime = 0
+	t.retryCount = 0
+}
+
 func (t *SAuthToken) updateRetryCount() {
 	if t.retryCount < MAX_OTP_RETRY {
 		t.retryCount += 1
@@ -257,8 +300,9 @@ func (t *SAuthToken) updateRetryCount() {
 }
 
 func (t *SAuthToken) VerifyTotpPasscode(s *mcclient.ClientSession, uid, passcode string) error {
-	if t.lockExpireTime > uint32(time.Now().Unix()) {
-		return errors.Wrapf(httperrors.ErrResourceBusy, "locked, retry after %d seconds", t.lockExpireTime-uint32(time.Now().Unix()))
+	err := t.checkLocked()
+	if err != nil {
+		return err
 	}
 
 	secret, err := fetchUserTotpCredSecret(s, uid)
@@ -267,9 +311,7 @@ func (t *SAuthToken) VerifyTotpPasscode(s *mcclient.ClientSession, uid, passcode
 	}
 
 	if totp.Validate(passcode, secret) {
-		t.verifyTotp = true
-		t.lockExpireTime = 0
-		t.retryCount = 0
+		t.setVerified()
 		return nil
 	}
 
@@ -54,3 +54,34 @@ func TestEncoeDecode(t *testing.T) {
 		t.Fatalf("token2 != token")
 	}
 }
+
+func TestDecodeMfaInitFlag(t *testing.T) {
+	cases := []struct {
+		token        SAuthToken
+		initTotp     bool
+		initWebAuthn bool
+	}{
+		{SAuthToken{token: "t", initTotp: true}, true, false},
+		{SAuthToken{token: "t", initWebAuthn: true}, false, true},
+		{SAuthToken{token: "t", initTotp: true, initWebAuthn: true}, true, true},
+	}
+	for _, c := range cases {
+		token, err := decodeBytes(c.token.encodeBytes())
+		if err != nil {
+			t.Fatalf("decodeBytes fail %s", err)
+		}
+		if token.initTotp != c.initTotp || token.initWebAuthn != c.initWebAuthn {
+			t.Errorf("expect totp %v webauthn %v, got %v %v", c.initTotp, c.initWebAuthn, token.initTotp, token.initWebAuthn)
+		}
+	}
+
+	// cookie issued before webauthn is supported
+	old := append([]byte{TotpEnable, TotpEnable, TotpEnable, TotpDisable, 0, 0, 0, 0, 0, 0}, "t"...)
+	token, err := decodeBytes(old)
+	if err != nil {
+		t.Fatalf("decodeBytes fail %s", err)
+	}
+	if !token.initTotp || token.initWebAuthn {
+		t.Errorf("old cookie should be totp initialized only")
+	}
+}
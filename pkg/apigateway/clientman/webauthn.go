@@ -0,0 +1,269 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientman
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apigateway/options"
+	agapi "yunion.io/x/onecloud/pkg/apis/apigateway"
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/identity"
+	"yunion.io/x/onecloud/pkg/util/webauthn"
+)
+
+const (
+	WEBAUTHN_REGISTER = "register"
+	WEBAUTHN_LOGIN    = "login"
+
+	WebAuthnTimeout = 5 * time.Minute
+)
+
+type sWebAuthnChallenge struct {
+	challenge []byte
+	expire    time.Time
+}
+
+// sChallengeStore keeps the pending challenges of ceremonies, each challenge
+// could only be used once
+type sChallengeStore struct {
+	lock       sync.Mutex
+	challenges map[string]sWebAuthnChallenge
+}
+
+var challengeStore = &sChallengeStore{
+	challenges: map[string]sWebAuthnChallenge{},
+}
+
+func (s *sChallengeStore) put(key string, challenge []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	for k, v := range s.challenges {
+		if v.expire.Before(now) {
+			delete(s.challenges, k)
+		}
+	}
+	s.challenges[key] = sWebAuthnChallenge{
+		challenge: challenge,
+		expire:    now.Add(WebAuthnTimeout),
+	}
+}
+
+func (s *sChallengeStore) pop(key string) ([]byte, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	c, ok := s.challenges[key]
+	if !ok {
+		return nil, false
+	}
+	delete(s.challenges, key)
+	if c.expire.Before(time.Now()) {
+		return nil, false
+	}
+	return c.challenge, true
+}
+
+func (t SAuthToken) challengeKey(purpose string) string {
+	return fmt.Sprintf("%s/%s", purpose, t.token)
+}
+
+// NewWebAuthnChallenge starts a ceremony of the login session
+func (t SAuthToken) NewWebAuthnChallenge(purpose string) ([]byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	challengeStore.put(t.challengeKey(purpose), challenge)
+	return challenge, nil
+}
+
+func (t SAuthToken) popWebAuthnChallenge(purpose string) ([]byte, error) {
+	challenge, ok := challengeStore.pop(t.challengeKey(purpose))
+	if !ok {
+		return nil, errors.Wrap(httperrors.ErrInvalidStatus, "webauthn challenge not found or expired")
+	}
+	return challenge, nil
+}
+
+// GetRelyingParty returns the relying party of the web console, the rp id
+// and origins not configured are derived from api_server, never from the
+// request which is controlled by the client
+func GetRelyingParty() (webauthn.SRelyingParty, error) {
+	rp := webauthn.SRelyingParty{
+		Id:      options.Options.WebAuthnRPId,
+		Name:    options.Options.WebAuthnRPName,
+		Origins: options.Options.WebAuthnOrigins,
+	}
+	if len(options.Options.ApiServer) > 0 {
+		u, err := url.Parse(options.Options.ApiServer)
+		if err == nil && len(u.Hostname()) > 0 {
+			if len(rp.Id) == 0 {
+				rp.Id = u.Hostname()
+			}
+			if len(rp.Origins) == 0 {
+				rp.Origins = []string{fmt.Sprintf("%s://%s", u.Scheme, u.Host)}
+			}
+		}
+	}
+	if len(rp.Id) == 0 {
+		return rp, errors.Wrap(httperrors.ErrNotSupported, "webauthn relying party is not configured, set webauthn_rp_id or api_server")
+	}
+	if len(rp.Origins) == 0 {
+		rp.Origins = []string{fmt.Sprintf("https://%s", rp.Id)}
+	}
+	return rp, nil
+}
+
+// IsWebAuthnEnabled returns whether WebAuthn is enabled and its relying party
+// is configured
+func IsWebAuthnEnabled() bool {
+	if !options.Options.EnableWebAuthn {
+		return false
+	}
+	_, err := GetRelyingParty()
+	return err == nil
+}
+
+func decodeWebAuthnFields(fields ...string) ([][]byte, error) {
+	ret := make([][]byte, len(fields))
+	for i := range fields {
+		data, err := webauthn.DecodeBase64URL(fields[i])
+		if err != nil || len(data) == 0 {
+			return nil, errors.Wrapf(httperrors.ErrInputParameter, "invalid base64url field #%d", i)
+		}
+		ret[i] = data
+	}
+	return ret, nil
+}
+
+// RegisterWebAuthnCredential verifies the result of navigator.credentials.create()
+// and saves the new credential. The first enrolled factor also verifies the session.
+func (t *SAuthToken) RegisterWebAuthnCredential(s *mcclient.ClientSession, uid string, rp webauthn.SRelyingParty, input agapi.SWebAuthnRegisterInput) (*modules.SWebAuthnCredential, error) {
+	challenge, err := t.popWebAuthnChallenge(WEBAUTHN_REGISTER)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := decodeWebAuthnFields(input.Response.ClientDataJSON, input.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	cred, err := rp.VerifyRegistration(fields[0], fields[1], challenge, false)
+	if err != nil {
+		return nil, errors.Wrap(httperrors.ErrInvalidCredential, err.Error())
+	}
+	creds, err := modules.Credentials.GetWebAuthnCredentials(s, uid)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetWebAuthnCredentials")
+	}
+	credId := webauthn.EncodeBase64URL(cred.Id)
+	for i := range creds {
+		if creds[i].CredentialId == credId {
+			return nil, errors.Wrap(httperrors.ErrDuplicateResource, "credential already registered")
+		}
+	}
+	blob := api.SWebAuthnCredentialBlob{
+		CredentialId: credId,
+		PublicKey:    webauthn.EncodeBase64URL(cred.PublicKey),
+		SignCount:    cred.SignCount,
+		Aaguid:       fmt.Sprintf("%x", cred.AAGUID),
+		Transports:   input.Response.Transports,
+	}
+	ret, err := modules.Credentials.CreateWebAuthnCredential(s, uid, input.Name, blob)
+	if err != nil {
+		return nil, errors.Wrap(err, "CreateWebAuthnCredential")
+	}
+	if !t.IsMfaInitialized() {
+		t.setVerified()
+	}
+	t.initWebAuthn = true
+	return &ret, nil
+}
+
+// VerifyWebAuthnAssertion verifies the result of navigator.credentials.get()
+// with the registered credentials of user
+func (t *SAuthToken) VerifyWebAuthnAssertion(s *mcclient.ClientSession, uid string, rp webauthn.SRelyingParty, input agapi.SWebAuthnLoginInput) error {
+	err := t.checkLocked()
+	if err != nil {
+		return err
+	}
+	challenge, err := t.popWebAuthnChallenge(WEBAUTHN_LOGIN)
+	if err != nil {
+		return err
+	}
+	fields, err := decodeWebAuthnFields(input.Id, input.Response.ClientDataJSON, input.Response.AuthenticatorData, input.Response.Signature)
+	if err != nil {
+		return err
+	}
+	creds, err := modules.Credentials.GetWebAuthnCredentials(s, uid)
+	if err != nil {
+		return errors.Wrap(err, "GetWebAuthnCredentials")
+	}
+	var cred *modules.SWebAuthnCredential
+	for i := range creds {
+		id, _ := webauthn.DecodeBase64URL(creds[i].CredentialId)
+		if bytes.Equal(id, fields[0]) {
+			cred = &creds[i]
+			break
+		}
+	}
+	if cred == nil {
+		t.updateRetryCount()
+		return errors.Wrap(httperrors.ErrInvalidCredential, "credential not registered")
+	}
+	pubKey, _ := webauthn.DecodeBase64URL(cred.PublicKey)
+	signCount, err := rp.VerifyAssertion(webauthn.SCredential{
+		Id:        fields[0],
+		PublicKey: pubKey,
+		SignCount: cred.SignCount,
+	}, fields[1], fields[2], fields[3], challenge, false)
+	if err != nil {
+		t.updateRetryCount()
+		return errors.Wrap(httperrors.ErrInvalidCredential, err.Error())
+	}
+	cred.SignCount = signCount
+	cred.LastUsedAt = time.Now().Unix()
+	err = modules.Credentials.UpdateWebAuthnCredential(s, *cred)
+	if err != nil {
+		return errors.Wrap(err, "UpdateWebAuthnCredential")
+	}
+	t.setVerified()
+	return nil
+}
+
+// VerifyRecoveryCode consumes a recovery code of user and returns the number of remaining codes
+func (t *SAuthToken) VerifyRecoveryCode(s *mcclient.ClientSession, uid string, code string) (int, error) {
+	err := t.checkLocked()
+	if err != nil {
+		return 0, err
+	}
+	left, err := modules.Credentials.UseRecoveryCode(s, uid, code)
+	if err != nil {
+		t.updateRetryCount()
+		return 0, err
+	}
+	t.setVerified()
+	return left, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientman
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/apigateway/options"
+)
+
+func TestGetRelyingParty(t *testing.T) {
+	options.Options = &options.GatewayOptions{}
+	cases := []struct {
+		apiServer string
+		rpId      string
+		origins   []string
+		wantId    string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			wantErr: true,
+		},
+		{
+			apiServer: "https://cloud.example.com:8443/",
+			wantId:    "cloud.example.com",
+			want:      []string{"https://cloud.example.com:8443"},
+		},
+		{
+			apiServer: "https://cloud.example.com",
+			rpId:      "example.com",
+			origins:   []string{"https://console.example.com"},
+			wantId:    "example.com",
+			want:      []string{"https://console.example.com"},
+		},
+		{
+			rpId:   "example.com",
+			wantId: "example.com",
+			want:   []string{"https://example.com"},
+		},
+	}
+	for _, c := range cases {
+		options.Options.ApiServer = c.apiServer
+		options.Options.WebAuthnRPId = c.rpId
+		options.Options.WebAuthnOrigins = c.origins
+		rp, err := GetRelyingParty()
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("api_server %q rp id %q: want error, got %#v", c.apiServer, c.rpId, rp)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("api_server %q rp id %q: %v", c.apiServer, c.rpId, err)
+		}
+		if rp.Id != c.wantId || !reflect.DeepEqual(rp.Origins, c.want) {
+			t.Errorf("api_server %q rp id %q: want %s %v, got %s %v", c.apiServer, c.rpId, c.wantId, c.want, rp.Id, rp.Origins)
+		}
+	}
+}
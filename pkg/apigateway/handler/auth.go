@@ -84,6 +84,11 @@ func (h *AuthHandlers) AddMethods() {
 		NewHP(h.resetTotpSecrets, "credential"),
 		NewHP(h.validatePasscode, "passcode"),
 		NewHP(h.resetTotpRecoveryQuestions, "recovery"),
+		NewHP(webauthnRegisterBegin, "webauthn", "register", "begin"),
+		NewHP(webauthnRegisterFinish, "webauthn", "register", "finish"),
+		NewHP(webauthnLoginBegin, "webauthn", "login", "begin"),
+		NewHP(webauthnLoginFinish, "webauthn", "login", "finish"),
+		NewHP(validateRecoveryCodeHandler, "recovery-code"),
 		NewHP(h.postLoginHandler, "login"),
 		NewHP(h.postLogoutHandler, "logout"),
 		NewHP(h.handleSsoLogin, "ssologin"),
@@ -100,18 +105,22 @@ func (h *AuthHandlers) AddMethods() {
 		NewHP(h.getResources, "scoped_resources"),
 		NewHP(fetchIdpBasicConfig, "idp", "<idp_id>", "info"),
 		NewHP(fetchIdpSAMLMetadata, "idp", "<idp_id>", "saml-metadata"),
+		NewHP(listWebAuthnCredentials, "webauthn", "credentials"),
+		NewHP(getRecoveryCodes, "recovery-codes"),
 	)
 	h.AddByMethod(POST, FetchAuthToken,
 		NewHP(h.resetUserPassword, "password"),
 		NewHP(h.getPermissionDetails, "permissions"),
 		NewHP(h.doCreatePolicies, "policies"),
 		NewHP(handleUnlinkIdp, "unlink-idp"),
+		NewHP(resetRecoveryCodes, "recovery-codes"),
 	)
 	h.AddByMethod(PATCH, FetchAuthToken,
 		NewHP(h.doPatchPolicy, "policies", "<policy_id>"),
 	)
 	h.AddByMethod(DELETE, FetchAuthToken,
 		NewHP(h.doDeletePolicies, "policies"),
+		NewHP(deleteWebAuthnCredential, "webauthn", "credentials", "<cred_id>"),
 	)
 }
 
@@ -587,8 +596,14 @@ func (h *AuthHandlers) doLogin(ctx context.Context, w http.ResponseWriter, req *
 		if err != nil {
 			return err
 		}
+		isWebAuthnInit, err := isUserWebAuthnCredInitialed(s, token.GetUserId())
+		if err != nil {
+			return err
+		}
 		isIdpLogin := body.Contains("idp_driver")
-		authToken = clientman.NewAuthToken(token.GetTokenString(), isUserEnableTotp(userInfo), isTotpInit, isIdpLogin)
+		enableMfa := isUserEnableTotp(userInfo) || isMfaRequiredByPolicy(token)
+		authToken = clientman.NewAuthToken(token.GetTokenString(), enableMfa, isTotpInit, isIdpLogin)
+		authToken.SetWebAuthnInitialized(isWebAuthnInit)
 	}
 
 	if !isUserAllowWebconsole(userInfo) {
@@ -879,6 +894,7 @@ func getUserInfo2(s *mcclient.ClientSession, uid string, pid string, loginIp str
 	data.Add(jsonutils.NewString(usrDomainName), "domain", "name")
 	data.Add(jsonutils.NewStringArray(auth.AdminCredential().GetRegions()), "regions")
 	data.Add(jsonutils.NewBool(options.Options.EnableTotp), "system_totp_on")
+	data.Add(jsonutils.NewBool(clientman.IsWebAuthnEnabled()), "system_webauthn_on")
 
 	var projName string
 	var projDomainId string
@@ -213,6 +213,11 @@ func initTotpSecrets(ctx context.Context, w http.ResponseWriter, req *http.Reque
 		resetTotpSecrets(ctx, w, req)
 		return
 	}
+	// 已注册安全密钥的用户需先通过验证才能初始化TOTP
+	if authToken.IsWebAuthnInitialized() && !authToken.IsTotpVerified() {
+		httperrors.ForbiddenError(ctx, w, "verify the registered second factor first")
+		return
+	}
 
 	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
 	code, err := doCreateUserTotpCred(s, t)
@@ -0,0 +1,398 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/apigateway/clientman"
+	"yunion.io/x/onecloud/pkg/apigateway/options"
+	agapi "yunion.io/x/onecloud/pkg/apis/apigateway"
+	"yunion.io/x/onecloud/pkg/appctx"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/identity"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/webauthn"
+)
+
+const webauthnPublicKeyType = "public-key"
+
+// 检查用户是否注册了WebAuthn安全密钥
+func isUserWebAuthnCredInitialed(s *mcclient.ClientSession, uid string) (bool, error) {
+	if !clientman.IsWebAuthnEnabled() {
+		return false, nil
+	}
+	creds, err := modules.Credentials.GetWebAuthnCredentials(s, uid)
+	if err != nil {
+		return false, err
+	}
+	return len(creds) > 0, nil
+}
+
+// 检查用户所在域或角色是否强制要求双因子认证
+func isMfaRequiredByPolicy(token mcclient.TokenCredential) bool {
+	if !clientman.IsMfaEnabled() {
+		return false
+	}
+	for _, domain := range options.Options.MfaRequiredDomains {
+		if domain == token.GetDomainId() || domain == token.GetDomainName() {
+			return true
+		}
+	}
+	for _, role := range options.Options.MfaRequiredRoles {
+		if utils.IsInStringArray(role, token.GetRoles()) || utils.IsInStringArray(role, token.GetRoleIds()) {
+			return true
+		}
+	}
+	return false
+}
+
+func webauthnCredentialDescriptors(creds []modules.SWebAuthnCredential) []agapi.SWebAuthnCredentialDescriptor {
+	ret := make([]agapi.SWebAuthnCredentialDescriptor, len(creds))
+	for i := range creds {
+		ret[i] = agapi.SWebAuthnCredentialDescriptor{
+			Type:       webauthnPublicKeyType,
+			Id:         creds[i].CredentialId,
+			Transports: creds[i].Transports,
+		}
+	}
+	return ret
+}
+
+func fetchWebAuthnAuthInfo(ctx context.Context, w http.ResponseWriter, req *http.Request) (mcclient.TokenCredential, *clientman.SAuthToken, webauthn.SRelyingParty, bool) {
+	if !options.Options.EnableWebAuthn {
+		httperrors.ForbiddenError(ctx, w, "webauthn is not enabled")
+		return nil, nil, webauthn.SRelyingParty{}, false
+	}
+	rp, err := clientman.GetRelyingParty()
+	if err != nil {
+		httperrors.ForbiddenError(ctx, w, "webauthn is not enabled: %v", err)
+		return nil, nil, rp, false
+	}
+	t, authToken, err := fetchAuthInfo(ctx, req)
+	if err != nil {
+		httperrors.InvalidCredentialError(ctx, w, "fetchAuthInfo fail: %s", err)
+		return nil, nil, rp, false
+	}
+	return t, authToken, rp, true
+}
+
+// 生成注册WebAuthn安全密钥的参数，用于 navigator.credentials.create()
+func webauthnRegisterBegin(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t, authToken, rp, ok := fetchWebAuthnAuthInfo(ctx, w, req)
+	if !ok {
+		return
+	}
+	// 已经有第二因子的用户需先通过验证才能注册新的安全密钥
+	if authToken.IsMfaInitialized() && !authToken.IsTotpVerified() {
+		httperrors.ForbiddenError(ctx, w, "verify the registered second factor first")
+		return
+	}
+
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	creds, err := modules.Credentials.GetWebAuthnCredentials(s, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	challenge, err := authToken.NewWebAuthnChallenge(clientman.WEBAUTHN_REGISTER)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+
+	opts := agapi.SWebAuthnCreationOptions{
+		Challenge: webauthn.EncodeBase64URL(challenge),
+		Rp: agapi.SWebAuthnRelyingParty{
+			Id:   rp.Id,
+			Name: rp.Name,
+		},
+		User: agapi.SWebAuthnUser{
+			Id:          webauthn.EncodeBase64URL([]byte(t.GetUserId())),
+			Name:        t.GetUserName(),
+			DisplayName: t.GetUserName(),
+		},
+		Timeout:            int(clientman.WebAuthnTimeout / time.Millisecond),
+		ExcludeCredentials: webauthnCredentialDescriptors(creds),
+		AuthenticatorSelection: agapi.SWebAuthnAuthenticatorSelection{
+			ResidentKey:      "discouraged",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	}
+	for _, alg := range webauthn.SupportedAlgorithms {
+		opts.PubKeyCredParams = append(opts.PubKeyCredParams, agapi.SWebAuthnCredParam{
+			Type: webauthnPublicKeyType,
+			Alg:  alg,
+		})
+	}
+
+	resp := jsonutils.NewDict()
+	resp.Add(jsonutils.Marshal(opts), "publicKey")
+	appsrv.SendJSON(w, resp)
+}
+
+// 验证 navigator.credentials.create() 的结果并保存安全密钥。首次注册时返回恢复码
+func webauthnRegisterFinish(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t, authToken, rp, ok := fetchWebAuthnAuthInfo(ctx, w, req)
+	if !ok {
+		return
+	}
+	if authToken.IsMfaInitialized() && !authToken.IsTotpVerified() {
+		httperrors.ForbiddenError(ctx, w, "verify the registered second factor first")
+		return
+	}
+
+	_, _, body := appsrv.FetchEnv(ctx, w, req)
+	if body == nil {
+		httperrors.InvalidInputError(ctx, w, "request body is empty")
+		return
+	}
+	input := agapi.SWebAuthnRegisterInput{}
+	err := body.Unmarshal(&input)
+	if err != nil {
+		httperrors.InvalidInputError(ctx, w, "unmarshal input: %v", err)
+		return
+	}
+
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	cred, err := authToken.RegisterWebAuthnCredential(s, t.GetUserId(), rp, input)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	saveAuthCookie(w, authToken, t)
+
+	resp := jsonutils.Marshal(webauthnCredentialDetails(*cred)).(*jsonutils.JSONDict)
+	// 首次注册第二因子时生成恢复码，恢复码只展示一次
+	_, err = modules.Credentials.CountRecoveryCodes(s, t.GetUserId())
+	if e, ok := err.(*httputils.JSONClientError); ok && e.Code == 404 {
+		codes, err := modules.Credentials.CreateRecoveryCodes(s, t.GetUserId(), options.Options.RecoveryCodeCount)
+		if err != nil {
+			log.Errorf("CreateRecoveryCodes for %s fail %s", t.GetUserId(), err)
+		} else {
+			resp.Add(jsonutils.NewStringArray(codes), "recovery_codes")
+		}
+	}
+	appsrv.SendJSON(w, resp)
+}
+
+// 生成WebAuthn认证参数，用于 navigator.credentials.get()
+func webauthnLoginBegin(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t, authToken, rp, ok := fetchWebAuthnAuthInfo(ctx, w, req)
+	if !ok {
+		return
+	}
+
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	creds, err := modules.Credentials.GetWebAuthnCredentials(s, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	if len(creds) == 0 {
+		httperrors.NotFoundError(ctx, w, "no webauthn credential registered")
+		return
+	}
+	challenge, err := authToken.NewWebAuthnChallenge(clientman.WEBAUTHN_LOGIN)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+
+	opts := agapi.SWebAuthnRequestOptions{
+		Challenge:        webauthn.EncodeBase64URL(challenge),
+		RpId:             rp.Id,
+		Timeout:          int(clientman.WebAuthnTimeout / time.Millisecond),
+		AllowCredentials: webauthnCredentialDescriptors(creds),
+		UserVerification: "preferred",
+	}
+	resp := jsonutils.NewDict()
+	resp.Add(jsonutils.Marshal(opts), "publicKey")
+	appsrv.SendJSON(w, resp)
+}
+
+// 验证 navigator.credentials.get() 的结果，通过后完成双因子认证
+func webauthnLoginFinish(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t, authToken, rp, ok := fetchWebAuthnAuthInfo(ctx, w, req)
+	if !ok {
+		return
+	}
+
+	_, _, body := appsrv.FetchEnv(ctx, w, req)
+	if body == nil {
+		httperrors.InvalidInputError(ctx, w, "request body is empty")
+		return
+	}
+	input := agapi.SWebAuthnLoginInput{}
+	err := body.Unmarshal(&input)
+	if err != nil {
+		httperrors.InvalidInputError(ctx, w, "unmarshal input: %v", err)
+		return
+	}
+
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	err = authToken.VerifyWebAuthnAssertion(s, t.GetUserId(), rp, input)
+
+	saveAuthCookie(w, authToken, t)
+
+	if err != nil {
+		log.Warningf("VerifyWebAuthnAssertion %s", err.Error())
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+
+	appsrv.SendJSON(w, jsonutils.NewDict())
+}
+
+// 使用一次性恢复码完成双因子认证，用于安全密钥丢失的情况
+func validateRecoveryCodeHandler(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	if !clientman.IsMfaEnabled() {
+		httperrors.ForbiddenError(ctx, w, "two-factor authentication is not enabled")
+		return
+	}
+	t, authToken, err := fetchAuthInfo(ctx, req)
+	if err != nil {
+		httperrors.InvalidCredentialError(ctx, w, "fetchAuthInfo fail: %s", err)
+		return
+	}
+
+	_, _, body := appsrv.FetchEnv(ctx, w, req)
+	if body == nil {
+		httperrors.InvalidInputError(ctx, w, "request body is empty")
+		return
+	}
+	input := agapi.SRecoveryCodeInput{}
+	body.Unmarshal(&input)
+	if len(input.Code) == 0 {
+		httperrors.MissingParameterError(ctx, w, "code")
+		return
+	}
+
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	left, err := authToken.VerifyRecoveryCode(s, t.GetUserId(), input.Code)
+
+	saveAuthCookie(w, authToken, t)
+
+	if err != nil {
+		log.Warningf("VerifyRecoveryCode %s", err.Error())
+		httperrors.InputParameterError(ctx, w, "invalid recovery code: %v", err)
+		return
+	}
+
+	resp := jsonutils.NewDict()
+	resp.Add(jsonutils.NewInt(int64(left)), "remaining")
+	appsrv.SendJSON(w, resp)
+}
+
+func webauthnCredentialDetails(cred modules.SWebAuthnCredential) agapi.SWebAuthnCredentialDetails {
+	ret := agapi.SWebAuthnCredentialDetails{
+		Id:         cred.Id,
+		Name:       cred.Name,
+		Aaguid:     cred.Aaguid,
+		Transports: cred.Transports,
+	}
+	if !cred.CreatedAt.IsZero() {
+		ret.CreatedAt = cred.CreatedAt.Format(time.RFC3339)
+	}
+	if cred.LastUsedAt > 0 {
+		ret.LastUsedAt = time.Unix(cred.LastUsedAt, 0).UTC().Format(time.RFC3339)
+	}
+	return ret
+}
+
+// 列出当前用户注册的安全密钥
+func listWebAuthnCredentials(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t := AppContextToken(ctx)
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	creds, err := modules.Credentials.GetWebAuthnCredentials(s, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	data := make([]agapi.SWebAuthnCredentialDetails, len(creds))
+	for i := range creds {
+		data[i] = webauthnCredentialDetails(creds[i])
+	}
+	resp := jsonutils.NewDict()
+	resp.Add(jsonutils.Marshal(data), "data")
+	appsrv.SendJSON(w, resp)
+}
+
+// 删除当前用户的安全密钥
+func deleteWebAuthnCredential(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t, authToken, err := fetchAuthInfo(ctx, req)
+	if err != nil {
+		httperrors.InvalidCredentialError(ctx, w, "fetchAuthInfo fail: %s", err)
+		return
+	}
+	params := appctx.AppContextParams(ctx)
+	credId := params["<cred_id>"]
+
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	err = modules.Credentials.RemoveWebAuthnCredential(s, t.GetUserId(), credId)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	init, err := isUserWebAuthnCredInitialed(s, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	authToken.SetWebAuthnInitialized(init)
+	saveAuthCookie(w, authToken, t)
+
+	appsrv.SendJSON(w, jsonutils.NewDict())
+}
+
+// 查询剩余的恢复码数量
+func getRecoveryCodes(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t := AppContextToken(ctx)
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	left, err := modules.Credentials.CountRecoveryCodes(s, t.GetUserId())
+	if err != nil {
+		if e, ok := err.(*httputils.JSONClientError); !ok || e.Code != 404 {
+			httperrors.GeneralServerError(ctx, w, err)
+			return
+		}
+	}
+	resp := jsonutils.NewDict()
+	resp.Add(jsonutils.NewInt(int64(left)), "remaining")
+	appsrv.SendJSON(w, resp)
+}
+
+// 重新生成恢复码，旧的恢复码全部失效
+func resetRecoveryCodes(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t := AppContextToken(ctx)
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	codes, err := modules.Credentials.CreateRecoveryCodes(s, t.GetUserId(), options.Options.RecoveryCodeCount)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	resp := jsonutils.NewDict()
+	resp.Add(jsonutils.NewStringArray(codes), "recovery_codes")
+	appsrv.SendJSON(w, resp)
+}
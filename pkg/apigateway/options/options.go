@@ -29,6 +29,15 @@ type GatewayOptions struct {
 	EnableTotp bool   `help:"Enable two-factor authentication" default:"false"`
 	TotpIssuer string `help:"TOTP issuer" default:"Cloudpods"`
 
+	EnableWebAuthn  bool     `help:"Enable WebAuthn (FIDO2) security keys and platform authenticators as second factor" default:"false"`
+	WebAuthnRPId    string   `help:"WebAuthn relying party id, the effective domain of web console, e.g. cloud.example.com. Default is the host of api_server"`
+	WebAuthnRPName  string   `help:"WebAuthn relying party display name" default:"Cloudpods"`
+	WebAuthnOrigins []string `help:"Allowed origins of WebAuthn client data, e.g. https://cloud.example.com. Default is the origin of api_server or https://<rp id>"`
+
+	MfaRequiredDomains []string `help:"Id or name of domains whose users are required to pass two-factor authentication"`
+	MfaRequiredRoles   []string `help:"Id or name of roles whose users are required to pass two-factor authentication"`
+	RecoveryCodeCount  int      `help:"Number of one-time MFA recovery codes generated for user" default:"10"`
+
 	SsoRedirectUrl     string `help:"SSO idp redirect URL"`
 	SsoAuthCallbackUrl string `help:"SSO idp auth callback URL"`
 	SsoLinkCallbackUrl string `help:"SSO idp link user callback URL"`
@@ -0,0 +1,107 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apigateway
+
+// WebAuthn ceremony options and responses, the binary fields are base64url
+// encoded as described in https://www.w3.org/TR/webauthn-3/#sctn-parseCreationOptionsFromJSON
+
+type SWebAuthnRelyingParty struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type SWebAuthnUser struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type SWebAuthnCredParam struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type SWebAuthnCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	Id         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type SWebAuthnAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// SWebAuthnCreationOptions is the argument of navigator.credentials.create()
+type SWebAuthnCreationOptions struct {
+	Challenge              string                          `json:"challenge"`
+	Rp                     SWebAuthnRelyingParty           `json:"rp"`
+	User                   SWebAuthnUser                   `json:"user"`
+	PubKeyCredParams       []SWebAuthnCredParam            `json:"pubKeyCredParams"`
+	Timeout                int                             `json:"timeout"`
+	ExcludeCredentials     []SWebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection SWebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                          `json:"attestation"`
+}
+
+// SWebAuthnRequestOptions is the argument of navigator.credentials.get()
+type SWebAuthnRequestOptions struct {
+	Challenge        string                          `json:"challenge"`
+	RpId             string                          `json:"rpId"`
+	Timeout          int                             `json:"timeout"`
+	AllowCredentials []SWebAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                          `json:"userVerification"`
+}
+
+type SWebAuthnAttestationResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON"`
+	AttestationObject string   `json:"attestationObject"`
+	Transports        []string `json:"transports"`
+}
+
+// SWebAuthnRegisterInput is the result of navigator.credentials.create()
+type SWebAuthnRegisterInput struct {
+	// 凭证名称，例如 "YubiKey 5"
+	Name string `json:"name"`
+
+	Id       string                       `json:"id"`
+	Response SWebAuthnAttestationResponse `json:"response"`
+}
+
+type SWebAuthnAssertionResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"userHandle"`
+}
+
+// SWebAuthnLoginInput is the result of navigator.credentials.get()
+type SWebAuthnLoginInput struct {
+	Id       string                     `json:"id"`
+	Response SWebAuthnAssertionResponse `json:"response"`
+}
+
+type SRecoveryCodeInput struct {
+	Code string `json:"code"`
+}
+
+type SWebAuthnCredentialDetails struct {
+	Id         string   `json:"id"`
+	Name       string   `json:"name"`
+	Aaguid     string   `json:"aaguid"`
+	Transports []string `json:"transports"`
+	CreatedAt  string   `json:"created_at"`
+	LastUsedAt string   `json:"last_used_at"`
+}
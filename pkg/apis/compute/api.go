@@ -328,6 +328,10 @@ type ServerConfigs struct {
 	// required: false
 	IsolatedDevices []*IsolatedDeviceConfig `json:"isolated_devices"`
 
+	// 要求虚拟机的vcpu和内存放在宿主机的同一个NUMA节点上, 仅对KVM生效
+	// required: false
+	NumaStrict bool `json:"numa_strict"`
+
 	// 裸金属磁盘配置列表
 	BaremetalDiskConfigs []*BaremetalDiskConfig `json:"baremetal_disk_configs"`
 
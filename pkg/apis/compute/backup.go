@@ -21,9 +21,11 @@ import (
 )
 
 const (
-	BACKUPSTORAGE_TYPE_NFS       = "nfs"
-	BACKUPSTORAGE_STATUS_ONLINE  = "online"
-	BACKUPSTORAGE_STATUS_OFFLINE = "offline"
+	BACKUPSTORAGE_TYPE_NFS            = "nfs"
+	BACKUPSTORAGE_TYPE_LOCAL          = "local"
+	BACKUPSTORAGE_TYPE_OBJECT_STORAGE = "object"
+	BACKUPSTORAGE_STATUS_ONLINE       = "online"
+	BACKUPSTORAGE_STATUS_OFFLINE      = "offline"
 
 	BACKUP_STATUS_CREATING                = "creating"
 	BACKUP_STATUS_CREATE_FAILED           = "create_failed"
@@ -52,7 +54,7 @@ type BackupStorageCreateInput struct {
 	apis.EnabledStatusInfrasResourceBaseCreateInput
 
 	// description: storage type
-	// enum: nfs
+	// enum: nfs,local,object
 	StorageType string `json:"storage_type"`
 
 	// description: host of nfs, storage_type 为 nfs 时, 此参数必传
@@ -63,6 +65,24 @@ type BackupStorageCreateInput struct {
 	// example: /nfs_root/
 	NfsSharedDir string `json:"nfs_shared_dir"`
 
+	// description: directory on hosts, storage_type 为 local 时, 此参数必传
+	// example: /opt/cloud/backups
+	LocalPath string `json:"local_path"`
+
+	// description: endpoint of S3 compatible object storage, storage_type 为 object 时, 此参数必传
+	// example: https://s3.example.com
+	ObjectEndpoint string `json:"object_endpoint"`
+
+	// description: bucket of object storage, storage_type 为 object 时, 此参数必传
+	// example: backups
+	ObjectBucket string `json:"object_bucket"`
+
+	// description: access key of object storage
+	ObjectAccessKey string `json:"object_access_key"`
+
+	// description: secret of object storage
+	ObjectSecret string `json:"object_secret"`
+
 	// description: Capacity size in MB
 	CapacityMb int `json:"capacity_mb"`
 }
@@ -76,6 +96,12 @@ type BackupStorageDetails struct {
 
 	NfsHost      string
 	NfsSharedDir string
+
+	LocalPath string
+
+	ObjectEndpoint  string
+	ObjectBucket    string
+	ObjectAccessKey string
 }
 
 type BackupStorageListInput struct {
@@ -107,6 +133,8 @@ type DiskBackupDetails struct {
 	BackupStorageName string `json:"backup_storage_name"`
 	// description: 是否是子备份
 	IsSubBackup bool `json:"is_sub_backup"`
+	// description: 增量备份链长度, 全量备份为1
+	BackupChainLength int `json:"backup_chain_length"`
 }
 
 type DiskBackupCreateInput struct {
@@ -117,6 +145,10 @@ type DiskBackupCreateInput struct {
 	DiskId string `json:"disk_id"`
 	// description: backup storage id
 	BackupStorageId string `json:"back_storage_id"`
+	// description: 增量备份, 基于该磁盘在同一备份存储上最近的备份
+	Incremental bool `json:"incremental"`
+	// swagger: ignore
+	ParentBackupId string `json:"parent_backup_id"`
 	// swagger: ignore
 	CloudregionId string `json:"cloudregion_id"`
 	// swagger:ignore
@@ -56,6 +56,7 @@ const (
 	CLOUD_PROVIDER_CLOUDPODS      = "Cloudpods"
 	CLOUD_PROVIDER_BINGO_CLOUD    = "BingoCloud"
 	CLOUD_PROVIDER_INCLOUD_SPHERE = "InCloudSphere"
+	CLOUD_PROVIDER_MOCKCLOUD      = "MockCloud"
 
 	CLOUD_PROVIDER_GENERICS3 = "S3"
 	CLOUD_PROVIDER_CEPH      = "Ceph"
@@ -124,6 +125,7 @@ var (
 		CLOUD_PROVIDER_NUTANIX,
 		CLOUD_PROVIDER_BINGO_CLOUD,
 		CLOUD_PROVIDER_INCLOUD_SPHERE,
+		CLOUD_PROVIDER_MOCKCLOUD,
 	}
 
 	CLOUD_PROVIDER_HOST_TYPE_MAP = map[string][]string{
@@ -189,6 +191,9 @@ var (
 		CLOUD_PROVIDER_INCLOUD_SPHERE: {
 			HOST_TYPE_BINGO_CLOUD,
 		},
+		CLOUD_PROVIDER_MOCKCLOUD: {
+			HOST_TYPE_MOCKCLOUD,
+		},
 	}
 )
 
@@ -47,6 +47,11 @@ const (
 	DBINSTANCE_SYNC_CONFIG = "sync_config" //同步配置
 
 	DBINSTANCE_REBOOT_FAILED = "reboot_failed" //重启失败
+	DBINSTANCE_STARTING      = "starting"      //启动中
+	DBINSTANCE_START_FAILED  = "start_failed"  //启动失败
+	DBINSTANCE_STOPPING      = "stopping"      //停止中
+	DBINSTANCE_STOPPED       = "stopped"       //已停止
+	DBINSTANCE_STOP_FAILED   = "stop_failed"   //停止失败
 	DBINSTANCE_CREATE_FAILED = "create_failed" //创建失败
 
 	DBINSTANCE_FAILE = "failed" //操作失败
@@ -184,4 +189,7 @@ var (
 		"ALTER ROUTINE", "EVENT", "TRIGGER",
 	}
 	QCLOUD_R_PRIVILEGE_SET = []string{"SELECT", "LOCK TABLES", "SHOW VIEW"}
+
+	// 支持启动和停止RDS实例的平台
+	DBINSTANCE_START_STOP_PROVIDERS = []string{CLOUD_PROVIDER_AWS, CLOUD_PROVIDER_GOOGLE, CLOUD_PROVIDER_MOCKCLOUD}
 )
@@ -311,6 +311,9 @@ type DiskAllocateFromBackupInput struct {
 	BackupId                string
 	BackupStorageId         string
 	BackupStorageAccessInfo *jsonutils.JSONDict
+	// ParentBackupChain is the backup chain, root first, an incremental
+	// backup depends on
+	ParentBackupChain []string
 }
 
 type DiskDeleteInput struct {
@@ -182,6 +182,7 @@ const (
 	HYPERVISOR_NUTANIX        = "nutanix"
 	HYPERVISOR_BINGO_CLOUD    = "bingocloud"
 	HYPERVISOR_INCLOUD_SPHERE = "incloudsphere"
+	HYPERVISOR_MOCKCLOUD      = "mockcloud"
 
 	//	HYPERVISOR_DEFAULT = HYPERVISOR_KVM
 	HYPERVISOR_DEFAULT = HYPERVISOR_KVM
@@ -232,6 +233,7 @@ var HYPERVISORS = []string{
 	HYPERVISOR_NUTANIX,
 	HYPERVISOR_BINGO_CLOUD,
 	HYPERVISOR_INCLOUD_SPHERE,
+	HYPERVISOR_MOCKCLOUD,
 }
 
 var ONECLOUD_HYPERVISORS = []string{
@@ -262,6 +264,7 @@ var PRIVATE_CLOUD_HYPERVISORS = []string{
 	HYPERVISOR_NUTANIX,
 	HYPERVISOR_BINGO_CLOUD,
 	HYPERVISOR_INCLOUD_SPHERE,
+	HYPERVISOR_MOCKCLOUD,
 }
 
 // var HYPERVISORS = []string{HYPERVISOR_ALIYUN}
@@ -289,6 +292,7 @@ var HYPERVISOR_HOSTTYPE = map[string]string{
 	HYPERVISOR_NUTANIX:        HOST_TYPE_NUTANIX,
 	HYPERVISOR_BINGO_CLOUD:    HOST_TYPE_BINGO_CLOUD,
 	HYPERVISOR_INCLOUD_SPHERE: HOST_TYPE_INCLOUD_SPHERE,
+	HYPERVISOR_MOCKCLOUD:      HOST_TYPE_MOCKCLOUD,
 }
 
 var HOSTTYPE_HYPERVISOR = map[string]string{
@@ -314,6 +318,7 @@ var HOSTTYPE_HYPERVISOR = map[string]string{
 	HOST_TYPE_NUTANIX:        HYPERVISOR_NUTANIX,
 	HOST_TYPE_BINGO_CLOUD:    HYPERVISOR_BINGO_CLOUD,
 	HOST_TYPE_INCLOUD_SPHERE: HYPERVISOR_INCLOUD_SPHERE,
+	HOST_TYPE_MOCKCLOUD:      HYPERVISOR_MOCKCLOUD,
 }
 
 const (
@@ -337,6 +342,18 @@ const (
 	VM_METADATA_OS_VERSION          = "os_version"
 	VM_METADATA_CGROUP_CPUSET       = "cgroup_cpuset"
 	VM_METADATA_ENABLE_MEMCLEAN     = "enable_memclean"
+	// VM_METADATA_NUMA_NODE is the host NUMA node the vcpus and memory of
+	// the guest are bound to
+	VM_METADATA_NUMA_NODE = "numa_node"
+	// ip addresses and filesystem usages reported by qemu guest agent
+	VM_METADATA_QGA_IP_ADDRS = "qga_ip_addrs"
+	VM_METADATA_QGA_FS_USAGE = "qga_fs_usage"
+	// live migration progress reported by source host
+	VM_METADATA_LIVE_MIGRATE_PROGRESS = "live_migrate_progress"
+
+	LIVE_MIGRATE_COMPRESSION_ZSTD = "zstd"
+	// reason reported by source host when live migration is cancelled
+	LIVE_MIGRATE_CANCELLED = "live migration cancelled"
 )
 
 func Hypervisors2HostTypes(hypervisors []string) []string {
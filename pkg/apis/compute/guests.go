@@ -23,6 +23,7 @@ import (
 
 	"yunion.io/x/onecloud/pkg/apis"
 	"yunion.io/x/onecloud/pkg/apis/billing"
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
 	imageapi "yunion.io/x/onecloud/pkg/apis/image"
 	"yunion.io/x/onecloud/pkg/httperrors"
 )
@@ -394,6 +395,41 @@ type GuestLiveMigrateInput struct {
 	SkipKernelCheck *bool `json:"skip_kernel_check"`
 	// 是否启用 tls
 	EnableTLS *bool `json:"enable_tls"`
+
+	GuestLiveMigrateTuning
+}
+
+// GuestLiveMigrateTuning qemu 热迁移调优参数
+type GuestLiveMigrateTuning struct {
+	// multifd 并发传输通道数, 0 表示不启用 multifd
+	MultifdChannels int `json:"multifd_channels"`
+	// multifd 内存压缩算法, 目前支持 zstd
+	Compression string `json:"compression"`
+	// xbzrle 缓存大小(MB), 0 表示不启用 xbzrle
+	XbzrleCacheSizeMb int `json:"xbzrle_cache_size_mb"`
+	// 是否启用 auto-converge, 默认启用
+	AutoConverge *bool `json:"auto_converge"`
+	// 最大迁移带宽(MB/s), 0 表示不限制
+	MaxBandwidthMb int `json:"max_bandwidth_mb"`
+	// 最大停机时间(ms), 0 表示使用宿主机默认配置
+	MaxDowntimeMs int `json:"max_downtime_ms"`
+	// 内存拷贝超过该秒数后切换为 postcopy, 0 表示不启用 postcopy
+	PostcopyAfterSeconds int `json:"postcopy_after_seconds"`
+}
+
+// GuestLiveMigrateProgress 热迁移进度, 保存在虚拟机元数据 live_migrate_progress
+type GuestLiveMigrateProgress struct {
+	Status string `json:"status"`
+	// 迁移进度百分比
+	Progress float64 `json:"progress"`
+	Mbps     float64 `json:"mbps"`
+	// 剩余内存(Bytes)
+	RemainingRam int64 `json:"remaining_ram"`
+	// 内存脏页速率(Bytes/s)
+	DirtyRate int64 `json:"dirty_rate"`
+	// 预计剩余时间(s), -1 表示无法收敛
+	EtaSeconds int64     `json:"eta_seconds"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 type GuestSetSecgroupInput struct {
@@ -846,6 +882,19 @@ type ServerMonitorInput struct {
 	QMP     bool
 }
 
+type ServerQgaSetPasswordInput struct {
+	// 用户名, 默认为虚拟机的登录账号
+	Username string `json:"username"`
+	// 新密码
+	Password string `json:"password"`
+}
+
+type ServerQgaGuestInfo struct {
+	OsInfo      *hostapi.GuestQgaOsInfo      `json:"os_info"`
+	Interfaces  []hostapi.GuestQgaInterface  `json:"interfaces"`
+	Filesystems []hostapi.GuestQgaFilesystem `json:"filesystems"`
+}
+
 type ServerQemuInfo struct {
 	Version string `json:"version"`
 	Cmdline string `json:"cmdline"`
@@ -477,6 +477,17 @@ type SHostPingInput struct {
 	RootPartitionUsedCapacityMb int `json:"root_partition_used_capacity_mb"`
 
 	StorageStats []SHostStorageStat `json:"storage_stats"`
+
+	NumaNodes []HostNumaNode `json:"numa_nodes"`
+}
+
+// HostNumaNode is a NUMA node of a host reported by the host agent
+type HostNumaNode struct {
+	NodeId int `json:"node_id"`
+	// cpus of the node
+	Cpus      []int `json:"cpus"`
+	MemSizeMb int   `json:"mem_size_mb"`
+	MemFreeMb int   `json:"mem_free_mb"`
 }
 
 type HostReserveCpusInput struct {
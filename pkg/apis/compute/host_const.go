@@ -41,6 +41,7 @@ const (
 	HOST_TYPE_NUTANIX        = "nutanix"
 	HOST_TYPE_BINGO_CLOUD    = "bingocloud"
 	HOST_TYPE_INCLOUD_SPHERE = "incloudsphere"
+	HOST_TYPE_MOCKCLOUD      = "mockcloud"
 
 	HOST_TYPE_DEFAULT = HOST_TYPE_HYPERVISOR
 
@@ -126,6 +127,7 @@ var HOST_TYPES = []string{
 	HOST_TYPE_NUTANIX,
 	HOST_TYPE_BINGO_CLOUD,
 	HOST_TYPE_INCLOUD_SPHERE,
+	HOST_TYPE_MOCKCLOUD,
 }
 
 var NIC_TYPES = []string{NIC_TYPE_IPMI, NIC_TYPE_ADMIN}
@@ -154,4 +156,6 @@ const (
 
 const (
 	HOSTMETA_RESERVED_CPUS_INFO = "reserved_cpus_info"
+	// HOSTMETA_NUMA_NODES is the json list of HostNumaNode reported by host ping
+	HOSTMETA_NUMA_NODES = "numa_nodes"
 )
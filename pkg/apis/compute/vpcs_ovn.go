@@ -16,6 +16,7 @@ package compute
 
 import (
 	"fmt"
+	"hash/crc32"
 
 	"yunion.io/x/pkg/util/netutils"
 )
@@ -52,6 +53,13 @@ const (
 	VpcInterExtMac2  = "ee:ee:ee:ee:ee:f1"
 )
 
+const (
+	// [100.65.128.0, 100.65.255.255], 8192 /30 links for vpc peering
+	sVpcPeeringCidr    = "100.65.128.0/17"
+	VpcPeeringLinkMask = 30
+	vpcPeeringLinkNum  = 1 << (32 - 17 - (32 - VpcPeeringLinkMask))
+)
+
 var (
 	vpcInterCidr   netutils.IPV4Prefix
 	vpcInterExtIP1 netutils.IPV4Addr
@@ -70,6 +78,19 @@ func VpcInterExtIP2() netutils.IPV4Addr {
 	return vpcInterExtIP2
 }
 
+var (
+	vpcPeeringCidr netutils.IPV4Prefix
+)
+
+// VpcPeeringLinkIPs returns addresses of the two router ports connecting
+// vpcs of the peering connection.  The first belongs to the requesting vpc,
+// the second to the peer vpc
+func VpcPeeringLinkIPs(peeringId string) (netutils.IPV4Addr, netutils.IPV4Addr) {
+	idx := crc32.ChecksumIEEE([]byte(peeringId)) % vpcPeeringLinkNum
+	netAddr := vpcPeeringCidr.Address + netutils.IPV4Addr(idx<<(32-VpcPeeringLinkMask))
+	return netAddr + 1, netAddr + 2
+}
+
 const (
 	sVpcMappedCidr      = "100.64.0.0/17"
 	VpcMappedIPMask     = 17
@@ -134,6 +155,8 @@ func init() {
 	vpcInterExtIP1 = mi(netutils.NewIPV4Addr(sVpcInterExtIP1))
 	vpcInterExtIP2 = mi(netutils.NewIPV4Addr(sVpcInterExtIP2))
 
+	vpcPeeringCidr = mp(netutils.NewIPV4Prefix(sVpcPeeringCidr))
+
 	vpcMappedCidr = mp(netutils.NewIPV4Prefix(sVpcMappedCidr))
 	vpcMappedGatewayIP = mi(netutils.NewIPV4Addr(sVpcMappedGatewayIP))
 
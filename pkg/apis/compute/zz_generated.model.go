@@ -129,8 +129,14 @@ type SBackupStorage struct {
 
 // SBackupStorageAccessInfo is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SBackupStorageAccessInfo.
 type SBackupStorageAccessInfo struct {
-	NfsHost      string `json:"nfs_host"`
-	NfsSharedDir string `json:"nfs_shared_dir"`
+	NfsHost         string `json:"nfs_host"`
+	NfsSharedDir    string `json:"nfs_shared_dir"`
+	LocalPath       string `json:"local_path,omitempty"`
+	ObjectEndpoint  string `json:"object_endpoint,omitempty"`
+	ObjectBucket    string `json:"object_bucket,omitempty"`
+	ObjectAccessKey string `json:"object_access_key,omitempty"`
+	// ObjectSecret is encrypted with the storage id
+	ObjectSecret string `json:"object_secret,omitempty"`
 }
 
 // SBaremetalagent is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SBaremetalagent.
@@ -651,6 +657,8 @@ type SDiskBackup struct {
 	DiskId          string `json:"disk_id"`
 	BackupStorageId string `json:"backup_storage_id"`
 	StorageId       string `json:"storage_id"`
+	// 增量备份所基于的备份, 全量备份为空
+	ParentBackupId string `json:"parent_backup_id"`
 	// 备份大小
 	SizeMb     int    `json:"size_mb"`
 	DiskSizeMb int    `json:"disk_size_mb"`
@@ -0,0 +1,72 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+type GuestQgaSetPasswordRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// password is the hash produced by crypt(3), linux only
+	Crypted bool `json:"crypted"`
+}
+
+type GuestQgaExecRequest struct {
+	Path string   `json:"path"`
+	Args []string `json:"args"`
+	// seconds to wait for the command exiting, default 30
+	Timeout int `json:"timeout"`
+}
+
+type GuestQgaExecResponse struct {
+	Exitcode int    `json:"exitcode"`
+	Signal   int    `json:"signal"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+type GuestQgaIpAddress struct {
+	// ipv4|ipv6
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	Prefix  int    `json:"prefix"`
+}
+
+type GuestQgaInterface struct {
+	Name        string              `json:"name"`
+	Mac         string              `json:"mac"`
+	IpAddresses []GuestQgaIpAddress `json:"ip_addresses"`
+}
+
+type GuestQgaFilesystem struct {
+	Name       string `json:"name"`
+	Mountpoint string `json:"mountpoint"`
+	Type       string `json:"type"`
+	UsedBytes  int64  `json:"used_bytes"`
+	TotalBytes int64  `json:"total_bytes"`
+}
+
+type GuestQgaOsInfo struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	PrettyName    string `json:"pretty_name"`
+	Version       string `json:"version"`
+	KernelRelease string `json:"kernel_release"`
+	Machine       string `json:"machine"`
+}
+
+type GuestQgaInfoResponse struct {
+	OsInfo      *GuestQgaOsInfo      `json:"os_info"`
+	Interfaces  []GuestQgaInterface  `json:"interfaces"`
+	Filesystems []GuestQgaFilesystem `json:"filesystems"`
+}
@@ -15,7 +15,14 @@
 package identity
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
 )
 
 const (
@@ -26,6 +33,9 @@ const (
 	RECOVERY_SECRETS_TYPE = "recovery_secret"
 	OIDC_CREDENTIAL_TYPE  = "oidc"
 	ENCRYPT_KEY_TYPE      = "enc_key"
+
+	WEBAUTHN_CREDENTIAL_TYPE = "webauthn"
+	RECOVERY_CODES_TYPE      = "recovery_code"
 )
 
 type SAccessKeySecretBlob struct {
@@ -40,6 +50,42 @@ func (info SAccessKeySecretBlob) IsValid() bool {
 	return false
 }
 
+// SWebAuthnCredentialBlob is the blob of a webauthn credential,
+// binary fields are base64url encoded
+type SWebAuthnCredentialBlob struct {
+	CredentialId string   `json:"credential_id"`
+	PublicKey    string   `json:"public_key"`
+	SignCount    uint32   `json:"sign_count"`
+	Aaguid       string   `json:"aaguid"`
+	Transports   []string `json:"transports"`
+	LastUsedAt   int64    `json:"last_used_at"`
+	Timestamp    int64    `json:"timestamp"`
+}
+
+func (blob SWebAuthnCredentialBlob) Validate() error {
+	if len(blob.CredentialId) == 0 {
+		return errors.Wrap(httperrors.ErrMissingParameter, "credential_id")
+	}
+	if len(blob.PublicKey) == 0 {
+		return errors.Wrap(httperrors.ErrMissingParameter, "public_key")
+	}
+	return nil
+}
+
+// SRecoveryCodesBlob keeps the sha256 hash of the unused one-time recovery codes
+type SRecoveryCodesBlob struct {
+	Codes     []string `json:"codes"`
+	Timestamp int64    `json:"timestamp"`
+}
+
+// HashRecoveryCode returns the hash of recovery code kept in SRecoveryCodesBlob,
+// the case and separators of the code are ignored
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
 type SAccessKeySecretInfo struct {
 	AccessKey string
 	SAccessKeySecretBlob
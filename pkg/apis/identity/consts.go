@@ -68,6 +68,7 @@ const (
 	IdentityDriverSAML   = "saml"
 	IdentityDriverOIDC   = "oidc"   // OpenID Connect
 	IdentityDriverOAuth2 = "oauth2" // OAuth2.0
+	IdentityDriverSCIM   = "scim"   // SCIM 2.0 provisioning
 
 	IdentityDriverStatusConnected    = "connected"
 	IdentityDriverStatusDisconnected = "disconnected"
@@ -77,6 +78,7 @@ const (
 	IdentityProviderSyncLocal  = "local"
 	IdentityProviderSyncFull   = "full"
 	IdentityProviderSyncOnAuth = "auth"
+	IdentityProviderSyncPush   = "push"
 
 	IdentitySyncStatusQueued  = "queued"
 	IdentitySyncStatusSyncing = "syncing"
@@ -97,6 +99,9 @@ var (
 		"ldap": []string{
 			"password",
 		},
+		"scim": []string{
+			"bearer_token",
+		},
 	}
 
 	CommonWhitelistOptionMap = map[string][]string{
@@ -31,6 +31,19 @@ type CredentialUpdateInput struct {
 
 	// enabled
 	Enabled *bool `json:"enabled"`
+
+	// 更新凭证内容，用于更新webauthn签名计数
+	Blob string `json:"blob"`
+}
+
+type CredentialUseRecoveryCodeInput struct {
+	// 恢复码，使用后即失效
+	Code string `json:"code"`
+}
+
+type CredentialUseRecoveryCodeOutput struct {
+	// 剩余的恢复码数量
+	Left int `json:"left"`
 }
 
 type CredentialCreateInput struct {
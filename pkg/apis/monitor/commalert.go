@@ -87,6 +87,8 @@ type CommonAlertQuery struct {
 	*AlertQuery
 	// metric points'value的运算方式
 	Reduce string `json:"reduce"`
+	// 运算方式的参数, 比如 predict_linear 预测的秒数
+	ReduceParams []float64 `json:"reduce_params"`
 	// 比较运算符, 比如: >, <, >=, <=
 	Comparator string `json:"comparator"`
 	// 报警阀值
@@ -157,6 +159,7 @@ type CommonAlertMetricDetails struct {
 	ThresholdStr  string    `json:"threshold_str"`
 	// metric points'value的运算方式
 	Reduce                 string           `json:"reduce"`
+	ReduceParams           []float64        `json:"reduce_params"`
 	DB                     string           `json:"db"`
 	Measurement            string           `json:"measurement"`
 	MeasurementDisplayName string           `json:"measurement_display_name"`
@@ -0,0 +1,81 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	REBALANCE_PLAN_STATUS_PENDING_APPROVAL = "pending_approval"
+	REBALANCE_PLAN_STATUS_EXECUTING        = "executing"
+	REBALANCE_PLAN_STATUS_COMPLETED        = "completed"
+	REBALANCE_PLAN_STATUS_FAILED           = "failed"
+	REBALANCE_PLAN_STATUS_REJECTED         = "rejected"
+	REBALANCE_PLAN_STATUS_EXPIRED          = "expired"
+
+	REBALANCE_STEP_STATUS_PENDING   = "pending"
+	REBALANCE_STEP_STATUS_MIGRATING = "migrating"
+	REBALANCE_STEP_STATUS_SUCCESS   = "success"
+	REBALANCE_STEP_STATUS_FAILED    = "failed"
+)
+
+// RebalancePlanStep is one live migration of a rebalance plan
+type RebalancePlanStep struct {
+	GuestId        string `json:"guest_id"`
+	GuestName      string `json:"guest_name"`
+	SourceHostId   string `json:"source_host_id"`
+	SourceHostName string `json:"source_host_name"`
+	TargetHostId   string `json:"target_host_id"`
+	TargetHostName string `json:"target_host_name"`
+	// Load is the guest load moved, in cpu cores or memory bytes
+	Load   float64 `json:"load"`
+	Status string  `json:"status"`
+	Error  string  `json:"error"`
+}
+
+type RebalancePlanListInput struct {
+	apis.StatusStandaloneResourceListInput
+
+	MetricType []string `json:"metric_type"`
+}
+
+type RebalancePlanDetails struct {
+	apis.StatusStandaloneResourceDetails
+
+	StepCount int `json:"step_count"`
+}
+
+type RebalancePlanCreateInput struct {
+	apis.StatusStandaloneResourceCreateInput
+
+	MetricType MigrationAlertMetricType `json:"metric_type"`
+	// AutoExecute run plan without approval
+	AutoExecute bool `json:"auto_execute"`
+	// MaxConcurrency is the max number of migrations running at the same time
+	MaxConcurrency  int                  `json:"max_concurrency"`
+	ImbalanceBefore float64              `json:"imbalance_before"`
+	ImbalanceAfter  float64              `json:"imbalance_after"`
+	Steps           []*RebalancePlanStep `json:"steps"`
+}
+
+type RebalancePlanApproveInput struct {
+	// MaxConcurrency overrides the concurrency of the plan
+	MaxConcurrency int `json:"max_concurrency"`
+}
+
+type RebalancePlanRejectInput struct {
+	Reason string `json:"reason"`
+}
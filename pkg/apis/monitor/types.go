@@ -15,7 +15,8 @@
 package monitor
 
 const (
-	DataSourceTypeInfluxdb = "influxdb"
+	DataSourceTypeInfluxdb   = "influxdb"
+	DataSourceTypePrometheus = "prometheus"
 )
 
 type DataSourceConfig struct {
@@ -58,6 +58,12 @@ var (
 		"median":       "median",
 		"diff":         "The difference between the latest value and the oldest value. The judgment basis value must be legal",
 		"percent_diff": "The difference between the new value and the old value,based on the percentage of the old value",
+		// trend-aware reducers, reduce_params are documented along the reducer
+		"stddev_deviation": "How many standard deviations the latest value is away from the mean of the values before it, reduce_params: [window points]",
+		"rate":             "The per-second increase of a counter",
+		"derivative":       "The per-second slope of the linear regression of the values",
+		"percent_change":   "The signed change of the latest value in percent of the oldest value of the window, reduce_params: [window seconds]",
+		"predict_linear":   "The value predicted by linear regression some seconds later, reduce_params: [seconds], default 3600",
 	}
 )
 
@@ -29,6 +29,9 @@ const (
 	FEISHU_ROBOT   = "feishu-robot"
 	DINGTALK_ROBOT = "dingtalk-robot"
 	WORKWX_ROBOT   = "workwx-robot"
+	SLACK_ROBOT    = "slack-robot"
+	TEAMS_ROBOT    = "teams-robot"
+	TELEGRAM_ROBOT = "telegram-robot"
 	WEBHOOK        = "webhook"
 
 	ROBOT = "robot"
@@ -77,6 +80,12 @@ const (
 	ROBOT_TYPE_DINGTALK = "dingtalk"
 	ROBOT_TYPE_WORKWX   = "workwx"
 	ROBOT_TYPE_WEBHOOK  = "webhook"
+	// ROBOT_TYPE_SLACK address: incoming webhook url or '<bot token>/<channel>'
+	ROBOT_TYPE_SLACK = "slack"
+	// ROBOT_TYPE_TEAMS address: incoming webhook url of the connector
+	ROBOT_TYPE_TEAMS = "teams"
+	// ROBOT_TYPE_TELEGRAM address: '<bot token>/<chat id>'
+	ROBOT_TYPE_TELEGRAM = "telegram"
 
 	ROBOT_STATUS_READY = "ready"
 
@@ -126,6 +135,8 @@ const (
 	TOPIC_RESOURCE_CLOUDPODS_COMPONENT      = "cloudpods_component"
 	TOPIC_RESOURCE_USER                     = "user"
 	TOPIC_RESOURCE_ACTION_LOG               = "action_log"
+	TOPIC_RESOURCE_QUOTA                    = "quota"
+	TOPIC_RESOURCE_QUOTA_CHANGE_REQUEST     = "quota_change_request"
 
 	SUBSCRIBER_TYPE_ROLE     = "role"
 	SUBSCRIBER_TYPE_ROBOT    = "robot"
@@ -54,6 +54,10 @@ var (
 
 	ActionExceedCount SAction = "exceed_count"
 
+	ActionExceedThreshold SAction = "exceed_threshold"
+	ActionApprove         SAction = "approve"
+	ActionReject          SAction = "reject"
+
 	ResultFailed  SResult = "failed"
 	ResultSucceed SResult = "succeed"
 )
@@ -19,10 +19,10 @@ import "yunion.io/x/onecloud/pkg/apis"
 type RobotCreateInput struct {
 	apis.SharableVirtualResourceCreateInput
 	// description: robot type
-	// enum: feishu,dingtalk,workwx,webhook
+	// enum: feishu,dingtalk,workwx,webhook,slack,teams,telegram
 	// example: webhook
 	Type string `json:"type"`
-	// description: address
+	// description: address, '<bot token>/<channel>' is also supported for slack and '<bot token>/<chat id>' for telegram
 	// example: http://helloworld.io/test/webhook
 	Address string `json:"address"`
 	// description: Language preference
@@ -38,7 +38,7 @@ type RobotListInput struct {
 	apis.SharableVirtualResourceListInput
 	apis.EnabledResourceBaseListInput
 	// description: robot type
-	// enum: feishu,dingtalk,workwx,webhook
+	// enum: feishu,dingtalk,workwx,webhook,slack,teams,telegram
 	// example: webhook
 	Type string `json:"type"`
 	// description: Language preference
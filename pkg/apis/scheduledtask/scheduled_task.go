@@ -51,6 +51,14 @@ type CycleTimerDetails struct {
 	WeekDays []int `json:"week_days"`
 	// description: 每月的几天
 	MonthDays []int `json:"month_days"`
+	// description: cron表达式
+	CronExpr string `json:"cron_expr"`
+	// description: cron表达式的时区
+	TimeZone string `json:"time_zone"`
+	// description: 随机延迟的最大秒数
+	JitterSeconds int `json:"jitter_seconds"`
+	// description: 错过触发时间后的策略
+	MissedRunPolicy string `json:"missed_run_policy"`
 	// description: 此周期任务的开始时间
 	StartTime time.Time `json:"start_time"`
 	// description: 此周期任务的截止时间
@@ -68,7 +76,7 @@ type ScheduledTaskListInput struct {
 
 	// description: resource type
 	// example: server
-	// enum: server
+	// enum: server,cloudaccount,disk,scalinggroup,dbinstance,elasticcache
 	ResourceType string `json:"resource_type"`
 
 	// description: label type
@@ -81,7 +89,7 @@ type ScheduledTaskListInput struct {
 
 	// description: operation
 	// example: stop
-	// enum: start,stop,restart
+	// enum: start,stop,restart,sync,instance-snapshot,change-config,snapshot,backup,set-desired-capacity
 	Operation string `json:"operation"`
 }
 
@@ -97,13 +105,16 @@ type ScheduledTaskCreateInput struct {
 	CycleTimer    CycleTimerCreateInput `json:"cycle_timer"`
 
 	// description: resource type
-	// enum: server
+	// enum: server,cloudaccount,disk,scalinggroup,dbinstance,elasticcache
 	// example: server
 	ResourceType string `json:"resource_type"`
-	// description: operation
-	// enum: start,stop,restart
+	// description: operation, server: start,stop,restart,instance-snapshot,change-config; cloudaccount: sync;
+	// disk: snapshot,backup; scalinggroup: set-desired-capacity; dbinstance,elasticcache: start,stop
+	// enum: start,stop,restart,sync,instance-snapshot,change-config,snapshot,backup,set-desired-capacity
 	// example: stop
 	Operation string `json:"operation"`
+	// description: 操作参数
+	OperationParams ScheduledTaskOperationParams `json:"operation_params"`
 	// description: label type
 	// enum: tag,id
 	// example: id
@@ -113,6 +124,32 @@ type ScheduledTaskCreateInput struct {
 	Labels []string
 }
 
+type ScheduledTaskOperationParams struct {
+	// description: 快照或备份名称前缀, 用于instance-snapshot, snapshot和backup操作
+	// example: daily
+	NamePrefix string `json:"name_prefix"`
+	// description: 主机快照是否保存内存, 用于instance-snapshot操作
+	WithMemory bool `json:"with_memory"`
+
+	// description: 实例类型, 用于change-config操作, 优先级高于vcpu_count和vmem_size
+	InstanceType string `json:"instance_type"`
+	// description: cpu大小, 用于change-config操作
+	VcpuCount int `json:"vcpu_count"`
+	// description: 内存大小, 用于change-config操作
+	// example: 4G
+	VmemSize string `json:"vmem_size"`
+	// description: 调整完配置后是否自动启动, 用于change-config操作
+	AutoStart bool `json:"auto_start"`
+
+	// description: 期望实例数, 用于set-desired-capacity操作
+	DesireInstanceNumber int `json:"desire_instance_number"`
+
+	// description: 备份存储Id, 用于backup操作
+	BackupStorageId string `json:"backup_storage_id"`
+	// description: 是否增量备份, 用于backup操作
+	Incremental bool `json:"incremental"`
+}
+
 type TimerCreateInput struct {
 
 	// description: 执行时间
@@ -122,7 +159,7 @@ type TimerCreateInput struct {
 type CycleTimerCreateInput struct {
 
 	// description: 周期类型
-	// enum: day,week,month
+	// enum: day,week,month,cron
 	CycleType string `json:"cycle_type"`
 
 	// description: 分(0-59)
@@ -141,6 +178,23 @@ type CycleTimerCreateInput struct {
 	// example: [1,4,31]
 	MonthDays []int `json:"month_days"`
 
+	// description: cron表达式, 5或6个字段, 仅周期类型为cron时有效, 触发间隔不能小于1分钟
+	// example: 0 2 * * mon-fri
+	CronExpr string `json:"cron_expr"`
+
+	// description: cron表达式的时区, 默认为服务所在时区
+	// example: Asia/Shanghai
+	TimeZone string `json:"time_zone"`
+
+	// description: 每次触发随机延迟的最大秒数
+	// example: 60
+	JitterSeconds int `json:"jitter_seconds"`
+
+	// description: 错过触发时间后的策略
+	// enum: skip,catch_up_once
+	// example: skip
+	MissedRunPolicy string `json:"missed_run_policy"`
+
 	// description: 开始时间
 	StartTime time.Time `json:"start_time"`
 
@@ -169,6 +223,14 @@ type ScheduledTaskActivityDetails struct {
 	SScheduledTaskActivity
 }
 
+// ScheduledTaskActivityResult is the result of the operation on one resource
+type ScheduledTaskActivityResult struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Succeed bool   `json:"succeed"`
+	Reason  string `json:"reason"`
+}
+
 type ScheduledTaskActivityListInput struct {
 	apis.StatusStandaloneResourceListInput
 	// description: 定时任务 ID or Name
@@ -20,14 +20,24 @@ const (
 
 	ST_STATUS_READY         = "ready"
 	ST_STATUS_CREATE_FAILED = "create_failed"
+	ST_STATUS_EXECUTING     = "executing"
 
 	ST_RESOURCE_SERVER       = "server"
 	ST_RESOURCE_CLOUDACCOUNT = "cloudaccount"
-
-	ST_RESOURCE_OPERATION_START   = "start"
-	ST_RESOURCE_OPERATION_STOP    = "stop"
-	ST_RESOURCE_OPERATION_RESTART = "restart"
-	ST_RESOURCE_OPERATION_SYNC    = "sync"
+	ST_RESOURCE_DISK         = "disk"
+	ST_RESOURCE_SCALINGGROUP = "scalinggroup"
+	ST_RESOURCE_DBINSTANCE   = "dbinstance"
+	ST_RESOURCE_ELASTICCACHE = "elasticcache"
+
+	ST_RESOURCE_OPERATION_START                = "start"
+	ST_RESOURCE_OPERATION_STOP                 = "stop"
+	ST_RESOURCE_OPERATION_RESTART              = "restart"
+	ST_RESOURCE_OPERATION_SYNC                 = "sync"
+	ST_RESOURCE_OPERATION_INSTANCE_SNAPSHOT    = "instance-snapshot"
+	ST_RESOURCE_OPERATION_CHANGE_CONFIG        = "change-config"
+	ST_RESOURCE_OPERATION_SNAPSHOT             = "snapshot"
+	ST_RESOURCE_OPERATION_BACKUP               = "backup"
+	ST_RESOURCE_OPERATION_SET_DESIRED_CAPACITY = "set-desired-capacity"
 
 	ST_LABEL_ID  = "id"
 	ST_LABEL_TAG = "tag"
@@ -42,4 +52,10 @@ const (
 	TIMER_TYPE_DAY   = "day"
 	TIMER_TYPE_WEEK  = "week"
 	TIMER_TYPE_MONTH = "month"
+	TIMER_TYPE_CRON  = "cron"
+
+	// skip the missed bells, run at the next bell
+	TIMER_MISSED_RUN_SKIP = "skip"
+	// run once for all the missed bells
+	TIMER_MISSED_RUN_CATCH_UP_ONCE = "catch_up_once"
 )
@@ -17,6 +17,8 @@ package scheduledtask
 import (
 	time "time"
 
+	"yunion.io/x/jsonutils"
+
 	"yunion.io/x/onecloud/pkg/apis"
 )
 
@@ -28,7 +30,9 @@ type SScheduledTask struct {
 	STimer
 	ResourceType string `json:"resource_type"`
 	Operation    string `json:"operation"`
-	LabelType    string `json:"label_type"`
+	// OperationParams is the marshaled ScheduledTaskOperationParams
+	OperationParams jsonutils.JSONObject `json:"operation_params"`
+	LabelType       string               `json:"label_type"`
 }
 
 // SScheduledTaskActivity is an autogenerated struct via yunion.io/x/onecloud/pkg/scheduledtask/models.SScheduledTaskActivity.
@@ -38,6 +42,8 @@ type SScheduledTaskActivity struct {
 	StartTime       time.Time `json:"start_time"`
 	EndTime         time.Time `json:"end_time"`
 	Reason          string    `json:"reason"`
+	// Results is the marshaled []ScheduledTaskActivityResult
+	Results jsonutils.JSONObject `json:"results"`
 }
 
 // SScheduledTaskLabel is an autogenerated struct via yunion.io/x/onecloud/pkg/scheduledtask/models.SScheduledTaskLabel.
@@ -59,5 +65,13 @@ type STimer struct {
 	WeekDays byte `json:"week_days"`
 	// 0-31 0 is unlimited
 	MonthDays uint32 `json:"month_days"`
-	IsExpired bool   `json:"is_expired"`
+	// CronExpr is the cron expression of cron type timer
+	CronExpr string `json:"cron_expr"`
+	// TimeZone the cron expression is evaluated in
+	TimeZone string `json:"time_zone"`
+	// JitterSeconds delays every bell by a random duration less than it
+	JitterSeconds int `json:"jitter_seconds"`
+	// MissedRunPolicy decides whether to run once for the missed bells
+	MissedRunPolicy string `json:"missed_run_policy"`
+	IsExpired       bool   `json:"is_expired"`
 }
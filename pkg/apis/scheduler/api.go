@@ -135,6 +135,9 @@ type CandidateResource struct {
 	Name      string           `json:"name"`
 	Disks     []*CandidateDisk `json:"disks"`
 	Nets      []*CandidateNet  `json:"nets"`
+	// NUMA node of the host the guest is bound to, set when numa_strict
+	// is requested
+	NumaNode *int `json:"numa_node,omitempty"`
 
 	// used by backup schedule
 	BackupCandidate *CandidateResource `json:"backup_candidate"`
@@ -0,0 +1,79 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webconsole
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	SESSION_RECORDING_STATUS_RECORDING = "recording"
+	SESSION_RECORDING_STATUS_READY     = "ready"
+	SESSION_RECORDING_STATUS_FAILED    = "failed"
+
+	SESSION_RECORDING_STORAGE_LOCAL  = "local"
+	SESSION_RECORDING_STORAGE_OBJECT = "object"
+
+	SESSION_TYPE_SSH  = "ssh"
+	SESSION_TYPE_KUBE = "kube"
+	SESSION_TYPE_IPMI = "ipmi"
+
+	// SESSION_RECORDING_CONTENT_TYPE is the media type of asciicast v2 files
+	SESSION_RECORDING_CONTENT_TYPE = "application/x-asciicast"
+)
+
+type SessionRecordingListInput struct {
+	apis.UserResourceListInput
+	apis.StatusResourceBaseListInput
+
+	// 以会话Id过滤
+	SessionId []string `json:"session_id"`
+	// 以被访问资源Id过滤
+	ObjId []string `json:"obj_id"`
+	// 以被访问资源类型过滤, 例如: server, host, pod
+	ObjType []string `json:"obj_type"`
+	// 以会话类型过滤
+	// enum: ssh, kube, ipmi
+	SessionType []string `json:"session_type"`
+}
+
+type SessionRecordingDetails struct {
+	apis.UserResourceDetails
+
+	// 录像时长(秒)
+	Duration float64 `json:"duration"`
+}
+
+type SessionRecordingCastInput struct {
+	// 跳过的事件数
+	Offset int `json:"offset"`
+	// 返回的事件数, 默认1000, 最大10000
+	Limit int `json:"limit"`
+}
+
+// SessionRecordingCastOutput is a page of the events of a recording, the
+// whole asciicast file is served by GET /webconsole/session_recordings/<id>/download
+type SessionRecordingCastOutput struct {
+	// asciicast v2 头部
+	Header string `json:"header"`
+	// asciicast v2 事件, 每个为一行json
+	Events []string `json:"events"`
+	// 下一页的offset, 没有更多事件时为-1
+	NextOffset int `json:"next_offset"`
+	// 录像开始时间
+	StartTime time.Time `json:"start_time"`
+}
@@ -42,6 +42,7 @@ import (
 	"yunion.io/x/onecloud/pkg/proxy"
 	"yunion.io/x/onecloud/pkg/util/ctx"
 	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/tracing"
 )
 
 type Application struct {
@@ -287,10 +288,12 @@ func (t *appTask) Run() {
 		}
 		t.ctx = context.WithValue(t.ctx, APP_CONTEXT_KEY_APP_PARAMS, t.appParams)
 		func() {
+			tracing.InjectServerRequestHeader(t.r.Header)
 			span := trace.StartServerTrace(&t.fw, t.r, t.appParams.Name, t.app.GetName(), t.hand.GetTags())
 			defer func() {
 				if !t.appParams.SkipTrace {
 					span.EndTrace()
+					tracing.SubmitTrace(span)
 				}
 			}()
 			t.ctx = context.WithValue(t.ctx, appctx.APP_CONTEXT_KEY_TRACE, span)
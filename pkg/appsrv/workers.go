@@ -307,11 +307,17 @@ func (wm *SWorkerManager) getState() SWorkerManagerStates {
 	return state
 }
 
-func WorkerStatsHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+// GetWorkerManagerStates returns states of all worker managers of the process
+func GetWorkerManagerStates() []SWorkerManagerStates {
 	stats := make([]SWorkerManagerStates, 0)
 	for i := 0; i < len(workerManagers); i += 1 {
 		stats = append(stats, workerManagers[i].getState())
 	}
+	return stats
+}
+
+func WorkerStatsHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	stats := GetWorkerManagerStates()
 	result := jsonutils.NewDict()
 	result.Add(jsonutils.Marshal(&stats), "workers")
 	fmt.Fprintf(w, result.String())
@@ -24,6 +24,7 @@ import (
 	"yunion.io/x/onecloud/pkg/appsrv"
 	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
 	"yunion.io/x/onecloud/pkg/util/seclib2"
+	"yunion.io/x/onecloud/pkg/util/tracing"
 )
 
 func InitApp(options *common_options.BaseOptions, dbAccess bool) *appsrv.Application {
@@ -31,6 +32,7 @@ func InitApp(options *common_options.BaseOptions, dbAccess bool) *appsrv.Applica
 	log.Infof("RequestWorkerCount: %d", options.RequestWorkerCount)
 	app := appsrv.NewApplication(options.ApplicationID, options.RequestWorkerCount, dbAccess)
 	app.CORSAllowHosts(options.CorsHosts)
+	tracing.Init(options.TracingExporter, options.OtlpTracesEndpoint)
 
 	// app.SetContext(appsrv.APP_CONTEXT_KEY_CACHE, cache)
 	// if dbConn != nil {
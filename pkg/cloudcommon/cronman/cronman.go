@@ -18,8 +18,10 @@ import (
 	"container/heap"
 	"context"
 	"fmt"
+	"math/rand"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"yunion.io/x/log"
@@ -30,6 +32,19 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/elect"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/cronexpr"
+)
+
+const (
+	// MISSED_RUN_SKIP drops the runs missed while the manager was stopped or
+	// blocked, the job runs at its next scheduled time
+	MISSED_RUN_SKIP = "skip"
+	// MISSED_RUN_CATCH_UP_ONCE runs the job once for all the missed runs
+	MISSED_RUN_CATCH_UP_ONCE = "catch_up_once"
+
+	// a run is considered missed if it is checked later than this after its
+	// scheduled time
+	missedRunThreshold = time.Minute
 )
 
 var (
@@ -71,12 +86,48 @@ func (t *TimerHour) Next(now time.Time) time.Time {
 	return time.Date(next.Year(), next.Month(), next.Day(), next.Hour(), t.min, t.sec, 0, next.Location())
 }
 
+// TimerCron bells at the times matched by a cron expression, delayed by a
+// random duration less than jitter
+type TimerCron struct {
+	schedule *cronexpr.SSchedule
+	jitter   time.Duration
+}
+
+func (t *TimerCron) Next(now time.Time) time.Time {
+	next := t.schedule.Next(now)
+	if next.IsZero() || t.jitter <= 0 {
+		return next
+	}
+	return next.Add(time.Duration(rand.Int63n(int64(t.jitter))))
+}
+
+type SCronJobOptions struct {
+	// TimeZone the expression is evaluated in if it has no CRON_TZ prefix,
+	// default is the local time zone
+	TimeZone string
+	// Jitter delays every run by a random duration less than it
+	Jitter time.Duration
+	// MissedRunPolicy is MISSED_RUN_SKIP or MISSED_RUN_CATCH_UP_ONCE, default
+	// is MISSED_RUN_SKIP
+	MissedRunPolicy string
+	// AllowOverlap allows a run to start while the previous one is running
+	AllowOverlap bool
+	StartRun     bool
+}
+
 type SCronJob struct {
 	Name     string
 	job      TCronJobFunction
 	Timer    ICronTimer
 	Next     time.Time
 	StartRun bool
+
+	// MissedRunPolicy is empty for interval jobs, which keep running once
+	// however late they are checked
+	MissedRunPolicy string
+	PreventOverlap  bool
+
+	running int32
 }
 
 type CronJobTimerHeap []*SCronJob
@@ -264,6 +315,55 @@ func (self *SCronJobManager) AddJobEveryFewHour(name string, hour, min, sec int,
 	return nil
 }
 
+// AddJobByCronExpr adds a job running at the times matched by a 5 or 6 field
+// cron expression
+func (self *SCronJobManager) AddJobByCronExpr(name, expr string, jobFunc TCronJobFunction, opts SCronJobOptions) error {
+	loc, err := cronexpr.LoadLocation(opts.TimeZone)
+	if err != nil {
+		return errors.Wrapf(err, "AddJobByCronExpr: invalid time zone %s", opts.TimeZone)
+	}
+	schedule, err := cronexpr.ParseInLocation(expr, loc)
+	if err != nil {
+		return errors.Wrap(err, "AddJobByCronExpr")
+	}
+	switch opts.MissedRunPolicy {
+	case "":
+		opts.MissedRunPolicy = MISSED_RUN_SKIP
+	case MISSED_RUN_SKIP, MISSED_RUN_CATCH_UP_ONCE:
+	default:
+		return errors.Errorf("AddJobByCronExpr: unknown missed run policy %s", opts.MissedRunPolicy)
+	}
+	if opts.Jitter < 0 {
+		return errors.Error("AddJobByCronExpr: jitter must >= 0")
+	}
+
+	self.dataLock.Lock()
+	defer self.dataLock.Unlock()
+
+	if !self.IsNameUnique(name) {
+		return ErrCronJobNameConflict
+	}
+
+	t := TimerCron{
+		schedule: schedule,
+		jitter:   opts.Jitter,
+	}
+	job := SCronJob{
+		Name:            name,
+		job:             jobFunc,
+		Timer:           &t,
+		StartRun:        opts.StartRun,
+		MissedRunPolicy: opts.MissedRunPolicy,
+		PreventOverlap:  !opts.AllowOverlap,
+	}
+	if !self.running {
+		self.jobs = append(self.jobs, &job)
+	} else {
+		self.addJob(&job)
+	}
+	return nil
+}
+
 func (self *SCronJobManager) addJob(newJob *SCronJob) {
 	now := time.Now()
 	newJob.Next = newJob.Timer.Next(now)
@@ -294,7 +394,14 @@ func (self *SCronJobManager) Remove(name string) error {
 
 func (self *SCronJobManager) next(now time.Time) {
 	for _, job := range self.jobs {
+		// a job keeps its Next while the manager is stopped, e.g. on losing
+		// the election, a Next in the past means runs were missed
+		missed := !job.Next.IsZero() && now.Sub(job.Next) > missedRunThreshold
 		job.Next = job.Timer.Next(now)
+		if missed && !job.StartRun && job.MissedRunPolicy == MISSED_RUN_CATCH_UP_ONCE {
+			log.Infof("Cron job %s missed runs, catch up once", job.Name)
+			job.runJob(false)
+		}
 	}
 }
 
@@ -369,7 +476,11 @@ func (self *SCronJobManager) runJobs(now time.Time) {
 	defer self.dataLock.Unlock()
 	for i := 0; i < len(self.jobs); i++ {
 		if !(self.jobs[i].Next.After(now) || self.jobs[i].Next.IsZero()) {
-			self.jobs[i].runJob(false)
+			if self.jobs[i].MissedRunPolicy == MISSED_RUN_SKIP && now.Sub(self.jobs[i].Next) > missedRunThreshold {
+				log.Warningf("Cron job %s missed run at %s, skip", self.jobs[i].Name, self.jobs[i].Next)
+			} else {
+				self.jobs[i].runJob(false)
+			}
 			self.jobs[i].Next = self.jobs[i].Timer.Next(now)
 			heap.Fix(&self.jobs, i)
 		}
@@ -385,11 +496,18 @@ func (job *SCronJob) Dump() string {
 }
 
 func (job *SCronJob) runJob(isStart bool) {
+	if job.PreventOverlap && !atomic.CompareAndSwapInt32(&job.running, 0, 1) {
+		log.Warningf("Cron job %s is still running, skip", job.Name)
+		return
+	}
 	job.StartRun = isStart
 	manager.workers.Run(job, nil, nil)
 }
 
 func (job *SCronJob) runJobInWorker(isStart bool) {
+	if job.PreventOverlap {
+		defer atomic.StoreInt32(&job.running, 0)
+	}
 	defer func() {
 		if r := recover(); r != nil {
 			log.Errorf("CronJob task %s run error: %s", job.Name, r)
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/cronexpr"
 )
 
 func TestSCronJobManager_AddRemoveJobs(t *testing.T) {
@@ -40,3 +41,42 @@ func TestSCronJobManager_AddRemoveJobs(t *testing.T) {
 	manager.AddJobEveryFewDays("Test7", 1, 1, 1, 1, testFunc, false)
 	t.Logf("Jobs \n%s", manager.String())
 }
+
+func TestSCronJobManager_AddJobByCronExpr(t *testing.T) {
+	manager := InitCronJobManager(false, 4)
+	testFunc := func(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {}
+	cases := []struct {
+		name    string
+		expr    string
+		opts    SCronJobOptions
+		wantErr bool
+	}{
+		{"Cron1", "*/5 * * * *", SCronJobOptions{}, false},
+		{"Cron2", "0 0 2 * * *", SCronJobOptions{TimeZone: "Asia/Shanghai", Jitter: time.Minute}, false},
+		{"Cron3", "0 2 * *", SCronJobOptions{}, true},
+		{"Cron4", "0 2 * * *", SCronJobOptions{TimeZone: "Nowhere/City"}, true},
+		{"Cron5", "0 2 * * *", SCronJobOptions{MissedRunPolicy: "always"}, true},
+		{"Cron1", "0 2 * * *", SCronJobOptions{}, true},
+	}
+	for _, c := range cases {
+		err := manager.AddJobByCronExpr(c.name, c.expr, testFunc, c.opts)
+		if (err != nil) != c.wantErr {
+			t.Errorf("AddJobByCronExpr(%s, %q) wantErr %v got %v", c.name, c.expr, c.wantErr, err)
+		}
+	}
+	manager.Remove("Cron1")
+	manager.Remove("Cron2")
+}
+
+func TestTimerCron_Next(t *testing.T) {
+	schedule, _ := cronexpr.Parse("0 * * * *")
+	timer := TimerCron{schedule: schedule, jitter: 10 * time.Minute}
+	now := time.Date(2022, 1, 1, 0, 30, 0, 0, time.UTC)
+	want := time.Date(2022, 1, 1, 1, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		next := timer.Next(now)
+		if next.Before(want) || !next.Before(want.Add(10*time.Minute)) {
+			t.Errorf("next %s out of jitter window", next)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quotas
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	napi "yunion.io/x/onecloud/pkg/apis/notify"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// SQuotaAlertManager keeps the warning threshold of the quotas, a notify
+// event is emitted when the usage of a quota item crosses the threshold
+type SQuotaAlertManager struct {
+	db.SResourceBaseManager
+}
+
+var (
+	QuotaAlertManager *SQuotaAlertManager
+
+	quotaAlertWorker = appsrv.NewWorkerManager("quotaAlertWorker", 1, 1024, true)
+
+	// quota extensions are enabled in the services serving the quota APIs,
+	// which register the tables of alerts, usage histories and change requests
+	quotaExtensionsEnabled = false
+)
+
+func init() {
+	QuotaAlertManager = &SQuotaAlertManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SQuotaAlert{},
+			"quota_alert_tbl",
+			"quota_alert",
+			"quota_alerts",
+		),
+	}
+	QuotaAlertManager.SetVirtualObject(QuotaAlertManager)
+}
+
+type SQuotaAlert struct {
+	db.SResourceBase
+
+	// keyword of the quota manager, e.g. quota, region_quota
+	QuotaType string `width:"32" charset:"ascii" nullable:"false" primary:"true" list:"user" json:"quota_type"`
+	// string representation of the quota keys
+	QuotaKey string `width:"512" charset:"ascii" nullable:"false" primary:"true" list:"user" json:"quota_key"`
+
+	DomainId  string `width:"64" charset:"ascii" nullable:"false" index:"true" list:"user" json:"domain_id"`
+	ProjectId string `name:"tenant_id" width:"64" charset:"ascii" nullable:"false" index:"true" list:"user" json:"project_id"`
+
+	// 告警阈值，使用量达到配额的百分比，0表示不告警
+	WarningPercent int `nullable:"false" default:"0" list:"user" json:"warning_percent"`
+	// 已告警的配额项，避免重复告警
+	AlertedItems string `width:"512" charset:"ascii" nullable:"false" list:"user" json:"alerted_items"`
+}
+
+type sQuotaAlertItem struct {
+	Name    string `json:"name"`
+	Limit   int    `json:"limit"`
+	Usage   int    `json:"usage"`
+	Percent int    `json:"percent"`
+}
+
+func (manager *SQuotaAlertManager) fetchAlert(quotaType string, keys IQuotaKeys) (*SQuotaAlert, error) {
+	alert := &SQuotaAlert{}
+	alert.SetModelManager(manager, alert)
+	err := manager.Query().Equals("quota_type", quotaType).Equals("quota_key", QuotaKeyString(keys)).First(alert)
+	if err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+func (manager *SQuotaAlertManager) setWarningPercent(ctx context.Context, quotaType string, keys IQuotaKeys, percent int) error {
+	if percent < 0 || percent > 100 {
+		return httperrors.NewOutOfRangeError("warning_percent %d out of range [0, 100]", percent)
+	}
+	ownerId := keys.OwnerId()
+	alert := &SQuotaAlert{
+		QuotaType:      quotaType,
+		QuotaKey:       QuotaKeyString(keys),
+		DomainId:       ownerId.GetProjectDomainId(),
+		ProjectId:      ownerId.GetProjectId(),
+		WarningPercent: percent,
+	}
+	alert.SetModelManager(manager, alert)
+	return manager.TableSpec().InsertOrUpdate(ctx, alert)
+}
+
+func (manager *SQuotaAlertManager) getWarningPercent(quotaType string, keys IQuotaKeys) int {
+	if !quotaExtensionsEnabled {
+		return 0
+	}
+	alert, err := manager.fetchAlert(quotaType, keys)
+	if err != nil {
+		return 0
+	}
+	return alert.WarningPercent
+}
+
+func (manager *SQuotaAlertManager) deleteAlerts(ctx context.Context, quotaType string, keys IQuotaKeys) error {
+	alert, err := manager.fetchAlert(quotaType, keys)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil
+		}
+		return errors.Wrap(err, "fetchAlert")
+	}
+	_, err = db.Update(alert, func() error {
+		return alert.MarkDelete()
+	})
+	return err
+}
+
+// exceededItems returns the items whose usage reaches percent of the limit,
+// the items of unlimited or zero quota are ignored
+func exceededItems(quota, usage IQuota, percent int) []sQuotaAlertItem {
+	ret := make([]sQuotaAlertItem, 0)
+	if percent <= 0 {
+		return ret
+	}
+	limits, _ := quota.ToJSON("").GetMap()
+	usages, _ := usage.ToJSON("").GetMap()
+	for name, v := range limits {
+		limit, err := v.Int()
+		if err != nil || limit <= 0 {
+			continue
+		}
+		used := int64(0)
+		if u, ok := usages[name]; ok {
+			used, _ = u.Int()
+		}
+		if used*100 >= limit*int64(percent) {
+			ret = append(ret, sQuotaAlertItem{
+				Name:    name,
+				Limit:   int(limit),
+				Usage:   int(used),
+				Percent: int(used * 100 / limit),
+			})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+func (manager *SQuotaBaseManager) checkQuotaAlert(ctx context.Context, quota IQuota) error {
+	keys := quota.GetKeys()
+	alert, err := QuotaAlertManager.fetchAlert(manager.Keyword(), keys)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil
+		}
+		return errors.Wrap(err, "fetchAlert")
+	}
+	if alert.WarningPercent <= 0 && len(alert.AlertedItems) == 0 {
+		return nil
+	}
+	usage := manager.newQuota()
+	err = manager.usageStore.GetQuota(ctx, keys, usage)
+	if err != nil {
+		return errors.Wrap(err, "usageStore.GetQuota")
+	}
+	items := exceededItems(quota, usage, alert.WarningPercent)
+	alerted := make([]string, 0)
+	if len(alert.AlertedItems) > 0 {
+		alerted = strings.Split(alert.AlertedItems, ",")
+	}
+	names := make([]string, 0, len(items))
+	newItems := make([]sQuotaAlertItem, 0)
+	for i := range items {
+		names = append(names, items[i].Name)
+		if !utils.IsInStringArray(items[i].Name, alerted) {
+			newItems = append(newItems, items[i])
+		}
+	}
+	alertedItems := strings.Join(names, ",")
+	if alertedItems != alert.AlertedItems {
+		_, err = db.Update(alert, func() error {
+			alert.AlertedItems = alertedItems
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "update alerted items")
+		}
+	}
+	if len(newItems) > 0 {
+		manager.notifyQuotaAlert(ctx, quota, alert.WarningPercent, newItems)
+	}
+	return nil
+}
+
+func (manager *SQuotaBaseManager) notifyQuotaAlert(ctx context.Context, quota IQuota, percent int, items []sQuotaAlertItem) {
+	keys := quota.GetKeys()
+	details := jsonutils.NewDict()
+	details.Update(jsonutils.Marshal(keys))
+	if names, err := manager.fetchKeyNames(ctx, keys); err == nil {
+		details.Update(names)
+	}
+	details.Set("quota_type", jsonutils.NewString(manager.Keyword()))
+	details.Set("quota_key", jsonutils.NewString(QuotaKeyString(keys)))
+	details.Set("warning_percent", jsonutils.NewInt(int64(percent)))
+	details.Set("items", jsonutils.Marshal(items))
+	log.Infof("quota %s %s exceeds warning threshold %d%%: %s", manager.Keyword(), QuotaKeyString(keys), percent, jsonutils.Marshal(items))
+	notifyclient.OwnerEventNotify(ctx, napi.ActionExceedThreshold, napi.TOPIC_RESOURCE_QUOTA, keys.OwnerId(), nil, details)
+}
+
+// fetchKeyNames returns the names of the ids in the keys, e.g. domain, project
+func (manager *SQuotaBaseManager) fetchKeyNames(ctx context.Context, keys IQuotaKeys) (*jsonutils.JSONDict, error) {
+	idNameMap, err := manager.keyList2IdNameMap(ctx, []IQuotaKeys{keys})
+	if err != nil {
+		return nil, err
+	}
+	ret := jsonutils.NewDict()
+	fields := keys.Fields()
+	values := keys.Values()
+	for i := range fields {
+		if strings.HasSuffix(fields[i], "_id") && len(values[i]) > 0 {
+			name := idNameMap[fields[i]][values[i]]
+			if fields[i] == "tenant_id" {
+				ret.Set("project", jsonutils.NewString(name))
+			} else {
+				ret.Set(fields[i][:len(fields[i])-3], jsonutils.NewString(name))
+			}
+		}
+	}
+	return ret, nil
+}
+
+type quotaAlertTask struct {
+	manager *SQuotaBaseManager
+	keys    IQuotaKeys
+}
+
+func (t *quotaAlertTask) Run() {
+	ctx := context.WithValue(context.Background(), "task", t)
+	quotas, err := t.manager.GetParentQuotas(ctx, t.keys)
+	if err != nil {
+		log.Errorf("GetParentQuotas %s fail %s", QuotaKeyString(t.keys), err)
+		return
+	}
+	for i := range quotas {
+		err := t.manager.checkQuotaAlert(ctx, quotas[i])
+		if err != nil {
+			log.Errorf("checkQuotaAlert %s fail %s", QuotaKeyString(quotas[i].GetKeys()), err)
+		}
+	}
+}
+
+func (t *quotaAlertTask) Dump() string {
+	return fmt.Sprintf("quotaAlertTask %s %s", t.manager.Keyword(), QuotaKeyString(t.keys))
+}
+
+// postQuotaAlertJob checks the warning threshold of the quotas covering the
+// keys after their usage changes
+func (manager *SQuotaBaseManager) postQuotaAlertJob(keys IQuotaKeys) {
+	if !quotaExtensionsEnabled {
+		return
+	}
+	task := quotaAlertTask{
+		manager: manager,
+		keys:    keys,
+	}
+	quotaAlertWorker.Run(&task, nil, nil)
+}
+
+func (manager *SQuotaBaseManager) setQuotaWarningPercent(ctx context.Context, userCred mcclient.TokenCredential, keys IQuotaKeys, percent int) error {
+	err := QuotaAlertManager.setWarningPercent(ctx, manager.Keyword(), keys, percent)
+	if err != nil {
+		return err
+	}
+	manager.postQuotaAlertJob(keys)
+	return nil
+}
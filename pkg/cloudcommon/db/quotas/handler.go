@@ -61,6 +61,10 @@ type SBaseQuotaSetInput struct {
 	// | delete  | 删除配额                                  |
 	//
 	Action string `json:"action"`
+
+	// 告警阈值，使用量达到配额的百分比时发送通知，0表示不告警
+	// require:false
+	WarningPercent *int `json:"warning_percent"`
 }
 
 type SBaseQuotaQueryInput struct {
@@ -73,6 +77,8 @@ type SBaseQuotaQueryInput struct {
 }
 
 func AddQuotaHandler(manager *SQuotaBaseManager, prefix string, app *appsrv.Application) {
+	quotaExtensionsEnabled = true
+
 	app.AddHandler2("GET",
 		fmt.Sprintf("%s/%s", prefix, manager.KeywordPlural()),
 		auth.Authenticate(manager.getQuotaHandler), nil, "get_quota", nil)
@@ -101,6 +107,38 @@ func AddQuotaHandler(manager *SQuotaBaseManager, prefix string, app *appsrv.Appl
 		fmt.Sprintf("%s/%s/domains/<domainid>/pending", prefix, manager.KeywordPlural()),
 		auth.Authenticate(manager.cleanPendingUsageHandler), nil, "clean_pending_usage_for_domain", nil)
 
+	app.AddHandler2("GET",
+		fmt.Sprintf("%s/%s/trend", prefix, manager.KeywordPlural()),
+		auth.Authenticate(manager.getQuotaTrendHandler), nil, "get_quota_trend", nil)
+
+	app.AddHandler2("GET",
+		fmt.Sprintf("%s/%s/domains/<domainid>/trend", prefix, manager.KeywordPlural()),
+		auth.Authenticate(manager.getQuotaTrendHandler), nil, "get_quota_trend_for_domain", nil)
+
+	app.AddHandler2("GET",
+		fmt.Sprintf("%s/%s/requests", prefix, manager.KeywordPlural()),
+		auth.Authenticate(manager.listQuotaRequestsHandler), nil, "list_quota_change_requests", nil)
+
+	app.AddHandler2("GET",
+		fmt.Sprintf("%s/%s/requests/<requestid>", prefix, manager.KeywordPlural()),
+		auth.Authenticate(manager.getQuotaRequestHandler), nil, "get_quota_change_request", nil)
+
+	app.AddHandler2("POST",
+		fmt.Sprintf("%s/%s/requests", prefix, manager.KeywordPlural()),
+		auth.Authenticate(manager.createQuotaRequestHandler), nil, "create_quota_change_request", nil)
+
+	app.AddHandler2("POST",
+		fmt.Sprintf("%s/%s/domains/<domainid>/requests", prefix, manager.KeywordPlural()),
+		auth.Authenticate(manager.createQuotaRequestHandler), nil, "create_quota_change_request_for_domain", nil)
+
+	app.AddHandler2("POST",
+		fmt.Sprintf("%s/%s/requests/<requestid>/approve", prefix, manager.KeywordPlural()),
+		auth.Authenticate(manager.approveQuotaRequestHandler), nil, "approve_quota_change_request", nil)
+
+	app.AddHandler2("POST",
+		fmt.Sprintf("%s/%s/requests/<requestid>/reject", prefix, manager.KeywordPlural()),
+		auth.Authenticate(manager.rejectQuotaRequestHandler), nil, "reject_quota_change_request", nil)
+
 	if manager.scope == rbacutils.ScopeProject {
 		app.AddHandler2("GET",
 			fmt.Sprintf("%s/%s/<tenantid>", prefix, manager.KeywordPlural()),
@@ -125,6 +163,14 @@ func AddQuotaHandler(manager *SQuotaBaseManager, prefix string, app *appsrv.Appl
 		app.AddHandler2("DELETE",
 			fmt.Sprintf("%s/%s/projects/<tenantid>/pending", prefix, manager.KeywordPlural()),
 			auth.Authenticate(manager.cleanPendingUsageHandler), nil, "clean_pending_usage_for_project", nil)
+
+		app.AddHandler2("GET",
+			fmt.Sprintf("%s/%s/projects/<tenantid>/trend", prefix, manager.KeywordPlural()),
+			auth.Authenticate(manager.getQuotaTrendHandler), nil, "get_quota_trend_for_project", nil)
+
+		app.AddHandler2("POST",
+			fmt.Sprintf("%s/%s/projects/<tenantid>/requests", prefix, manager.KeywordPlural()),
+			auth.Authenticate(manager.createQuotaRequestHandler), nil, "create_quota_change_request_for_project", nil)
 	}
 }
 
@@ -134,6 +180,9 @@ func (manager *SQuotaBaseManager) queryQuota(ctx context.Context, quota IQuota,
 	keys := quota.GetKeys()
 	ret.Update(jsonutils.Marshal(keys))
 	ret.Update(quota.ToJSON(""))
+	if percent := QuotaAlertManager.getWarningPercent(manager.Keyword(), keys); percent > 0 {
+		ret.Set("warning_percent", jsonutils.NewInt(int64(percent)))
+	}
 
 	if !consts.EnableQuotaCheck() {
 		return ret, nil
@@ -394,6 +443,15 @@ func (manager *SQuotaBaseManager) setQuotaHandler(ctx context.Context, w http.Re
 	}
 
 	action, _ := body.GetString(manager.KeywordPlural(), "action")
+	warningPercent := -1
+	if body.Contains(manager.KeywordPlural(), "warning_percent") {
+		percent, _ := body.Int(manager.KeywordPlural(), "warning_percent")
+		if percent < 0 || percent > 100 {
+			httperrors.GeneralServerError(ctx, w, httperrors.NewOutOfRangeError("warning_percent %d out of range [0, 100]", percent))
+			return
+		}
+		warningPercent = int(percent)
+	}
 	var policyAction string
 	if action == QUOTA_ACTION_DELETE {
 		if isNew {
@@ -429,6 +487,10 @@ func (manager *SQuotaBaseManager) setQuotaHandler(ctx context.Context, w http.Re
 			httperrors.GeneralServerError(ctx, w, err)
 			return
 		}
+		err = QuotaAlertManager.deleteAlerts(ctx, manager.Keyword(), keys)
+		if err != nil {
+			log.Errorf("delete quota alerts %s fail %s", QuotaKeyString(keys), err)
+		}
 	} else {
 		switch action {
 		case QUOTA_ACTION_ADD:
@@ -453,6 +515,14 @@ func (manager *SQuotaBaseManager) setQuotaHandler(ctx context.Context, w http.Re
 			httperrors.GeneralServerError(ctx, w, err)
 			return
 		}
+
+		if warningPercent >= 0 {
+			err = manager.setQuotaWarningPercent(ctx, userCred, keys, warningPercent)
+			if err != nil {
+				httperrors.GeneralServerError(ctx, w, err)
+				return
+			}
+		}
 	}
 
 	quotaList, err := manager.listQuotas(ctx, userCred, baseKeys.OwnerId().GetProjectDomainId(), baseKeys.OwnerId().GetProjectId(), scope == rbacutils.ScopeDomain, false, true)
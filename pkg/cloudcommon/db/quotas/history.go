@@ -0,0 +1,304 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quotas
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+)
+
+const (
+	// a usage sample is recorded at most once per interval for a quota key
+	quotaUsageHistoryInterval = time.Hour
+	// samples older than the retention are purged
+	quotaUsageHistoryRetention = 90 * 24 * time.Hour
+
+	QUOTA_TREND_DEFAULT_DAYS = 30
+)
+
+// SQuotaUsageHistoryManager keeps the samples of quota usages to forecast the
+// date when a quota is exhausted
+type SQuotaUsageHistoryManager struct {
+	db.SResourceBaseManager
+}
+
+var (
+	QuotaUsageHistoryManager *SQuotaUsageHistoryManager
+
+	usageHistoryRecordTime     = make(map[string]time.Time)
+	usageHistoryRecordTimeLock = &sync.Mutex{}
+)
+
+func init() {
+	QuotaUsageHistoryManager = &SQuotaUsageHistoryManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SQuotaUsageHistory{},
+			"quota_usage_history_tbl",
+			"quota_usage_history",
+			"quota_usage_histories",
+		),
+	}
+	QuotaUsageHistoryManager.SetVirtualObject(QuotaUsageHistoryManager)
+}
+
+type SQuotaUsageHistory struct {
+	db.SResourceBase
+
+	RowId int64 `primary:"true" auto_increment:"true" list:"user" json:"row_id"`
+
+	QuotaType string `width:"32" charset:"ascii" nullable:"false" list:"user" json:"quota_type"`
+	QuotaKey  string `width:"512" charset:"ascii" nullable:"false" list:"user" json:"quota_key"`
+
+	// usage of the quota items, e.g. {"cpu":10,"memory":20480}
+	Usage jsonutils.JSONObject `nullable:"false" list:"user" json:"usage"`
+}
+
+func (manager *SQuotaUsageHistoryManager) record(ctx context.Context, quotaType string, usage IQuota) {
+	key := fmt.Sprintf("%s/%s", quotaType, QuotaKeyString(usage.GetKeys()))
+	usageHistoryRecordTimeLock.Lock()
+	last, ok := usageHistoryRecordTime[key]
+	if ok && time.Since(last) < quotaUsageHistoryInterval {
+		usageHistoryRecordTimeLock.Unlock()
+		return
+	}
+	usageHistoryRecordTime[key] = time.Now()
+	usageHistoryRecordTimeLock.Unlock()
+
+	history := &SQuotaUsageHistory{
+		QuotaType: quotaType,
+		QuotaKey:  QuotaKeyString(usage.GetKeys()),
+		Usage:     usage.ToJSON(""),
+	}
+	history.SetModelManager(manager, history)
+	err := manager.TableSpec().Insert(ctx, history)
+	if err != nil {
+		log.Errorf("insert quota usage history %s fail %s", key, err)
+	}
+}
+
+func (manager *SQuotaUsageHistoryManager) purge(ctx context.Context) error {
+	_, err := sqlchemy.GetDB().Exec(
+		fmt.Sprintf("delete from %s where created_at < ?", manager.TableSpec().Name()),
+		time.Now().Add(-quotaUsageHistoryRetention),
+	)
+	if err != nil {
+		return errors.Wrap(err, "purge quota usage histories")
+	}
+	return nil
+}
+
+func (manager *SQuotaUsageHistoryManager) fetchHistories(quotaType string, keys IQuotaKeys, since time.Time) ([]SQuotaUsageHistory, error) {
+	q := manager.Query().Equals("quota_type", quotaType).Equals("quota_key", QuotaKeyString(keys))
+	q = q.GE("created_at", since).Asc("created_at")
+	histories := make([]SQuotaUsageHistory, 0)
+	err := db.FetchModelObjects(manager, q, &histories)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return histories, nil
+}
+
+type sUsageSample struct {
+	at    time.Time
+	value float64
+}
+
+// SQuotaItemTrend is the usage trend of a quota item
+type SQuotaItemTrend struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
+	Usage int    `json:"usage"`
+	// 每天平均增长量
+	DailyIncrease float64 `json:"daily_increase"`
+	// 预计配额耗尽的时间，为空表示按当前趋势不会耗尽
+	ExhaustAt *time.Time `json:"exhaust_at,omitempty"`
+}
+
+// forecastExhaustion fits the samples with least squares and returns the daily
+// increase of the usage and the time when the usage reaches the limit
+func forecastExhaustion(samples []sUsageSample, current float64, limit float64, now time.Time) (float64, *time.Time) {
+	if limit >= 0 && current >= limit {
+		return 0, &now
+	}
+	if len(samples) < 2 {
+		return 0, nil
+	}
+	base := samples[0].at
+	n := float64(len(samples))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.at.Sub(base).Hours() / 24
+		sumX += x
+		sumY += s.value
+		sumXY += x * s.value
+		sumXX += x * x
+	}
+	denom := n*sumXX - sumX*sumX
+	if math.Abs(denom) < 1e-9 {
+		return 0, nil
+	}
+	slope := (n*sumXY - sumX*sumY) / denom
+	if slope <= 0 || limit < 0 {
+		return slope, nil
+	}
+	days := (limit - current) / slope
+	exhaustAt := now.Add(time.Duration(days * 24 * float64(time.Hour)))
+	return slope, &exhaustAt
+}
+
+func (manager *SQuotaBaseManager) getQuotaTrend(ctx context.Context, quota IQuota, days int) ([]SQuotaItemTrend, error) {
+	keys := quota.GetKeys()
+	now := time.Now()
+	histories, err := QuotaUsageHistoryManager.fetchHistories(manager.Keyword(), keys, now.Add(-time.Duration(days)*24*time.Hour))
+	if err != nil {
+		return nil, errors.Wrap(err, "fetchHistories")
+	}
+	usage := manager.newQuota()
+	err = manager.usageStore.GetQuota(ctx, keys, usage)
+	if err != nil {
+		return nil, errors.Wrap(err, "usageStore.GetQuota")
+	}
+	limits, _ := quota.ToJSON("").GetMap()
+	usages, _ := usage.ToJSON("").GetMap()
+	ret := make([]SQuotaItemTrend, 0, len(limits))
+	for name, v := range limits {
+		limit, err := v.Int()
+		if err != nil {
+			continue
+		}
+		current := int64(0)
+		if u, ok := usages[name]; ok {
+			current, _ = u.Int()
+		}
+		samples := make([]sUsageSample, 0, len(histories)+1)
+		for i := range histories {
+			if histories[i].Usage == nil {
+				continue
+			}
+			val, err := histories[i].Usage.Int(name)
+			if err != nil {
+				continue
+			}
+			samples = append(samples, sUsageSample{at: histories[i].CreatedAt, value: float64(val)})
+		}
+		samples = append(samples, sUsageSample{at: now, value: float64(current)})
+		trend := SQuotaItemTrend{
+			Name:  name,
+			Limit: int(limit),
+			Usage: int(current),
+		}
+		if limit == 0 {
+			// nothing is allowed, no trend at all
+			ret = append(ret, trend)
+			continue
+		}
+		trend.DailyIncrease, trend.ExhaustAt = forecastExhaustion(samples, float64(current), float64(limit), now)
+		ret = append(ret, trend)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret, nil
+}
+
+func (manager *SQuotaBaseManager) getQuotaTrendHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	params, query, _ := appsrv.FetchEnv(ctx, w, r)
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+
+	var ownerId mcclient.IIdentityProvider
+	var scope rbacutils.TRbacScope
+	var err error
+
+	projectId := params["<tenantid>"]
+	domainId := params["<domainid>"]
+	if len(projectId) > 0 || len(domainId) > 0 {
+		data := jsonutils.NewDict()
+		if len(domainId) > 0 {
+			data.Add(jsonutils.NewString(domainId), "project_domain")
+		} else if len(projectId) > 0 {
+			data.Add(jsonutils.NewString(projectId), "project")
+		}
+		ownerId, scope, err, _ = db.FetchCheckQueryOwnerScope(ctx, userCred, data, manager.GetIQuotaManager(), policy.PolicyActionGet, true)
+		if err != nil {
+			httperrors.GeneralServerError(ctx, w, err)
+			return
+		}
+	} else {
+		scopeStr, _ := query.GetString("scope")
+		if scopeStr == "project" && manager.scope == rbacutils.ScopeProject {
+			scope = rbacutils.ScopeProject
+		} else if scopeStr == "domain" {
+			scope = rbacutils.ScopeDomain
+		} else {
+			scope = manager.scope
+		}
+		ownerId = userCred
+	}
+
+	var keys IQuotaKeys
+	if manager.scope == rbacutils.ScopeProject {
+		keys = OwnerIdProjectQuotaKeys(scope, ownerId)
+	} else {
+		keys = OwnerIdDomainQuotaKeys(ownerId)
+	}
+	days, _ := query.Int("days")
+	if days <= 0 {
+		days = QUOTA_TREND_DEFAULT_DAYS
+	}
+
+	quota := manager.newQuota()
+	quota.SetKeys(keys)
+	err = manager.getQuotaByKeys(ctx, keys, quota)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			httperrors.NotFoundError(ctx, w, "Quota %s not found", QuotaKeyString(keys))
+			return
+		}
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	trends, err := manager.getQuotaTrend(ctx, quota, int(days))
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+
+	ret := jsonutils.Marshal(keys).(*jsonutils.JSONDict)
+	if names, err := manager.fetchKeyNames(ctx, keys); err == nil {
+		ret.Update(names)
+	}
+	ret.Set("days", jsonutils.NewInt(days))
+	ret.Set("trends", jsonutils.Marshal(trends))
+	rbody := jsonutils.NewDict()
+	rbody.Set(manager.Keyword(), ret)
+	appsrv.SendJSON(w, rbody)
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quotas
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForecastExhaustion(t *testing.T) {
+	now := time.Date(2022, 3, 11, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	samples := []sUsageSample{
+		{at: now.Add(-10 * day), value: 0},
+		{at: now.Add(-5 * day), value: 10},
+		{at: now, value: 20},
+	}
+
+	slope, exhaustAt := forecastExhaustion(samples, 20, 100, now)
+	if slope < 1.99 || slope > 2.01 {
+		t.Errorf("daily increase want 2, got %f", slope)
+	}
+	if exhaustAt == nil || exhaustAt.Sub(now.Add(40*day)) > time.Minute || now.Add(40*day).Sub(*exhaustAt) > time.Minute {
+		t.Errorf("exhaust at want %s, got %v", now.Add(40*day), exhaustAt)
+	}
+
+	// unlimited quota never exhausts
+	if _, exhaustAt := forecastExhaustion(samples, 20, -1, now); exhaustAt != nil {
+		t.Errorf("unlimited quota should not exhaust, got %s", exhaustAt)
+	}
+
+	// already exhausted
+	if _, exhaustAt := forecastExhaustion(samples, 20, 20, now); exhaustAt == nil || !exhaustAt.Equal(now) {
+		t.Errorf("exhausted quota want %s, got %v", now, exhaustAt)
+	}
+
+	// decreasing usage
+	decreasing := []sUsageSample{
+		{at: now.Add(-2 * day), value: 30},
+		{at: now, value: 20},
+	}
+	if slope, exhaustAt := forecastExhaustion(decreasing, 20, 100, now); slope >= 0 || exhaustAt != nil {
+		t.Errorf("decreasing usage should not exhaust, got %f %v", slope, exhaustAt)
+	}
+
+	// not enough samples
+	if _, exhaustAt := forecastExhaustion(samples[2:], 20, 100, now); exhaustAt != nil {
+		t.Errorf("single sample should not forecast, got %s", exhaustAt)
+	}
+}
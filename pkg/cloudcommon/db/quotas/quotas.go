@@ -145,6 +145,7 @@ func (manager *SQuotaBaseManager) changeUsage(ctx context.Context, userCred mccl
 			}
 		}
 	}
+	manager.postQuotaAlertJob(usage.GetKeys())
 	return nil
 }
 
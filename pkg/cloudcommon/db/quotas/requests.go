@@ -0,0 +1,445 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quotas
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/reflectutils"
+
+	napi "yunion.io/x/onecloud/pkg/apis/notify"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/consts"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/i18n"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+)
+
+const (
+	QUOTA_REQUEST_STATUS_PENDING      = "pending"
+	QUOTA_REQUEST_STATUS_APPROVED     = "approved"
+	QUOTA_REQUEST_STATUS_REJECTED     = "rejected"
+	QUOTA_REQUEST_STATUS_APPLY_FAILED = "apply_failed"
+)
+
+// SQuotaChangeRequestManager keeps the requests of raising quotas submitted
+// by the project or domain members, which are applied once approved by an
+// administrator with the privilege to set the quota
+type SQuotaChangeRequestManager struct {
+	db.SResourceBaseManager
+}
+
+var QuotaChangeRequestManager *SQuotaChangeRequestManager
+
+func init() {
+	QuotaChangeRequestManager = &SQuotaChangeRequestManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SQuotaChangeRequest{},
+			"quota_change_request_tbl",
+			"quota_change_request",
+			"quota_change_requests",
+		),
+	}
+	QuotaChangeRequestManager.SetVirtualObject(QuotaChangeRequestManager)
+}
+
+type SQuotaChangeRequest struct {
+	db.SResourceBase
+
+	Id string `width:"128" charset:"ascii" primary:"true" list:"user" json:"id"`
+
+	// keyword of the quota manager, e.g. quota, region_quota
+	QuotaType string `width:"32" charset:"ascii" nullable:"false" index:"true" list:"user" json:"quota_type"`
+	QuotaKey  string `width:"512" charset:"ascii" nullable:"false" list:"user" json:"quota_key"`
+
+	DomainId  string `width:"64" charset:"ascii" nullable:"false" index:"true" list:"user" json:"domain_id"`
+	ProjectId string `name:"tenant_id" width:"64" charset:"ascii" nullable:"false" index:"true" list:"user" json:"project_id"`
+
+	// 申请的配额，包括配额的键值和需要调整的配额项
+	Quota jsonutils.JSONObject `nullable:"false" list:"user" json:"quota"`
+	// 申请理由
+	Reason string `width:"256" charset:"utf8" nullable:"true" list:"user" json:"reason"`
+	// 申请状态，pending|approved|rejected|apply_failed
+	Status string `width:"16" charset:"ascii" nullable:"false" default:"pending" index:"true" list:"user" json:"status"`
+
+	RequesterId string `width:"128" charset:"ascii" nullable:"false" list:"user" json:"requester_id"`
+	Requester   string `width:"128" charset:"utf8" nullable:"false" list:"user" json:"requester"`
+
+	ReviewerId    string    `width:"128" charset:"ascii" nullable:"true" list:"user" json:"reviewer_id"`
+	Reviewer      string    `width:"128" charset:"utf8" nullable:"true" list:"user" json:"reviewer"`
+	ReviewComment string    `width:"256" charset:"utf8" nullable:"true" list:"user" json:"review_comment"`
+	ReviewedAt    time.Time `nullable:"true" list:"user" json:"reviewed_at"`
+}
+
+func (req *SQuotaChangeRequest) BeforeInsert() {
+	if len(req.Id) == 0 {
+		req.Id = db.DefaultUUIDGenerator()
+	}
+}
+
+func (req *SQuotaChangeRequest) GetId() string {
+	return req.Id
+}
+
+// quotaSummary returns the requested quota items in the form of
+// cpu=10, memory=20480
+func (req *SQuotaChangeRequest) quotaSummary(quota IQuota) string {
+	items, _ := quota.ToJSON("").GetMap()
+	summary := make([]string, 0, len(items))
+	for name, v := range items {
+		if val, _ := v.Int(); val > 0 {
+			summary = append(summary, fmt.Sprintf("%s=%d", name, val))
+		}
+	}
+	sort.Strings(summary)
+	return strings.Join(summary, ", ")
+}
+
+func (manager *SQuotaChangeRequestManager) fetchRequest(id string) (*SQuotaChangeRequest, error) {
+	req := &SQuotaChangeRequest{}
+	req.SetModelManager(manager, req)
+	err := manager.Query().Equals("id", id).First(req)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// quotaOwnerScope returns the least scope of the privilege required to access
+// the quota of the owner
+func quotaOwnerScope(userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider) rbacutils.TRbacScope {
+	if ownerId.GetProjectDomainId() != userCred.GetProjectDomainId() {
+		return rbacutils.ScopeSystem
+	}
+	if len(ownerId.GetProjectId()) == 0 {
+		return rbacutils.ScopeDomain
+	}
+	if ownerId.GetProjectId() != userCred.GetProjectId() {
+		return rbacutils.ScopeDomain
+	}
+	return rbacutils.ScopeProject
+}
+
+// quotaReviewScope returns the scope of the privilege required to approve the
+// change request of the quota, which is the same as setting the quota
+func quotaReviewScope(userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider) rbacutils.TRbacScope {
+	if len(ownerId.GetProjectId()) > 0 && ownerId.GetProjectDomainId() == userCred.GetProjectDomainId() {
+		return rbacutils.ScopeDomain
+	}
+	return rbacutils.ScopeSystem
+}
+
+func (manager *SQuotaBaseManager) allowQuotaAction(userCred mcclient.TokenCredential, action string, requireScope rbacutils.TRbacScope) bool {
+	ownerScope, policyResult := policy.PolicyManager.AllowScope(userCred, consts.GetServiceType(), manager.KeywordPlural(), action)
+	return policyResult.Result.IsAllow() && !requireScope.HigherThan(ownerScope)
+}
+
+func (manager *SQuotaBaseManager) createQuotaRequestHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	params, _, body := appsrv.FetchEnv(ctx, w, r)
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+
+	projectId := params["<tenantid>"]
+	domainId := params["<domainid>"]
+	data := jsonutils.NewDict()
+	if len(projectId) > 0 {
+		data.Add(jsonutils.NewString(projectId), "project")
+	} else if len(domainId) > 0 {
+		data.Add(jsonutils.NewString(domainId), "project_domain")
+	}
+
+	quota := manager.newQuota()
+	err := body.Unmarshal(quota, manager.KeywordPlural())
+	if err != nil {
+		log.Errorf("Fail to decode JSON request body: %s", err)
+		httperrors.InvalidInputError(i18n.WithRequestLang(ctx, r), w, "fail to decode body")
+		return
+	}
+	reason, _ := body.GetString(manager.KeywordPlural(), "reason")
+
+	isBaseQuota := false
+	if manager.scope == rbacutils.ScopeDomain {
+		isBaseQuota = IsBaseDomainQuotaKeys(quota.GetKeys())
+	} else {
+		isBaseQuota = IsBaseProjectQuotaKeys(quota.GetKeys())
+	}
+	ownerId, scope, _, err := manager.fetchSetQuotaScope(ctx, userCred, data, isBaseQuota)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+
+	var baseKeys IQuotaKeys
+	if manager.scope == rbacutils.ScopeDomain {
+		baseKeys = OwnerIdDomainQuotaKeys(ownerId)
+	} else {
+		baseKeys = OwnerIdProjectQuotaKeys(scope, ownerId)
+	}
+	reflectutils.FillEmbededStructValue(reflect.Indirect(reflect.ValueOf(quota)), reflect.ValueOf(baseKeys))
+	keys := quota.GetKeys()
+
+	// the requester should be able to view the quota
+	if !manager.allowQuotaAction(userCred, policy.PolicyActionGet, quotaOwnerScope(userCred, keys.OwnerId())) {
+		httperrors.ForbiddenError(ctx, w, "not enough privilleges")
+		return
+	}
+
+	req := &SQuotaChangeRequest{
+		QuotaType:   manager.Keyword(),
+		QuotaKey:    QuotaKeyString(keys),
+		DomainId:    keys.OwnerId().GetProjectDomainId(),
+		ProjectId:   keys.OwnerId().GetProjectId(),
+		Reason:      reason,
+		Status:      QUOTA_REQUEST_STATUS_PENDING,
+		RequesterId: userCred.GetUserId(),
+		Requester:   userCred.GetUserName(),
+	}
+	summary := req.quotaSummary(quota)
+	if len(summary) == 0 {
+		httperrors.InputParameterError(ctx, w, "no quota item requested")
+		return
+	}
+
+	cnt, err := QuotaChangeRequestManager.Query().Equals("quota_type", req.QuotaType).Equals("quota_key", req.QuotaKey).
+		Equals("status", QUOTA_REQUEST_STATUS_PENDING).CountWithError()
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	if cnt > 0 {
+		httperrors.ConflictError(ctx, w, "a pending change request of quota %s exists", req.QuotaKey)
+		return
+	}
+
+	requested := jsonutils.Marshal(keys).(*jsonutils.JSONDict)
+	items, _ := quota.ToJSON("").GetMap()
+	for name, v := range items {
+		if val, _ := v.Int(); val > 0 {
+			requested.Set(name, v)
+		}
+	}
+	req.Quota = requested
+	req.SetModelManager(QuotaChangeRequestManager, req)
+	err = QuotaChangeRequestManager.TableSpec().Insert(ctx, req)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, errors.Wrap(err, "Insert"))
+		return
+	}
+
+	manager.notifyQuotaRequest(ctx, req, napi.ActionCreate, summary, nil)
+	manager.sendQuotaRequest(w, req)
+}
+
+func (manager *SQuotaBaseManager) listQuotaRequestsHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	_, query, _ := appsrv.FetchEnv(ctx, w, r)
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+
+	allowScope, policyResult := policy.PolicyManager.AllowScope(userCred, consts.GetServiceType(), manager.KeywordPlural(), policy.PolicyActionList)
+	if !policyResult.Result.IsAllow() {
+		httperrors.ForbiddenError(ctx, w, "not allow to list quota change requests")
+		return
+	}
+
+	q := QuotaChangeRequestManager.Query().Equals("quota_type", manager.Keyword())
+	switch allowScope {
+	case rbacutils.ScopeSystem:
+		if domainId, _ := query.GetString("domain_id"); len(domainId) > 0 {
+			q = q.Equals("domain_id", domainId)
+		}
+	case rbacutils.ScopeDomain:
+		q = q.Equals("domain_id", userCred.GetProjectDomainId())
+	default:
+		q = q.Equals("tenant_id", userCred.GetProjectId())
+	}
+	if projectId, _ := query.GetString("project_id"); len(projectId) > 0 {
+		q = q.Equals("tenant_id", projectId)
+	}
+	if status, _ := query.GetString("status"); len(status) > 0 {
+		q = q.Equals("status", status)
+	}
+
+	total, err := q.CountWithError()
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	limit, _ := query.Int("limit")
+	if limit <= 0 {
+		limit = 20
+	}
+	offset, _ := query.Int("offset")
+	q = q.Desc("created_at").Limit(int(limit)).Offset(int(offset))
+
+	reqs := make([]SQuotaChangeRequest, 0)
+	err = db.FetchModelObjects(QuotaChangeRequestManager, q, &reqs)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+
+	rbody := jsonutils.NewDict()
+	rbody.Set(QuotaChangeRequestManager.KeywordPlural(), jsonutils.Marshal(reqs))
+	rbody.Set("total", jsonutils.NewInt(int64(total)))
+	rbody.Set("limit", jsonutils.NewInt(limit))
+	rbody.Set("offset", jsonutils.NewInt(offset))
+	appsrv.SendJSON(w, rbody)
+}
+
+// fetchQuotaRequest fetches the change request of the manager's quota and
+// checks whether the user is allowed to view it
+func (manager *SQuotaBaseManager) fetchQuotaRequest(ctx context.Context, userCred mcclient.TokenCredential, id string) (*SQuotaChangeRequest, error) {
+	req, err := QuotaChangeRequestManager.fetchRequest(id)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(QuotaChangeRequestManager.Keyword(), id)
+		}
+		return nil, errors.Wrap(err, "fetchRequest")
+	}
+	if req.QuotaType != manager.Keyword() {
+		return nil, httperrors.NewResourceNotFoundError2(QuotaChangeRequestManager.Keyword(), id)
+	}
+	if req.RequesterId != userCred.GetUserId() {
+		ownerId := &db.SOwnerId{DomainId: req.DomainId, ProjectId: req.ProjectId}
+		if !manager.allowQuotaAction(userCred, policy.PolicyActionGet, quotaOwnerScope(userCred, ownerId)) {
+			return nil, httperrors.NewForbiddenError("not allow to view quota change request %s", id)
+		}
+	}
+	return req, nil
+}
+
+func (manager *SQuotaBaseManager) getQuotaRequestHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	params, _, _ := appsrv.FetchEnv(ctx, w, r)
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+
+	req, err := manager.fetchQuotaRequest(ctx, userCred, params["<requestid>"])
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	manager.sendQuotaRequest(w, req)
+}
+
+func (manager *SQuotaBaseManager) approveQuotaRequestHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	manager.reviewQuotaRequest(ctx, w, r, true)
+}
+
+func (manager *SQuotaBaseManager) rejectQuotaRequestHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	manager.reviewQuotaRequest(ctx, w, r, false)
+}
+
+func (manager *SQuotaBaseManager) reviewQuotaRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, approve bool) {
+	params, _, body := appsrv.FetchEnv(ctx, w, r)
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+
+	req, err := manager.fetchQuotaRequest(ctx, userCred, params["<requestid>"])
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	if req.Status != QUOTA_REQUEST_STATUS_PENDING {
+		httperrors.GeneralServerError(ctx, w, httperrors.NewInvalidStatusError("quota change request %s is %s", req.Id, req.Status))
+		return
+	}
+	ownerId := &db.SOwnerId{DomainId: req.DomainId, ProjectId: req.ProjectId}
+	if !manager.allowQuotaAction(userCred, policy.PolicyActionUpdate, quotaReviewScope(userCred, ownerId)) {
+		httperrors.ForbiddenError(ctx, w, "not enough privilleges")
+		return
+	}
+
+	quota := manager.newQuota()
+	err = req.Quota.Unmarshal(quota)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, errors.Wrap(err, "Unmarshal quota"))
+		return
+	}
+
+	var comment string
+	if body != nil {
+		comment, _ = body.GetString("comment")
+	}
+	status := QUOTA_REQUEST_STATUS_REJECTED
+	action := napi.ActionReject
+	if approve {
+		status = QUOTA_REQUEST_STATUS_APPROVED
+		action = napi.ActionApprove
+		err = manager.applyQuotaRequest(ctx, userCred, quota)
+		if err != nil {
+			log.Errorf("apply quota change request %s fail %s", req.Id, err)
+			status = QUOTA_REQUEST_STATUS_APPLY_FAILED
+			comment = err.Error()
+		}
+	}
+
+	_, err = db.Update(req, func() error {
+		req.Status = status
+		req.ReviewerId = userCred.GetUserId()
+		req.Reviewer = userCred.GetUserName()
+		req.ReviewComment = comment
+		req.ReviewedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, errors.Wrap(err, "update quota change request"))
+		return
+	}
+
+	if status != QUOTA_REQUEST_STATUS_APPLY_FAILED {
+		manager.notifyQuotaRequest(ctx, req, action, req.quotaSummary(quota), []string{req.RequesterId})
+	}
+	manager.sendQuotaRequest(w, req)
+}
+
+// applyQuotaRequest raises the quota to the requested items
+func (manager *SQuotaBaseManager) applyQuotaRequest(ctx context.Context, userCred mcclient.TokenCredential, quota IQuota) error {
+	keys := quota.GetKeys()
+	oquota := manager.newQuota()
+	oquota.SetKeys(keys)
+	err := manager.getQuotaByKeys(ctx, keys, oquota)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return errors.Wrapf(err, "get quota %s", QuotaKeyString(keys))
+	}
+	oquota.Update(quota)
+	return manager.SetQuota(ctx, userCred, oquota)
+}
+
+func (manager *SQuotaBaseManager) notifyQuotaRequest(ctx context.Context, req *SQuotaChangeRequest, action napi.SAction, summary string, receiverIds []string) {
+	details := jsonutils.Marshal(req).(*jsonutils.JSONDict)
+	ownerId := &db.SOwnerId{DomainId: req.DomainId, ProjectId: req.ProjectId}
+	if names, err := manager.fetchKeyNames(ctx, OwnerIdProjectQuotaKeys(rbacutils.ScopeProject, ownerId)); err == nil {
+		details.Update(names)
+	}
+	details.Set("quota", jsonutils.NewString(summary))
+	notifyclient.OwnerEventNotify(ctx, action, napi.TOPIC_RESOURCE_QUOTA_CHANGE_REQUEST, ownerId, receiverIds, details)
+}
+
+func (manager *SQuotaBaseManager) sendQuotaRequest(w http.ResponseWriter, req *SQuotaChangeRequest) {
+	rbody := jsonutils.NewDict()
+	rbody.Set(QuotaChangeRequestManager.Keyword(), jsonutils.Marshal(req))
+	appsrv.SendJSON(w, rbody)
+}
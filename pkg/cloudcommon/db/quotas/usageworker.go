@@ -95,6 +95,11 @@ func (t *quotaTask) Run() {
 
 	clearDirty(t.key)
 
+	if quotaExtensionsEnabled {
+		QuotaUsageHistoryManager.record(ctx, t.manager.Keyword(), usage)
+		t.manager.postQuotaAlertJob(t.keys)
+	}
+
 	if t.usageChan != nil {
 		t.usageChan <- usage
 	}
@@ -137,6 +142,13 @@ func (manager *SQuotaBaseManager) CalculateQuotaUsages(ctx context.Context, user
 	}
 
 	log.Infof("CalculateQuotaUsages")
+	if quotaExtensionsEnabled {
+		err := QuotaUsageHistoryManager.purge(ctx)
+		if err != nil {
+			log.Errorf("purge quota usage histories fail %s", err)
+		}
+	}
+
 	quota := manager.newQuota()
 	keys := quota.GetKeys()
 	keyFields := keys.Fields()
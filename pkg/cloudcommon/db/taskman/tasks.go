@@ -44,6 +44,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	"yunion.io/x/onecloud/pkg/util/httputils"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
+	"yunion.io/x/onecloud/pkg/util/tracing"
 )
 
 const (
@@ -412,6 +413,16 @@ func execITask(taskValue reflect.Value, task *STask, odata jsonutils.JSONObject,
 	ctxData := task.GetRequestContext()
 	ctx := ctxData.GetContext()
 
+	// each stage joins the trace of the request starting the task
+	stageSpan := tracing.StartInternalTrace(appctx.AppContextTrace(ctx),
+		fmt.Sprintf("%s.%s-%s", ctxData.Trace.Id, task.Id, task.Stage),
+		fmt.Sprintf("%s.%s", task.TaskName, task.Stage), ctxData.ServiceName)
+	if stageSpan != nil {
+		stageSpan.Tags = map[string]string{"task_id": task.Id, "object_id": task.ObjId}
+		ctx = context.WithValue(ctx, appctx.APP_CONTEXT_KEY_TRACE, stageSpan)
+		defer tracing.EndTrace(stageSpan)
+	}
+
 	taskFailed := false
 
 	var data jsonutils.JSONObject
@@ -325,6 +325,27 @@ func SystemExceptionNotifyWithResult(ctx context.Context, action api.SAction, re
 	systemEventNotify(ctx, action, resType, result, string(npk.NotifyPriorityCritical), obj)
 }
 
+// OwnerEventNotify notifies the event of a resource that is not a db model,
+// the subscribers of the owner's project and domain are notified as well as
+// the receivers
+func OwnerEventNotify(ctx context.Context, action api.SAction, resType string, ownerId mcclient.IIdentityProvider, receiverIds []string, obj *jsonutils.JSONDict) {
+	event := api.Event.WithAction(action).WithResourceType(resType)
+	params := api.NotificationManagerEventNotifyInput{
+		ReceiverIds:     receiverIds,
+		ResourceDetails: obj,
+		Event:           event.String(),
+		Priority:        string(npk.NotifyPriorityNormal),
+	}
+	if ownerId != nil {
+		params.ProjectId = ownerId.GetProjectId()
+		params.ProjectDomainId = ownerId.GetProjectDomainId()
+	}
+	t := eventTask{
+		params: params,
+	}
+	notifyClientWorkerMan.Run(&t, nil, nil)
+}
+
 func RawNotifyWithCtx(ctx context.Context, recipientId []string, isGroup bool, channel npk.TNotifyChannel, priority npk.TNotifyPriority, event string, data jsonutils.JSONObject) {
 	rawNotify(ctx, sNotifyParams{
 		recipientId: recipientId,
@@ -112,6 +112,9 @@ type BaseOptions struct {
 	PlatformNames map[string]string `help:"identity name of this platform by language"`
 
 	EnableTlsMigration bool `help:"Enable TLS migration" default:"false"`
+
+	TracingExporter    string `help:"Exporter of distributed tracing spans" default:"none" choices:"none|otlp"`
+	OtlpTracesEndpoint string `help:"OTLP/HTTP endpoint receiving tracing spans, e.g. http://otel-collector:4318/v1/traces"`
 }
 
 const (
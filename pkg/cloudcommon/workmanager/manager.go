@@ -110,7 +110,7 @@ func (w *SWorkManager) delayTask(ctx context.Context, task DelayTaskFunc, params
 		return
 	} else {
 		// delayTask should have a new context.Context with value 'taskid'
-		ctx = context.WithValue(context.Background(), appctx.APP_CONTEXT_KEY_TASK_ID, ctx.Value(appctx.APP_CONTEXT_KEY_TASK_ID))
+		ctx = withTraceContext(context.WithValue(context.Background(), appctx.APP_CONTEXT_KEY_TASK_ID, ctx.Value(appctx.APP_CONTEXT_KEY_TASK_ID)), ctx)
 		w.add()
 		t := workerTask{
 			ctx:    ctx,
@@ -165,6 +165,9 @@ func (w *SWorkManager) delayTaskWithoutReqctx(
 	if ctx != nil && ctx.Value(appctx.APP_CONTEXT_KEY_TASK_ID) != nil {
 		newCtx = context.WithValue(newCtx, appctx.APP_CONTEXT_KEY_TASK_ID, ctx.Value(appctx.APP_CONTEXT_KEY_TASK_ID))
 	}
+	if ctx != nil {
+		newCtx = withTraceContext(newCtx, ctx)
+	}
 	ctx = newCtx
 	w.add()
 	t := delayWorkerTask{
@@ -176,6 +179,21 @@ func (w *SWorkManager) delayTaskWithoutReqctx(
 	w.worker.Run(&t, nil, nil)
 }
 
+// withTraceContext keeps the trace of request in the context of delay task,
+// so the callbacks of task join the same trace
+func withTraceContext(newCtx context.Context, ctx context.Context) context.Context {
+	for _, key := range []appctx.AppContextKey{
+		appctx.APP_CONTEXT_KEY_TRACE,
+		appctx.APP_CONTEXT_KEY_REQUEST_ID,
+		appctx.APP_CONTEXT_KEY_APPNAME,
+	} {
+		if val := ctx.Value(key); val != nil {
+			newCtx = context.WithValue(newCtx, key, val)
+		}
+	}
+	return newCtx
+}
+
 func (w *SWorkManager) Stop() {
 	log.Infof("WorkManager stop, waitting for workers ...")
 	for w.curCount > 0 {
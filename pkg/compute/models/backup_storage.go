@@ -16,11 +16,14 @@ package models
 
 import (
 	"context"
+	"net/url"
+	"path/filepath"
 	"reflect"
 	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/gotypes"
 	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
@@ -65,6 +68,14 @@ func init() {
 type SBackupStorageAccessInfo struct {
 	NfsHost      string `json:"nfs_host"`
 	NfsSharedDir string `json:"nfs_shared_dir"`
+
+	LocalPath string `json:"local_path,omitempty"`
+
+	ObjectEndpoint  string `json:"object_endpoint,omitempty"`
+	ObjectBucket    string `json:"object_bucket,omitempty"`
+	ObjectAccessKey string `json:"object_access_key,omitempty"`
+	// ObjectSecret is encrypted with the storage id
+	ObjectSecret string `json:"object_secret,omitempty"`
 }
 
 func (ba *SBackupStorageAccessInfo) String() string {
@@ -81,7 +92,7 @@ func (bs *SBackupStorageManager) ValidateCreateData(ctx context.Context, userCre
 	if err != nil {
 		return input, err
 	}
-	if !utils.IsInStringArray(input.StorageType, []string{api.BACKUPSTORAGE_TYPE_NFS}) {
+	if !utils.IsInStringArray(input.StorageType, []string{api.BACKUPSTORAGE_TYPE_NFS, api.BACKUPSTORAGE_TYPE_LOCAL, api.BACKUPSTORAGE_TYPE_OBJECT_STORAGE}) {
 		return input, httperrors.NewInputParameterError("Invalid storage type %s", input.StorageType)
 	}
 	switch input.StorageType {
@@ -92,18 +103,53 @@ func (bs *SBackupStorageManager) ValidateCreateData(ctx context.Context, userCre
 		if input.NfsSharedDir == "" {
 			return input, httperrors.NewInputParameterError("nfs_shared_dir is required when storage type is nfs")
 		}
+	case api.BACKUPSTORAGE_TYPE_LOCAL:
+		if input.LocalPath == "" {
+			return input, httperrors.NewInputParameterError("local_path is required when storage type is local")
+		}
+		if !filepath.IsAbs(input.LocalPath) {
+			return input, httperrors.NewInputParameterError("local_path %s is not an absolute path", input.LocalPath)
+		}
+	case api.BACKUPSTORAGE_TYPE_OBJECT_STORAGE:
+		if input.ObjectEndpoint == "" {
+			return input, httperrors.NewInputParameterError("object_endpoint is required when storage type is object")
+		}
+		u, err := url.Parse(input.ObjectEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return input, httperrors.NewInputParameterError("invalid object_endpoint %s, should be http(s)://host[:port]", input.ObjectEndpoint)
+		}
+		if input.ObjectBucket == "" {
+			return input, httperrors.NewInputParameterError("object_bucket is required when storage type is object")
+		}
 	}
 	return input, nil
 }
 
 func (bs *SBackupStorage) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
 	bs.SetEnabled(true)
-	nfsHost, _ := data.GetString("nfs_host")
-	nfsSharedDir, _ := data.GetString("nfs_shared_dir")
+	input := api.BackupStorageCreateInput{}
+	err := data.Unmarshal(&input)
+	if err != nil {
+		return errors.Wrap(err, "Unmarshal")
+	}
 	bs.Status = api.BACKUPSTORAGE_STATUS_ONLINE
 	bs.AccessInfo = &SBackupStorageAccessInfo{
-		NfsHost:      nfsHost,
-		NfsSharedDir: nfsSharedDir,
+		NfsHost:         input.NfsHost,
+		NfsSharedDir:    input.NfsSharedDir,
+		LocalPath:       input.LocalPath,
+		ObjectEndpoint:  input.ObjectEndpoint,
+		ObjectBucket:    input.ObjectBucket,
+		ObjectAccessKey: input.ObjectAccessKey,
+	}
+	if len(input.ObjectSecret) > 0 {
+		// the secret is encrypted with the storage id, assign the id
+		// before insert so that the plaintext never reaches the database
+		bs.Id = db.DefaultUUIDGenerator()
+		sec, err := utils.EncryptAESBase64(bs.Id, input.ObjectSecret)
+		if err != nil {
+			return errors.Wrap(err, "encrypt object secret")
+		}
+		bs.AccessInfo.ObjectSecret = sec
 	}
 	return bs.SEnabledStatusInfrasResourceBase.CustomizeCreate(ctx, userCred, ownerId, query, data)
 }
@@ -132,9 +178,29 @@ func (bs *SBackupStorage) PostCreate(ctx context.Context, userCred mcclient.Toke
 	bs.SetStatus(userCred, api.BACKUPSTORAGE_STATUS_OFFLINE, "")
 }
 
+// GetAccessInfo returns the access info sent to hosts, it carries the
+// storage type and the decrypted object secret
+func (bs *SBackupStorage) GetAccessInfo() (*jsonutils.JSONDict, error) {
+	accessInfo := *bs.AccessInfo
+	if len(accessInfo.ObjectSecret) > 0 {
+		secret, err := utils.DescryptAESBase64(bs.Id, accessInfo.ObjectSecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "decrypt object secret")
+		}
+		accessInfo.ObjectSecret = secret
+	}
+	ret := jsonutils.Marshal(&accessInfo).(*jsonutils.JSONDict)
+	ret.Set("storage_type", jsonutils.NewString(bs.StorageType))
+	return ret, nil
+}
+
 func (bs *SBackupStorage) getMoreDetails(ctx context.Context, out api.BackupStorageDetails) api.BackupStorageDetails {
 	out.NfsHost = bs.AccessInfo.NfsHost
 	out.NfsSharedDir = bs.AccessInfo.NfsSharedDir
+	out.LocalPath = bs.AccessInfo.LocalPath
+	out.ObjectEndpoint = bs.AccessInfo.ObjectEndpoint
+	out.ObjectBucket = bs.AccessInfo.ObjectBucket
+	out.ObjectAccessKey = bs.AccessInfo.ObjectAccessKey
 	return out
 }
 
@@ -911,6 +911,28 @@ func (self *SDBInstance) PerformReboot(ctx context.Context, userCred mcclient.To
 	return nil, self.StartDBInstanceRebootTask(ctx, userCred, jsonutils.NewDict(), "")
 }
 
+// 启动RDS实例
+func (self *SDBInstance) PerformStart(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, []string{api.DBINSTANCE_STOPPED, api.DBINSTANCE_START_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("Cannot do start dbinstance in status %s", self.Status)
+	}
+	if !utils.IsInStringArray(self.GetProviderName(), api.DBINSTANCE_START_STOP_PROVIDERS) {
+		return nil, httperrors.NewUnsupportOperationError("Not support start dbinstance of %s", self.GetProviderName())
+	}
+	return nil, self.StartDBInstanceStartTask(ctx, userCred, jsonutils.NewDict(), "")
+}
+
+// 停止RDS实例
+func (self *SDBInstance) PerformStop(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, []string{api.DBINSTANCE_RUNNING, api.DBINSTANCE_STOP_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("Cannot do stop dbinstance in status %s", self.Status)
+	}
+	if !utils.IsInStringArray(self.GetProviderName(), api.DBINSTANCE_START_STOP_PROVIDERS) {
+		return nil, httperrors.NewUnsupportOperationError("Not support stop dbinstance of %s", self.GetProviderName())
+	}
+	return nil, self.StartDBInstanceStopTask(ctx, userCred, jsonutils.NewDict(), "")
+}
+
 //同步RDS实例状态
 func (self *SDBInstance) PerformSyncstatus(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	return self.PerformSync(ctx, userCred, query, data)
@@ -1134,6 +1156,26 @@ func (self *SDBInstance) StartDBInstanceRebootTask(ctx context.Context, userCred
 	return nil
 }
 
+func (self *SDBInstance) StartDBInstanceStartTask(ctx context.Context, userCred mcclient.TokenCredential, data *jsonutils.JSONDict, parentTaskId string) error {
+	self.SetStatus(userCred, api.DBINSTANCE_STARTING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "DBInstanceStartTask", self, userCred, data, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SDBInstance) StartDBInstanceStopTask(ctx context.Context, userCred mcclient.TokenCredential, data *jsonutils.JSONDict, parentTaskId string) error {
+	self.SetStatus(userCred, api.DBINSTANCE_STOPPING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "DBInstanceStopTask", self, userCred, data, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
 func (self *SDBInstance) StartDBInstanceSyncTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
 	return StartResourceSyncStatusTask(ctx, userCred, self, "DBInstanceSyncTask", parentTaskId)
 }
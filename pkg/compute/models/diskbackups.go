@@ -24,15 +24,18 @@ import (
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/gotypes"
+	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/compute/options"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
+	"yunion.io/x/onecloud/pkg/util/version"
 )
 
 type SDiskBackupManager struct {
@@ -56,6 +59,8 @@ type SDiskBackup struct {
 	DiskId          string `width:"36" charset:"ascii" nullable:"true" create:"required" list:"user" index:"true"`
 	BackupStorageId string `width:"36" charset:"ascii" nullable:"true" create:"required" list:"user" index:"true"`
 	StorageId       string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+	// 增量备份所基于的备份, 全量备份为空
+	ParentBackupId string `width:"36" charset:"ascii" nullable:"true" create:"optional" list:"user" index:"true"`
 
 	// 备份大小
 	SizeMb     int    `nullable:"false" list:"user" create:"optional"`
@@ -143,6 +148,15 @@ func (self *SDiskBackup) ValidateDeleteCondition(ctx context.Context, info jsonu
 	if is {
 		return httperrors.NewBadRequestError("disk backup referenced by instance backup")
 	}
+	children, err := self.GetChildBackups()
+	if err != nil {
+		return errors.Wrap(err, "GetChildBackups")
+	}
+	for i := range children {
+		if children[i].Status != api.BACKUP_STATUS_READY {
+			return httperrors.NewBadRequestError("incremental backup %s of the backup is in status %s", children[i].Name, children[i].Status)
+		}
+	}
 	return nil
 }
 
@@ -160,16 +174,72 @@ func (dm *SDiskBackupManager) FetchCustomizeColumns(
 	regionRows := dm.SCloudregionResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
 	encRows := dm.SEncryptedResourceManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
 
+	parents, err := dm.fetchBackupParents(objs)
+	if err != nil {
+		log.Errorf("fetchBackupParents: %v", err)
+	}
 	for i := range rows {
 		rows[i].VirtualResourceDetails = virtRows[i]
 		rows[i].ManagedResourceInfo = manRows[i]
 		rows[i].CloudregionResourceInfo = regionRows[i]
 		rows[i].EncryptedResourceDetails = encRows[i]
-		rows[i] = objs[i].(*SDiskBackup).getMoreDetails(rows[i])
+		backup := objs[i].(*SDiskBackup)
+		rows[i] = backup.getMoreDetails(rows[i])
+		if parents != nil {
+			chain, err := walkBackupChain(backup.Id, backup.ParentBackupId, func(id string) (string, error) {
+				parentId, ok := parents[id]
+				if !ok {
+					return "", errors.Wrapf(errors.ErrNotFound, "backup %s", id)
+				}
+				return parentId, nil
+			})
+			if err == nil {
+				rows[i].BackupChainLength = len(chain) + 1
+			}
+		}
 	}
 	return rows
 }
 
+// fetchBackupParents returns the parent ids of the backups in objs and
+// of all their ancestors, it takes a query per level of the chains
+// instead of one per backup
+func (dm *SDiskBackupManager) fetchBackupParents(objs []interface{}) (map[string]string, error) {
+	parents := make(map[string]string)
+	pending := make([]string, 0)
+	for i := range objs {
+		backup := objs[i].(*SDiskBackup)
+		parents[backup.Id] = backup.ParentBackupId
+	}
+	for i := range objs {
+		parentId := objs[i].(*SDiskBackup).ParentBackupId
+		if _, ok := parents[parentId]; len(parentId) > 0 && !ok && !utils.IsInStringArray(parentId, pending) {
+			pending = append(pending, parentId)
+		}
+	}
+	for len(pending) > 0 {
+		q := dm.Query("id", "parent_backup_id").In("id", pending)
+		rows := make([]struct {
+			Id             string
+			ParentBackupId string
+		}, 0)
+		err := q.All(&rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "query parent backups")
+		}
+		pending = make([]string, 0)
+		for _, row := range rows {
+			parents[row.Id] = row.ParentBackupId
+		}
+		for _, row := range rows {
+			if _, ok := parents[row.ParentBackupId]; len(row.ParentBackupId) > 0 && !ok && !utils.IsInStringArray(row.ParentBackupId, pending) {
+				pending = append(pending, row.ParentBackupId)
+			}
+		}
+	}
+	return parents, nil
+}
+
 func (db *SDiskBackup) getMoreDetails(out api.DiskBackupDetails) api.DiskBackupDetails {
 	disk, _ := db.GetDisk()
 	if disk != nil {
@@ -210,6 +280,129 @@ func (db *SDiskBackup) GetBackupStorage() (*SBackupStorage, error) {
 	return ibs.(*SBackupStorage), nil
 }
 
+// IsLiveBackupSupported returns whether the disk is backed up from its
+// running guest, with the changes since the parent backup tracked by dirty
+// bitmaps, instead of from a snapshot
+func (db *SDiskBackup) IsLiveBackupSupported(userCred mcclient.TokenCredential) bool {
+	disk, err := db.GetDisk()
+	if err != nil || disk.IsEncrypted() {
+		return false
+	}
+	storage, err := disk.GetStorage()
+	if err != nil || storage.StorageType != api.STORAGE_LOCAL {
+		return false
+	}
+	guest := disk.GetGuest()
+	if guest == nil || guest.Hypervisor != api.HYPERVISOR_KVM || guest.Status != api.VM_RUNNING {
+		return false
+	}
+	// job-dismiss and query-jobs are added in qemu 3.0
+	return version.GE(guest.GetQemuVersion(userCred), "3.0.0")
+}
+
+// GetParentBackupChain returns the ids of the backups, root first, an
+// incremental backup depends on, it is empty for full backups
+func (db *SDiskBackup) GetParentBackupChain() ([]string, error) {
+	return walkBackupChain(db.Id, db.ParentBackupId, func(id string) (string, error) {
+		parent, err := DiskBackupManager.FetchById(id)
+		if err != nil {
+			return "", errors.Wrapf(err, "fetch parent backup %s", id)
+		}
+		return parent.(*SDiskBackup).ParentBackupId, nil
+	})
+}
+
+// walkBackupChain follows the parents of backupId, looked up by getParent,
+// from parentId up to the full backup and returns their ids root first
+func walkBackupChain(backupId, parentId string, getParent func(id string) (string, error)) ([]string, error) {
+	chain := make([]string, 0)
+	for len(parentId) > 0 {
+		if parentId == backupId || utils.IsInStringArray(parentId, chain) {
+			return nil, errors.Errorf("backup chain of %s has a loop at %s", backupId, parentId)
+		}
+		chain = append(chain, parentId)
+		var err error
+		parentId, err = getParent(parentId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+// canExtendBackupChain reports whether an incremental backup may be based
+// on the last backup of parentChain without exceeding maxLength
+func canExtendBackupChain(parentChain []string, maxLength int) bool {
+	return len(parentChain)+2 <= maxLength
+}
+
+// ReparentChildren bases the incremental backups of the backup on its
+// parent once the data of the backup has been merged into them, sizeMbs
+// holds the new sizes of the merged children
+func (backup *SDiskBackup) ReparentChildren(sizeMbs map[string]int) error {
+	children, err := backup.GetChildBackups()
+	if err != nil {
+		return errors.Wrap(err, "GetChildBackups")
+	}
+	for i := range children {
+		child := &children[i]
+		_, err := db.Update(child, func() error {
+			child.reparentTo(backup.ParentBackupId, sizeMbs)
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "reparent backup %s", child.Id)
+		}
+	}
+	return nil
+}
+
+func (backup *SDiskBackup) reparentTo(parentId string, sizeMbs map[string]int) {
+	backup.ParentBackupId = parentId
+	if sizeMb, ok := sizeMbs[backup.Id]; ok {
+		backup.SizeMb = sizeMb
+	}
+}
+
+func (backup *SDiskBackup) GetChildBackups() ([]SDiskBackup, error) {
+	q := DiskBackupManager.Query().Equals("parent_backup_id", backup.Id)
+	backups := make([]SDiskBackup, 0)
+	err := db.FetchModelObjects(DiskBackupManager, q, &backups)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return backups, nil
+}
+
+// getIncrementalParent returns the latest ready backup of the disk on the
+// backup storage an incremental backup can be based on, nil if a full
+// backup should be taken
+func (dm *SDiskBackupManager) getIncrementalParent(disk *SDisk, backupStorageId string) (*SDiskBackup, error) {
+	q := dm.Query().Equals("disk_id", disk.Id).Equals("backup_storage_id", backupStorageId)
+	q = q.Equals("status", api.BACKUP_STATUS_READY).Equals("disk_size_mb", disk.DiskSize)
+	q = q.Desc("created_at").Limit(1)
+	backups := make([]SDiskBackup, 0)
+	err := db.FetchModelObjects(dm, q, &backups)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	if len(backups) == 0 {
+		return nil, nil
+	}
+	parent := &backups[0]
+	chain, err := parent.GetParentBackupChain()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetParentBackupChain")
+	}
+	if !canExtendBackupChain(chain, options.Options.MaxDiskBackupChainLength) {
+		return nil, nil
+	}
+	return parent, nil
+}
+
 func (db *SDiskBackup) GetRegionDriver() (IRegionDriver, error) {
 	cloudRegion, err := db.GetRegion()
 	if err != nil {
@@ -268,6 +461,19 @@ func (dm *SDiskBackupManager) ValidateCreateData(
 	if bs.Status != api.BACKUPSTORAGE_STATUS_ONLINE {
 		return input, httperrors.NewForbiddenError("can't backup guest to backup storage with status %s", bs.Status)
 	}
+	input.ParentBackupId = ""
+	if input.Incremental {
+		if len(disk.EncryptKeyId) > 0 {
+			return input, httperrors.NewNotSupportedError("incremental backup of encrypted disk is not supported")
+		}
+		parent, err := dm.getIncrementalParent(disk, bs.Id)
+		if err != nil {
+			return input, errors.Wrap(err, "getIncrementalParent")
+		}
+		if parent != nil {
+			input.ParentBackupId = parent.Id
+		}
+	}
 	storage, err := disk.GetStorage()
 	if err != nil {
 		return input, errors.Wrapf(err, "unable to get storage of disk %s", disk.GetId())
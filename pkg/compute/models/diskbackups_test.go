@@ -0,0 +1,131 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/pkg/errors"
+)
+
+func TestWalkBackupChain(t *testing.T) {
+	cases := []struct {
+		name     string
+		id       string
+		parents  map[string]string
+		want     []string
+		wantLoop bool
+	}{
+		{
+			name:    "full backup",
+			id:      "a",
+			parents: map[string]string{"a": ""},
+			want:    []string{},
+		},
+		{
+			name:    "incremental backup",
+			id:      "c",
+			parents: map[string]string{"a": "", "b": "a", "c": "b"},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:     "loop through self",
+			id:       "a",
+			parents:  map[string]string{"a": "b", "b": "a"},
+			wantLoop: true,
+		},
+		{
+			name:     "loop above",
+			id:       "c",
+			parents:  map[string]string{"a": "b", "b": "a", "c": "b"},
+			wantLoop: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			chain, err := walkBackupChain(c.id, c.parents[c.id], func(id string) (string, error) {
+				parentId, ok := c.parents[id]
+				if !ok {
+					return "", errors.ErrNotFound
+				}
+				return parentId, nil
+			})
+			if c.wantLoop {
+				if err == nil {
+					t.Fatalf("want loop error, got chain %v", chain)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("walkBackupChain: %v", err)
+			}
+			if !reflect.DeepEqual(chain, c.want) {
+				t.Fatalf("want chain %v, got %v", c.want, chain)
+			}
+		})
+	}
+
+	_, err := walkBackupChain("b", "a", func(id string) (string, error) {
+		return "", errors.ErrNotFound
+	})
+	if errors.Cause(err) != errors.ErrNotFound {
+		t.Fatalf("want missing parent error, got %v", err)
+	}
+}
+
+func TestCanExtendBackupChain(t *testing.T) {
+	cases := []struct {
+		parentChain []string
+		maxLength   int
+		want        bool
+	}{
+		// the parent is a full backup, the new backup makes a chain of 2
+		{[]string{}, 2, true},
+		{[]string{}, 1, false},
+		{[]string{"a", "b"}, 4, true},
+		{[]string{"a", "b"}, 3, false},
+	}
+	for _, c := range cases {
+		if got := canExtendBackupChain(c.parentChain, c.maxLength); got != c.want {
+			t.Errorf("canExtendBackupChain(%v, %d) = %v, want %v", c.parentChain, c.maxLength, got, c.want)
+		}
+	}
+}
+
+func TestDiskBackupReparentTo(t *testing.T) {
+	// deleting b of a <- b <- {c, d} merges b into c and d
+	deleted := &SDiskBackup{ParentBackupId: "a"}
+	deleted.Id = "b"
+	children := []SDiskBackup{
+		{ParentBackupId: "b", SizeMb: 10},
+		{ParentBackupId: "b", SizeMb: 20},
+	}
+	children[0].Id = "c"
+	children[1].Id = "d"
+	sizeMbs := map[string]int{"c": 15}
+	for i := range children {
+		children[i].reparentTo(deleted.ParentBackupId, sizeMbs)
+	}
+	if children[0].ParentBackupId != "a" || children[1].ParentBackupId != "a" {
+		t.Fatalf("children should be based on a, got %s %s", children[0].ParentBackupId, children[1].ParentBackupId)
+	}
+	if children[0].SizeMb != 15 {
+		t.Errorf("merged size of c should be 15, got %d", children[0].SizeMb)
+	}
+	if children[1].SizeMb != 20 {
+		t.Errorf("size of d without merged size should be kept, got %d", children[1].SizeMb)
+	}
+}
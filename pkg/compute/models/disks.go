@@ -753,10 +753,19 @@ func (self *SDisk) getDiskAllocateFromBackupInput(ctx context.Context, backupId
 	if err != nil {
 		return nil, errors.Wrapf(err, "unable to get backupstorage of backup %s", backupId)
 	}
+	chain, err := backup.GetParentBackupChain()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get backup chain of backup %s", backupId)
+	}
+	accessInfo, err := bs.GetAccessInfo()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get access info of backupstorage %s", bs.GetId())
+	}
 	return &api.DiskAllocateFromBackupInput{
 		BackupId:                backupId,
 		BackupStorageId:         bs.GetId(),
-		BackupStorageAccessInfo: jsonutils.Marshal(bs.AccessInfo).(*jsonutils.JSONDict),
+		BackupStorageAccessInfo: accessInfo,
+		ParentBackupChain:       chain,
 	}, nil
 }
 
@@ -1213,6 +1213,46 @@ func (self *SElasticcache) StartRestartTask(ctx context.Context, userCred mcclie
 	return nil
 }
 
+func (self *SElasticcache) PerformStart(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, []string{api.ELASTIC_CACHE_STATUS_STOPPED, api.ELASTIC_CACHE_STATUS_START_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("Cannot do start elasticcache instance in status %s", self.Status)
+	}
+	if !utils.IsInStringArray(self.GetProviderName(), api.ELASTIC_CACHE_START_STOP_PROVIDERS) {
+		return nil, httperrors.NewUnsupportOperationError("Not support start elasticcache instance of %s", self.GetProviderName())
+	}
+	return nil, self.StartElasticcacheStartTask(ctx, userCred, "")
+}
+
+func (self *SElasticcache) StartElasticcacheStartTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	self.SetStatus(userCred, api.ELASTIC_CACHE_STATUS_STARTING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "ElasticcacheStartTask", self, userCred, jsonutils.NewDict(), parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SElasticcache) PerformStop(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, []string{api.ELASTIC_CACHE_STATUS_RUNNING, api.ELASTIC_CACHE_STATUS_STOP_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("Cannot do stop elasticcache instance in status %s", self.Status)
+	}
+	if !utils.IsInStringArray(self.GetProviderName(), api.ELASTIC_CACHE_START_STOP_PROVIDERS) {
+		return nil, httperrors.NewUnsupportOperationError("Not support stop elasticcache instance of %s", self.GetProviderName())
+	}
+	return nil, self.StartElasticcacheStopTask(ctx, userCred, "")
+}
+
+func (self *SElasticcache) StartElasticcacheStopTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	self.SetStatus(userCred, api.ELASTIC_CACHE_STATUS_STOPPING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "ElasticcacheStopTask", self, userCred, jsonutils.NewDict(), parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
 func (self *SElasticcache) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
 	if self.DisableDelete.IsTrue() {
 		return httperrors.NewInvalidStatusError("Elastic cache is locked, cannot delete")
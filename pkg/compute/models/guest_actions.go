@@ -553,13 +553,49 @@ func (self *SGuest) PerformLiveMigrate(ctx context.Context, userCred mcclient.To
 	if err := self.validateMigrate(ctx, userCred, nil, input); err != nil {
 		return nil, err
 	}
+	if err := self.validateLiveMigrateTuning(&input.GuestLiveMigrateTuning); err != nil {
+		return nil, err
+	}
 	if input.EnableTLS == nil {
 		input.EnableTLS = &options.Options.EnableTlsMigration
 	}
-	return nil, self.StartGuestLiveMigrateTask(ctx, userCred, self.Status, input.PreferHost, input.SkipCpuCheck, input.SkipKernelCheck, input.EnableTLS, "")
+	return nil, self.StartGuestLiveMigrateTask(ctx, userCred, self.Status, input.PreferHost, input.SkipCpuCheck, input.SkipKernelCheck, input.EnableTLS, &input.GuestLiveMigrateTuning, "")
+}
+
+func (self *SGuest) validateLiveMigrateTuning(tuning *api.GuestLiveMigrateTuning) error {
+	if tuning.MultifdChannels < 0 || tuning.MultifdChannels > 255 {
+		return httperrors.NewInputParameterError("multifd_channels must be in range 0-255")
+	}
+	if tuning.XbzrleCacheSizeMb < 0 || tuning.MaxBandwidthMb < 0 || tuning.MaxDowntimeMs < 0 || tuning.PostcopyAfterSeconds < 0 {
+		return httperrors.NewInputParameterError("live migrate tuning parameters must not be negative")
+	}
+	if len(tuning.Compression) > 0 {
+		if tuning.Compression != api.LIVE_MIGRATE_COMPRESSION_ZSTD {
+			return httperrors.NewInputParameterError("unsupported compression %s", tuning.Compression)
+		}
+		if tuning.MultifdChannels == 0 {
+			return httperrors.NewInputParameterError("compression requires multifd_channels")
+		}
+	}
+	if tuning.MultifdChannels == 0 && tuning.PostcopyAfterSeconds == 0 {
+		return nil
+	}
+	// block migration of local disks is done by the migration stream,
+	// which supports neither multifd nor postcopy
+	disks, err := self.GetDisks()
+	if err != nil {
+		return errors.Wrap(err, "GetDisks")
+	}
+	for i := range disks {
+		storage, _ := disks[i].GetStorage()
+		if storage != nil && utils.IsInStringArray(storage.StorageType, api.STORAGE_LOCAL_TYPES) {
+			return httperrors.NewUnsupportOperationError("multifd and postcopy are not supported for guest with local storage disks")
+		}
+	}
+	return nil
 }
 
-func (self *SGuest) StartGuestLiveMigrateTask(ctx context.Context, userCred mcclient.TokenCredential, guestStatus, preferHostId string, skipCpuCheck *bool, skipKernelCheck *bool, enableTLS *bool, parentTaskId string) error {
+func (self *SGuest) StartGuestLiveMigrateTask(ctx context.Context, userCred mcclient.TokenCredential, guestStatus, preferHostId string, skipCpuCheck *bool, skipKernelCheck *bool, enableTLS *bool, tuning *api.GuestLiveMigrateTuning, parentTaskId string) error {
 	self.SetStatus(userCred, api.VM_START_MIGRATE, "")
 	data := jsonutils.NewDict()
 	if len(preferHostId) > 0 {
@@ -574,6 +610,9 @@ func (self *SGuest) StartGuestLiveMigrateTask(ctx context.Context, userCred mccl
 	if enableTLS != nil {
 		data.Set("enable_tls", jsonutils.NewBool(*enableTLS))
 	}
+	if tuning != nil {
+		data.Set("tuning", jsonutils.Marshal(tuning))
+	}
 	data.Set("guest_status", jsonutils.NewString(guestStatus))
 	dedicateMigrateTask := "GuestLiveMigrateTask"
 	if self.GetHypervisor() != api.HYPERVISOR_KVM {
@@ -588,6 +627,30 @@ func (self *SGuest) StartGuestLiveMigrateTask(ctx context.Context, userCred mccl
 	return nil
 }
 
+// PerformCancelLiveMigrate aborts the running live migration, the guest keeps
+// running on the source host and the guest prepared on the target host is undeployed
+func (self *SGuest) PerformCancelLiveMigrate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if self.GetHypervisor() != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewUnsupportOperationError("Not support cancel live migrate for %s", self.GetHypervisor())
+	}
+	if self.Status != api.VM_MIGRATING {
+		return nil, httperrors.NewInvalidStatusError("Cannot cancel live migrate in status %s", self.Status)
+	}
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	url := fmt.Sprintf("%s/servers/%s/cancel-live-migrate", host.ManagerUri, self.Id)
+	header := http.Header{}
+	header.Add("X-Auth-Token", userCred.GetTokenString())
+	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, jsonutils.NewDict(), false)
+	if err != nil {
+		return nil, err
+	}
+	db.OpsLog.LogEvent(self, db.ACT_MIGRATING, "cancel live migrate", userCred)
+	return nil, nil
+}
+
 func (self *SGuest) PerformClone(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	if self.IsEncrypted() {
 		return nil, httperrors.NewForbiddenError("cannot clone encrypted server")
@@ -4829,7 +4892,9 @@ func (self *SGuest) PerformInstanceSnapshot(
 			ctx, userCred, pendingUsage, pendingUsage, false)
 		return nil, httperrors.NewInternalServerError("start create snapshot task failed: %s", err)
 	}
-	return nil, nil
+	return jsonutils.Marshal(map[string]string{
+		"instance_snapshot_id": instanceSnapshot.Id,
+	}), nil
 }
 
 func (self *SGuest) PerformInstanceBackup(
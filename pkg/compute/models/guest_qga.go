@@ -0,0 +1,173 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/timeutils"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
+)
+
+func (self *SGuest) validateQgaStatus() error {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return httperrors.NewUnsupportOperationError("guest agent is not supported by %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_RUNNING {
+		return httperrors.NewInvalidStatusError("Cannot use guest agent in status %s", self.Status)
+	}
+	return nil
+}
+
+func (self *SGuest) requestQga(ctx context.Context, userCred mcclient.TokenCredential, action string, body jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	url := fmt.Sprintf("%s/servers/%s/%s", host.ManagerUri, self.Id, action)
+	header := http.Header{}
+	header.Add("X-Auth-Token", userCred.GetTokenString())
+	if body == nil {
+		body = jsonutils.NewDict()
+	}
+	_, res, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (self *SGuest) PerformQgaPing(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	data jsonutils.JSONObject,
+) (jsonutils.JSONObject, error) {
+	if err := self.validateQgaStatus(); err != nil {
+		return nil, err
+	}
+	return self.requestQga(ctx, userCred, "qga-ping", nil)
+}
+
+// PerformQgaSetPassword resets the password of the running guest by the qemu
+// guest agent without reboot
+func (self *SGuest) PerformQgaSetPassword(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input *api.ServerQgaSetPasswordInput,
+) (jsonutils.JSONObject, error) {
+	if err := self.validateQgaStatus(); err != nil {
+		return nil, err
+	}
+	if len(input.Password) == 0 {
+		return nil, httperrors.NewMissingParameterError("password")
+	}
+	if err := seclib2.ValidatePassword(input.Password); err != nil {
+		return nil, err
+	}
+	if len(input.Username) == 0 {
+		input.Username = self.GetMetadata(ctx, api.VM_METADATA_LOGIN_ACCOUNT, userCred)
+	}
+	if len(input.Username) == 0 {
+		if self.IsWindows() {
+			input.Username = api.VM_DEFAULT_WINDOWS_LOGIN_USER
+		} else {
+			input.Username = api.VM_DEFAULT_LINUX_LOGIN_USER
+		}
+	}
+	params := jsonutils.NewDict()
+	params.Set("username", jsonutils.NewString(input.Username))
+	params.Set("password", jsonutils.NewString(input.Password))
+	_, err := self.requestQga(ctx, userCred, "qga-set-password", params)
+	if err != nil {
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_RESET_PSWD, err, userCred, false)
+		return nil, err
+	}
+
+	var loginKey string
+	if len(self.KeypairId) > 0 {
+		loginKey, err = seclib2.EncryptBase64(self.GetKeypairPublicKey(), input.Password)
+	} else {
+		loginKey, err = utils.EncryptAESBase64(self.Id, input.Password)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "encrypt password")
+	}
+	self.saveOldPassword(ctx, userCred)
+	self.SetAllMetadata(ctx, map[string]interface{}{
+		api.VM_METADATA_LOGIN_ACCOUNT:       input.Username,
+		api.VM_METADATA_LOGIN_KEY:           loginKey,
+		api.VM_METADATA_LOGIN_KEY_TIMESTAMP: timeutils.UtcNow(),
+	}, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_RESET_PSWD, input.Username, userCred, true)
+	return nil, nil
+}
+
+// GetDetailsQgaGuestInfo fetches the in-guest os info, ip addresses and
+// filesystem usages, the ip addresses and usages are cached in metadata
+func (self *SGuest) GetDetailsQgaGuestInfo(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+) (*api.ServerQgaGuestInfo, error) {
+	if err := self.validateQgaStatus(); err != nil {
+		return nil, err
+	}
+	res, err := self.requestQga(ctx, userCred, "qga-guest-info", nil)
+	if err != nil {
+		return nil, err
+	}
+	info := &api.ServerQgaGuestInfo{}
+	err = res.Unmarshal(info)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	ips := []string{}
+	for _, iface := range info.Interfaces {
+		for _, addr := range iface.IpAddresses {
+			ips = append(ips, addr.Address)
+		}
+	}
+	usages := jsonutils.NewDict()
+	for _, fs := range info.Filesystems {
+		usage := jsonutils.NewDict()
+		usage.Set("used", jsonutils.NewInt(fs.UsedBytes))
+		usage.Set("total", jsonutils.NewInt(fs.TotalBytes))
+		usages.Set(fs.Mountpoint, usage)
+	}
+	err = self.SetAllMetadata(ctx, map[string]interface{}{
+		api.VM_METADATA_QGA_IP_ADDRS: strings.Join(ips, ","),
+		api.VM_METADATA_QGA_FS_USAGE: usages.String(),
+	}, userCred)
+	if err != nil {
+		log.Errorf("save guest %s qga info fail: %s", self.Name, err)
+	}
+	return info, nil
+}
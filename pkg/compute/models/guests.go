@@ -1077,6 +1077,18 @@ func (guest *SGuest) SetHostIdWithBackup(userCred mcclient.TokenCredential, mast
 	return err
 }
 
+// SetNumaNode records the host NUMA node the scheduler bound the guest
+// to, a nil node clears it
+func (guest *SGuest) SetNumaNode(ctx context.Context, userCred mcclient.TokenCredential, node *int) error {
+	if node == nil {
+		if len(guest.GetMetadata(ctx, api.VM_METADATA_NUMA_NODE, userCred)) == 0 {
+			return nil
+		}
+		return guest.RemoveMetadata(ctx, api.VM_METADATA_NUMA_NODE, userCred)
+	}
+	return guest.SetMetadata(ctx, api.VM_METADATA_NUMA_NODE, *node, userCred)
+}
+
 func (guest *SGuest) ValidateResizeDisk(disk *SDisk, storage *SStorage) error {
 	return guest.GetDriver().ValidateResizeDisk(guest, disk, storage)
 }
@@ -1603,10 +1615,15 @@ func (manager *SGuestManager) validateCreateData(
 	}
 
 	isoDevArray := input.IsolatedDevices
+	vfCount := 0
 	for idx := 0; idx < len(isoDevArray); idx += 1 { // .Contains(fmt.Sprintf("isolated_device.%d", idx)); idx += 1 {
 		if input.Backup {
 			return nil, httperrors.NewBadRequestError("Cannot create backup with isolated device")
 		}
+		if isoDevArray[idx].DevType == api.NIC_VF_TYPE && len(isoDevArray[idx].Id) == 0 && len(isoDevArray[idx].Model) == 0 {
+			vfCount += 1
+			continue
+		}
 		devConfig, err := IsolatedDeviceManager.parseDeviceInfo(userCred, isoDevArray[idx])
 		if err != nil {
 			return nil, httperrors.NewInputParameterError("parse isolated device description error %s", err)
@@ -1617,6 +1634,19 @@ func (manager *SGuestManager) validateCreateData(
 		}
 		input.IsolatedDevices[idx] = devConfig
 	}
+	// each vfio-pci nic is backed by a SR-IOV VF
+	for _, netConfig := range input.Networks {
+		if netConfig.Driver != api.NETWORK_DRIVER_VFIO {
+			continue
+		}
+		if vfCount > 0 {
+			vfCount -= 1
+			continue
+		}
+		input.IsolatedDevices = append(input.IsolatedDevices, &api.IsolatedDeviceConfig{
+			DevType: api.NIC_VF_TYPE,
+		})
+	}
 
 	keypairId := input.KeypairId
 	if len(keypairId) > 0 {
@@ -5380,6 +5410,10 @@ func (self *SGuest) ToSchedDesc() *schedapi.ScheduleInput {
 	self.FillGroupSchedDesc(config.ServerConfigs)
 	self.FillDiskSchedDesc(config.ServerConfigs)
 	self.FillNetSchedDesc(config.ServerConfigs)
+	// 已绑定 NUMA 节点的虚机迁移时仍要求目标宿主机有可容纳的节点
+	if len(self.GetMetadata(context.Background(), api.VM_METADATA_NUMA_NODE, nil)) > 0 {
+		config.NumaStrict = true
+	}
 	if len(self.HostId) > 0 && regutils.MatchUUID(self.HostId) {
 		desc.HostId = self.HostId
 	}
@@ -4062,6 +4062,9 @@ func (self *SHost) PerformPing(ctx context.Context, userCred mcclient.TokenCrede
 		}
 		self.SetMetadata(ctx, "root_partition_used_capacity_mb", input.RootPartitionUsedCapacityMb, userCred)
 		self.SetMetadata(ctx, "memory_used_mb", input.MemoryUsedMb, userCred)
+		if len(input.NumaNodes) > 0 {
+			self.SetMetadata(ctx, api.HOSTMETA_NUMA_NODES, input.NumaNodes, userCred)
+		}
 	}
 	if self.HostStatus != api.HOST_ONLINE {
 		self.PerformOnline(ctx, userCred, query, nil)
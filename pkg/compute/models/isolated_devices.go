@@ -457,10 +457,19 @@ func (manager *SIsolatedDeviceManager) attachSpecificDeviceToGuest(ctx context.C
 }
 
 func (manager *SIsolatedDeviceManager) attachHostDeviceToGuestByModel(ctx context.Context, guest *SGuest, host *SHost, devConfig *api.IsolatedDeviceConfig, userCred mcclient.TokenCredential) error {
-	if len(devConfig.Model) == 0 {
+	var devs []SIsolatedDevice
+	var err error
+	if len(devConfig.Model) != 0 {
+		devs, err = manager.findHostUnusedByModel(devConfig.Model, host.Id)
+	} else if devConfig.DevType == api.NIC_VF_TYPE {
+		// VFs of vfio-pci nics are requested by type only
+		devs, err = manager.findHostUnusedByDevType(devConfig.DevType, host.Id)
+	} else {
 		return fmt.Errorf("Not found model from info: %#v", devConfig)
 	}
-	devs, err := manager.findHostUnusedByModel(devConfig.Model, host.Id)
+	if err == nil {
+		devs, err = manager.filterCreatableMdevs(devs, host.Id)
+	}
 	if err != nil || len(devs) == 0 {
 		return fmt.Errorf("Can't found %s model on host", host.Id)
 	}
@@ -516,6 +525,44 @@ func (manager *SIsolatedDeviceManager) findHostUnusedByModel(model string, hostI
 	return devs, nil
 }
 
+// filterCreatableMdevs drops mdev slots whose parent device is already
+// partitioned by instances of another mdev type
+func (manager *SIsolatedDeviceManager) filterCreatableMdevs(devs []SIsolatedDevice, hostId string) ([]SIsolatedDevice, error) {
+	used := make([]SIsolatedDevice, 0)
+	q := manager.Query().Equals("dev_type", api.MDEV_TYPE).Equals("host_id", hostId).IsNotEmpty("guest_id")
+	if err := db.FetchModelObjects(manager, q, &used); err != nil {
+		return nil, err
+	}
+	if len(used) == 0 {
+		return devs, nil
+	}
+	parentModels := make(map[string]string)
+	for _, dev := range used {
+		parentModels[strings.Split(dev.Addr, "/")[0]] = dev.Model
+	}
+	ret := make([]SIsolatedDevice, 0, len(devs))
+	for _, dev := range devs {
+		if dev.DevType == api.MDEV_TYPE {
+			if model, ok := parentModels[strings.Split(dev.Addr, "/")[0]]; ok && model != dev.Model {
+				continue
+			}
+		}
+		ret = append(ret, dev)
+	}
+	return ret, nil
+}
+
+func (manager *SIsolatedDeviceManager) findHostUnusedByDevType(devType string, hostId string) ([]SIsolatedDevice, error) {
+	devs := make([]SIsolatedDevice, 0)
+	q := manager.findUnusedQuery()
+	q = q.Equals("dev_type", devType).Equals("host_id", hostId)
+	err := db.FetchModelObjects(manager, q, &devs)
+	if err != nil {
+		return nil, err
+	}
+	return devs, nil
+}
+
 func (manager *SIsolatedDeviceManager) ReleaseDevicesOfGuest(ctx context.Context, guest *SGuest, userCred mcclient.TokenCredential) error {
 	devs := manager.findAttachedDevicesOfGuest(guest)
 	if devs == nil {
@@ -97,6 +97,10 @@ type IRegionDriver interface {
 	RequestCreateVpc(ctx context.Context, userCred mcclient.TokenCredential, region *SCloudregion, vpc *SVpc, task taskman.ITask) error
 	RequestDeleteVpc(ctx context.Context, userCred mcclient.TokenCredential, region *SCloudregion, vpc *SVpc, task taskman.ITask) error
 
+	ValidateCreateVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, vpc *SVpc, peerVpc *SVpc, input api.VpcPeeringConnectionCreateInput) (api.VpcPeeringConnectionCreateInput, error)
+	RequestCreateVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *SVpcPeeringConnection, task taskman.ITask) error
+	RequestDeleteVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *SVpcPeeringConnection, task taskman.ITask) error
+
 	// Region Driver Snapshot Policy Apis
 	//ValidateCreateSnapshotPolicyData(context.Context, mcclient.TokenCredential, *compute.SSnapshotPolicyCreateInput, mcclient.IIdentityProvider, *jsonutils.JSONDict) error
 	RequestUpdateSnapshotPolicy(ctx context.Context, userCred mcclient.TokenCredential, sp *SSnapshotPolicy, input cloudprovider.SnapshotPolicyInput, task taskman.ITask) error
@@ -213,6 +217,9 @@ type INatGatewayDriver interface {
 	IsSupportedNatGateway() bool
 	IsSupportedNatAutoRenew() bool
 	RequestAssociateEipForNAT(ctx context.Context, userCred mcclient.TokenCredential, nat *SNatGateway, eip *SElasticip, task taskman.ITask) error
+	RequestCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, nat *SNatGateway, task taskman.ITask) error
+	RequestCreateNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, snat *SNatSEntry, task taskman.ITask) error
+	RequestCreateNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, dnat *SNatDEntry, task taskman.ITask) error
 	ValidateCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, input api.NatgatewayCreateInput) (api.NatgatewayCreateInput, error)
 	OnNatEntryDeleteComplete(ctx context.Context, userCred mcclient.TokenCredential, eip *SElasticip) error
 }
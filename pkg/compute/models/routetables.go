@@ -489,6 +489,33 @@ func (man *SRouteTableManager) insertFromCloud(ctx context.Context, userCred mcc
 	return routeTable, nil
 }
 
+func (man *SRouteTableManager) insertOnecloudRouteTable(ctx context.Context, userCred mcclient.TokenCredential, vpc *SVpc) (*SRouteTable, error) {
+	routeTable := &SRouteTable{
+		Type:   api.ROUTE_TABLE_TYPE_VPC,
+		Routes: &api.SRoutes{},
+	}
+	routeTable.VpcId = vpc.Id
+	routeTable.Status = api.ROUTE_TABLE_AVAILABLE
+	routeTable.DomainId = vpc.DomainId
+	routeTable.SetModelManager(man, routeTable)
+	err := func() error {
+		lockman.LockRawObject(ctx, man.Keyword(), "name")
+		defer lockman.ReleaseRawObject(ctx, man.Keyword(), "name")
+
+		newName, err := db.GenerateName(ctx, man, userCred, routeTableBasename("", vpc.Name))
+		if err != nil {
+			return err
+		}
+		routeTable.Name = newName
+		return man.TableSpec().Insert(ctx, routeTable)
+	}()
+	if err != nil {
+		return nil, err
+	}
+	db.OpsLog.LogEvent(routeTable, db.ACT_CREATE, routeTable.GetShortDesc(ctx), userCred)
+	return routeTable, nil
+}
+
 func (self *SRouteTable) syncRemoveCloudRouteTable(ctx context.Context, userCred mcclient.TokenCredential) error {
 	lockman.LockObject(ctx, self)
 	defer lockman.ReleaseObject(ctx, self)
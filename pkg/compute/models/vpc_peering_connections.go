@@ -21,11 +21,11 @@ import (
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
-	"yunion.io/x/pkg/util/netutils"
 	"yunion.io/x/sqlchemy"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/httperrors"
@@ -143,8 +143,19 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 	}
 	peerVpc := _peerVpc.(*SVpc)
 
+	if len(vpc.ManagerId) == 0 && len(peerVpc.ManagerId) == 0 {
+		region, err := vpc.GetRegion()
+		if err != nil {
+			return input, httperrors.NewGeneralError(errors.Wrapf(err, "vpc.GetRegion"))
+		}
+		input, err = region.GetDriver().ValidateCreateVpcPeeringConnection(ctx, userCred, vpc, peerVpc, input)
+		if err != nil {
+			return input, err
+		}
+		return input, manager.checkPeerExists(vpc, peerVpc)
+	}
 	if len(vpc.ManagerId) == 0 || len(peerVpc.ManagerId) == 0 {
-		return input, httperrors.NewInputParameterError("Only public cloud support vpcpeering")
+		return input, httperrors.NewInputParameterError("vpc peering between onecloud vpc and public cloud vpc is not supported")
 	}
 
 	// get account,providerFactory
@@ -161,31 +172,12 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 
 	// check vpc ip range overlap
 	if !factory.IsSupportVpcPeeringVpcCidrOverlap() {
-		vpcIpv4Ranges := []netutils.IPV4AddrRange{}
-		peervpcIpv4Ranges := []netutils.IPV4AddrRange{}
-		vpcCidrBlocks := strings.Split(vpc.CidrBlock, ",")
-		peervpcCidrBlocks := strings.Split(peerVpc.CidrBlock, ",")
-		for i := range vpcCidrBlocks {
-			vpcIpv4Range, err := netutils.NewIPV4Prefix(vpcCidrBlocks[i])
-			if err != nil {
-				return input, httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", vpcCidrBlocks[i]))
-			}
-			vpcIpv4Ranges = append(vpcIpv4Ranges, vpcIpv4Range.ToIPRange())
-		}
-
-		for i := range peervpcCidrBlocks {
-			peervpcIpv4Range, err := netutils.NewIPV4Prefix(peervpcCidrBlocks[i])
-			if err != nil {
-				return input, httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", peervpcCidrBlocks[i]))
-			}
-			peervpcIpv4Ranges = append(peervpcIpv4Ranges, peervpcIpv4Range.ToIPRange())
+		overlap, err := vpc.IsCidrOverlap(peerVpc)
+		if err != nil {
+			return input, httperrors.NewGeneralError(err)
 		}
-		for i := range vpcIpv4Ranges {
-			for j := range peervpcIpv4Ranges {
-				if vpcIpv4Ranges[i].IsOverlap(peervpcIpv4Ranges[j]) {
-					return input, httperrors.NewNotSupportedError("ipv4 range overlap")
-				}
-			}
+		if overlap {
+			return input, httperrors.NewNotSupportedError("ipv4 range overlap")
 		}
 	}
 
@@ -204,18 +196,21 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 		}
 	}
 
-	// existed peer check
+	return input, manager.checkPeerExists(vpc, peerVpc)
+}
+
+// existed peer check
+func (manager *SVpcPeeringConnectionManager) checkPeerExists(vpc, peerVpc *SVpc) error {
 	vpcPC := SVpcPeeringConnection{}
-	err = manager.Query().Equals("vpc_id", vpc.Id).Equals("peer_vpc_id", peerVpc.Id).First(&vpcPC)
+	err := manager.Query().Equals("vpc_id", vpc.Id).Equals("peer_vpc_id", peerVpc.Id).First(&vpcPC)
 	if err == nil {
-		return input, httperrors.NewNotSupportedError("vpc %s and vpc %s have already connected", input.VpcId, input.PeerVpcId)
+		return httperrors.NewNotSupportedError("vpc %s and vpc %s have already connected", vpc.Name, peerVpc.Name)
 	} else {
 		if errors.Cause(err) != sql.ErrNoRows {
-			return input, httperrors.NewGeneralError(err)
+			return httperrors.NewGeneralError(err)
 		}
 	}
-
-	return input, nil
+	return nil
 }
 
 func (self *SVpcPeeringConnection) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
@@ -384,6 +379,72 @@ func (self *SVpcPeeringConnection) SyncWithCloudPeerConnection(ctx context.Conte
 	return nil
 }
 
+// SyncOnecloudRoutes adds routes to cidr blocks of the other side into route
+// tables of both onecloud vpcs, or removes them when add is false
+func (self *SVpcPeeringConnection) SyncOnecloudRoutes(ctx context.Context, userCred mcclient.TokenCredential, add bool) error {
+	vpc, err := self.GetVpc()
+	if err != nil {
+		return errors.Wrapf(err, "GetVpc")
+	}
+	peerVpc, err := self.GetPeerVpc()
+	if err != nil {
+		return errors.Wrapf(err, "GetPeerVpc")
+	}
+	err = vpc.syncPeeringRoutes(ctx, userCred, self.Id, peerVpc, add)
+	if err != nil {
+		return errors.Wrapf(err, "sync routes of vpc %s", vpc.Name)
+	}
+	err = peerVpc.syncPeeringRoutes(ctx, userCred, self.Id, vpc, add)
+	if err != nil {
+		return errors.Wrapf(err, "sync routes of vpc %s", peerVpc.Name)
+	}
+	return nil
+}
+
+func (self *SVpc) syncPeeringRoutes(ctx context.Context, userCred mcclient.TokenCredential, peeringId string, peerVpc *SVpc, add bool) error {
+	lockman.LockRawObject(ctx, "route-tables", self.Id)
+	defer lockman.ReleaseRawObject(ctx, "route-tables", self.Id)
+
+	var routeTable *SRouteTable
+	routeTables := self.GetRouteTables()
+	if len(routeTables) > 0 {
+		routeTable = &routeTables[0]
+	} else if !add {
+		return nil
+	} else {
+		var err error
+		routeTable, err = RouteTableManager.insertOnecloudRouteTable(ctx, userCred, self)
+		if err != nil {
+			return errors.Wrapf(err, "insertOnecloudRouteTable")
+		}
+	}
+
+	routes := api.SRoutes{}
+	if routeTable.Routes != nil {
+		for _, route := range *routeTable.Routes {
+			if route.NextHopType == api.NEXT_HOP_TYPE_VPCPEERING && route.NextHopId == peeringId {
+				continue
+			}
+			routes = append(routes, route)
+		}
+	}
+	if add {
+		for _, cidr := range strings.Split(peerVpc.CidrBlock, ",") {
+			routes = append(routes, &api.SRoute{
+				Type:        api.ROUTE_ENTRY_TYPE_PROPAGATE,
+				Cidr:        cidr,
+				NextHopType: api.NEXT_HOP_TYPE_VPCPEERING,
+				NextHopId:   peeringId,
+			})
+		}
+	}
+	_, err := db.Update(routeTable, func() error {
+		routeTable.Routes = &routes
+		return nil
+	})
+	return err
+}
+
 func (self *SVpcPeeringConnection) GetVpc() (*SVpc, error) {
 	vpc, err := VpcManager.FetchById(self.VpcId)
 	if err != nil {
@@ -980,6 +980,39 @@ func (self *SVpc) getIPRanges() []netutils.IPV4AddrRange {
 	return ret
 }
 
+func (self *SVpc) parseIPRanges() ([]netutils.IPV4AddrRange, error) {
+	ret := []netutils.IPV4AddrRange{}
+	blocks := strings.Split(self.CidrBlock, ",")
+	for _, block := range blocks {
+		prefix, err := netutils.NewIPV4Prefix(block)
+		if err != nil {
+			return nil, errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", block)
+		}
+		ret = append(ret, prefix.ToIPRange())
+	}
+	return ret, nil
+}
+
+// IsCidrOverlap reports whether any cidr block of the vpc overlaps with that of other
+func (self *SVpc) IsCidrOverlap(other *SVpc) (bool, error) {
+	ranges, err := self.parseIPRanges()
+	if err != nil {
+		return false, err
+	}
+	otherRanges, err := other.parseIPRanges()
+	if err != nil {
+		return false, err
+	}
+	for i := range ranges {
+		for j := range otherRanges {
+			if ranges[i].IsOverlap(otherRanges[j]) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 func (self *SVpc) containsIPV4Range(a netutils.IPV4AddrRange) bool {
 	ranges := self.getIPRanges()
 	for i := range ranges {
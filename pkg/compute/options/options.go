@@ -101,6 +101,9 @@ type ComputeOptions struct {
 	DefaultMaxSnapshotCount       int `default:"9" help:"Per Disk max snapshot count, default 9"`
 	DefaultMaxManualSnapshotCount int `default:"2" help:"Per Disk max manual snapshot count, default 2"`
 
+	// backup options
+	MaxDiskBackupChainLength int `default:"16" help:"Max length of an incremental disk backup chain, a full backup is taken when exceeded, default 16"`
+
 	//snapshot policy options
 	RetentionDaysLimit  int `default:"49" help:"Days of snapshot retention, default 49 days"`
 	TimePointsLimit     int `default:"1" help:"time point of every days, default 1 point"`
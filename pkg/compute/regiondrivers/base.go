@@ -211,6 +211,18 @@ func (self *SBaseRegionDriver) RequestAssociateEipForNAT(ctx context.Context, us
 	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestAssociateEipForNAT")
 }
 
+func (self *SBaseRegionDriver) RequestCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestCreateNatGateway")
+}
+
+func (self *SBaseRegionDriver) RequestCreateNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, snat *models.SNatSEntry, task taskman.ITask) error {
+	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestCreateNatSEntry")
+}
+
+func (self *SBaseRegionDriver) RequestCreateNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, dnat *models.SNatDEntry, task taskman.ITask) error {
+	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestCreateNatDEntry")
+}
+
 func (self *SBaseRegionDriver) IsVpcCreateNeedInputCidr() bool {
 	return true
 }
@@ -223,6 +235,18 @@ func (self *SBaseRegionDriver) RequestDeleteVpc(ctx context.Context, userCred mc
 	return fmt.Errorf("Not implement RequestDeleteVpc")
 }
 
+func (self *SBaseRegionDriver) ValidateCreateVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, vpc *models.SVpc, peerVpc *models.SVpc, input api.VpcPeeringConnectionCreateInput) (api.VpcPeeringConnectionCreateInput, error) {
+	return input, errors.Wrapf(cloudprovider.ErrNotImplemented, "ValidateCreateVpcPeeringConnection")
+}
+
+func (self *SBaseRegionDriver) RequestCreateVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *models.SVpcPeeringConnection, task taskman.ITask) error {
+	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestCreateVpcPeeringConnection")
+}
+
+func (self *SBaseRegionDriver) RequestDeleteVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *models.SVpcPeeringConnection, task taskman.ITask) error {
+	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestDeleteVpcPeeringConnection")
+}
+
 func (self *SBaseRegionDriver) IsAllowSecurityGroupNameRepeat() bool {
 	return false
 }
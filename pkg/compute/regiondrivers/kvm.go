@@ -28,6 +28,7 @@ import (
 	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
 
+	billing_api "yunion.io/x/onecloud/pkg/apis/billing"
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	hostapi "yunion.io/x/onecloud/pkg/apis/host"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
@@ -1044,6 +1045,57 @@ func (self *SKVMRegionDriver) RequestDeleteVpc(ctx context.Context, userCred mcc
 	return nil
 }
 
+func (self *SKVMRegionDriver) ValidateCreateVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, vpc *models.SVpc, peerVpc *models.SVpc, input api.VpcPeeringConnectionCreateInput) (api.VpcPeeringConnectionCreateInput, error) {
+	if vpc.Id == api.DEFAULT_VPC_ID || peerVpc.Id == api.DEFAULT_VPC_ID {
+		return input, httperrors.NewNotSupportedError("default vpc cannot be peered")
+	}
+	if vpc.Id == peerVpc.Id {
+		return input, httperrors.NewInputParameterError("cannot peer vpc %s with itself", vpc.Name)
+	}
+	if vpc.CloudregionId != peerVpc.CloudregionId {
+		return input, httperrors.NewNotSupportedError("%s not supported CrossRegion vpcpeering", self.GetProvider())
+	}
+	overlap, err := vpc.IsCidrOverlap(peerVpc)
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	if overlap {
+		return input, httperrors.NewNotSupportedError("ipv4 range overlap")
+	}
+	// routes are installed on both sides, a reversed connection is a duplicate
+	cnt, err := models.VpcPeeringConnectionManager.Query().Equals("vpc_id", peerVpc.Id).Equals("peer_vpc_id", vpc.Id).CountWithError()
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return input, httperrors.NewNotSupportedError("vpc %s and vpc %s have already connected", peerVpc.Name, vpc.Name)
+	}
+	return input, nil
+}
+
+func (self *SKVMRegionDriver) RequestCreateVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *models.SVpcPeeringConnection, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		err := peer.SyncOnecloudRoutes(ctx, userCred, true)
+		if err != nil {
+			return nil, errors.Wrapf(err, "SyncOnecloudRoutes")
+		}
+		peer.SetStatus(userCred, api.VPC_PEERING_CONNECTION_STATUS_ACTIVE, "")
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SKVMRegionDriver) RequestDeleteVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *models.SVpcPeeringConnection, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		err := peer.SyncOnecloudRoutes(ctx, userCred, false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "SyncOnecloudRoutes")
+		}
+		return nil, nil
+	})
+	return nil
+}
+
 func (self *SKVMRegionDriver) GetEipDefaultChargeType() string {
 	return api.EIP_CHARGE_TYPE_BY_BANDWIDTH
 }
@@ -1530,7 +1582,11 @@ func (self *SKVMRegionDriver) RequestPackInstanceBackup(ctx context.Context, ib
 	body := jsonutils.NewDict()
 	body.Set("package_name", jsonutils.NewString(packageName))
 	body.Set("backup_storage_id", jsonutils.NewString(backupStorage.GetId()))
-	body.Set("backup_storage_access_info", jsonutils.Marshal(backupStorage.AccessInfo))
+	accessInfo, err := backupStorage.GetAccessInfo()
+	if err != nil {
+		return errors.Wrap(err, "unable to get backup storage access info")
+	}
+	body.Set("backup_storage_access_info", accessInfo)
 	body.Set("backup_ids", jsonutils.Marshal(backupIds))
 	body.Set("metadata", jsonutils.Marshal(metadata))
 	header := task.GetTaskRequestHeader()
@@ -1556,7 +1612,11 @@ func (self *SKVMRegionDriver) RequestUnpackInstanceBackup(ctx context.Context, i
 	body := jsonutils.NewDict()
 	body.Set("package_name", jsonutils.NewString(packageName))
 	body.Set("backup_storage_id", jsonutils.NewString(backupStorage.GetId()))
-	body.Set("backup_storage_access_info", jsonutils.Marshal(backupStorage.AccessInfo))
+	accessInfo, err := backupStorage.GetAccessInfo()
+	if err != nil {
+		return errors.Wrap(err, "unable to get backup storage access info")
+	}
+	body.Set("backup_storage_access_info", accessInfo)
 	if metadataOnly {
 		body.Set("metadata_only", jsonutils.JSONTrue)
 	}
@@ -1577,7 +1637,11 @@ func (self *SKVMRegionDriver) RequestSyncBackupStorageStatus(ctx context.Context
 		url := fmt.Sprintf("%s/storages/sync-backup-storage", host.ManagerUri)
 		body := jsonutils.NewDict()
 		body.Set("backup_storage_id", jsonutils.NewString(bs.GetId()))
-		body.Set("backup_storage_access_info", jsonutils.Marshal(bs.AccessInfo))
+		accessInfo, err := bs.GetAccessInfo()
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get backup storage access info")
+		}
+		body.Set("backup_storage_access_info", accessInfo)
 		header := task.GetTaskRequestHeader()
 		_, res, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
 		if err != nil {
@@ -1654,7 +1718,11 @@ func (self *SKVMRegionDriver) RequestSyncDiskBackupStatus(ctx context.Context, u
 		body := jsonutils.NewDict()
 		body.Set("backup_id", jsonutils.NewString(backup.GetId()))
 		body.Set("backup_storage_id", jsonutils.NewString(backupStroage.GetId()))
-		body.Set("backup_storage_access_info", jsonutils.Marshal(backupStroage.AccessInfo))
+		accessInfo, err := backupStroage.GetAccessInfo()
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get backup storage access info")
+		}
+		body.Set("backup_storage_access_info", accessInfo)
 		header := task.GetTaskRequestHeader()
 		_, res, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
 		if err != nil {
@@ -1702,8 +1770,70 @@ func (self *SKVMRegionDriver) RequestSyncSnapshotStatus(ctx context.Context, use
 	return nil
 }
 
+func (self *SKVMRegionDriver) IsSupportedNatGateway() bool {
+	return true
+}
+
+func (self *SKVMRegionDriver) IsSupportedNatAutoRenew() bool {
+	return false
+}
+
+func (self *SKVMRegionDriver) ValidateCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, input api.NatgatewayCreateInput) (api.NatgatewayCreateInput, error) {
+	if input.VpcId == api.DEFAULT_VPC_ID {
+		return input, httperrors.NewUnsupportOperationError("nat gateway is not supported in default vpc")
+	}
+	_vpc, err := models.VpcManager.FetchById(input.VpcId)
+	if err != nil {
+		return input, httperrors.NewGeneralError(errors.Wrapf(err, "VpcManager.FetchById(%s)", input.VpcId))
+	}
+	vpc := _vpc.(*models.SVpc)
+	// translated traffic goes out through the eip gateway of the vpc
+	switch vpc.ExternalAccessMode {
+	case api.VPC_EXTERNAL_ACCESS_MODE_EIP, api.VPC_EXTERNAL_ACCESS_MODE_EIP_DISTGW:
+	default:
+		return input, httperrors.NewInputParameterError("vpc %s external access mode %q does not support eip", vpc.Name, vpc.ExternalAccessMode)
+	}
+	if input.BillingType == billing_api.BILLING_TYPE_PREPAID {
+		return input, httperrors.NewInputParameterError("prepaid nat gateway is not supported")
+	}
+	return input, nil
+}
+
+func (self *SKVMRegionDriver) RequestCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		// the rules are rendered by vpcagent, nothing to create here
+		return nil, nat.SetStatus(userCred, api.NAT_STAUTS_AVAILABLE, "")
+	})
+	return nil
+}
+
+func (self *SKVMRegionDriver) RequestCreateNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, snat *models.SNatSEntry, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SKVMRegionDriver) RequestCreateNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, dnat *models.SNatDEntry, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SKVMRegionDriver) RequestSyncNatGatewayStatus(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nat.SetStatus(userCred, api.NAT_STAUTS_AVAILABLE, "syncstatus")
+	})
+	return nil
+}
+
 func (self *SKVMRegionDriver) RequestAssociateEipForNAT(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, eip *models.SElasticip, task taskman.ITask) error {
-	return errors.Wrapf(cloudprovider.ErrNotSupported, "RequestAssociateEipForNAT")
+	opts := api.ElasticipAssociateInput{
+		InstanceType: api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY,
+		InstanceId:   nat.Id,
+	}
+	return eip.StartEipAssociateTask(ctx, userCred, jsonutils.Marshal(opts).(*jsonutils.JSONDict), task.GetTaskId())
 }
 
 func (self *SKVMRegionDriver) RequestPreSnapshotPolicyApply(ctx context.Context, userCred mcclient.
@@ -1880,7 +2010,28 @@ func (self *SKVMRegionDriver) RequestDeleteBackup(ctx context.Context, backup *m
 	body := jsonutils.NewDict()
 	body.Set("backup_id", jsonutils.NewString(backup.GetId()))
 	body.Set("backup_storage_id", jsonutils.NewString(backupStroage.GetId()))
-	body.Set("backup_storage_access_info", jsonutils.Marshal(backupStroage.AccessInfo))
+	accessInfo, err := backupStroage.GetAccessInfo()
+	if err != nil {
+		return errors.Wrap(err, "unable to get backup storage access info")
+	}
+	body.Set("backup_storage_access_info", accessInfo)
+	children, err := backup.GetChildBackups()
+	if err != nil {
+		return errors.Wrap(err, "unable to get child backups")
+	}
+	if len(children) > 0 {
+		// incremental backups depending on the backup absorb its data
+		chain, err := backup.GetParentBackupChain()
+		if err != nil {
+			return errors.Wrap(err, "unable to get backup chain")
+		}
+		childIds := make([]string, len(children))
+		for i := range children {
+			childIds[i] = children[i].Id
+		}
+		body.Set("parent_backup_chain", jsonutils.NewStringArray(chain))
+		body.Set("child_backup_ids", jsonutils.NewStringArray(childIds))
+	}
 	header := task.GetTaskRequestHeader()
 	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
 	if err != nil {
@@ -1909,10 +2060,25 @@ func (self *SKVMRegionDriver) RequestCreateBackup(ctx context.Context, backup *m
 	host, _ := guest.GetHost()
 	url := fmt.Sprintf("%s/disks/%s/backup/%s", host.ManagerUri, storage.Id, disk.Id)
 	body := jsonutils.NewDict()
-	body.Set("snapshot_id", jsonutils.NewString(snapshotId))
+	if len(snapshotId) > 0 {
+		body.Set("snapshot_id", jsonutils.NewString(snapshotId))
+	} else {
+		body.Set("live", jsonutils.JSONTrue)
+	}
 	body.Set("backup_id", jsonutils.NewString(backup.GetId()))
 	body.Set("backup_storage_id", jsonutils.NewString(backupStroage.GetId()))
-	body.Set("backup_storage_access_info", jsonutils.Marshal(backupStroage.AccessInfo))
+	accessInfo, err := backupStroage.GetAccessInfo()
+	if err != nil {
+		return errors.Wrap(err, "unable to get backup storage access info")
+	}
+	body.Set("backup_storage_access_info", accessInfo)
+	if len(backup.ParentBackupId) > 0 {
+		chain, err := backup.GetParentBackupChain()
+		if err != nil {
+			return errors.Wrap(err, "unable to get backup chain")
+		}
+		body.Set("parent_backup_chain", jsonutils.NewStringArray(chain))
+	}
 	if len(backup.EncryptKeyId) > 0 {
 		body.Set("encrypt_key_id", jsonutils.NewString(backup.EncryptKeyId))
 	}
@@ -1942,6 +2108,11 @@ func (self *SKVMRegionDriver) RequestAssociateEip(ctx context.Context, userCred
 			if err != nil {
 				return nil, err
 			}
+		case api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY:
+			nat := obj.(*models.SNatGateway)
+			if nat.VpcId == api.DEFAULT_VPC_ID {
+				return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "nat gateway %s in default vpc", nat.Name)
+			}
 		default:
 			return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "instance type %s", input.InstanceType)
 		}
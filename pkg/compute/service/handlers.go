@@ -84,6 +84,10 @@ func InitHandlers(app *appsrv.Application) {
 		models.InfrasUsageManager,
 		models.InfrasPendingUsageManager,
 
+		quotas.QuotaAlertManager,
+		quotas.QuotaUsageHistoryManager,
+		quotas.QuotaChangeRequestManager,
+
 		models.CloudproviderCapabilityManager,
 
 		models.ScalingTimerManager,
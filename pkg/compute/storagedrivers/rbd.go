@@ -188,6 +188,13 @@ func (self *SRbdStorageDriver) RequestCreateSnapshot(ctx context.Context, snapsh
 	if err != nil {
 		return errors.Wrapf(err, "storage.GetMasterHost")
 	}
+	// the host running the guest freezes its filesystems before snapshot
+	if guest := disk.GetGuest(); guest != nil && guest.Status == api.VM_RUNNING {
+		guestHost, err := guest.GetHost()
+		if err == nil {
+			host = guestHost
+		}
+	}
 	url := fmt.Sprintf("%s/disks/%s/snapshot/%s", host.ManagerUri, storage.Id, disk.Id)
 	header := task.GetTaskRequestHeader()
 	params := jsonutils.NewDict()
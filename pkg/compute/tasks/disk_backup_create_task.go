@@ -75,6 +75,12 @@ func (self *DiskBackupCreateTask) OnInit(ctx context.Context, obj db.IStandalone
 		self.OnSnapshot(ctx, backup, nil)
 		return
 	}
+	if !self.Params.Contains("only_snapshot") && backup.IsLiveBackupSupported(self.UserCred) {
+		// the host backs up the disk of the running guest directly
+		self.Params.Set("live", jsonutils.JSONTrue)
+		self.OnSnapshot(ctx, backup, nil)
+		return
+	}
 	backup.SetStatus(self.UserCred, api.BACKUP_STATUS_SNAPSHOT, "")
 	snapshot, err := self.CreateSnapshot(ctx, backup)
 	if err != nil {
@@ -119,6 +125,16 @@ func (self *DiskBackupCreateTask) OnSnapshotFailed(ctx context.Context, backup *
 }
 
 func (self *DiskBackupCreateTask) OnSave(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	log.Infof("data from RequestCreateBackup: %s", data)
+	sizeMb, _ := data.Int("size_mb")
+	db.Update(backup, func() error {
+		backup.SizeMb = int(sizeMb)
+		return nil
+	})
+	if jsonutils.QueryBoolean(self.Params, "live", false) {
+		self.taksSuccess(ctx, backup, nil)
+		return
+	}
 	// cleanup snapshot
 	snapshotId, _ := self.Params.GetString("snapshot_id")
 	self.SetStage("OnCleanupSnapshot", nil)
@@ -127,12 +143,6 @@ func (self *DiskBackupCreateTask) OnSave(ctx context.Context, backup *models.SDi
 		self.taskFailed(ctx, backup, jsonutils.NewString(err.Error()), api.BACKUP_STATUS_CLEANUP_SNAPSHOT_FAILED)
 		return
 	}
-	log.Infof("data from RequestCreateBackup: %s", data)
-	sizeMb, _ := data.Int("size_mb")
-	db.Update(backup, func() error {
-		backup.SizeMb = int(sizeMb)
-		return nil
-	})
 	snapshot := snapshotModel.(*models.SSnapshot)
 	err = snapshot.StartSnapshotDeleteTask(ctx, self.UserCred, false, self.GetId())
 	if err != nil {
@@ -142,6 +152,10 @@ func (self *DiskBackupCreateTask) OnSave(ctx context.Context, backup *models.SDi
 }
 
 func (self *DiskBackupCreateTask) OnSaveFailed(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	if jsonutils.QueryBoolean(self.Params, "live", false) {
+		self.taskFailed(ctx, backup, data, api.BACKUP_STATUS_SAVE_FAILED)
+		return
+	}
 	snapshotId, _ := self.Params.GetString("snapshot_id")
 	snapshotModel, err := models.SnapshotManager.FetchById(snapshotId)
 	if err != nil {
@@ -16,6 +16,7 @@ package tasks
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"yunion.io/x/jsonutils"
@@ -62,6 +63,15 @@ func (self *DiskBackupDeleteTask) OnInit(ctx context.Context, obj db.IStandalone
 }
 
 func (self *DiskBackupDeleteTask) OnDelete(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	// the data of the backup has been merged into its incremental backups
+	sizes := make(map[string]int)
+	if data != nil && data.Contains("child_size_mbs") {
+		data.Unmarshal(&sizes, "child_size_mbs")
+	}
+	if err := backup.ReparentChildren(sizes); err != nil {
+		self.taskFailed(ctx, backup, jsonutils.NewString(err.Error()))
+		return
+	}
 	self.taskSuccess(ctx, backup, nil)
 }
 
@@ -70,10 +80,23 @@ func (self *DiskBackupDeleteTask) OnDeleteFailed(ctx context.Context, backup *mo
 	log.Infof("OnDeleteFailed data: %s", data)
 	if forceDelete := jsonutils.QueryBoolean(self.Params, "force_delete", false); !forceDelete {
 		self.taskFailed(ctx, backup, data)
+		return
 	}
 	reason, _ := data.GetString("__reason__")
 	if !strings.Contains(reason, api.BackupStorageOffline) {
 		self.taskFailed(ctx, backup, data)
+		return
+	}
+	// incremental backups can not absorb the data of the backup while the
+	// backup storage is offline, they would be left without a base
+	children, err := backup.GetChildBackups()
+	if err != nil {
+		self.taskFailed(ctx, backup, jsonutils.NewString(err.Error()))
+		return
+	}
+	if len(children) > 0 {
+		self.taskFailed(ctx, backup, jsonutils.NewString(fmt.Sprintf("backup has %d incremental backups, can't force delete it while backup storage is offline", len(children))))
+		return
 	}
 	log.Infof("delete backup %s failed, force delete", backup.GetId())
 	self.taskSuccess(ctx, backup, nil)
@@ -0,0 +1,74 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type ElasticcacheStopTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(ElasticcacheStopTask{})
+}
+
+func (self *ElasticcacheStopTask) taskFail(ctx context.Context, ec *models.SElasticcache, err error) {
+	ec.SetStatus(self.GetUserCred(), api.ELASTIC_CACHE_STATUS_STOP_FAILED, err.Error())
+	db.OpsLog.LogEvent(ec, db.ACT_STOP_FAIL, err, self.UserCred)
+	logclient.AddActionLogWithStartable(self, ec, logclient.ACT_VM_STOP, err, self.UserCred, false)
+	notifyclient.NotifySystemErrorWithCtx(ctx, ec.Id, ec.Name, api.ELASTIC_CACHE_STATUS_STOP_FAILED, err.Error())
+	self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+}
+
+func (self *ElasticcacheStopTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	ec := obj.(*models.SElasticcache)
+	iregion, err := ec.GetIRegion(ctx)
+	if err != nil {
+		self.taskFail(ctx, ec, errors.Wrap(err, "GetIRegion"))
+		return
+	}
+	iec, err := iregion.GetIElasticcacheById(ec.ExternalId)
+	if err != nil {
+		self.taskFail(ctx, ec, errors.Wrapf(err, "GetIElasticcacheById(%s)", ec.ExternalId))
+		return
+	}
+	err = iec.Stop()
+	if err != nil {
+		self.taskFail(ctx, ec, errors.Wrap(err, "Stop"))
+		return
+	}
+	err = cloudprovider.WaitStatusWithDelay(iec, api.ELASTIC_CACHE_STATUS_STOPPED, 10*time.Second, 10*time.Second, 1800*time.Second)
+	if err != nil {
+		self.taskFail(ctx, ec, errors.Wrap(err, "WaitStatusWithDelay"))
+		return
+	}
+	ec.SetStatus(self.GetUserCred(), api.ELASTIC_CACHE_STATUS_STOPPED, "")
+	logclient.AddActionLogWithStartable(self, ec, logclient.ACT_VM_STOP, nil, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
@@ -140,6 +140,10 @@ func (self *GuestBatchCreateTask) allocateGuestOnHost(ctx context.Context, guest
 
 	host, _ := guest.GetHost()
 
+	if err := guest.SetNumaNode(ctx, self.UserCred, candidate.NumaNode); err != nil {
+		log.Errorf("guest %s SetNumaNode fail %s", guest.Name, err)
+	}
+
 	quotaCpuMem := models.SQuota{Count: 1, Cpu: int(guest.VcpuCount), Memory: guest.VmemSize}
 	keys, err := guest.GetQuotaKeys()
 	if err != nil {
@@ -17,8 +17,10 @@ package tasks
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/utils"
 
@@ -105,6 +107,10 @@ func (self *GuestMigrateTask) SaveScheduleResult(ctx context.Context, obj ISched
 
 	body := jsonutils.NewDict()
 	body.Set("target_host_id", jsonutils.NewString(targetHostId))
+	// numa_node metadata is only switched after the guest lands on target host
+	if target.NumaNode != nil {
+		body.Set("target_numa_node", jsonutils.NewInt(int64(*target.NumaNode)))
+	}
 	// for params notes
 	body.Set("target_host_name", jsonutils.NewString(targetHost.Name))
 	srcHost := models.HostManager.FetchHostById(guest.HostId)
@@ -242,6 +248,9 @@ func (self *GuestMigrateTask) OnSrcPrepareComplete(ctx context.Context, guest *m
 	guestStatus, _ := self.Params.GetString("guest_status")
 	if !self.isRescueMode() && (guestStatus == api.VM_RUNNING || guestStatus == api.VM_SUSPEND) {
 		body.Set("live_migrate", jsonutils.JSONTrue)
+		if tuning, _ := self.Params.Get("tuning"); tuning != nil {
+			body.Set("tuning", tuning)
+		}
 	}
 
 	headers := self.GetTaskRequestHeader()
@@ -384,6 +393,7 @@ func (self *GuestMigrateTask) sharedStorageMigrateConf(ctx context.Context, gues
 	body.Set("qemu_version", jsonutils.NewString(guest.GetQemuVersion(self.UserCred)))
 	body.Set("qemu_cmdline", jsonutils.NewString(guest.GetQemuCmdline(self.UserCred)))
 	targetDesc := guest.GetJsonDescAtHypervisor(ctx, targetHost)
+	self.setTargetNumaNode(targetDesc)
 	body.Set("desc", jsonutils.Marshal(targetDesc))
 
 	sourceHost, _ := guest.GetHost()
@@ -438,6 +448,7 @@ func (self *GuestMigrateTask) localStorageMigrateConf(ctx context.Context,
 		}
 		targetDesc.Disks[i].TargetStorageId = targetStorageId
 	}
+	self.setTargetNumaNode(targetDesc)
 
 	body.Set("desc", jsonutils.Marshal(targetDesc))
 	body.Set("rebase_disks", jsonutils.JSONTrue)
@@ -461,6 +472,9 @@ func (self *GuestLiveMigrateTask) OnStartDestComplete(ctx context.Context, guest
 	body.Set("live_migrate_dest_port", liveMigrateDestPort)
 	body.Set("dest_ip", jsonutils.NewString(targetHost.AccessIp))
 	body.Set("enable_tls", jsonutils.NewBool(jsonutils.QueryBoolean(self.GetParams(), "enable_tls", false)))
+	if tuning, _ := self.Params.Get("tuning"); tuning != nil {
+		body.Set("tuning", tuning)
+	}
 
 	headers := self.GetTaskRequestHeader()
 
@@ -507,12 +521,49 @@ func (self *GuestMigrateTask) setGuest(ctx context.Context, guest *models.SGuest
 	if err != nil {
 		return err
 	}
+	if err := guest.SetNumaNode(ctx, self.UserCred, self.getTargetNumaNode()); err != nil {
+		log.Errorf("guest %s SetNumaNode fail %s", guest.Name, err)
+	}
 	return nil
 }
 
+func (self *GuestMigrateTask) getTargetNumaNode() *int {
+	if !self.Params.Contains("target_numa_node") {
+		return nil
+	}
+	node, err := self.Params.Int("target_numa_node")
+	if err != nil {
+		return nil
+	}
+	ret := int(node)
+	return &ret
+}
+
+// setTargetNumaNode binds the guest started on target host to the scheduled
+// NUMA node without touching the metadata of the running guest
+func (self *GuestMigrateTask) setTargetNumaNode(desc *api.GuestJsonDesc) {
+	if desc.Metadata == nil {
+		desc.Metadata = map[string]string{}
+	}
+	if node := self.getTargetNumaNode(); node != nil {
+		desc.Metadata[api.VM_METADATA_NUMA_NODE] = fmt.Sprintf("%d", *node)
+	} else {
+		delete(desc.Metadata, api.VM_METADATA_NUMA_NODE)
+	}
+}
+
 func (self *GuestLiveMigrateTask) OnLiveMigrateCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
 	targetHostId, _ := self.Params.GetString("target_host_id")
 	guest.StartUndeployGuestTask(ctx, self.UserCred, "", targetHostId)
+	if strings.Contains(data.String(), api.LIVE_MIGRATE_CANCELLED) {
+		// guest keeps running on source host after cancelled by user
+		guestStatus, _ := self.Params.GetString("guest_status")
+		guest.SetStatus(self.UserCred, guestStatus, api.LIVE_MIGRATE_CANCELLED)
+		db.OpsLog.LogEvent(guest, db.ACT_MIGRATE_FAIL, data, self.UserCred)
+		logclient.AddActionLogWithContext(ctx, guest, logclient.ACT_MIGRATE, data, self.UserCred, false)
+		self.SetStageFailed(ctx, data)
+		return
+	}
 	self.TaskFailed(ctx, guest, data)
 }
 
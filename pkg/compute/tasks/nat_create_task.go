@@ -16,19 +16,15 @@ package tasks
 
 import (
 	"context"
-	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
 
-	billing_api "yunion.io/x/onecloud/pkg/apis/billing"
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
-	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/models"
-	"yunion.io/x/onecloud/pkg/util/billing"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 )
 
@@ -49,64 +45,13 @@ func (self *NatGatewayCreateTask) taskFailed(ctx context.Context, nat *models.SN
 func (self *NatGatewayCreateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
 	nat := obj.(*models.SNatGateway)
 
-	opts := cloudprovider.NatGatewayCreateOptions{
-		Name:    nat.Name,
-		Desc:    nat.Description,
-		NatSpec: nat.NatSpec,
-	}
-
-	vpc, err := nat.GetVpc()
+	self.SetStage("OnCreateNatGatewayCreateComplete", nil)
+	region, _ := nat.GetRegion()
+	err := region.GetDriver().RequestCreateNatGateway(ctx, self.GetUserCred(), nat, self)
 	if err != nil {
-		self.taskFailed(ctx, nat, errors.Wrapf(err, "nat.GetVpc"))
+		self.taskFailed(ctx, nat, errors.Wrapf(err, "RequestCreateNatGateway"))
 		return
 	}
-
-	opts.VpcId = vpc.ExternalId
-
-	if len(nat.NetworkId) > 0 {
-		_network, err := models.NetworkManager.FetchById(nat.NetworkId)
-		if err != nil {
-			self.taskFailed(ctx, nat, errors.Wrapf(err, "NetworkManager.FetchById(%s)", nat.NetworkId))
-			return
-		}
-		network := _network.(*models.SNetwork)
-		opts.NetworkId = network.ExternalId
-	}
-
-	if nat.BillingType == billing_api.BILLING_TYPE_PREPAID {
-		bc, err := billing.ParseBillingCycle(nat.BillingCycle)
-		if err != nil {
-			self.taskFailed(ctx, nat, errors.Wrapf(err, "ParseBillingCycle(%s)", nat.BillingCycle))
-			return
-		}
-		bc.AutoRenew = nat.AutoRenew
-		opts.BillingCycle = &bc
-	}
-
-	self.SetStage("OnCreateNatGatewayCreateComplete", nil)
-	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
-		iVpc, err := vpc.GetIVpc(ctx)
-		if err != nil {
-			return nil, errors.Wrapf(err, "vpc.GetIVpc")
-		}
-
-		iNat, err := iVpc.CreateINatGateway(&opts)
-		if err != nil {
-			return nil, errors.Wrapf(err, "iVpc.CreateINatGateway")
-		}
-		err = db.SetExternalId(nat, self.GetUserCred(), iNat.GetGlobalId())
-		if err != nil {
-			return nil, errors.Wrapf(err, "db.SetExternalId")
-		}
-
-		err = cloudprovider.WaitStatus(iNat, api.NAT_STAUTS_AVAILABLE, time.Second*5, time.Minute*10)
-		if err != nil {
-			return nil, errors.Wrapf(err, "cloudprovider.WaitStatus")
-		}
-
-		nat.SyncWithCloudNatGateway(ctx, self.GetUserCred(), nat.GetCloudprovider(), iNat)
-		return nil, nil
-	})
 }
 
 func (self *NatGatewayCreateTask) OnCreateNatGatewayCreateComplete(ctx context.Context, nat *models.SNatGateway, body jsonutils.JSONObject) {
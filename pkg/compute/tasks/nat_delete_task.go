@@ -47,6 +47,11 @@ func (self *NatGatewayDeleteTask) taskFailed(ctx context.Context, nat *models.SN
 func (self *NatGatewayDeleteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
 	nat := obj.(*models.SNatGateway)
 
+	if len(nat.ExternalId) == 0 {
+		self.OnEipDissociateComplete(ctx, nat, nil)
+		return
+	}
+
 	iNat, err := nat.GetINatGateway(ctx)
 	if err != nil {
 		if errors.Cause(err) == cloudprovider.ErrNotFound {
@@ -106,6 +111,11 @@ func (self *NatGatewayDeleteTask) OnEipDissociateCompleteFailed(ctx context.Cont
 }
 
 func (self *NatGatewayDeleteTask) doDeleteNatGateway(ctx context.Context, nat *models.SNatGateway) {
+	if len(nat.ExternalId) == 0 {
+		self.taskComplete(ctx, nat)
+		return
+	}
+
 	iNat, err := nat.GetINatGateway(ctx)
 	if err != nil {
 		if errors.Cause(err) == cloudprovider.ErrNotFound {
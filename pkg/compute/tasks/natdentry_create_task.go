@@ -16,7 +16,6 @@ package tasks
 
 import (
 	"context"
-	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
@@ -24,7 +23,6 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
-	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 )
@@ -87,42 +85,24 @@ func (self *SNatDEntryCreateTask) OnAssociateEipComplete(ctx context.Context, dn
 		self.taskFailed(ctx, dnat, errors.Wrapf(err, "dnat.GetNatgateway"))
 		return
 	}
-	iNat, err := nat.GetINatGateway(ctx)
-	if err != nil {
-		self.taskFailed(ctx, dnat, errors.Wrapf(err, "nat.GetINatGateway"))
-		return
-	}
 
-	eip, err := dnat.GetEip()
-	if err != nil {
-		self.taskFailed(ctx, dnat, errors.Wrapf(err, "dnat.GetEip"))
-		return
-	}
-
-	// construct a DNat RUle
-	rule := cloudprovider.SNatDRule{
-		Protocol:     dnat.IpProtocol,
-		InternalIP:   dnat.InternalIP,
-		InternalPort: dnat.InternalPort,
-		ExternalIP:   dnat.ExternalIP,
-		ExternalPort: dnat.ExternalPort,
-		ExternalIPID: eip.ExternalId,
-	}
-	iDnat, err := iNat.CreateINatDEntry(rule)
+	self.SetStage("OnCreateNatDEntryComplete", nil)
+	region, _ := nat.GetRegion()
+	err = region.GetDriver().RequestCreateNatDEntry(ctx, self.GetUserCred(), dnat, self)
 	if err != nil {
-		self.taskFailed(ctx, dnat, errors.Wrapf(err, "iNat.CreateINatDEntry"))
+		self.taskFailed(ctx, dnat, errors.Wrapf(err, "RequestCreateNatDEntry"))
 		return
 	}
+}
 
-	err = db.SetExternalId(dnat, self.UserCred, iDnat.GetGlobalId())
-	if err != nil {
-		self.taskFailed(ctx, dnat, errors.Wrapf(err, "db.SetExternalId(%s)", iDnat.GetGlobalId()))
-		return
-	}
+func (self *SNatDEntryCreateTask) OnCreateNatDEntryCompleteFailed(ctx context.Context, dnat *models.SNatDEntry, reason jsonutils.JSONObject) {
+	self.taskFailed(ctx, dnat, errors.Errorf(reason.String()))
+}
 
-	err = cloudprovider.WaitStatus(iDnat, api.NAT_STAUTS_AVAILABLE, 10*time.Second, 5*time.Minute)
+func (self *SNatDEntryCreateTask) OnCreateNatDEntryComplete(ctx context.Context, dnat *models.SNatDEntry, body jsonutils.JSONObject) {
+	nat, err := dnat.GetNatgateway()
 	if err != nil {
-		self.taskFailed(ctx, dnat, errors.Wrapf(err, "cloudprovider.WaitStatus"))
+		self.taskFailed(ctx, dnat, errors.Wrapf(err, "dnat.GetNatgateway"))
 		return
 	}
 
@@ -16,7 +16,6 @@ package tasks
 
 import (
 	"context"
-	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
@@ -24,7 +23,6 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
-	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 )
@@ -87,48 +85,24 @@ func (self *SNatSEntryCreateTask) OnAssociateEipComplete(ctx context.Context, sn
 		self.taskFailed(ctx, snat, errors.Wrapf(err, "snat.GetNatgateway"))
 		return
 	}
-	iNat, err := nat.GetINatGateway(ctx)
-	if err != nil {
-		self.taskFailed(ctx, snat, errors.Wrapf(err, "nat.GetINatGateway"))
-		return
-	}
-
-	eip, err := snat.GetEip()
-	if err != nil {
-		self.taskFailed(ctx, snat, errors.Wrapf(err, "snat.GetEip"))
-		return
-	}
 
-	// construct a DNat RUle
-	rule := cloudprovider.SNatSRule{
-		ExternalIP:   snat.IP,
-		ExternalIPID: eip.ExternalId,
-	}
-	if len(snat.SourceCIDR) > 0 {
-		rule.SourceCIDR = snat.SourceCIDR
-	} else {
-		network, err := snat.GetNetwork()
-		if err != nil {
-			self.taskFailed(ctx, snat, errors.Wrapf(err, "snat.GetNetwork"))
-			return
-		}
-		rule.NetworkID = network.ExternalId
-	}
-	iSnat, err := iNat.CreateINatSEntry(rule)
+	self.SetStage("OnCreateNatSEntryComplete", nil)
+	region, _ := nat.GetRegion()
+	err = region.GetDriver().RequestCreateNatSEntry(ctx, self.GetUserCred(), snat, self)
 	if err != nil {
-		self.taskFailed(ctx, snat, errors.Wrapf(err, "CreateINatSEntry"))
+		self.taskFailed(ctx, snat, errors.Wrapf(err, "RequestCreateNatSEntry"))
 		return
 	}
+}
 
-	err = db.SetExternalId(snat, self.UserCred, iSnat.GetGlobalId())
-	if err != nil {
-		self.taskFailed(ctx, snat, errors.Wrapf(err, "db.SetExternalId(%s)", iSnat.GetGlobalId()))
-		return
-	}
+func (self *SNatSEntryCreateTask) OnCreateNatSEntryCompleteFailed(ctx context.Context, snat *models.SNatSEntry, reason jsonutils.JSONObject) {
+	self.taskFailed(ctx, snat, errors.Errorf(reason.String()))
+}
 
-	err = cloudprovider.WaitStatus(iSnat, api.NAT_STAUTS_AVAILABLE, 10*time.Second, 5*time.Minute)
+func (self *SNatSEntryCreateTask) OnCreateNatSEntryComplete(ctx context.Context, snat *models.SNatSEntry, body jsonutils.JSONObject) {
+	nat, err := snat.GetNatgateway()
 	if err != nil {
-		self.taskFailed(ctx, snat, errors.Wrapf(err, "cloudprovider.WaitStatus(iSnat)"))
+		self.taskFailed(ctx, snat, errors.Wrapf(err, "snat.GetNatgateway"))
 		return
 	}
 
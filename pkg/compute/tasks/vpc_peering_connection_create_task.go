@@ -23,7 +23,6 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
-	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 )
@@ -51,53 +50,28 @@ func (self *VpcPeeringConnectionCreateTask) OnInit(ctx context.Context, obj db.I
 		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetVpc"))
 		return
 	}
-
-	peerVpc, err := peer.GetPeerVpc()
-	if err != nil {
-		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetPeerVpc"))
-		return
-	}
-
-	iVpc, err := vpc.GetIVpc(ctx)
+	region, err := vpc.GetRegion()
 	if err != nil {
-		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetIVpc"))
+		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetRegion"))
 		return
 	}
 
-	iPeerVpc, err := peerVpc.GetIVpc(ctx)
-	if err != nil {
-		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetIVpc"))
-		return
-	}
-
-	opts := &cloudprovider.VpcPeeringConnectionCreateOptions{
-		Name:          peer.Name,
-		Desc:          peer.Description,
-		Bandwidth:     peer.Bandwidth,
-		PeerVpcId:     iPeerVpc.GetId(),
-		PeerRegionId:  iPeerVpc.GetRegion().GetId(),
-		PeerAccountId: iPeerVpc.GetAuthorityOwnerId(),
-	}
-	iPeerConnection, err := iVpc.CreateICloudVpcPeeringConnection(opts)
+	self.SetStage("OnCreateVpcPeeringConnectionComplete", nil)
+	err = region.GetDriver().RequestCreateVpcPeeringConnection(ctx, self.GetUserCred(), peer, self)
 	if err != nil {
-		self.taskFailed(ctx, peer, errors.Wrapf(err, "CreateICloudVpcPeeringConnection"))
-		return
-	}
-	err = iPeerVpc.AcceptICloudVpcPeeringConnection(iPeerConnection.GetGlobalId())
-	if err != nil {
-		self.taskFailed(ctx, peer, errors.Wrapf(err, "AcceptICloudVpcPeeringConnection"))
+		self.taskFailed(ctx, peer, errors.Wrapf(err, "RequestCreateVpcPeeringConnection"))
 		return
 	}
+}
 
-	iPeerConnection.Refresh()
-	err = peer.SyncWithCloudPeerConnection(ctx, self.GetUserCred(), iPeerConnection, nil)
-	if err != nil {
-		self.taskFailed(ctx, peer, errors.Wrapf(err, "SyncWithCloudPeerConnection"))
-		return
-	}
+func (self *VpcPeeringConnectionCreateTask) OnCreateVpcPeeringConnectionComplete(ctx context.Context, peer *models.SVpcPeeringConnection, body jsonutils.JSONObject) {
 	self.taskComplete(ctx, peer)
 }
 
+func (self *VpcPeeringConnectionCreateTask) OnCreateVpcPeeringConnectionCompleteFailed(ctx context.Context, peer *models.SVpcPeeringConnection, body jsonutils.JSONObject) {
+	self.taskFailed(ctx, peer, errors.Errorf(body.String()))
+}
+
 func (self *VpcPeeringConnectionCreateTask) taskComplete(ctx context.Context, peer *models.SVpcPeeringConnection) {
 	logclient.AddActionLogWithStartable(self, peer, logclient.ACT_CREATE, nil, self.UserCred, true)
 	self.SetStageComplete(ctx, nil)
@@ -23,7 +23,6 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
-	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 )
@@ -57,33 +56,24 @@ func (self *VpcPeeringConnectionDeleteTask) OnInit(ctx context.Context, obj db.I
 		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetVpc"))
 		return
 	}
-
-	iVpc, err := vpc.GetIVpc(ctx)
+	region, err := vpc.GetRegion()
 	if err != nil {
-		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetIVpc"))
-		return
-	}
-
-	if len(peer.ExternalId) == 0 {
-		self.taskComplete(ctx, peer)
+		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetRegion"))
 		return
 	}
 
-	iPeer, err := iVpc.GetICloudVpcPeeringConnectionById(peer.ExternalId)
+	self.SetStage("OnDeleteVpcPeeringConnectionComplete", nil)
+	err = region.GetDriver().RequestDeleteVpcPeeringConnection(ctx, self.GetUserCred(), peer, self)
 	if err != nil {
-		if errors.Cause(err) != cloudprovider.ErrNotFound {
-			self.taskFailed(ctx, peer, errors.Wrapf(err, "GetICloudVpcPeeringConnectionById(%s)", peer.ExternalId))
-			return
-		}
-		self.taskComplete(ctx, peer)
-		return
-	}
-
-	err = iPeer.Delete()
-	if err != nil {
-		self.taskFailed(ctx, peer, errors.Wrapf(err, "Delete"))
+		self.taskFailed(ctx, peer, errors.Wrapf(err, "RequestDeleteVpcPeeringConnection"))
 		return
 	}
+}
 
+func (self *VpcPeeringConnectionDeleteTask) OnDeleteVpcPeeringConnectionComplete(ctx context.Context, peer *models.SVpcPeeringConnection, body jsonutils.JSONObject) {
 	self.taskComplete(ctx, peer)
 }
+
+func (self *VpcPeeringConnectionDeleteTask) OnDeleteVpcPeeringConnectionCompleteFailed(ctx context.Context, peer *models.SVpcPeeringConnection, body jsonutils.JSONObject) {
+	self.taskFailed(ctx, peer, errors.Errorf(body.String()))
+}
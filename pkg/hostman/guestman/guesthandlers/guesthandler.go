@@ -77,6 +77,7 @@ func AddGuestTaskHandler(prefix string, app *appsrv.Application) {
 			"src-prepare-migrate":   guestSrcPrepareMigrate,
 			"dest-prepare-migrate":  guestDestPrepareMigrate,
 			"live-migrate":          guestLiveMigrate,
+			"cancel-live-migrate":   guestCancelLiveMigrate,
 			"resume":                guestResume,
 			"drive-mirror":          guestDriveMirror,
 			"hotplug-cpu-mem":       guestHotplugCpuMem,
@@ -92,6 +93,10 @@ func AddGuestTaskHandler(prefix string, app *appsrv.Application) {
 			"cpuset-remove":         guestCPUSetRemove,
 			"memory-snapshot":       guestMemorySnapshot,
 			"memory-snapshot-reset": guestMemorySnapshotReset,
+			"qga-ping":              guestQgaPing,
+			"qga-set-password":      guestQgaSetPassword,
+			"qga-exec":              guestQgaExec,
+			"qga-guest-info":        guestQgaGuestInfo,
 		} {
 			app.AddHandler("POST",
 				fmt.Sprintf("%s/%s/<sid>/%s", prefix, keyWord, action),
@@ -411,6 +416,12 @@ func guestDestPrepareMigrateInternal(ctx context.Context, userCred mcclient.Toke
 	params.MemorySnapshotsUri = msUri
 	msIds, _ := jsonutils.GetStringArray(body, "src_memory_snapshots")
 	params.SrcMemorySnapshots = msIds
+	if body.Contains("tuning") {
+		params.Tuning = new(computeapi.GuestLiveMigrateTuning)
+		if err := body.Unmarshal(params.Tuning, "tuning"); err != nil {
+			return httperrors.NewInputParameterError("unmarshal tuning: %s", err)
+		}
+	}
 
 	params.UserCred = userCred
 
@@ -435,16 +446,34 @@ func guestLiveMigrate(ctx context.Context, userCred mcclient.TokenCredential, si
 		return nil, httperrors.NewMissingParameterError("is_local_storage")
 	}
 	enableTLS := jsonutils.QueryBoolean(body, "enable_tls", false)
+	var tuning *computeapi.GuestLiveMigrateTuning
+	if body.Contains("tuning") {
+		tuning = new(computeapi.GuestLiveMigrateTuning)
+		if err := body.Unmarshal(tuning, "tuning"); err != nil {
+			return nil, httperrors.NewInputParameterError("unmarshal tuning: %s", err)
+		}
+	}
 	hostutils.DelayTaskWithoutReqctx(ctx, guestman.GetGuestManager().LiveMigrate, &guestman.SLiveMigrate{
 		Sid:       sid,
 		DestPort:  int(destPort),
 		DestIp:    destIp,
 		IsLocal:   isLocal,
 		EnableTLS: enableTLS,
+		Tuning:    tuning,
 	})
 	return nil, nil
 }
 
+func guestCancelLiveMigrate(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	if !guestman.GetGuestManager().IsGuestExist(sid) {
+		return nil, httperrors.NewNotFoundError("Guest %s not found", sid)
+	}
+	if err := guestman.GetGuestManager().CancelLiveMigrate(sid); err != nil {
+		return nil, httperrors.NewBadRequestError("%s", err)
+	}
+	return nil, nil
+}
+
 func guestResume(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
 	if !guestman.GetGuestManager().IsGuestExist(sid) {
 		return nil, httperrors.NewNotFoundError("Guest %s not found", sid)
@@ -755,3 +784,46 @@ func guestMemorySnapshotDelete(ctx context.Context, w http.ResponseWriter, r *ht
 		GuestMemorySnapshotDeleteRequest: input,
 	})
 }
+
+func guestQgaPing(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	guest, ok := guestman.GetGuestManager().GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("guest %s not found", sid)
+	}
+	return nil, guest.QgaPing()
+}
+
+func guestQgaSetPassword(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := new(hostapi.GuestQgaSetPasswordRequest)
+	if err := body.Unmarshal(input); err != nil {
+		return nil, err
+	}
+	if input.Password == "" {
+		return nil, httperrors.NewMissingParameterError("password")
+	}
+	guest, ok := guestman.GetGuestManager().GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("guest %s not found", sid)
+	}
+	return nil, guest.QgaSetPassword(input)
+}
+
+func guestQgaExec(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := new(hostapi.GuestQgaExecRequest)
+	if err := body.Unmarshal(input); err != nil {
+		return nil, err
+	}
+	guest, ok := guestman.GetGuestManager().GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("guest %s not found", sid)
+	}
+	return guest.QgaExec(input)
+}
+
+func guestQgaGuestInfo(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	guest, ok := guestman.GetGuestManager().GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("guest %s not found", sid)
+	}
+	return guest.QgaGuestInfo()
+}
@@ -17,6 +17,7 @@ package guestman
 import (
 	"yunion.io/x/jsonutils"
 
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
 	hostapi "yunion.io/x/onecloud/pkg/apis/host"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
@@ -64,6 +65,8 @@ type SDestPrepareMigrate struct {
 	MemorySnapshotsUri string
 	SrcMemorySnapshots []string
 
+	Tuning *computeapi.GuestLiveMigrateTuning
+
 	UserCred mcclient.TokenCredential
 }
 
@@ -73,6 +76,7 @@ type SLiveMigrate struct {
 	DestIp    string
 	IsLocal   bool
 	EnableTLS bool
+	Tuning    *computeapi.GuestLiveMigrateTuning
 }
 
 type SDriverMirror struct {
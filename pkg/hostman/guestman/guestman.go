@@ -906,6 +906,9 @@ func (m *SGuestManager) DestPrepareMigrate(ctx context.Context, params interface
 		startParams.Set("need_migrate", jsonutils.JSONTrue)
 		startParams.Set("source_qemu_cmdline", jsonutils.NewString(migParams.SourceQemuCmdline))
 		startParams.Set("live_migrate_use_tls", jsonutils.NewBool(migParams.EnableTLS))
+		if migParams.Tuning != nil {
+			startParams.Set("live_migrate_tuning", jsonutils.Marshal(migParams.Tuning))
+		}
 		if len(migParams.MigrateCerts) > 0 {
 			if err := guest.WriteMigrateCerts(migParams.MigrateCerts); err != nil {
 				return nil, errors.Wrap(err, "write migrate certs")
@@ -955,6 +958,17 @@ func (m *SGuestManager) LiveMigrate(ctx context.Context, params interface{}) (js
 	return nil, nil
 }
 
+func (m *SGuestManager) CancelLiveMigrate(sid string) error {
+	guest, ok := m.GetServer(sid)
+	if !ok {
+		return httperrors.NewNotFoundError("Not found")
+	}
+	if guest.MigrateTask == nil {
+		return errors.Errorf("guest %s has no live migration in progress", sid)
+	}
+	return guest.MigrateTask.Cancel()
+}
+
 func (m *SGuestManager) CanMigrate(sid string) bool {
 	m.ServersLock.Lock()
 	defer m.ServersLock.Unlock()
@@ -1249,6 +1263,33 @@ func Init(host hostutils.IHost, serversPath string) {
 	}
 }
 
+// QgaFreezeDiskFs freezes the filesystems of the running guest the disk is
+// attached to, the returned function thaws them. It is a noop if the disk is
+// not used by a running guest on this host.
+func (m *SGuestManager) QgaFreezeDiskFs(diskId string) func() {
+	guest := m.getRunningGuestByDisk(diskId)
+	if guest == nil {
+		return func() {}
+	}
+	return guest.qgaFreezeFs()
+}
+
+func (m *SGuestManager) getRunningGuestByDisk(diskId string) *SKVMGuestInstance {
+	var guest *SKVMGuestInstance
+	m.Servers.Range(func(k, v interface{}) bool {
+		s := v.(*SKVMGuestInstance)
+		if !s.IsRunning() {
+			return true
+		}
+		if s.getDiskDesc(diskId) != nil {
+			guest = s
+			return false
+		}
+		return true
+	})
+	return guest
+}
+
 func GetGuestManager() *SGuestManager {
 	return guestManager
 }
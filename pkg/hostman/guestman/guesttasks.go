@@ -21,6 +21,7 @@ import (
 	"path"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"yunion.io/x/jsonutils"
@@ -39,6 +40,7 @@ import (
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/logclient"
 	"yunion.io/x/onecloud/pkg/util/procutils"
 	"yunion.io/x/onecloud/pkg/util/qemuimg"
 	"yunion.io/x/onecloud/pkg/util/timeutils2"
@@ -653,6 +655,81 @@ func (t *SGuestIsolatedDeviceSyncTask) delDeviceCallBack(opts []*isolated_device
  *  GuestLiveMigrateTask
 **/
 
+type sMigrateSetting struct {
+	Capability bool
+	Key        string
+	Value      interface{}
+}
+
+// setMigrateSettings set migrate capabilities and parameters one by one
+func setMigrateSettings(m monitor.Monitor, settings []sMigrateSetting, onSucc func(), onFail func(string)) {
+	if len(settings) == 0 {
+		onSucc()
+		return
+	}
+	setting := settings[0]
+	cb := func(res string) {
+		if strings.Contains(strings.ToLower(res), "error") {
+			onFail(fmt.Sprintf("Migrate set %s error: %s", setting.Key, res))
+			return
+		}
+		setMigrateSettings(m, settings[1:], onSucc, onFail)
+	}
+	if setting.Capability {
+		m.MigrateSetCapability(setting.Key, setting.Value.(string), cb)
+	} else {
+		m.MigrateSetParameter(setting.Key, setting.Value, cb)
+	}
+}
+
+// getMigrateCapabilitySettings returns settings must be applied on both
+// source and destination before migration starts
+func getMigrateCapabilitySettings(tuning *api.GuestLiveMigrateTuning) []sMigrateSetting {
+	settings := []sMigrateSetting{}
+	if tuning == nil {
+		return settings
+	}
+	if tuning.MultifdChannels > 0 {
+		settings = append(settings,
+			sMigrateSetting{Capability: true, Key: "multifd", Value: "on"},
+			sMigrateSetting{Key: "multifd-channels", Value: tuning.MultifdChannels},
+		)
+		if len(tuning.Compression) > 0 {
+			settings = append(settings, sMigrateSetting{Key: "multifd-compression", Value: tuning.Compression})
+		}
+	}
+	if tuning.PostcopyAfterSeconds > 0 {
+		settings = append(settings, sMigrateSetting{Capability: true, Key: "postcopy-ram", Value: "on"})
+	}
+	return settings
+}
+
+func getSrcMigrateSettings(tuning *api.GuestLiveMigrateTuning) []sMigrateSetting {
+	autoConverge := "on"
+	if tuning != nil && tuning.AutoConverge != nil && !*tuning.AutoConverge {
+		autoConverge = "off"
+	}
+	settings := []sMigrateSetting{
+		{Capability: true, Key: "zero-blocks", Value: "on"},
+		// https://wiki.qemu.org/Features/AutoconvergeLiveMigration
+		{Capability: true, Key: "auto-converge", Value: autoConverge},
+	}
+	if tuning == nil {
+		return settings
+	}
+	settings = append(settings, getMigrateCapabilitySettings(tuning)...)
+	if tuning.XbzrleCacheSizeMb > 0 {
+		settings = append(settings,
+			sMigrateSetting{Capability: true, Key: "xbzrle", Value: "on"},
+			sMigrateSetting{Key: "xbzrle-cache-size", Value: int64(tuning.XbzrleCacheSizeMb) * 1024 * 1024},
+		)
+	}
+	if tuning.MaxBandwidthMb > 0 {
+		settings = append(settings, sMigrateSetting{Key: "max-bandwidth", Value: int64(tuning.MaxBandwidthMb) * 1024 * 1024})
+	}
+	return settings
+}
+
 type SGuestLiveMigrateTask struct {
 	*SKVMGuestInstance
 
@@ -663,6 +740,15 @@ type SGuestLiveMigrateTask struct {
 
 	timeoutAt        time.Time
 	doTimeoutMigrate bool
+
+	postcopyAt time.Time
+	// stateLock guards postcopyStarted and cancelled, which exclude each
+	// other since a migration in postcopy can't be cancelled
+	stateLock       sync.Mutex
+	postcopyStarted bool
+	cancelled       bool
+
+	progressSyncAt time.Time
 }
 
 func NewGuestLiveMigrateTask(
@@ -674,16 +760,7 @@ func NewGuestLiveMigrateTask(
 }
 
 func (s *SGuestLiveMigrateTask) Start() {
-	s.Monitor.MigrateSetCapability("zero-blocks", "on", s.onSetZeroBlocks)
-}
-
-func (s *SGuestLiveMigrateTask) onSetZeroBlocks(res string) {
-	if strings.Contains(strings.ToLower(res), "error") {
-		s.migrateFailed(fmt.Sprintf("Migrate set capability zero-blocks error: %s", res))
-		return
-	}
-	// https://wiki.qemu.org/Features/AutoconvergeLiveMigration
-	s.Monitor.MigrateSetCapability("auto-converge", "on", s.startMigrate)
+	setMigrateSettings(s.Monitor, getSrcMigrateSettings(s.params.Tuning), s.startMigrate, s.migrateFailed)
 }
 
 func (s *SGuestLiveMigrateTask) startRamMigrateTimeout() {
@@ -700,11 +777,7 @@ func (s *SGuestLiveMigrateTask) startRamMigrateTimeout() {
 	log.Infof("migrate timeout seconds: %d now: %v expectfinial: %v", migSeconds, time.Now(), s.timeoutAt)
 }
 
-func (s *SGuestLiveMigrateTask) startMigrate(res string) {
-	if strings.Contains(strings.ToLower(res), "error") {
-		s.migrateFailed(fmt.Sprintf("Migrate set capability auto-converge error: %s", res))
-		return
-	}
+func (s *SGuestLiveMigrateTask) startMigrate() {
 	if s.params.EnableTLS {
 		// https://wiki.qemu.org/Features/MigrationTLS
 		// first remove possible existing tls0
@@ -740,7 +813,39 @@ func (s *SGuestLiveMigrateTask) startMigrate(res string) {
 	}
 }
 
+func (s *SGuestLiveMigrateTask) isCancelled() bool {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+	return s.cancelled
+}
+
+// tryStartPostcopy marks the switch to postcopy unless cancelled
+func (s *SGuestLiveMigrateTask) tryStartPostcopy() bool {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+	if s.cancelled || s.postcopyStarted {
+		return false
+	}
+	s.postcopyStarted = true
+	return true
+}
+
+// tryCancel marks the migration cancelled unless switched to postcopy
+func (s *SGuestLiveMigrateTask) tryCancel() bool {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+	if s.postcopyStarted {
+		return false
+	}
+	s.cancelled = true
+	return true
+}
+
 func (s *SGuestLiveMigrateTask) doMigrate() {
+	if s.isCancelled() {
+		s.migrateFailed(api.LIVE_MIGRATE_CANCELLED)
+		return
+	}
 	var copyIncremental = false
 	if s.params.IsLocal {
 		// copy disk data
@@ -751,7 +856,11 @@ func (s *SGuestLiveMigrateTask) doMigrate() {
 }
 
 func (s *SGuestLiveMigrateTask) onSetMigrateDowntime(res string) {
-	s.Monitor.MigrateSetParameter("downtime-limit", int(options.HostOptions.DefaultLiveMigrateDowntime*1000), s.startMigrateStatusCheck)
+	downtimeMs := int(options.HostOptions.DefaultLiveMigrateDowntime * 1000)
+	if s.params.Tuning != nil && s.params.Tuning.MaxDowntimeMs > 0 {
+		downtimeMs = s.params.Tuning.MaxDowntimeMs
+	}
+	s.Monitor.MigrateSetParameter("downtime-limit", downtimeMs, s.startMigrateStatusCheck)
 }
 
 func (s *SGuestLiveMigrateTask) startMigrateStatusCheck(res string) {
@@ -768,7 +877,7 @@ func (s *SGuestLiveMigrateTask) startMigrateStatusCheck(res string) {
 			break
 		case <-time.After(time.Second * 5):
 			if s.Monitor != nil {
-				s.Monitor.GetMigrateStatus(s.onGetMigrateStatus)
+				s.Monitor.GetMigrateStats(s.onGetMigrateStats)
 			} else {
 				log.Errorf("server %s(%s) migrate stopped unexpectedly", s.GetId(), s.GetName())
 				s.migrateFailed(fmt.Sprintf("Migrate error: %s", res))
@@ -778,14 +887,65 @@ func (s *SGuestLiveMigrateTask) startMigrateStatusCheck(res string) {
 	}
 }
 
+func (s *SGuestLiveMigrateTask) onGetMigrateStats(info *monitor.MigrationInfo, err error) {
+	if err != nil {
+		log.Errorf("server %s(%s) query migrate stats: %v", s.GetId(), s.GetName(), err)
+		return
+	}
+	if info.Status == "active" || info.Status == "postcopy-active" {
+		hostutils.UpdateServerProgress(context.Background(), s.GetId(), info.Progress(), info.Mbps())
+		if time.Since(s.progressSyncAt) >= time.Second*time.Duration(options.HostOptions.LiveMigrateProgressSyncSeconds) {
+			s.syncMigrateProgress(info.Status, info)
+		}
+	}
+	s.onGetMigrateStatus(info.Phase())
+}
+
+// syncMigrateProgress write migration progress to guest metadata
+func (s *SGuestLiveMigrateTask) syncMigrateProgress(status string, info *monitor.MigrationInfo) {
+	s.progressSyncAt = time.Now()
+	progress := api.GuestLiveMigrateProgress{
+		Status:    status,
+		UpdatedAt: s.progressSyncAt,
+	}
+	if status == "completed" {
+		progress.Progress = 100
+	} else if info != nil {
+		progress.Progress = info.Progress()
+		progress.Mbps = info.Mbps()
+		progress.RemainingRam = info.RemainingRam()
+		progress.DirtyRate = info.DirtyRate()
+		progress.EtaSeconds = info.EstimatedSeconds()
+	}
+	meta := jsonutils.NewDict()
+	meta.Set(api.VM_METADATA_LIVE_MIGRATE_PROGRESS, jsonutils.NewString(jsonutils.Marshal(progress).String()))
+	go s.SyncMetadata(meta)
+	if status == "active" || status == "postcopy-active" {
+		// the final status is logged by region
+		obj := logclient.NewSimpleObject(s.GetId(), s.GetName(), "server")
+		go logclient.AddSimpleActionLog(obj, logclient.ACT_MIGRATE, progress, hostutils.GetComputeSession(context.Background()).GetToken(), true)
+	}
+}
+
 func (s *SGuestLiveMigrateTask) onGetMigrateStatus(status string) {
 	if status == "completed" {
 		s.migrateComplete()
+	} else if status == "cancelled" && s.isCancelled() {
+		s.onMigrateCancelled()
 	} else if status == "failed" || status == "cancelled" {
 		s.migrateFailed(fmt.Sprintf("Query migrate got status: %s", status))
 	} else if status == "migrate_disk_copy" {
 		// do nothing, simply wait
 	} else if status == "migrate_ram_copy" {
+		if s.params.Tuning != nil && s.params.Tuning.PostcopyAfterSeconds > 0 {
+			if s.postcopyAt.IsZero() {
+				s.postcopyAt = time.Now().Add(time.Second * time.Duration(s.params.Tuning.PostcopyAfterSeconds))
+			} else if s.postcopyAt.Before(time.Now()) && s.tryStartPostcopy() {
+				log.Infof("server %s(%s) ram copy exceeds %ds, switch to postcopy", s.GetId(), s.GetName(), s.params.Tuning.PostcopyAfterSeconds)
+				s.Monitor.MigrateStartPostcopy(s.onMigrateStartPostcopy)
+				return
+			}
+		}
 		if s.timeoutAt.IsZero() {
 			s.startRamMigrateTimeout()
 		} else if !s.doTimeoutMigrate && s.timeoutAt.Before(time.Now()) {
@@ -807,7 +967,39 @@ func (s *SGuestLiveMigrateTask) onMigrateStartPostcopy(res string) {
 	}
 }
 
+// Cancel aborts the migration, the task fails with LIVE_MIGRATE_CANCELLED
+// after qemu reports cancelled status
+func (s *SGuestLiveMigrateTask) Cancel() error {
+	if s.Monitor == nil {
+		return errors.Errorf("server %s monitor not connected", s.GetId())
+	}
+	if !s.tryCancel() {
+		return errors.Errorf("can't cancel live migration in postcopy phase")
+	}
+	s.Monitor.MigrateCancel(func(res string) {
+		if strings.Contains(strings.ToLower(res), "error") {
+			log.Errorf("server %s(%s) migrate cancel: %s", s.GetId(), s.GetName(), res)
+		}
+	})
+	return nil
+}
+
+func (s *SGuestLiveMigrateTask) onMigrateCancelled() {
+	if s.doTimeoutMigrate {
+		// guest was paused to finish migration, resume it
+		s.Monitor.SimpleCommand("cont", func(res string) {
+			if len(res) > 0 {
+				log.Errorf("server %s(%s) cont after migrate cancelled: %s", s.GetId(), s.GetName(), res)
+			}
+			s.migrateFailed(api.LIVE_MIGRATE_CANCELLED)
+		})
+		return
+	}
+	s.migrateFailed(api.LIVE_MIGRATE_CANCELLED)
+}
+
 func (s *SGuestLiveMigrateTask) migrateComplete() {
+	s.syncMigrateProgress("completed", nil)
 	s.MigrateTask = nil
 	if s.c != nil {
 		close(s.c)
@@ -819,6 +1011,11 @@ func (s *SGuestLiveMigrateTask) migrateComplete() {
 }
 
 func (s *SGuestLiveMigrateTask) migrateFailed(msg string) {
+	status := "failed"
+	if s.isCancelled() {
+		status = "cancelled"
+	}
+	s.syncMigrateProgress(status, nil)
 	cleanup := func() {
 		s.MigrateTask = nil
 		if s.c != nil {
@@ -1282,14 +1479,17 @@ type SGuestDiskSnapshotTask struct {
 	*SGuestReloadDiskTask
 
 	snapshotId string
+	// thaw the guest filesystems frozen by guest agent
+	thawFs func()
 }
 
 func NewGuestDiskSnapshotTask(
-	ctx context.Context, s *SKVMGuestInstance, disk storageman.IDisk, snapshotId string,
+	ctx context.Context, s *SKVMGuestInstance, disk storageman.IDisk, snapshotId string, thawFs func(),
 ) *SGuestDiskSnapshotTask {
 	return &SGuestDiskSnapshotTask{
 		SGuestReloadDiskTask: NewGuestReloadDiskTask(ctx, s, disk),
 		snapshotId:           snapshotId,
+		thawFs:               thawFs,
 	}
 }
 
@@ -1312,6 +1512,7 @@ func (s *SGuestDiskSnapshotTask) onReloadBlkdevSucc(res string) {
 }
 
 func (s *SGuestDiskSnapshotTask) onSnapshotBlkdevFail(reason string) {
+	s.thawFs()
 	snapshotDir := s.disk.GetSnapshotDir()
 	snapshotPath := path.Join(snapshotDir, s.snapshotId)
 	output, err := procutils.NewCommand("mv", "-f", snapshotPath, s.disk.GetPath()).Output()
@@ -1323,6 +1524,7 @@ func (s *SGuestDiskSnapshotTask) onSnapshotBlkdevFail(reason string) {
 
 func (s *SGuestDiskSnapshotTask) onResumeSucc(res string) {
 	log.Infof("guest disk snapshot task resume succ %s", res)
+	s.thawFs()
 	snapshotLocation := path.Join(s.disk.GetSnapshotLocation(), s.snapshotId)
 	body := jsonutils.NewDict()
 	body.Set("location", jsonutils.NewString(snapshotLocation))
@@ -0,0 +1,225 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/monitor"
+	"yunion.io/x/onecloud/pkg/hostman/storageman"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+// A disk of a running guest is backed up by a drive-backup job instead of a
+// snapshot. Each backup adds the persistent dirty bitmap named after it in
+// the same transaction the job starts in, so the next backup copies only the
+// clusters written since. The bitmap gets lost when the guest restarts
+// uncleanly or its disk is snapshotted, the backup then copies the whole
+// disk and compares it with the parent backup chain instead.
+
+const LIVE_BACKUP_JOB_TIMEOUT = 24 * time.Hour
+
+func liveBackupBitmap(backupId string) string {
+	return "backup_" + backupId
+}
+
+func (s *SKVMGuestInstance) getDiskDesc(diskId string) *api.GuestdiskJsonDesc {
+	for _, disk := range s.Desc.Disks {
+		if disk.DiskId == diskId {
+			return disk
+		}
+	}
+	return nil
+}
+
+// DiskLiveBackup backs up the disk used by a running guest on this host
+func (m *SGuestManager) DiskLiveBackup(ctx context.Context, disk storageman.IDisk, backup *storageman.SDiskBakcup) (jsonutils.JSONObject, error) {
+	guest := m.getRunningGuestByDisk(disk.GetId())
+	if guest == nil {
+		return nil, errors.Errorf("disk %s is not used by any running guest", disk.GetId())
+	}
+	return guest.liveBackupDisk(ctx, disk, backup)
+}
+
+func (s *SKVMGuestInstance) liveBackupDisk(ctx context.Context, disk storageman.IDisk, backup *storageman.SDiskBakcup) (ret jsonutils.JSONObject, err error) {
+	drive := fmt.Sprintf("drive_%d", s.getDiskDesc(disk.GetId()).Index)
+	srcImg, err := qemuimg.NewQemuImage(disk.GetPath())
+	if err != nil {
+		return nil, errors.Wrapf(err, "NewQemuImage %s", disk.GetPath())
+	}
+	backupDir := disk.GetBackupDir()
+	if output, err := procutils.NewCommand("mkdir", "-p", backupDir).Output(); err != nil {
+		return nil, errors.Wrapf(err, "mkdir %s failed: %s", backupDir, output)
+	}
+	backupPath := path.Join(backupDir, backup.BackupId)
+	img, err := qemuimg.NewQemuImage(backupPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "NewQemuImage %s", backupPath)
+	}
+	if err := img.CreateQcow2(srcImg.GetSizeMB(), false, "", "", "", ""); err != nil {
+		return nil, errors.Wrapf(err, "create %s", backupPath)
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(backupPath)
+			// the next backup can not be incremental to a failed one
+			s.removeBackupBitmap(drive, liveBackupBitmap(backup.BackupId))
+		}
+	}()
+
+	parentId := ""
+	if cnt := len(backup.ParentBackupChain); cnt > 0 {
+		parentId = backup.ParentBackupChain[cnt-1]
+	}
+	incremental, err := s.driveBackup(drive, backupPath, parentId, backup.BackupId)
+	if err != nil {
+		return nil, errors.Wrap(err, "drive backup")
+	}
+	if incremental {
+		// the bitmap is cleared by the succeeded job
+		s.removeBackupBitmap(drive, liveBackupBitmap(parentId))
+		// the parent is only in the backup storage, rebase unsafely
+		if err := img.Rebase(parentId, true); err != nil {
+			return nil, errors.Wrapf(err, "rebase %s onto parent", backupPath)
+		}
+	}
+	sizeMb := int64(img.GetActualSizeMB())
+	res, err := disk.GetStorage().StorageBackup(ctx, &storageman.SStorageBackup{
+		BackupId:                backup.BackupId,
+		BackupStorageId:         backup.BackupStorageId,
+		BackupStorageAccessInfo: backup.BackupStorageAccessInfo,
+		ParentBackupChain:       backup.ParentBackupChain,
+		Incremental:             incremental,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to SStorageBackup")
+	}
+	if res != nil && res.Contains("size_mb") {
+		sizeMb, _ = res.Int("size_mb")
+	}
+	data := jsonutils.NewDict()
+	data.Set("size_mb", jsonutils.NewInt(sizeMb))
+	return data, nil
+}
+
+// driveBackup backs up drive into the existing image target and returns
+// whether only the clusters changed since the parent backup are copied
+func (s *SKVMGuestInstance) driveBackup(drive, target, parentId, backupId string) (bool, error) {
+	jobId := "backup_" + backupId
+	newBitmap := liveBackupBitmap(backupId)
+	incremental := len(parentId) > 0
+	var err error
+	if incremental {
+		err = s.startDriveBackup(jobId, drive, target, "incremental", liveBackupBitmap(parentId), newBitmap)
+		if err != nil {
+			log.Warningf("guest %s incremental backup of %s failed, fallback to full: %s", s.GetName(), drive, err)
+			incremental = false
+		}
+	}
+	if !incremental {
+		err = s.startDriveBackup(jobId, drive, target, "full", "", newBitmap)
+	}
+	if err != nil {
+		return false, err
+	}
+	return incremental, s.waitJobConcluded(jobId)
+}
+
+func (s *SKVMGuestInstance) startDriveBackup(jobId, drive, target, syncMode, bitmap, newBitmap string) error {
+	if s.Monitor == nil {
+		return errors.Errorf("guest %s monitor not connected", s.GetName())
+	}
+	// the job backs up the disk as it is when started
+	thaw := s.qgaFreezeFs()
+	defer thaw()
+	res := make(chan string, 1)
+	s.Monitor.DriveBackup(jobId, drive, target, syncMode, bitmap, newBitmap, func(r string) {
+		res <- r
+	})
+	select {
+	case <-time.After(time.Second * 30):
+		return errors.Errorf("start drive backup timeout")
+	case r := <-res:
+		if len(r) > 0 {
+			return errors.Error(r)
+		}
+		return nil
+	}
+}
+
+func (s *SKVMGuestInstance) getJob(jobId string) (*monitor.Job, error) {
+	if s.Monitor == nil {
+		return nil, errors.Errorf("guest %s monitor not connected", s.GetName())
+	}
+	res := make(chan *monitor.Job, 1)
+	s.Monitor.GetJob(jobId, func(job *monitor.Job) {
+		res <- job
+	})
+	select {
+	case <-time.After(time.Second * 30):
+		return nil, errors.Errorf("query job %s timeout", jobId)
+	case job := <-res:
+		if job == nil {
+			return nil, errors.Wrapf(errors.ErrNotFound, "job %s", jobId)
+		}
+		return job, nil
+	}
+}
+
+func (s *SKVMGuestInstance) waitJobConcluded(jobId string) error {
+	deadline := time.Now().Add(LIVE_BACKUP_JOB_TIMEOUT)
+	for time.Now().Before(deadline) {
+		job, err := s.getJob(jobId)
+		if err != nil {
+			return err
+		}
+		if job.Status == "concluded" {
+			s.Monitor.JobDismiss(jobId, func(res string) {
+				if len(res) > 0 {
+					log.Errorf("guest %s dismiss job %s: %s", s.GetName(), jobId, res)
+				}
+			})
+			if len(job.Error) > 0 {
+				return errors.Errorf("job %s failed: %s", jobId, job.Error)
+			}
+			return nil
+		}
+		log.Debugf("guest %s job %s %s %d/%d", s.GetName(), jobId, job.Status, job.CurrentProgress, job.TotalProgress)
+		time.Sleep(time.Second * 3)
+	}
+	s.Monitor.CancelBlockJob(jobId, true, func(string) {})
+	return errors.Errorf("wait job %s timeout", jobId)
+}
+
+func (s *SKVMGuestInstance) removeBackupBitmap(drive, bitmap string) {
+	if s.Monitor == nil {
+		return
+	}
+	s.Monitor.BlockDirtyBitmapRemove(drive, bitmap, func(res string) {
+		if len(res) > 0 {
+			log.Warningf("guest %s remove bitmap %s of %s: %s", s.GetName(), bitmap, drive, res)
+		}
+	})
+}
@@ -0,0 +1,237 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/hostman/monitor"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+)
+
+const (
+	// filesystems are thawed anyway after the duration in case the snapshot
+	// task never completes
+	QGA_FSFREEZE_MAX_DURATION = 2 * time.Minute
+
+	QGA_EXEC_DEFAULT_TIMEOUT = 30
+)
+
+func (s *SKVMGuestInstance) GetQgaSocketPath() string {
+	return path.Join(s.HomeDir(), "qga.sock")
+}
+
+func (s *SKVMGuestInstance) getQga() (*monitor.QemuGuestAgent, error) {
+	if !s.IsRunning() {
+		return nil, httperrors.NewInvalidStatusError("guest %s is not running", s.GetName())
+	}
+	if !fileutils2.Exists(s.GetQgaSocketPath()) {
+		return nil, httperrors.NewUnsupportOperationError("guest %s has no guest agent channel", s.GetName())
+	}
+	s.qgaLock.Lock()
+	defer s.qgaLock.Unlock()
+	if s.qga == nil {
+		s.qga = monitor.NewQemuGuestAgent(s.Id, s.GetQgaSocketPath())
+	}
+	return s.qga, nil
+}
+
+func (s *SKVMGuestInstance) closeQga() {
+	s.qgaLock.Lock()
+	defer s.qgaLock.Unlock()
+	if s.qga != nil {
+		s.qga.Close()
+		s.qga = nil
+	}
+}
+
+// qgaFreezeFs freezes the filesystems of the guest to take an application
+// consistent snapshot, the returned function thaws them. The guests without a
+// responding agent are snapshotted crash consistently as before.
+func (s *SKVMGuestInstance) qgaFreezeFs() func() {
+	noop := func() {}
+	qga, err := s.getQga()
+	if err != nil {
+		return noop
+	}
+	err = qga.GuestPing()
+	if err != nil {
+		log.Warningf("guest %s agent not responding, skip fsfreeze: %s", s.GetName(), err)
+		return noop
+	}
+	cnt, err := qga.FsfreezeFreeze()
+	if err != nil {
+		log.Errorf("guest %s fsfreeze fail: %s", s.GetName(), err)
+		// part of the filesystems may be frozen
+		if _, err := qga.FsfreezeThaw(); err != nil {
+			log.Errorf("guest %s fsfreeze thaw fail: %s", s.GetName(), err)
+		}
+		return noop
+	}
+	log.Infof("guest %s %d filesystems frozen", s.GetName(), cnt)
+
+	var timer *time.Timer
+	once := &sync.Once{}
+	thaw := func() {
+		once.Do(func() {
+			timer.Stop()
+			cnt, err := qga.FsfreezeThaw()
+			if err != nil {
+				log.Errorf("guest %s fsfreeze thaw fail: %s", s.GetName(), err)
+				return
+			}
+			log.Infof("guest %s %d filesystems thawed", s.GetName(), cnt)
+		})
+	}
+	timer = time.AfterFunc(QGA_FSFREEZE_MAX_DURATION, func() {
+		log.Warningf("guest %s filesystems frozen over %s, thaw", s.GetName(), QGA_FSFREEZE_MAX_DURATION)
+		thaw()
+	})
+	return thaw
+}
+
+func (s *SKVMGuestInstance) QgaPing() error {
+	qga, err := s.getQga()
+	if err != nil {
+		return err
+	}
+	return qga.GuestPing()
+}
+
+// QgaSetPassword resets the password of the running guest without reboot
+func (s *SKVMGuestInstance) QgaSetPassword(input *hostapi.GuestQgaSetPasswordRequest) error {
+	isWindows := s.getOsname() == OS_NAME_WINDOWS
+	if input.Crypted && isWindows {
+		return httperrors.NewInputParameterError("crypted password is not supported by windows")
+	}
+	username := input.Username
+	if len(username) == 0 {
+		if isWindows {
+			username = "Administrator"
+		} else {
+			username = "root"
+		}
+	}
+	qga, err := s.getQga()
+	if err != nil {
+		return err
+	}
+	err = qga.GuestSetUserPassword(username, input.Password, input.Crypted)
+	if err != nil {
+		return errors.Wrapf(err, "set password of %s", username)
+	}
+	return nil
+}
+
+func (s *SKVMGuestInstance) QgaExec(input *hostapi.GuestQgaExecRequest) (*hostapi.GuestQgaExecResponse, error) {
+	if len(input.Path) == 0 {
+		return nil, httperrors.NewMissingParameterError("path")
+	}
+	timeout := input.Timeout
+	if timeout <= 0 {
+		timeout = QGA_EXEC_DEFAULT_TIMEOUT
+	}
+	qga, err := s.getQga()
+	if err != nil {
+		return nil, err
+	}
+	res, err := qga.ExecCommand(input.Path, input.Args, time.Duration(timeout)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &hostapi.GuestQgaExecResponse{
+		Exitcode: res.Exitcode,
+		Signal:   res.Signal,
+		Stdout:   res.Stdout,
+		Stderr:   res.Stderr,
+	}, nil
+}
+
+// QgaGuestInfo collects the os info, ip addresses and filesystem usages
+// inside the guest
+func (s *SKVMGuestInstance) QgaGuestInfo() (*hostapi.GuestQgaInfoResponse, error) {
+	qga, err := s.getQga()
+	if err != nil {
+		return nil, err
+	}
+	ret := &hostapi.GuestQgaInfoResponse{}
+
+	// guest-get-osinfo is supported since qemu-ga 2.10
+	osInfo, err := qga.GuestGetOsinfo()
+	if err != nil {
+		log.Warningf("guest %s get osinfo fail: %s", s.GetName(), err)
+	} else {
+		ret.OsInfo = &hostapi.GuestQgaOsInfo{
+			Id:            osInfo.Id,
+			Name:          osInfo.Name,
+			PrettyName:    osInfo.PrettyName,
+			Version:       osInfo.Version,
+			KernelRelease: osInfo.KernelRelease,
+			Machine:       osInfo.Machine,
+		}
+	}
+
+	ifaces, err := qga.GuestNetworkGetInterfaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "GuestNetworkGetInterfaces")
+	}
+	ret.Interfaces = make([]hostapi.GuestQgaInterface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		if iface.Name == "lo" || strings.HasPrefix(iface.Name, "Loopback") {
+			continue
+		}
+		nic := hostapi.GuestQgaInterface{
+			Name: iface.Name,
+			Mac:  iface.HardwareAddress,
+		}
+		for _, addr := range iface.IpAddresses {
+			nic.IpAddresses = append(nic.IpAddresses, hostapi.GuestQgaIpAddress{
+				Type:    addr.IpAddressType,
+				Address: addr.IpAddress,
+				Prefix:  addr.Prefix,
+			})
+		}
+		ret.Interfaces = append(ret.Interfaces, nic)
+	}
+
+	fsinfo, err := qga.GuestGetFsinfo()
+	if err != nil {
+		log.Warningf("guest %s get fsinfo fail: %s", s.GetName(), err)
+	} else {
+		ret.Filesystems = make([]hostapi.GuestQgaFilesystem, 0, len(fsinfo))
+		for _, fs := range fsinfo {
+			// used-bytes and total-bytes are reported since qemu-ga 3.0
+			if fs.TotalBytes == 0 {
+				continue
+			}
+			ret.Filesystems = append(ret.Filesystems, hostapi.GuestQgaFilesystem{
+				Name:       fs.Name,
+				Mountpoint: fs.Mountpoint,
+				Type:       fs.Type,
+				UsedBytes:  fs.UsedBytes,
+				TotalBytes: fs.TotalBytes,
+			})
+		}
+	}
+	return ret, nil
+}
@@ -24,6 +24,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"yunion.io/x/jsonutils"
@@ -81,6 +82,7 @@ type SKVMInstanceRuntime struct {
 
 	LiveMigrateDestPort *int
 	LiveMigrateUseTls   bool
+	LiveMigrateTuning   *api.GuestLiveMigrateTuning
 
 	SyncMeta *jsonutils.JSONDict
 
@@ -102,6 +104,9 @@ type SKVMGuestInstance struct {
 	Desc    *desc.SGuestDesc
 	Monitor monitor.Monitor
 	manager *SGuestManager
+
+	qga     *monitor.QemuGuestAgent
+	qgaLock *sync.Mutex
 }
 
 func NewKVMGuestInstance(id string, manager *SGuestManager) *SKVMGuestInstance {
@@ -111,6 +116,7 @@ func NewKVMGuestInstance(id string, manager *SGuestManager) *SKVMGuestInstance {
 		},
 		Id:      id,
 		manager: manager,
+		qgaLock: &sync.Mutex{},
 	}
 }
 
@@ -784,8 +790,17 @@ func (s *SKVMGuestInstance) onMonitorConnected(ctx context.Context) {
 	})
 }
 
-func (s *SKVMGuestInstance) setDestMigrateTLS(ctx context.Context, data *jsonutils.JSONDict) {
-	port, _ := data.Int("live_migrate_dest_port")
+// isDeferMigrateIncoming returns true if qemu started with `-incoming defer`
+// and migrate capabilities must be set before migrate incoming
+func (s *SKVMGuestInstance) isDeferMigrateIncoming() bool {
+	return s.LiveMigrateUseTls || len(getMigrateCapabilitySettings(s.LiveMigrateTuning)) > 0
+}
+
+func (s *SKVMGuestInstance) setDestMigrate(ctx context.Context, data *jsonutils.JSONDict) {
+	if !s.LiveMigrateUseTls {
+		s.setDestMigrateIncoming(ctx, data)
+		return
+	}
 	s.Monitor.ObjectAdd("tls-creds-x509", map[string]string{
 		"dir":         s.getPKIDirPath(),
 		"endpoint":    "server",
@@ -796,20 +811,27 @@ func (s *SKVMGuestInstance) setDestMigrateTLS(ctx context.Context, data *jsonuti
 			hostutils.TaskFailed(ctx, fmt.Sprintf("Migrate add tls-creds-x509 object server tls0 error: %s", res))
 			return
 		}
-		s.Monitor.MigrateSetParameter("tls-creds", "tls0", func(res string) {
+		s.setDestMigrateIncoming(ctx, data)
+	})
+}
+
+func (s *SKVMGuestInstance) setDestMigrateIncoming(ctx context.Context, data *jsonutils.JSONDict) {
+	port, _ := data.Int("live_migrate_dest_port")
+	settings := getMigrateCapabilitySettings(s.LiveMigrateTuning)
+	if s.LiveMigrateUseTls {
+		settings = append([]sMigrateSetting{{Key: "tls-creds", Value: "tls0"}}, settings...)
+	}
+	setMigrateSettings(s.Monitor, settings, func() {
+		address := fmt.Sprintf("tcp:0:%d", port)
+		s.Monitor.MigrateIncoming(address, func(res string) {
 			if strings.Contains(strings.ToLower(res), "error") {
-				hostutils.TaskFailed(ctx, fmt.Sprintf("Migrate set tls-creds tls0 error: %s", res))
+				hostutils.TaskFailed(ctx, fmt.Sprintf("Migrate set incoming %q error: %s", address, res))
 				return
 			}
-			address := fmt.Sprintf("tcp:0:%d", port)
-			s.Monitor.MigrateIncoming(address, func(res string) {
-				if strings.Contains(strings.ToLower(res), "error") {
-					hostutils.TaskFailed(ctx, fmt.Sprintf("Migrate set incoming %q error: %s", address, res))
-					return
-				}
-				hostutils.TaskComplete(ctx, data)
-			})
+			hostutils.TaskComplete(ctx, data)
 		})
+	}, func(msg string) {
+		hostutils.TaskFailed(ctx, msg)
 	})
 }
 
@@ -819,8 +841,8 @@ func (s *SKVMGuestInstance) onGetQemuVersion(ctx context.Context, version string
 	if s.LiveMigrateDestPort != nil && ctx != nil {
 		body := jsonutils.NewDict()
 		body.Set("live_migrate_dest_port", jsonutils.NewInt(int64(*s.LiveMigrateDestPort)))
-		if s.LiveMigrateUseTls {
-			s.setDestMigrateTLS(ctx, body)
+		if s.isDeferMigrateIncoming() {
+			s.setDestMigrate(ctx, body)
 		} else {
 			hostutils.TaskComplete(ctx, body)
 		}
@@ -850,6 +872,7 @@ func (s *SKVMGuestInstance) onMonitorDisConnect(err error) {
 	}
 	s.clearCgroup(0)
 	s.Monitor = nil
+	s.closeQga()
 }
 
 func (s *SKVMGuestInstance) startDiskBackupMirror(ctx context.Context) {
@@ -1229,6 +1252,7 @@ func (s *SKVMGuestInstance) ExitCleanup(clear bool) {
 		s.Monitor.Disconnect()
 		s.Monitor = nil
 	}
+	s.closeQga()
 }
 
 func (s *SKVMGuestInstance) CleanupCpuset() {
@@ -1308,6 +1332,7 @@ func (s *SKVMGuestInstance) Delete(ctx context.Context, migrated bool) error {
 func (s *SKVMGuestInstance) Stop() bool {
 	s.ExitCleanup(true)
 	if s.scriptStop() {
+		s.manager.GetHost().GetIsolatedDeviceManager().CleanupGuestDevices(s.Desc.IsolatedDevices)
 		return true
 	} else {
 		return false
@@ -1729,6 +1754,14 @@ func (s *SKVMGuestInstance) setCgroupCPUSet() {
 			log.Errorf("failed unmarshal server %s cpuset %s", s.Id, err)
 			return
 		}
+	} else if node := s.getNumaNode(); node != nil {
+		// keep vcpus on the numa node the memory is bound to
+		cpus, err := hostinfo.GetNumaNodeCpus(*node)
+		if err != nil {
+			log.Errorf("failed get server %s numa node %d cpus: %s", s.Id, *node, err)
+			return
+		}
+		input = &api.ServerCPUSetInput{CPUS: cpus}
 	}
 	if _, err := s.CPUSet(context.Background(), input); err != nil {
 		log.Errorf("Do CPUSet error: %v", err)
@@ -1993,11 +2026,13 @@ func (s *SKVMGuestInstance) ExecDiskSnapshotTask(
 		if !s.isLiveSnapshotEnabled() {
 			return nil, fmt.Errorf("Guest dosen't support live snapshot")
 		}
+		thaw := s.qgaFreezeFs()
 		err := disk.CreateSnapshot(snapshotId, encryptKey, encFormat, encAlg)
 		if err != nil {
+			thaw()
 			return nil, errors.Wrap(err, "disk.CreateSnapshot")
 		}
-		task := NewGuestDiskSnapshotTask(ctx, s, disk, snapshotId)
+		task := NewGuestDiskSnapshotTask(ctx, s, disk, snapshotId, thaw)
 		task.Start()
 		return nil, nil
 	} else {
@@ -35,6 +35,7 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/qemu"
 	qemucerts "yunion.io/x/onecloud/pkg/hostman/guestman/qemu/certs"
+	"yunion.io/x/onecloud/pkg/hostman/hostinfo"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/util/procutils"
 	"yunion.io/x/onecloud/pkg/util/qemutils"
@@ -112,6 +113,25 @@ func (s *SKVMGuestInstance) isMemcleanEnabled() bool {
 	return s.Desc.Metadata["enable_memclean"] == "true"
 }
 
+// getNumaNode returns the host NUMA node the scheduler bound the guest to,
+// nil if not bound or the node is not present on this host
+func (s *SKVMGuestInstance) getNumaNode() *int {
+	nodeStr, ok := s.Desc.Metadata[api.VM_METADATA_NUMA_NODE]
+	if !ok || len(nodeStr) == 0 {
+		return nil
+	}
+	node, err := strconv.Atoi(nodeStr)
+	if err != nil {
+		log.Errorf("guest %s invalid numa node %q", s.Id, nodeStr)
+		return nil
+	}
+	if _, err := hostinfo.GetNumaNodeCpus(node); err != nil {
+		log.Warningf("guest %s numa node %d not available: %v", s.Id, node, err)
+		return nil
+	}
+	return &node
+}
+
 func (s *SKVMGuestInstance) getMachine() string {
 	machine := s.Desc.Machine
 	if machine == "" {
@@ -228,6 +248,7 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 		HomeDir:              s.HomeDir(),
 		HugepagesEnabled:     s.manager.host.IsHugepagesEnabled(),
 		EnableMemfd:          s.isMemcleanEnabled(),
+		NumaNode:             s.getNumaNode(),
 		PidFilePath:          s.GetPidFilePath(),
 		BIOS:                 s.getBios(),
 	}
@@ -278,8 +299,16 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 	}
 	isolatedDevsParams := s.manager.GetHost().GetIsolatedDeviceManager().GetQemuParams(devAddrs)
 	input.IsolatedDevicesParams = isolatedDevsParams
+	devScripts, err := s.manager.GetHost().GetIsolatedDeviceManager().GetGuestStartScripts(s.Desc.IsolatedDevices, s.Desc.Nics)
+	if err != nil {
+		return "", errors.Wrap(err, "GetGuestStartScripts")
+	}
+	cmd += devScripts
 
 	for _, nic := range input.Nics {
+		if nic.Driver == api.NETWORK_DRIVER_VFIO {
+			continue
+		}
 		downscript := s.getNicDownScriptPath(nic)
 		cmd += fmt.Sprintf("%s %s\n", downscript, nic.Ifname)
 	}
@@ -509,6 +538,13 @@ function nic_mtu() {
 			s.LiveMigrateUseTls = true
 			input.LiveMigrateUseTLS = true
 		}
+		if data.Contains("live_migrate_tuning") {
+			s.LiveMigrateTuning = new(api.GuestLiveMigrateTuning)
+			if err := data.Unmarshal(s.LiveMigrateTuning, "live_migrate_tuning"); err != nil {
+				return "", errors.Wrap(err, "unmarshal live_migrate_tuning")
+			}
+		}
+		input.LiveMigrateDeferIncoming = s.isDeferMigrateIncoming()
 	} else if s.Desc.IsSlave {
 		input.IsSlave = true
 		input.LiveMigratePort = uint(s.manager.GetFreePortByBase(LIVE_MIGRATE_PORT_BASE))
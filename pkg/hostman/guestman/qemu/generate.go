@@ -42,6 +42,7 @@ type GenerateStartOptionsInput struct {
 	UUID                  string
 	Mem                   uint64
 	Cpu                   uint
+	NumaNode              *int
 	Name                  string
 	OsName                string
 	HugepagesEnabled      bool
@@ -75,9 +76,11 @@ type GenerateStartOptionsInput struct {
 	NeedMigrate           bool
 	LiveMigratePort       uint
 	LiveMigrateUseTLS     bool
-	IsSlave               bool
-	IsMaster              bool
-	EnablePvpanic         bool
+	// set migrate capabilities by qmp before migrate incoming
+	LiveMigrateDeferIncoming bool
+	IsSlave                  bool
+	IsMaster                 bool
+	EnablePvpanic            bool
 
 	EncryptKeyPath string
 }
@@ -131,11 +134,11 @@ func GenerateStartOptions(
 
 	var memDev string
 	if input.HugepagesEnabled {
-		memDev = drvOpt.MemPath(input.Mem, fmt.Sprintf("/dev/hugepages/%s", input.UUID))
+		memDev = drvOpt.MemPath(input.Mem, fmt.Sprintf("/dev/hugepages/%s", input.UUID), input.NumaNode)
 	} else if input.EnableMemfd {
-		memDev = drvOpt.MemFd(input.Mem)
+		memDev = drvOpt.MemFd(input.Mem, input.NumaNode)
 	} else {
-		memDev = drvOpt.MemDev(input.Mem)
+		memDev = drvOpt.MemDev(input.Mem, input.NumaNode)
 	}
 	opts = append(opts, memDev)
 
@@ -412,6 +415,10 @@ func generateNicOptions(drvOpt QemuOptions, input *GenerateStartOptionsInput) ([
 	 */
 	withAddr := false
 	for idx := range nics {
+		if nics[idx].Driver == api.NETWORK_DRIVER_VFIO {
+			// passthrough by NIC-VF isolated device
+			continue
+		}
 		netDevOpt, err := getNicNetdevOption(drvOpt, nics[idx], input.IsKVMSupport)
 		if err != nil {
 			return nil, errors.Wrapf(err, "getNicNetdevOption %v", nics[idx])
@@ -515,7 +522,7 @@ func getRNGRandomOptions(drvOpt QemuOptions) []string {
 func getMigrateOptions(drvOpt QemuOptions, input *GenerateStartOptionsInput) []string {
 	opts := []string{}
 	if input.NeedMigrate {
-		if input.LiveMigrateUseTLS {
+		if input.LiveMigrateUseTLS || input.LiveMigrateDeferIncoming {
 			opts = append(opts, fmt.Sprintf("-incoming defer"))
 		} else {
 			opts = append(opts, fmt.Sprintf("-incoming tcp:0:%d", input.LiveMigratePort))
@@ -94,9 +94,9 @@ type QemuOptions interface {
 	Name(name string) string
 	UUID(enable bool, uuid string) string
 	Memory(sizeMB uint64) string
-	MemPath(sizeMB uint64, p string) string
-	MemDev(sizeMB uint64) string
-	MemFd(sizeMB uint64) string
+	MemPath(sizeMB uint64, p string, hostNode *int) string
+	MemDev(sizeMB uint64, hostNode *int) string
+	MemFd(sizeMB uint64, hostNode *int) string
 	Boot(order string, enableMenu bool) string
 	BIOS(file string) string
 	Device(devStr string) string
@@ -246,16 +246,24 @@ func (o baseOptions) MemPrealloc() string {
 	return "-mem-prealloc"
 }
 
-func (o baseOptions) MemPath(sizeMB uint64, p string) string {
-	return fmt.Sprintf("-object memory-backend-file,id=mem,size=%dM,mem-path=%s,share=on,prealloc=on -numa node,memdev=mem", sizeMB, p)
+// memHostNodes binds guest memory to the given host NUMA node
+func memHostNodes(hostNode *int) string {
+	if hostNode == nil {
+		return ""
+	}
+	return fmt.Sprintf(",host-nodes=%d,policy=bind", *hostNode)
+}
+
+func (o baseOptions) MemPath(sizeMB uint64, p string, hostNode *int) string {
+	return fmt.Sprintf("-object memory-backend-file,id=mem,size=%dM,mem-path=%s,share=on,prealloc=on%s -numa node,memdev=mem", sizeMB, p, memHostNodes(hostNode))
 }
 
-func (o baseOptions) MemDev(sizeMB uint64) string {
-	return fmt.Sprintf("-object memory-backend-ram,id=mem,size=%dM -numa node,memdev=mem", sizeMB)
+func (o baseOptions) MemDev(sizeMB uint64, hostNode *int) string {
+	return fmt.Sprintf("-object memory-backend-ram,id=mem,size=%dM%s -numa node,memdev=mem", sizeMB, memHostNodes(hostNode))
 }
 
-func (o baseOptions) MemFd(sizeMB uint64) string {
-	return fmt.Sprintf("-object memory-backend-memfd,id=mem,size=%dM,share=on,prealloc=on -numa node,memdev=mem", sizeMB)
+func (o baseOptions) MemFd(sizeMB uint64, hostNode *int) string {
+	return fmt.Sprintf("-object memory-backend-memfd,id=mem,size=%dM,share=on,prealloc=on%s -numa node,memdev=mem", sizeMB, memHostNodes(hostNode))
 }
 
 func (o baseOptions) Boot(order string, enableMenu bool) string {
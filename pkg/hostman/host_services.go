@@ -125,16 +125,35 @@ func (host *SHostService) RunService() {
 	host.initHandlers(app)
 
 	// Init Metadata handler
+	metadataService := &metadata.Service{
+		Address: options.HostOptions.Address,
+		Port:    options.HostOptions.Port + 1000,
+		DescGetter: metadata.DescGetterFunc(func(ip string) *desc.SGuestDesc {
+			guestDesc, _ := guestman.GetGuestManager().GetGuestNicDesc("", ip, "", "", false)
+			return guestDesc
+		}),
+		Region:       options.HostOptions.Region,
+		RequireToken: options.HostOptions.MetadataRequireToken,
+	}
+	if len(options.HostOptions.MetadataIdentityKeyFile) > 0 {
+		key, err := metadata.LoadIdentityKey(options.HostOptions.MetadataIdentityKeyFile)
+		if err != nil {
+			log.Errorf("load metadata identity key: %s", err)
+		} else {
+			metadataService.IdentityKey = key
+		}
+	}
+	if len(options.HostOptions.MetadataVendorDataFile) > 0 {
+		vendorData, err := ioutil.ReadFile(options.HostOptions.MetadataVendorDataFile)
+		if err != nil {
+			log.Errorf("read metadata vendor data %s: %s", options.HostOptions.MetadataVendorDataFile, err)
+		} else {
+			metadataService.VendorData = string(vendorData)
+		}
+	}
 	go metadata.Start(
 		app_common.InitApp(&options.HostOptions.BaseOptions, false),
-		&metadata.Service{
-			Address: options.HostOptions.Address,
-			Port:    options.HostOptions.Port + 1000,
-			DescGetter: metadata.DescGetterFunc(func(ip string) *desc.SGuestDesc {
-				guestDesc, _ := guestman.GetGuestManager().GetGuestNicDesc("", ip, "", "", false)
-				return guestDesc
-			}),
-		},
+		metadataService,
 	)
 
 	cronManager.AddJobEveryFewDays(
@@ -158,6 +177,9 @@ func (host *SHostService) initHandlers(app *appsrv.Application) {
 	downloader.AddDownloadHandler("", app)
 	kubehandlers.AddKubeAgentHandler("", app)
 	hosthandler.AddHostHandler("", app)
+	if options.HostOptions.EnablePrometheusExporter {
+		app.AddDefaultHandler("GET", "/metrics", hostmetrics.PrometheusMetricsHandler, "metrics")
+	}
 
 	app_common.ExportOptionsHandler(app, &options.HostOptions)
 }
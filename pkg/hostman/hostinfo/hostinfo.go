@@ -1733,6 +1733,16 @@ func (h *SHostInfo) probeSyncIsolatedDevices() (*jsonutils.JSONArray, error) {
 	if err := h.IsolatedDeviceMan.ProbePCIDevices(options.HostOptions.DisableGPU, options.HostOptions.DisableUSB); err != nil {
 		return nil, errors.Wrap(err, "ProbePCIDevices")
 	}
+	if len(options.HostOptions.SRIOVNics) > 0 {
+		if err := h.IsolatedDeviceMan.ProbeSRIOVDevices(options.HostOptions.SRIOVNics); err != nil {
+			return nil, errors.Wrap(err, "ProbeSRIOVDevices")
+		}
+	}
+	if !options.HostOptions.DisableMdev {
+		if err := h.IsolatedDeviceMan.ProbeMdevDevices(options.HostOptions.MdevTypes); err != nil {
+			return nil, errors.Wrap(err, "ProbeMdevDevices")
+		}
+	}
 
 	objs, err := h.getRemoteIsolatedDevices()
 	if err != nil {
@@ -1762,6 +1772,11 @@ func (h *SHostInfo) probeSyncIsolatedDevices() (*jsonutils.JSONArray, error) {
 		if obj, err := isolated_device.SyncDeviceInfo(h.GetSession(), h.HostId, dev); err != nil {
 			return nil, errors.Wrapf(err, "Sync device %s", dev)
 		} else {
+			// mdev instance uuid is taken from cloud id of newly created device
+			if len(dev.GetCloudId()) == 0 {
+				id, _ := obj.GetString("id")
+				dev.SetDeviceInfo(isolated_device.CloudDeviceInfo{Id: id})
+			}
 			updateDevs.Add(obj)
 		}
 	}
@@ -0,0 +1,112 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostinfo
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/util/cgrouputils"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+)
+
+const sysNumaNodePath = "/sys/devices/system/node"
+
+// getNumaNodes reads the NUMA nodes of the host from sysfs, it returns
+// nothing on hosts without NUMA support
+func getNumaNodes() ([]api.HostNumaNode, error) {
+	files, err := ioutil.ReadDir(sysNumaNodePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "read numa nodes")
+	}
+	nodes := make([]api.HostNumaNode, 0)
+	for _, f := range files {
+		if !f.IsDir() || !strings.HasPrefix(f.Name(), "node") {
+			continue
+		}
+		nodeId, err := strconv.Atoi(strings.TrimPrefix(f.Name(), "node"))
+		if err != nil {
+			continue
+		}
+		nodePath := path.Join(sysNumaNodePath, f.Name())
+		cpulist, err := fileutils2.FileGetContents(path.Join(nodePath, "cpulist"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "read cpulist of node %d", nodeId)
+		}
+		meminfo, err := fileutils2.FileGetContents(path.Join(nodePath, "meminfo"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "read meminfo of node %d", nodeId)
+		}
+		nodes = append(nodes, parseNumaNode(nodeId, cpulist, meminfo))
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].NodeId < nodes[j].NodeId
+	})
+	return nodes, nil
+}
+
+// GetNumaNodeCpus returns the cpus of a host NUMA node, an error is
+// returned if the node does not exist
+func GetNumaNodeCpus(nodeId int) ([]int, error) {
+	cpulist, err := fileutils2.FileGetContents(path.Join(sysNumaNodePath, "node"+strconv.Itoa(nodeId), "cpulist"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "read cpulist of node %d", nodeId)
+	}
+	return parseNumaNode(nodeId, cpulist, "").Cpus, nil
+}
+
+// parseNumaNode parses the cpulist, e.g. 0-3,8-11, and the meminfo, lines
+// like "Node 0 MemTotal: 65772384 kB", of a node
+func parseNumaNode(nodeId int, cpulist, meminfo string) api.HostNumaNode {
+	node := api.HostNumaNode{
+		NodeId: nodeId,
+		Cpus:   make([]int, 0),
+	}
+	cpulist = strings.TrimSpace(cpulist)
+	if len(cpulist) > 0 {
+		for _, cpu := range strings.Split(cgrouputils.ParseCpusetStr(cpulist), ",") {
+			if id, err := strconv.Atoi(cpu); err == nil {
+				node.Cpus = append(node.Cpus, id)
+			}
+		}
+	}
+	for _, line := range strings.Split(meminfo, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		sizeKb, err := strconv.Atoi(fields[3])
+		if err != nil {
+			continue
+		}
+		switch fields[2] {
+		case "MemTotal:":
+			node.MemSizeMb = sizeKb / 1024
+		case "MemFree:":
+			node.MemFreeMb = sizeKb / 1024
+		}
+	}
+	return node
+}
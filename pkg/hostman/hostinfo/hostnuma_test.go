@@ -0,0 +1,43 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNumaNode(t *testing.T) {
+	meminfo := `Node 1 MemTotal:       65772384 kB
+Node 1 MemFree:        32886192 kB
+Node 1 MemUsed:        32886192 kB
+`
+	node := parseNumaNode(1, "4-5,10\n", meminfo)
+	if node.NodeId != 1 {
+		t.Errorf("node id %d", node.NodeId)
+	}
+	if !reflect.DeepEqual(node.Cpus, []int{4, 5, 10}) {
+		t.Errorf("cpus %v", node.Cpus)
+	}
+	if node.MemSizeMb != 64230 || node.MemFreeMb != 32115 {
+		t.Errorf("memory %d/%d", node.MemFreeMb, node.MemSizeMb)
+	}
+
+	// memory only node
+	node = parseNumaNode(2, "\n", "")
+	if len(node.Cpus) != 0 || node.MemSizeMb != 0 {
+		t.Errorf("empty node %#v", node)
+	}
+}
@@ -113,6 +113,12 @@ func (p *SHostPingTask) payload() api.SHostPingInput {
 	memFree := int(info.Available / 1024 / 1024)
 	memUsed := memTotal - memFree
 	data.MemoryUsedMb = memUsed
+	numaNodes, err := getNumaNodes()
+	if err != nil {
+		log.Errorf("get numa nodes: %v", err)
+	} else {
+		data.NumaNodes = numaNodes
+	}
 	return data
 }
 
@@ -21,6 +21,7 @@ import (
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
@@ -153,6 +154,9 @@ type SGuestMonitorCollector struct {
 	monitors       map[string]*SGuestMonitor
 	prevPids       map[string]int
 	prevReportData *jsonutils.JSONDict
+
+	promLock    sync.Mutex
+	promSamples []sPromSample
 }
 
 func NewGuestMonitorCollector() *SGuestMonitorCollector {
@@ -224,6 +228,8 @@ func (s *SGuestMonitorCollector) CollectReportData() (ret string) {
 	}
 
 	s.prevReportData = reportData.DeepCopy().(*jsonutils.JSONDict)
+	// telegraf lines consume meta of reportData, collect samples beforehand
+	s.setPrometheusSamples(s.toPrometheusSamples(reportData))
 	ret = s.toTelegrafReportData(reportData)
 	return
 }
@@ -232,39 +238,9 @@ func (s *SGuestMonitorCollector) toTelegrafReportData(data *jsonutils.JSONDict)
 	ret := []string{}
 	vs, _ := data.GetMap()
 	for guestId, report := range vs {
-		var vmName, vmIp, scalingGroupId, tenant, tenantId, domainId, projectDomain string
-		if gm, ok := s.monitors[guestId]; ok {
-			vmName = gm.Name
-			vmIp = gm.Ip
-			scalingGroupId = gm.ScalingGroupId
-			tenant = gm.Tenant
-			tenantId = gm.TenantId
-			domainId = gm.DomainId
-			projectDomain = gm.ProjectDomain
-		}
-
 		rs, _ := report.(*jsonutils.JSONDict).GetMap()
 		for metrics, stat := range rs {
-			tags := map[string]string{
-				"vm_id": guestId, "vm_name": vmName, "vm_ip": vmIp,
-				"is_vm": "true", hostconsts.TELEGRAF_TAG_KEY_BRAND: hostconsts.TELEGRAF_TAG_ONECLOUD_BRAND,
-				hostconsts.TELEGRAF_TAG_KEY_RES_TYPE: "guest",
-			}
-			if len(scalingGroupId) > 0 {
-				tags["vm_scaling_group_id"] = scalingGroupId
-			}
-			if len(tenant) > 0 {
-				tags["tenant"] = tenant
-			}
-			if len(tenantId) > 0 {
-				tags["tenant_id"] = tenantId
-			}
-			if len(domainId) > 0 {
-				tags["domain_id"] = domainId
-			}
-			if len(projectDomain) > 0 {
-				tags["project_domain"] = projectDomain
-			}
+			tags := s.guestTags(guestId)
 			if val, ok := stat.(*jsonutils.JSONDict); ok {
 				line := s.addTelegrafLine(metrics, tags, val)
 				ret = append(ret, line)
@@ -280,6 +256,40 @@ func (s *SGuestMonitorCollector) toTelegrafReportData(data *jsonutils.JSONDict)
 	return strings.Join(ret, "\n")
 }
 
+func (s *SGuestMonitorCollector) guestTags(guestId string) map[string]string {
+	var vmName, vmIp, scalingGroupId, tenant, tenantId, domainId, projectDomain string
+	if gm, ok := s.monitors[guestId]; ok {
+		vmName = gm.Name
+		vmIp = gm.Ip
+		scalingGroupId = gm.ScalingGroupId
+		tenant = gm.Tenant
+		tenantId = gm.TenantId
+		domainId = gm.DomainId
+		projectDomain = gm.ProjectDomain
+	}
+	tags := map[string]string{
+		"vm_id": guestId, "vm_name": vmName, "vm_ip": vmIp,
+		"is_vm": "true", hostconsts.TELEGRAF_TAG_KEY_BRAND: hostconsts.TELEGRAF_TAG_ONECLOUD_BRAND,
+		hostconsts.TELEGRAF_TAG_KEY_RES_TYPE: "guest",
+	}
+	if len(scalingGroupId) > 0 {
+		tags["vm_scaling_group_id"] = scalingGroupId
+	}
+	if len(tenant) > 0 {
+		tags["tenant"] = tenant
+	}
+	if len(tenantId) > 0 {
+		tags["tenant_id"] = tenantId
+	}
+	if len(domainId) > 0 {
+		tags["domain_id"] = domainId
+	}
+	if len(projectDomain) > 0 {
+		tags["project_domain"] = projectDomain
+	}
+	return tags
+}
+
 func (s *SGuestMonitorCollector) addTelegrafLine(
 	metrics string, tags map[string]string, stat *jsonutils.JSONDict,
 ) string {
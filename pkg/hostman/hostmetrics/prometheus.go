@@ -0,0 +1,208 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostmetrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/hostman/monitor"
+	"yunion.io/x/onecloud/pkg/hostman/storageman"
+)
+
+const (
+	PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	promTypeGauge   = "gauge"
+	promTypeCounter = "counter"
+	promTypeSummary = "summary"
+)
+
+type sPromSample struct {
+	// Family is the metric name in TYPE line, defaults to Name
+	Family string
+	Name   string
+	Type   string
+	Labels map[string]string
+	Value  float64
+}
+
+func (sample *sPromSample) family() string {
+	if len(sample.Family) > 0 {
+		return sample.Family
+	}
+	return sample.Name
+}
+
+// PrometheusMetricsHandler exposes guest metrics of the last collection
+// and host agent internal metrics in prometheus text format
+func PrometheusMetricsHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	samples := collectAgentSamples()
+	if hostMetricsCollector != nil {
+		samples = append(samples, hostMetricsCollector.guestMonitor.getPrometheusSamples()...)
+	}
+	w.Header().Set("Content-Type", PrometheusContentType)
+	writePromSamples(w, samples)
+}
+
+func (s *SGuestMonitorCollector) setPrometheusSamples(samples []sPromSample) {
+	s.promLock.Lock()
+	defer s.promLock.Unlock()
+	s.promSamples = samples
+}
+
+func (s *SGuestMonitorCollector) getPrometheusSamples() []sPromSample {
+	s.promLock.Lock()
+	defer s.promLock.Unlock()
+	return s.promSamples
+}
+
+func (s *SGuestMonitorCollector) toPrometheusSamples(data *jsonutils.JSONDict) []sPromSample {
+	ret := []sPromSample{}
+	vs, _ := data.GetMap()
+	for guestId, report := range vs {
+		tags := s.guestTags(guestId)
+		rs, _ := report.(*jsonutils.JSONDict).GetMap()
+		for measurement, stat := range rs {
+			if val, ok := stat.(*jsonutils.JSONDict); ok {
+				ret = append(ret, statToPromSamples(measurement, tags, val)...)
+			} else if val, ok := stat.(*jsonutils.JSONArray); ok {
+				ss, _ := val.GetArray()
+				for _, statItem := range ss {
+					if item, ok := statItem.(*jsonutils.JSONDict); ok {
+						ret = append(ret, statToPromSamples(measurement, tags, item)...)
+					}
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// statToPromSamples converts fields of one stat to gauges, meta of the stat
+// except uptime becomes labels, e.g. ifname of netio
+func statToPromSamples(measurement string, tags map[string]string, stat *jsonutils.JSONDict) []sPromSample {
+	labels := make(map[string]string, len(tags))
+	for k, v := range tags {
+		labels[k] = v
+	}
+	if meta, err := stat.GetMap("meta"); err == nil {
+		for k, v := range meta {
+			if k == "uptime" {
+				continue
+			}
+			labels[k], _ = v.GetString()
+		}
+	}
+
+	ret := []sPromSample{}
+	ss, _ := stat.GetMap()
+	for k, v := range ss {
+		if k == "meta" {
+			continue
+		}
+		str, _ := v.GetString()
+		value, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, sPromSample{
+			Name:   promName(measurement + "_" + k),
+			Type:   promTypeGauge,
+			Labels: labels,
+			Value:  value,
+		})
+	}
+	return ret
+}
+
+func collectAgentSamples() []sPromSample {
+	ret := []sPromSample{}
+	for _, state := range appsrv.GetWorkerManagerStates() {
+		labels := map[string]string{"worker": state.Name}
+		ret = append(ret,
+			sPromSample{Name: "host_agent_worker_queue_size", Type: promTypeGauge, Labels: labels, Value: float64(state.QueueCnt)},
+			sPromSample{Name: "host_agent_worker_active_count", Type: promTypeGauge, Labels: labels, Value: float64(state.ActiveWorkerCnt)},
+			sPromSample{Name: "host_agent_worker_max_count", Type: promTypeGauge, Labels: labels, Value: float64(state.MaxWorkerCnt)},
+		)
+	}
+
+	const qmpFamily = "host_agent_qmp_command_duration_seconds"
+	for cmd, stats := range monitor.GetQmpCommandStats() {
+		labels := map[string]string{"command": cmd}
+		ret = append(ret,
+			sPromSample{Family: qmpFamily, Name: qmpFamily + "_sum", Type: promTypeSummary, Labels: labels, Value: stats.TotalSeconds},
+			sPromSample{Family: qmpFamily, Name: qmpFamily + "_count", Type: promTypeSummary, Labels: labels, Value: float64(stats.Count)},
+		)
+	}
+
+	hits, misses := storageman.GetImageCacheStats()
+	ret = append(ret,
+		sPromSample{Name: "host_agent_image_cache_requests_total", Type: promTypeCounter, Labels: map[string]string{"result": "hit"}, Value: float64(hits)},
+		sPromSample{Name: "host_agent_image_cache_requests_total", Type: promTypeCounter, Labels: map[string]string{"result": "miss"}, Value: float64(misses)},
+	)
+	return ret
+}
+
+func writePromSamples(w io.Writer, samples []sPromSample) {
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].family() < samples[j].family()
+	})
+	lastFamily := ""
+	for i := range samples {
+		sample := &samples[i]
+		if family := sample.family(); family != lastFamily {
+			fmt.Fprintf(w, "# TYPE %s %s\n", family, sample.Type)
+			lastFamily = family
+		}
+		fmt.Fprintf(w, "%s%s %s\n", sample.Name, promLabels(sample.Labels), strconv.FormatFloat(sample.Value, 'g', -1, 64))
+	}
+}
+
+func promLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", promName(k), promLabelValueEscaper.Replace(labels[k])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var promLabelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promName replaces characters not allowed in metric and label names
+func promName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
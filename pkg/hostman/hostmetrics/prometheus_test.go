@@ -0,0 +1,49 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostmetrics
+
+import (
+	"bytes"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+)
+
+func TestWritePromSamples(t *testing.T) {
+	stat := jsonutils.NewDict()
+	meta := jsonutils.NewDict()
+	meta.Set("ifname", jsonutils.NewString("vnic0"))
+	meta.Set("uptime", jsonutils.NewInt(100))
+	stat.Set("meta", meta)
+	stat.Set("bps_recv", jsonutils.NewFloat64(1.5))
+
+	samples := statToPromSamples("vm_netio", map[string]string{"vm_name": `a "b"`}, stat)
+	samples = append(samples, sPromSample{
+		Family: "x_seconds",
+		Name:   "x_seconds_count",
+		Type:   promTypeSummary,
+		Value:  3,
+	})
+
+	buf := &bytes.Buffer{}
+	writePromSamples(buf, samples)
+	want := "# TYPE vm_netio_bps_recv gauge\n" +
+		`vm_netio_bps_recv{ifname="vnic0",vm_name="a \"b\""} 1.5` + "\n" +
+		"# TYPE x_seconds summary\n" +
+		"x_seconds_count 3\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -86,10 +86,14 @@ type IsolatedDeviceManager interface {
 	GetDevices() []IDevice
 	GetDeviceByIdent(vendorDevId string, addr string) IDevice
 	ProbePCIDevices(skipGPUs, skipUSBs bool) error
+	ProbeSRIOVDevices(nics []string) error
+	ProbeMdevDevices(mdevTypes []string) error
 	StartDetachTask()
 	BatchCustomProbe() error
 	AppendDetachedDevice(dev *CloudDeviceInfo)
 	GetQemuParams(devAddrs []string) *QemuParams
+	GetGuestStartScripts(devs []*api.IsolatedDeviceJsonDesc, nics []*api.GuestnetworkJsonDesc) (string, error)
+	CleanupGuestDevices(devs []*api.IsolatedDeviceJsonDesc)
 }
 
 type isolatedDeviceManager struct {
@@ -142,6 +146,71 @@ func (man *isolatedDeviceManager) ProbePCIDevices(skipGPUs, skipUSBs bool) error
 	return nil
 }
 
+func (man *isolatedDeviceManager) ProbeSRIOVDevices(nics []string) error {
+	for _, nic := range nics {
+		vfs, err := getSRIOVVFDevices(nic)
+		if err != nil {
+			return errors.Wrapf(err, "getSRIOVVFDevices %s", nic)
+		}
+		for _, vf := range vfs {
+			man.devices = append(man.devices, vf)
+		}
+	}
+	return nil
+}
+
+func (man *isolatedDeviceManager) ProbeMdevDevices(mdevTypes []string) error {
+	mdevs, err := getMdevDevices(mdevTypes)
+	if err != nil {
+		return errors.Wrap(err, "getMdevDevices")
+	}
+	for _, mdev := range mdevs {
+		man.devices = append(man.devices, mdev)
+	}
+	return nil
+}
+
+// GetGuestStartScripts returns commands preparing guest isolated devices
+// before qemu starts: mdev instances are created on demand and NIC VFs take
+// mac and vlan of guest nics using vfio-pci driver in order.
+func (man *isolatedDeviceManager) GetGuestStartScripts(devs []*api.IsolatedDeviceJsonDesc, nics []*api.GuestnetworkJsonDesc) (string, error) {
+	vfNics := make([]*api.GuestnetworkJsonDesc, 0)
+	for _, nic := range nics {
+		if nic.Driver == api.NETWORK_DRIVER_VFIO {
+			vfNics = append(vfNics, nic)
+		}
+	}
+	cmd := ""
+	for _, desc := range devs {
+		switch dev := man.GetDeviceByAddr(desc.Addr).(type) {
+		case *sMdevDevice:
+			cmd += dev.GetCreateCmd(desc.Id) + "\n"
+		case *sSRIOVVFDevice:
+			if len(vfNics) == 0 {
+				return "", errors.Errorf("no %s nic for VF %s", api.NETWORK_DRIVER_VFIO, desc.Addr)
+			}
+			cmd += dev.GetNicSetupCmd(vfNics[0].Mac, vfNics[0].Vlan) + "\n"
+			vfNics = vfNics[1:]
+		}
+	}
+	if len(vfNics) > 0 {
+		return "", errors.Errorf("%d %s nics have no VF attached", len(vfNics), api.NETWORK_DRIVER_VFIO)
+	}
+	return cmd, nil
+}
+
+// CleanupGuestDevices removes mdev instances created for the guest
+func (man *isolatedDeviceManager) CleanupGuestDevices(devs []*api.IsolatedDeviceJsonDesc) {
+	for _, desc := range devs {
+		if desc.DevType != api.MDEV_TYPE {
+			continue
+		}
+		if err := removeMdevInstance(desc.Id); err != nil {
+			log.Errorf("cleanup guest device %s: %v", desc.Addr, err)
+		}
+	}
+}
+
 func (man *isolatedDeviceManager) getSession() *mcclient.ClientSession {
 	return man.host.GetSession()
 }
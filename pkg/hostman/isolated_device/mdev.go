@@ -0,0 +1,188 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolated_device
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+)
+
+const (
+	SYSFS_MDEV_BUS_DIR     = "/sys/class/mdev_bus"
+	SYSFS_MDEV_DEVICES_DIR = "/sys/bus/mdev/devices"
+)
+
+type sMdevType struct {
+	// type id, e.g. nvidia-222
+	Id string
+	// human readable name, e.g. GRID T4-2Q
+	Name               string
+	AvailableInstances int
+	// instances already created of this type
+	Instances int
+}
+
+func (t *sMdevType) GetModelName() string {
+	if len(t.Name) != 0 {
+		return t.Name
+	}
+	return t.Id
+}
+
+// listMdevTypes list supported mdev types of a parent device directory,
+// e.g. /sys/class/mdev_bus/0000:3b:00.0
+func listMdevTypes(parentDir string) ([]*sMdevType, error) {
+	typesDir := path.Join(parentDir, "mdev_supported_types")
+	fis, err := ioutil.ReadDir(typesDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", typesDir)
+	}
+	types := make([]*sMdevType, 0, len(fis))
+	for _, fi := range fis {
+		typeDir := path.Join(typesDir, fi.Name())
+		t := &sMdevType{Id: fi.Name()}
+		if name, err := fileutils2.FileGetContents(path.Join(typeDir, "name")); err == nil {
+			t.Name = strings.TrimSpace(name)
+		}
+		t.AvailableInstances, err = readSysfsInt(path.Join(typeDir, "available_instances"))
+		if err != nil {
+			return nil, err
+		}
+		if insts, err := ioutil.ReadDir(path.Join(typeDir, "devices")); err == nil {
+			t.Instances = len(insts)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+type sMdevDevice struct {
+	*sBaseDevice
+	// full pci address of parent device
+	parentAddr string
+	mdevType   *sMdevType
+}
+
+// newMdevDevice register one mdev instance slot of parent device,
+// the slot address is `<parent_addr>/<index>`
+func newMdevDevice(parent *PCIDevice, parentAddr string, mdevType *sMdevType, index int) *sMdevDevice {
+	dev := *parent
+	dev.Addr = fmt.Sprintf("%s/%d", parent.Addr, index)
+	dev.ModelName = mdevType.GetModelName()
+	dev.RestIOMMUGroupDevs = nil
+	return &sMdevDevice{
+		sBaseDevice: newBaseDevice(&dev, api.MDEV_TYPE),
+		parentAddr:  parentAddr,
+		mdevType:    mdevType,
+	}
+}
+
+func (dev *sMdevDevice) GetCPUCmd() string {
+	return ""
+}
+
+func (dev *sMdevDevice) GetVGACmd() string {
+	return ""
+}
+
+func (dev *sMdevDevice) CustomProbe() error {
+	return nil
+}
+
+func (dev *sMdevDevice) DetectByAddr() error {
+	parentDir := path.Join(SYSFS_MDEV_BUS_DIR, dev.parentAddr)
+	if !fileutils2.Exists(parentDir) {
+		return errors.Wrapf(errors.ErrNotFound, "mdev parent %s", dev.parentAddr)
+	}
+	return nil
+}
+
+func getMdevDevicePath(uuid string) string {
+	return path.Join(SYSFS_MDEV_DEVICES_DIR, uuid)
+}
+
+// GetPassthroughCmd use the cloud device id as mdev instance uuid
+func (dev *sMdevDevice) GetPassthroughCmd(_ int) string {
+	return fmt.Sprintf(" -device vfio-pci,sysfsdev=%s", getMdevDevicePath(dev.GetCloudId()))
+}
+
+func (dev *sMdevDevice) GetIOMMUGroupDeviceCmd() string {
+	return ""
+}
+
+// GetCreateCmd returns command creating mdev instance on demand
+func (dev *sMdevDevice) GetCreateCmd(uuid string) string {
+	createPath := path.Join(SYSFS_MDEV_BUS_DIR, dev.parentAddr, "mdev_supported_types", dev.mdevType.Id, "create")
+	return fmt.Sprintf("[ -e %s ] || echo %s > %s", getMdevDevicePath(uuid), uuid, createPath)
+}
+
+func removeMdevInstance(uuid string) error {
+	devPath := getMdevDevicePath(uuid)
+	if !fileutils2.Exists(devPath) {
+		return nil
+	}
+	if err := fileutils2.FilePutContents(path.Join(devPath, "remove"), "1", false); err != nil {
+		return errors.Wrapf(err, "remove mdev %s", uuid)
+	}
+	return nil
+}
+
+func (dev *sMdevDevice) GetHotPlugOptions() ([]*HotPlugOption, error) {
+	return nil, fmt.Errorf("Not implemented")
+}
+
+func (dev *sMdevDevice) GetHotUnplugOptions() ([]*HotUnplugOption, error) {
+	return nil, fmt.Errorf("Not implemented")
+}
+
+func getMdevDevices(enabledTypes []string) ([]*sMdevDevice, error) {
+	fis, err := ioutil.ReadDir(SYSFS_MDEV_BUS_DIR)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", SYSFS_MDEV_BUS_DIR)
+	}
+	devs := make([]*sMdevDevice, 0)
+	for _, fi := range fis {
+		parentAddr := fi.Name()
+		parent, err := detectPCIDevByAddrWithoutIOMMUGroup(shortPCIAddr(parentAddr))
+		if err != nil {
+			return nil, errors.Wrapf(err, "detect mdev parent %s", parentAddr)
+		}
+		types, err := listMdevTypes(path.Join(SYSFS_MDEV_BUS_DIR, parentAddr))
+		if err != nil {
+			return nil, err
+		}
+		index := 0
+		for _, t := range types {
+			if len(enabledTypes) != 0 && !utils.IsInStringArray(t.Id, enabledTypes) {
+				continue
+			}
+			for i := 0; i < t.AvailableInstances+t.Instances; i++ {
+				devs = append(devs, newMdevDevice(parent, parentAddr, t, index))
+				index++
+			}
+			log.Infof("Add mdev type %s(%s) of %s: %d instances", t.Id, t.Name, parentAddr, t.AvailableInstances+t.Instances)
+		}
+	}
+	return devs, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolated_device
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func Test_listMdevTypes(t *testing.T) {
+	parentDir, err := ioutil.TempDir("", "mdev-parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parentDir)
+
+	for _, mt := range []struct {
+		id        string
+		name      string
+		available string
+		instances []string
+	}{
+		{id: "nvidia-222", name: "GRID T4-1B\n", available: "14\n", instances: []string{"c5f7e2b0-5d6a-4c49-9f3c-1f1e4b2f7a01", "c5f7e2b0-5d6a-4c49-9f3c-1f1e4b2f7a02"}},
+		{id: "nvidia-230", available: "0\n"},
+	} {
+		typeDir := path.Join(parentDir, "mdev_supported_types", mt.id)
+		if err := os.MkdirAll(path.Join(typeDir, "devices"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if len(mt.name) != 0 {
+			ioutil.WriteFile(path.Join(typeDir, "name"), []byte(mt.name), 0644)
+		}
+		ioutil.WriteFile(path.Join(typeDir, "available_instances"), []byte(mt.available), 0644)
+		for _, inst := range mt.instances {
+			os.Mkdir(path.Join(typeDir, "devices", inst), 0755)
+		}
+	}
+
+	types, err := listMdevTypes(parentDir)
+	if err != nil {
+		t.Fatalf("listMdevTypes: %v", err)
+	}
+	if len(types) != 2 {
+		t.Fatalf("want 2 types, got %d", len(types))
+	}
+	if got := types[0]; got.Id != "nvidia-222" || got.GetModelName() != "GRID T4-1B" || got.AvailableInstances != 14 || got.Instances != 2 {
+		t.Errorf("unexpected type %#v", got)
+	}
+	if got := types[1]; got.GetModelName() != "nvidia-230" || got.AvailableInstances != 0 || got.Instances != 0 {
+		t.Errorf("unexpected type %#v", got)
+	}
+
+	parent := &PCIDevice{Addr: "3b:00.0", VendorId: "10de", DeviceId: "1eb8", ModelName: "Tesla T4"}
+	dev := newMdevDevice(parent, "0000:3b:00.0", types[0], 3)
+	if dev.GetAddr() != "3b:00.0/3" || dev.GetModelName() != "GRID T4-1B" || dev.GetDeviceType() != api.MDEV_TYPE {
+		t.Errorf("unexpected mdev device %s", dev)
+	}
+	if parent.Addr != "3b:00.0" {
+		t.Errorf("parent device modified: %s", parent.Addr)
+	}
+	uuid := "c5f7e2b0-5d6a-4c49-9f3c-1f1e4b2f7a03"
+	want := "[ -e /sys/bus/mdev/devices/" + uuid + " ] || echo " + uuid + " > /sys/class/mdev_bus/0000:3b:00.0/mdev_supported_types/nvidia-222/create"
+	if got := dev.GetCreateCmd(uuid); got != want {
+		t.Errorf("GetCreateCmd() = %q, want %q", got, want)
+	}
+}
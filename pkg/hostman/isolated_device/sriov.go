@@ -0,0 +1,250 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolated_device
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+)
+
+const (
+	SYSFS_CLASS_NET_DIR   = "/sys/class/net"
+	SYSFS_PCI_DEVICES_DIR = "/sys/bus/pci/devices"
+)
+
+// parseSRIOVNic parse SR-IOV nic option of `ifname:num_vfs` format,
+// num_vfs is optional and means using all supported VFs.
+func parseSRIOVNic(spec string) (string, int, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) > 2 || len(parts[0]) == 0 {
+		return "", 0, errors.Errorf("invalid sriov nic %q", spec)
+	}
+	if len(parts) == 1 {
+		return parts[0], -1, nil
+	}
+	numVfs, err := strconv.Atoi(parts[1])
+	if err != nil || numVfs < 0 {
+		return "", 0, errors.Errorf("invalid sriov nic %q vf number", spec)
+	}
+	return parts[0], numVfs, nil
+}
+
+// shortPCIAddr trims pci domain of `0000:3b:00.0` format address
+func shortPCIAddr(addr string) string {
+	if len(addr) == 12 && strings.HasPrefix(addr, "0000:") {
+		return addr[5:]
+	}
+	return addr
+}
+
+func readSysfsInt(p string) (int, error) {
+	content, err := fileutils2.FileGetContents(p)
+	if err != nil {
+		return 0, errors.Wrapf(err, "read %s", p)
+	}
+	return strconv.Atoi(strings.TrimSpace(content))
+}
+
+type sSRIOVPhysicalFunction struct {
+	Ifname string
+	// full pci address of `0000:3b:00.0` format
+	Addr     string
+	TotalVfs int
+}
+
+func getSRIOVPhysicalFunction(ifname string) (*sSRIOVPhysicalFunction, error) {
+	devPath, err := filepath.EvalSymlinks(path.Join(SYSFS_CLASS_NET_DIR, ifname, "device"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "nic %s pci device", ifname)
+	}
+	pf := &sSRIOVPhysicalFunction{
+		Ifname: ifname,
+		Addr:   path.Base(devPath),
+	}
+	pf.TotalVfs, err = readSysfsInt(path.Join(devPath, "sriov_totalvfs"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "nic %s not support SR-IOV", ifname)
+	}
+	return pf, nil
+}
+
+func (pf *sSRIOVPhysicalFunction) sysfsPath(name string) string {
+	return path.Join(SYSFS_PCI_DEVICES_DIR, pf.Addr, name)
+}
+
+func (pf *sSRIOVPhysicalFunction) GetNumVfs() (int, error) {
+	return readSysfsInt(pf.sysfsPath("sriov_numvfs"))
+}
+
+// SetNumVfs create or destroy VFs, kernel requires resetting sriov_numvfs
+// to 0 before changing it to another non-zero value.
+func (pf *sSRIOVPhysicalFunction) SetNumVfs(numVfs int) error {
+	if numVfs < 0 || numVfs > pf.TotalVfs {
+		numVfs = pf.TotalVfs
+	}
+	cur, err := pf.GetNumVfs()
+	if err != nil {
+		return err
+	}
+	if cur == numVfs {
+		return nil
+	}
+	numVfsPath := pf.sysfsPath("sriov_numvfs")
+	if cur != 0 {
+		if err := fileutils2.FilePutContents(numVfsPath, "0", false); err != nil {
+			return errors.Wrapf(err, "destroy %s VFs", pf.Ifname)
+		}
+	}
+	if numVfs == 0 {
+		return nil
+	}
+	if err := fileutils2.FilePutContents(numVfsPath, strconv.Itoa(numVfs), false); err != nil {
+		return errors.Wrapf(err, "create %d VFs on %s", numVfs, pf.Ifname)
+	}
+	return nil
+}
+
+// ListVfAddrs returns pci addresses of VFs indexed by vf number
+func (pf *sSRIOVPhysicalFunction) ListVfAddrs() ([]string, error) {
+	numVfs, err := pf.GetNumVfs()
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, numVfs)
+	for i := 0; i < numVfs; i++ {
+		vfPath, err := os.Readlink(pf.sysfsPath(fmt.Sprintf("virtfn%d", i)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s virtfn%d", pf.Ifname, i)
+		}
+		addrs = append(addrs, path.Base(vfPath))
+	}
+	return addrs, nil
+}
+
+// overrideVFIOPCIDriver bind vfio-pci driver by driver_override, VFs share the
+// same vendor device id so writing vfio-pci new_id repeatedly doesn't work.
+func overrideVFIOPCIDriver(addr string) error {
+	devPath := path.Join(SYSFS_PCI_DEVICES_DIR, addr)
+	if err := fileutils2.FilePutContents(path.Join(devPath, "driver_override"), VFIO_PCI_KERNEL_DRIVER, false); err != nil {
+		return errors.Wrap(err, "set driver_override")
+	}
+	if fileutils2.Exists(path.Join(devPath, "driver")) {
+		if err := fileutils2.FilePutContents(path.Join(devPath, "driver", "unbind"), addr, false); err != nil {
+			return errors.Wrap(err, "unbind driver")
+		}
+	}
+	if err := fileutils2.FilePutContents("/sys/bus/pci/drivers_probe", addr, false); err != nil {
+		return errors.Wrap(err, "drivers_probe")
+	}
+	return nil
+}
+
+type sSRIOVVFDevice struct {
+	*sBaseDevice
+	pfIfname string
+	vfIndex  int
+}
+
+func newSRIOVVFDevice(dev *PCIDevice, pfIfname string, vfIndex int) *sSRIOVVFDevice {
+	return &sSRIOVVFDevice{
+		sBaseDevice: newBaseDevice(dev, api.NIC_VF_TYPE),
+		pfIfname:    pfIfname,
+		vfIndex:     vfIndex,
+	}
+}
+
+func (dev *sSRIOVVFDevice) GetCPUCmd() string {
+	return ""
+}
+
+func (dev *sSRIOVVFDevice) GetVGACmd() string {
+	return ""
+}
+
+func (dev *sSRIOVVFDevice) CustomProbe() error {
+	driver, err := dev.GetKernelDriver()
+	if err != nil {
+		return err
+	}
+	if driver != VFIO_PCI_KERNEL_DRIVER {
+		return fmt.Errorf("VF %s is occupied by another driver: %s", dev.GetAddr(), driver)
+	}
+	return nil
+}
+
+func (dev *sSRIOVVFDevice) DetectByAddr() error {
+	_, err := detectPCIDevByAddrWithoutIOMMUGroup(dev.GetAddr())
+	return err
+}
+
+// GetNicSetupCmd returns command setting VF mac and vlan through its PF,
+// vlan 1 means untagged in onecloud.
+func (dev *sSRIOVVFDevice) GetNicSetupCmd(mac string, vlan int) string {
+	if vlan <= 1 {
+		vlan = 0
+	}
+	return fmt.Sprintf("ip link set %s vf %d mac %s vlan %d", dev.pfIfname, dev.vfIndex, mac, vlan)
+}
+
+func (dev *sSRIOVVFDevice) GetHotPlugOptions() ([]*HotPlugOption, error) {
+	return nil, fmt.Errorf("Not implemented")
+}
+
+func (dev *sSRIOVVFDevice) GetHotUnplugOptions() ([]*HotUnplugOption, error) {
+	return nil, fmt.Errorf("Not implemented")
+}
+
+func getSRIOVVFDevices(spec string) ([]*sSRIOVVFDevice, error) {
+	ifname, numVfs, err := parseSRIOVNic(spec)
+	if err != nil {
+		return nil, err
+	}
+	pf, err := getSRIOVPhysicalFunction(ifname)
+	if err != nil {
+		return nil, err
+	}
+	if err := pf.SetNumVfs(numVfs); err != nil {
+		return nil, err
+	}
+	addrs, err := pf.ListVfAddrs()
+	if err != nil {
+		return nil, err
+	}
+	devs := make([]*sSRIOVVFDevice, 0, len(addrs))
+	for idx, addr := range addrs {
+		dev, err := detectPCIDevByAddrWithoutIOMMUGroup(shortPCIAddr(addr))
+		if err != nil {
+			return nil, errors.Wrapf(err, "detect VF %s", addr)
+		}
+		if !dev.IsVFIOPCIDriverUsed() {
+			if err := overrideVFIOPCIDriver(addr); err != nil {
+				return nil, errors.Wrapf(err, "bind VF %s vfio-pci driver", addr)
+			}
+		}
+		devs = append(devs, newSRIOVVFDevice(dev, ifname, idx))
+		log.Infof("Add SR-IOV VF device: %s vf %d => %s", ifname, idx, addr)
+	}
+	return devs, nil
+}
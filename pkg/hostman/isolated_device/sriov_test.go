@@ -0,0 +1,56 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolated_device
+
+import (
+	"testing"
+)
+
+func Test_parseSRIOVNic(t *testing.T) {
+	tests := []struct {
+		spec       string
+		wantIfname string
+		wantNumVfs int
+		wantErr    bool
+	}{
+		{spec: "eth1:8", wantIfname: "eth1", wantNumVfs: 8},
+		{spec: "eth1", wantIfname: "eth1", wantNumVfs: -1},
+		{spec: "eth1:0", wantIfname: "eth1", wantNumVfs: 0},
+		{spec: "eth1:x", wantErr: true},
+		{spec: ":8", wantErr: true},
+		{spec: "eth1:8:1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			ifname, numVfs, err := parseSRIOVNic(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSRIOVNic() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ifname != tt.wantIfname || numVfs != tt.wantNumVfs {
+				t.Errorf("parseSRIOVNic() = %q, %d, want %q, %d", ifname, numVfs, tt.wantIfname, tt.wantNumVfs)
+			}
+		})
+	}
+}
+
+func Test_sSRIOVVFDevice_GetNicSetupCmd(t *testing.T) {
+	dev := newSRIOVVFDevice(&PCIDevice{Addr: "3b:02.1"}, "eth1", 1)
+	if got, want := dev.GetNicSetupCmd("00:22:aa:bb:cc:01", 1), "ip link set eth1 vf 1 mac 00:22:aa:bb:cc:01 vlan 0"; got != want {
+		t.Errorf("GetNicSetupCmd() = %q, want %q", got, want)
+	}
+	if got, want := dev.GetNicSetupCmd("00:22:aa:bb:cc:01", 100), "ip link set eth1 vf 1 mac 00:22:aa:bb:cc:01 vlan 100"; got != want {
+		t.Errorf("GetNicSetupCmd() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
+)
+
+// SInstanceIdentityDocument is compatible with the EC2 instance identity
+// document, it is signed by the region key shared by all hosts of the region
+// so the guest can prove its identity to a third party
+type SInstanceIdentityDocument struct {
+	InstanceId       string `json:"instanceId"`
+	InstanceType     string `json:"instanceType"`
+	AccountId        string `json:"accountId"`
+	DomainId         string `json:"domainId"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone"`
+	HostId           string `json:"hostId"`
+	PrivateIp        string `json:"privateIp"`
+	Architecture     string `json:"architecture"`
+	Version          string `json:"version"`
+}
+
+const identityDocumentVersion = "2017-09-30"
+
+func (s *Service) getIdentityDocument(guestDesc *desc.SGuestDesc) *SInstanceIdentityDocument {
+	doc := &SInstanceIdentityDocument{
+		InstanceId:       guestDesc.Uuid,
+		InstanceType:     guestDesc.Flavor,
+		AccountId:        guestDesc.TenantId,
+		DomainId:         guestDesc.DomainId,
+		Region:           s.Region,
+		AvailabilityZone: guestDesc.Zone,
+		HostId:           guestDesc.HostId,
+		Architecture:     guestDesc.Metadata["os_arch"],
+		Version:          identityDocumentVersion,
+	}
+	if doc.InstanceType == "" {
+		doc.InstanceType = "customized"
+	}
+	if len(guestDesc.Nics) > 0 {
+		doc.PrivateIp = guestDesc.Nics[0].Ip
+	}
+	return doc
+}
+
+// LoadIdentityKey loads the PEM encoded rsa private key of the region
+func LoadIdentityKey(keyFile string) (*rsa.PrivateKey, error) {
+	cont, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", keyFile)
+	}
+	block, _ := pem.Decode(cont)
+	if block == nil {
+		return nil, errors.Wrapf(httperrors.ErrInvalidFormat, "%s is not a PEM file", keyFile)
+	}
+	return seclib2.DecodePrivateKey(cont)
+}
+
+// SignIdentityDocument signs the document with RSA-SHA256 and returns the
+// signature in base64
+func SignIdentityDocument(key *rsa.PrivateKey, document []byte) (string, error) {
+	hashed := sha256.Sum256(document)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", errors.Wrap(err, "SignPKCS1v15")
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// VerifyIdentityDocument verifies the signature of the document against the
+// PEM encoded public key of the region
+func VerifyIdentityDocument(publicKey []byte, document []byte, signature string) error {
+	block, _ := pem.Decode(publicKey)
+	if block == nil {
+		return errors.Wrap(httperrors.ErrInvalidFormat, "invalid public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return errors.Wrap(err, "ParsePKIXPublicKey")
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return errors.Wrap(errors.ErrNotSupported, "not a rsa public key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return errors.Wrap(err, "decode signature")
+	}
+	hashed := sha256.Sum256(document)
+	return rsa.VerifyPKCS1v15(rsaPub, crypto.SHA256, hashed[:], sig)
+}
+
+func (s *Service) dynamicData(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	guestDesc := s.getGuestDesc(r)
+	if guestDesc == nil {
+		hostutils.Response(ctx, w, "")
+		return
+	}
+
+	req := appsrv.SplitPath(r.URL.Path)[2:]
+	switch {
+	case len(req) == 0:
+		hostutils.Response(ctx, w, "instance-identity/")
+		return
+	case len(req) == 1 && req[0] == "instance-identity":
+		resNames := []string{"document"}
+		if s.IdentityKey != nil {
+			resNames = append(resNames, "signature")
+		}
+		hostutils.Response(ctx, w, strings.Join(resNames, "\n"))
+		return
+	case len(req) == 2 && req[0] == "instance-identity":
+		doc := jsonutils.Marshal(s.getIdentityDocument(guestDesc)).PrettyString()
+		switch req[1] {
+		case "document":
+			hostutils.Response(ctx, w, doc)
+			return
+		case "signature":
+			if s.IdentityKey == nil {
+				break
+			}
+			sig, err := SignIdentityDocument(s.IdentityKey, []byte(doc))
+			if err != nil {
+				hostutils.Response(ctx, w, httperrors.NewGeneralError(err))
+				return
+			}
+			hostutils.Response(ctx, w, sig)
+			return
+		}
+	}
+	hostutils.Response(ctx, w, httperrors.NewNotFoundError("Resource not handled"))
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+)
+
+func TestTokenStore(t *testing.T) {
+	ts := newTokenStore()
+	token := ts.issue("10.0.0.2", time.Minute)
+	if !ts.verify("10.0.0.2", token) {
+		t.Errorf("token should be valid")
+	}
+	if ts.verify("10.0.0.3", token) {
+		t.Errorf("token should be bound to the issuing ip")
+	}
+	if ts.verify("10.0.0.2", "invalid") {
+		t.Errorf("unknown token should be invalid")
+	}
+	expired := ts.issue("10.0.0.2", -time.Second)
+	if ts.verify("10.0.0.2", expired) {
+		t.Errorf("expired token should be invalid")
+	}
+}
+
+func TestIdentityDocumentSignature(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %s", err)
+	}
+	pubBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %s", err)
+	}
+	pub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes})
+
+	doc := []byte(`{"instanceId":"7d8e7a2c"}`)
+	sig, err := SignIdentityDocument(key, doc)
+	if err != nil {
+		t.Fatalf("SignIdentityDocument: %s", err)
+	}
+	if err := VerifyIdentityDocument(pub, doc, sig); err != nil {
+		t.Errorf("VerifyIdentityDocument: %s", err)
+	}
+	if err := VerifyIdentityDocument(pub, []byte(`{"instanceId":"forged"}`), sig); err == nil {
+		t.Errorf("forged document should not be verified")
+	}
+}
+
+func TestNetworkConfig(t *testing.T) {
+	guestDesc := &desc.SGuestDesc{}
+	guestDesc.Nics = []*api.GuestnetworkJsonDesc{
+		{
+			Mac:     "00:22:0a:00:00:02",
+			Ip:      "10.0.0.2",
+			Masklen: 24,
+			Gateway: "10.0.0.1",
+			Dns:     "114.114.114.114,8.8.8.8",
+			Index:   0,
+			Routes:  jsonutils.Marshal([][]string{{"192.168.0.0/16", "10.0.0.254"}}),
+		},
+		{
+			Mac:   "00:22:0a:00:00:03",
+			Index: 1,
+		},
+		{
+			Mac:     "00:22:0a:00:00:04",
+			Virtual: true,
+			Index:   2,
+		},
+	}
+
+	conf := getNetworkConfig(guestDesc)
+	if len(conf.Ethernets) != 2 {
+		t.Fatalf("expect 2 ethernets, got %d", len(conf.Ethernets))
+	}
+	eth0 := conf.Ethernets["eth0"]
+	if eth0.Addresses[0] != "10.0.0.2/24" || eth0.Gateway4 != "10.0.0.1" {
+		t.Errorf("unexpected eth0 %s", jsonutils.Marshal(eth0))
+	}
+	if eth0.Nameservers == nil || len(eth0.Nameservers.Addresses) != 2 {
+		t.Errorf("unexpected eth0 nameservers %s", jsonutils.Marshal(eth0))
+	}
+	if len(eth0.Routes) != 1 || eth0.Routes[0].To != "192.168.0.0/16" {
+		t.Errorf("unexpected eth0 routes %s", jsonutils.Marshal(eth0))
+	}
+	if !conf.Ethernets["eth1"].Dhcp4 {
+		t.Errorf("eth1 should use dhcp")
+	}
+
+	data := getOpenstackNetworkData(guestDesc)
+	if len(data.Links) != 2 || len(data.Networks) != 2 || len(data.Services) != 2 {
+		t.Fatalf("unexpected network data %s", jsonutils.Marshal(data))
+	}
+	net0 := data.Networks[0]
+	if net0.Netmask != "255.255.255.0" || len(net0.Routes) != 2 {
+		t.Errorf("unexpected network0 %s", jsonutils.Marshal(net0))
+	}
+	if net0.Routes[1].Network != "192.168.0.0" || net0.Routes[1].Netmask != "255.255.0.0" {
+		t.Errorf("unexpected network0 routes %s", jsonutils.Marshal(net0.Routes))
+	}
+	if data.Networks[1].Type != "ipv4_dhcp" {
+		t.Errorf("network1 should use dhcp")
+	}
+}
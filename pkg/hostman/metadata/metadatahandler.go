@@ -17,6 +17,7 @@ package metadata
 // NOTE keep imports minimal.  DO NOT IMPORT guestman
 import (
 	"context"
+	"crypto/rsa"
 	"encoding/base64"
 	"fmt"
 	"net"
@@ -56,6 +57,17 @@ type Service struct {
 	Port    int
 
 	DescGetter DescGetter
+
+	// Region is reported in the instance identity document
+	Region string
+	// IdentityKey is the region key signing the instance identity document
+	IdentityKey *rsa.PrivateKey
+	// RequireToken only serves the requests carrying a session token
+	RequireToken bool
+	// VendorData is served to cloud-init as vendor-data
+	VendorData string
+
+	tokens *sTokenStore
 }
 
 func (s *Service) getGuestDesc(r *http.Request) (guestDesc *desc.SGuestDesc) {
@@ -69,22 +81,59 @@ func (s *Service) getGuestDesc(r *http.Request) (guestDesc *desc.SGuestDesc) {
 
 func (s *Service) addHandler(app *appsrv.Application) {
 	prefix := ""
+	version := `(latest|\d{4}-\d{2}-\d{2})`
+	s.tokens = newTokenStore()
+
+	app.AddHandler("PUT", fmt.Sprintf("%s/<version:%s>/api/token",
+		prefix, version), s.issueToken)
 
 	for _, method := range []string{"GET", "HEAD"} {
 		app.AddHandler(method, fmt.Sprintf("%s/<version:%s>",
-			prefix, `(latest|\d{4}-\d{2}-\d{2})`), s.versionOnly)
+			prefix, version), s.withToken(s.versionOnly))
 	}
 
 	for _, method := range []string{"GET", "HEAD"} {
 		app.AddHandler(method, fmt.Sprintf("%s/<version:%s>/user-data",
-			prefix, `(latest|\d{4}-\d{2}-\d{2})`), s.userData)
+			prefix, version), s.withToken(s.userData))
 		app.AddHandler(method, fmt.Sprintf("%s/<version:%s>/meta-data",
-			prefix, `(latest|\d{4}-\d{2}-\d{2})`), s.metaData)
+			prefix, version), s.withToken(s.metaData))
+		app.AddHandler(method, fmt.Sprintf("%s/<version:%s>/vendor-data",
+			prefix, version), s.withToken(s.vendorData))
+		app.AddHandler(method, fmt.Sprintf("%s/<version:%s>/network-config",
+			prefix, version), s.withToken(s.networkConfig))
+		app.AddHandler(method, fmt.Sprintf("%s/<version:%s>/dynamic",
+			prefix, version), s.withToken(s.dynamicData))
+	}
+
+	// openstack compatible metadata
+	for _, method := range []string{"GET", "HEAD"} {
+		app.AddHandler(method, fmt.Sprintf("%s/openstack", prefix),
+			s.withToken(s.openstackVersions))
+		app.AddHandler(method, fmt.Sprintf("%s/openstack/<version:%s>",
+			prefix, version), s.withToken(s.openstackVersionOnly))
+		for _, res := range []string{"meta_data.json", "network_data.json", "user_data", "vendor_data.json"} {
+			app.AddHandler(method, fmt.Sprintf("%s/openstack/<version:%s>/%s",
+				prefix, version, res), s.withToken(s.openstackData))
+		}
 	}
 }
 
 func (s *Service) versionOnly(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-	hostutils.Response(ctx, w, strings.Join([]string{"meta-data", "user-data"}, "\n"))
+	resNames := []string{"dynamic/", "meta-data", "network-config", "user-data", "vendor-data"}
+	hostutils.Response(ctx, w, strings.Join(resNames, "\n"))
+}
+
+func (s *Service) vendorData(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	hostutils.Response(ctx, w, s.VendorData)
+}
+
+func (s *Service) networkConfig(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	guestDesc := s.getGuestDesc(r)
+	if guestDesc == nil {
+		hostutils.Response(ctx, w, "")
+		return
+	}
+	hostutils.Response(ctx, w, getNetworkConfigYAML(guestDesc))
 }
 
 func (s *Service) userData(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,253 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/util/netutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+)
+
+type sNetworkConfigRoute struct {
+	To  string `json:"to"`
+	Via string `json:"via"`
+}
+
+type sNetworkConfigNameservers struct {
+	Addresses []string `json:"addresses"`
+	Search    []string `json:"search"`
+}
+
+type sNetworkConfigMatch struct {
+	Macaddress string `json:"macaddress"`
+}
+
+type sNetworkConfigEthernet struct {
+	Match       sNetworkConfigMatch        `json:"match"`
+	SetName     string                     `json:"set-name"`
+	Dhcp4       bool                       `json:"dhcp4"`
+	Addresses   []string                   `json:"addresses"`
+	Gateway4    string                     `json:"gateway4"`
+	Mtu         int                        `json:"mtu"`
+	Nameservers *sNetworkConfigNameservers `json:"nameservers"`
+	Routes      []sNetworkConfigRoute      `json:"routes"`
+}
+
+// sNetworkConfig is the cloud-init network config version 2
+type sNetworkConfig struct {
+	Version   int                               `json:"version"`
+	Ethernets map[string]sNetworkConfigEthernet `json:"ethernets"`
+}
+
+func guestNicName(nic *api.GuestnetworkJsonDesc) string {
+	return fmt.Sprintf("eth%d", nic.Index)
+}
+
+// guestNics returns the nics configured inside the guest, virtual nics for
+// teaming are excluded
+func guestNics(guestDesc *desc.SGuestDesc) []*api.GuestnetworkJsonDesc {
+	nics := make([]*api.GuestnetworkJsonDesc, 0, len(guestDesc.Nics))
+	for _, nic := range guestDesc.Nics {
+		if nic.Virtual {
+			continue
+		}
+		nics = append(nics, nic)
+	}
+	return nics
+}
+
+func splitAddrs(addrs string) []string {
+	ret := make([]string, 0)
+	for _, addr := range strings.Split(addrs, ",") {
+		addr = strings.TrimSpace(addr)
+		if len(addr) > 0 {
+			ret = append(ret, addr)
+		}
+	}
+	return ret
+}
+
+// nicRoutes returns the static routes of the nic as [prefix, gateway] pairs
+func nicRoutes(nic *api.GuestnetworkJsonDesc) [][]string {
+	routes := make([][]string, 0)
+	if nic.Routes != nil {
+		nic.Routes.Unmarshal(&routes)
+	}
+	return routes
+}
+
+// getDefaultGatewayNic returns the index of the nic holding the default route
+func getDefaultGatewayNic(nics []*api.GuestnetworkJsonDesc) int {
+	for i, nic := range nics {
+		if len(nic.Gateway) > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+func getNetworkConfig(guestDesc *desc.SGuestDesc) *sNetworkConfig {
+	conf := &sNetworkConfig{
+		Version:   2,
+		Ethernets: map[string]sNetworkConfigEthernet{},
+	}
+	nics := guestNics(guestDesc)
+	gwIdx := getDefaultGatewayNic(nics)
+	for i, nic := range nics {
+		name := guestNicName(nic)
+		eth := sNetworkConfigEthernet{
+			Match:   sNetworkConfigMatch{Macaddress: nic.Mac},
+			SetName: name,
+			Mtu:     nic.Mtu,
+		}
+		if len(nic.Ip) == 0 {
+			eth.Dhcp4 = true
+			conf.Ethernets[name] = eth
+			continue
+		}
+		eth.Addresses = []string{fmt.Sprintf("%s/%d", nic.Ip, nic.Masklen)}
+		if i == gwIdx {
+			eth.Gateway4 = nic.Gateway
+		}
+		dns := splitAddrs(nic.Dns)
+		if len(dns) > 0 {
+			eth.Nameservers = &sNetworkConfigNameservers{
+				Addresses: dns,
+				Search:    splitAddrs(nic.Domain),
+			}
+		}
+		for _, route := range nicRoutes(nic) {
+			if len(route) != 2 {
+				continue
+			}
+			eth.Routes = append(eth.Routes, sNetworkConfigRoute{To: route[0], Via: route[1]})
+		}
+		conf.Ethernets[name] = eth
+	}
+	return conf
+}
+
+type sOpenstackLink struct {
+	Id                 string `json:"id"`
+	Type               string `json:"type"`
+	EthernetMacAddress string `json:"ethernet_mac_address"`
+	Mtu                int    `json:"mtu"`
+	VifId              string `json:"vif_id"`
+}
+
+type sOpenstackRoute struct {
+	Network string `json:"network"`
+	Netmask string `json:"netmask"`
+	Gateway string `json:"gateway"`
+}
+
+type sOpenstackNetwork struct {
+	Id        string            `json:"id"`
+	Link      string            `json:"link"`
+	Type      string            `json:"type"`
+	IpAddress string            `json:"ip_address"`
+	Netmask   string            `json:"netmask"`
+	Routes    []sOpenstackRoute `json:"routes"`
+	NetworkId string            `json:"network_id"`
+}
+
+type sOpenstackService struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+// sOpenstackNetworkData is the openstack network_data.json
+type sOpenstackNetworkData struct {
+	Links    []sOpenstackLink    `json:"links"`
+	Networks []sOpenstackNetwork `json:"networks"`
+	Services []sOpenstackService `json:"services"`
+}
+
+func masklen2Netmask(masklen int8) string {
+	return netutils.Masklen2Mask(masklen).String()
+}
+
+func getOpenstackNetworkData(guestDesc *desc.SGuestDesc) *sOpenstackNetworkData {
+	data := &sOpenstackNetworkData{
+		Links:    []sOpenstackLink{},
+		Networks: []sOpenstackNetwork{},
+		Services: []sOpenstackService{},
+	}
+	nics := guestNics(guestDesc)
+	gwIdx := getDefaultGatewayNic(nics)
+	dnsSet := map[string]bool{}
+	for i, nic := range nics {
+		name := guestNicName(nic)
+		data.Links = append(data.Links, sOpenstackLink{
+			Id:                 name,
+			Type:               "phy",
+			EthernetMacAddress: nic.Mac,
+			Mtu:                nic.Mtu,
+			VifId:              nic.NetId,
+		})
+		network := sOpenstackNetwork{
+			Id:        fmt.Sprintf("network%d", i),
+			Link:      name,
+			NetworkId: nic.NetId,
+			Routes:    []sOpenstackRoute{},
+		}
+		if len(nic.Ip) == 0 {
+			network.Type = "ipv4_dhcp"
+			data.Networks = append(data.Networks, network)
+			continue
+		}
+		network.Type = "ipv4"
+		network.IpAddress = nic.Ip
+		network.Netmask = masklen2Netmask(nic.Masklen)
+		if i == gwIdx {
+			network.Routes = append(network.Routes, sOpenstackRoute{
+				Network: "0.0.0.0",
+				Netmask: "0.0.0.0",
+				Gateway: nic.Gateway,
+			})
+		}
+		for _, route := range nicRoutes(nic) {
+			if len(route) != 2 {
+				continue
+			}
+			prefix, err := netutils.NewIPV4Prefix(route[0])
+			if err != nil {
+				continue
+			}
+			network.Routes = append(network.Routes, sOpenstackRoute{
+				Network: prefix.Address.String(),
+				Netmask: masklen2Netmask(prefix.MaskLen),
+				Gateway: route[1],
+			})
+		}
+		data.Networks = append(data.Networks, network)
+		for _, dns := range splitAddrs(nic.Dns) {
+			if !dnsSet[dns] {
+				dnsSet[dns] = true
+				data.Services = append(data.Services, sOpenstackService{Type: "dns", Address: dns})
+			}
+		}
+	}
+	return data
+}
+
+func getNetworkConfigYAML(guestDesc *desc.SGuestDesc) string {
+	return jsonutils.Marshal(getNetworkConfig(guestDesc)).YAMLString()
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+// user tags of the guest metadata are exposed in meta_data.json
+const userTagPrefix = "user:"
+
+type sOpenstackKey struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+// sOpenstackMetaData is the openstack meta_data.json
+type sOpenstackMetaData struct {
+	Uuid             string            `json:"uuid"`
+	Name             string            `json:"name"`
+	Hostname         string            `json:"hostname"`
+	AvailabilityZone string            `json:"availability_zone"`
+	ProjectId        string            `json:"project_id"`
+	LaunchIndex      int               `json:"launch_index"`
+	PublicKeys       map[string]string `json:"public_keys"`
+	Keys             []sOpenstackKey   `json:"keys"`
+	Meta             map[string]string `json:"meta"`
+}
+
+func getOpenstackMetaData(guestDesc *desc.SGuestDesc) *sOpenstackMetaData {
+	md := &sOpenstackMetaData{
+		Uuid:             guestDesc.Uuid,
+		Name:             guestDesc.Name,
+		Hostname:         guestDesc.Name,
+		AvailabilityZone: guestDesc.Zone,
+		ProjectId:        guestDesc.TenantId,
+		Meta:             map[string]string{},
+	}
+	if guestDesc.Pubkey != "" {
+		keyName := guestDesc.Keypair
+		if keyName == "" {
+			keyName = "default"
+		}
+		md.PublicKeys = map[string]string{keyName: guestDesc.Pubkey}
+		md.Keys = []sOpenstackKey{{Name: keyName, Type: "ssh", Data: guestDesc.Pubkey}}
+	}
+	for k, v := range guestDesc.Metadata {
+		if strings.HasPrefix(k, userTagPrefix) {
+			md.Meta[k[len(userTagPrefix):]] = v
+		}
+	}
+	return md
+}
+
+func (s *Service) openstackVersions(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	hostutils.Response(ctx, w, "latest")
+}
+
+func (s *Service) openstackVersionOnly(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	resNames := []string{"meta_data.json", "network_data.json", "user_data", "vendor_data.json"}
+	hostutils.Response(ctx, w, strings.Join(resNames, "\n"))
+}
+
+func (s *Service) openstackData(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	guestDesc := s.getGuestDesc(r)
+	if guestDesc == nil {
+		hostutils.Response(ctx, w, httperrors.NewNotFoundError("guest not found"))
+		return
+	}
+
+	req := appsrv.SplitPath(r.URL.Path)[2:]
+	switch req[0] {
+	case "meta_data.json":
+		hostutils.Response(ctx, w, jsonutils.Marshal(getOpenstackMetaData(guestDesc)))
+	case "network_data.json":
+		hostutils.Response(ctx, w, jsonutils.Marshal(getOpenstackNetworkData(guestDesc)))
+	case "vendor_data.json":
+		vendorData := jsonutils.NewDict()
+		if len(s.VendorData) > 0 {
+			vendorData.Set("cloud-init", jsonutils.NewString(s.VendorData))
+		}
+		hostutils.Response(ctx, w, vendorData)
+	case "user_data":
+		s.userData(ctx, w, r)
+	}
+}
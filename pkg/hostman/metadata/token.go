@@ -0,0 +1,129 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+// IMDSv2 style session token, a guest first PUT /latest/api/token to get a
+// token and carries it in the following requests
+const (
+	TOKEN_HEADER     = "X-aws-ec2-metadata-token"
+	TOKEN_TTL_HEADER = "X-aws-ec2-metadata-token-ttl-seconds"
+
+	TOKEN_MAX_TTL_SECONDS = 21600
+)
+
+type sSessionToken struct {
+	ip     string
+	expire time.Time
+}
+
+type sTokenStore struct {
+	lock   *sync.Mutex
+	tokens map[string]sSessionToken
+}
+
+func newTokenStore() *sTokenStore {
+	return &sTokenStore{
+		lock:   &sync.Mutex{},
+		tokens: map[string]sSessionToken{},
+	}
+}
+
+func (ts *sTokenStore) issue(ip string, ttl time.Duration) string {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	now := time.Now()
+	for token, t := range ts.tokens {
+		if now.After(t.expire) {
+			delete(ts.tokens, token)
+		}
+	}
+	buf := make([]byte, 42)
+	rand.Read(buf)
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	ts.tokens[token] = sSessionToken{
+		ip:     ip,
+		expire: now.Add(ttl),
+	}
+	return token
+}
+
+// verify returns whether the token is issued to the ip and not expired
+func (ts *sTokenStore) verify(ip string, token string) bool {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	t, ok := ts.tokens[token]
+	if !ok {
+		return false
+	}
+	if time.Now().After(t.expire) {
+		delete(ts.tokens, token)
+		return false
+	}
+	return t.ip == ip
+}
+
+func (s *Service) issueToken(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	// reject the requests forwarded by a proxy inside the guest
+	if len(r.Header.Get("X-Forwarded-For")) > 0 {
+		hostutils.Response(ctx, w, httperrors.NewForbiddenError("forwarded request is not allowed"))
+		return
+	}
+	ttl, err := strconv.Atoi(r.Header.Get(TOKEN_TTL_HEADER))
+	if err != nil || ttl <= 0 || ttl > TOKEN_MAX_TTL_SECONDS {
+		hostutils.Response(ctx, w, httperrors.NewInputParameterError("invalid %s, should be in range 1-%d", TOKEN_TTL_HEADER, TOKEN_MAX_TTL_SECONDS))
+		return
+	}
+	ip, _, _ := net.SplitHostPort(r.RemoteAddr)
+	token := s.tokens.issue(ip, time.Duration(ttl)*time.Second)
+	w.Header().Set(TOKEN_TTL_HEADER, strconv.Itoa(ttl))
+	hostutils.Response(ctx, w, token)
+}
+
+// withToken verifies the session token of the request. The requests without
+// token are served unless RequireToken is set, while an invalid token is
+// always rejected.
+func (s *Service) withToken(f appsrv.FilterHandler) appsrv.FilterHandler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(TOKEN_HEADER)
+		if len(token) == 0 {
+			if s.RequireToken {
+				hostutils.Response(ctx, w, httperrors.NewUnauthorizedError("session token required"))
+				return
+			}
+		} else {
+			ip, _, _ := net.SplitHostPort(r.RemoteAddr)
+			if !s.tokens.verify(ip, token) {
+				hostutils.Response(ctx, w, httperrors.NewUnauthorizedError("invalid session token"))
+				return
+			}
+		}
+		f(ctx, w, r)
+	}
+}
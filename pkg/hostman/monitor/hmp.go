@@ -301,7 +301,7 @@ func (m *HmpMonitor) MigrateSetCapability(capability, state string, callback Str
 }
 
 func (m *HmpMonitor) MigrateSetParameter(key string, val interface{}, callback StringCallback) {
-	cmd := fmt.Sprintf("migrate_set_parameter %s %s", key, val)
+	cmd := fmt.Sprintf("migrate_set_parameter %s %v", key, val)
 	m.Query(cmd, callback)
 }
 
@@ -340,6 +340,17 @@ func (m *HmpMonitor) GetMigrateStatus(callback StringCallback) {
 	m.Query("info migrate", cb)
 }
 
+func (m *HmpMonitor) GetMigrateStats(callback MigrateStatsCallback) {
+	cb := func(output string) {
+		callback(parseHmpMigrationInfo(output), nil)
+	}
+	m.Query("info migrate", cb)
+}
+
+func (m *HmpMonitor) MigrateCancel(callback StringCallback) {
+	m.Query("migrate_cancel", callback)
+}
+
 func (m *HmpMonitor) MigrateStartPostcopy(callback StringCallback) {
 	cb := func(output string) {
 		log.Infof("MigrateStartPostcopy %s: %s", m.server, output)
@@ -498,6 +509,22 @@ func (m *HmpMonitor) CancelBlockJob(driveName string, force bool, callback Strin
 	m.Query(cmd, callback)
 }
 
+func (m *HmpMonitor) DriveBackup(jobId, drive, target, syncMode, bitmap, newBitmap string, callback StringCallback) {
+	go callback("error: drive backup with dirty bitmap is not supported by hmp monitor")
+}
+
+func (m *HmpMonitor) BlockDirtyBitmapRemove(node, name string, callback StringCallback) {
+	go callback("error: block dirty bitmap is not supported by hmp monitor")
+}
+
+func (m *HmpMonitor) GetJob(jobId string, callback func(*Job)) {
+	go callback(nil)
+}
+
+func (m *HmpMonitor) JobDismiss(jobId string, callback StringCallback) {
+	go callback("error: job dismiss is not supported by hmp monitor")
+}
+
 func (m *HmpMonitor) NetdevAdd(id, netType string, params map[string]string, callback StringCallback) {
 	cmd := fmt.Sprintf("netdev_add %s,id=%s", netType, id)
 	for k, v := range params {
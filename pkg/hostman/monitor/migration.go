@@ -0,0 +1,184 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MigrationStats is ram or disk section of query-migrate result
+type MigrationStats struct {
+	Transferred    int64   `json:"transferred"`
+	Remaining      int64   `json:"remaining"`
+	Total          int64   `json:"total"`
+	Mbps           float64 `json:"mbps"`
+	DirtyPagesRate int64   `json:"dirty-pages-rate"`
+	DirtySyncCount int64   `json:"dirty-sync-count"`
+	PageSize       int64   `json:"page-size"`
+}
+
+// MigrationInfo is result of query-migrate
+type MigrationInfo struct {
+	Status           string          `json:"status"`
+	TotalTime        int64           `json:"total-time"`
+	ExpectedDowntime int64           `json:"expected-downtime"`
+	Ram              *MigrationStats `json:"ram"`
+	Disk             *MigrationStats `json:"disk"`
+}
+
+type MigrateStatsCallback func(*MigrationInfo, error)
+
+// Phase returns migrate status used by live migrate task, active status is
+// split into migrate_disk_copy and migrate_ram_copy
+func (i *MigrationInfo) Phase() string {
+	if i.Status != "active" {
+		return i.Status
+	}
+	if i.Disk != nil && i.Disk.Remaining > 0 {
+		return "migrate_disk_copy"
+	}
+	if i.Ram != nil && i.Ram.Remaining > 0 {
+		return "migrate_ram_copy"
+	}
+	return i.Status
+}
+
+// Progress returns transferred percent of ram and disk
+func (i *MigrationInfo) Progress() float64 {
+	var total, remaining int64
+	for _, s := range []*MigrationStats{i.Ram, i.Disk} {
+		if s != nil {
+			total += s.Total
+			remaining += s.Remaining
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return (1 - float64(remaining)/float64(total)) * 100.0
+}
+
+func (i *MigrationInfo) Mbps() float64 {
+	var mbps float64
+	for _, s := range []*MigrationStats{i.Ram, i.Disk} {
+		if s != nil {
+			mbps += s.Mbps
+		}
+	}
+	return mbps
+}
+
+func (i *MigrationInfo) RemainingRam() int64 {
+	if i.Ram == nil {
+		return 0
+	}
+	return i.Ram.Remaining
+}
+
+// DirtyRate returns ram dirty rate in bytes per second
+func (i *MigrationInfo) DirtyRate() int64 {
+	if i.Ram == nil {
+		return 0
+	}
+	pageSize := i.Ram.PageSize
+	if pageSize == 0 {
+		pageSize = 4096
+	}
+	return i.Ram.DirtyPagesRate * pageSize
+}
+
+// EstimatedSeconds returns estimated seconds to finish migration, -1 means
+// migration won't converge as guest dirties memory faster than transferring
+func (i *MigrationInfo) EstimatedSeconds() int64 {
+	var remaining int64
+	for _, s := range []*MigrationStats{i.Ram, i.Disk} {
+		if s != nil {
+			remaining += s.Remaining
+		}
+	}
+	if remaining == 0 {
+		return 0
+	}
+	rate := i.Mbps()*1000*1000/8 - float64(i.DirtyRate())
+	if rate <= 0 {
+		return -1
+	}
+	return int64(float64(remaining) / rate)
+}
+
+// parseHmpMigrationInfo parse output of `info migrate`
+func parseHmpMigrationInfo(output string) *MigrationInfo {
+	info := &MigrationInfo{}
+	ram := &MigrationStats{}
+	disk := &MigrationStats{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:idx]))
+		fields := strings.Fields(line[idx+1:])
+		if len(fields) == 0 {
+			continue
+		}
+		if key == "migration status" {
+			info.Status = fields[0]
+			continue
+		}
+		val, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		num := int64(val)
+		if len(fields) > 1 && fields[1] == "kbytes" {
+			num *= 1024
+		}
+		switch key {
+		case "total time":
+			info.TotalTime = num
+		case "expected downtime":
+			info.ExpectedDowntime = num
+		case "transferred ram":
+			ram.Transferred = num
+		case "remaining ram":
+			ram.Remaining = num
+		case "total ram":
+			ram.Total = num
+		case "throughput":
+			ram.Mbps = val
+		case "dirty pages rate":
+			ram.DirtyPagesRate = num
+		case "dirty sync count":
+			ram.DirtySyncCount = num
+		case "page size":
+			ram.PageSize = num
+		case "transferred disk":
+			disk.Transferred = num
+		case "remaining disk":
+			disk.Remaining = num
+		case "total disk":
+			disk.Total = num
+		}
+	}
+	if ram.Total > 0 {
+		info.Ram = ram
+	}
+	if disk.Total > 0 {
+		info.Disk = disk
+	}
+	return info
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseHmpMigrationInfo(t *testing.T) {
+	output := `capabilities: xbzrle: off rdma-pin-all: off auto-converge: on zero-blocks: on
+Migration status: active
+total time: 20002 milliseconds
+expected downtime: 300 milliseconds
+setup: 65 milliseconds
+transferred ram: 621752 kbytes
+throughput: 268.57 mbps
+remaining ram: 139468 kbytes
+total ram: 12600136 kbytes
+duplicate: 2966538 pages
+normal: 148629 pages
+dirty sync count: 1
+page size: 4 kbytes
+`
+	info := parseHmpMigrationInfo(output)
+	if info.Status != "active" {
+		t.Fatalf("status want active got %s", info.Status)
+	}
+	if info.Ram == nil || info.Disk != nil {
+		t.Fatalf("ram stats want parsed and disk stats empty: %#v", info)
+	}
+	if info.Ram.Remaining != 139468*1024 {
+		t.Errorf("remaining ram want %d got %d", 139468*1024, info.Ram.Remaining)
+	}
+	if info.Ram.PageSize != 4096 {
+		t.Errorf("page size want 4096 got %d", info.Ram.PageSize)
+	}
+	if info.Ram.Mbps != 268.57 {
+		t.Errorf("mbps want 268.57 got %f", info.Ram.Mbps)
+	}
+	if phase := info.Phase(); phase != "migrate_ram_copy" {
+		t.Errorf("phase want migrate_ram_copy got %s", phase)
+	}
+}
+
+func TestMigrationInfoEstimatedSeconds(t *testing.T) {
+	cases := []struct {
+		name string
+		ret  string
+		want int64
+	}{
+		{
+			name: "converging",
+			ret:  `{"status":"active","ram":{"mbps":800,"remaining":1000000000,"total":2000000000,"dirty-pages-rate":0,"page-size":4096}}`,
+			want: 10,
+		},
+		{
+			name: "not converging",
+			ret:  `{"status":"active","ram":{"mbps":80,"remaining":1000000000,"total":2000000000,"dirty-pages-rate":10000,"page-size":4096}}`,
+			want: -1,
+		},
+		{
+			name: "completed",
+			ret:  `{"status":"completed","ram":{"mbps":800,"remaining":0,"total":2000000000}}`,
+			want: 0,
+		},
+	}
+	for _, c := range cases {
+		info := new(MigrationInfo)
+		if err := json.Unmarshal([]byte(c.ret), info); err != nil {
+			t.Fatalf("%s: unmarshal %v", c.name, err)
+		}
+		if got := info.EstimatedSeconds(); got != c.want {
+			t.Errorf("%s: estimated seconds want %d got %d", c.name, c.want, got)
+		}
+	}
+}
@@ -50,6 +50,17 @@ type BlockJob struct {
 	speedMbps float64
 }
 
+// Job is a job kept by qemu until dismissed
+type Job struct {
+	Id   string
+	Type string
+	// created|running|paused|ready|standby|waiting|pending|aborting|concluded|null
+	Status          string
+	CurrentProgress int64  `json:"current-progress"`
+	TotalProgress   int64  `json:"total-progress"`
+	Error           string `json:"error"`
+}
+
 type QemuBlock struct {
 	IoStatus  string `json:"io-status"`
 	Device    string
@@ -185,7 +196,9 @@ type Monitor interface {
 	MigrateIncoming(address string, callback StringCallback)
 	Migrate(destStr string, copyIncremental, copyFull bool, callback StringCallback)
 	GetMigrateStatus(callback StringCallback)
+	GetMigrateStats(callback MigrateStatsCallback)
 	MigrateStartPostcopy(callback StringCallback)
+	MigrateCancel(callback StringCallback)
 
 	ReloadDiskBlkdev(device, path string, callback StringCallback)
 	SetVncPassword(proto, password string, callback StringCallback)
@@ -194,6 +207,10 @@ type Monitor interface {
 	ResizeDisk(driveName string, sizeMB int64, callback StringCallback)
 	BlockIoThrottle(driveName string, bps, iops int64, callback StringCallback)
 	CancelBlockJob(driveName string, force bool, callback StringCallback)
+	DriveBackup(jobId, drive, target, syncMode, bitmap, newBitmap string, callback StringCallback)
+	BlockDirtyBitmapRemove(node, name string, callback StringCallback)
+	GetJob(jobId string, callback func(*Job))
+	JobDismiss(jobId string, callback StringCallback)
 
 	NetdevAdd(id, netType string, params map[string]string, callback StringCallback)
 	NetdevDel(id string, callback StringCallback)
@@ -0,0 +1,419 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+// https://www.qemu.org/docs/master/interop/qemu-ga-ref.html
+/*
+The guest agent speaks a QMP like protocol over the virtio-serial channel,
+a request is answered by exactly one response:
+    { "return": json-value }
+    { "error": { "class": json-string, "desc": json-string } }
+The channel may hold stale responses of the commands timed out before, so the
+client resynchronizes by guest-sync-delimited after each (re)connection, the
+agent emits a 0xFF sentinel before the response of guest-sync-delimited.
+*/
+
+const (
+	QGA_DEFAULT_TIMEOUT = 10 * time.Second
+	// the agent answers guest-sync-delimited at once if it is running
+	QGA_SYNC_TIMEOUT = 3 * time.Second
+	// freezing filesystems flushes all dirty pages, which may take a while
+	QGA_FSFREEZE_TIMEOUT = 60 * time.Second
+
+	QGA_FSFREEZE_STATUS_THAWED = "thawed"
+	QGA_FSFREEZE_STATUS_FROZEN = "frozen"
+
+	qgaSyncDelimiter = 0xFF
+)
+
+var ErrQgaNotConnected = errors.Error("guest agent not connected")
+
+type qgaCommand struct {
+	Execute string      `json:"execute"`
+	Args    interface{} `json:"arguments,omitempty"`
+}
+
+type qgaResponse struct {
+	Return json.RawMessage `json:"return"`
+	Error  *Error          `json:"error"`
+}
+
+type GuestOsInfo struct {
+	KernelRelease string `json:"kernel-release"`
+	KernelVersion string `json:"kernel-version"`
+	Machine       string `json:"machine"`
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	PrettyName    string `json:"pretty-name"`
+	Version       string `json:"version"`
+	VersionId     string `json:"version-id"`
+	Variant       string `json:"variant"`
+	VariantId     string `json:"variant-id"`
+}
+
+type GuestIpAddress struct {
+	// ipv4|ipv6
+	IpAddressType string `json:"ip-address-type"`
+	IpAddress     string `json:"ip-address"`
+	Prefix        int    `json:"prefix"`
+}
+
+type GuestNetworkInterface struct {
+	Name            string           `json:"name"`
+	HardwareAddress string           `json:"hardware-address"`
+	IpAddresses     []GuestIpAddress `json:"ip-addresses"`
+}
+
+type GuestDiskAddress struct {
+	BusType string `json:"bus-type"`
+	Bus     int    `json:"bus"`
+	Target  int    `json:"target"`
+	Unit    int    `json:"unit"`
+	Serial  string `json:"serial"`
+	Dev     string `json:"dev"`
+}
+
+type GuestFilesystemInfo struct {
+	Name       string             `json:"name"`
+	Mountpoint string             `json:"mountpoint"`
+	Type       string             `json:"type"`
+	UsedBytes  int64              `json:"used-bytes"`
+	TotalBytes int64              `json:"total-bytes"`
+	Disk       []GuestDiskAddress `json:"disk"`
+}
+
+type GuestExecStatus struct {
+	Exited   bool   `json:"exited"`
+	Exitcode int    `json:"exitcode"`
+	Signal   int    `json:"signal"`
+	OutData  string `json:"out-data"`
+	ErrData  string `json:"err-data"`
+	OutTrunc bool   `json:"out-truncated"`
+	ErrTrunc bool   `json:"err-truncated"`
+}
+
+type GuestExecResult struct {
+	Exitcode int
+	Signal   int
+	Stdout   string
+	Stderr   string
+}
+
+// QemuGuestAgent is a synchronous client of the qemu guest agent, the
+// commands are serialized because the agent serves one request at a time
+type QemuGuestAgent struct {
+	id         string
+	socketPath string
+
+	mutex  *sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func NewQemuGuestAgent(id, socketPath string) *QemuGuestAgent {
+	return &QemuGuestAgent{
+		id:         id,
+		socketPath: socketPath,
+		mutex:      &sync.Mutex{},
+	}
+}
+
+func (qga *QemuGuestAgent) connect() error {
+	conn, err := net.DialTimeout("unix", qga.socketPath, QGA_DEFAULT_TIMEOUT)
+	if err != nil {
+		return errors.Wrapf(ErrQgaNotConnected, "dial %s: %s", qga.socketPath, err)
+	}
+	qga.conn = conn
+	qga.reader = bufio.NewReader(conn)
+	err = qga.sync()
+	if err != nil {
+		qga.close()
+		return errors.Wrap(err, "guest-sync-delimited")
+	}
+	return nil
+}
+
+func (qga *QemuGuestAgent) close() {
+	if qga.conn != nil {
+		qga.conn.Close()
+		qga.conn = nil
+		qga.reader = nil
+	}
+}
+
+// Close disconnects from the guest agent, a later command reconnects
+func (qga *QemuGuestAgent) Close() {
+	qga.mutex.Lock()
+	defer qga.mutex.Unlock()
+	qga.close()
+}
+
+func (qga *QemuGuestAgent) write(cmd *qgaCommand) error {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal")
+	}
+	if cmd.Execute != "guest-set-user-password" {
+		log.Debugf("QGA Write %s: %s", qga.id, string(data))
+	}
+	_, err = qga.conn.Write(append(data, '\n'))
+	return err
+}
+
+func (qga *QemuGuestAgent) readResponse() (*qgaResponse, error) {
+	for {
+		line, err := qga.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		if len(line) <= 1 {
+			continue
+		}
+		resp := &qgaResponse{}
+		err = json.Unmarshal(line, resp)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unmarshal response %q", line)
+		}
+		return resp, nil
+	}
+}
+
+func (qga *QemuGuestAgent) sync() error {
+	qga.conn.SetDeadline(time.Now().Add(QGA_SYNC_TIMEOUT))
+	defer qga.conn.SetDeadline(time.Time{})
+
+	// reset the parser of the agent in case of a partial request
+	_, err := qga.conn.Write([]byte{qgaSyncDelimiter})
+	if err != nil {
+		return err
+	}
+	id := rand.Int63n(1 << 31)
+	err = qga.write(&qgaCommand{
+		Execute: "guest-sync-delimited",
+		Args:    map[string]int64{"id": id},
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		b, err := qga.reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b == qgaSyncDelimiter {
+			break
+		}
+	}
+	for {
+		resp, err := qga.readResponse()
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		var ret int64
+		if json.Unmarshal(resp.Return, &ret) == nil && ret == id {
+			return nil
+		}
+	}
+}
+
+// Execute sends the command to the guest agent and unmarshals its return
+// into result if result is not nil
+func (qga *QemuGuestAgent) Execute(cmd string, args interface{}, timeout time.Duration, result interface{}) error {
+	qga.mutex.Lock()
+	defer qga.mutex.Unlock()
+
+	if qga.conn == nil {
+		err := qga.connect()
+		if err != nil {
+			return err
+		}
+	}
+
+	qga.conn.SetDeadline(time.Now().Add(timeout))
+	err := qga.write(&qgaCommand{Execute: cmd, Args: args})
+	if err != nil {
+		qga.close()
+		return errors.Wrapf(err, "write %s", cmd)
+	}
+	resp, err := qga.readResponse()
+	if err != nil {
+		// the response may arrive later, drop the connection to resync
+		qga.close()
+		return errors.Wrapf(err, "read %s", cmd)
+	}
+	qga.conn.SetDeadline(time.Time{})
+	if resp.Error != nil {
+		return errors.Wrap(resp.Error, cmd)
+	}
+	if result != nil {
+		err = json.Unmarshal(resp.Return, result)
+		if err != nil {
+			return errors.Wrapf(err, "unmarshal %s return %s", cmd, resp.Return)
+		}
+	}
+	return nil
+}
+
+func (qga *QemuGuestAgent) GuestPing() error {
+	return qga.Execute("guest-ping", nil, QGA_DEFAULT_TIMEOUT, nil)
+}
+
+func (qga *QemuGuestAgent) FsfreezeStatus() (string, error) {
+	var status string
+	err := qga.Execute("guest-fsfreeze-status", nil, QGA_DEFAULT_TIMEOUT, &status)
+	return status, err
+}
+
+// FsfreezeFreeze flushes and freezes all the freezable filesystems of the
+// guest, returns the number of filesystems frozen
+func (qga *QemuGuestAgent) FsfreezeFreeze() (int, error) {
+	var cnt int
+	err := qga.Execute("guest-fsfreeze-freeze", nil, QGA_FSFREEZE_TIMEOUT, &cnt)
+	return cnt, err
+}
+
+func (qga *QemuGuestAgent) FsfreezeThaw() (int, error) {
+	var cnt int
+	err := qga.Execute("guest-fsfreeze-thaw", nil, QGA_FSFREEZE_TIMEOUT, &cnt)
+	return cnt, err
+}
+
+func (qga *QemuGuestAgent) GuestExec(path string, args []string, env []string, captureOutput bool) (int, error) {
+	params := map[string]interface{}{
+		"path":           path,
+		"capture-output": captureOutput,
+	}
+	if len(args) > 0 {
+		params["arg"] = args
+	}
+	if len(env) > 0 {
+		params["env"] = env
+	}
+	ret := struct {
+		Pid int `json:"pid"`
+	}{}
+	err := qga.Execute("guest-exec", params, QGA_DEFAULT_TIMEOUT, &ret)
+	return ret.Pid, err
+}
+
+func (qga *QemuGuestAgent) GuestExecStatus(pid int) (*GuestExecStatus, error) {
+	status := &GuestExecStatus{}
+	err := qga.Execute("guest-exec-status", map[string]int{"pid": pid}, QGA_DEFAULT_TIMEOUT, status)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ExecCommand runs the command in the guest and waits for its exit, the
+// output of the command is captured
+func (qga *QemuGuestAgent) ExecCommand(path string, args []string, timeout time.Duration) (*GuestExecResult, error) {
+	pid, err := qga.GuestExec(path, args, nil, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "GuestExec")
+	}
+	deadline := time.Now().Add(timeout)
+	interval := 100 * time.Millisecond
+	for {
+		status, err := qga.GuestExecStatus(pid)
+		if err != nil {
+			return nil, errors.Wrap(err, "GuestExecStatus")
+		}
+		if status.Exited {
+			res := &GuestExecResult{
+				Exitcode: status.Exitcode,
+				Signal:   status.Signal,
+			}
+			out, err := base64.StdEncoding.DecodeString(status.OutData)
+			if err != nil {
+				return nil, errors.Wrap(err, "decode out-data")
+			}
+			res.Stdout = string(out)
+			errOut, err := base64.StdEncoding.DecodeString(status.ErrData)
+			if err != nil {
+				return nil, errors.Wrap(err, "decode err-data")
+			}
+			res.Stderr = string(errOut)
+			return res, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.Wrapf(errors.ErrTimeout, "command %s pid %d not exited in %s", path, pid, timeout)
+		}
+		time.Sleep(interval)
+		if interval < time.Second {
+			interval *= 2
+		}
+	}
+}
+
+// GuestSetUserPassword sets the password of the user in the guest, the
+// password is the hash produced by crypt(3) if crypted is true, which is not
+// supported by the windows agent
+func (qga *QemuGuestAgent) GuestSetUserPassword(username, password string, crypted bool) error {
+	params := map[string]interface{}{
+		"username": username,
+		"password": base64.StdEncoding.EncodeToString([]byte(password)),
+		"crypted":  crypted,
+	}
+	return qga.Execute("guest-set-user-password", params, QGA_DEFAULT_TIMEOUT, nil)
+}
+
+func (qga *QemuGuestAgent) GuestGetOsinfo() (*GuestOsInfo, error) {
+	info := &GuestOsInfo{}
+	err := qga.Execute("guest-get-osinfo", nil, QGA_DEFAULT_TIMEOUT, info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (qga *QemuGuestAgent) GuestNetworkGetInterfaces() ([]GuestNetworkInterface, error) {
+	ifaces := make([]GuestNetworkInterface, 0)
+	err := qga.Execute("guest-network-get-interfaces", nil, QGA_DEFAULT_TIMEOUT, &ifaces)
+	if err != nil {
+		return nil, err
+	}
+	return ifaces, nil
+}
+
+func (qga *QemuGuestAgent) GuestGetFsinfo() ([]GuestFilesystemInfo, error) {
+	fsinfo := make([]GuestFilesystemInfo, 0)
+	err := qga.Execute("guest-get-fsinfo", nil, QGA_DEFAULT_TIMEOUT, &fsinfo)
+	if err != nil {
+		return nil, err
+	}
+	return fsinfo, nil
+}
+
+func (qga *QemuGuestAgent) String() string {
+	return fmt.Sprintf("qga %s(%s)", qga.id, qga.socketPath)
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeGuestAgent answers the requests like qemu-ga, a stale response is sent
+// on connection to verify the client resynchronizes
+func fakeGuestAgent(t *testing.T, l net.Listener, passwords map[string]string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			conn.Write([]byte("{\"return\": {}}\n"))
+			reader := bufio.NewReader(conn)
+			for {
+				line, err := reader.ReadBytes('\n')
+				if err != nil {
+					return
+				}
+				// strip the sentinel written by the client
+				for len(line) > 0 && line[0] == qgaSyncDelimiter {
+					line = line[1:]
+				}
+				cmd := struct {
+					Execute string                 `json:"execute"`
+					Args    map[string]interface{} `json:"arguments"`
+				}{}
+				if err := json.Unmarshal(line, &cmd); err != nil {
+					t.Errorf("unmarshal %q: %s", line, err)
+					return
+				}
+				var resp string
+				switch cmd.Execute {
+				case "guest-sync-delimited":
+					id, _ := json.Marshal(cmd.Args["id"])
+					resp = "\xff{\"return\": " + string(id) + "}"
+				case "guest-ping":
+					resp = `{"return": {}}`
+				case "guest-set-user-password":
+					passwd, _ := base64.StdEncoding.DecodeString(cmd.Args["password"].(string))
+					passwords[cmd.Args["username"].(string)] = string(passwd)
+					resp = `{"return": {}}`
+				case "guest-exec":
+					resp = `{"return": {"pid": 42}}`
+				case "guest-exec-status":
+					resp = `{"return": {"exited": true, "exitcode": 1, "out-data": "` +
+						base64.StdEncoding.EncodeToString([]byte("hello\n")) + `"}}`
+				case "guest-fsfreeze-freeze":
+					resp = `{"return": 2}`
+				default:
+					resp = `{"error": {"class": "CommandNotFound", "desc": "The command ` + cmd.Execute + ` has not been found"}}`
+				}
+				conn.Write([]byte(resp + "\n"))
+			}
+		}(conn)
+	}
+}
+
+func TestQemuGuestAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qga")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "qga.sock")
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+	defer l.Close()
+	passwords := map[string]string{}
+	go fakeGuestAgent(t, l, passwords)
+
+	qga := NewQemuGuestAgent("test", socketPath)
+	defer qga.Close()
+
+	if err := qga.GuestPing(); err != nil {
+		t.Fatalf("GuestPing: %s", err)
+	}
+	if cnt, err := qga.FsfreezeFreeze(); err != nil || cnt != 2 {
+		t.Errorf("FsfreezeFreeze: %d %v", cnt, err)
+	}
+	if err := qga.GuestSetUserPassword("root", "p@ss", false); err != nil {
+		t.Errorf("GuestSetUserPassword: %s", err)
+	} else if passwords["root"] != "p@ss" {
+		t.Errorf("password of root: %q", passwords["root"])
+	}
+	res, err := qga.ExecCommand("/bin/echo", []string{"hello"}, QGA_DEFAULT_TIMEOUT)
+	if err != nil {
+		t.Fatalf("ExecCommand: %s", err)
+	}
+	if res.Exitcode != 1 || res.Stdout != "hello\n" {
+		t.Errorf("ExecCommand result: %#v", res)
+	}
+	if _, err := qga.GuestGetOsinfo(); err == nil {
+		t.Errorf("GuestGetOsinfo should fail")
+	}
+
+	// reconnect after close
+	qga.Close()
+	if err := qga.GuestPing(); err != nil {
+		t.Errorf("GuestPing after reconnect: %s", err)
+	}
+}
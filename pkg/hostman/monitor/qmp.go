@@ -288,10 +288,18 @@ func (m *QmpMonitor) query() {
 }
 
 func (m *QmpMonitor) Query(cmd *Command, cb qmpMonitorCallBack) {
+	start := time.Now()
+	timedCb := func(res *Response) {
+		observeQmpCommand(cmd.Execute, time.Since(start))
+		if cb != nil {
+			cb(res)
+		}
+	}
+
 	// push cmd
 	m.mutex.Lock()
 	m.commandQueue = append(m.commandQueue, cmd)
-	m.callbackQueue = append(m.callbackQueue, cb)
+	m.callbackQueue = append(m.callbackQueue, timedCb)
 	m.mutex.Unlock()
 
 	if m.connected {
@@ -747,6 +755,35 @@ func (m *QmpMonitor) GetMigrateStatus(callback StringCallback) {
 	m.Query(cmd, cb)
 }
 
+func (m *QmpMonitor) GetMigrateStats(callback MigrateStatsCallback) {
+	var (
+		cmd = &Command{Execute: "query-migrate"}
+		cb  = func(res *Response) {
+			if res.ErrorVal != nil {
+				callback(nil, res.ErrorVal)
+				return
+			}
+			info := new(MigrationInfo)
+			if err := json.Unmarshal(res.Return, info); err != nil {
+				callback(nil, errors.Wrapf(err, "unmarshal query-migrate %s", res.Return))
+				return
+			}
+			callback(info, nil)
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) MigrateCancel(callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{Execute: "migrate_cancel"}
+	)
+	m.Query(cmd, cb)
+}
+
 func (m *QmpMonitor) MigrateStartPostcopy(callback StringCallback) {
 	var (
 		cmd = &Command{Execute: "migrate-start-postcopy"}
@@ -998,6 +1035,113 @@ func (m *QmpMonitor) CancelBlockJob(driveName string, force bool, callback Strin
 	m.HumanMonitorCommand(cmd, callback)
 }
 
+// DriveBackup starts a backup job of drive into the existing qcow2 image
+// target. The incremental backup copies the clusters marked dirty in
+// bitmap, which is cleared once the job succeeds. The persistent bitmap
+// newBitmap, if given, is added in the same transaction to track the
+// changes since this backup.
+func (m *QmpMonitor) DriveBackup(jobId, drive, target, syncMode, bitmap, newBitmap string, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		backup = map[string]interface{}{
+			"job-id":       jobId,
+			"device":       drive,
+			"target":       target,
+			"mode":         "existing",
+			"format":       "qcow2",
+			"sync":         syncMode,
+			"auto-dismiss": false,
+		}
+		actions = []interface{}{}
+	)
+	if len(bitmap) > 0 {
+		backup["bitmap"] = bitmap
+	}
+	if len(newBitmap) > 0 {
+		actions = append(actions, map[string]interface{}{
+			"type": "block-dirty-bitmap-add",
+			"data": map[string]interface{}{
+				"node":       drive,
+				"name":       newBitmap,
+				"persistent": true,
+			},
+		})
+	}
+	actions = append(actions, map[string]interface{}{
+		"type": "drive-backup",
+		"data": backup,
+	})
+	cmd := &Command{
+		Execute: "transaction",
+		Args: map[string]interface{}{
+			"actions": actions,
+		},
+	}
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) BlockDirtyBitmapRemove(node, name string, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "block-dirty-bitmap-remove",
+			Args: map[string]interface{}{
+				"node": node,
+				"name": name,
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+// GetJob calls back with the job of jobId, nil if it is not found
+func (m *QmpMonitor) GetJob(jobId string, callback func(*Job)) {
+	var cb = func(res *Response) {
+		if res.ErrorVal != nil {
+			log.Errorf("GetJob for %s %s", m.server, res.ErrorVal.Error())
+			callback(nil)
+			return
+		}
+		jobs := []Job{}
+		ret, err := jsonutils.Parse(res.Return)
+		if err == nil {
+			err = ret.Unmarshal(&jobs)
+		}
+		if err != nil {
+			log.Errorf("GetJob for %s parse %s: %s", m.server, res.Return, err)
+			callback(nil)
+			return
+		}
+		for i := range jobs {
+			if jobs[i].Id == jobId {
+				callback(&jobs[i])
+				return
+			}
+		}
+		callback(nil)
+	}
+	m.Query(&Command{Execute: "query-jobs"}, cb)
+}
+
+func (m *QmpMonitor) JobDismiss(jobId string, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "job-dismiss",
+			Args: map[string]interface{}{
+				"id": jobId,
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
 func (m *QmpMonitor) BlockJobComplete(drive string, callback StringCallback) {
 	m.HumanMonitorCommand(fmt.Sprintf("block_job_complete %s", drive), callback)
 }
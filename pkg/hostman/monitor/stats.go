@@ -0,0 +1,57 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+type QmpCommandStats struct {
+	Count        int64
+	TotalSeconds float64
+}
+
+var (
+	qmpStatsLock sync.Mutex
+	qmpStats     = map[string]*QmpCommandStats{}
+)
+
+func observeQmpCommand(cmd string, elapsed time.Duration) {
+	qmpStatsLock.Lock()
+	defer qmpStatsLock.Unlock()
+
+	stats, ok := qmpStats[cmd]
+	if !ok {
+		stats = &QmpCommandStats{}
+		qmpStats[cmd] = stats
+	}
+	stats.Count += 1
+	stats.TotalSeconds += elapsed.Seconds()
+}
+
+// GetQmpCommandStats returns count and accumulated latency of qmp commands
+// keyed by command name.  Latency counts from when the command was queued
+// to when its response arrived
+func GetQmpCommandStats() map[string]QmpCommandStats {
+	qmpStatsLock.Lock()
+	defer qmpStatsLock.Unlock()
+
+	ret := make(map[string]QmpCommandStats, len(qmpStats))
+	for cmd, stats := range qmpStats {
+		ret[cmd] = *stats
+	}
+	return ret
+}
@@ -125,7 +125,8 @@ type SHostOptions struct {
 	SnapshotDirSuffix  string `help:"Snapshot dir name equal diskId concat snapshot dir suffix" default:"_snap"`
 	SnapshotRecycleDay int    `default:"1" help:"Snapshot Recycle delete Duration day"`
 
-	EnableTelegraf bool `default:"true" help:"enable send monitoring data to telegraf"`
+	EnableTelegraf           bool `default:"true" help:"enable send monitoring data to telegraf"`
+	EnablePrometheusExporter bool `default:"false" help:"expose monitoring data in prometheus text format at /metrics"`
 
 	DisableSetCgroup bool `default:"false" help:"disable cgroup for guests"`
 
@@ -170,6 +171,10 @@ type SHostOptions struct {
 	DisableGPU bool `help:"force disable GPU detect" default:"false" json:"disable_gpu"`
 	DisableUSB bool `help:"force disable USB detect" default:"true" json:"disable_usb"`
 
+	SRIOVNics   []string `help:"SR-IOV physical NICs and number of VFs to create on them, e.g. eth1:8" json:"sriov_nics"`
+	DisableMdev bool     `help:"force disable mediated device detect" default:"true" json:"disable_mdev"`
+	MdevTypes   []string `help:"mdev types exposed as isolated devices, e.g. nvidia-222, all supported types if empty" json:"mdev_types"`
+
 	EthtoolEnableGso bool `help:"use ethtool to turn on or off GSO(generic segment offloading)" default:"false" json:"ethtool_enable_gso"`
 
 	EnableVmUuid bool `help:"enable vm UUID" default:"true" json:"enable_vm_uuid"`
@@ -178,12 +183,17 @@ type SHostOptions struct {
 
 	RestrictQemuImgConvertWorker bool `help:"restrict qemu-img convert worker" default:"false"`
 
-	DefaultLiveMigrateDowntime float32 `help:"allow downtime in seconds for live migrate" default:"5.0"`
+	DefaultLiveMigrateDowntime     float32 `help:"allow downtime in seconds for live migrate" default:"5.0"`
+	LiveMigrateProgressSyncSeconds int     `help:"interval in seconds of syncing live migrate progress to guest metadata" default:"30"`
 
 	LocalBackupStoragePath string `help:"path for mounting backup nfs storage" default:"/opt/cloud/workspace/backupstorage"`
 	LocalBackupTempPath    string `help:"the local temporary directory for backup" default:"/opt/cloud/workspace/run/backups"`
 
 	BinaryMemcleanPath string `help:"execute binary memclean path" default:"/opt/yunion/bin/memclean"`
+
+	MetadataRequireToken    bool   `help:"metadata service only serves requests carrying a session token" default:"false"`
+	MetadataIdentityKeyFile string `help:"PEM private key of the region signing instance identity documents"`
+	MetadataVendorDataFile  string `help:"file served to cloud-init as vendor-data"`
 }
 
 var (
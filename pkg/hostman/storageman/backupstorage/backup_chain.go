@@ -0,0 +1,196 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backupstorage
+
+import (
+	"io/ioutil"
+	"path"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+// An incremental backup is a qcow2 image that only holds the clusters
+// changed since its parent backup, its backing file is the bare id of the
+// parent backup. Members of a chain are fetched into the same directory
+// so that qemu-img resolves the relative backing files by itself.
+
+func withChainWorkDir(prefix string, f func(workDir string) error) error {
+	workDir, err := ioutil.TempDir(options.HostOptions.LocalBackupTempPath, prefix)
+	if err != nil {
+		return errors.Wrap(err, "create tempdir")
+	}
+	defer func() {
+		if output, err := procutils.NewCommand("rm", "-rf", workDir).Output(); err != nil {
+			log.Errorf("unable to rm %s: %s", workDir, output)
+		}
+	}()
+	return f(workDir)
+}
+
+// fetchBackupChain copies the backups of chain, root first, into workDir
+// and returns the path of the last one
+func fetchBackupChain(bs IBackupStorage, workDir string, chain []string) (string, error) {
+	if len(chain) == 0 {
+		return "", errors.Error("empty backup chain")
+	}
+	for _, backupId := range chain {
+		if err := bs.CopyBackupTo(path.Join(workDir, backupId), backupId); err != nil {
+			return "", errors.Wrapf(err, "fetch backup %s", backupId)
+		}
+	}
+	return path.Join(workDir, chain[len(chain)-1]), nil
+}
+
+// ConvertIncrementalFrom saves the image at srcPath as backupId holding only
+// the clusters that differ from the backup chain parentChain, root first.
+// It returns the actual size of the saved backup.
+//
+// Changed clusters are found by comparing the whole image with the chain,
+// so the disk is read and the chain fetched in full. It is used when the
+// changes are not tracked by the dirty bitmap of a running guest.
+func ConvertIncrementalFrom(bs IBackupStorage, srcPath string, format qemuimg.TImageFormat, backupId string, parentChain []string) (int, error) {
+	if len(parentChain) == 0 {
+		return bs.ConvertFrom(srcPath, format, backupId)
+	}
+	var sizeMb int
+	err := withChainWorkDir("incr", func(workDir string) error {
+		if _, err := fetchBackupChain(bs, workDir, parentChain); err != nil {
+			return err
+		}
+		fullPath := srcPath
+		if format != qemuimg.QCOW2 {
+			// the source may not be a file, e.g. rbd
+			fullPath = path.Join(workDir, backupId+".full")
+			srcInfo := qemuimg.SImageInfo{
+				Path:    srcPath,
+				Format:  format,
+				IoLevel: qemuimg.IONiceNone,
+			}
+			destInfo := qemuimg.SImageInfo{
+				Path:    fullPath,
+				Format:  qemuimg.QCOW2,
+				IoLevel: qemuimg.IONiceNone,
+			}
+			if err := qemuimg.Convert(srcInfo, destInfo, true, nil); err != nil {
+				return errors.Wrapf(err, "convert %s", srcPath)
+			}
+		}
+		// an empty overlay of the full image rebased onto the parent
+		// keeps exactly the clusters whose content changed
+		deltaPath := path.Join(workDir, backupId)
+		delta, err := qemuimg.NewQemuImage(deltaPath)
+		if err != nil {
+			return errors.Wrapf(err, "NewQemuImage %s", deltaPath)
+		}
+		if err := delta.CreateQcow2(0, true, fullPath, "", "", ""); err != nil {
+			return errors.Wrapf(err, "create overlay of %s", fullPath)
+		}
+		if err := delta.Rebase(parentChain[len(parentChain)-1], false); err != nil {
+			return errors.Wrapf(err, "rebase %s onto parent", deltaPath)
+		}
+		if err := bs.CopyBackupFrom(deltaPath, backupId); err != nil {
+			return errors.Wrapf(err, "save backup %s", backupId)
+		}
+		sizeMb = delta.GetActualSizeMB()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return sizeMb, nil
+}
+
+// ConvertBackupChainTo flattens the backup chain, root first, into an
+// image of format at destPath
+func ConvertBackupChainTo(bs IBackupStorage, destPath string, format qemuimg.TImageFormat, chain []string) error {
+	if len(chain) == 1 {
+		return bs.ConvertTo(destPath, format, chain[0])
+	}
+	return withChainWorkDir("flatten", func(workDir string) error {
+		topPath, err := fetchBackupChain(bs, workDir, chain)
+		if err != nil {
+			return err
+		}
+		srcInfo := qemuimg.SImageInfo{
+			Path:    topPath,
+			Format:  qemuimg.QCOW2,
+			IoLevel: qemuimg.IONiceNone,
+		}
+		destInfo := qemuimg.SImageInfo{
+			Path:    destPath,
+			Format:  format,
+			IoLevel: qemuimg.IONiceNone,
+		}
+		return qemuimg.Convert(srcInfo, destInfo, format == qemuimg.QCOW2, nil)
+	})
+}
+
+// CopyBackupChainTo flattens the backup chain, root first, into a
+// standalone qcow2 image at targetFilename
+func CopyBackupChainTo(bs IBackupStorage, targetFilename string, chain []string) error {
+	if len(chain) == 1 {
+		return bs.CopyBackupTo(targetFilename, chain[0])
+	}
+	return ConvertBackupChainTo(bs, targetFilename, qemuimg.QCOW2, chain)
+}
+
+// MergeBackupIntoChildren moves the data of the last backup of chain into
+// each of its child backups so that it can be removed, the children
+// become children of its parent, or full backups if it is the root. It
+// returns the new actual sizes of the children.
+func MergeBackupIntoChildren(bs IBackupStorage, chain []string, childIds []string) (map[string]int, error) {
+	sizes := make(map[string]int)
+	if len(childIds) == 0 {
+		return sizes, nil
+	}
+	newParent := ""
+	if len(chain) > 1 {
+		newParent = chain[len(chain)-2]
+	}
+	err := withChainWorkDir("merge", func(workDir string) error {
+		if _, err := fetchBackupChain(bs, workDir, chain); err != nil {
+			return err
+		}
+		for _, childId := range childIds {
+			childPath := path.Join(workDir, childId)
+			if err := bs.CopyBackupTo(childPath, childId); err != nil {
+				return errors.Wrapf(err, "fetch backup %s", childId)
+			}
+			child, err := qemuimg.NewQemuImage(childPath)
+			if err != nil {
+				return errors.Wrapf(err, "NewQemuImage %s", childPath)
+			}
+			// safe rebase copies the clusters of the removed backup
+			// that differ from the new parent into the child
+			if err := child.Rebase(newParent, false); err != nil {
+				return errors.Wrapf(err, "rebase %s", childId)
+			}
+			if err := bs.CopyBackupFrom(childPath, childId); err != nil {
+				return errors.Wrapf(err, "save backup %s", childId)
+			}
+			sizes[childId] = child.GetActualSizeMB()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sizes, nil
+}
@@ -40,15 +40,41 @@ type IBackupStorage interface {
 var backupStoragePool *sync.Map = &sync.Map{}
 
 func NewBackupStorage(backupStroageId string, backupStorageAccessInfo *jsonutils.JSONDict) (IBackupStorage, error) {
-	nfsHost, err := backupStorageAccessInfo.GetString("nfs_host")
-	if err != nil {
-		return nil, fmt.Errorf("need nfs_host in backup_storage_access_info")
-	}
-	nfsSharedDir, err := backupStorageAccessInfo.GetString("nfs_shared_dir")
-	if err != nil {
-		return nil, fmt.Errorf("need nfs_shared_dir in backup_storage_access_info")
+	// access info of storages created before storage_type was added has
+	// no storage_type and is always nfs
+	storageType, _ := backupStorageAccessInfo.GetString("storage_type")
+	switch storageType {
+	case "", api.BACKUPSTORAGE_TYPE_NFS:
+		nfsHost, err := backupStorageAccessInfo.GetString("nfs_host")
+		if err != nil {
+			return nil, fmt.Errorf("need nfs_host in backup_storage_access_info")
+		}
+		nfsSharedDir, err := backupStorageAccessInfo.GetString("nfs_shared_dir")
+		if err != nil {
+			return nil, fmt.Errorf("need nfs_shared_dir in backup_storage_access_info")
+		}
+		return NewNFSBackupStorage(backupStroageId, nfsHost, nfsSharedDir), nil
+	case api.BACKUPSTORAGE_TYPE_LOCAL:
+		localPath, err := backupStorageAccessInfo.GetString("local_path")
+		if err != nil {
+			return nil, fmt.Errorf("need local_path in backup_storage_access_info")
+		}
+		return NewLocalBackupStorage(backupStroageId, localPath), nil
+	case api.BACKUPSTORAGE_TYPE_OBJECT_STORAGE:
+		endpoint, err := backupStorageAccessInfo.GetString("object_endpoint")
+		if err != nil {
+			return nil, fmt.Errorf("need object_endpoint in backup_storage_access_info")
+		}
+		bucket, err := backupStorageAccessInfo.GetString("object_bucket")
+		if err != nil {
+			return nil, fmt.Errorf("need object_bucket in backup_storage_access_info")
+		}
+		accessKey, _ := backupStorageAccessInfo.GetString("object_access_key")
+		secret, _ := backupStorageAccessInfo.GetString("object_secret")
+		return NewObjectBackupStorage(backupStroageId, endpoint, bucket, accessKey, secret)
+	default:
+		return nil, fmt.Errorf("unsupported backup storage type %s", storageType)
 	}
-	return NewNFSBackupStorage(backupStroageId, nfsHost, nfsSharedDir), nil
 }
 
 func GetBackupStorage(backupStroageId string, backupStorageAccessInfo *jsonutils.JSONDict) (IBackupStorage, error) {
@@ -0,0 +1,343 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backupstorage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+// SLocalBackupStorage keeps backups in a directory of the host, the
+// directory may be a local disk or a shared filesystem mounted by the
+// administrator
+type SLocalBackupStorage struct {
+	BackupStorageId string
+	Path            string
+}
+
+func NewLocalBackupStorage(backupStorageId, localPath string) *SLocalBackupStorage {
+	return &SLocalBackupStorage{
+		BackupStorageId: backupStorageId,
+		Path:            localPath,
+	}
+}
+
+func (s *SLocalBackupStorage) getBackupDir() string {
+	return path.Join(s.Path, "backups")
+}
+
+func (s *SLocalBackupStorage) getPackageDir() string {
+	return path.Join(s.Path, "backuppacks")
+}
+
+func (s *SLocalBackupStorage) ensureDirs() error {
+	for _, dir := range []string{s.getBackupDir(), s.getPackageDir()} {
+		if fileutils2.Exists(dir) {
+			continue
+		}
+		output, err := procutils.NewCommand("mkdir", "-p", dir).Output()
+		if err != nil {
+			log.Errorf("mkdir %s failed: %s", dir, output)
+			return errors.Wrapf(err, "mkdir %s failed: %s", dir, output)
+		}
+	}
+	return nil
+}
+
+const (
+	PackageDiskFilename     = "disk"
+	PackageMetadataFilename = "metadata"
+)
+
+func (s *SLocalBackupStorage) CopyBackupFrom(srcFilename string, backupId string) error {
+	if err := s.ensureDirs(); err != nil {
+		return err
+	}
+	backupDir := s.getBackupDir()
+	targetFilename := path.Join(backupDir, backupId)
+	if output, err := procutils.NewCommand("cp", srcFilename, targetFilename).Output(); err != nil {
+		log.Errorf("unable to cp %s to %s: %s", srcFilename, targetFilename, output)
+		return errors.Wrapf(err, "cp %s to %s failed and output is %q", srcFilename, targetFilename, output)
+	}
+	return nil
+}
+
+func (s *SLocalBackupStorage) CopyBackupTo(targetFilename string, backupId string) error {
+	backupDir := s.getBackupDir()
+	srcFilename := path.Join(backupDir, backupId)
+	if output, err := procutils.NewCommand("cp", srcFilename, targetFilename).Output(); err != nil {
+		log.Errorf("unable to cp %s to %s: %s", srcFilename, targetFilename, output)
+		return errors.Wrapf(err, "cp %s to %s failed and output is %q", srcFilename, targetFilename, output)
+	}
+	return nil
+}
+
+func (s *SLocalBackupStorage) InstancePack(ctx context.Context, packageName string, backupIds []string, metadata *api.InstanceBackupPackMetadata) (string, error) {
+	if err := s.ensureDirs(); err != nil {
+		return "", err
+	}
+	tmpFileDir, err := ioutil.TempDir(options.HostOptions.LocalBackupTempPath, "pack")
+	if err != nil {
+		return "", errors.Wrap(err, "create tempdir")
+	}
+	defer func() {
+		if output, err := procutils.NewCommand("rm", "-rf", tmpFileDir).Output(); err != nil {
+			log.Errorf("unable to rm %s: %s", tmpFileDir, output)
+		}
+	}()
+
+	backupDir := s.getBackupDir()
+
+	packagePath := path.Join(tmpFileDir, packageName)
+	tmpPkgFilename := path.Join(tmpFileDir, packageName+".tar")
+
+	err = func() error {
+		if _, err := procutils.NewCommand("touch", packageName).Output(); err != nil {
+			return errors.Wrapf(err, "create %s", packageName)
+		}
+		return nil
+	}()
+	if err != nil {
+		return "", errors.Wrap(err, "A package with the same name already exists")
+	}
+
+	output, err := procutils.NewCommand("mkdir", "-p", packagePath).Output()
+	if err != nil {
+		log.Errorf("mkdir %s failed: %s", packagePath, output)
+		return "", errors.Wrapf(err, "mkdir %s failed: %s", packagePath, output)
+	}
+	defer func() {
+		if output, err := procutils.NewCommand("rm", "-rf", packagePath).Output(); err != nil {
+			log.Errorf("unable to rm %s: %s", packagePath, output)
+		}
+	}()
+	// copy disk files
+	for i, backupId := range backupIds {
+		packageDiskPath := path.Join(packagePath, fmt.Sprintf("%s_%d", PackageDiskFilename, i))
+		backupPath := path.Join(backupDir, backupId)
+		if output, err := procutils.NewCommand("cp", backupPath, packageDiskPath).Output(); err != nil {
+			log.Errorf("unable to cp %s to %s: %s", backupPath, packageDiskPath, output)
+			return "", errors.Wrapf(err, "cp %s to %s failed and output is %q", backupPath, packageDiskPath, output)
+		}
+	}
+	// save snapshot metadata
+	packageMetadataPath := path.Join(packagePath, PackageMetadataFilename)
+	err = ioutil.WriteFile(packageMetadataPath, []byte(jsonutils.Marshal(metadata).PrettyString()), 0644)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to write to %s", packageMetadataPath)
+	}
+	// tar
+	if output, err := procutils.NewCommand("tar", "-cf", tmpPkgFilename, "-C", tmpFileDir, packageName).Output(); err != nil {
+		log.Errorf("unable to 'tar -cf %s -C %s %s': %s", tmpPkgFilename, tmpFileDir, packageName, output)
+		return "", errors.Wrap(err, "unable to tar")
+	}
+	// move to pack dir
+	var packageFilename string
+	{
+		lockman.LockRawObject(ctx, "package", packageName)
+		defer lockman.ReleaseRawObject(ctx, "package", packageName)
+
+		packageDir := s.getPackageDir()
+
+		// find the filename
+		tried := 0
+		packageFilename = path.Join(packageDir, packageName+".tar")
+		for fileutils2.Exists(packageFilename) {
+			tried++
+			packageFilename = path.Join(packageDir, fmt.Sprintf("%s-%d.tar", packageName, tried))
+		}
+
+		// move file
+		if output, err := procutils.NewCommand("cp", "-a", tmpPkgFilename, packageFilename).Output(); err != nil {
+			log.Errorf("cp %s to %s fail: %s", tmpPkgFilename, packageFilename, output)
+			return "", errors.Wrap(err, "cp")
+		}
+	}
+	return packageFilename, nil
+}
+
+func (s *SLocalBackupStorage) InstanceUnpack(ctx context.Context, packageName string, metadataOnly bool) ([]string, *api.InstanceBackupPackMetadata, error) {
+	if err := s.ensureDirs(); err != nil {
+		return nil, nil, err
+	}
+	// create temp working dir
+	tmpFileDir, err := ioutil.TempDir(options.HostOptions.LocalBackupTempPath, "unpack")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create tempdir")
+	}
+	defer func() {
+		if output, err := procutils.NewCommand("rm", "-rf", tmpFileDir).Output(); err != nil {
+			log.Errorf("unable to rm %s: %s", tmpFileDir, output)
+		}
+	}()
+
+	backupDir := s.getBackupDir()
+	packageDir := s.getPackageDir()
+	if strings.HasSuffix(packageName, ".tar") {
+		// remove suffix
+		packageName = packageName[:len(packageName)-4]
+	}
+	packageFilename := path.Join(packageDir, packageName+".tar")
+	if !fileutils2.Exists(packageFilename) {
+		return nil, nil, errors.Wrapf(errors.ErrNotFound, "package %s does not exists", packageName)
+	}
+
+	// untar to temp dir
+	packagePath := path.Join(tmpFileDir, packageName)
+	log.Infof("unpack to %s", packagePath)
+	untarArgs := []string{
+		"-xf", packageFilename, "-C", tmpFileDir,
+	}
+	if metadataOnly {
+		untarArgs = append(untarArgs, fmt.Sprintf("%s/metadata", packageName))
+	} else {
+		untarArgs = append(untarArgs, packageName)
+	}
+	if output, err := procutils.NewCommand("tar", untarArgs...).Output(); err != nil {
+		log.Errorf("unable to 'tar -xf %s -C %s %s': %s", packageFilename, tmpFileDir, packageName, output)
+		return nil, nil, errors.Wrap(err, "unable to untar")
+	}
+	// unpack metadata
+	packageMetadataPath := path.Join(packagePath, PackageMetadataFilename)
+	metadataBytes, err := ioutil.ReadFile(packageMetadataPath)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to read metadata file")
+	}
+	metadataJson, err := jsonutils.Parse(metadataBytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to parse string to json")
+	}
+	metadata := &api.InstanceBackupPackMetadata{}
+	err = metadataJson.Unmarshal(metadata)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unmarshal backup metadata")
+	}
+	// copy disk files only if !metadataOnly
+	backupIds := make([]string, len(metadata.DiskMetadatas))
+	if !metadataOnly {
+		for i := 0; i < len(metadata.DiskMetadatas); i++ {
+			backupId := db.DefaultUUIDGenerator()
+			backupIds[i] = backupId
+			backupPath := path.Join(backupDir, backupId)
+			packageDiskPath := path.Join(packagePath, fmt.Sprintf("%s_%d", PackageDiskFilename, i))
+			if output, err := procutils.NewCommand("cp", "-a", packageDiskPath, backupPath).Output(); err != nil {
+				return nil, nil, errors.Wrapf(err, "mv %s to %s failed and output is %q", packageDiskPath, backupPath, output)
+			}
+		}
+	}
+	return backupIds, metadata, nil
+}
+
+func (s *SLocalBackupStorage) ConvertFrom(srcPath string, format qemuimg.TImageFormat, backupId string) (int, error) {
+	if err := s.ensureDirs(); err != nil {
+		return 0, err
+	}
+	backupDir := s.getBackupDir()
+	destPath := path.Join(backupDir, backupId)
+	srcInfo := qemuimg.SImageInfo{
+		Path:     srcPath,
+		Format:   format,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	destInfo := qemuimg.SImageInfo{
+		Path:     destPath,
+		Format:   qemuimg.QCOW2,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	err := qemuimg.Convert(srcInfo, destInfo, true, nil)
+	if err != nil {
+		return 0, err
+	}
+	newImage, err := qemuimg.NewQemuImage(destPath)
+	if err != nil {
+		return 0, err
+	}
+	return newImage.GetActualSizeMB(), nil
+}
+
+func (s *SLocalBackupStorage) ConvertTo(destPath string, format qemuimg.TImageFormat, backupId string) error {
+	backupDir := s.getBackupDir()
+	srcPath := path.Join(backupDir, backupId)
+	srcInfo := qemuimg.SImageInfo{
+		Path:     srcPath,
+		Format:   qemuimg.QCOW2,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	destInfo := qemuimg.SImageInfo{
+		Path:     destPath,
+		Format:   format,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	var workerOpts []string
+	if options.HostOptions.RestrictQemuImgConvertWorker {
+		workerOpts = nil
+	} else {
+		workerOpts = []string{"-W", "-m", "16"}
+	}
+	return qemuimg.Convert(srcInfo, destInfo, false, workerOpts)
+}
+
+func (s *SLocalBackupStorage) GetBackupPath(backupId string) string {
+	backupDir := s.getBackupDir()
+	return path.Join(backupDir, backupId)
+}
+
+func (s *SLocalBackupStorage) RemoveBackup(backupId string) error {
+	backupDir := s.getBackupDir()
+	filename := path.Join(backupDir, backupId)
+	if !fileutils2.Exists(filename) {
+		return nil
+	}
+	if output, err := procutils.NewCommand("rm", filename).Output(); err != nil {
+		log.Errorf("unable to rm %s: %s", filename, output)
+		return errors.Wrapf(err, "rm %s failed and output is %q", filename, output)
+	}
+	return nil
+}
+
+func (s *SLocalBackupStorage) IsExists(backupId string) (bool, error) {
+	backupDir := s.getBackupDir()
+	filename := path.Join(backupDir, backupId)
+	return fileutils2.Exists(filename), nil
+}
+
+func (s *SLocalBackupStorage) IsOnline() (bool, string, error) {
+	if !fileutils2.IsDir(s.Path) {
+		return false, fmt.Sprintf("%s: %s is not a directory", api.BackupStorageOffline, s.Path), nil
+	}
+	if err := s.ensureDirs(); err != nil {
+		return false, err.Error(), nil
+	}
+	return true, "", nil
+}
@@ -17,19 +17,14 @@ package backupstorage
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"path"
-	"strings"
 	"sync"
 	"time"
 
-	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
-	"yunion.io/x/onecloud/pkg/cloudcommon/db"
-	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
 	"yunion.io/x/onecloud/pkg/util/procutils"
@@ -38,33 +33,28 @@ import (
 
 var ErrorBackupStorageOffline error = errors.Error(api.BackupStorageOffline)
 
+// SNFSBackupStorage mounts the nfs shared dir under LocalBackupStoragePath
+// and works on it like a local backup storage
 type SNFSBackupStorage struct {
-	BackupStorageId string
-	Path            string
-	NfsHost         string
-	NfsSharedDir    string
-	lock            *sync.Mutex
-	userNumber      int
+	SLocalBackupStorage
+	NfsHost      string
+	NfsSharedDir string
+	lock         *sync.Mutex
+	userNumber   int
 }
 
 func NewNFSBackupStorage(backupStorageId, nfsHost, nfsSharedDir string) *SNFSBackupStorage {
 	return &SNFSBackupStorage{
-		BackupStorageId: backupStorageId,
-		NfsHost:         nfsHost,
-		NfsSharedDir:    nfsSharedDir,
-		Path:            path.Join(options.HostOptions.LocalBackupStoragePath, backupStorageId),
-		lock:            &sync.Mutex{},
+		SLocalBackupStorage: SLocalBackupStorage{
+			BackupStorageId: backupStorageId,
+			Path:            path.Join(options.HostOptions.LocalBackupStoragePath, backupStorageId),
+		},
+		NfsHost:      nfsHost,
+		NfsSharedDir: nfsSharedDir,
+		lock:         &sync.Mutex{},
 	}
 }
 
-func (s *SNFSBackupStorage) getBackupDir() string {
-	return path.Join(s.Path, "backups")
-}
-
-func (s *SNFSBackupStorage) getPackageDir() string {
-	return path.Join(s.Path, "backuppacks")
-}
-
 func (s *SNFSBackupStorage) checkAndMount() error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -86,21 +76,8 @@ func (s *SNFSBackupStorage) checkAndMount() error {
 	if err != nil {
 		return errors.Wrap(ErrorBackupStorageOffline, err.Error())
 	}
-	backupDir := s.getBackupDir()
-	if !fileutils2.Exists(backupDir) {
-		output, err := procutils.NewCommand("mkdir", "-p", backupDir).Output()
-		if err != nil {
-			log.Errorf("mkdir %s failed: %s", backupDir, output)
-			return errors.Wrapf(err, "mkdir %s failed: %s", backupDir, output)
-		}
-	}
-	packageDir := s.getPackageDir()
-	if !fileutils2.Exists(packageDir) {
-		output, err := procutils.NewCommand("mkdir", "-p", packageDir).Output()
-		if err != nil {
-			log.Errorf("mkdir %s failed: %s", packageDir, output)
-			return errors.Wrapf(err, "mkdir %s failed: %s", packageDir, output)
-		}
+	if err := s.ensureDirs(); err != nil {
+		return err
 	}
 	s.userNumber++
 	return nil
@@ -126,13 +103,7 @@ func (s *SNFSBackupStorage) CopyBackupFrom(srcFilename string, backupId string)
 		return errors.Wrap(err, "unable to checkAndMount")
 	}
 	defer s.unMount()
-	backupDir := s.getBackupDir()
-	targetFilename := path.Join(backupDir, backupId)
-	if output, err := procutils.NewCommand("cp", srcFilename, targetFilename).Output(); err != nil {
-		log.Errorf("unable to cp %s to %s: %s", srcFilename, targetFilename, output)
-		return errors.Wrapf(err, "cp %s to %s failed and output is %q", srcFilename, targetFilename, output)
-	}
-	return nil
+	return s.SLocalBackupStorage.CopyBackupFrom(srcFilename, backupId)
 }
 
 func (s *SNFSBackupStorage) CopyBackupTo(targetFilename string, backupId string) error {
@@ -141,20 +112,9 @@ func (s *SNFSBackupStorage) CopyBackupTo(targetFilename string, backupId string)
 		return errors.Wrap(err, "unable to checkAndMount")
 	}
 	defer s.unMount()
-	backupDir := s.getBackupDir()
-	srcFilename := path.Join(backupDir, backupId)
-	if output, err := procutils.NewCommand("cp", srcFilename, targetFilename).Output(); err != nil {
-		log.Errorf("unable to cp %s to %s: %s", srcFilename, targetFilename, output)
-		return errors.Wrapf(err, "cp %s to %s failed and output is %q", srcFilename, targetFilename, output)
-	}
-	return nil
+	return s.SLocalBackupStorage.CopyBackupTo(targetFilename, backupId)
 }
 
-const (
-	PackageDiskFilename     = "disk"
-	PackageMetadataFilename = "metadata"
-)
-
 /*
 func (s *SNFSBackupStorage) Pack(backupId string, packageName string, metadata jsonutils.JSONObject) error {
 	err := s.checkAndMount()
@@ -263,85 +223,7 @@ func (s *SNFSBackupStorage) InstancePack(ctx context.Context, packageName string
 		return "", errors.Wrap(err, "unable to checkAndMount")
 	}
 	defer s.unMount()
-
-	tmpFileDir, err := ioutil.TempDir(options.HostOptions.LocalBackupTempPath, "pack")
-	if err != nil {
-		return "", errors.Wrap(err, "create tempdir")
-	}
-	defer func() {
-		if output, err := procutils.NewCommand("rm", "-rf", tmpFileDir).Output(); err != nil {
-			log.Errorf("unable to rm %s: %s", tmpFileDir, output)
-		}
-	}()
-
-	backupDir := s.getBackupDir()
-
-	packagePath := path.Join(tmpFileDir, packageName)
-	tmpPkgFilename := path.Join(tmpFileDir, packageName+".tar")
-
-	err = func() error {
-		if _, err := procutils.NewCommand("touch", packageName).Output(); err != nil {
-			return errors.Wrapf(err, "create %s", packageName)
-		}
-		return nil
-	}()
-	if err != nil {
-		return "", errors.Wrap(err, "A package with the same name already exists")
-	}
-
-	output, err := procutils.NewCommand("mkdir", "-p", packagePath).Output()
-	if err != nil {
-		log.Errorf("mkdir %s failed: %s", packagePath, output)
-		return "", errors.Wrapf(err, "mkdir %s failed: %s", packagePath, output)
-	}
-	defer func() {
-		if output, err := procutils.NewCommand("rm", "-rf", packagePath).Output(); err != nil {
-			log.Errorf("unable to rm %s: %s", packagePath, output)
-		}
-	}()
-	// copy disk files
-	for i, backupId := range backupIds {
-		packageDiskPath := path.Join(packagePath, fmt.Sprintf("%s_%d", PackageDiskFilename, i))
-		backupPath := path.Join(backupDir, backupId)
-		if output, err := procutils.NewCommand("cp", backupPath, packageDiskPath).Output(); err != nil {
-			log.Errorf("unable to cp %s to %s: %s", backupPath, packageDiskPath, output)
-			return "", errors.Wrapf(err, "cp %s to %s failed and output is %q", backupPath, packageDiskPath, output)
-		}
-	}
-	// save snapshot metadata
-	packageMetadataPath := path.Join(packagePath, PackageMetadataFilename)
-	err = ioutil.WriteFile(packageMetadataPath, []byte(jsonutils.Marshal(metadata).PrettyString()), 0644)
-	if err != nil {
-		return "", errors.Wrapf(err, "unable to write to %s", packageMetadataPath)
-	}
-	// tar
-	if output, err := procutils.NewCommand("tar", "-cf", tmpPkgFilename, "-C", tmpFileDir, packageName).Output(); err != nil {
-		log.Errorf("unable to 'tar -cf %s -C %s %s': %s", tmpPkgFilename, tmpFileDir, packageName, output)
-		return "", errors.Wrap(err, "unable to tar")
-	}
-	// move to pack dir
-	var packageFilename string
-	{
-		lockman.LockRawObject(ctx, "package", packageName)
-		defer lockman.ReleaseRawObject(ctx, "package", packageName)
-
-		packageDir := s.getPackageDir()
-
-		// find the filename
-		tried := 0
-		packageFilename = path.Join(packageDir, packageName+".tar")
-		for fileutils2.Exists(packageFilename) {
-			tried++
-			packageFilename = path.Join(packageDir, fmt.Sprintf("%s-%d.tar", packageName, tried))
-		}
-
-		// move file
-		if output, err := procutils.NewCommand("cp", "-a", tmpPkgFilename, packageFilename).Output(); err != nil {
-			log.Errorf("cp %s to %s fail: %s", tmpPkgFilename, packageFilename, output)
-			return "", errors.Wrap(err, "cp")
-		}
-	}
-	return packageFilename, nil
+	return s.SLocalBackupStorage.InstancePack(ctx, packageName, backupIds, metadata)
 }
 
 func (s *SNFSBackupStorage) InstanceUnpack(ctx context.Context, packageName string, metadataOnly bool) ([]string, *api.InstanceBackupPackMetadata, error) {
@@ -350,73 +232,7 @@ func (s *SNFSBackupStorage) InstanceUnpack(ctx context.Context, packageName stri
 		return nil, nil, errors.Wrap(err, "unable to checkAndMount")
 	}
 	defer s.unMount()
-
-	// create temp working dir
-	tmpFileDir, err := ioutil.TempDir(options.HostOptions.LocalBackupTempPath, "unpack")
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "create tempdir")
-	}
-	defer func() {
-		if output, err := procutils.NewCommand("rm", "-rf", tmpFileDir).Output(); err != nil {
-			log.Errorf("unable to rm %s: %s", tmpFileDir, output)
-		}
-	}()
-
-	backupDir := s.getBackupDir()
-	packageDir := s.getPackageDir()
-	if strings.HasSuffix(packageName, ".tar") {
-		// remove suffix
-		packageName = packageName[:len(packageName)-4]
-	}
-	packageFilename := path.Join(packageDir, packageName+".tar")
-	if !fileutils2.Exists(packageFilename) {
-		return nil, nil, errors.Wrapf(errors.ErrNotFound, "package %s does not exists", packageName)
-	}
-
-	// untar to temp dir
-	packagePath := path.Join(tmpFileDir, packageName)
-	log.Infof("unpack to %s", packagePath)
-	untarArgs := []string{
-		"-xf", packageFilename, "-C", tmpFileDir,
-	}
-	if metadataOnly {
-		untarArgs = append(untarArgs, fmt.Sprintf("%s/metadata", packageName))
-	} else {
-		untarArgs = append(untarArgs, packageName)
-	}
-	if output, err := procutils.NewCommand("tar", untarArgs...).Output(); err != nil {
-		log.Errorf("unable to 'tar -xf %s -C %s %s': %s", packageFilename, tmpFileDir, packageName, output)
-		return nil, nil, errors.Wrap(err, "unable to untar")
-	}
-	// unpack metadata
-	packageMetadataPath := path.Join(packagePath, PackageMetadataFilename)
-	metadataBytes, err := ioutil.ReadFile(packageMetadataPath)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "unable to read metadata file")
-	}
-	metadataJson, err := jsonutils.Parse(metadataBytes)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "unable to parse string to json")
-	}
-	metadata := &api.InstanceBackupPackMetadata{}
-	err = metadataJson.Unmarshal(metadata)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "unmarshal backup metadata")
-	}
-	// copy disk files only if !metadataOnly
-	backupIds := make([]string, len(metadata.DiskMetadatas))
-	if !metadataOnly {
-		for i := 0; i < len(metadata.DiskMetadatas); i++ {
-			backupId := db.DefaultUUIDGenerator()
-			backupIds[i] = backupId
-			backupPath := path.Join(backupDir, backupId)
-			packageDiskPath := path.Join(packagePath, fmt.Sprintf("%s_%d", PackageDiskFilename, i))
-			if output, err := procutils.NewCommand("cp", "-a", packageDiskPath, backupPath).Output(); err != nil {
-				return nil, nil, errors.Wrapf(err, "mv %s to %s failed and output is %q", packageDiskPath, backupPath, output)
-			}
-		}
-	}
-	return backupIds, metadata, nil
+	return s.SLocalBackupStorage.InstanceUnpack(ctx, packageName, metadataOnly)
 }
 
 func (s *SNFSBackupStorage) ConvertFrom(srcPath string, format qemuimg.TImageFormat, backupId string) (int, error) {
@@ -425,29 +241,7 @@ func (s *SNFSBackupStorage) ConvertFrom(srcPath string, format qemuimg.TImageFor
 		return 0, errors.Wrap(err, "unable to checkAndMount")
 	}
 	defer s.unMount()
-	backupDir := s.getBackupDir()
-	destPath := path.Join(backupDir, backupId)
-	srcInfo := qemuimg.SImageInfo{
-		Path:     srcPath,
-		Format:   format,
-		IoLevel:  qemuimg.IONiceNone,
-		Password: "",
-	}
-	destInfo := qemuimg.SImageInfo{
-		Path:     destPath,
-		Format:   qemuimg.QCOW2,
-		IoLevel:  qemuimg.IONiceNone,
-		Password: "",
-	}
-	err = qemuimg.Convert(srcInfo, destInfo, true, nil)
-	if err != nil {
-		return 0, err
-	}
-	newImage, err := qemuimg.NewQemuImage(destPath)
-	if err != nil {
-		return 0, err
-	}
-	return newImage.GetActualSizeMB(), nil
+	return s.SLocalBackupStorage.ConvertFrom(srcPath, format, backupId)
 }
 
 func (s *SNFSBackupStorage) ConvertTo(destPath string, format qemuimg.TImageFormat, backupId string) error {
@@ -456,32 +250,7 @@ func (s *SNFSBackupStorage) ConvertTo(destPath string, format qemuimg.TImageForm
 		return errors.Wrap(err, "unable to checkAndMount")
 	}
 	defer s.unMount()
-	backupDir := s.getBackupDir()
-	srcPath := path.Join(backupDir, backupId)
-	srcInfo := qemuimg.SImageInfo{
-		Path:     srcPath,
-		Format:   qemuimg.QCOW2,
-		IoLevel:  qemuimg.IONiceNone,
-		Password: "",
-	}
-	destInfo := qemuimg.SImageInfo{
-		Path:     destPath,
-		Format:   format,
-		IoLevel:  qemuimg.IONiceNone,
-		Password: "",
-	}
-	var workerOpts []string
-	if options.HostOptions.RestrictQemuImgConvertWorker {
-		workerOpts = nil
-	} else {
-		workerOpts = []string{"-W", "-m", "16"}
-	}
-	return qemuimg.Convert(srcInfo, destInfo, false, workerOpts)
-}
-
-func (s *SNFSBackupStorage) GetBackupPath(backupId string) string {
-	backupDir := s.getBackupDir()
-	return path.Join(backupDir, backupId)
+	return s.SLocalBackupStorage.ConvertTo(destPath, format, backupId)
 }
 
 func (s *SNFSBackupStorage) RemoveBackup(backupId string) error {
@@ -490,16 +259,7 @@ func (s *SNFSBackupStorage) RemoveBackup(backupId string) error {
 		return errors.Wrap(err, "unable to checkAndMount")
 	}
 	defer s.unMount()
-	backupDir := s.getBackupDir()
-	filename := path.Join(backupDir, backupId)
-	if !fileutils2.Exists(filename) {
-		return nil
-	}
-	if output, err := procutils.NewCommand("rm", filename).Output(); err != nil {
-		log.Errorf("unable to rm %s: %s", filename, output)
-		return errors.Wrapf(err, "rm %s failed and output is %q", filename, output)
-	}
-	return nil
+	return s.SLocalBackupStorage.RemoveBackup(backupId)
 }
 
 func (s *SNFSBackupStorage) IsExists(backupId string) (bool, error) {
@@ -508,9 +268,7 @@ func (s *SNFSBackupStorage) IsExists(backupId string) (bool, error) {
 		return false, errors.Wrap(err, "unable to checkAndMount")
 	}
 	defer s.unMount()
-	backupDir := s.getBackupDir()
-	filename := path.Join(backupDir, backupId)
-	return fileutils2.Exists(filename), nil
+	return s.SLocalBackupStorage.IsExists(backupId)
 }
 
 func (s *SNFSBackupStorage) IsOnline() (bool, string, error) {
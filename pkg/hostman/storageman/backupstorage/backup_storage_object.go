@@ -0,0 +1,497 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backupstorage
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/s3cli"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+const (
+	// objectPartSize is the part size of multipart upload, large backups
+	// are uploaded in parts of this size
+	objectPartSize = 64 * 1024 * 1024
+
+	objectChecksumMeta = "Md5sum"
+
+	ErrBackupChecksumMismatch = errors.Error("backup checksum mismatch")
+)
+
+// SObjectBackupStorage keeps backups and packages as objects of a bucket
+// of an S3 compatible object storage. Restores stream the objects, new
+// backups and packages are built in LocalBackupTempPath because qemu-img
+// and tar write seekable files.
+type SObjectBackupStorage struct {
+	BackupStorageId string
+	Endpoint        string
+	Bucket          string
+
+	client *s3cli.Client
+}
+
+func NewObjectBackupStorage(backupStorageId, endpoint, bucket, accessKey, secret string) (*SObjectBackupStorage, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid object storage endpoint %s", endpoint)
+	}
+	host, secure := u.Host, u.Scheme == "https"
+	if len(host) == 0 {
+		// endpoint without scheme
+		host, secure = endpoint, false
+	}
+	cli, err := s3cli.New(host, accessKey, secret, secure, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3cli.New")
+	}
+	return &SObjectBackupStorage{
+		BackupStorageId: backupStorageId,
+		Endpoint:        endpoint,
+		Bucket:          bucket,
+		client:          cli,
+	}, nil
+}
+
+func (s *SObjectBackupStorage) getBackupKey(backupId string) string {
+	return path.Join("backups", backupId)
+}
+
+func (s *SObjectBackupStorage) getPackageKey(packageName string) string {
+	return path.Join("backuppacks", packageName+".tar")
+}
+
+func isObjectNotFound(err error) bool {
+	switch s3cli.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NotFound":
+		return true
+	}
+	return false
+}
+
+func (s *SObjectBackupStorage) isObjectExists(key string) (bool, error) {
+	_, err := s.client.StatObject(s.Bucket, key, s3cli.StatObjectOptions{})
+	if err != nil {
+		if isObjectNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "StatObject %s", key)
+	}
+	return true, nil
+}
+
+// upload puts the file with its md5 checksum as user metadata, files
+// larger than objectPartSize are uploaded with multipart upload
+func (s *SObjectBackupStorage) upload(ctx context.Context, filename string, key string) error {
+	checksum, err := fileutils2.MD5(filename)
+	if err != nil {
+		return errors.Wrapf(err, "md5 %s", filename)
+	}
+	opts := s3cli.PutObjectOptions{
+		ContentType:  "application/octet-stream",
+		PartSize:     objectPartSize,
+		UserMetadata: map[string]string{objectChecksumMeta: checksum},
+	}
+	if _, err := s.client.FPutObjectWithContext(ctx, s.Bucket, key, filename, opts); err != nil {
+		return errors.Wrapf(err, "upload %s to %s", filename, key)
+	}
+	return nil
+}
+
+// download streams the object to filename and verifies the checksum
+// saved by upload
+func (s *SObjectBackupStorage) download(ctx context.Context, key string, filename string) error {
+	obj, err := s.client.GetObjectWithContext(ctx, s.Bucket, key, s3cli.GetObjectOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "GetObject %s", key)
+	}
+	defer obj.Close()
+	info, err := obj.Stat()
+	if err != nil {
+		if isObjectNotFound(err) {
+			return errors.Wrapf(errors.ErrNotFound, "object %s", key)
+		}
+		return errors.Wrapf(err, "stat object %s", key)
+	}
+	fp, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrapf(err, "create %s", filename)
+	}
+	defer fp.Close()
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(fp, hash), obj); err != nil {
+		return errors.Wrapf(err, "download %s to %s", key, filename)
+	}
+	expect := info.Metadata.Get("X-Amz-Meta-" + objectChecksumMeta)
+	if len(expect) > 0 {
+		if actual := hex.EncodeToString(hash.Sum(nil)); actual != expect {
+			return errors.Wrapf(ErrBackupChecksumMismatch, "object %s expect %s got %s", key, expect, actual)
+		}
+	}
+	return nil
+}
+
+func (s *SObjectBackupStorage) mkTempDir(prefix string) (string, func(), error) {
+	tmpFileDir, err := ioutil.TempDir(options.HostOptions.LocalBackupTempPath, prefix)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "create tempdir")
+	}
+	return tmpFileDir, func() {
+		if output, err := procutils.NewCommand("rm", "-rf", tmpFileDir).Output(); err != nil {
+			log.Errorf("unable to rm %s: %s", tmpFileDir, output)
+		}
+	}, nil
+}
+
+func (s *SObjectBackupStorage) CopyBackupFrom(srcFilename string, backupId string) error {
+	return s.upload(context.Background(), srcFilename, s.getBackupKey(backupId))
+}
+
+func (s *SObjectBackupStorage) CopyBackupTo(targetFilename string, backupId string) error {
+	return s.download(context.Background(), s.getBackupKey(backupId), targetFilename)
+}
+
+func (s *SObjectBackupStorage) ConvertFrom(srcPath string, format qemuimg.TImageFormat, backupId string) (int, error) {
+	tmpFileDir, cleanup, err := s.mkTempDir("convert")
+	if err != nil {
+		return 0, err
+	}
+	defer cleanup()
+	destPath := path.Join(tmpFileDir, backupId)
+	srcInfo := qemuimg.SImageInfo{
+		Path:     srcPath,
+		Format:   format,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	destInfo := qemuimg.SImageInfo{
+		Path:     destPath,
+		Format:   qemuimg.QCOW2,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	err = qemuimg.Convert(srcInfo, destInfo, true, nil)
+	if err != nil {
+		return 0, err
+	}
+	newImage, err := qemuimg.NewQemuImage(destPath)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.upload(context.Background(), destPath, s.getBackupKey(backupId)); err != nil {
+		return 0, err
+	}
+	return newImage.GetActualSizeMB(), nil
+}
+
+// streamSource returns a qemu-img source reading the backup object over
+// http through the qemu curl block driver, qcow2 needs random access so
+// the object can not be piped. The object is served on loopback under a
+// random path by the storage client, so the endpoint certificate is
+// verified by the client and no credential or presigned url shows up on
+// the qemu-img command line. The returned function stops serving.
+func (s *SObjectBackupStorage) streamSource(backupId string) (string, func(), error) {
+	key := s.getBackupKey(backupId)
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", nil, errors.Wrap(err, "generate token")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "listen loopback")
+	}
+	servePath := "/" + hex.EncodeToString(token)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != servePath {
+				http.NotFound(w, r)
+				return
+			}
+			obj, err := s.client.GetObjectWithContext(r.Context(), s.Bucket, key, s3cli.GetObjectOptions{})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			defer obj.Close()
+			info, err := obj.Stat()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			// the object supports seek, so range requests are served
+			// by ranged gets of the object
+			http.ServeContent(w, r, path.Base(key), info.LastModified, obj)
+		}),
+	}
+	go srv.Serve(listener)
+	src := jsonutils.NewDict()
+	src.Set("file.driver", jsonutils.NewString("http"))
+	src.Set("file.url", jsonutils.NewString(fmt.Sprintf("http://%s%s", listener.Addr().String(), servePath)))
+	return "json:" + src.String(), func() { srv.Close() }, nil
+}
+
+func (s *SObjectBackupStorage) ConvertTo(destPath string, format qemuimg.TImageFormat, backupId string) error {
+	destInfo := qemuimg.SImageInfo{
+		Path:     destPath,
+		Format:   format,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	var workerOpts []string
+	if options.HostOptions.RestrictQemuImgConvertWorker {
+		workerOpts = nil
+	} else {
+		workerOpts = []string{"-W", "-m", "16"}
+	}
+	srcInfo := qemuimg.SImageInfo{
+		Format:   qemuimg.QCOW2,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	streamSrc, stopStream, err := s.streamSource(backupId)
+	if err == nil {
+		srcInfo.Path = streamSrc
+		err = qemuimg.Convert(srcInfo, destInfo, false, workerOpts)
+		stopStream()
+		if err == nil {
+			return nil
+		}
+	}
+	// qemu built without the curl block driver can not read the object
+	// directly, stage it in the temp dir then
+	log.Warningf("stream restore of backup %s failed, fallback to staged restore: %v", backupId, err)
+	tmpFileDir, cleanup, err := s.mkTempDir("convert")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	srcPath := path.Join(tmpFileDir, backupId)
+	if err := s.download(context.Background(), s.getBackupKey(backupId), srcPath); err != nil {
+		return err
+	}
+	srcInfo.Path = srcPath
+	return qemuimg.Convert(srcInfo, destInfo, false, workerOpts)
+}
+
+func (s *SObjectBackupStorage) InstancePack(ctx context.Context, packageName string, backupIds []string, metadata *api.InstanceBackupPackMetadata) (string, error) {
+	tmpFileDir, cleanup, err := s.mkTempDir("pack")
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	packagePath := path.Join(tmpFileDir, packageName)
+	tmpPkgFilename := path.Join(tmpFileDir, packageName+".tar")
+	output, err := procutils.NewCommand("mkdir", "-p", packagePath).Output()
+	if err != nil {
+		log.Errorf("mkdir %s failed: %s", packagePath, output)
+		return "", errors.Wrapf(err, "mkdir %s failed: %s", packagePath, output)
+	}
+	// download disk files
+	for i, backupId := range backupIds {
+		packageDiskPath := path.Join(packagePath, fmt.Sprintf("%s_%d", PackageDiskFilename, i))
+		if err := s.download(ctx, s.getBackupKey(backupId), packageDiskPath); err != nil {
+			return "", err
+		}
+	}
+	// save snapshot metadata
+	packageMetadataPath := path.Join(packagePath, PackageMetadataFilename)
+	err = ioutil.WriteFile(packageMetadataPath, []byte(jsonutils.Marshal(metadata).PrettyString()), 0644)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to write to %s", packageMetadataPath)
+	}
+	// tar
+	if output, err := procutils.NewCommand("tar", "-cf", tmpPkgFilename, "-C", tmpFileDir, packageName).Output(); err != nil {
+		log.Errorf("unable to 'tar -cf %s -C %s %s': %s", tmpPkgFilename, tmpFileDir, packageName, output)
+		return "", errors.Wrap(err, "unable to tar")
+	}
+	// upload to the first free package name
+	lockman.LockRawObject(ctx, "package", packageName)
+	defer lockman.ReleaseRawObject(ctx, "package", packageName)
+
+	tried := 0
+	finalName := packageName
+	for {
+		exists, err := s.isObjectExists(s.getPackageKey(finalName))
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			break
+		}
+		tried++
+		finalName = fmt.Sprintf("%s-%d", packageName, tried)
+	}
+	if err := s.upload(ctx, tmpPkgFilename, s.getPackageKey(finalName)); err != nil {
+		return "", err
+	}
+	return finalName + ".tar", nil
+}
+
+// InstanceUnpack reads the package tar stream from the object storage and
+// uploads the disks in it as backups while reading, nothing is staged
+// locally
+func (s *SObjectBackupStorage) InstanceUnpack(ctx context.Context, packageName string, metadataOnly bool) ([]string, *api.InstanceBackupPackMetadata, error) {
+	packageName = strings.TrimSuffix(path.Base(packageName), ".tar")
+	key := s.getPackageKey(packageName)
+	obj, err := s.client.GetObjectWithContext(ctx, s.Bucket, key, s3cli.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "GetObject %s", key)
+	}
+	defer obj.Close()
+	if _, err := obj.Stat(); err != nil {
+		if isObjectNotFound(err) {
+			return nil, nil, errors.Wrapf(errors.ErrNotFound, "package %s does not exists", packageName)
+		}
+		return nil, nil, errors.Wrapf(err, "stat object %s", key)
+	}
+	return s.unpackStream(ctx, obj, metadataOnly)
+}
+
+func (s *SObjectBackupStorage) unpackStream(ctx context.Context, reader io.Reader, metadataOnly bool) ([]string, *api.InstanceBackupPackMetadata, error) {
+	var metadata *api.InstanceBackupPackMetadata
+	diskBackupIds := make(map[int]string)
+	tr := tar.NewReader(reader)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "read package")
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Base(hdr.Name)
+		if name == PackageMetadataFilename {
+			metadataBytes, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "unable to read metadata file")
+			}
+			metadataJson, err := jsonutils.Parse(metadataBytes)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "unable to parse string to json")
+			}
+			metadata = &api.InstanceBackupPackMetadata{}
+			err = metadataJson.Unmarshal(metadata)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "unmarshal backup metadata")
+			}
+			if metadataOnly {
+				break
+			}
+			continue
+		}
+		if metadataOnly || !strings.HasPrefix(name, PackageDiskFilename+"_") {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(name, PackageDiskFilename+"_"))
+		if err != nil {
+			continue
+		}
+		backupId := db.DefaultUUIDGenerator()
+		if err := s.uploadStream(ctx, tr, hdr.Size, s.getBackupKey(backupId)); err != nil {
+			return nil, nil, errors.Wrapf(err, "upload disk %d", index)
+		}
+		diskBackupIds[index] = backupId
+	}
+	if metadata == nil {
+		return nil, nil, errors.Wrap(errors.ErrNotFound, "no metadata in package")
+	}
+	backupIds := make([]string, len(metadata.DiskMetadatas))
+	if !metadataOnly {
+		for i := range backupIds {
+			backupId, ok := diskBackupIds[i]
+			if !ok {
+				return nil, nil, errors.Wrapf(errors.ErrNotFound, "no disk %d in package", i)
+			}
+			backupIds[i] = backupId
+		}
+	}
+	return backupIds, metadata, nil
+}
+
+// uploadStream puts size bytes of reader with the md5 checksum computed
+// while uploading
+func (s *SObjectBackupStorage) uploadStream(ctx context.Context, reader io.Reader, size int64, key string) error {
+	hash := md5.New()
+	opts := s3cli.PutObjectOptions{
+		ContentType: "application/octet-stream",
+		PartSize:    objectPartSize,
+	}
+	if _, err := s.client.PutObjectWithContext(ctx, s.Bucket, key, io.TeeReader(reader, hash), size, opts); err != nil {
+		return errors.Wrapf(err, "upload %s", key)
+	}
+	// the checksum is known only after the upload, save it by copying the
+	// object onto itself with the metadata replaced
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	src := s3cli.NewSourceInfo(s.Bucket, key, nil)
+	dst, err := s3cli.NewDestinationInfo(s.Bucket, key, nil, map[string]string{objectChecksumMeta: checksum})
+	if err != nil {
+		return errors.Wrap(err, "NewDestinationInfo")
+	}
+	if err := s.client.CopyObject(dst, src); err != nil {
+		return errors.Wrapf(err, "save checksum of %s", key)
+	}
+	return nil
+}
+
+func (s *SObjectBackupStorage) RemoveBackup(backupId string) error {
+	key := s.getBackupKey(backupId)
+	if err := s.client.RemoveObject(s.Bucket, key); err != nil && !isObjectNotFound(err) {
+		return errors.Wrapf(err, "RemoveObject %s", key)
+	}
+	return nil
+}
+
+func (s *SObjectBackupStorage) IsExists(backupId string) (bool, error) {
+	return s.isObjectExists(s.getBackupKey(backupId))
+}
+
+func (s *SObjectBackupStorage) IsOnline() (bool, string, error) {
+	exists, _, err := s.client.BucketExists(s.Bucket)
+	if err != nil {
+		return false, fmt.Sprintf("%s: %s", api.BackupStorageOffline, err), nil
+	}
+	if !exists {
+		return false, fmt.Sprintf("%s: bucket %s not found", api.BackupStorageOffline, s.Bucket), nil
+	}
+	return true, "", nil
+}
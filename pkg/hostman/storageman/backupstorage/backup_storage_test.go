@@ -0,0 +1,178 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backupstorage
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestNewBackupStorage(t *testing.T) {
+	cases := []struct {
+		name     string
+		info     *jsonutils.JSONDict
+		wantType interface{}
+		wantErr  bool
+	}{
+		{
+			// storages created before storage_type was added
+			name: "legacy nfs",
+			info: jsonutils.Marshal(map[string]string{
+				"nfs_host":       "10.0.0.1",
+				"nfs_shared_dir": "/backup",
+			}).(*jsonutils.JSONDict),
+			wantType: &SNFSBackupStorage{},
+		},
+		{
+			name: "nfs",
+			info: jsonutils.Marshal(map[string]string{
+				"storage_type":   api.BACKUPSTORAGE_TYPE_NFS,
+				"nfs_host":       "10.0.0.1",
+				"nfs_shared_dir": "/backup",
+			}).(*jsonutils.JSONDict),
+			wantType: &SNFSBackupStorage{},
+		},
+		{
+			name: "nfs without host",
+			info: jsonutils.Marshal(map[string]string{
+				"storage_type": api.BACKUPSTORAGE_TYPE_NFS,
+			}).(*jsonutils.JSONDict),
+			wantErr: true,
+		},
+		{
+			name: "local",
+			info: jsonutils.Marshal(map[string]string{
+				"storage_type": api.BACKUPSTORAGE_TYPE_LOCAL,
+				"local_path":   "/opt/backup",
+			}).(*jsonutils.JSONDict),
+			wantType: &SLocalBackupStorage{},
+		},
+		{
+			name: "object",
+			info: jsonutils.Marshal(map[string]string{
+				"storage_type":      api.BACKUPSTORAGE_TYPE_OBJECT_STORAGE,
+				"object_endpoint":   "http://127.0.0.1:9000",
+				"object_bucket":     "backup",
+				"object_access_key": "ak",
+				"object_secret":     "sk",
+			}).(*jsonutils.JSONDict),
+			wantType: &SObjectBackupStorage{},
+		},
+		{
+			name: "object without bucket",
+			info: jsonutils.Marshal(map[string]string{
+				"storage_type":    api.BACKUPSTORAGE_TYPE_OBJECT_STORAGE,
+				"object_endpoint": "http://127.0.0.1:9000",
+			}).(*jsonutils.JSONDict),
+			wantErr: true,
+		},
+		{
+			name: "unknown",
+			info: jsonutils.Marshal(map[string]string{
+				"storage_type": "ceph",
+			}).(*jsonutils.JSONDict),
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bs, err := NewBackupStorage("bs-test", c.info)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %T", bs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewBackupStorage: %v", err)
+			}
+			switch c.wantType.(type) {
+			case *SNFSBackupStorage:
+				_, ok := bs.(*SNFSBackupStorage)
+				if !ok {
+					t.Fatalf("want nfs storage, got %T", bs)
+				}
+			case *SLocalBackupStorage:
+				_, ok := bs.(*SLocalBackupStorage)
+				if !ok {
+					t.Fatalf("want local storage, got %T", bs)
+				}
+			case *SObjectBackupStorage:
+				_, ok := bs.(*SObjectBackupStorage)
+				if !ok {
+					t.Fatalf("want object storage, got %T", bs)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalBackupStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backupstorage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bs := NewLocalBackupStorage("bs-test", path.Join(dir, "store"))
+	if online, _, _ := bs.IsOnline(); online {
+		t.Fatalf("storage without directory should be offline")
+	}
+	if err := os.Mkdir(bs.Path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if online, reason, err := bs.IsOnline(); !online || err != nil {
+		t.Fatalf("storage should be online: %s %v", reason, err)
+	}
+
+	src := path.Join(dir, "src")
+	if err := ioutil.WriteFile(src, []byte("backup data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.CopyBackupFrom(src, "backup-1"); err != nil {
+		t.Fatalf("CopyBackupFrom: %v", err)
+	}
+	if exists, _ := bs.IsExists("backup-1"); !exists {
+		t.Fatalf("backup-1 should exist")
+	}
+	if bs.GetBackupPath("backup-1") != path.Join(bs.Path, "backups", "backup-1") {
+		t.Fatalf("unexpected backup path %s", bs.GetBackupPath("backup-1"))
+	}
+
+	dst := path.Join(dir, "dst")
+	if err := bs.CopyBackupTo(dst, "backup-1"); err != nil {
+		t.Fatalf("CopyBackupTo: %v", err)
+	}
+	data, err := ioutil.ReadFile(dst)
+	if err != nil || string(data) != "backup data" {
+		t.Fatalf("restored %q %v", data, err)
+	}
+
+	if err := bs.RemoveBackup("backup-1"); err != nil {
+		t.Fatalf("RemoveBackup: %v", err)
+	}
+	if exists, _ := bs.IsExists("backup-1"); exists {
+		t.Fatalf("backup-1 should be removed")
+	}
+	if err := bs.RemoveBackup("backup-1"); err != nil {
+		t.Fatalf("RemoveBackup of missing backup: %v", err)
+	}
+}
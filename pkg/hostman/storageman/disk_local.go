@@ -438,16 +438,22 @@ func (d *SLocalDisk) DiskBackup(ctx context.Context, params interface{}) (jsonut
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to backup snapshot")
 	}
-	_, err = d.Storage.StorageBackup(ctx, &SStorageBackup{
+	ret, err := d.Storage.StorageBackup(ctx, &SStorageBackup{
 		BackupId:                diskBackup.BackupId,
 		BackupStorageId:         diskBackup.BackupStorageId,
 		BackupStorageAccessInfo: diskBackup.BackupStorageAccessInfo,
+		ParentBackupChain:       diskBackup.ParentBackupChain,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to SStorageBackup")
 	}
+	sizeMb := int64(newImage.GetActualSizeMB())
+	if ret != nil && ret.Contains("size_mb") {
+		// incremental backup is smaller than the cloned image
+		sizeMb, _ = ret.Int("size_mb")
+	}
 	data := jsonutils.NewDict()
-	data.Set("size_mb", jsonutils.NewInt(int64(newImage.GetActualSizeMB())))
+	data.Set("size_mb", jsonutils.NewInt(sizeMb))
 	return data, nil
 }
 
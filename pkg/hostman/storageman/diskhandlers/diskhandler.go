@@ -331,7 +331,13 @@ func diskSnapshot(ctx context.Context, userCred mcclient.TokenCredential, storag
 	if err != nil {
 		return nil, httperrors.NewMissingParameterError("snapshot_id")
 	}
-	hostutils.DelayTask(ctx, disk.DiskSnapshot, snapshotId)
+	hostutils.DelayTask(ctx, func(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+		// shared storage snapshots bypass the guest snapshot path, freeze the
+		// filesystems of the running guest here to keep backups consistent
+		thaw := guestman.GetGuestManager().QgaFreezeDiskFs(diskId)
+		defer thaw()
+		return disk.DiskSnapshot(ctx, params)
+	}, snapshotId)
 	return nil, nil
 }
 
@@ -383,7 +389,7 @@ func diskBackup(ctx context.Context, userCred mcclient.TokenCredential, storage
 	if err != nil {
 		return nil, errors.Wrap(err, "JsonUnmarshal")
 	}
-	if len(backupInfo.SnapshotId) == 0 {
+	if len(backupInfo.SnapshotId) == 0 && !backupInfo.Live {
 		return nil, httperrors.NewMissingParameterError("snapshot_id")
 	}
 	if len(backupInfo.BackupId) == 0 {
@@ -393,6 +399,12 @@ func diskBackup(ctx context.Context, userCred mcclient.TokenCredential, storage
 		return nil, httperrors.NewMissingParameterError("backup_storage_id")
 	}
 	backupInfo.UserCred = userCred
+	if backupInfo.Live {
+		hostutils.DelayTask(ctx, func(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+			return guestman.GetGuestManager().DiskLiveBackup(ctx, disk, backupInfo)
+		}, nil)
+		return nil, nil
+	}
 	hostutils.DelayTask(ctx, disk.DiskBackup, backupInfo)
 	return nil, nil
 }
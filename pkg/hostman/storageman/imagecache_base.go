@@ -16,6 +16,7 @@ package storageman
 
 import (
 	"context"
+	"sync/atomic"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/hostman/storageman/remotefile"
@@ -32,3 +33,14 @@ type IImageCache interface {
 
 	GetDesc() *remotefile.SImageDesc
 }
+
+var (
+	imageCacheHits   int64
+	imageCacheMisses int64
+)
+
+// GetImageCacheStats returns how many local image cache acquisitions were
+// served from cache and how many had to fetch the image
+func GetImageCacheStats() (hits int64, misses int64) {
+	return atomic.LoadInt64(&imageCacheHits), atomic.LoadInt64(&imageCacheMisses)
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"path"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -155,8 +156,10 @@ func (l *SLocalImageCache) Acquire(ctx context.Context, input api.CacheImageInpu
 		return errors.Wrapf(err, "prepare")
 	}
 	if isOk {
+		atomic.AddInt64(&imageCacheHits, 1)
 		return nil
 	}
+	atomic.AddInt64(&imageCacheMisses, 1)
 	return l.fetch(ctx, input, callback)
 }
 
@@ -112,6 +112,7 @@ func (s *SLocalStorage) CreateDiskFromBackup(ctx context.Context, disk IDisk, in
 			BackupId:                input.DiskInfo.Backup.BackupId,
 			BackupStorageId:         input.DiskInfo.Backup.BackupStorageId,
 			BackupStorageAccessInfo: input.DiskInfo.Backup.BackupStorageAccessInfo.Copy(),
+			ParentBackupChain:       input.DiskInfo.Backup.ParentBackupChain,
 		})
 		if err != nil {
 			return errors.Wrap(err, "unable to storageBackupRecovery")
@@ -156,9 +157,20 @@ func (s *SLocalStorage) StorageBackup(ctx context.Context, params interface{}) (
 		return nil, err
 	}
 	backupPath := path.Join(s.GetBackupDir(), sbParams.BackupId)
-	err = backupStorage.CopyBackupFrom(backupPath, sbParams.BackupId)
-	if err != nil {
-		return nil, err
+	var ret jsonutils.JSONObject
+	if len(sbParams.ParentBackupChain) > 0 && !sbParams.Incremental {
+		sizeMb, err := backupstorage.ConvertIncrementalFrom(backupStorage, backupPath, qemuimg.QCOW2, sbParams.BackupId, sbParams.ParentBackupChain)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to save incremental backup")
+		}
+		data := jsonutils.NewDict()
+		data.Set("size_mb", jsonutils.NewInt(int64(sizeMb)))
+		ret = data
+	} else {
+		err = backupStorage.CopyBackupFrom(backupPath, sbParams.BackupId)
+		if err != nil {
+			return nil, err
+		}
 	}
 	// remove local backup
 	output, err := procutils.NewCommand("rm", backupPath).Output()
@@ -166,7 +178,7 @@ func (s *SLocalStorage) StorageBackup(ctx context.Context, params interface{}) (
 		log.Errorf("rm %s failed %s", backupPath, output)
 		return nil, errors.Wrapf(err, "rm %s failed %s", backupPath, output)
 	}
-	return nil, nil
+	return ret, nil
 }
 
 func (s *SLocalStorage) storageBackupRecovery(ctx context.Context, sbParams *SStorageBackup) (jsonutils.JSONObject, error) {
@@ -175,7 +187,7 @@ func (s *SLocalStorage) storageBackupRecovery(ctx context.Context, sbParams *SSt
 		return nil, err
 	}
 	backupPath := path.Join(s.GetBackupDir(), sbParams.BackupId)
-	return nil, backupStorage.CopyBackupTo(backupPath, sbParams.BackupId)
+	return nil, backupstorage.CopyBackupChainTo(backupStorage, backupPath, sbParams.GetBackupChain())
 }
 
 func (s *SLocalStorage) StorageBackupRecovery(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
@@ -298,7 +298,7 @@ func (s *SRbdStorage) resetDisk(pool string, diskId string, snapshotId string) e
 	return snap.Rollback()
 }
 
-func (s *SRbdStorage) createBackup(pool string, diskId string, snapshotId string, backupId string, backupStorageId string, backupStorageAccessInfo *jsonutils.JSONDict) (int, error) {
+func (s *SRbdStorage) createBackup(pool string, diskId string, snapshotId string, backupId string, backupStorageId string, backupStorageAccessInfo *jsonutils.JSONDict, parentBackupChain []string) (int, error) {
 	client, err := s.GetClient()
 	if err != nil {
 		return 0, errors.Wrapf(err, "GetClient")
@@ -330,7 +330,7 @@ func (s *SRbdStorage) createBackup(pool string, diskId string, snapshotId string
 	}
 	srcPath := fmt.Sprintf("rbd:%s/%s%s", pool, backupName, s.getStorageConfString())
 	// convert
-	sizeMb, err := backupStorage.ConvertFrom(srcPath, qemuimg.RAW, backupId)
+	sizeMb, err := backupstorage.ConvertIncrementalFrom(backupStorage, srcPath, qemuimg.RAW, backupId, parentBackupChain)
 	if err != nil {
 		return 0, errors.Wrapf(err, "unable to ConvertFrom with srcPath %s and format %s", srcPath, qemuimg.RAW.String())
 	}
@@ -589,7 +589,8 @@ func (s *SRbdStorage) CreateDiskFromBackup(ctx context.Context, disk IDisk, inpu
 	if err != nil {
 		return errors.Wrap(err, "unable to GetBackupStorage")
 	}
-	err = backupStorage.ConvertTo(destPath, qemuimg.RAW, backup.BackupId)
+	chain := append(append([]string{}, backup.ParentBackupChain...), backup.BackupId)
+	err = backupstorage.ConvertBackupChainTo(backupStorage, destPath, qemuimg.RAW, chain)
 	if err != nil {
 		return errors.Wrapf(err, "unable to Convert to with destPath %s and format %s", destPath, qemuimg.RAW.String())
 	}
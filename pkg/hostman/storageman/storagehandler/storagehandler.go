@@ -362,10 +362,14 @@ func storageDeleteBackup(ctx context.Context, w http.ResponseWriter, r *http.Req
 		hostutils.Response(ctx, w, httperrors.NewMissingParameterError("backup_storage_access_info"))
 		return
 	}
+	parentBackupChain := jsonutils.GetQueryStringArray(body, "parent_backup_chain")
+	childBackupIds := jsonutils.GetQueryStringArray(body, "child_backup_ids")
 	hostutils.DelayTask(ctx, deleteBackup, &storageman.SStorageBackup{
 		BackupId:                backupId,
 		BackupStorageId:         backupStorageId,
 		BackupStorageAccessInfo: backupStorageAccessInfo.(*jsonutils.JSONDict),
+		ParentBackupChain:       parentBackupChain,
+		ChildBackupIds:          childBackupIds,
 	})
 	hostutils.ResponseOk(ctx, w)
 }
@@ -387,11 +391,21 @@ func deleteBackup(ctx context.Context, params interface{}) (jsonutils.JSONObject
 	if err != nil {
 		return nil, err
 	}
+	var ret jsonutils.JSONObject
+	if len(sbParams.ChildBackupIds) > 0 {
+		sizes, err := backupstorage.MergeBackupIntoChildren(backupStorage, sbParams.GetBackupChain(), sbParams.ChildBackupIds)
+		if err != nil {
+			return nil, errors.Wrap(err, "MergeBackupIntoChildren")
+		}
+		data := jsonutils.NewDict()
+		data.Set("child_size_mbs", jsonutils.Marshal(sizes))
+		ret = data
+	}
 	err = backupStorage.RemoveBackup(sbParams.BackupId)
 	if err != nil {
 		return nil, err
 	}
-	return nil, nil
+	return ret, nil
 }
 
 func storageDeleteSnapshots(ctx context.Context, w http.ResponseWriter, r *http.Request) {
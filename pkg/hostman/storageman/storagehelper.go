@@ -51,6 +51,12 @@ type SDiskBakcup struct {
 	BackupId                string              `json:"backup_id"`
 	BackupStorageId         string              `json:"backup_storage_id"`
 	BackupStorageAccessInfo *jsonutils.JSONDict `json:"backup_storage_access_info"`
+	// ParentBackupChain is the backup chain, root first, the backup is
+	// incremental to, empty for full backup
+	ParentBackupChain []string `json:"parent_backup_chain"`
+	// Live backs up the disk of the running guest by dirty bitmaps
+	// instead of the snapshot
+	Live bool `json:"live"`
 
 	EncryptKeyId string `json:"encrypt_key_id"`
 
@@ -61,6 +67,20 @@ type SStorageBackup struct {
 	BackupId                string
 	BackupStorageId         string
 	BackupStorageAccessInfo *jsonutils.JSONDict
+	ParentBackupChain       []string
+	// Incremental is true if the backup image already holds only the
+	// clusters changed since its parent, its backing file set
+	Incremental bool
+	// ChildBackupIds are the incremental backups to merge the backup into
+	// before it is removed
+	ChildBackupIds []string
+}
+
+// GetBackupChain returns the backup chain, root first, ending at the backup
+func (sb *SStorageBackup) GetBackupChain() []string {
+	chain := make([]string, 0, len(sb.ParentBackupChain)+1)
+	chain = append(chain, sb.ParentBackupChain...)
+	return append(chain, sb.BackupId)
 }
 
 type SStoragePackBackup struct {
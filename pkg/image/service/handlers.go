@@ -65,6 +65,10 @@ func InitHandlers(app *appsrv.Application) {
 		models.QuotaManager,
 		models.QuotaUsageManager,
 		models.QuotaPendingUsageManager,
+
+		quotas.QuotaAlertManager,
+		quotas.QuotaUsageHistoryManager,
+		quotas.QuotaChangeRequestManager,
 	} {
 		db.RegisterModelManager(manager)
 	}
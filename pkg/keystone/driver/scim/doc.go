@@ -0,0 +1,19 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scim implements a SCIM 2.0 (RFC 7643, RFC 7644) service provider
+// bound to a keystone identity provider. Users and groups pushed by the
+// SCIM client are provisioned into the domain of the identity provider and
+// linked to it through the id mappings.
+package scim // import "yunion.io/x/onecloud/pkg/keystone/driver/scim"
@@ -0,0 +1,486 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+)
+
+// IFilter is a parsed SCIM filter expression, RFC 7644 section 3.4.2.2
+type IFilter interface {
+	Match(obj jsonutils.JSONObject) bool
+}
+
+const (
+	filterOpEq = "eq"
+	filterOpNe = "ne"
+	filterOpCo = "co"
+	filterOpSw = "sw"
+	filterOpEw = "ew"
+	filterOpPr = "pr"
+	filterOpGt = "gt"
+	filterOpGe = "ge"
+	filterOpLt = "lt"
+	filterOpLe = "le"
+)
+
+var compareOps = []string{filterOpEq, filterOpNe, filterOpCo, filterOpSw, filterOpEw, filterOpGt, filterOpGe, filterOpLt, filterOpLe}
+
+// sAttrFilter compares the values of an attribute path, e.g. 'name.familyName co "O'Malley"'
+type sAttrFilter struct {
+	Path  []string
+	Op    string
+	Value jsonutils.JSONObject
+}
+
+// sLogicFilter combines two filters with 'and' or 'or'
+type sLogicFilter struct {
+	Op    string
+	Left  IFilter
+	Right IFilter
+}
+
+type sNotFilter struct {
+	Filter IFilter
+}
+
+// sValuePathFilter applies a filter to the elements of a multi-valued
+// attribute, e.g. 'emails[type eq "work" and value co "@example.com"]'
+type sValuePathFilter struct {
+	Attr   string
+	Filter IFilter
+}
+
+func (f *sLogicFilter) Match(obj jsonutils.JSONObject) bool {
+	if f.Op == "and" {
+		return f.Left.Match(obj) && f.Right.Match(obj)
+	}
+	return f.Left.Match(obj) || f.Right.Match(obj)
+}
+
+func (f *sNotFilter) Match(obj jsonutils.JSONObject) bool {
+	return !f.Filter.Match(obj)
+}
+
+func (f *sValuePathFilter) Match(obj jsonutils.JSONObject) bool {
+	for _, elem := range getAttrValues(obj, f.Attr) {
+		if f.Filter.Match(elem) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *sAttrFilter) Match(obj jsonutils.JSONObject) bool {
+	values := resolvePath(obj, f.Path)
+	if f.Op == filterOpPr {
+		for _, v := range values {
+			if !isEmptyValue(v) {
+				return true
+			}
+		}
+		return false
+	}
+	if f.Op == filterOpNe {
+		return !(&sAttrFilter{Path: f.Path, Op: filterOpEq, Value: f.Value}).Match(obj)
+	}
+	for _, v := range values {
+		if compareValue(v, f.Op, f.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyValue(v jsonutils.JSONObject) bool {
+	switch vv := v.(type) {
+	case *jsonutils.JSONString:
+		return len(vv.Value()) == 0
+	case *jsonutils.JSONArray:
+		return vv.Length() == 0
+	case *jsonutils.JSONDict:
+		return vv.Length() == 0
+	}
+	return v == nil || v == jsonutils.JSONNull
+}
+
+// compareValue compares an attribute value with the value in filter,
+// string comparison is case-insensitive as attributes are not caseExact
+// except the id which is compared exactly anyway by uuid.
+func compareValue(v jsonutils.JSONObject, op string, target jsonutils.JSONObject) bool {
+	if target == jsonutils.JSONNull {
+		return op == filterOpEq && isEmptyValue(v)
+	}
+	switch t := target.(type) {
+	case *jsonutils.JSONBool:
+		b, err := parseBool(v)
+		return err == nil && op == filterOpEq && b == t.Value()
+	case *jsonutils.JSONInt, *jsonutils.JSONFloat:
+		a, err1 := parseFloat(v)
+		b, err2 := parseFloat(target)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		switch op {
+		case filterOpEq:
+			return a == b
+		case filterOpGt:
+			return a > b
+		case filterOpGe:
+			return a >= b
+		case filterOpLt:
+			return a < b
+		case filterOpLe:
+			return a <= b
+		}
+		return false
+	}
+	a, err := v.GetString()
+	if err != nil {
+		return false
+	}
+	b, _ := target.GetString()
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	switch op {
+	case filterOpEq:
+		return a == b
+	case filterOpCo:
+		return strings.Contains(a, b)
+	case filterOpSw:
+		return strings.HasPrefix(a, b)
+	case filterOpEw:
+		return strings.HasSuffix(a, b)
+	case filterOpGt:
+		return a > b
+	case filterOpGe:
+		return a >= b
+	case filterOpLt:
+		return a < b
+	case filterOpLe:
+		return a <= b
+	}
+	return false
+}
+
+func parseFloat(v jsonutils.JSONObject) (float64, error) {
+	switch vv := v.(type) {
+	case *jsonutils.JSONInt:
+		return float64(vv.Value()), nil
+	case *jsonutils.JSONFloat:
+		return vv.Value(), nil
+	}
+	s, err := v.GetString()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseBool accepts boolean as well as string values like "False",
+// which are sent by some SCIM clients
+func parseBool(v jsonutils.JSONObject) (bool, error) {
+	if v == nil {
+		return false, errors.ErrNotFound
+	}
+	if b, ok := v.(*jsonutils.JSONBool); ok {
+		return b.Value(), nil
+	}
+	s, err := v.GetString()
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(strings.ToLower(s))
+}
+
+// ParseFilter parses a SCIM filter expression
+func ParseFilter(filter string) (IFilter, error) {
+	tokens, err := tokenizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	p := &sFilterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, errors.Wrapf(ErrInvalidFilter, "unexpected %q", p.tokens[p.pos].text)
+	}
+	return f, nil
+}
+
+type sFilterToken struct {
+	text string
+	// quoted string, number, boolean or null
+	value jsonutils.JSONObject
+}
+
+func tokenizeFilter(filter string) ([]sFilterToken, error) {
+	tokens := make([]sFilterToken, 0)
+	for i := 0; i < len(filter); {
+		c := filter[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			tokens = append(tokens, sFilterToken{text: string(c)})
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(filter) && filter[j] != '"'; j++ {
+				if filter[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(filter) {
+				return nil, errors.Wrap(ErrInvalidFilter, "unterminated string")
+			}
+			val, err := jsonutils.ParseString(filter[i : j+1])
+			if err != nil {
+				return nil, errors.Wrapf(ErrInvalidFilter, "invalid string %s", filter[i:j+1])
+			}
+			tokens = append(tokens, sFilterToken{text: filter[i : j+1], value: val})
+			i = j + 1
+		default:
+			j := i
+			for ; j < len(filter) && !strings.ContainsRune(" \t()[]\"", rune(filter[j])); j++ {
+			}
+			tokens = append(tokens, sFilterToken{text: filter[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type sFilterParser struct {
+	tokens []sFilterToken
+	pos    int
+}
+
+func (p *sFilterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *sFilterParser) peekKeyword(kw string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].value == nil && strings.EqualFold(p.tokens[p.pos].text, kw)
+}
+
+func (p *sFilterParser) expect(text string) error {
+	if p.peek() != text {
+		return errors.Wrapf(ErrInvalidFilter, "expect %q at %d", text, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *sFilterParser) parseOr() (IFilter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &sLogicFilter{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *sFilterParser) parseAnd() (IFilter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &sLogicFilter{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *sFilterParser) parseUnary() (IFilter, error) {
+	if p.peekKeyword("not") {
+		p.pos++
+		err := p.expect("(")
+		if err != nil {
+			return nil, err
+		}
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		err = p.expect(")")
+		if err != nil {
+			return nil, err
+		}
+		return &sNotFilter{Filter: f}, nil
+	}
+	if p.peek() == "(" {
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		err = p.expect(")")
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	return p.parseAttrExp()
+}
+
+func (p *sFilterParser) parseAttrExp() (IFilter, error) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].value != nil {
+		return nil, errors.Wrapf(ErrInvalidFilter, "expect attribute path at %d", p.pos)
+	}
+	attr := p.tokens[p.pos].text
+	if strings.ContainsAny(attr, "()[]") {
+		return nil, errors.Wrapf(ErrInvalidFilter, "expect attribute path at %d", p.pos)
+	}
+	p.pos++
+	if p.peek() == "[" {
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		err = p.expect("]")
+		if err != nil {
+			return nil, err
+		}
+		return &sValuePathFilter{Attr: trimSchema(attr), Filter: f}, nil
+	}
+	path := splitAttrPath(attr)
+	if p.pos >= len(p.tokens) {
+		return nil, errors.Wrapf(ErrInvalidFilter, "missing operator after %s", attr)
+	}
+	op := strings.ToLower(p.tokens[p.pos].text)
+	p.pos++
+	if op == filterOpPr {
+		return &sAttrFilter{Path: path, Op: op}, nil
+	}
+	found := false
+	for _, o := range compareOps {
+		if o == op {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.Wrapf(ErrInvalidFilter, "unsupported operator %s", op)
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, errors.Wrapf(ErrInvalidFilter, "missing value after %s %s", attr, op)
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	val := tok.value
+	if val == nil {
+		switch strings.ToLower(tok.text) {
+		case "true":
+			val = jsonutils.JSONTrue
+		case "false":
+			val = jsonutils.JSONFalse
+		case "null":
+			val = jsonutils.JSONNull
+		default:
+			if i, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+				val = jsonutils.NewInt(i)
+			} else if f, err := strconv.ParseFloat(tok.text, 64); err == nil {
+				val = jsonutils.NewFloat64(f)
+			} else {
+				return nil, errors.Wrapf(ErrInvalidFilter, "invalid value %s", tok.text)
+			}
+		}
+	}
+	return &sAttrFilter{Path: path, Op: op, Value: val}, nil
+}
+
+// trimSchema strips the schema URN prefix of a fully qualified attribute,
+// e.g. urn:ietf:params:scim:schemas:core:2.0:User:userName
+func trimSchema(attr string) string {
+	lower := strings.ToLower(attr)
+	for _, schema := range []string{SCHEMA_USER, SCHEMA_GROUP} {
+		prefix := strings.ToLower(schema) + ":"
+		if strings.HasPrefix(lower, prefix) {
+			return attr[len(prefix):]
+		}
+	}
+	return attr
+}
+
+func splitAttrPath(attr string) []string {
+	return strings.Split(trimSchema(attr), ".")
+}
+
+// getAttr returns the attribute of a complex value, attribute names are case-insensitive
+func getAttr(obj jsonutils.JSONObject, name string) jsonutils.JSONObject {
+	dict, ok := obj.(*jsonutils.JSONDict)
+	if !ok {
+		return nil
+	}
+	v, err := dict.GetIgnoreCases(name)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// getAttrValues returns the values of an attribute, multi-valued attribute is flattened
+func getAttrValues(obj jsonutils.JSONObject, name string) []jsonutils.JSONObject {
+	v := getAttr(obj, name)
+	if v == nil {
+		return nil
+	}
+	if arr, ok := v.(*jsonutils.JSONArray); ok {
+		ret, _ := arr.GetArray()
+		return ret
+	}
+	return []jsonutils.JSONObject{v}
+}
+
+// resolvePath returns the values at the attribute path, complex values
+// without sub-attribute are represented by their 'value' sub-attribute
+func resolvePath(obj jsonutils.JSONObject, path []string) []jsonutils.JSONObject {
+	values := []jsonutils.JSONObject{obj}
+	for _, name := range path {
+		next := make([]jsonutils.JSONObject, 0)
+		for _, v := range values {
+			next = append(next, getAttrValues(v, name)...)
+		}
+		values = next
+	}
+	for i := range values {
+		if sub := getAttr(values[i], "value"); sub != nil {
+			values[i] = sub
+		}
+	}
+	return values
+}
@@ -0,0 +1,529 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/appctx"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/keystone/driver"
+	"yunion.io/x/onecloud/pkg/keystone/models"
+)
+
+const (
+	API_PREFIX = "/scim/v2"
+
+	maxPayloadSize = 4 * 1024 * 1024
+)
+
+func AddHandler(app *appsrv.Application) {
+	prefix := API_PREFIX + "/<idp_id>"
+	app.AddHandler2("GET", prefix+"/ServiceProviderConfig", scimHandler(serviceProviderConfigHandler), nil, "scim_service_provider_config", nil)
+	app.AddHandler2("POST", prefix+"/Bulk", scimHandler(bulkHandler), nil, "scim_bulk", nil)
+	for _, resType := range []string{RESOURCE_TYPE_USER, RESOURCE_TYPE_GROUP} {
+		path := fmt.Sprintf("%s/%ss", prefix, resType)
+		name := "scim_" + strings.ToLower(resType)
+		app.AddHandler2("GET", path, scimHandler(resourceHandler), nil, name+"_list", nil)
+		app.AddHandler2("POST", path, scimHandler(resourceHandler), nil, name+"_create", nil)
+		app.AddHandler2("GET", path+"/<id>", scimHandler(resourceHandler), nil, name+"_get", nil)
+		app.AddHandler2("PUT", path+"/<id>", scimHandler(resourceHandler), nil, name+"_replace", nil)
+		app.AddHandler2("PATCH", path+"/<id>", scimHandler(resourceHandler), nil, name+"_patch", nil)
+		app.AddHandler2("DELETE", path+"/<id>", scimHandler(resourceHandler), nil, name+"_delete", nil)
+	}
+}
+
+// sRequest is a SCIM request, either from HTTP or an operation of bulk request
+type sRequest struct {
+	Method string
+	// resource type of the endpoint, User or Group
+	ResourceType string
+	Id           string
+	Query        jsonutils.JSONObject
+	Body         jsonutils.JSONObject
+}
+
+type sResponse struct {
+	Status   int
+	Location string
+	Body     jsonutils.JSONObject
+}
+
+type scimHandlerFunc func(ctx context.Context, p *sProvider, req *sRequest) (*sResponse, error)
+
+func scimHandler(f scimHandlerFunc) appsrv.FilterHandler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		params := appctx.AppContextParams(ctx)
+		p, err := authenticate(r, params["<idp_id>"])
+		if err != nil {
+			sendError(w, err)
+			return
+		}
+		req := &sRequest{
+			Method: r.Method,
+			Id:     params["<id>"],
+		}
+		for _, resType := range []string{RESOURCE_TYPE_USER, RESOURCE_TYPE_GROUP} {
+			if strings.Contains(r.URL.Path, "/"+resType+"s") {
+				req.ResourceType = resType
+			}
+		}
+		req.Query, err = jsonutils.ParseQueryString(r.URL.RawQuery)
+		if err != nil {
+			sendError(w, errors.Wrap(httperrors.ErrInputParameter, "invalid query string"))
+			return
+		}
+		if r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" {
+			req.Body, err = fetchBody(r)
+			if err != nil {
+				sendError(w, err)
+				return
+			}
+		}
+		resp, err := f(ctx, p, req)
+		if err != nil {
+			sendError(w, err)
+			return
+		}
+		sendResponse(w, resp)
+	}
+}
+
+func fetchBody(r *http.Request) (jsonutils.JSONObject, error) {
+	defer r.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, maxPayloadSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "read body")
+	}
+	if len(data) > maxPayloadSize {
+		return nil, errors.Wrapf(ErrTooMany, "payload exceeds %d bytes", maxPayloadSize)
+	}
+	body, err := jsonutils.Parse(data)
+	if err != nil {
+		return nil, errors.Wrapf(httperrors.ErrInvalidFormat, "invalid json: %v", err)
+	}
+	return body, nil
+}
+
+func sendResponse(w http.ResponseWriter, resp *sResponse) {
+	if len(resp.Location) > 0 {
+		w.Header().Set("Location", resp.Location)
+	}
+	if resp.Body == nil {
+		w.WriteHeader(resp.Status)
+		return
+	}
+	output := []byte(resp.Body.String())
+	w.Header().Set("Content-Type", CONTENT_TYPE)
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(output)))
+	w.WriteHeader(resp.Status)
+	w.Write(output)
+}
+
+func sendError(w http.ResponseWriter, err error) {
+	code, serr := NewError(err)
+	if code >= 500 {
+		log.Errorf("SCIM request fail: %s", err)
+	}
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+	}
+	sendResponse(w, &sResponse{Status: code, Body: jsonutils.Marshal(serr)})
+}
+
+// authenticate checks the bearer token against the one configured in the identity provider
+func authenticate(r *http.Request, idpId string) (*sProvider, error) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return nil, httperrors.NewUnauthorizedError("missing bearer token")
+	}
+	token := strings.TrimSpace(auth[7:])
+	idp, err := models.IdentityProviderManager.FetchIdentityProviderById(idpId)
+	if err != nil {
+		// do not tell the existence of identity provider to unauthenticated client
+		return nil, httperrors.NewUnauthorizedError("invalid bearer token")
+	}
+	if idp.Driver != api.IdentityDriverSCIM {
+		return nil, httperrors.NewUnauthorizedError("invalid bearer token")
+	}
+	conf, err := models.GetConfigs(idp, true, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetConfigs")
+	}
+	backend, err := driver.GetDriver(idp.Driver, idp.Id, idp.Name, idp.Template, idp.TargetDomainId, conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "driver.GetDriver")
+	}
+	drv := backend.(*SSCIMDriver)
+	if len(drv.scimConfig.BearerToken) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(drv.scimConfig.BearerToken)) != 1 {
+		return nil, httperrors.NewUnauthorizedError("invalid bearer token")
+	}
+	if idp.Enabled.IsFalse() {
+		return nil, errors.Wrap(httperrors.ErrInvalidStatus, "identity provider disabled")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	} else if proto := r.Header.Get("X-Forwarded-Proto"); len(proto) > 0 {
+		scheme = proto
+	}
+	return &sProvider{
+		idp:     idp,
+		conf:    drv.scimConfig,
+		baseUrl: fmt.Sprintf("%s://%s%s/%s", scheme, r.Host, API_PREFIX, idp.Id),
+	}, nil
+}
+
+func serviceProviderConfigHandler(ctx context.Context, p *sProvider, req *sRequest) (*sResponse, error) {
+	return &sResponse{Status: http.StatusOK, Body: jsonutils.MarshalAll(getServiceProviderConfig())}, nil
+}
+
+func resourceHandler(ctx context.Context, p *sProvider, req *sRequest) (*sResponse, error) {
+	if req.Method == "GET" && len(req.Id) == 0 {
+		return p.list(req.ResourceType, req.Query)
+	}
+	if req.Method == "POST" {
+		return p.create(ctx, req.ResourceType, req.Body)
+	}
+	if req.ResourceType == RESOURCE_TYPE_USER {
+		return p.handleUser(ctx, req)
+	}
+	return p.handleGroup(ctx, req)
+}
+
+func (p *sProvider) create(ctx context.Context, resType string, body jsonutils.JSONObject) (*sResponse, error) {
+	if body == nil {
+		return nil, errors.Wrap(ErrInvalidValue, "empty body")
+	}
+	var res *jsonutils.JSONDict
+	if resType == RESOURCE_TYPE_USER {
+		user, err := p.createUser(ctx, body)
+		if err != nil {
+			return nil, err
+		}
+		res = p.userToResource(user)
+	} else {
+		group, err := p.createGroup(ctx, body)
+		if err != nil {
+			return nil, err
+		}
+		res = p.groupToResource(group, true)
+	}
+	location, _ := res.GetString("meta", "location")
+	return &sResponse{Status: http.StatusCreated, Location: location, Body: res}, nil
+}
+
+func (p *sProvider) handleUser(ctx context.Context, req *sRequest) (*sResponse, error) {
+	user, err := p.fetchUser(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	switch req.Method {
+	case "GET":
+		res := p.userToResource(user)
+		return &sResponse{Status: http.StatusOK, Body: projectAttributes(res, req.Query)}, nil
+	case "DELETE":
+		err = p.deleteUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return &sResponse{Status: http.StatusNoContent}, nil
+	case "PUT":
+		if req.Body == nil {
+			return nil, errors.Wrap(ErrInvalidValue, "empty body")
+		}
+		err = p.replaceUser(ctx, user, req.Body)
+	case "PATCH":
+		res := p.userToResource(user)
+		err = applyPatchRequest(res, req.Body)
+		if err != nil {
+			return nil, err
+		}
+		err = p.replaceUser(ctx, user, res)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sResponse{Status: http.StatusOK, Body: p.userToResource(user)}, nil
+}
+
+func (p *sProvider) handleGroup(ctx context.Context, req *sRequest) (*sResponse, error) {
+	group, err := p.fetchGroup(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	switch req.Method {
+	case "GET":
+		res := p.groupToResource(group, !isExcluded(req.Query, "members"))
+		return &sResponse{Status: http.StatusOK, Body: projectAttributes(res, req.Query)}, nil
+	case "DELETE":
+		err = p.deleteGroup(ctx, group)
+		if err != nil {
+			return nil, err
+		}
+		return &sResponse{Status: http.StatusNoContent}, nil
+	case "PUT":
+		if req.Body == nil {
+			return nil, errors.Wrap(ErrInvalidValue, "empty body")
+		}
+		err = p.replaceGroup(ctx, group, req.Body)
+	case "PATCH":
+		res := p.groupToResource(group, true)
+		err = applyPatchRequest(res, req.Body)
+		if err != nil {
+			return nil, err
+		}
+		err = p.replaceGroup(ctx, group, res)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// members could be large, return them only on request
+	withMembers := utils.IsInStringArray("members", attributeList(req.Query, "attributes"))
+	return &sResponse{Status: http.StatusOK, Body: p.groupToResource(group, withMembers)}, nil
+}
+
+func applyPatchRequest(res *jsonutils.JSONDict, body jsonutils.JSONObject) error {
+	if body == nil {
+		return errors.Wrap(ErrInvalidValue, "empty body")
+	}
+	patch := SPatchRequest{}
+	err := body.Unmarshal(&patch)
+	if err != nil {
+		return errors.Wrapf(ErrInvalidValue, "invalid patch request: %v", err)
+	}
+	if len(patch.Operations) == 0 {
+		return errors.Wrap(ErrInvalidValue, "empty Operations")
+	}
+	return ApplyPatch(res, patch.Operations)
+}
+
+func attributeList(query jsonutils.JSONObject, key string) []string {
+	str, _ := query.GetString(key)
+	ret := make([]string, 0)
+	for _, attr := range strings.Split(str, ",") {
+		attr = strings.TrimSpace(trimSchema(strings.TrimSpace(attr)))
+		if len(attr) > 0 {
+			ret = append(ret, strings.ToLower(strings.SplitN(attr, ".", 2)[0]))
+		}
+	}
+	return ret
+}
+
+func isExcluded(query jsonutils.JSONObject, attr string) bool {
+	attr = strings.ToLower(attr)
+	if utils.IsInStringArray(attr, attributeList(query, "excludedAttributes")) {
+		return true
+	}
+	attrs := attributeList(query, "attributes")
+	return len(attrs) > 0 && !utils.IsInStringArray(attr, attrs)
+}
+
+// projectAttributes keeps the attributes requested by 'attributes' or
+// 'excludedAttributes' query, id and schemas are always returned
+func projectAttributes(res *jsonutils.JSONDict, query jsonutils.JSONObject) *jsonutils.JSONDict {
+	ret := jsonutils.NewDict()
+	for k, v := range res.Value() {
+		if k == "id" || k == "schemas" || !isExcluded(query, k) {
+			ret.Set(k, v)
+		}
+	}
+	return ret
+}
+
+func (p *sProvider) list(resType string, query jsonutils.JSONObject) (*sResponse, error) {
+	var filter IFilter
+	if filterStr, _ := query.GetString("filter"); len(filterStr) > 0 {
+		var err error
+		filter, err = ParseFilter(filterStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	startIndex, _ := query.Int("startIndex")
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count := int64(DEFAULT_PAGE_SIZE)
+	if query.Contains("count") {
+		count, _ = query.Int("count")
+	}
+	if count < 0 {
+		count = 0
+	} else if count > MAX_PAGE_SIZE {
+		count = MAX_PAGE_SIZE
+	}
+
+	// the common lookup of SCIM clients, e.g. userName eq "alice", is done by database
+	var nameEq string
+	if af, ok := filter.(*sAttrFilter); ok && af.Op == filterOpEq && len(af.Path) == 1 {
+		nameAttr := "userName"
+		if resType == RESOURCE_TYPE_GROUP {
+			nameAttr = "displayName"
+		}
+		if strings.EqualFold(af.Path[0], nameAttr) {
+			nameEq, _ = af.Value.GetString()
+		}
+	}
+
+	resources := make([]*jsonutils.JSONDict, 0)
+	if resType == RESOURCE_TYPE_USER {
+		users, err := p.listUsers(nameEq)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			resources = append(resources, p.userToResource(&users[i]))
+		}
+	} else {
+		groups, err := p.listGroups(nameEq)
+		if err != nil {
+			return nil, err
+		}
+		withMembers := filter != nil || !isExcluded(query, "members")
+		for i := range groups {
+			resources = append(resources, p.groupToResource(&groups[i], withMembers))
+		}
+	}
+
+	matched := make([]jsonutils.JSONObject, 0)
+	for _, res := range resources {
+		if filter == nil || filter.Match(res) {
+			matched = append(matched, res)
+		}
+	}
+	ret := SListResponse{
+		Schemas:      []string{SCHEMA_LIST_RESPONSE},
+		TotalResults: len(matched),
+		StartIndex:   int(startIndex),
+		Resources:    []jsonutils.JSONObject{},
+	}
+	for i := int(startIndex) - 1; i < len(matched) && len(ret.Resources) < int(count); i++ {
+		ret.Resources = append(ret.Resources, projectAttributes(matched[i].(*jsonutils.JSONDict), query))
+	}
+	ret.ItemsPerPage = len(ret.Resources)
+	return &sResponse{Status: http.StatusOK, Body: jsonutils.MarshalAll(ret)}, nil
+}
+
+// bulkHandler processes the operations in order, 'bulkId:<id>' in the data
+// of later operations is replaced by the id of the resource created by
+// the operation with the bulkId, RFC 7644 section 3.7
+func bulkHandler(ctx context.Context, p *sProvider, req *sRequest) (*sResponse, error) {
+	if req.Body == nil {
+		return nil, errors.Wrap(ErrInvalidValue, "empty body")
+	}
+	bulk := SBulkRequest{}
+	err := req.Body.Unmarshal(&bulk)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidValue, "invalid bulk request: %v", err)
+	}
+	if len(bulk.Operations) > MAX_BULK_OPS {
+		return nil, errors.Wrapf(ErrTooMany, "at most %d operations", MAX_BULK_OPS)
+	}
+	bulkIds := make(map[string]string)
+	ret := SBulkResponse{
+		Schemas:    []string{SCHEMA_BULK_RESPONSE},
+		Operations: []SBulkOperationResult{},
+	}
+	errCnt := 0
+	for _, op := range bulk.Operations {
+		if bulk.FailOnErrors > 0 && errCnt >= bulk.FailOnErrors {
+			break
+		}
+		result := SBulkOperationResult{
+			Method: op.Method,
+			BulkId: op.BulkId,
+		}
+		resp, err := p.bulkOperation(ctx, op, bulkIds)
+		if err != nil {
+			errCnt++
+			code, serr := NewError(err)
+			result.Status = fmt.Sprintf("%d", code)
+			result.Response = jsonutils.Marshal(serr)
+		} else {
+			result.Status = fmt.Sprintf("%d", resp.Status)
+			result.Location = resp.Location
+			if resp.Body != nil {
+				location, _ := resp.Body.GetString("meta", "location")
+				result.Location = location
+				if len(op.BulkId) > 0 {
+					bulkIds[op.BulkId], _ = resp.Body.GetString("id")
+				}
+			}
+		}
+		ret.Operations = append(ret.Operations, result)
+	}
+	return &sResponse{Status: http.StatusOK, Body: jsonutils.Marshal(ret)}, nil
+}
+
+func (p *sProvider) bulkOperation(ctx context.Context, op SBulkOperation, bulkIds map[string]string) (*sResponse, error) {
+	method := strings.ToUpper(op.Method)
+	if !utils.IsInStringArray(method, []string{"POST", "PUT", "PATCH", "DELETE"}) {
+		return nil, errors.Wrapf(ErrInvalidValue, "unsupported method %s", op.Method)
+	}
+	if method == "POST" && len(op.BulkId) == 0 {
+		return nil, errors.Wrap(ErrInvalidValue, "bulkId is required for POST")
+	}
+	path := op.Path
+	data := op.Data
+	for bulkId, id := range bulkIds {
+		ref := "bulkId:" + bulkId
+		path = strings.ReplaceAll(path, ref, id)
+		if data != nil && strings.Contains(data.String(), ref) {
+			var err error
+			data, err = jsonutils.ParseString(strings.ReplaceAll(data.String(), ref, id))
+			if err != nil {
+				return nil, errors.Wrapf(ErrInvalidValue, "replace %s: %v", ref, err)
+			}
+		}
+	}
+	if strings.Contains(path, "bulkId:") || (data != nil && strings.Contains(data.String(), "bulkId:")) {
+		return nil, errors.Wrap(ErrInvalidValue, "unresolved bulkId reference")
+	}
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	req := &sRequest{
+		Method: method,
+		Query:  jsonutils.NewDict(),
+		Body:   data,
+	}
+	switch segs[0] {
+	case "Users":
+		req.ResourceType = RESOURCE_TYPE_USER
+	case "Groups":
+		req.ResourceType = RESOURCE_TYPE_GROUP
+	default:
+		return nil, errors.Wrapf(ErrInvalidPath, "invalid path %s", op.Path)
+	}
+	if len(segs) > 2 || (len(segs) == 1) != (method == "POST") {
+		return nil, errors.Wrapf(ErrInvalidPath, "invalid path %s for %s", op.Path, method)
+	}
+	if len(segs) == 2 {
+		req.Id = segs[1]
+	}
+	return resourceHandler(ctx, p, req)
+}
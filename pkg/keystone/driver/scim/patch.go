@@ -0,0 +1,313 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	PATCH_OP_ADD     = "add"
+	PATCH_OP_REMOVE  = "remove"
+	PATCH_OP_REPLACE = "replace"
+)
+
+// sPatchPath is the parsed path of a patch operation, in the form of
+// attr[.subAttr] or attr[valueFilter][.subAttr], RFC 7644 section 3.5.2
+type sPatchPath struct {
+	Attr    string
+	Filter  IFilter
+	SubAttr string
+}
+
+func isExtensionAttr(path string) bool {
+	path = trimSchema(strings.TrimSpace(path))
+	return strings.HasPrefix(strings.ToLower(path), "urn:")
+}
+
+func parsePatchPath(path string) (*sPatchPath, error) {
+	path = trimSchema(strings.TrimSpace(path))
+	ret := &sPatchPath{}
+	if pos := strings.Index(path, "["); pos >= 0 {
+		end := strings.LastIndex(path, "]")
+		if end < pos {
+			return nil, errors.Wrapf(ErrInvalidPath, "unbalanced bracket in %s", path)
+		}
+		filter, err := ParseFilter(path[pos+1 : end])
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidPath, "%s: %v", path, err)
+		}
+		ret.Attr = path[:pos]
+		ret.Filter = filter
+		rest := path[end+1:]
+		if len(rest) > 0 {
+			if rest[0] != '.' || len(rest) == 1 {
+				return nil, errors.Wrapf(ErrInvalidPath, "invalid sub-attribute in %s", path)
+			}
+			ret.SubAttr = rest[1:]
+		}
+	} else {
+		parts := strings.SplitN(path, ".", 2)
+		ret.Attr = parts[0]
+		if len(parts) == 2 {
+			ret.SubAttr = parts[1]
+		}
+	}
+	if len(ret.Attr) == 0 || strings.ContainsAny(ret.Attr, " ()[]\"") {
+		return nil, errors.Wrapf(ErrInvalidPath, "invalid attribute in %s", path)
+	}
+	return ret, nil
+}
+
+// setAttr sets the attribute of a complex value, replacing the existing
+// one regardless of its case
+func setAttr(dict *jsonutils.JSONDict, name string, value jsonutils.JSONObject) {
+	dict.RemoveIgnoreCase(name)
+	dict.Set(name, value)
+}
+
+// ApplyPatch applies the patch operations to the SCIM resource in place
+func ApplyPatch(resource *jsonutils.JSONDict, ops []SPatchOperation) error {
+	for i := range ops {
+		err := applyPatchOperation(resource, ops[i])
+		if err != nil {
+			return errors.Wrapf(err, "operation %d", i)
+		}
+	}
+	return nil
+}
+
+func applyPatchOperation(resource *jsonutils.JSONDict, op SPatchOperation) error {
+	opName := strings.ToLower(op.Op)
+	switch opName {
+	case PATCH_OP_ADD, PATCH_OP_REPLACE, PATCH_OP_REMOVE:
+	default:
+		return errors.Wrapf(ErrInvalidValue, "unsupported op %q", op.Op)
+	}
+	if len(op.Path) == 0 {
+		if opName == PATCH_OP_REMOVE {
+			return errors.Wrap(ErrNoTarget, "path is required for remove")
+		}
+		// the value is a complex value of the attributes to add or replace
+		values, ok := op.Value.(*jsonutils.JSONDict)
+		if !ok {
+			return errors.Wrap(ErrInvalidValue, "value should be an object without path")
+		}
+		for k, v := range values.Value() {
+			err := applyPatchOperation(resource, SPatchOperation{Op: opName, Path: k, Value: v})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if isExtensionAttr(op.Path) {
+		// attributes of schema extensions, e.g. the enterprise user, are not supported and ignored
+		return nil
+	}
+	path, err := parsePatchPath(op.Path)
+	if err != nil {
+		return err
+	}
+	if opName != PATCH_OP_REMOVE && op.Value == nil {
+		return errors.Wrapf(ErrInvalidValue, "missing value for %s", op.Path)
+	}
+	if path.Filter != nil {
+		return patchFiltered(resource, opName, path, op.Value)
+	}
+	if len(path.SubAttr) > 0 {
+		return patchSubAttr(resource, opName, path, op.Value)
+	}
+	current := getAttr(resource, path.Attr)
+	switch opName {
+	case PATCH_OP_ADD:
+		if arr, ok := current.(*jsonutils.JSONArray); ok {
+			// add to multi-valued attribute, skip the existing values
+			for _, v := range valueList(op.Value) {
+				if indexOfValue(arr, v) < 0 {
+					arr.Add(v)
+				}
+			}
+			return nil
+		}
+		if cur, ok := current.(*jsonutils.JSONDict); ok {
+			if val, ok := op.Value.(*jsonutils.JSONDict); ok {
+				cur.Update(val)
+				return nil
+			}
+		}
+		setAttr(resource, path.Attr, op.Value)
+	case PATCH_OP_REPLACE:
+		setAttr(resource, path.Attr, op.Value)
+	case PATCH_OP_REMOVE:
+		arr, ok := current.(*jsonutils.JSONArray)
+		if ok && op.Value != nil {
+			// remove the given values only, e.g. members in value [{"value": "<id>"}]
+			for _, v := range valueList(op.Value) {
+				if idx := indexOfValue(arr, v); idx >= 0 {
+					setAttr(resource, path.Attr, removeAt(arr, idx))
+					arr = getAttr(resource, path.Attr).(*jsonutils.JSONArray)
+				}
+			}
+			return nil
+		}
+		resource.RemoveIgnoreCase(path.Attr)
+	}
+	return nil
+}
+
+func patchSubAttr(resource *jsonutils.JSONDict, opName string, path *sPatchPath, value jsonutils.JSONObject) error {
+	current := getAttr(resource, path.Attr)
+	switch cur := current.(type) {
+	case *jsonutils.JSONDict:
+		if opName == PATCH_OP_REMOVE {
+			cur.RemoveIgnoreCase(path.SubAttr)
+		} else {
+			setAttr(cur, path.SubAttr, value)
+		}
+	case *jsonutils.JSONArray:
+		elems, _ := cur.GetArray()
+		for _, elem := range elems {
+			if dict, ok := elem.(*jsonutils.JSONDict); ok {
+				if opName == PATCH_OP_REMOVE {
+					dict.RemoveIgnoreCase(path.SubAttr)
+				} else {
+					setAttr(dict, path.SubAttr, value)
+				}
+			}
+		}
+	case nil:
+		if opName == PATCH_OP_REMOVE {
+			return nil
+		}
+		dict := jsonutils.NewDict()
+		dict.Set(path.SubAttr, value)
+		resource.Set(path.Attr, dict)
+	default:
+		return errors.Wrapf(ErrInvalidPath, "%s is not a complex attribute", path.Attr)
+	}
+	return nil
+}
+
+func patchFiltered(resource *jsonutils.JSONDict, opName string, path *sPatchPath, value jsonutils.JSONObject) error {
+	current := getAttr(resource, path.Attr)
+	arr, ok := current.(*jsonutils.JSONArray)
+	if !ok && current != nil {
+		return errors.Wrapf(ErrInvalidPath, "%s is not multi-valued", path.Attr)
+	}
+	if arr == nil {
+		arr = jsonutils.NewArray()
+	}
+	elems, _ := arr.GetArray()
+	nelems := make([]jsonutils.JSONObject, 0, len(elems))
+	matched := 0
+	for _, elem := range elems {
+		if !path.Filter.Match(elem) {
+			nelems = append(nelems, elem)
+			continue
+		}
+		matched++
+		switch {
+		case opName == PATCH_OP_REMOVE && len(path.SubAttr) == 0:
+			continue
+		case opName == PATCH_OP_REMOVE:
+			if dict, ok := elem.(*jsonutils.JSONDict); ok {
+				dict.RemoveIgnoreCase(path.SubAttr)
+			}
+		case len(path.SubAttr) > 0:
+			if dict, ok := elem.(*jsonutils.JSONDict); ok {
+				setAttr(dict, path.SubAttr, value)
+			}
+		case opName == PATCH_OP_REPLACE:
+			elem = value
+		default:
+			if dict, ok := elem.(*jsonutils.JSONDict); ok {
+				if val, ok := value.(*jsonutils.JSONDict); ok {
+					dict.Update(val)
+				}
+			}
+		}
+		nelems = append(nelems, elem)
+	}
+	if matched == 0 {
+		if opName == PATCH_OP_REMOVE {
+			return nil
+		}
+		// add the element identified by the filter, e.g. 'emails[type eq "work"].value'
+		elem, err := newElementFromFilter(path, value)
+		if err != nil {
+			return err
+		}
+		nelems = append(nelems, elem)
+	}
+	setAttr(resource, path.Attr, jsonutils.NewArray(nelems...))
+	return nil
+}
+
+func newElementFromFilter(path *sPatchPath, value jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	filter, ok := path.Filter.(*sAttrFilter)
+	if !ok || filter.Op != filterOpEq || len(filter.Path) != 1 {
+		return nil, errors.Wrapf(ErrNoTarget, "no value of %s matches the filter", path.Attr)
+	}
+	elem := jsonutils.NewDict()
+	if dict, ok := value.(*jsonutils.JSONDict); ok && len(path.SubAttr) == 0 {
+		elem.Update(dict)
+	} else if len(path.SubAttr) > 0 {
+		elem.Set(path.SubAttr, value)
+	} else {
+		return nil, errors.Wrapf(ErrInvalidValue, "value of %s should be an object", path.Attr)
+	}
+	setAttr(elem, filter.Path[0], filter.Value)
+	return elem, nil
+}
+
+func valueList(value jsonutils.JSONObject) []jsonutils.JSONObject {
+	if arr, ok := value.(*jsonutils.JSONArray); ok {
+		ret, _ := arr.GetArray()
+		return ret
+	}
+	return []jsonutils.JSONObject{value}
+}
+
+// indexOfValue finds the element of multi-valued attribute, complex values
+// are identified by their 'value' sub-attribute
+func indexOfValue(arr *jsonutils.JSONArray, v jsonutils.JSONObject) int {
+	key := v
+	if sub := getAttr(v, "value"); sub != nil {
+		key = sub
+	}
+	elems, _ := arr.GetArray()
+	for i, elem := range elems {
+		ekey := elem
+		if sub := getAttr(elem, "value"); sub != nil {
+			ekey = sub
+		}
+		if ekey.String() == key.String() {
+			return i
+		}
+	}
+	return -1
+}
+
+func removeAt(arr *jsonutils.JSONArray, idx int) *jsonutils.JSONArray {
+	elems, _ := arr.GetArray()
+	nelems := make([]jsonutils.JSONObject, 0, len(elems))
+	nelems = append(nelems, elems[:idx]...)
+	nelems = append(nelems, elems[idx+1:]...)
+	return jsonutils.NewArray(nelems...)
+}
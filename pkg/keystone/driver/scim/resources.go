@@ -0,0 +1,498 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/pkg/util/timeutils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/keystone/models"
+)
+
+// sProvider provisions the SCIM resources into an identity provider
+type sProvider struct {
+	idp  *models.SIdentityProvider
+	conf *api.SSCIMIdpConfigOptions
+	// base url of the SCIM endpoint, e.g. https://keystone:30357/scim/v2/<idp_id>
+	baseUrl string
+}
+
+type sUserAttributes struct {
+	ExternalId  string
+	UserName    string
+	Displayname string
+	Email       string
+	Mobile      string
+	Active      tristate.TriState
+}
+
+type sGroupAttributes struct {
+	ExternalId  string
+	Displayname string
+	MemberIds   []string
+}
+
+func getString(obj jsonutils.JSONObject, path ...string) string {
+	for _, name := range path {
+		obj = getAttr(obj, name)
+		if obj == nil {
+			return ""
+		}
+	}
+	s, _ := obj.GetString()
+	return strings.TrimSpace(s)
+}
+
+// getPrimaryValue returns the value of the primary or the first element of multi-valued attribute
+func getPrimaryValue(obj jsonutils.JSONObject, name string) string {
+	values := getAttrValues(obj, name)
+	for _, v := range values {
+		if primary, err := parseBool(getAttr(v, "primary")); err == nil && primary {
+			return getString(v, "value")
+		}
+	}
+	if len(values) > 0 {
+		return getString(values[0], "value")
+	}
+	return ""
+}
+
+func parseUserAttributes(res jsonutils.JSONObject) (*sUserAttributes, error) {
+	attrs := &sUserAttributes{
+		ExternalId:  getString(res, "externalId"),
+		UserName:    getString(res, "userName"),
+		Displayname: getString(res, "displayName"),
+		Email:       getPrimaryValue(res, "emails"),
+		Mobile:      getPrimaryValue(res, "phoneNumbers"),
+	}
+	if len(attrs.UserName) == 0 {
+		return nil, errors.Wrap(ErrInvalidValue, "userName is required")
+	}
+	if len(attrs.Displayname) == 0 {
+		attrs.Displayname = getString(res, "name", "formatted")
+	}
+	if len(attrs.Displayname) == 0 {
+		attrs.Displayname = strings.TrimSpace(fmt.Sprintf("%s %s", getString(res, "name", "givenName"), getString(res, "name", "familyName")))
+	}
+	if active := getAttr(res, "active"); active != nil {
+		enabled, err := parseBool(active)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidValue, "invalid active %s", active)
+		}
+		attrs.Active = tristate.NewFromBool(enabled)
+	}
+	return attrs, nil
+}
+
+func parseGroupAttributes(res jsonutils.JSONObject) (*sGroupAttributes, error) {
+	attrs := &sGroupAttributes{
+		ExternalId:  getString(res, "externalId"),
+		Displayname: getString(res, "displayName"),
+	}
+	if len(attrs.Displayname) == 0 {
+		return nil, errors.Wrap(ErrInvalidValue, "displayName is required")
+	}
+	for _, member := range getAttrValues(res, "members") {
+		id := getString(member, "value")
+		if len(id) > 0 {
+			attrs.MemberIds = append(attrs.MemberIds, id)
+		}
+	}
+	return attrs, nil
+}
+
+func (p *sProvider) getDomain(ctx context.Context) (*models.SDomain, error) {
+	domainName := p.conf.DomainName
+	if len(domainName) == 0 {
+		domainName = p.idp.Name
+	}
+	desc := fmt.Sprintf("%s provider %s", p.idp.Driver, p.idp.Name)
+	return p.idp.GetSingleDomain(ctx, api.DefaultRemoteDomainId, domainName, desc, true)
+}
+
+func (p *sProvider) getExternalId(publicId string, entityType string) string {
+	idmaps, err := models.IdmappingManager.FetchEntities(publicId, entityType)
+	if err != nil {
+		return ""
+	}
+	for i := range idmaps {
+		if idmaps[i].IdpId == p.idp.Id {
+			return idmaps[i].IdpEntityId
+		}
+	}
+	return ""
+}
+
+func (p *sProvider) linkedQuery(manager db.IModelManager, entityType string) *sqlchemy.SQuery {
+	idq := models.IdmappingManager.FetchPublicIdsExcludesQuery(p.idp.Id, entityType, nil)
+	return manager.Query().In("id", idq.SubQuery())
+}
+
+func (p *sProvider) meta(resourceType string, model db.IModel, createdAt, updatedAt string) *jsonutils.JSONDict {
+	meta := jsonutils.NewDict()
+	meta.Set("resourceType", jsonutils.NewString(resourceType))
+	meta.Set("created", jsonutils.NewString(createdAt))
+	meta.Set("lastModified", jsonutils.NewString(updatedAt))
+	meta.Set("location", jsonutils.NewString(fmt.Sprintf("%s/%ss/%s", p.baseUrl, resourceType, model.GetId())))
+	return meta
+}
+
+func (p *sProvider) userToResource(user *models.SUser) *jsonutils.JSONDict {
+	res := jsonutils.NewDict()
+	res.Set("schemas", jsonutils.NewStringArray([]string{SCHEMA_USER}))
+	res.Set("id", jsonutils.NewString(user.Id))
+	if extId := p.getExternalId(user.Id, api.IdMappingEntityUser); len(extId) > 0 {
+		res.Set("externalId", jsonutils.NewString(extId))
+	}
+	res.Set("userName", jsonutils.NewString(user.Name))
+	if len(user.Displayname) > 0 {
+		res.Set("displayName", jsonutils.NewString(user.Displayname))
+		name := jsonutils.NewDict()
+		name.Set("formatted", jsonutils.NewString(user.Displayname))
+		res.Set("name", name)
+	}
+	res.Set("active", jsonutils.NewBool(user.Enabled.Bool()))
+	for key, val := range map[string]string{"emails": user.Email, "phoneNumbers": user.Mobile} {
+		if len(val) == 0 {
+			continue
+		}
+		elem := jsonutils.NewDict()
+		elem.Set("value", jsonutils.NewString(val))
+		elem.Set("type", jsonutils.NewString("work"))
+		elem.Set("primary", jsonutils.JSONTrue)
+		res.Set(key, jsonutils.NewArray(elem))
+	}
+	groups := jsonutils.NewArray()
+	for _, group := range p.getUserGroups(user.Id) {
+		elem := jsonutils.NewDict()
+		elem.Set("value", jsonutils.NewString(group.Id))
+		elem.Set("display", jsonutils.NewString(group.Name))
+		elem.Set("$ref", jsonutils.NewString(fmt.Sprintf("%s/Groups/%s", p.baseUrl, group.Id)))
+		groups.Add(elem)
+	}
+	res.Set("groups", groups)
+	res.Set("meta", p.meta(RESOURCE_TYPE_USER, user, timeutils.IsoTime(user.CreatedAt), timeutils.IsoTime(user.UpdatedAt)))
+	return res
+}
+
+func (p *sProvider) groupToResource(group *models.SGroup, withMembers bool) *jsonutils.JSONDict {
+	res := jsonutils.NewDict()
+	res.Set("schemas", jsonutils.NewStringArray([]string{SCHEMA_GROUP}))
+	res.Set("id", jsonutils.NewString(group.Id))
+	if extId := p.getExternalId(group.Id, api.IdMappingEntityGroup); len(extId) > 0 {
+		res.Set("externalId", jsonutils.NewString(extId))
+	}
+	res.Set("displayName", jsonutils.NewString(group.Name))
+	if withMembers {
+		members := jsonutils.NewArray()
+		for _, user := range p.getGroupUsers(group.Id) {
+			elem := jsonutils.NewDict()
+			elem.Set("value", jsonutils.NewString(user.Id))
+			elem.Set("display", jsonutils.NewString(user.Name))
+			elem.Set("type", jsonutils.NewString(RESOURCE_TYPE_USER))
+			elem.Set("$ref", jsonutils.NewString(fmt.Sprintf("%s/Users/%s", p.baseUrl, user.Id)))
+			members.Add(elem)
+		}
+		res.Set("members", members)
+	}
+	res.Set("meta", p.meta(RESOURCE_TYPE_GROUP, group, timeutils.IsoTime(group.CreatedAt), timeutils.IsoTime(group.UpdatedAt)))
+	return res
+}
+
+func (p *sProvider) getUserGroups(userId string) []models.SGroup {
+	q := p.linkedQuery(models.GroupManager, api.IdMappingEntityGroup)
+	q = q.In("id", models.UsergroupManager.Query("group_id").Equals("user_id", userId).SubQuery())
+	groups := make([]models.SGroup, 0)
+	err := db.FetchModelObjects(models.GroupManager, q, &groups)
+	if err != nil {
+		return nil
+	}
+	return groups
+}
+
+func (p *sProvider) getGroupUsers(groupId string) []models.SUser {
+	// only the members provisioned by the identity provider
+	q := p.linkedQuery(models.UserManager, api.IdMappingEntityUser)
+	q = q.In("id", models.UsergroupManager.Query("user_id").Equals("group_id", groupId).SubQuery())
+	users := make([]models.SUser, 0)
+	err := db.FetchModelObjects(models.UserManager, q, &users)
+	if err != nil {
+		return nil
+	}
+	return users
+}
+
+func (p *sProvider) listUsers(userName string) ([]models.SUser, error) {
+	q := p.linkedQuery(models.UserManager, api.IdMappingEntityUser)
+	if len(userName) > 0 {
+		q = q.Equals("name", userName)
+	}
+	users := make([]models.SUser, 0)
+	err := db.FetchModelObjects(models.UserManager, q.Asc("created_at"), &users)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return users, nil
+}
+
+func (p *sProvider) listGroups(displayName string) ([]models.SGroup, error) {
+	q := p.linkedQuery(models.GroupManager, api.IdMappingEntityGroup)
+	if len(displayName) > 0 {
+		q = q.Equals("name", displayName)
+	}
+	groups := make([]models.SGroup, 0)
+	err := db.FetchModelObjects(models.GroupManager, q.Asc("created_at"), &groups)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return groups, nil
+}
+
+func (p *sProvider) fetchUser(id string) (*models.SUser, error) {
+	obj, err := models.UserManager.FetchById(id)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(models.UserManager.Keyword(), id)
+		}
+		return nil, errors.Wrap(err, "FetchById")
+	}
+	user := obj.(*models.SUser)
+	if !user.LinkedWithIdp(p.idp.Id) {
+		return nil, httperrors.NewResourceNotFoundError2(models.UserManager.Keyword(), id)
+	}
+	return user, nil
+}
+
+func (p *sProvider) fetchGroup(id string) (*models.SGroup, error) {
+	obj, err := models.GroupManager.FetchById(id)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(models.GroupManager.Keyword(), id)
+		}
+		return nil, errors.Wrap(err, "FetchById")
+	}
+	group := obj.(*models.SGroup)
+	if !group.LinkedWithIdp(p.idp.Id) {
+		return nil, httperrors.NewResourceNotFoundError2(models.GroupManager.Keyword(), id)
+	}
+	return group, nil
+}
+
+// checkNameUniqueness makes sure the name is not taken by other users or
+// groups in the domain, instead of generating an alternative name silently
+func checkNameUniqueness(manager db.IModelManager, domainId, name, selfId string) error {
+	q := manager.Query().Equals("domain_id", domainId).Equals("name", name)
+	if len(selfId) > 0 {
+		q = q.NotEquals("id", selfId)
+	}
+	cnt, err := q.CountWithError()
+	if err != nil {
+		return errors.Wrap(err, "CountWithError")
+	}
+	if cnt > 0 {
+		return errors.Wrapf(ErrUniqueness, "%s %s already exists", manager.Keyword(), name)
+	}
+	return nil
+}
+
+func (p *sProvider) createUser(ctx context.Context, res jsonutils.JSONObject) (*models.SUser, error) {
+	attrs, err := parseUserAttributes(res)
+	if err != nil {
+		return nil, err
+	}
+	extId := attrs.ExternalId
+	if len(extId) == 0 {
+		extId = attrs.UserName
+	}
+	_, err = models.IdmappingManager.FetchByIdpAndEntityId(ctx, p.idp.Id, extId, api.IdMappingEntityUser)
+	if err == nil {
+		return nil, errors.Wrapf(ErrUniqueness, "user %s already exists", extId)
+	} else if errors.Cause(err) != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "FetchByIdpAndEntityId")
+	}
+	domain, err := p.getDomain(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "getDomain")
+	}
+	err = checkNameUniqueness(models.UserManager, domain.Id, attrs.UserName, "")
+	if err != nil {
+		return nil, err
+	}
+	user, err := p.idp.SyncOrCreateUser(ctx, extId, attrs.UserName, domain.Id, !attrs.Active.IsFalse(), func(user *models.SUser) {
+		user.Displayname = attrs.Displayname
+		user.Email = attrs.Email
+		user.Mobile = attrs.Mobile
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "SyncOrCreateUser")
+	}
+	db.OpsLog.LogEvent(user, db.ACT_CREATE, user.GetShortDesc(ctx), models.GetDefaultAdminCred())
+	return user, nil
+}
+
+func (p *sProvider) replaceUser(ctx context.Context, user *models.SUser, res jsonutils.JSONObject) error {
+	attrs, err := parseUserAttributes(res)
+	if err != nil {
+		return err
+	}
+	if attrs.UserName != user.Name {
+		err = checkNameUniqueness(models.UserManager, user.DomainId, attrs.UserName, user.Id)
+		if err != nil {
+			return err
+		}
+	}
+	diff, err := db.Update(user, func() error {
+		user.Name = attrs.UserName
+		user.Displayname = attrs.Displayname
+		user.Email = attrs.Email
+		user.Mobile = attrs.Mobile
+		if !attrs.Active.IsNone() {
+			user.Enabled = attrs.Active
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Update")
+	}
+	db.OpsLog.LogEvent(user, db.ACT_UPDATE, diff, models.GetDefaultAdminCred())
+	return nil
+}
+
+// deleteUser deletes the user as the LDAP sync does to the removed entries
+func (p *sProvider) deleteUser(ctx context.Context, user *models.SUser) error {
+	// validate before unlink, the user is left intact if it can't be deleted
+	err := user.ValidateUnlinkDeleteCondition(ctx, p.idp.Id)
+	if err != nil {
+		return errors.Wrap(err, "ValidateUnlinkDeleteCondition")
+	}
+	err = user.UnlinkIdp(p.idp.Id)
+	if err != nil {
+		return errors.Wrap(err, "UnlinkIdp")
+	}
+	return user.Delete(ctx, models.GetDefaultAdminCred())
+}
+
+// resolveMembers makes sure the members are users provisioned by the identity provider
+func (p *sProvider) resolveMembers(memberIds []string) ([]string, error) {
+	if len(memberIds) == 0 {
+		return []string{}, nil
+	}
+	q := p.linkedQuery(models.UserManager, api.IdMappingEntityUser).In("id", memberIds)
+	users := make([]models.SUser, 0)
+	err := db.FetchModelObjects(models.UserManager, q, &users)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	ret := make([]string, 0, len(users))
+	found := make(map[string]bool)
+	for i := range users {
+		ret = append(ret, users[i].Id)
+		found[users[i].Id] = true
+	}
+	for _, id := range memberIds {
+		if !found[id] {
+			return nil, errors.Wrapf(ErrInvalidValue, "member %s not found", id)
+		}
+	}
+	return ret, nil
+}
+
+func (p *sProvider) createGroup(ctx context.Context, res jsonutils.JSONObject) (*models.SGroup, error) {
+	attrs, err := parseGroupAttributes(res)
+	if err != nil {
+		return nil, err
+	}
+	extId := attrs.ExternalId
+	if len(extId) == 0 {
+		extId = attrs.Displayname
+	}
+	_, err = models.IdmappingManager.FetchByIdpAndEntityId(ctx, p.idp.Id, extId, api.IdMappingEntityGroup)
+	if err == nil {
+		return nil, errors.Wrapf(ErrUniqueness, "group %s already exists", extId)
+	} else if errors.Cause(err) != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "FetchByIdpAndEntityId")
+	}
+	memberIds, err := p.resolveMembers(attrs.MemberIds)
+	if err != nil {
+		return nil, err
+	}
+	domain, err := p.getDomain(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "getDomain")
+	}
+	err = checkNameUniqueness(models.GroupManager, domain.Id, attrs.Displayname, "")
+	if err != nil {
+		return nil, err
+	}
+	group, err := models.GroupManager.RegisterExternalGroup(ctx, p.idp.Id, domain.Id, extId, attrs.Displayname)
+	if err != nil {
+		return nil, errors.Wrap(err, "RegisterExternalGroup")
+	}
+	db.OpsLog.LogEvent(group, db.ACT_CREATE, group.GetShortDesc(ctx), models.GetDefaultAdminCred())
+	models.UsergroupManager.SyncGroupUsers(ctx, models.GetDefaultAdminCred(), group.Id, memberIds)
+	return group, nil
+}
+
+func (p *sProvider) replaceGroup(ctx context.Context, group *models.SGroup, res jsonutils.JSONObject) error {
+	attrs, err := parseGroupAttributes(res)
+	if err != nil {
+		return err
+	}
+	memberIds, err := p.resolveMembers(attrs.MemberIds)
+	if err != nil {
+		return err
+	}
+	if attrs.Displayname != group.Name {
+		err = checkNameUniqueness(models.GroupManager, group.DomainId, attrs.Displayname, group.Id)
+		if err != nil {
+			return err
+		}
+		diff, err := db.Update(group, func() error {
+			group.Name = attrs.Displayname
+			group.Displayname = attrs.Displayname
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "Update")
+		}
+		db.OpsLog.LogEvent(group, db.ACT_UPDATE, diff, models.GetDefaultAdminCred())
+	}
+	models.UsergroupManager.SyncGroupUsers(ctx, models.GetDefaultAdminCred(), group.Id, memberIds)
+	return nil
+}
+
+func (p *sProvider) deleteGroup(ctx context.Context, group *models.SGroup) error {
+	// validate before unlink, the group is left intact if it can't be deleted
+	err := group.ValidateUnlinkDeleteCondition(ctx, p.idp.Id)
+	if err != nil {
+		return errors.Wrap(err, "ValidateUnlinkDeleteCondition")
+	}
+	err = group.UnlinkIdp(p.idp.Id)
+	if err != nil {
+		return errors.Wrap(err, "UnlinkIdp")
+	}
+	return group.Delete(ctx, models.GetDefaultAdminCred())
+}
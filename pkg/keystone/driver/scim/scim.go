@@ -0,0 +1,78 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/keystone/driver"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// SCIM identity provider, users and groups are pushed by the SCIM client
+// instead of being pulled or created on authentication
+type SSCIMDriver struct {
+	driver.SBaseIdentityDriver
+
+	scimConfig *api.SSCIMIdpConfigOptions
+}
+
+func NewSCIMDriver(idpId, idpName, template, targetDomainId string, conf api.TConfigs) (driver.IIdentityBackend, error) {
+	base, err := driver.NewBaseIdentityDriver(idpId, idpName, template, targetDomainId, conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewBaseIdentityDriver")
+	}
+	drv := SSCIMDriver{SBaseIdentityDriver: base}
+	drv.SetVirtualObject(&drv)
+	err = drv.prepareConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "prepareConfig")
+	}
+	return &drv, nil
+}
+
+func (self *SSCIMDriver) prepareConfig() error {
+	if self.scimConfig == nil {
+		conf := api.SSCIMIdpConfigOptions{}
+		confJson := jsonutils.Marshal(self.Config[api.IdentityDriverSCIM])
+		err := confJson.Unmarshal(&conf)
+		if err != nil {
+			return errors.Wrap(err, "json.Unmarshal")
+		}
+		self.scimConfig = &conf
+	}
+	return nil
+}
+
+func (self *SSCIMDriver) GetSsoRedirectUri(ctx context.Context, callbackUrl, state string) (string, error) {
+	return "", errors.Wrap(httperrors.ErrNotSupported, "not a SSO driver")
+}
+
+func (self *SSCIMDriver) Authenticate(ctx context.Context, ident mcclient.SAuthenticationIdentity) (*api.SUserExtended, error) {
+	return nil, errors.Wrap(httperrors.ErrNotSupported, "SCIM identity provider does not authenticate users")
+}
+
+func (self *SSCIMDriver) Sync(ctx context.Context) error {
+	return nil
+}
+
+func (self *SSCIMDriver) Probe(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,229 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+)
+
+const testUser = `{
+	"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+	"id": "u1",
+	"userName": "Alice",
+	"active": true,
+	"name": {"givenName": "Alice", "familyName": "O'Malley"},
+	"emails": [
+		{"value": "alice@home.org", "type": "home"},
+		{"value": "alice@example.com", "type": "work", "primary": true}
+	],
+	"meta": {"created": "2020-01-02T00:00:00Z"}
+}`
+
+func TestFilter(t *testing.T) {
+	user, _ := jsonutils.ParseString(testUser)
+	cases := []struct {
+		filter string
+		match  bool
+	}{
+		{`userName eq "alice"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName sw "AL"`, true},
+		{`userName ne "alice"`, false},
+		{`name.familyName co "malley"`, true},
+		{`emails co "example.com"`, true},
+		{`emails.value ew "home.org"`, true},
+		{`emails[type eq "work" and value co "@example.com"]`, true},
+		{`emails[type eq "work" and value co "@home.org"]`, false},
+		{`title pr`, false},
+		{`name pr and active eq true`, true},
+		{`active eq false or not (userName eq "bob")`, true},
+		{`(userName eq "bob" or userName eq "carol") and active eq true`, false},
+		{`meta.created gt "2019-12-31T00:00:00Z"`, true},
+		{`USERNAME EQ "ALICE"`, true},
+	}
+	for _, c := range cases {
+		f, err := ParseFilter(c.filter)
+		if err != nil {
+			t.Errorf("parse %s: %v", c.filter, err)
+			continue
+		}
+		if f.Match(user) != c.match {
+			t.Errorf("filter %s: expect %v", c.filter, c.match)
+		}
+	}
+
+	for _, filter := range []string{
+		`userName eq`,
+		`userName xx "a"`,
+		`(userName eq "a"`,
+		`userName eq "a" and`,
+		`emails[type eq "work"`,
+		`userName eq "a`,
+		`userName eq alice`,
+	} {
+		_, err := ParseFilter(filter)
+		if errors.Cause(err) != ErrInvalidFilter {
+			t.Errorf("filter %s: expect invalid filter, got %v", filter, err)
+		}
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	cases := []struct {
+		name   string
+		ops    string
+		check  string
+		hasErr bool
+	}{
+		{
+			name:  "replace without path",
+			ops:   `[{"op": "replace", "value": {"active": false, "name.familyName": "Smith"}}]`,
+			check: `active eq false and name.familyName eq "Smith" and name.givenName eq "Alice"`,
+		},
+		{
+			name:  "azure style",
+			ops:   `[{"op": "Replace", "path": "active", "value": "False"}]`,
+			check: `active eq false`,
+		},
+		{
+			name:  "replace filtered sub-attribute",
+			ops:   `[{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "alice@corp.com"}]`,
+			check: `emails[type eq "work" and value eq "alice@corp.com"] and emails[type eq "home"]`,
+		},
+		{
+			name:  "add element by filter",
+			ops:   `[{"op": "replace", "path": "phoneNumbers[type eq \"mobile\"].value", "value": "123"}]`,
+			check: `phoneNumbers[type eq "mobile" and value eq "123"]`,
+		},
+		{
+			name:  "remove filtered",
+			ops:   `[{"op": "remove", "path": "emails[type eq \"home\"]"}]`,
+			check: `not (emails[type eq "home"]) and emails[type eq "work"]`,
+		},
+		{
+			name:  "add multi-valued",
+			ops:   `[{"op": "add", "path": "emails", "value": [{"value": "alice@example.com"}, {"value": "a@b.c"}]}]`,
+			check: `emails eq "a@b.c"`,
+		},
+		{
+			name:  "remove attribute",
+			ops:   `[{"op": "remove", "path": "name.givenName"}, {"op": "remove", "path": "active"}]`,
+			check: `not (name.givenName pr) and not (active pr)`,
+		},
+		{
+			name:  "extension ignored",
+			ops:   `[{"op": "add", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "R&D"}]`,
+			check: `userName eq "alice"`,
+		},
+		{
+			name:   "remove without path",
+			ops:    `[{"op": "remove"}]`,
+			hasErr: true,
+		},
+		{
+			name:   "invalid op",
+			ops:    `[{"op": "move", "path": "active", "value": true}]`,
+			hasErr: true,
+		},
+		{
+			name:   "unmatched filter",
+			ops:    `[{"op": "replace", "path": "emails[value co \"zzz\"].type", "value": "other"}]`,
+			hasErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			obj, _ := jsonutils.ParseString(testUser)
+			user := obj.(*jsonutils.JSONDict)
+			opsJson, err := jsonutils.ParseString(c.ops)
+			if err != nil {
+				t.Fatalf("parse ops: %v", err)
+			}
+			ops := make([]SPatchOperation, 0)
+			err = opsJson.Unmarshal(&ops)
+			if err != nil {
+				t.Fatalf("unmarshal ops: %v", err)
+			}
+			err = ApplyPatch(user, ops)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("expect error, got %s", user)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			f, err := ParseFilter(c.check)
+			if err != nil {
+				t.Fatalf("parse check: %v", err)
+			}
+			if !f.Match(user) {
+				t.Errorf("%s not match %s", user, c.check)
+			}
+		})
+	}
+}
+
+func TestPatchGroupMembers(t *testing.T) {
+	obj, _ := jsonutils.ParseString(`{"displayName": "ops", "members": [{"value": "u1"}, {"value": "u2"}]}`)
+	group := obj.(*jsonutils.JSONDict)
+	opsJson, _ := jsonutils.ParseString(`[
+		{"op": "add", "path": "members", "value": [{"value": "u3"}, {"value": "u1"}]},
+		{"op": "remove", "path": "members", "value": [{"value": "u2"}]},
+		{"op": "remove", "path": "members[value eq \"u1\"]"}
+	]`)
+	ops := make([]SPatchOperation, 0)
+	opsJson.Unmarshal(&ops)
+	err := ApplyPatch(group, ops)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	attrs, err := parseGroupAttributes(group)
+	if err != nil {
+		t.Fatalf("parseGroupAttributes: %v", err)
+	}
+	if len(attrs.MemberIds) != 1 || attrs.MemberIds[0] != "u3" {
+		t.Errorf("unexpected members %v", attrs.MemberIds)
+	}
+}
+
+func TestParseUserAttributes(t *testing.T) {
+	obj, _ := jsonutils.ParseString(testUser)
+	attrs, err := parseUserAttributes(obj)
+	if err != nil {
+		t.Fatalf("parseUserAttributes: %v", err)
+	}
+	if attrs.UserName != "Alice" || attrs.Displayname != "Alice O'Malley" || attrs.Email != "alice@example.com" || !attrs.Active.IsTrue() {
+		t.Errorf("unexpected attributes %#v", attrs)
+	}
+	_, err = parseUserAttributes(jsonutils.NewDict())
+	if errors.Cause(err) != ErrInvalidValue {
+		t.Errorf("expect invalid value, got %v", err)
+	}
+}
+
+func TestNewError(t *testing.T) {
+	code, serr := NewError(errors.Wrap(ErrUniqueness, "user alice already exists"))
+	if code != 409 || serr.Status != "409" || serr.ScimType != "uniqueness" {
+		t.Errorf("unexpected error %d %#v", code, serr)
+	}
+	out := jsonutils.Marshal(serr)
+	if typ, _ := out.GetString("scimType"); typ != "uniqueness" {
+		t.Errorf("unexpected json %s", out)
+	}
+}
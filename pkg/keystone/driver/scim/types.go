@@ -0,0 +1,195 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"strconv"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	SCHEMA_USER                    = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCHEMA_GROUP                   = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCHEMA_SERVICE_PROVIDER_CONFIG = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCHEMA_LIST_RESPONSE           = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCHEMA_PATCH_OP                = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCHEMA_BULK_REQUEST            = "urn:ietf:params:scim:api:messages:2.0:BulkRequest"
+	SCHEMA_BULK_RESPONSE           = "urn:ietf:params:scim:api:messages:2.0:BulkResponse"
+	SCHEMA_ERROR                   = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	CONTENT_TYPE = "application/scim+json"
+
+	RESOURCE_TYPE_USER  = "User"
+	RESOURCE_TYPE_GROUP = "Group"
+
+	DEFAULT_PAGE_SIZE = 100
+	MAX_PAGE_SIZE     = 1000
+	MAX_BULK_OPS      = 1000
+)
+
+const (
+	ErrInvalidFilter = errors.Error("invalid filter")
+	ErrInvalidPath   = errors.Error("invalid path")
+	ErrNoTarget      = errors.Error("no target")
+	ErrInvalidValue  = errors.Error("invalid value")
+	ErrUniqueness    = errors.Error("uniqueness")
+	ErrTooMany       = errors.Error("too many")
+)
+
+// scimTypes of the errors, RFC 7644 section 3.12
+var scimErrorTypes = map[errors.Error]string{
+	ErrInvalidFilter: "invalidFilter",
+	ErrInvalidPath:   "invalidPath",
+	ErrNoTarget:      "noTarget",
+	ErrInvalidValue:  "invalidValue",
+	ErrUniqueness:    "uniqueness",
+	ErrTooMany:       "tooMany",
+}
+
+func init() {
+	httperrors.RegisterErrorHttpCode(ErrInvalidFilter, 400)
+	httperrors.RegisterErrorHttpCode(ErrInvalidPath, 400)
+	httperrors.RegisterErrorHttpCode(ErrNoTarget, 400)
+	httperrors.RegisterErrorHttpCode(ErrInvalidValue, 400)
+	httperrors.RegisterErrorHttpCode(ErrUniqueness, 409)
+	httperrors.RegisterErrorHttpCode(ErrTooMany, 400)
+}
+
+type SError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType"`
+	Detail   string   `json:"detail"`
+}
+
+func NewError(err error) (int, SError) {
+	je := httperrors.NewGeneralError(err)
+	ret := SError{
+		Schemas: []string{SCHEMA_ERROR},
+		Status:  strconv.Itoa(je.Code),
+		Detail:  je.Details,
+	}
+	if cause, ok := errors.Cause(err).(errors.Error); ok {
+		ret.ScimType = scimErrorTypes[cause]
+	}
+	return je.Code, ret
+}
+
+type SListResponse struct {
+	Schemas      []string               `json:"schemas"`
+	TotalResults int                    `json:"totalResults"`
+	StartIndex   int                    `json:"startIndex"`
+	ItemsPerPage int                    `json:"itemsPerPage"`
+	Resources    []jsonutils.JSONObject `json:"Resources"`
+}
+
+type SPatchOperation struct {
+	Op    string               `json:"op"`
+	Path  string               `json:"path"`
+	Value jsonutils.JSONObject `json:"value"`
+}
+
+type SPatchRequest struct {
+	Schemas    []string          `json:"schemas"`
+	Operations []SPatchOperation `json:"Operations"`
+}
+
+type SBulkOperation struct {
+	Method  string               `json:"method"`
+	BulkId  string               `json:"bulkId"`
+	Version string               `json:"version"`
+	Path    string               `json:"path"`
+	Data    jsonutils.JSONObject `json:"data"`
+}
+
+type SBulkRequest struct {
+	Schemas      []string         `json:"schemas"`
+	FailOnErrors int              `json:"failOnErrors"`
+	Operations   []SBulkOperation `json:"Operations"`
+}
+
+type SBulkOperationResult struct {
+	Method   string               `json:"method"`
+	BulkId   string               `json:"bulkId"`
+	Location string               `json:"location"`
+	Status   string               `json:"status"`
+	Response jsonutils.JSONObject `json:"response"`
+}
+
+type SBulkResponse struct {
+	Schemas    []string               `json:"schemas"`
+	Operations []SBulkOperationResult `json:"Operations"`
+}
+
+type sSupported struct {
+	Supported bool `json:"supported"`
+}
+
+type sBulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type sFilterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type sAuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+type SServiceProviderConfig struct {
+	Schemas               []string                `json:"schemas"`
+	Patch                 sSupported              `json:"patch"`
+	Bulk                  sBulkSupported          `json:"bulk"`
+	Filter                sFilterSupported        `json:"filter"`
+	ChangePassword        sSupported              `json:"changePassword"`
+	Sort                  sSupported              `json:"sort"`
+	Etag                  sSupported              `json:"etag"`
+	AuthenticationSchemes []sAuthenticationScheme `json:"authenticationSchemes"`
+}
+
+func getServiceProviderConfig() SServiceProviderConfig {
+	return SServiceProviderConfig{
+		Schemas: []string{SCHEMA_SERVICE_PROVIDER_CONFIG},
+		Patch:   sSupported{Supported: true},
+		Bulk: sBulkSupported{
+			Supported:      true,
+			MaxOperations:  MAX_BULK_OPS,
+			MaxPayloadSize: maxPayloadSize,
+		},
+		Filter: sFilterSupported{
+			Supported:  true,
+			MaxResults: MAX_PAGE_SIZE,
+		},
+		AuthenticationSchemes: []sAuthenticationScheme{
+			{
+				Type:        "oauthbearertoken",
+				Name:        "OAuth Bearer Token",
+				Description: "Authentication with the bearer token configured in the identity provider",
+				Primary:     true,
+			},
+		},
+	}
+}
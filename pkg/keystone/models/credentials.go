@@ -16,10 +16,13 @@ package models
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/gotypes"
 	"yunion.io/x/pkg/tristate"
@@ -27,6 +30,7 @@ import (
 
 	api "yunion.io/x/onecloud/pkg/apis/identity"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/keystone/keys"
 	"yunion.io/x/onecloud/pkg/mcclient"
@@ -147,6 +151,10 @@ func (manager *SCredentialManager) ValidateCreateData(
 	if len(blob) == 0 {
 		return input, httperrors.NewInputParameterError("missing input field blob")
 	}
+	err := validateCredentialBlob(input.Type, blob)
+	if err != nil {
+		return input, err
+	}
 	blobEnc, err := keys.CredentialKeyManager.Encrypt([]byte(blob))
 	if err != nil {
 		return input, httperrors.NewInternalServerError("encrypt error %s", err)
@@ -168,6 +176,13 @@ func (self *SCredential) ValidateDeleteCondition(ctx context.Context, info jsonu
 func (self *SCredential) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.CredentialUpdateInput) (api.CredentialUpdateInput, error) {
 	var err error
 
+	if len(input.Blob) > 0 {
+		err = validateCredentialBlob(self.Type, input.Blob)
+		if err != nil {
+			return input, err
+		}
+	}
+
 	input.StandaloneResourceBaseUpdateInput, err = self.SStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.StandaloneResourceBaseUpdateInput)
 	if err != nil {
 		return input, errors.Wrap(err, "SStandaloneResourceBase.ValidateUpdateData")
@@ -176,6 +191,44 @@ func (self *SCredential) ValidateUpdateData(ctx context.Context, userCred mcclie
 	return input, nil
 }
 
+func (self *SCredential) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SStandaloneResourceBase.PostUpdate(ctx, userCred, query, data)
+
+	blob, _ := data.GetString("blob")
+	if len(blob) > 0 {
+		err := self.saveBlob([]byte(blob))
+		if err != nil {
+			log.Errorf("credential %s save blob fail %s", self.Id, err)
+		}
+	}
+}
+
+// validateCredentialBlob checks the blob of credential types with a well known format
+func validateCredentialBlob(credType string, blob string) error {
+	var obj interface {
+		Validate() error
+	}
+	switch credType {
+	case api.WEBAUTHN_CREDENTIAL_TYPE:
+		obj = &api.SWebAuthnCredentialBlob{}
+	default:
+		return nil
+	}
+	blobJson, err := jsonutils.ParseString(blob)
+	if err != nil {
+		return httperrors.NewInputParameterError("invalid %s blob: %s", credType, err)
+	}
+	err = blobJson.Unmarshal(obj)
+	if err != nil {
+		return httperrors.NewInputParameterError("invalid %s blob: %s", credType, err)
+	}
+	err = obj.Validate()
+	if err != nil {
+		return httperrors.NewInputParameterError("invalid %s blob: %s", credType, err)
+	}
+	return nil
+}
+
 func (manager *SCredentialManager) FetchCustomizeColumns(
 	ctx context.Context,
 	userCred mcclient.TokenCredential,
@@ -214,6 +267,88 @@ func (self *SCredential) getBlob() []byte {
 	return keys.CredentialKeyManager.Decrypt([]byte(self.EncryptedBlob))
 }
 
+func (self *SCredential) saveBlob(blob []byte) error {
+	blobEnc, err := keys.CredentialKeyManager.Encrypt(blob)
+	if err != nil {
+		return errors.Wrap(err, "Encrypt")
+	}
+	_, err = db.Update(self, func() error {
+		self.EncryptedBlob = string(blobEnc)
+		self.KeyHash = keys.CredentialKeyManager.PrimaryKeyHash()
+		return nil
+	})
+	return err
+}
+
+// replaceBlob saves the blob only if the encrypted blob is not changed since
+// oldBlob is read
+func (self *SCredential) replaceBlob(oldBlob string, blob []byte) error {
+	blobEnc, err := keys.CredentialKeyManager.Encrypt(blob)
+	if err != nil {
+		return errors.Wrap(err, "Encrypt")
+	}
+	result, err := sqlchemy.GetDB().Exec(
+		fmt.Sprintf(
+			"update %s set encrypted_blob = ?, key_hash = ?, updated_at = ?, update_version = update_version + 1 where id = ? and encrypted_blob = ?",
+			CredentialManager.TableSpec().Name(),
+		), string(blobEnc), keys.CredentialKeyManager.PrimaryKeyHash(), time.Now().UTC(), self.Id, oldBlob,
+	)
+	if err != nil {
+		return errors.Wrap(err, "update encrypted_blob")
+	}
+	cnt, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "RowsAffected")
+	}
+	if cnt != 1 {
+		return errors.Wrapf(httperrors.ErrConflict, "credential %s is changed concurrently", self.Id)
+	}
+	return nil
+}
+
+// 使用恢复码，恢复码的校验和删除在服务端一次完成，同一个恢复码只能使用一次
+func (self *SCredential) PerformUseRecoveryCode(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.CredentialUseRecoveryCodeInput) (jsonutils.JSONObject, error) {
+	if self.Type != api.RECOVERY_CODES_TYPE {
+		return nil, httperrors.NewUnsupportOperationError("credential %s is not %s", self.Id, api.RECOVERY_CODES_TYPE)
+	}
+	if len(input.Code) == 0 {
+		return nil, httperrors.NewMissingParameterError("code")
+	}
+
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	// the codes may be consumed by others before the lock is acquired
+	obj, err := CredentialManager.FetchById(self.Id)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchById")
+	}
+	cred := obj.(*SCredential)
+	blobJson, err := jsonutils.Parse(cred.getBlob())
+	if err != nil {
+		return nil, errors.Wrap(err, "parse recovery codes")
+	}
+	blob := api.SRecoveryCodesBlob{}
+	err = blobJson.Unmarshal(&blob)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal recovery codes")
+	}
+	hash := api.HashRecoveryCode(input.Code)
+	for i := range blob.Codes {
+		if subtle.ConstantTimeCompare([]byte(blob.Codes[i]), []byte(hash)) != 1 {
+			continue
+		}
+		blob.Codes = append(blob.Codes[:i], blob.Codes[i+1:]...)
+		// conditional update keeps the code from being used twice through other keystone instances
+		err = cred.replaceBlob(cred.EncryptedBlob, []byte(jsonutils.Marshal(&blob).String()))
+		if err != nil {
+			return nil, errors.Wrap(err, "replaceBlob")
+		}
+		return jsonutils.Marshal(api.CredentialUseRecoveryCodeOutput{Left: len(blob.Codes)}), nil
+	}
+	return nil, httperrors.NewInvalidCredentialError("invalid recovery code")
+}
+
 func (self *SCredential) GetAccessKeySecret() (*api.SAccessKeySecretBlob, error) {
 	if self.Type == api.ACCESS_SECRET_TYPE || self.Type == api.OIDC_CREDENTIAL_TYPE {
 		blobJson, err := jsonutils.Parse(self.getBlob())
@@ -181,6 +181,15 @@ func (group *SGroup) ValidateDeleteCondition(ctx context.Context, info jsonutils
 	return group.SIdentityBaseResource.ValidateDeleteCondition(ctx, nil)
 }
 
+// ValidateUnlinkDeleteCondition validates the group could be deleted once
+// unlinked with the identity provider idpId
+func (group *SGroup) ValidateUnlinkDeleteCondition(ctx context.Context, idpId string) error {
+	if group.IsReadOnly() && !group.LinkedWithIdp(idpId) {
+		return httperrors.NewForbiddenError("readonly")
+	}
+	return group.SIdentityBaseResource.ValidateDeleteCondition(ctx, nil)
+}
+
 func (group *SGroup) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
 	err := AssignmentManager.projectRemoveAllGroup(ctx, userCred, group)
 	if err != nil {
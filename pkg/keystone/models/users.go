@@ -781,12 +781,21 @@ func (user *SUser) PostUpdate(ctx context.Context, userCred mcclient.TokenCreden
 }
 
 func (user *SUser) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	return user.ValidateUnlinkDeleteCondition(ctx, "")
+}
+
+// ValidateUnlinkDeleteCondition validates the user could be deleted once
+// unlinked with the identity provider idpId
+func (user *SUser) ValidateUnlinkDeleteCondition(ctx context.Context, idpId string) error {
 	idMappings, err := user.getIdmappings()
 	if err != nil {
 		return errors.Wrap(err, "getIdmappings")
 	}
 	if !user.IsLocal() && len(idMappings) > 0 {
 		for _, idmaping := range idMappings {
+			if idmaping.IdpId == idpId {
+				continue
+			}
 			idp, err := IdentityProviderManager.FetchIdentityProviderById(idmaping.IdpId)
 			if err != nil && errors.Cause(err) == sql.ErrNoRows {
 				return errors.Wrap(err, "IdentityProviderManager.FetchIdentityProviderById")
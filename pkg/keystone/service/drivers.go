@@ -25,5 +25,6 @@ import (
 	_ "yunion.io/x/onecloud/pkg/keystone/driver/oauth2/wechat"
 	_ "yunion.io/x/onecloud/pkg/keystone/driver/oidc"
 	_ "yunion.io/x/onecloud/pkg/keystone/driver/saml"
+	_ "yunion.io/x/onecloud/pkg/keystone/driver/scim"
 	_ "yunion.io/x/onecloud/pkg/keystone/driver/sql"
 )
@@ -22,6 +22,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/keystone/cronjobs"
+	"yunion.io/x/onecloud/pkg/keystone/driver/scim"
 	"yunion.io/x/onecloud/pkg/keystone/models"
 	"yunion.io/x/onecloud/pkg/keystone/options"
 	"yunion.io/x/onecloud/pkg/keystone/tokens"
@@ -47,6 +48,7 @@ func InitHandlers(app *appsrv.Application) {
 	app_common.ExportOptionsHandler(app, &options.Options)
 
 	tokens.AddHandler(app)
+	scim.AddHandler(app)
 
 	for _, manager := range []db.IModelManager{
 		taskman.TaskManager,
@@ -75,6 +77,10 @@ func InitHandlers(app *appsrv.Application) {
 		models.IdentityQuotaManager,
 		models.IdentityUsageManager,
 		models.IdentityPendingUsageManager,
+
+		quotas.QuotaAlertManager,
+		quotas.QuotaUsageHistoryManager,
+		quotas.QuotaChangeRequestManager,
 	} {
 		db.RegisterModelManager(manager)
 	}
@@ -36,6 +36,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
 	"yunion.io/x/onecloud/pkg/logger/extern"
 	"yunion.io/x/onecloud/pkg/logger/options"
+	"yunion.io/x/onecloud/pkg/logger/sinks"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 )
@@ -256,9 +257,38 @@ func (self *SActionlog) PostCreate(ctx context.Context, userCred mcclient.TokenC
 		db.DistinctFieldManager.InsertOrUpdate(ctx, ActionLog, k, v)
 	}
 
+	sinks.Forward(self.toSinkRecord())
+
 	ActionLog.notifyIfExceedCount(ctx, self)
 }
 
+func (self *SActionlog) toSinkRecord() *sinks.SRecord {
+	return &sinks.SRecord{
+		Id:              self.Id,
+		OpsTime:         self.OpsTime,
+		StartTime:       self.StartTime,
+		Service:         self.Service,
+		Action:          self.Action,
+		Success:         self.Success,
+		Severity:        string(self.Severity),
+		Kind:            string(self.Kind),
+		ObjType:         self.ObjType,
+		ObjId:           self.ObjId,
+		ObjName:         self.ObjName,
+		Notes:           self.Notes,
+		UserId:          self.UserId,
+		User:            self.User,
+		DomainId:        self.DomainId,
+		Domain:          self.Domain,
+		Roles:           self.Roles,
+		Ip:              self.Ip,
+		ProjectId:       self.ProjectId,
+		Project:         self.Project,
+		ProjectDomainId: self.ProjectDomainId,
+		ProjectDomain:   self.ProjectDomain,
+	}
+}
+
 func (manager *SActionlogManager) notifyIfExceedCount(ctx context.Context, l *SActionlog) {
 	exceedCnt := options.Options.ActionLogExceedCount
 	if exceedCnt <= 0 {
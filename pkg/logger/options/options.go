@@ -15,8 +15,13 @@
 package options
 
 import (
+	"reflect"
+
+	"yunion.io/x/log"
+
 	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
 	"yunion.io/x/onecloud/pkg/logger/extern"
+	"yunion.io/x/onecloud/pkg/logger/sinks"
 )
 
 type SLoggerOptions struct {
@@ -37,12 +42,27 @@ type SLoggerOptions struct {
 	SyslogVendorCode string `help:"vendor code of syslog" default:"0003"`
 	SyslogSeparator  string `help:"syslog message field separator" default:","`
 	SyslogSepEscape  string `help:"syslog message separate escape string" default:"+"`
+
+	ActionLogSinks              []string `help:"push every action log to the sinks, e.g. syslog+tls://siem:6514?format=cef, kafka://broker1:9092,broker2:9092/topic, https://siem/hook?token=xxx&service=compute&success=false"`
+	ActionLogSinkSpoolDir       string   `help:"directory to spill the action logs not delivered to the sinks" default:"/opt/cloud/workspace/logger/sinks"`
+	ActionLogSinkSpoolMaxSizeMb int      `help:"max size of the spool file of a sink in MB" default:"1024"`
+	ActionLogSinkQueueSize      int      `help:"capacity of the in-memory queue of a sink" default:"1024"`
+	ActionLogSinkMaxRetries     int      `help:"times a batch is retried before spilled to disk" default:"3"`
 }
 
 var (
 	Options SLoggerOptions
 )
 
+func InitSinks(opts *SLoggerOptions) error {
+	return sinks.Init(opts.ActionLogSinks, sinks.SOptions{
+		SpoolDir:      opts.ActionLogSinkSpoolDir,
+		SpoolMaxBytes: int64(opts.ActionLogSinkSpoolMaxSizeMb) * 1024 * 1024,
+		QueueSize:     opts.ActionLogSinkQueueSize,
+		MaxRetries:    opts.ActionLogSinkMaxRetries,
+	})
+}
+
 func OnOptionsChange(oldOptions, newOptions interface{}) bool {
 	oldOpts := oldOptions.(*SLoggerOptions)
 	newOpts := newOptions.(*SLoggerOptions)
@@ -56,6 +76,13 @@ func OnOptionsChange(oldOptions, newOptions interface{}) bool {
 		changed = true
 	}
 
+	if !reflect.DeepEqual(oldOpts.ActionLogSinks, newOpts.ActionLogSinks) {
+		err := InitSinks(newOpts)
+		if err != nil {
+			log.Errorf("InitSinks fail: %s", err)
+		}
+	}
+
 	if oldOpts.SyslogUrl != newOpts.SyslogUrl {
 		err := extern.InitSyslog(newOpts.SyslogUrl)
 		if err != nil {
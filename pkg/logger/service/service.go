@@ -62,5 +62,10 @@ func StartService() {
 		extern.InitSyslog(opts.SyslogUrl)
 	}
 
+	err := options.InitSinks(opts)
+	if err != nil {
+		log.Errorf("InitSinks fail: %s", err)
+	}
+
 	app_common.ServeForever(app, baseOpts)
 }
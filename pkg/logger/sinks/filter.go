@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/pkg/utils"
+)
+
+// SFilter selects the records forwarded to a sink, empty filter matches all
+type SFilter struct {
+	Services []string
+	// actions, a trailing '*' matches the prefix, e.g. 'delete*'
+	Actions []string
+	Success tristate.TriState
+}
+
+func splitList(str string) []string {
+	ret := make([]string, 0)
+	for _, s := range strings.Split(str, ",") {
+		s = strings.TrimSpace(s)
+		if len(s) > 0 {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+func parseFilter(query url.Values) (SFilter, error) {
+	filter := SFilter{
+		Services: splitList(query.Get("service")),
+		Actions:  splitList(query.Get("action")),
+		Success:  tristate.None,
+	}
+	if success := query.Get("success"); len(success) > 0 {
+		b, err := strconv.ParseBool(success)
+		if err != nil {
+			return filter, errors.Wrapf(err, "invalid success %q", success)
+		}
+		filter.Success = tristate.NewFromBool(b)
+	}
+	return filter, nil
+}
+
+func (f SFilter) Match(r *SRecord) bool {
+	if len(f.Services) > 0 && !utils.IsInStringArray(r.Service, f.Services) {
+		return false
+	}
+	if len(f.Actions) > 0 {
+		matched := false
+		for _, action := range f.Actions {
+			if action == r.Action || (strings.HasSuffix(action, "*") && strings.HasPrefix(r.Action, action[:len(action)-1])) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if !f.Success.IsNone() && f.Success.Bool() != r.Success {
+		return false
+	}
+	return true
+}
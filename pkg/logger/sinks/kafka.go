@@ -0,0 +1,106 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// sKafkaWriter produces the messages to a topic, the sink url is
+// kafka[+tls]://[user:password@]broker1:9092[,broker2:9092]/topic and
+// SASL/PLAIN authentication is used if user is given
+type sKafkaWriter struct {
+	brokers []string
+	topic   string
+	config  *sarama.Config
+
+	producer sarama.SyncProducer
+}
+
+func newKafkaWriter(conf *SSinkConfig) (*sKafkaWriter, error) {
+	w := &sKafkaWriter{
+		brokers: splitList(conf.Url.Host),
+		topic:   strings.Trim(conf.Url.Path, "/"),
+	}
+	if len(w.topic) == 0 {
+		return nil, errors.Errorf("missing topic in kafka sink url")
+	}
+	config := sarama.NewConfig()
+	config.ClientID = "cloudpods-logger"
+	config.Net.DialTimeout = 10 * time.Second
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Return.Successes = true
+	config.Producer.Retry.Max = 0
+	switch conf.Url.Scheme {
+	case "kafka":
+	case "kafka+tls":
+		tlsConf, err := conf.tlsConfig("")
+		if err != nil {
+			return nil, err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConf
+	default:
+		return nil, errors.Errorf("unsupported kafka scheme %s", conf.Url.Scheme)
+	}
+	if conf.Url.User != nil {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.User = conf.Url.User.Username()
+		config.Net.SASL.Password, _ = conf.Url.User.Password()
+	}
+	w.config = config
+	return w, nil
+}
+
+func (w *sKafkaWriter) Encode(r *SRecord, payload []byte) []byte {
+	return payload
+}
+
+func (w *sKafkaWriter) Write(ctx context.Context, msgs [][]byte) error {
+	if w.producer == nil {
+		producer, err := sarama.NewSyncProducer(w.brokers, w.config)
+		if err != nil {
+			return errors.Wrapf(err, "connect to kafka %s", strings.Join(w.brokers, ","))
+		}
+		w.producer = producer
+	}
+	pmsgs := make([]*sarama.ProducerMessage, len(msgs))
+	for i := range msgs {
+		pmsgs[i] = &sarama.ProducerMessage{
+			Topic: w.topic,
+			Value: sarama.ByteEncoder(msgs[i]),
+		}
+	}
+	err := w.producer.SendMessages(pmsgs)
+	if err != nil {
+		return errors.Wrapf(err, "produce to %s", w.topic)
+	}
+	return nil
+}
+
+func (w *sKafkaWriter) Close() error {
+	if w.producer == nil {
+		return nil
+	}
+	err := w.producer.Close()
+	w.producer = nil
+	return err
+}
@@ -0,0 +1,296 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	retryMinInterval = time.Second
+	retryMaxInterval = time.Minute
+)
+
+type SOptions struct {
+	// directory of the spool files
+	SpoolDir string
+	// max size of the spool file of a sink
+	SpoolMaxBytes int64
+	// capacity of the in-memory queue of a sink
+	QueueSize int
+	// times a batch is retried before it is spilled to disk
+	MaxRetries int
+}
+
+type sSink struct {
+	conf   *SSinkConfig
+	writer IWriter
+	opts   SOptions
+
+	queue chan []byte
+	// spill holds the messages overflowing the queue to be written to
+	// the spool, the messages are dropped if it is full too
+	spill     chan []byte
+	spool     *sSpool
+	stop      chan struct{}
+	done      chan struct{}
+	spillDone chan struct{}
+}
+
+var (
+	sinksLock sync.Mutex
+	sinks     []*sSink
+)
+
+// Init starts the sinks and stops the previous ones
+func Init(urls []string, opts SOptions) error {
+	newSinks := make([]*sSink, 0, len(urls))
+	for _, u := range urls {
+		conf, err := ParseSinkUrl(u)
+		if err != nil {
+			return errors.Wrapf(err, "parse sink %s", u)
+		}
+		writer, err := conf.NewWriter()
+		if err != nil {
+			return errors.Wrapf(err, "sink %s", conf.Name)
+		}
+		newSinks = append(newSinks, newSink(conf, writer, opts))
+	}
+	if len(newSinks) > 0 {
+		err := os.MkdirAll(opts.SpoolDir, 0700)
+		if err != nil {
+			return errors.Wrapf(err, "mkdir %s", opts.SpoolDir)
+		}
+	}
+
+	sinksLock.Lock()
+	oldSinks := sinks
+	sinks = newSinks
+	sinksLock.Unlock()
+
+	for _, s := range oldSinks {
+		s.Stop()
+	}
+	for _, s := range newSinks {
+		log.Infof("start action log sink %s %s", s.conf.Name, s.conf.Url.Redacted())
+		go s.run()
+	}
+	return nil
+}
+
+// Forward queues the record to the sinks whose filter matches, it never
+// blocks on a slow sink
+func Forward(r *SRecord) {
+	sinksLock.Lock()
+	curSinks := sinks
+	sinksLock.Unlock()
+
+	for _, s := range curSinks {
+		if s.conf.Filter.Match(r) {
+			s.enqueue(s.writer.Encode(r, r.Format(s.conf.Format)))
+		}
+	}
+}
+
+func newSink(conf *SSinkConfig, writer IWriter, opts SOptions) *sSink {
+	return &sSink{
+		conf:      conf,
+		writer:    writer,
+		opts:      opts,
+		queue:     make(chan []byte, opts.QueueSize),
+		spill:     make(chan []byte, opts.QueueSize),
+		spool:     newSpool(filepath.Join(opts.SpoolDir, conf.Name+".spool"), opts.SpoolMaxBytes),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		spillDone: make(chan struct{}),
+	}
+}
+
+func (s *sSink) enqueue(msg []byte) {
+	select {
+	case s.queue <- msg:
+	default:
+		// the sink falls behind, spill to disk
+		select {
+		case s.spill <- msg:
+		default:
+			log.Errorf("sink %s drop message: spill queue is full", s.conf.Name)
+		}
+	}
+}
+
+// runSpill appends the overflowing messages to the spool
+func (s *sSink) runSpill() {
+	defer close(s.spillDone)
+
+	for {
+		select {
+		case msg := <-s.spill:
+			s.appendSpool(append([][]byte{msg}, drain(s.spill)...))
+		case <-s.stop:
+			s.appendSpool(drain(s.spill))
+			return
+		}
+	}
+}
+
+func (s *sSink) appendSpool(msgs [][]byte) {
+	if len(msgs) == 0 {
+		return
+	}
+	err := s.spool.Append(msgs)
+	if err != nil {
+		log.Errorf("sink %s drop %d messages: %s", s.conf.Name, len(msgs), err)
+	}
+}
+
+// Stop stops the worker and spills the queued messages to disk
+func (s *sSink) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+func (s *sSink) isStopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for the duration and returns false if the sink is stopped
+func (s *sSink) sleep(d time.Duration) bool {
+	select {
+	case <-s.stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// drain returns the messages in c without blocking
+func drain(c chan []byte) [][]byte {
+	msgs := make([][]byte, 0)
+	for {
+		select {
+		case msg := <-c:
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+// nextBatch returns the messages in spool first, and then those in the
+// queue, fromSpool tells where the messages come from
+func (s *sSink) nextBatch() (msgs [][]byte, offset int64, fromSpool bool) {
+	if s.spool.Pending() {
+		msgs, offset, err := s.spool.Read(s.conf.BatchSize)
+		if err != nil {
+			log.Errorf("sink %s read spool: %s", s.conf.Name, err)
+		} else if len(msgs) > 0 {
+			return msgs, offset, true
+		} else {
+			s.spool.Commit(offset)
+		}
+	}
+	select {
+	case <-s.stop:
+		return nil, 0, false
+	case msg := <-s.queue:
+		msgs = [][]byte{msg}
+	case <-time.After(retryMaxInterval):
+		// check the spool periodically
+		return nil, 0, false
+	}
+	for len(msgs) < s.conf.BatchSize {
+		select {
+		case msg := <-s.queue:
+			msgs = append(msgs, msg)
+		default:
+			return msgs, 0, false
+		}
+	}
+	return msgs, 0, false
+}
+
+// send writes the messages and retries on error
+func (s *sSink) send(msgs [][]byte) error {
+	var err error
+	interval := retryMinInterval
+	for i := 0; i <= s.opts.MaxRetries; i++ {
+		if i > 0 {
+			if !s.sleep(interval) {
+				return errors.Wrap(err, "sink stopped")
+			}
+			interval *= 2
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), retryMaxInterval)
+		err = s.writer.Write(ctx, msgs)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		log.Warningf("sink %s write %d messages fail (%d/%d): %s", s.conf.Name, len(msgs), i+1, s.opts.MaxRetries+1, err)
+	}
+	return err
+}
+
+func (s *sSink) run() {
+	defer close(s.done)
+	defer s.writer.Close()
+
+	go s.runSpill()
+	defer func() {
+		<-s.spillDone
+	}()
+
+	backoff := retryMinInterval
+	for !s.isStopped() {
+		msgs, offset, fromSpool := s.nextBatch()
+		if len(msgs) == 0 {
+			continue
+		}
+		err := s.send(msgs)
+		if err == nil {
+			backoff = retryMinInterval
+			if fromSpool {
+				err = s.spool.Commit(offset)
+				if err != nil {
+					log.Errorf("sink %s commit spool: %s", s.conf.Name, err)
+				}
+			}
+			continue
+		}
+		if !fromSpool {
+			s.appendSpool(msgs)
+		}
+		if !s.sleep(backoff) {
+			break
+		}
+		if backoff *= 2; backoff > retryMaxInterval {
+			backoff = retryMaxInterval
+		}
+	}
+	s.appendSpool(drain(s.queue))
+}
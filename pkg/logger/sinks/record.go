@@ -0,0 +1,155 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/util/version"
+
+	api "yunion.io/x/onecloud/pkg/apis/logger"
+)
+
+const (
+	FORMAT_JSON = "json"
+	FORMAT_CEF  = "cef"
+
+	CEF_VENDOR  = "Yunion"
+	CEF_PRODUCT = "Cloudpods"
+)
+
+// SRecord is the action log forwarded to sinks
+type SRecord struct {
+	Id        int64     `json:"id"`
+	OpsTime   time.Time `json:"ops_time"`
+	StartTime time.Time `json:"start_time"`
+	Service   string    `json:"service"`
+	Action    string    `json:"action"`
+	Success   bool      `json:"success"`
+	Severity  string    `json:"severity"`
+	Kind      string    `json:"kind"`
+
+	ObjType string `json:"obj_type"`
+	ObjId   string `json:"obj_id"`
+	ObjName string `json:"obj_name"`
+	Notes   string `json:"notes"`
+
+	UserId          string `json:"user_id"`
+	User            string `json:"user"`
+	DomainId        string `json:"domain_id"`
+	Domain          string `json:"domain"`
+	Roles           string `json:"roles"`
+	Ip              string `json:"ip"`
+	ProjectId       string `json:"tenant_id"`
+	Project         string `json:"tenant"`
+	ProjectDomainId string `json:"project_domain_id"`
+	ProjectDomain   string `json:"project_domain"`
+}
+
+// syslogSeverity returns the RFC 5424 severity
+func (r *SRecord) syslogSeverity() int {
+	switch api.TEventSeverity(r.Severity) {
+	case api.SeverityEmergency:
+		return 0
+	case api.SeverityAlert:
+		return 1
+	case api.SeverityCritical:
+		return 2
+	case api.SeverityError:
+		return 3
+	case api.SeverityWarning:
+		return 4
+	case api.SeverityNotice:
+		return 5
+	case api.SeverityDebug:
+		return 7
+	}
+	if !r.Success {
+		return 3
+	}
+	return 6
+}
+
+// cefSeverity maps the syslog severity to CEF severity, 10 is the most important
+func (r *SRecord) cefSeverity() int {
+	return []int{10, 9, 8, 7, 5, 4, 3, 1}[r.syslogSeverity()]
+}
+
+func (r *SRecord) Format(format string) []byte {
+	if format == FORMAT_CEF {
+		return []byte(r.formatCEF())
+	}
+	return []byte(jsonutils.MarshalAll(r).String())
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+)
+
+// formatCEF formats the record in ArcSight Common Event Format
+//
+//	CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+func (r *SRecord) formatCEF() string {
+	outcome := "success"
+	if !r.Success {
+		outcome = "failure"
+	}
+	header := []string{
+		"CEF:0",
+		CEF_VENDOR,
+		CEF_PRODUCT,
+		version.GetShortString(),
+		fmt.Sprintf("%s:%s", r.Service, r.Action),
+		fmt.Sprintf("%s %s", r.Action, r.ObjType),
+		fmt.Sprintf("%d", r.cefSeverity()),
+	}
+	for i := 1; i < len(header)-1; i++ {
+		header[i] = cefHeaderEscaper.Replace(header[i])
+	}
+	exts := [][2]string{
+		{"rt", fmt.Sprintf("%d", r.OpsTime.UnixNano()/int64(time.Millisecond))},
+		{"externalId", fmt.Sprintf("%d", r.Id)},
+		{"cat", r.ObjType},
+		{"act", r.Action},
+		{"outcome", outcome},
+		{"suid", r.UserId},
+		{"suser", r.User},
+		{"src", r.Ip},
+		{"cs1Label", "service"},
+		{"cs1", r.Service},
+		{"cs2Label", "objId"},
+		{"cs2", r.ObjId},
+		{"cs3Label", "objName"},
+		{"cs3", r.ObjName},
+		{"cs4Label", "project"},
+		{"cs4", r.Project},
+		{"cs5Label", "domain"},
+		{"cs5", r.Domain},
+		{"cs6Label", "kind"},
+		{"cs6", r.Kind},
+		{"msg", r.Notes},
+	}
+	parts := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		if len(ext[1]) > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%s", ext[0], cefExtensionEscaper.Replace(ext[1])))
+		}
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(parts, " ")
+}
@@ -0,0 +1,156 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+)
+
+const (
+	SINK_SYSLOG  = "syslog"
+	SINK_KAFKA   = "kafka"
+	SINK_WEBHOOK = "webhook"
+
+	DEFAULT_BATCH_SIZE = 100
+)
+
+// IWriter delivers the messages to the sink
+type IWriter interface {
+	// Encode wraps the formatted record into the message of the sink
+	Encode(r *SRecord, payload []byte) []byte
+	// Write sends the messages in order, the whole batch is retried on error
+	Write(ctx context.Context, msgs [][]byte) error
+	Close() error
+}
+
+// query parameters consumed by the sinks, which are not passed to the endpoint
+var sinkOptionKeys = []string{"name", "format", "batch", "service", "action", "success", "facility", "app_name", "insecure", "ca", "token"}
+
+// SSinkConfig is the parsed sink url
+type SSinkConfig struct {
+	// name of the sink, used as the spool file name
+	Name      string
+	Type      string
+	Format    string
+	BatchSize int
+	Filter    SFilter
+
+	// endpoint url without the sink options
+	Url   *url.URL
+	Query url.Values
+}
+
+var nameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// ParseSinkUrl parses the sink url, e.g. syslog+tcp://host:514?format=cef
+func ParseSinkUrl(str string) (*SSinkConfig, error) {
+	u, err := url.Parse(str)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid sink url")
+	}
+	if len(u.Host) == 0 {
+		return nil, errors.Errorf("missing host in sink url %s", u.Redacted())
+	}
+	conf := &SSinkConfig{
+		Query:     u.Query(),
+		BatchSize: DEFAULT_BATCH_SIZE,
+		Format:    FORMAT_JSON,
+	}
+	switch {
+	case strings.HasPrefix(u.Scheme, SINK_SYSLOG):
+		conf.Type = SINK_SYSLOG
+		conf.Format = FORMAT_CEF
+	case strings.HasPrefix(u.Scheme, SINK_KAFKA):
+		conf.Type = SINK_KAFKA
+	case u.Scheme == "http" || u.Scheme == "https":
+		conf.Type = SINK_WEBHOOK
+	default:
+		return nil, errors.Errorf("unsupported sink scheme %s", u.Scheme)
+	}
+	if format := conf.Query.Get("format"); len(format) > 0 {
+		if !utils.IsInStringArray(format, []string{FORMAT_JSON, FORMAT_CEF}) {
+			return nil, errors.Errorf("unsupported format %s", format)
+		}
+		conf.Format = format
+	}
+	if batch := conf.Query.Get("batch"); len(batch) > 0 {
+		conf.BatchSize, err = strconv.Atoi(batch)
+		if err != nil || conf.BatchSize <= 0 {
+			return nil, errors.Errorf("invalid batch %s", batch)
+		}
+	}
+	conf.Filter, err = parseFilter(conf.Query)
+	if err != nil {
+		return nil, err
+	}
+	conf.Name = conf.Query.Get("name")
+	if len(conf.Name) == 0 {
+		conf.Name = fmt.Sprintf("%s-%s%s", conf.Type, u.Host, u.Path)
+	}
+	conf.Name = strings.Trim(nameSanitizer.ReplaceAllString(conf.Name, "_"), "_")
+
+	endpoint := *u
+	query := u.Query()
+	for _, k := range sinkOptionKeys {
+		query.Del(k)
+	}
+	endpoint.RawQuery = query.Encode()
+	conf.Url = &endpoint
+	return conf, nil
+}
+
+func (conf *SSinkConfig) tlsConfig(serverName string) (*tls.Config, error) {
+	tlsConf := &tls.Config{
+		ServerName: serverName,
+	}
+	if insecure, _ := strconv.ParseBool(conf.Query.Get("insecure")); insecure {
+		tlsConf.InsecureSkipVerify = true
+	}
+	if ca := conf.Query.Get("ca"); len(ca) > 0 {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read ca %s", ca)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in %s", ca)
+		}
+		tlsConf.RootCAs = pool
+	}
+	return tlsConf, nil
+}
+
+// NewWriter creates the writer of the sink
+func (conf *SSinkConfig) NewWriter() (IWriter, error) {
+	switch conf.Type {
+	case SINK_SYSLOG:
+		return newSyslogWriter(conf)
+	case SINK_KAFKA:
+		return newKafkaWriter(conf)
+	default:
+		return newWebhookWriter(conf)
+	}
+}
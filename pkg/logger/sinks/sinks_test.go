@@ -0,0 +1,290 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"yunion.io/x/jsonutils"
+)
+
+func TestParseSinkUrl(t *testing.T) {
+	cases := []struct {
+		url      string
+		sinkType string
+		format   string
+		endpoint string
+		wantErr  bool
+	}{
+		{
+			url:      "syslog+tls://siem.example.com:6514?facility=local3",
+			sinkType: SINK_SYSLOG,
+			format:   FORMAT_CEF,
+			endpoint: "syslog+tls://siem.example.com:6514",
+		},
+		{
+			url:      "kafka://b1:9092,b2:9092/audit?format=json&batch=50",
+			sinkType: SINK_KAFKA,
+			format:   FORMAT_JSON,
+			endpoint: "kafka://b1:9092,b2:9092/audit",
+		},
+		{
+			url:      "https://siem.example.com/hook?token=abc&service=compute&key=1",
+			sinkType: SINK_WEBHOOK,
+			format:   FORMAT_JSON,
+			endpoint: "https://siem.example.com/hook?key=1",
+		},
+		{
+			url:     "ftp://siem.example.com",
+			wantErr: true,
+		},
+		{
+			url:     "syslog://siem.example.com?format=xml",
+			wantErr: true,
+		},
+		{
+			url:     "https://siem.example.com?success=maybe",
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		conf, err := ParseSinkUrl(c.url)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: expect error", c.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", c.url, err)
+			continue
+		}
+		if conf.Type != c.sinkType || conf.Format != c.format || conf.Url.String() != c.endpoint {
+			t.Errorf("%s: got %s %s %s", c.url, conf.Type, conf.Format, conf.Url.String())
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	conf, err := ParseSinkUrl("https://siem/hook?service=compute,image&action=delete*&success=false")
+	if err != nil {
+		t.Fatalf("ParseSinkUrl: %s", err)
+	}
+	cases := []struct {
+		record SRecord
+		want   bool
+	}{
+		{SRecord{Service: "compute", Action: "delete_server", Success: false}, true},
+		{SRecord{Service: "image", Action: "delete", Success: false}, true},
+		{SRecord{Service: "compute", Action: "delete_server", Success: true}, false},
+		{SRecord{Service: "compute", Action: "create", Success: false}, false},
+		{SRecord{Service: "identity", Action: "delete", Success: false}, false},
+	}
+	for _, c := range cases {
+		if got := conf.Filter.Match(&c.record); got != c.want {
+			t.Errorf("%s %s %v: want %v", c.record.Service, c.record.Action, c.record.Success, c.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	r := &SRecord{
+		Id:      12,
+		OpsTime: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		Service: "compute",
+		Action:  "delete",
+		ObjType: "server",
+		ObjName: "vm|1",
+		User:    "alice",
+		Notes:   "a=b\nc",
+	}
+	cef := string(r.Format(FORMAT_CEF))
+	for _, want := range []string{
+		"CEF:0|",
+		"|compute:delete|delete server|",
+		"rt=1641092645000",
+		"externalId=12",
+		"outcome=failure",
+		`cs3=vm|1`,
+		`msg=a\=b\nc`,
+	} {
+		if !strings.Contains(cef, want) {
+			t.Errorf("cef %s missing %s", cef, want)
+		}
+	}
+
+	obj, err := jsonutils.Parse(r.Format(FORMAT_JSON))
+	if err != nil {
+		t.Fatalf("parse json: %s", err)
+	}
+	if success, _ := obj.Bool("success"); success {
+		t.Errorf("json %s: success should be false", obj)
+	}
+	if user, _ := obj.GetString("user"); user != "alice" {
+		t.Errorf("json %s: user want alice", obj)
+	}
+}
+
+func TestSyslogEncode(t *testing.T) {
+	conf, err := ParseSinkUrl("syslog+udp://127.0.0.1?facility=local3&app_name=audit")
+	if err != nil {
+		t.Fatalf("ParseSinkUrl: %s", err)
+	}
+	w, err := newSyslogWriter(conf)
+	if err != nil {
+		t.Fatalf("newSyslogWriter: %s", err)
+	}
+	r := &SRecord{OpsTime: time.Now(), Success: true}
+	msg := string(w.Encode(r, []byte("payload")))
+	// local3 * 8 + informational
+	if !strings.HasPrefix(msg, "<158>1 ") {
+		t.Errorf("unexpected header %s", msg)
+	}
+	if !strings.HasSuffix(msg, " audit "+strings.Fields(msg)[4]+" actionlog - payload") {
+		t.Errorf("unexpected message %s", msg)
+	}
+}
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinks")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.spool")
+	s := newSpool(path, 64)
+	if err := s.Append([][]byte{[]byte("m1"), []byte("m2"), []byte("m3")}); err != nil {
+		t.Fatalf("Append: %s", err)
+	}
+	if err := s.Append([][]byte{make([]byte, 64)}); err == nil {
+		t.Errorf("Append should exceed the max size")
+	}
+
+	msgs, offset, err := s.Read(2)
+	if err != nil || len(msgs) != 2 || string(msgs[1]) != "m2" {
+		t.Fatalf("Read: %q %s", msgs, err)
+	}
+	if err := s.Commit(offset); err != nil {
+		t.Fatalf("Commit: %s", err)
+	}
+
+	// the committed offset survives a restart
+	s = newSpool(path, 64)
+	msgs, offset, _ = s.Read(10)
+	if len(msgs) != 1 || string(msgs[0]) != "m3" {
+		t.Fatalf("Read after commit: %q", msgs)
+	}
+	s.Commit(offset)
+	if s.Pending() {
+		t.Errorf("spool should be empty")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("spool file should be removed")
+	}
+}
+
+func TestSpoolCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinks")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.spool")
+	// 3 frames of 6 bytes fit in the limit
+	s := newSpool(path, 18)
+	s.compactMinLen = 12
+	if err := s.Append([][]byte{[]byte("m1"), []byte("m2"), []byte("m3")}); err != nil {
+		t.Fatalf("Append: %s", err)
+	}
+	_, offset, _ := s.Read(1)
+	s.Commit(offset)
+	// the consumed bytes are not counted
+	if err := s.Append([][]byte{[]byte("m4")}); err != nil {
+		t.Fatalf("Append after commit: %s", err)
+	}
+	_, offset, _ = s.Read(2)
+	if err := s.Commit(offset); err != nil {
+		t.Fatalf("Commit: %s", err)
+	}
+	// 3 of the 4 frames are consumed and compacted away
+	if size := s.size(); size != 6 {
+		t.Errorf("spool should be compacted to 6 bytes, got %d", size)
+	}
+	if _, err := os.Stat(s.offsetPath()); !os.IsNotExist(err) {
+		t.Errorf("offset should be reset")
+	}
+	msgs, _, _ := s.Read(10)
+	if len(msgs) != 1 || string(msgs[0]) != "m4" {
+		t.Fatalf("Read after compact: %q", msgs)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	bodies := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies <- string(body)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "sinks")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = Init([]string{srv.URL + "/hook?token=secret&service=compute"}, SOptions{
+		SpoolDir:   dir,
+		QueueSize:  10,
+		MaxRetries: 1,
+	})
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+	defer Init(nil, SOptions{})
+
+	Forward(&SRecord{Id: 1, Service: "identity", Action: "login"})
+	Forward(&SRecord{Id: 2, Service: "compute", Action: "create"})
+
+	select {
+	case body := <-bodies:
+		arr, err := jsonutils.ParseString(body)
+		if err != nil {
+			t.Fatalf("parse %s: %s", body, err)
+		}
+		items, _ := arr.GetArray()
+		if len(items) != 1 {
+			t.Fatalf("expect 1 record, got %s", body)
+		}
+		if id, _ := items[0].Int("id"); id != 2 {
+			t.Errorf("unexpected record %s", items[0])
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("webhook not called")
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	ErrSpoolFull = errors.Error("spool is full")
+
+	spoolFrameHeaderSize = 4
+
+	// the consumed messages are compacted away once they are more than
+	// the live ones and at least the size
+	spoolCompactMinBytes = 1 << 20
+)
+
+// sSpool is the on-disk buffer of a sink. Messages are appended as
+// length-prefixed frames and consumed from the committed offset, which is
+// persisted so that the undelivered messages survive a restart. maxBytes
+// limits the size of the messages not consumed.
+type sSpool struct {
+	lock          sync.Mutex
+	path          string
+	maxBytes      int64
+	compactMinLen int64
+}
+
+func newSpool(path string, maxBytes int64) *sSpool {
+	return &sSpool{
+		path:          path,
+		maxBytes:      maxBytes,
+		compactMinLen: spoolCompactMinBytes,
+	}
+}
+
+func (s *sSpool) offsetPath() string {
+	return s.path + ".offset"
+}
+
+func (s *sSpool) size() int64 {
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+func (s *sSpool) readOffset() int64 {
+	data, err := ioutil.ReadFile(s.offsetPath())
+	if err != nil {
+		return 0
+	}
+	offset, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return offset
+}
+
+// Pending tells whether there are messages not consumed
+func (s *sSpool) Pending() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.size() > s.readOffset()
+}
+
+func (s *sSpool) Append(msgs [][]byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	total := int64(0)
+	for _, msg := range msgs {
+		total += int64(len(msg) + spoolFrameHeaderSize)
+	}
+	if s.maxBytes > 0 && s.size()-s.readOffset()+total > s.maxBytes {
+		return errors.Wrapf(ErrSpoolFull, "%s exceeds %d bytes", s.path, s.maxBytes)
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrap(err, "open spool")
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	header := make([]byte, spoolFrameHeaderSize)
+	for _, msg := range msgs {
+		binary.BigEndian.PutUint32(header, uint32(len(msg)))
+		w.Write(header)
+		w.Write(msg)
+	}
+	err = w.Flush()
+	if err != nil {
+		return errors.Wrap(err, "write spool")
+	}
+	return nil
+}
+
+// Read returns at most max messages from the committed offset and the offset
+// after them, which should be committed once the messages are delivered
+func (s *sSpool) Read(max int) ([][]byte, int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	offset := s.readOffset()
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, errors.Wrap(err, "open spool")
+	}
+	defer f.Close()
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "seek spool")
+	}
+	r := bufio.NewReader(f)
+	msgs := make([][]byte, 0)
+	header := make([]byte, spoolFrameHeaderSize)
+	for len(msgs) < max {
+		_, err = io.ReadFull(r, header)
+		if err == nil {
+			msg := make([]byte, binary.BigEndian.Uint32(header))
+			_, err = io.ReadFull(r, msg)
+			if err == nil {
+				msgs = append(msgs, msg)
+				offset += int64(spoolFrameHeaderSize + len(msg))
+				continue
+			}
+		}
+		if err != io.EOF && len(msgs) == 0 {
+			// skip the truncated frame written by an interrupted append
+			log.Warningf("skip truncated frame at %d of spool %s", offset, s.path)
+			offset = s.size()
+		}
+		break
+	}
+	return msgs, offset, nil
+}
+
+// Commit marks the messages before offset as delivered, the spool is
+// removed when all messages are delivered
+func (s *sSpool) Commit(offset int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	size := s.size()
+	if offset >= size {
+		os.Remove(s.offsetPath())
+		err := os.Remove(s.path)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "remove spool")
+		}
+		return nil
+	}
+	if offset >= s.compactMinLen && offset > size-offset {
+		return s.compact(offset)
+	}
+	return ioutil.WriteFile(s.offsetPath(), []byte(strconv.FormatInt(offset, 10)), 0600)
+}
+
+// compact rewrites the spool with the messages after offset only
+func (s *sSpool) compact(offset int64) error {
+	src, err := os.Open(s.path)
+	if err != nil {
+		return errors.Wrap(err, "open spool")
+	}
+	defer src.Close()
+	_, err = src.Seek(offset, io.SeekStart)
+	if err != nil {
+		return errors.Wrap(err, "seek spool")
+	}
+	tmpPath := s.path + ".tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.Wrap(err, "create compacted spool")
+	}
+	_, err = io.Copy(dst, src)
+	if err == nil {
+		err = dst.Sync()
+	}
+	dst.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return errors.Wrap(err, "write compacted spool")
+	}
+	// reset the offset before replacing the spool, a crash in between
+	// redelivers the consumed messages rather than loses any
+	err = os.Remove(s.offsetPath())
+	if err != nil && !os.IsNotExist(err) {
+		os.Remove(tmpPath)
+		return errors.Wrap(err, "remove spool offset")
+	}
+	err = os.Rename(tmpPath, s.path)
+	if err != nil {
+		os.Remove(tmpPath)
+		return errors.Wrap(err, "rename compacted spool")
+	}
+	return nil
+}
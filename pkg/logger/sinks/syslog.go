@@ -0,0 +1,194 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+const (
+	syslogTimeout    = 10 * time.Second
+	syslogNilValue   = "-"
+	syslogMsgId      = "actionlog"
+	syslogDefaultApp = "cloudaudit"
+)
+
+// sSyslogWriter sends RFC 5424 messages, the messages are framed by octet
+// counting over TCP and TLS (RFC 6587, RFC 5425), one datagram per message over UDP
+type sSyslogWriter struct {
+	network  string
+	addr     string
+	tlsConf  *tls.Config
+	facility int
+	hostname string
+	appName  string
+
+	conn net.Conn
+}
+
+func newSyslogWriter(conf *SSinkConfig) (*sSyslogWriter, error) {
+	w := &sSyslogWriter{
+		addr:     conf.Url.Host,
+		facility: syslogFacilities["local0"],
+		appName:  syslogDefaultApp,
+	}
+	switch conf.Url.Scheme {
+	case "syslog+udp":
+		w.network = "udp"
+	case "syslog", "syslog+tcp":
+		w.network = "tcp"
+	case "syslog+tls":
+		w.network = "tcp"
+		tlsConf, err := conf.tlsConfig(conf.Url.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		w.tlsConf = tlsConf
+	default:
+		return nil, errors.Errorf("unsupported syslog scheme %s", conf.Url.Scheme)
+	}
+	if len(conf.Url.Port()) == 0 {
+		port := "514"
+		if w.tlsConf != nil {
+			port = "6514"
+		}
+		w.addr = net.JoinHostPort(conf.Url.Hostname(), port)
+	}
+	if facility := conf.Query.Get("facility"); len(facility) > 0 {
+		f, ok := syslogFacilities[facility]
+		if !ok {
+			return nil, errors.Errorf("unknown syslog facility %s", facility)
+		}
+		w.facility = f
+	}
+	if appName := conf.Query.Get("app_name"); len(appName) > 0 {
+		w.appName = appName
+	}
+	w.hostname, _ = os.Hostname()
+	return w, nil
+}
+
+// syslogField returns the value of header field, which is printable ascii without space
+func syslogField(s string, maxLen int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) == 0 {
+		return syslogNilValue
+	}
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+	return s
+}
+
+// Encode formats the message as
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (w *sSyslogWriter) Encode(r *SRecord, payload []byte) []byte {
+	pri := w.facility*8 + r.syslogSeverity()
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s %s ",
+		pri,
+		r.OpsTime.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogField(w.hostname, 255),
+		syslogField(w.appName, 48),
+		os.Getpid(),
+		syslogMsgId,
+		syslogNilValue,
+	)
+	return append([]byte(header), payload...)
+}
+
+func (w *sSyslogWriter) connect(ctx context.Context) error {
+	if w.conn != nil {
+		return nil
+	}
+	dialer := &net.Dialer{Timeout: syslogTimeout}
+	var conn net.Conn
+	var err error
+	if w.tlsConf != nil {
+		conn, err = tls.DialWithDialer(dialer, w.network, w.addr, w.tlsConf)
+	} else {
+		conn, err = dialer.DialContext(ctx, w.network, w.addr)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "dial %s %s", w.network, w.addr)
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *sSyslogWriter) Write(ctx context.Context, msgs [][]byte) error {
+	err := w.connect(ctx)
+	if err != nil {
+		return err
+	}
+	w.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+	for _, msg := range msgs {
+		if w.network == "tcp" {
+			msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+		}
+		_, err = w.conn.Write(msg)
+		if err != nil {
+			// reconnect on next write
+			w.Close()
+			return errors.Wrapf(err, "write to %s", w.addr)
+		}
+	}
+	return nil
+}
+
+func (w *sSyslogWriter) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
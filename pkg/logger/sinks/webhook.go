@@ -0,0 +1,90 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const webhookTimeout = 30 * time.Second
+
+// sWebhookWriter posts the messages of a batch in one request, as a JSON
+// array in json format or newline separated text in cef format
+type sWebhookWriter struct {
+	url    string
+	token  string
+	format string
+	client *http.Client
+}
+
+func newWebhookWriter(conf *SSinkConfig) (*sWebhookWriter, error) {
+	insecure, _ := strconv.ParseBool(conf.Query.Get("insecure"))
+	return &sWebhookWriter{
+		url:    conf.Url.String(),
+		token:  conf.Query.Get("token"),
+		format: conf.Format,
+		client: httputils.GetClient(insecure, webhookTimeout),
+	}, nil
+}
+
+func (w *sWebhookWriter) Encode(r *SRecord, payload []byte) []byte {
+	return payload
+}
+
+func (w *sWebhookWriter) Write(ctx context.Context, msgs [][]byte) error {
+	var body []byte
+	contentType := "application/json"
+	if w.format == FORMAT_JSON {
+		body = append([]byte("["), bytes.Join(msgs, []byte(","))...)
+		body = append(body, ']')
+	} else {
+		contentType = "text/plain; charset=utf-8"
+		body = append(bytes.Join(msgs, []byte("\n")), '\n')
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "NewRequest")
+	}
+	req.Header.Set("Content-Type", contentType)
+	if len(w.token) > 0 {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", w.token))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "post webhook")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("webhook response %d: %s", resp.StatusCode, msg)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+func (w *sWebhookWriter) Close() error {
+	return nil
+}
@@ -15,6 +15,7 @@
 package identity
 
 import (
+	"crypto/rand"
 	"encoding/base64"
 	"fmt"
 	"net/url"
@@ -45,6 +46,12 @@ const (
 	RECOVERY_SECRETS_TYPE = api.RECOVERY_SECRETS_TYPE
 	OIDC_CREDENTIAL_TYPE  = api.OIDC_CREDENTIAL_TYPE
 	ENCRYPT_KEY_TYPE      = api.ENCRYPT_KEY_TYPE
+
+	WEBAUTHN_CREDENTIAL_TYPE = api.WEBAUTHN_CREDENTIAL_TYPE
+	RECOVERY_CODES_TYPE      = api.RECOVERY_CODES_TYPE
+
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeLength   = 10
 )
 
 type STotpSecret struct {
@@ -69,6 +76,13 @@ type SRecoverySecretSet struct {
 	Timestamp int64
 }
 
+type SWebAuthnCredential struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	api.SWebAuthnCredentialBlob
+}
+
 type SOpenIDConnectCredential struct {
 	ClientId string `json:"client_id"`
 	// Secret      string `json:"secret"`
@@ -512,6 +526,179 @@ func (manager *SCredentialManager) SaveRecoverySecrets(s *mcclient.ClientSession
 	return nil
 }
 
+func (manager *SCredentialManager) GetWebAuthnCredentials(s *mcclient.ClientSession, uid string) ([]SWebAuthnCredential, error) {
+	secrets, err := manager.fetchCredentials(s, WEBAUTHN_CREDENTIAL_TYPE, uid, "")
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]SWebAuthnCredential, 0, len(secrets))
+	for i := range secrets {
+		blobStr, _ := secrets[i].GetString("blob")
+		blobJson, _ := jsonutils.ParseString(blobStr)
+		if blobJson == nil {
+			continue
+		}
+		cred := SWebAuthnCredential{}
+		blobJson.Unmarshal(&cred.SWebAuthnCredentialBlob)
+		cred.Id, _ = secrets[i].GetString("id")
+		cred.Name, _ = secrets[i].GetString("name")
+		cred.CreatedAt, _ = secrets[i].GetTime("created_at")
+		ret = append(ret, cred)
+	}
+	return ret, nil
+}
+
+func (manager *SCredentialManager) CreateWebAuthnCredential(s *mcclient.ClientSession, uid string, name string, blob api.SWebAuthnCredentialBlob) (SWebAuthnCredential, error) {
+	cred := SWebAuthnCredential{SWebAuthnCredentialBlob: blob}
+	cred.Timestamp = time.Now().Unix()
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.NewString(DEFAULT_PROJECT), "project_id")
+	params.Add(jsonutils.NewString(WEBAUTHN_CREDENTIAL_TYPE), "type")
+	params.Add(jsonutils.NewString(uid), "user_id")
+	params.Add(jsonutils.NewString(jsonutils.Marshal(&cred.SWebAuthnCredentialBlob).String()), "blob")
+	if len(name) > 0 {
+		params.Add(jsonutils.NewString(name), "generate_name")
+	}
+	result, err := manager.Create(s, params)
+	if err != nil {
+		return cred, errors.Wrap(err, "Create")
+	}
+	cred.Id, _ = result.GetString("id")
+	cred.Name, _ = result.GetString("name")
+	cred.CreatedAt, _ = result.GetTime("created_at")
+	return cred, nil
+}
+
+// UpdateWebAuthnCredential saves the sign count and last used time of the credential
+func (manager *SCredentialManager) UpdateWebAuthnCredential(s *mcclient.ClientSession, cred SWebAuthnCredential) error {
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.NewString(jsonutils.Marshal(&cred.SWebAuthnCredentialBlob).String()), "blob")
+	_, err := manager.Update(s, cred.Id, params)
+	return err
+}
+
+func (manager *SCredentialManager) RemoveWebAuthnCredential(s *mcclient.ClientSession, uid string, id string) error {
+	creds, err := manager.GetWebAuthnCredentials(s, uid)
+	if err != nil {
+		return err
+	}
+	for i := range creds {
+		if creds[i].Id == id {
+			_, err := manager.Delete(s, id, nil)
+			return err
+		}
+	}
+	return httperrors.NewResourceNotFoundError2(WEBAUTHN_CREDENTIAL_TYPE, id)
+}
+
+func (manager *SCredentialManager) RemoveWebAuthnCredentials(s *mcclient.ClientSession, uid string) error {
+	return manager.removeCredentials(s, WEBAUTHN_CREDENTIAL_TYPE, uid, "")
+}
+
+// randomRecoveryCode picks the characters of code uniformly from the alphabet,
+// the random bytes beyond the largest multiple of the alphabet size are rejected
+func randomRecoveryCode() (string, error) {
+	limit := 256 - 256%len(recoveryCodeAlphabet)
+	code := make([]byte, 0, recoveryCodeLength)
+	buf := make([]byte, recoveryCodeLength)
+	for len(code) < recoveryCodeLength {
+		_, err := rand.Read(buf)
+		if err != nil {
+			return "", errors.Wrap(err, "rand.Read")
+		}
+		for _, b := range buf {
+			if int(b) >= limit || len(code) >= recoveryCodeLength {
+				continue
+			}
+			code = append(code, recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+		}
+	}
+	return fmt.Sprintf("%s-%s", code[:recoveryCodeLength/2], code[recoveryCodeLength/2:]), nil
+}
+
+func (manager *SCredentialManager) fetchRecoveryCodes(s *mcclient.ClientSession, uid string) (string, api.SRecoveryCodesBlob, error) {
+	secrets, err := manager.fetchCredentials(s, RECOVERY_CODES_TYPE, uid, "")
+	if err != nil {
+		return "", api.SRecoveryCodesBlob{}, err
+	}
+	latestId, latest := "", api.SRecoveryCodesBlob{}
+	for i := range secrets {
+		blobStr, _ := secrets[i].GetString("blob")
+		blobJson, _ := jsonutils.ParseString(blobStr)
+		if blobJson != nil {
+			curr := api.SRecoveryCodesBlob{}
+			blobJson.Unmarshal(&curr)
+			if len(latestId) == 0 || curr.Timestamp > latest.Timestamp {
+				latestId, _ = secrets[i].GetString("id")
+				latest = curr
+			}
+		}
+	}
+	if len(latestId) == 0 {
+		return "", latest, httperrors.NewNotFoundError("no recovery codes for %s", uid)
+	}
+	return latestId, latest, nil
+}
+
+// CreateRecoveryCodes replaces the recovery codes of user and returns the plain codes,
+// which are only shown to user once
+func (manager *SCredentialManager) CreateRecoveryCodes(s *mcclient.ClientSession, uid string, count int) ([]string, error) {
+	err := manager.removeCredentials(s, RECOVERY_CODES_TYPE, uid, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "remove recovery codes")
+	}
+	codes := make([]string, count)
+	blob := api.SRecoveryCodesBlob{
+		Codes:     make([]string, count),
+		Timestamp: time.Now().Unix(),
+	}
+	for i := range codes {
+		codes[i], err = randomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		blob.Codes[i] = api.HashRecoveryCode(codes[i])
+	}
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.NewString(DEFAULT_PROJECT), "project_id")
+	params.Add(jsonutils.NewString(RECOVERY_CODES_TYPE), "type")
+	params.Add(jsonutils.NewString(uid), "user_id")
+	params.Add(jsonutils.NewString(jsonutils.Marshal(&blob).String()), "blob")
+	_, err = manager.Create(s, params)
+	if err != nil {
+		return nil, errors.Wrap(err, "Create")
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode consumes a recovery code of user and returns the number of remaining codes,
+// the code is checked and removed by keystone atomically
+func (manager *SCredentialManager) UseRecoveryCode(s *mcclient.ClientSession, uid string, code string) (int, error) {
+	id, _, err := manager.fetchRecoveryCodes(s, uid)
+	if err != nil {
+		return 0, err
+	}
+	input := api.CredentialUseRecoveryCodeInput{Code: code}
+	result, err := manager.PerformAction(s, id, "use-recovery-code", jsonutils.Marshal(input))
+	if err != nil {
+		return 0, err
+	}
+	output := api.CredentialUseRecoveryCodeOutput{}
+	err = result.Unmarshal(&output)
+	if err != nil {
+		return 0, errors.Wrap(err, "Unmarshal")
+	}
+	return output.Left, nil
+}
+
+func (manager *SCredentialManager) CountRecoveryCodes(s *mcclient.ClientSession, uid string) (int, error) {
+	_, blob, err := manager.fetchRecoveryCodes(s, uid)
+	if err != nil {
+		return 0, err
+	}
+	return len(blob.Codes), nil
+}
+
 func (manager *SCredentialManager) DoCreateEncryptKey(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	name, _ := params.GetString("name")
 	alg, _ := params.GetString("alg")
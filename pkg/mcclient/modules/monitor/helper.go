@@ -190,6 +190,26 @@ func (c *AlertCondition) Median() *AlertCondition {
 	return c.setReducer("median")
 }
 
+func (c *AlertCondition) StddevDeviation(window int) *AlertCondition {
+	return c.setReducer("stddev_deviation", float64(window))
+}
+
+func (c *AlertCondition) Rate() *AlertCondition {
+	return c.setReducer("rate")
+}
+
+func (c *AlertCondition) Derivative() *AlertCondition {
+	return c.setReducer("derivative")
+}
+
+func (c *AlertCondition) PercentChange(window time.Duration) *AlertCondition {
+	return c.setReducer("percent_change", window.Seconds())
+}
+
+func (c *AlertCondition) PredictLinear(after time.Duration) *AlertCondition {
+	return c.setReducer("predict_linear", after.Seconds())
+}
+
 func (c *AlertCondition) setEvaluator(typ string, threshold float64) *AlertCondition {
 	c.evaluator = &monitor.Condition{
 		Type:   typ,
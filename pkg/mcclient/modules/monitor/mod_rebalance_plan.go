@@ -0,0 +1,45 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+var (
+	RebalancePlanManager *SRebalancePlanManager
+)
+
+type SRebalancePlanManager struct {
+	*modulebase.ResourceManager
+}
+
+func init() {
+	RebalancePlanManager = NewRebalancePlanManager()
+	modules.Register(RebalancePlanManager)
+	RebalancePlanManager.SetApiVersion(mcclient.V2_API_VERSION)
+	modules.RegisterV2(RebalancePlanManager)
+}
+
+func NewRebalancePlanManager() *SRebalancePlanManager {
+	m := modules.NewMonitorV2Manager("rebalanceplan", "rebalanceplans",
+		[]string{"id", "name", "status", "metric_type", "auto_execute", "max_concurrency", "imbalance_before", "imbalance_after", "step_count"},
+		[]string{"steps"})
+	return &SRebalancePlanManager{
+		ResourceManager: &m,
+	}
+}
@@ -59,6 +59,10 @@ func (this *QuotaManager) getURL2(params jsonutils.JSONObject, extra string) str
 		if primary {
 			query.Add(jsonutils.JSONTrue, "primary")
 		}
+		days, _ := params.Int("days")
+		if days > 0 {
+			query.Add(jsonutils.NewInt(days), "days")
+		}
 	}
 	if len(extra) > 0 {
 		url = httputils.JoinPath(url, extra)
@@ -133,6 +137,49 @@ func (this *QuotaManager) DoQuotaSet(s *mcclient.ClientSession, params jsonutils
 	return ret, nil
 }
 
+func (this *QuotaManager) GetQuotaTrend(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return modulebase.Get(this.ResourceManager, s, this.getURL2(params, "trend"), this.Keyword)
+}
+
+func (this *QuotaManager) CreateChangeRequest(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	url := this.getURL2(params, "requests")
+	body := jsonutils.NewDict()
+	body.Add(params, this.KeywordPlural)
+	return modulebase.Post(this.ResourceManager, s, url, body, "quota_change_request")
+}
+
+func (this *QuotaManager) ListChangeRequests(s *mcclient.ClientSession, params jsonutils.JSONObject) (*modulebase.ListResult, error) {
+	url := fmt.Sprintf("/%s/requests", this.URLPath())
+	if params != nil {
+		if dict, ok := params.(*jsonutils.JSONDict); ok && dict.Size() > 0 {
+			url += "?" + dict.QueryString()
+		}
+	}
+	return modulebase.List(this.ResourceManager, s, url, "quota_change_requests")
+}
+
+func (this *QuotaManager) GetChangeRequest(s *mcclient.ClientSession, id string) (jsonutils.JSONObject, error) {
+	url := fmt.Sprintf("/%s/requests/%s", this.URLPath(), id)
+	return modulebase.Get(this.ResourceManager, s, url, "quota_change_request")
+}
+
+func (this *QuotaManager) ApproveChangeRequest(s *mcclient.ClientSession, id string, comment string) (jsonutils.JSONObject, error) {
+	return this.reviewChangeRequest(s, id, "approve", comment)
+}
+
+func (this *QuotaManager) RejectChangeRequest(s *mcclient.ClientSession, id string, comment string) (jsonutils.JSONObject, error) {
+	return this.reviewChangeRequest(s, id, "reject", comment)
+}
+
+func (this *QuotaManager) reviewChangeRequest(s *mcclient.ClientSession, id string, action string, comment string) (jsonutils.JSONObject, error) {
+	url := fmt.Sprintf("/%s/requests/%s/%s", this.URLPath(), id, action)
+	body := jsonutils.NewDict()
+	if len(comment) > 0 {
+		body.Add(jsonutils.NewString(comment), "comment")
+	}
+	return modulebase.Post(this.ResourceManager, s, url, body, "quota_change_request")
+}
+
 var (
 	Quotas        QuotaManager
 	ProjectQuotas QuotaManager
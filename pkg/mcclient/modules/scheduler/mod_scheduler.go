@@ -92,9 +92,43 @@ func (this *SchedulerManager) Test(s *mcclient.ClientSession, params *api.Schedu
 }
 
 func (this *SchedulerManager) DoForecast(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	data := params.(*jsonutils.JSONDict)
+	if err := fillProjectDomain(s, data); err != nil {
+		return nil, err
+	}
+	url := newSchedURL("forecast")
+	_, obj, err := modulebase.JsonRequest(this.ResourceManager, s, "POST", url, nil, data)
+	if err != nil {
+		return nil, err
+	}
+	return obj, err
+}
+
+// DoSimulate schedules a batch of hypothetical guests one after another
+// without reserving any resource
+func (this *SchedulerManager) DoSimulate(s *mcclient.ClientSession, specs []*api.ScheduleInput) (jsonutils.JSONObject, error) {
+	specsJson := jsonutils.NewArray()
+	for _, spec := range specs {
+		data := spec.JSON(spec)
+		if err := fillProjectDomain(s, data); err != nil {
+			return nil, err
+		}
+		specsJson.Add(data)
+	}
+	body := jsonutils.NewDict()
+	body.Set("specs", specsJson)
+	url := newSchedURL("simulate")
+	_, obj, err := modulebase.JsonRequest(this.ResourceManager, s, "POST", url, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return obj, err
+}
+
+func fillProjectDomain(s *mcclient.ClientSession, data *jsonutils.JSONDict) error {
 	projectId := s.GetProjectId()
 	domainId := s.GetProjectDomainId()
-	cliProjectId, _ := params.GetString("project_id")
+	cliProjectId, _ := data.GetString("project_id")
 	if cliProjectId != "" {
 		projectId = cliProjectId
 		domainId = ""
@@ -103,19 +137,13 @@ func (this *SchedulerManager) DoForecast(s *mcclient.ClientSession, params jsonu
 		adminSession := auth.GetAdminSession(context.TODO(), "", "")
 		ret, err := identity.Projects.Get(adminSession, projectId, nil)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		domainId, _ = ret.GetString("domain_id")
 	}
-	data := params.(*jsonutils.JSONDict)
 	data.Set("domain_id", jsonutils.NewString(domainId))
 	data.Set("project_id", jsonutils.NewString(projectId))
-	url := newSchedURL("forecast")
-	_, obj, err := modulebase.JsonRequest(this.ResourceManager, s, "POST", url, nil, data)
-	if err != nil {
-		return nil, err
-	}
-	return obj, err
+	return nil
 }
 
 func (this *SchedulerManager) Cleanup(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
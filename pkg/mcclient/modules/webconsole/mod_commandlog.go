@@ -37,7 +37,7 @@ func NewCommandLogManager() *CommandLogManager {
 		modulebase.ResourceManager{
 			BaseManager: *modulebase.NewBaseManager("webconsole", "", "webconsole", []string{
 				"id", "ops_time", "obj_id", "obj_type", "obj_name", "user", "user_id", "tenant", "tenant_id", "owner_tenant_id", "action", "notes",
-				"session_id", "accessed_at", "type", "login_user", "start_time", "ps1", "command",
+				"session_id", "accessed_at", "type", "login_user", "start_time", "ps1", "command", "recording_id",
 			}, nil),
 			Keyword: "commandlog", KeywordPlural: "commandlogs",
 		},
@@ -0,0 +1,45 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webconsole
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+)
+
+var (
+	SessionRecording *SessionRecordingManager
+)
+
+func init() {
+	SessionRecording = NewSessionRecordingManager()
+
+	modulebase.Register("v1", SessionRecording)
+}
+
+type SessionRecordingManager struct {
+	modulebase.ResourceManager
+}
+
+func NewSessionRecordingManager() *SessionRecordingManager {
+	return &SessionRecordingManager{
+		modulebase.ResourceManager{
+			BaseManager: *modulebase.NewBaseManager("webconsole", "", "webconsole", []string{
+				"id", "name", "status", "session_id", "session_type", "obj_id", "obj_type", "obj_name", "login_user",
+				"owner_name", "tenant", "start_time", "end_time", "size_bytes", "storage_type",
+			}, nil),
+			Keyword: "session_recording", KeywordPlural: "session_recordings",
+		},
+	}
+}
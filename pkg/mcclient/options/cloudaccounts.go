@@ -1110,6 +1110,33 @@ func (opts *SBingoCloudAccountUpdateCredentialOptions) Params() (jsonutils.JSONO
 	return jsonutils.Marshal(opts.SAccessKeyCredential), nil
 }
 
+type SMockCloudAccountCreateOptions struct {
+	SCloudAccountCreateBaseOptions
+	ACCOUNT string `help:"Account id of the in-memory state store" json:"access_key_id"`
+	Fixture string `help:"Path of the json fixture seeding the state store" json:"endpoint"`
+}
+
+func (opts *SMockCloudAccountCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts)
+	params.(*jsonutils.JSONDict).Add(jsonutils.NewString(api.CLOUD_PROVIDER_MOCKCLOUD), "provider")
+	return params, nil
+}
+
+type SMockCloudAccountUpdateOptions struct {
+	SCloudAccountUpdateBaseOptions
+}
+
+type SMockCloudAccountUpdateCredentialOptions struct {
+	SCloudAccountIdOptions
+	ACCOUNT string `help:"Account id of the in-memory state store" json:"access_key_id"`
+}
+
+func (opts *SMockCloudAccountUpdateCredentialOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.NewString(opts.ACCOUNT), "access_key_id")
+	return params, nil
+}
+
 type SInCloudSphereAccountCreateOptions struct {
 	SCloudAccountCreateBaseOptions
 	HOST string
@@ -56,6 +56,7 @@ type DiskBackupCreateOptions struct {
 	options.BaseCreateOptions
 	DISKID          string `help:"disk id" json:"disk_id"`
 	BACKUPSTORAGEID string `help:"back storage id" json:"backup_storage_id"`
+	Incremental     bool   `help:"backup incrementally on the latest backup of the disk" json:"incremental"`
 }
 
 func (opts *DiskBackupCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -111,10 +112,15 @@ func (opts *BackupStorageIdOptions) Params() (jsonutils.JSONObject, error) {
 
 type BackupStorageCreateOptions struct {
 	options.BaseCreateOptions
-	StorageType  string `help:"storage type" choices:"nfs"`
-	NfsHost      string `help:"nfs host, required when storage_type is nfs"`
-	NfsSharedDir string `help:"nfs shared dir, required when storage_type is nfs" `
-	CapacityMb   int    `help:"capacity, unit mb"`
+	StorageType     string `help:"storage type" choices:"nfs|local|object"`
+	NfsHost         string `help:"nfs host, required when storage_type is nfs"`
+	NfsSharedDir    string `help:"nfs shared dir, required when storage_type is nfs" `
+	LocalPath       string `help:"directory on hosts, required when storage_type is local"`
+	ObjectEndpoint  string `help:"endpoint of S3 compatible object storage, required when storage_type is object"`
+	ObjectBucket    string `help:"bucket of object storage, required when storage_type is object"`
+	ObjectAccessKey string `help:"access key of object storage"`
+	ObjectSecret    string `help:"secret of object storage"`
+	CapacityMb      int    `help:"capacity, unit mb"`
 }
 
 func (opts *BackupStorageCreateOptions) Params() (jsonutils.JSONObject, error) {
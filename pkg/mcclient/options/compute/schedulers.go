@@ -15,6 +15,11 @@
 package compute
 
 import (
+	"io/ioutil"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
 	"yunion.io/x/onecloud/pkg/apis/scheduler"
 	"yunion.io/x/onecloud/pkg/mcclient"
 )
@@ -99,3 +104,34 @@ func (o SchedulerForecastOptions) Params(s *mcclient.ClientSession) (*scheduler.
 	input.ScheduleBaseConfig = *opts
 	return input, nil
 }
+
+type SchedulerSimulateOptions struct {
+	SchedulerTestBaseOptions
+	SpecFile string `help:"JSON or YAML file of a list of specs simulated one after another, the options describe the only spec if not set"`
+}
+
+func (o SchedulerSimulateOptions) Params(s *mcclient.ClientSession) ([]*scheduler.ScheduleInput, error) {
+	if len(o.SpecFile) == 0 {
+		data, err := o.data(s)
+		if err != nil {
+			return nil, err
+		}
+		input := new(scheduler.ScheduleInput)
+		input.ServerConfig = *data
+		input.ScheduleBaseConfig = *o.options()
+		return []*scheduler.ScheduleInput{input}, nil
+	}
+	content, err := ioutil.ReadFile(o.SpecFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", o.SpecFile)
+	}
+	obj, err := jsonutils.ParseYAML(string(content))
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s", o.SpecFile)
+	}
+	specs := make([]*scheduler.ScheduleInput, 0)
+	if err := obj.Unmarshal(&specs); err != nil {
+		return nil, errors.Wrap(err, "unmarshal specs")
+	}
+	return specs, nil
+}
@@ -792,6 +792,17 @@ func (o *ServerMonitorOptions) Params() (jsonutils.JSONObject, error) {
 	return options.StructToParams(o)
 }
 
+type ServerQgaSetPasswordOptions struct {
+	ServerIdOptions
+
+	Username string `help:"Login user name, default is the login account of the server"`
+	PASSWORD string `help:"New password"`
+}
+
+func (o *ServerQgaSetPasswordOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(o)
+}
+
 type ServerSaveImageOptions struct {
 	ServerIdOptions
 	IMAGE     string `help:"Image name" json:"name"`
@@ -968,6 +979,14 @@ type ServerLiveMigrateOptions struct {
 	SkipCpuCheck    *bool  `help:"Skip check CPU mode of the target host" json:"skip_cpu_check"`
 	SkipKernelCheck *bool  `help:"Skip target kernel version check" json:"skip_kernel_check"`
 	EnableTLS       *bool  `help:"Enable tls migration" json:"enable_tls"`
+
+	MultifdChannels      int    `help:"Number of multifd channels, 0 means disable multifd" json:"multifd_channels"`
+	Compression          string `help:"Multifd compression method" choices:"zstd" json:"compression"`
+	XbzrleCacheSizeMb    int    `help:"Xbzrle cache size in MB, 0 means disable xbzrle" json:"xbzrle_cache_size_mb"`
+	AutoConverge         *bool  `help:"Enable auto-converge, default is true" json:"auto_converge"`
+	MaxBandwidthMb       int    `help:"Max migration bandwidth in MB/s" json:"max_bandwidth_mb"`
+	MaxDowntimeMs        int    `help:"Max allowed downtime in milliseconds" json:"max_downtime_ms"`
+	PostcopyAfterSeconds int    `help:"Switch to postcopy after ram copying for seconds, 0 means disable postcopy" json:"postcopy_after_seconds"`
 }
 
 func (o *ServerLiveMigrateOptions) GetId() string {
@@ -16,6 +16,7 @@ package monitor
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -87,7 +88,7 @@ func (o AlertPauseOptions) Description() string {
 }
 
 type AlertConditionOptions struct {
-	REDUCER    string   `help:"Metric query reducer, e.g. 'avg'" choices:"avg|sum|min|max|count|last|median"`
+	REDUCER    string   `help:"Metric query reducer, e.g. 'avg'" choices:"avg|sum|min|max|count|last|median|stddev_deviation|rate|derivative|percent_change|predict_linear"`
 	DATABASE   string   `help:"Metric database, e.g. 'telegraf'"`
 	METRIC     string   `help:"Query metric format <measurement>.<field>, e.g. 'cpu.cpu_usage'"`
 	COMPARATOR string   `help:"Evaluator compare" choices:"gt|lt"`
@@ -95,6 +96,9 @@ type AlertConditionOptions struct {
 	Period     string   `help:"Query metric period e.g. '5m', '1h'" default:"5m"`
 	Tag        []string `help:"Query tag, e.g. 'zone=zon0,name=vmname'"`
 	For        string   `help:"For time duration"`
+
+	ReducerWindow string `help:"Baseline of percent_change reducer, e.g. '1h', or number of points of stddev_deviation reducer"`
+	PredictAfter  string `help:"How far predict_linear reducer predicts, e.g. '24h'" default:"1h"`
 }
 
 func (opt AlertConditionOptions) Params(conf *monitor2.AlertConfig) (*monitor2.AlertCondition, error) {
@@ -124,6 +128,36 @@ func (opt AlertConditionOptions) Params(conf *monitor2.AlertConfig) (*monitor2.A
 		cond.Last()
 	case "median":
 		cond.Median()
+	case "stddev_deviation":
+		window := 0
+		if len(opt.ReducerWindow) > 0 {
+			var err error
+			window, err = strconv.Atoi(opt.ReducerWindow)
+			if err != nil {
+				return nil, fmt.Errorf("invalid reducer window %s: %v", opt.ReducerWindow, err)
+			}
+		}
+		cond.StddevDeviation(window)
+	case "rate":
+		cond.Rate()
+	case "derivative":
+		cond.Derivative()
+	case "percent_change":
+		var window time.Duration
+		if len(opt.ReducerWindow) > 0 {
+			var err error
+			window, err = time.ParseDuration(opt.ReducerWindow)
+			if err != nil {
+				return nil, fmt.Errorf("invalid reducer window %s: %v", opt.ReducerWindow, err)
+			}
+		}
+		cond.PercentChange(window)
+	case "predict_linear":
+		after, err := time.ParseDuration(opt.PredictAfter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid predict after %s: %v", opt.PredictAfter, err)
+		}
+		cond.PredictLinear(after)
 	}
 
 	q := cond.Query().From(opt.Period)
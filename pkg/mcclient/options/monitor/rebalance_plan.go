@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type RebalancePlanListOptions struct {
+	options.BaseListOptions
+	MetricType []string `help:"Rebalance plan metric type" choices:"cpu.usage_active|mem.available"`
+}
+
+func (o *RebalancePlanListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(o)
+}
+
+type RebalancePlanShowOptions struct {
+	ID string `help:"ID or name of rebalance plan" json:"-"`
+}
+
+func (o *RebalancePlanShowOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(o)
+}
+
+func (o *RebalancePlanShowOptions) GetId() string {
+	return o.ID
+}
+
+type RebalancePlanApproveOptions struct {
+	RebalancePlanShowOptions
+	MaxConcurrency int `help:"Max number of migrations running at the same time"`
+}
+
+func (o *RebalancePlanApproveOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(o)
+}
+
+type RebalancePlanRejectOptions struct {
+	RebalancePlanShowOptions
+	Reason string `help:"Reason of rejection"`
+}
+
+func (o *RebalancePlanRejectOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(o)
+}
@@ -23,7 +23,7 @@ import (
 type RobotListOptions struct {
 	options.BaseListOptions
 	Lang    string
-	Type    string `choices:"feishu|dingtalk|workwx|webhook"`
+	Type    string `choices:"feishu|dingtalk|workwx|webhook|slack|teams|telegram"`
 	Enabled *bool
 }
 
@@ -33,7 +33,7 @@ func (rl *RobotListOptions) Params() (jsonutils.JSONObject, error) {
 
 type RobotCreateOptions struct {
 	NAME    string
-	Type    string `choices:"feishu|dingtalk|workwx|webhook"`
+	Type    string `choices:"feishu|dingtalk|workwx|webhook|slack|teams|telegram"`
 	Address string
 	Lang    string
 }
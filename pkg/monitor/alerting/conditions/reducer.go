@@ -218,6 +218,10 @@ var percentDiff = func(newest, oldest float64) float64 {
 }
 
 func NewAlertReducer(cond *monitor.Condition) (Reducer, error) {
+	if isTrendReducer(cond.Type) {
+		return newTrendReducer(cond)
+	}
+
 	if len(cond.Operators) == 0 {
 		return newSimpleReducer(cond), nil
 	}
@@ -0,0 +1,266 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conditions
+
+import (
+	"math"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+	"yunion.io/x/onecloud/pkg/monitor/validators"
+)
+
+const (
+	// REDUCER_STDDEV_DEVIATION reduces to the distance, in standard
+	// deviations, between the latest value and the mean of the values
+	// before it. Params: [window], the number of points before the latest
+	// one used as baseline, all of them when omitted or 0.
+	REDUCER_STDDEV_DEVIATION = "stddev_deviation"
+	// REDUCER_RATE reduces to the per-second increase of a counter,
+	// counter resets are taken into account.
+	REDUCER_RATE = "rate"
+	// REDUCER_DERIVATIVE reduces to the per-second slope of the
+	// least-squares line fitted to the series.
+	REDUCER_DERIVATIVE = "derivative"
+	// REDUCER_PERCENT_CHANGE reduces to the signed change, in percent, of
+	// the latest value compared to the oldest value of the window.
+	// Params: [window seconds], the whole series when omitted or 0.
+	REDUCER_PERCENT_CHANGE = "percent_change"
+	// REDUCER_PREDICT_LINEAR reduces to the value predicted by linear
+	// regression some seconds after the latest point.
+	// Params: [seconds], defaults to 3600.
+	REDUCER_PREDICT_LINEAR = "predict_linear"
+
+	defaultPredictLinearSeconds = 3600
+)
+
+var (
+	TrendReducerTypes = []string{
+		REDUCER_STDDEV_DEVIATION,
+		REDUCER_RATE,
+		REDUCER_DERIVATIVE,
+		REDUCER_PERCENT_CHANGE,
+		REDUCER_PREDICT_LINEAR,
+	}
+)
+
+// trendReducer reduces a timeseries by looking at how its values change
+// over time instead of at the values themselves
+type trendReducer struct {
+	Type   string
+	Opt    string
+	Params []float64
+}
+
+type trendSample struct {
+	// seconds
+	ts    float64
+	value float64
+}
+
+func (s *trendReducer) GetParams() []float64 {
+	return s.Params
+}
+
+func (s *trendReducer) GetType() string {
+	return s.Type
+}
+
+func (s *trendReducer) param(idx int, defVal float64) float64 {
+	if len(s.Params) > idx {
+		return s.Params[idx]
+	}
+	return defVal
+}
+
+func (s *trendReducer) samples(series *tsdb.TimeSeries) ([]trendSample, error) {
+	samples := make([]trendSample, 0, len(series.Points))
+	for _, point := range series.Points {
+		var value float64
+		if len(s.Opt) == 0 {
+			if !point.IsValid() {
+				continue
+			}
+			value = point.Value()
+		} else {
+			if !point.IsValids() {
+				continue
+			}
+			var err error
+			value, err = (&mathReducer{Opt: s.Opt}).mathValue(point.Values())
+			if err != nil {
+				return nil, err
+			}
+		}
+		samples = append(samples, trendSample{
+			ts:    point.Timestamp() / 1000,
+			value: value,
+		})
+	}
+	return samples, nil
+}
+
+func (s *trendReducer) Reduce(series *tsdb.TimeSeries) (*float64, []string) {
+	valArr := make([]string, 0)
+	samples, err := s.samples(series)
+	if err != nil || len(samples) == 0 {
+		return nil, valArr
+	}
+	var (
+		value float64
+		ok    bool
+	)
+	switch s.Type {
+	case REDUCER_STDDEV_DEVIATION:
+		value, ok = stddevDeviation(samples, int(s.param(0, 0)))
+	case REDUCER_RATE:
+		value, ok = counterRate(samples)
+	case REDUCER_DERIVATIVE:
+		var slope float64
+		slope, _, ok = linearRegression(samples, samples[len(samples)-1].ts)
+		value = slope
+	case REDUCER_PERCENT_CHANGE:
+		value, ok = percentChange(samples, s.param(0, 0))
+	case REDUCER_PREDICT_LINEAR:
+		var slope, intercept float64
+		slope, intercept, ok = linearRegression(samples, samples[len(samples)-1].ts)
+		value = intercept + slope*s.param(0, defaultPredictLinearSeconds)
+	}
+	if !ok {
+		return nil, valArr
+	}
+	return &value, valArr
+}
+
+func stddevDeviation(samples []trendSample, window int) (float64, bool) {
+	last := samples[len(samples)-1].value
+	baseline := samples[:len(samples)-1]
+	if window > 0 && len(baseline) > window {
+		baseline = baseline[len(baseline)-window:]
+	}
+	if len(baseline) < 2 {
+		return 0, false
+	}
+	mean := float64(0)
+	for _, sample := range baseline {
+		mean += sample.value
+	}
+	mean = mean / float64(len(baseline))
+	variance := float64(0)
+	for _, sample := range baseline {
+		variance += (sample.value - mean) * (sample.value - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(baseline)))
+	deviation := math.Abs(last - mean)
+	if stddev == 0 {
+		if deviation == 0 {
+			return 0, true
+		}
+		// any change of a flat series is infinitely far from it
+		return math.MaxFloat64, true
+	}
+	return deviation / stddev, true
+}
+
+func counterRate(samples []trendSample) (float64, bool) {
+	if len(samples) < 2 {
+		return 0, false
+	}
+	span := samples[len(samples)-1].ts - samples[0].ts
+	if span <= 0 {
+		return 0, false
+	}
+	increase := float64(0)
+	for i := 1; i < len(samples); i++ {
+		delta := samples[i].value - samples[i-1].value
+		if delta < 0 {
+			// counter reset, it restarted from zero
+			delta = samples[i].value
+		}
+		increase += delta
+	}
+	return increase / span, true
+}
+
+// linearRegression fits a least-squares line to the samples and returns its
+// slope per second and its value at time origin
+func linearRegression(samples []trendSample, origin float64) (float64, float64, bool) {
+	if len(samples) < 2 {
+		return 0, 0, false
+	}
+	var sumX, sumY, sumXY, sumX2 float64
+	n := float64(len(samples))
+	for _, sample := range samples {
+		x := sample.ts - origin
+		sumX += x
+		sumY += sample.value
+		sumXY += x * sample.value
+		sumX2 += x * x
+	}
+	covXY := sumXY - sumX*sumY/n
+	varX := sumX2 - sumX*sumX/n
+	if varX == 0 {
+		return 0, 0, false
+	}
+	slope := covXY / varX
+	intercept := sumY/n - slope*sumX/n
+	return slope, intercept, true
+}
+
+func percentChange(samples []trendSample, window float64) (float64, bool) {
+	if len(samples) < 2 {
+		return 0, false
+	}
+	last := samples[len(samples)-1]
+	base := samples[0]
+	if window > 0 {
+		for _, sample := range samples {
+			if sample.ts >= last.ts-window {
+				base = sample
+				break
+			}
+		}
+	}
+	if base.value == 0 {
+		return 0, false
+	}
+	return (last.value - base.value) / math.Abs(base.value) * 100, true
+}
+
+func newTrendReducer(cond *monitor.Condition) (*trendReducer, error) {
+	reducer := &trendReducer{
+		Type:   cond.Type,
+		Params: cond.Params,
+	}
+	if len(cond.Operators) != 0 {
+		if !utils.IsInStringArray(cond.Operators[0], validators.CommonAlertReducerFieldOpts) {
+			return nil, errors.Wrapf(errors.Error("reducer operator is ilegal"), "operator: %s", cond.Operators[0])
+		}
+		reducer.Opt = cond.Operators[0]
+	}
+	for _, param := range cond.Params {
+		if param < 0 || math.IsNaN(param) {
+			return nil, errors.Errorf("%s reducer param %v is ilegal", cond.Type, param)
+		}
+	}
+	return reducer, nil
+}
+
+func isTrendReducer(typ string) bool {
+	return utils.IsInStringArray(typ, TrendReducerTypes)
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conditions
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+func TestTrendReducer(t *testing.T) {
+	Convey("Test trend reducer by calculating", t, func() {
+
+		Convey("NewAlertReducer returns trend reducer", func() {
+			reducer, err := NewAlertReducer(&monitor.Condition{Type: REDUCER_PREDICT_LINEAR, Params: []float64{60}})
+			So(err, ShouldBeNil)
+			So(reducer.GetType(), ShouldEqual, REDUCER_PREDICT_LINEAR)
+
+			_, err = NewAlertReducer(&monitor.Condition{Type: REDUCER_RATE, Operators: []string{"*"}})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("stddev_deviation", func() {
+			result := testTrendReducer(REDUCER_STDDEV_DEVIATION, nil, 10, 12, 10, 12, 17)
+			So(result, ShouldNotBeNil)
+			So(*result, ShouldAlmostEqual, float64(6))
+		})
+
+		Convey("stddev_deviation with window", func() {
+			result := testTrendReducer(REDUCER_STDDEV_DEVIATION, []float64{2}, 100, 10, 12, 17)
+			So(*result, ShouldAlmostEqual, float64(6))
+		})
+
+		Convey("stddev_deviation of flat series", func() {
+			result := testTrendReducer(REDUCER_STDDEV_DEVIATION, nil, 5, 5, 5, 5)
+			So(*result, ShouldEqual, float64(0))
+		})
+
+		Convey("rate should handle counter reset", func() {
+			// 0 -> 10 -> 20, reset, 5 -> 15 increases 35 in 40 seconds
+			result := testTrendReducer(REDUCER_RATE, nil, 0, 10, 20, 5, 15)
+			So(*result, ShouldAlmostEqual, float64(35)/40)
+		})
+
+		Convey("derivative", func() {
+			result := testTrendReducer(REDUCER_DERIVATIVE, nil, 100, 90, 80, 70)
+			So(*result, ShouldAlmostEqual, float64(-1))
+		})
+
+		Convey("percent_change over the whole series", func() {
+			result := testTrendReducer(REDUCER_PERCENT_CHANGE, nil, 50, 60, 40)
+			So(*result, ShouldAlmostEqual, float64(-20))
+		})
+
+		Convey("percent_change over window", func() {
+			result := testTrendReducer(REDUCER_PERCENT_CHANGE, []float64{10}, 50, 80, 100)
+			So(*result, ShouldAlmostEqual, float64(25))
+		})
+
+		Convey("predict_linear", func() {
+			result := testTrendReducer(REDUCER_PREDICT_LINEAR, []float64{30}, 10, 20, 30, 40)
+			So(*result, ShouldAlmostEqual, float64(70))
+		})
+
+		Convey("trend reducers need at least two values", func() {
+			for _, typ := range []string{REDUCER_RATE, REDUCER_DERIVATIVE, REDUCER_PERCENT_CHANGE, REDUCER_PREDICT_LINEAR} {
+				So(testTrendReducer(typ, nil, 1), ShouldBeNil)
+			}
+		})
+
+		Convey("trend reducers should ignore null values", func() {
+			reducer, err := NewAlertReducer(&monitor.Condition{Type: REDUCER_DERIVATIVE})
+			So(err, ShouldBeNil)
+			series := &tsdb.TimeSeries{
+				Name: "test time series",
+			}
+			series.Points = append(series.Points, tsdb.NewTimePointByVal(1, 0))
+			series.Points = append(series.Points, tsdb.NewTimePoint(nil, 10000))
+			series.Points = append(series.Points, tsdb.NewTimePointByVal(3, 20000))
+			result, _ := reducer.Reduce(series)
+			So(*result, ShouldAlmostEqual, float64(0.1))
+		})
+	})
+}
+
+// testTrendReducer reduces datapoints that are 10 seconds apart
+func testTrendReducer(reducerType string, params []float64, datapoints ...float64) *float64 {
+	reducer, err := NewAlertReducer(&monitor.Condition{Type: reducerType, Params: params})
+	if err != nil {
+		return nil
+	}
+	series := &tsdb.TimeSeries{
+		Name: "test time series",
+	}
+
+	for idx := range datapoints {
+		series.Points = append(series.Points, tsdb.NewTimePointByVal(datapoints[idx], float64(idx*10000)))
+	}
+	reduce, _ := reducer.Reduce(series)
+	return reduce
+}
@@ -86,6 +86,10 @@ type IMetricDriver interface {
 	SetHostCurrent(h IHost, values map[string]float64) error
 	GetTarget(host jsonutils.JSONObject) (ITarget, error)
 	GetCondition(s *monitor.AlertSetting) (ICondition, error)
+	// GetHostUsage returns the used and total capacity of host by the metric values
+	GetHostUsage(host *HostResource, values map[string]float64) (float64, float64)
+	// GetGuestLoads returns guests load keyed by id in the same unit of host usage
+	GetGuestLoads(ds *tsdb.DataSource, guests []*guestResource) (map[string]float64, error)
 }
 
 type ICondition interface {
@@ -605,11 +609,14 @@ func doMigrate(ctx context.Context, s *mcclient.ClientSession, rules *Rules, rst
 }
 
 func doMigrateByPair(s *mcclient.ClientSession, pair *resultPair) (jsonutils.JSONObject, error) {
-	gst := pair.source
+	return liveMigrateServer(s, pair.source.GetId(), pair.target.GetId())
+}
+
+func liveMigrateServer(s *mcclient.ClientSession, serverId string, hostId string) (jsonutils.JSONObject, error) {
 	trueObj := true
 	input := &compute_options.ServerLiveMigrateOptions{
-		ID:              gst.GetId(),
-		PreferHost:      pair.target.GetId(),
+		ID:              serverId,
+		PreferHost:      hostId,
 		SkipCpuCheck:    &trueObj,
 		SkipKernelCheck: &trueObj,
 	}
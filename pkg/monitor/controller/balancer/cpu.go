@@ -97,6 +97,39 @@ func (m *cpuCondition) IsFitTarget(t ITarget, c ICandidate) error {
 	return errors.Errorf("host:%s:current(%f) + guest:%s:score(%f) >= threshold(%f)", t.GetName(), t.GetCurrent(), c.GetName(), c.GetScore(), m.GetThreshold())
 }
 
+func (c *cpuUsageActive) GetHostUsage(host *HostResource, vals map[string]float64) (float64, float64) {
+	cpuCount := float64(host.cpuCount)
+	return vals["usage_active"] / 100 * cpuCount, cpuCount
+}
+
+func (c *cpuUsageActive) GetGuestLoads(ds *tsdb.DataSource, guests []*guestResource) (map[string]float64, error) {
+	ret := make(map[string]float64)
+	if len(guests) == 0 {
+		return ret, nil
+	}
+	res := make([]IResource, len(guests))
+	for i := range guests {
+		res[i] = guests[i]
+	}
+	metrics, err := InfluxdbQuery(ds, "vm_id", res, &TsdbQuery{
+		Database:    monitor.METRIC_DATABASE_TELE,
+		Measurement: "vm_cpu",
+		Fields:      []string{"usage_active"},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "InfluxdbQuery guests vm_cpu")
+	}
+	for _, gst := range guests {
+		metric := metrics.Get(gst.GetId())
+		if metric == nil {
+			continue
+		}
+		vcpuCount, _ := gst.guest.Int("vcpu_count")
+		ret[gst.GetId()] = metric.Values["usage_active"] / 100 * float64(vcpuCount)
+	}
+	return ret, nil
+}
+
 // cpuCandidate implements ICandidate
 type cpuCandidate struct {
 	*guestResource
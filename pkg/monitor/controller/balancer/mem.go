@@ -87,6 +87,26 @@ func (m *memCondition) IsFitTarget(t ITarget, c ICandidate) error {
 	return errors.Errorf("host:%s:current(%f) - guest:%s:score(%f) <= threshold(%f)", t.GetName(), t.GetCurrent(), c.GetName(), c.GetScore(), m.GetThreshold())
 }
 
+func (ma *memAvailable) GetHostUsage(host *HostResource, vals map[string]float64) (float64, float64) {
+	total := vals["total"]
+	if total <= 0 {
+		total = host.totalMemSize
+	}
+	return total - vals["available"], total
+}
+
+func (ma *memAvailable) GetGuestLoads(_ *tsdb.DataSource, guests []*guestResource) (map[string]float64, error) {
+	ret := make(map[string]float64)
+	for _, gst := range guests {
+		memSizeMB, err := gst.guest.Int("vmem_size")
+		if err != nil {
+			return nil, errors.Wrapf(err, "get vmem_size of guest %s", gst.GetName())
+		}
+		ret[gst.GetId()] = float64(memSizeMB * 1024 * 1024)
+	}
+	return ret, nil
+}
+
 // memCandidate implements ICandidate
 type memCandidate struct {
 	*guestResource
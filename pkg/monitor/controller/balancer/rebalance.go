@@ -0,0 +1,182 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balancer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"yunion.io/x/log"
+)
+
+// RebalanceGuest is a guest which can be moved by the cluster rebalancer
+type RebalanceGuest struct {
+	Id   string
+	Name string
+	// Load is in the same unit of host usage, e.g. cpu cores or memory bytes
+	Load float64
+	// Groups is the ids of anti-affinity instance groups the guest joined
+	Groups []string
+}
+
+// RebalanceHost is a host with the guests on it
+type RebalanceHost struct {
+	Id       string
+	Name     string
+	Capacity float64
+	Used     float64
+	Guests   []*RebalanceGuest
+}
+
+// Utilization returns the used percentage of host
+func (h *RebalanceHost) Utilization() float64 {
+	if h.Capacity <= 0 {
+		return 0
+	}
+	return h.Used / h.Capacity * 100
+}
+
+func (h *RebalanceHost) removeGuest(g *RebalanceGuest) {
+	for i := range h.Guests {
+		if h.Guests[i].Id == g.Id {
+			h.Guests = append(h.Guests[:i], h.Guests[i+1:]...)
+			return
+		}
+	}
+}
+
+type RebalanceMove struct {
+	Guest  *RebalanceGuest
+	Source *RebalanceHost
+	Target *RebalanceHost
+}
+
+// MoveChecker returns error if guest can't be moved from src to dst
+type MoveChecker func(g *RebalanceGuest, src, dst *RebalanceHost) error
+
+// Imbalance returns the standard deviation of hosts utilization percentage
+func Imbalance(hosts []*RebalanceHost) float64 {
+	if len(hosts) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, h := range hosts {
+		sum += h.Utilization()
+	}
+	avg := sum / float64(len(hosts))
+	variance := 0.0
+	for _, h := range hosts {
+		delta := h.Utilization() - avg
+		variance += delta * delta
+	}
+	return math.Sqrt(variance / float64(len(hosts)))
+}
+
+func imbalanceAfterMove(hosts []*RebalanceHost, src, dst *RebalanceHost, load float64) float64 {
+	src.Used -= load
+	dst.Used += load
+	ret := Imbalance(hosts)
+	src.Used += load
+	dst.Used -= load
+	return ret
+}
+
+type rebalanceOption struct {
+	guest     *RebalanceGuest
+	target    *RebalanceHost
+	imbalance float64
+}
+
+// PlanRebalance greedily moves guests from the most utilized hosts to the less utilized ones,
+// picking the move which reduces the imbalance most each round, until the imbalance
+// is not greater than threshold, maxMoves is reached or no move can reduce the imbalance.
+// The hosts are updated in place to reflect the planned moves.
+func PlanRebalance(hosts []*RebalanceHost, maxMoves int, threshold float64, check MoveChecker) []*RebalanceMove {
+	moves := make([]*RebalanceMove, 0)
+	moved := make(map[string]bool)
+	rejected := make(map[string]bool)
+	for maxMoves <= 0 || len(moves) < maxMoves {
+		cur := Imbalance(hosts)
+		if cur <= threshold {
+			break
+		}
+		sources := make([]*RebalanceHost, len(hosts))
+		copy(sources, hosts)
+		sort.SliceStable(sources, func(i, j int) bool {
+			return sources[i].Utilization() > sources[j].Utilization()
+		})
+		var move *RebalanceMove
+		for _, src := range sources {
+			move = findRebalanceMove(hosts, src, cur, moved, rejected, check)
+			if move != nil {
+				break
+			}
+		}
+		if move == nil {
+			break
+		}
+		move.Source.Used -= move.Guest.Load
+		move.Source.removeGuest(move.Guest)
+		move.Target.Used += move.Guest.Load
+		move.Target.Guests = append(move.Target.Guests, move.Guest)
+		moved[move.Guest.Id] = true
+		moves = append(moves, move)
+	}
+	return moves
+}
+
+func findRebalanceMove(hosts []*RebalanceHost, src *RebalanceHost, cur float64, moved, rejected map[string]bool, check MoveChecker) *RebalanceMove {
+	opts := make([]rebalanceOption, 0)
+	for _, g := range src.Guests {
+		if moved[g.Id] || g.Load <= 0 {
+			continue
+		}
+		for _, dst := range hosts {
+			if dst.Id == src.Id || dst.Utilization() >= src.Utilization() {
+				continue
+			}
+			if rejected[fmt.Sprintf("%s/%s", g.Id, dst.Id)] {
+				continue
+			}
+			if dst.Used+g.Load > dst.Capacity {
+				continue
+			}
+			after := imbalanceAfterMove(hosts, src, dst, g.Load)
+			if after >= cur-1e-6 {
+				continue
+			}
+			opts = append(opts, rebalanceOption{guest: g, target: dst, imbalance: after})
+		}
+	}
+	sort.SliceStable(opts, func(i, j int) bool {
+		return opts[i].imbalance < opts[j].imbalance
+	})
+	for _, opt := range opts {
+		if check != nil {
+			if err := check(opt.guest, src, opt.target); err != nil {
+				log.Debugf("rebalance: skip moving guest %s to host %s: %v", opt.guest.Name, opt.target.Name, err)
+				rejected[fmt.Sprintf("%s/%s", opt.guest.Id, opt.target.Id)] = true
+				continue
+			}
+		}
+		return &RebalanceMove{
+			Guest:  opt.guest,
+			Source: src,
+			Target: opt.target,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,426 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balancer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/cloudcommon/cronman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/monitor/models"
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+const (
+	EventActionRebalanceMigrating     = "rebalance_migrating"
+	EventActionRebalanceMigrateSucc   = "rebalance_migrate_success"
+	EventActionRebalanceMigrateFailed = "rebalance_migrate_fail"
+)
+
+type RebalanceOptions struct {
+	MetricType monitor.MigrationAlertMetricType
+	// Threshold is the max standard deviation of hosts utilization percentage tolerated
+	Threshold      float64
+	MaxMoves       int
+	MaxConcurrency int
+	AutoExecute    bool
+}
+
+type rebalanceInstanceGroup struct {
+	Id              string
+	Name            string
+	Granularity     int
+	ForceDispersion bool
+}
+
+// GenerateRebalancePlan computes a migration plan reducing the imbalance of hosts utilization,
+// returns nil if hosts are balanced enough or there is a plan still executing.
+func GenerateRebalancePlan(ctx context.Context, s *mcclient.ClientSession, opts *RebalanceOptions) (*models.SRebalancePlan, error) {
+	executing, err := models.RebalancePlanManager.GetExecutingPlans(string(opts.MetricType))
+	if err != nil {
+		return nil, errors.Wrap(err, "GetExecutingPlans")
+	}
+	if len(executing) > 0 {
+		log.Infof("rebalance plan %s of %s is executing, skip planning", executing[0].GetName(), opts.MetricType)
+		return nil, nil
+	}
+	drv, err := GetMetricDrivers().Get(opts.MetricType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get metric driver %s", opts.MetricType)
+	}
+	dsObj, err := models.DataSourceManager.GetDefaultSource()
+	if err != nil {
+		return nil, errors.Wrap(err, "Get default DataSource")
+	}
+	ds := dsObj.ToTSDBDataSource("")
+
+	groups, guestGroups, err := fetchInstanceGroups(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetchInstanceGroups")
+	}
+	archHosts, err := collectRebalanceHosts(drv, ds, guestGroups)
+	if err != nil {
+		return nil, errors.Wrap(err, "collectRebalanceHosts")
+	}
+
+	allHosts := make([]*RebalanceHost, 0)
+	archs := make([]string, 0)
+	for arch, hosts := range archHosts {
+		archs = append(archs, arch)
+		allHosts = append(allHosts, hosts...)
+	}
+	sort.Strings(archs)
+	before := Imbalance(allHosts)
+
+	checker := newRebalanceChecker(s, groups)
+	moves := make([]*RebalanceMove, 0)
+	for _, arch := range archs {
+		maxMoves := 0
+		if opts.MaxMoves > 0 {
+			maxMoves = opts.MaxMoves - len(moves)
+			if maxMoves <= 0 {
+				break
+			}
+		}
+		moves = append(moves, PlanRebalance(archHosts[arch], maxMoves, opts.Threshold, checker)...)
+	}
+	if len(moves) == 0 {
+		log.Infof("rebalance %s: imbalance %.2f, no migration planned", opts.MetricType, before)
+		return nil, nil
+	}
+
+	steps := make([]*monitor.RebalancePlanStep, len(moves))
+	for i, move := range moves {
+		steps[i] = &monitor.RebalancePlanStep{
+			GuestId:        move.Guest.Id,
+			GuestName:      move.Guest.Name,
+			SourceHostId:   move.Source.Id,
+			SourceHostName: move.Source.Name,
+			TargetHostId:   move.Target.Id,
+			TargetHostName: move.Target.Name,
+			Load:           move.Guest.Load,
+		}
+	}
+	input := &monitor.RebalancePlanCreateInput{
+		MetricType:      opts.MetricType,
+		AutoExecute:     opts.AutoExecute,
+		MaxConcurrency:  opts.MaxConcurrency,
+		ImbalanceBefore: before,
+		ImbalanceAfter:  Imbalance(allHosts),
+		Steps:           steps,
+	}
+	plan, err := models.RebalancePlanManager.CreatePlan(ctx, s.GetToken(), input)
+	if err != nil {
+		return nil, errors.Wrap(err, "CreatePlan")
+	}
+	if opts.AutoExecute {
+		if err := plan.StartExecuteTask(ctx, s.GetToken(), ""); err != nil {
+			return nil, errors.Wrapf(err, "StartExecuteTask of plan %s", plan.GetName())
+		}
+	}
+	return plan, nil
+}
+
+// NewRebalanceCronJob returns the cron job periodically planning cluster rebalance
+func NewRebalanceCronJob(opts *RebalanceOptions, region string) cronman.TCronJobFunction {
+	return func(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+		plan, err := GenerateRebalancePlan(ctx, auth.GetAdminSession(ctx, region, ""), opts)
+		if err != nil {
+			log.Errorf("GenerateRebalancePlan of %s: %v", opts.MetricType, err)
+			return
+		}
+		if plan != nil {
+			log.Infof("rebalance plan %s generated, imbalance %.2f -> %.2f", plan.GetName(), plan.ImbalanceBefore, plan.ImbalanceAfter)
+		}
+	}
+}
+
+// collectRebalanceHosts returns the online kvm hosts with running guests grouped by cpu architecture
+func collectRebalanceHosts(drv IMetricDriver, ds *tsdb.DataSource, guestGroups map[string][]string) (map[string][]*RebalanceHost, error) {
+	ok, hObjs := models.MonitorResourceManager.GetResourceObjByResType(monitor.METRIC_RES_TYPE_HOST)
+	if !ok {
+		return nil, errors.Errorf("GetResourceObjByResType host returns false")
+	}
+	hostRes := make([]IResource, 0)
+	hosts := make(map[string]*HostResource)
+	for _, obj := range hObjs {
+		hostType, _ := obj.GetString("host_type")
+		enabled, _ := obj.Bool("enabled")
+		hostStatus, _ := obj.GetString("host_status")
+		if hostType != computeapi.HOST_TYPE_HYPERVISOR || !enabled || hostStatus != computeapi.HOST_ONLINE {
+			continue
+		}
+		host, err := newHostResource(obj)
+		if err != nil {
+			return nil, errors.Wrapf(err, "newHostResource %s", obj)
+		}
+		hosts[host.GetId()] = host
+		hostRes = append(hostRes, host)
+	}
+	if len(hostRes) < 2 {
+		return nil, nil
+	}
+	metrics, err := InfluxdbQuery(ds, "host_id", hostRes, drv.GetTsdbQuery())
+	if err != nil {
+		return nil, errors.Wrap(err, "InfluxdbQuery hosts metrics")
+	}
+
+	ok, gObjs := models.MonitorResourceManager.GetResourceObjByResType(monitor.METRIC_RES_TYPE_GUEST)
+	if !ok {
+		return nil, errors.Errorf("GetResourceObjByResType guest returns false")
+	}
+	guests := make([]*guestResource, 0)
+	guestHosts := make(map[string]string)
+	for _, obj := range gObjs {
+		hostId, _ := obj.GetString("host_id")
+		status, _ := obj.GetString("status")
+		host, ok := hosts[hostId]
+		if !ok || status != computeapi.VM_RUNNING {
+			continue
+		}
+		gst, err := newGuestResource(obj, host.GetName())
+		if err != nil {
+			return nil, errors.Wrapf(err, "newGuestResource %s", obj)
+		}
+		guests = append(guests, gst)
+		guestHosts[gst.GetId()] = hostId
+	}
+	loads, err := drv.GetGuestLoads(ds, guests)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetGuestLoads")
+	}
+
+	rHosts := make(map[string]*RebalanceHost)
+	ret := make(map[string][]*RebalanceHost)
+	for _, h := range hostRes {
+		host := h.(*HostResource)
+		m := metrics.Get(host.GetId())
+		if m == nil {
+			log.Warningf("rebalance: metrics of host %s not found, skip it", host.GetName())
+			continue
+		}
+		used, capacity := drv.GetHostUsage(host, m.Values)
+		rHost := &RebalanceHost{
+			Id:       host.GetId(),
+			Name:     host.GetName(),
+			Capacity: capacity,
+			Used:     used,
+			Guests:   make([]*RebalanceGuest, 0),
+		}
+		rHosts[rHost.Id] = rHost
+		arch, _ := host.host.GetString("cpu_architecture")
+		ret[arch] = append(ret[arch], rHost)
+	}
+	for _, gst := range guests {
+		rHost, ok := rHosts[guestHosts[gst.GetId()]]
+		if !ok {
+			continue
+		}
+		rHost.Guests = append(rHost.Guests, &RebalanceGuest{
+			Id:     gst.GetId(),
+			Name:   gst.GetName(),
+			Load:   loads[gst.GetId()],
+			Groups: guestGroups[gst.GetId()],
+		})
+	}
+	return ret, nil
+}
+
+type iListManager interface {
+	List(s *mcclient.ClientSession, params jsonutils.JSONObject) (*modulebase.ListResult, error)
+	KeyString() string
+}
+
+func listAllResources(s *mcclient.ClientSession, man iListManager) ([]jsonutils.JSONObject, error) {
+	ret := make([]jsonutils.JSONObject, 0)
+	for {
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString("system"), "scope")
+		params.Add(jsonutils.NewInt(0), "limit")
+		params.Add(jsonutils.NewInt(int64(len(ret))), "offset")
+		result, err := man.List(s, params)
+		if err != nil {
+			return nil, errors.Wrapf(err, "list %s", man.KeyString())
+		}
+		ret = append(ret, result.Data...)
+		if len(result.Data) == 0 || len(ret) >= result.Total {
+			break
+		}
+	}
+	return ret, nil
+}
+
+// fetchInstanceGroups returns the enabled instance groups and the group ids of each guest
+func fetchInstanceGroups(s *mcclient.ClientSession) (map[string]*rebalanceInstanceGroup, map[string][]string, error) {
+	objs, err := listAllResources(s, &compute.InstanceGroups)
+	if err != nil {
+		return nil, nil, err
+	}
+	groups := make(map[string]*rebalanceInstanceGroup)
+	for _, obj := range objs {
+		if enabled, _ := obj.Bool("enabled"); !enabled {
+			continue
+		}
+		group := new(rebalanceInstanceGroup)
+		group.Id, _ = obj.GetString("id")
+		group.Name, _ = obj.GetString("name")
+		granularity, _ := obj.Int("granularity")
+		group.Granularity = int(granularity)
+		group.ForceDispersion, _ = obj.Bool("force_dispersion")
+		groups[group.Id] = group
+	}
+	joints, err := listAllResources(s, &compute.InstanceGroupGuests)
+	if err != nil {
+		return nil, nil, err
+	}
+	guestGroups := make(map[string][]string)
+	for _, obj := range joints {
+		groupId, _ := obj.GetString("group_id")
+		guestId, _ := obj.GetString("guest_id")
+		if _, ok := groups[groupId]; ok {
+			guestGroups[guestId] = append(guestGroups[guestId], groupId)
+		}
+	}
+	return groups, guestGroups, nil
+}
+
+// newAntiAffinityChecker refuses moves exceeding the granularity of the guest's force dispersion groups
+func newAntiAffinityChecker(groups map[string]*rebalanceInstanceGroup) MoveChecker {
+	return func(g *RebalanceGuest, src, dst *RebalanceHost) error {
+		for _, gid := range g.Groups {
+			group, ok := groups[gid]
+			if !ok || !group.ForceDispersion {
+				continue
+			}
+			cnt := 0
+			for _, og := range dst.Guests {
+				for _, ogid := range og.Groups {
+					if ogid == gid {
+						cnt++
+						break
+					}
+				}
+			}
+			if cnt >= group.Granularity {
+				return errors.Errorf("instance group %s allows at most %d guests on host %s", group.Name, group.Granularity, dst.Name)
+			}
+		}
+		return nil
+	}
+}
+
+// newRebalanceChecker checks anti-affinity groups first, then asks scheduler whether
+// the guest can be live migrated to target host, which respects the predicates and schedtags.
+func newRebalanceChecker(s *mcclient.ClientSession, groups map[string]*rebalanceInstanceGroup) MoveChecker {
+	antiAffinity := newAntiAffinityChecker(groups)
+	return func(g *RebalanceGuest, src, dst *RebalanceHost) error {
+		if err := antiAffinity(g, src, dst); err != nil {
+			return err
+		}
+		params := jsonutils.Marshal(&computeapi.ServerMigrateForecastInput{
+			PreferHostId: dst.Id,
+			LiveMigrate:  true,
+			SkipCpuCheck: true,
+		})
+		ret, err := compute.Servers.PerformAction(s, g.Id, "migrate-forecast", params)
+		if err != nil {
+			return errors.Wrap(err, "migrate-forecast")
+		}
+		if canCreate, _ := ret.Bool("can_create"); !canCreate {
+			return errors.Errorf("scheduler forecast can't migrate to host: %s", ret)
+		}
+		return nil
+	}
+}
+
+// ExecuteRebalancePlan live migrates the pending steps of plan, at most MaxConcurrency at the same time,
+// and records the progress of each step.
+func ExecuteRebalancePlan(ctx context.Context, s *mcclient.ClientSession, plan *models.SRebalancePlan) error {
+	steps, err := plan.GetSteps()
+	if err != nil {
+		return errors.Wrap(err, "GetSteps")
+	}
+	concurrency := plan.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	wg := new(sync.WaitGroup)
+	errs := make([]error, 0)
+	lock := new(sync.Mutex)
+	for i := range steps {
+		if steps[i].Status == monitor.REBALANCE_STEP_STATUS_SUCCESS {
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(idx int, step *monitor.RebalancePlanStep) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := executeRebalanceStep(ctx, s, plan, idx, step); err != nil {
+				lock.Lock()
+				errs = append(errs, errors.Wrapf(err, "migrate %s to %s", step.GuestName, step.TargetHostName))
+				lock.Unlock()
+			}
+		}(i, steps[i])
+	}
+	wg.Wait()
+	return errors.NewAggregate(errs)
+}
+
+func executeRebalanceStep(ctx context.Context, s *mcclient.ClientSession, plan *models.SRebalancePlan, idx int, step *monitor.RebalancePlanStep) error {
+	userCred := s.GetToken()
+	setStep := func(status string, err error) {
+		step.Status = status
+		if err != nil {
+			step.Error = err.Error()
+		}
+		if err := plan.SetStep(ctx, idx, step); err != nil {
+			log.Errorf("set rebalance plan %s step %d: %v", plan.GetName(), idx, err)
+		}
+	}
+	setStep(monitor.REBALANCE_STEP_STATUS_MIGRATING, nil)
+	db.OpsLog.LogEvent(plan, EventActionRebalanceMigrating, step, userCred)
+	err := func() error {
+		if _, err := liveMigrateServer(s, step.GuestId, step.TargetHostId); err != nil {
+			return err
+		}
+		noteStr := fmt.Sprintf("rebalance plan %s step %d", plan.GetName(), idx)
+		return waitServerMigrated(s, step.GuestId, step.GuestName, step.SourceHostId, step.TargetHostId, noteStr)
+	}()
+	if err != nil {
+		setStep(monitor.REBALANCE_STEP_STATUS_FAILED, err)
+		db.OpsLog.LogEvent(plan, EventActionRebalanceMigrateFailed, step, userCred)
+		return err
+	}
+	setStep(monitor.REBALANCE_STEP_STATUS_SUCCESS, nil)
+	db.OpsLog.LogEvent(plan, EventActionRebalanceMigrateSucc, step, userCred)
+	return nil
+}
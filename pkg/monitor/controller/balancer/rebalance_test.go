@@ -0,0 +1,118 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balancer
+
+import (
+	"testing"
+
+	"yunion.io/x/pkg/errors"
+)
+
+func newTestRebalanceHost(id string, capacity float64, loads map[string]float64) *RebalanceHost {
+	h := &RebalanceHost{
+		Id:       id,
+		Name:     id,
+		Capacity: capacity,
+		Guests:   make([]*RebalanceGuest, 0),
+	}
+	for gid, load := range loads {
+		h.Used += load
+		h.Guests = append(h.Guests, &RebalanceGuest{Id: gid, Name: gid, Load: load})
+	}
+	return h
+}
+
+func TestPlanRebalance(t *testing.T) {
+	t.Run("move from busy host to idle host", func(t *testing.T) {
+		hosts := []*RebalanceHost{
+			newTestRebalanceHost("h1", 16, map[string]float64{"g1": 4, "g2": 4, "g3": 4}),
+			newTestRebalanceHost("h2", 16, map[string]float64{}),
+		}
+		before := Imbalance(hosts)
+		moves := PlanRebalance(hosts, 0, 5, nil)
+		if len(moves) != 1 {
+			t.Fatalf("expect 1 move, got %d", len(moves))
+		}
+		if moves[0].Target.Id != "h2" {
+			t.Errorf("expect move to h2, got %s", moves[0].Target.Id)
+		}
+		if after := Imbalance(hosts); after >= before {
+			t.Errorf("imbalance not reduced: %f -> %f", before, after)
+		}
+		if len(hosts[0].Guests) != 2 || len(hosts[1].Guests) != 1 {
+			t.Errorf("hosts guests not updated: %d, %d", len(hosts[0].Guests), len(hosts[1].Guests))
+		}
+	})
+
+	t.Run("balanced hosts", func(t *testing.T) {
+		hosts := []*RebalanceHost{
+			newTestRebalanceHost("h1", 16, map[string]float64{"g1": 4}),
+			newTestRebalanceHost("h2", 16, map[string]float64{"g2": 4}),
+		}
+		if moves := PlanRebalance(hosts, 0, 5, nil); len(moves) != 0 {
+			t.Errorf("expect no move, got %d", len(moves))
+		}
+	})
+
+	t.Run("max moves", func(t *testing.T) {
+		hosts := []*RebalanceHost{
+			newTestRebalanceHost("h1", 16, map[string]float64{"g1": 2, "g2": 2, "g3": 2, "g4": 2, "g5": 2, "g6": 2}),
+			newTestRebalanceHost("h2", 16, map[string]float64{}),
+		}
+		if moves := PlanRebalance(hosts, 1, 0, nil); len(moves) != 1 {
+			t.Errorf("expect 1 move, got %d", len(moves))
+		}
+	})
+
+	t.Run("checker rejects target", func(t *testing.T) {
+		hosts := []*RebalanceHost{
+			newTestRebalanceHost("h1", 16, map[string]float64{"g1": 4, "g2": 4, "g3": 4}),
+			newTestRebalanceHost("h2", 16, map[string]float64{}),
+			newTestRebalanceHost("h3", 16, map[string]float64{"g4": 1}),
+		}
+		check := func(g *RebalanceGuest, src, dst *RebalanceHost) error {
+			if dst.Id == "h2" {
+				return errors.Errorf("h2 is not schedulable")
+			}
+			return nil
+		}
+		moves := PlanRebalance(hosts, 0, 0, check)
+		if len(moves) == 0 {
+			t.Fatalf("expect moves to h3")
+		}
+		for _, m := range moves {
+			if m.Target.Id == "h2" {
+				t.Errorf("guest %s moved to rejected host h2", m.Guest.Id)
+			}
+		}
+	})
+
+	t.Run("anti affinity", func(t *testing.T) {
+		hosts := []*RebalanceHost{
+			newTestRebalanceHost("h1", 16, map[string]float64{"g1": 4, "g2": 4}),
+			newTestRebalanceHost("h2", 16, map[string]float64{"g3": 1}),
+		}
+		for _, g := range hosts[0].Guests {
+			g.Groups = []string{"grp"}
+		}
+		hosts[1].Guests[0].Groups = []string{"grp"}
+		check := newAntiAffinityChecker(map[string]*rebalanceInstanceGroup{
+			"grp": {Id: "grp", Name: "grp", Granularity: 1, ForceDispersion: true},
+		})
+		if moves := PlanRebalance(hosts, 0, 0, check); len(moves) != 0 {
+			t.Errorf("expect no move by anti affinity, got %d", len(moves))
+		}
+	})
+}
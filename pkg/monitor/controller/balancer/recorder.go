@@ -107,13 +107,27 @@ func (r *sRecorder) StartWatchMigratingProcess(ctx context.Context, s *mcclient.
 }
 
 func (r *sRecorder) startWatchMigratingProcess(ctx context.Context, s *mcclient.ClientSession, alert *models.SMigrationAlert, note *models.MigrateNote) {
-	interval := time.Second * 30
 	noteStr := jsonutils.Marshal(note).String()
-	serverId := note.Guest.Id
-	serverName := note.Guest.Name
-	sourceHostId := note.Guest.HostId
-	targetHostId := note.Target.Id
-	if err := wait.PollImmediateInfinite(interval, func() (bool, error) {
+	if err := waitServerMigrated(s, note.Guest.Id, note.Guest.Name, note.Guest.HostId, note.Target.Id, noteStr); err != nil {
+		note.Error = err.Error()
+		r.Record(s.GetToken(), alert, note, EventActionMigrateFail)
+		if err := alert.SetMigrateNote(ctx, note, true); err != nil {
+			log.Errorf("Delete alert %s(%s) migrate note on failure: %s", alert.GetName(), alert.GetId(), noteStr)
+		}
+	} else {
+		r.Record(s.GetToken(), alert, note, EventActionMigrateSuccess)
+		man := models.MonitorResourceManager
+		man.SyncManually(ctx)
+		if err := alert.SetMigrateNote(ctx, note, true); err != nil {
+			log.Errorf("Delete alert %s(%s) migrate note on success: %s", alert.GetName(), alert.GetId(), noteStr)
+		}
+	}
+}
+
+// waitServerMigrated polls server until it is running on target host or the migration failed
+func waitServerMigrated(s *mcclient.ClientSession, serverId, serverName, sourceHostId, targetHostId string, noteStr string) error {
+	interval := time.Second * 30
+	return wait.PollImmediateInfinite(interval, func() (bool, error) {
 		log.Infof("start to watch migrating process %s", noteStr)
 		srvObj, err := computemod.Servers.Get(s, serverId, jsonutils.NewDict())
 		if err != nil {
@@ -148,20 +162,7 @@ func (r *sRecorder) startWatchMigratingProcess(ctx context.Context, s *mcclient.
 		}
 		log.Infof("%s: server status %q, continue watching", noteStr, status)
 		return false, nil
-	}); err != nil {
-		note.Error = err.Error()
-		r.Record(s.GetToken(), alert, note, EventActionMigrateFail)
-		if err := alert.SetMigrateNote(ctx, note, true); err != nil {
-			log.Errorf("Delete alert %s(%s) migrate note on failure: %s", alert.GetName(), alert.GetId(), noteStr)
-		}
-	} else {
-		r.Record(s.GetToken(), alert, note, EventActionMigrateSuccess)
-		man := models.MonitorResourceManager
-		man.SyncManually(ctx)
-		if err := alert.SetMigrateNote(ctx, note, true); err != nil {
-			log.Errorf("Delete alert %s(%s) migrate note on success: %s", alert.GetName(), alert.GetId(), noteStr)
-		}
-	}
+	})
 }
 
 func (r *sRecorder) RecordMigrateError(userCred mcclient.TokenCredential, alert *models.SMigrationAlert, note *models.MigrateNote, err error) error {
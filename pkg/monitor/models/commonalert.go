@@ -213,6 +213,9 @@ func (man *SCommonAlertManager) ValidateCreateData(
 			if _, ok := monitor.AlertReduceFunc[query.Reduce]; !ok {
 				return data, httperrors.NewInputParameterError("the reduce is illegal: %s", query.Reduce)
 			}
+			if err := validators.ValidateAlertConditionReducer(monitor.Condition{Type: query.Reduce, Params: query.ReduceParams}); err != nil {
+				return data, err
+			}
 			/*if query.Threshold == 0 {
 				return data, httperrors.NewInputParameterError("threshold is meaningless")
 			}*/
@@ -783,6 +786,7 @@ func getCommonAlertMetricDetailsFromCondition(cond *monitor.AlertCondition,
 		metricDetails.Threshold = cond.Evaluator.Params[0]
 	}
 	metricDetails.Reduce = cond.Reducer.Type
+	metricDetails.ReduceParams = cond.Reducer.Params
 
 	metricDetails.ConditionType = cond.Type
 	if metricDetails.ConditionType == monitor.METRIC_QUERY_TYPE_NO_DATA {
@@ -915,7 +919,7 @@ func (man *SCommonAlertManager) toAlertCreatInput(input monitor.CommonAlertCreat
 		condition := monitor.AlertCondition{
 			Type:    conditionType,
 			Query:   *metricquery.AlertQuery,
-			Reducer: monitor.Condition{Type: metricquery.Reduce},
+			Reducer: monitor.Condition{Type: metricquery.Reduce, Params: metricquery.ReduceParams},
 			Evaluator: monitor.Condition{Type: getQueryEvalType(metricquery.Comparator),
 				Params: []float64{fieldOperatorThreshold(metricquery.FieldOpt, metricquery.Threshold)}},
 			Operator: "and",
@@ -994,6 +998,9 @@ func (alert *SCommonAlert) ValidateUpdateData(
 			if _, ok := monitor.AlertReduceFunc[query.Reduce]; !ok {
 				return data, httperrors.NewInputParameterError("the reduce is illegal: %s", query.Reduce)
 			}
+			if err := validators.ValidateAlertConditionReducer(monitor.Condition{Type: query.Reduce, Params: query.ReduceParams}); err != nil {
+				return data, err
+			}
 			/*if query.Threshold == 0 {
 				return data, httperrors.NewInputParameterError("threshold is meaningless")
 			}*/
@@ -106,13 +106,23 @@ func (man *SDataSourceManager) initDefaultDataSource(ctx context.Context) error
 			log.Errorf("get empty public session for region %s", region)
 			return
 		}
-		url, err := s.GetServiceURL("influxdb", epType)
-		if err != nil {
-			log.Errorf("get influxdb public url: %v", err)
-			return
+		dsType := options.Options.TsdbDriver
+		if dsType == "" {
+			dsType = monitor.DataSourceTypeInfluxdb
+		}
+		url := ""
+		if dsType == monitor.DataSourceTypePrometheus && options.Options.PrometheusUrl != "" {
+			url = options.Options.PrometheusUrl
+		} else {
+			url, err = s.GetServiceURL(dsType, epType)
+			if err != nil {
+				log.Errorf("get %s public url: %v", dsType, err)
+				return
+			}
 		}
 		if ds != nil {
 			if _, err := db.Update(ds, func() error {
+				ds.Type = dsType
 				ds.Url = url
 				return nil
 			}); err != nil {
@@ -121,12 +131,12 @@ func (man *SDataSourceManager) initDefaultDataSource(ctx context.Context) error
 			return
 		}
 		ds = &SDataSource{
-			Type: monitor.DataSourceTypeInfluxdb,
+			Type: dsType,
 			Url:  url,
 		}
 		ds.Name = DefaultDataSource
 		if err := man.TableSpec().Insert(ctx, ds); err != nil {
-			log.Errorf("insert default influxdb: %v", err)
+			log.Errorf("insert default %s: %v", dsType, err)
 		}
 	}
 	wait.Forever(initF, 30*time.Second)
@@ -196,6 +206,11 @@ func (self *SDataSourceManager) GetDatabases() (jsonutils.JSONObject, error) {
 	if err != nil {
 		return jsonutils.JSONNull, errors.Wrap(err, "s.GetDefaultSource")
 	}
+	if dataSource.IsPrometheus() {
+		// prometheus has no databases, all series are in the default one
+		ret.Add(jsonutils.NewStringArray([]string{monitor.METRIC_DATABASE_TELE}), "databases")
+		return ret, nil
+	}
 	db := influxdb.NewInfluxdb(dataSource.Url)
 	//db.SetDatabase("telegraf")
 	databases, err := db.GetDatabases()
@@ -228,6 +243,9 @@ func (self *SDataSourceManager) getMeasurementQueryInfluxdb(query jsonutils.JSON
 	if err != nil {
 		return rtnMeasurements, errors.Wrap(err, "s.GetDefaultSource")
 	}
+	if dataSource.IsPrometheus() {
+		return self.getPrometheusMeasurementNames(dataSource)
+	}
 	db := influxdb.NewInfluxdb(dataSource.Url)
 	db.SetDatabase(database)
 	var buffer bytes.Buffer
@@ -273,10 +291,22 @@ func (self *SDataSourceManager) GetMeasurementsWithDescriptionInfos(query jsonut
 	if err != nil {
 		return jsonutils.JSONNull, errors.Wrap(err, "s.GetDefaultSource")
 	}
-	db := influxdb.NewInfluxdb(dataSource.Url)
-	filterMeasurements, err := self.filterMeasurementsByTime(*db, measurements, query, tagFilter)
-	if err != nil {
-		return jsonutils.JSONNull, errors.Wrap(err, "filterMeasurementsByTime error")
+	var filterMeasurements []monitor.InfluxMeasurement
+	if dataSource.IsPrometheus() {
+		timeF, err := self.getFromAndToFromParam(query)
+		if err != nil {
+			return jsonutils.JSONNull, err
+		}
+		filterMeasurements, err = self.getPrometheusMeasurements(dataSource, &timeF)
+		if err != nil {
+			return jsonutils.JSONNull, errors.Wrap(err, "getPrometheusMeasurements")
+		}
+	} else {
+		db := influxdb.NewInfluxdb(dataSource.Url)
+		filterMeasurements, err = self.filterMeasurementsByTime(*db, measurements, query, tagFilter)
+		if err != nil {
+			return jsonutils.JSONNull, errors.Wrap(err, "filterMeasurementsByTime error")
+		}
 	}
 	filterMeasurements = self.getMetricDescriptions(filterMeasurements)
 	if len(filterMeasurements) != 0 {
@@ -321,6 +351,14 @@ func (self *SDataSourceManager) GetMeasurementsWithOutTimeFilter(query jsonutils
 	if err != nil {
 		return jsonutils.JSONNull, errors.Wrap(err, "s.GetDefaultSource")
 	}
+	if dataSource.IsPrometheus() {
+		measurements, err := self.getPrometheusMeasurementNames(dataSource)
+		if err != nil {
+			return jsonutils.JSONNull, err
+		}
+		ret.Add(jsonutils.Marshal(&measurements), "measurements")
+		return ret, nil
+	}
 	db := influxdb.NewInfluxdb(dataSource.Url)
 	db.SetDatabase(database)
 	var buffer bytes.Buffer
@@ -591,13 +629,22 @@ func (self *SDataSourceManager) GetMetricMeasurement(query jsonutils.JSONObject,
 		return nil, err
 	}
 
-	db := influxdb.NewInfluxdb(dataSource.Url)
-	db.SetDatabase(database)
-
 	output := new(monitor.InfluxMeasurement)
 	output.Measurement = measurement
 	output.Database = database
 	output.TagValue = make(map[string][]string, 0)
+	if dataSource.IsPrometheus() {
+		output.FieldKey = []string{field}
+		err = self.fillPrometheusMetricTags(dataSource, field, timeF, output)
+		if err != nil {
+			return jsonutils.JSONNull, errors.Wrap(err, "fillPrometheusMetricTags")
+		}
+		self.filterRtnTags(output)
+		return jsonutils.Marshal(output), nil
+	}
+
+	db := influxdb.NewInfluxdb(dataSource.Url)
+	db.SetDatabase(database)
 	for _, val := range monitor.METRIC_ATTRI {
 		err = getAttributesOnMeasurement(database, val, output, db)
 		if err != nil {
@@ -752,6 +799,10 @@ func (self *SDataSourceManager) AddSubscription(subscription InfluxdbSubscriptio
 	if err != nil {
 		return errors.Wrap(err, "s.GetDefaultSource")
 	}
+	if dataSource.IsPrometheus() {
+		log.Infof("datasource %s is prometheus, skip influxdb subscription %s", dataSource.Name, subscription.SubName)
+		return nil
+	}
 
 	db := influxdb.NewInfluxdbWithDebug(dataSource.Url, true)
 	db.SetDatabase(subscription.DataBase)
@@ -778,6 +829,9 @@ func (self *SDataSourceManager) DropSubscription(subscription InfluxdbSubscripti
 	if err != nil {
 		return errors.Wrap(err, "s.GetDefaultSource")
 	}
+	if dataSource.IsPrometheus() {
+		return nil
+	}
 
 	db := influxdb.NewInfluxdb(dataSource.Url)
 	db.SetDatabase(subscription.DataBase)
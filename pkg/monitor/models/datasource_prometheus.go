@@ -0,0 +1,129 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+	"yunion.io/x/onecloud/pkg/monitor/tsdb/driver/prometheus"
+)
+
+// The measurement and tag pickers are built on influxdb SHOW queries, for
+// prometheus datasources the same results are derived from the series
+// metadata API. InfluxQL measurement and tag filters do not apply there and
+// are ignored.
+
+func (ds *SDataSource) IsPrometheus() bool {
+	return ds.Type == monitor.DataSourceTypePrometheus
+}
+
+func (ds *SDataSource) getPrometheusClient() (*prometheus.SMetadataClient, error) {
+	return prometheus.NewMetadataClient(ds.ToTSDBDataSource(""))
+}
+
+func parsePrometheusTimeRange(timeF *timeFilter) (time.Time, time.Time) {
+	if timeF == nil {
+		return time.Time{}, time.Time{}
+	}
+	tr := tsdb.NewTimeRange(timeF.From, timeF.To)
+	return tr.MustGetFrom(), tr.MustGetTo()
+}
+
+// getPrometheusMeasurements returns the known measurements having series
+// within timeF, with the fields found, all series are considered if timeF
+// is nil
+func (self *SDataSourceManager) getPrometheusMeasurements(ds *SDataSource, timeF *timeFilter) ([]monitor.InfluxMeasurement, error) {
+	cli, err := ds.getPrometheusClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "getPrometheusClient")
+	}
+	start, end := parsePrometheusTimeRange(timeF)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+	defer cancel()
+	names, err := cli.LabelValues(ctx, prometheus.MetricNameLabel, nil, start, end)
+	if err != nil {
+		return nil, errors.Wrap(err, "get metric names")
+	}
+	known, err := MetricMeasurementManager.getInfluxdbMeasurements()
+	if err != nil {
+		return nil, errors.Wrap(err, "getInfluxdbMeasurements")
+	}
+	knownNames := make([]string, len(known))
+	for i := range known {
+		knownNames[i] = known[i].Measurement
+	}
+	ret := make([]monitor.InfluxMeasurement, 0)
+	for _, measurement := range known {
+		fields := prometheus.MeasurementFields(measurement.Measurement, knownNames, names)
+		if len(fields) == 0 {
+			continue
+		}
+		measurement.FieldKey = fields
+		ret = append(ret, measurement)
+	}
+	return ret, nil
+}
+
+// fillPrometheusMetricTags fills tag keys and values of the series of field
+// of output within timeF
+func (self *SDataSourceManager) fillPrometheusMetricTags(ds *SDataSource, field string, timeF timeFilter, output *monitor.InfluxMeasurement) error {
+	cli, err := ds.getPrometheusClient()
+	if err != nil {
+		return errors.Wrap(err, "getPrometheusClient")
+	}
+	start, end := parsePrometheusTimeRange(&timeF)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	series, err := cli.Series(ctx, []string{prometheus.MetricName(output.Measurement, field)}, start, end)
+	if err != nil {
+		return errors.Wrap(err, "get series")
+	}
+	for _, labels := range series {
+		for key, val := range labels {
+			if key == prometheus.MetricNameLabel || filterTagKey(key) {
+				continue
+			}
+			val = self._renderTagVal(val)
+			if len(val) == 0 || filterTagValue(val) {
+				continue
+			}
+			if !utils.IsInStringArray(val, output.TagValue[key]) {
+				output.TagValue[key] = append(output.TagValue[key], val)
+			}
+		}
+	}
+	return nil
+}
+
+func (self *SDataSourceManager) getPrometheusMeasurementNames(ds *SDataSource) ([]monitor.InfluxMeasurement, error) {
+	measurements, err := self.getPrometheusMeasurements(ds, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "getPrometheusMeasurements")
+	}
+	ret := make([]monitor.InfluxMeasurement, len(measurements))
+	for i := range measurements {
+		ret[i] = monitor.InfluxMeasurement{
+			Database:    measurements[i].Database,
+			Measurement: measurements[i].Measurement,
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,229 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+var (
+	RebalancePlanManager *SRebalancePlanManager
+)
+
+func init() {
+	RebalancePlanManager = &SRebalancePlanManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SRebalancePlan{},
+			"rebalanceplan_tbl",
+			"rebalanceplan",
+			"rebalanceplans",
+		),
+	}
+	RebalancePlanManager.SetVirtualObject(RebalancePlanManager)
+}
+
+// SRebalancePlanManager stores the migration plans computed by the cluster balancer
+type SRebalancePlanManager struct {
+	db.SStatusStandaloneResourceBaseManager
+}
+
+type SRebalancePlan struct {
+	db.SStatusStandaloneResourceBase
+
+	MetricType      string               `width:"36" charset:"ascii" nullable:"false" list:"admin" get:"admin"`
+	AutoExecute     bool                 `nullable:"false" default:"false" list:"admin" get:"admin"`
+	MaxConcurrency  int                  `nullable:"false" default:"1" list:"admin" get:"admin" update:"admin"`
+	ImbalanceBefore float64              `list:"admin" get:"admin"`
+	ImbalanceAfter  float64              `list:"admin" get:"admin"`
+	Steps           jsonutils.JSONObject `nullable:"true" list:"admin" get:"admin"`
+}
+
+func (man *SRebalancePlanManager) NamespaceScope() rbacutils.TRbacScope {
+	return rbacutils.ScopeSystem
+}
+
+func (man *SRebalancePlanManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input *monitor.RebalancePlanCreateInput) (*monitor.RebalancePlanCreateInput, error) {
+	return nil, httperrors.NewUnsupportOperationError("rebalance plan is generated by the cluster balancer")
+}
+
+func (man *SRebalancePlanManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query monitor.RebalancePlanListInput) (*sqlchemy.SQuery, error) {
+	q, err := man.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.MetricType) > 0 {
+		q = q.In("metric_type", query.MetricType)
+	}
+	return q, nil
+}
+
+func (man *SRebalancePlanManager) OrderByExtraFields(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query monitor.RebalancePlanListInput) (*sqlchemy.SQuery, error) {
+	return man.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+}
+
+func (man *SRebalancePlanManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	return man.SStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (man *SRebalancePlanManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []monitor.RebalancePlanDetails {
+	rows := make([]monitor.RebalancePlanDetails, len(objs))
+	stdRows := man.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = monitor.RebalancePlanDetails{
+			StatusStandaloneResourceDetails: stdRows[i],
+		}
+		steps, _ := objs[i].(*SRebalancePlan).GetSteps()
+		rows[i].StepCount = len(steps)
+	}
+	return rows
+}
+
+// CreatePlan saves a plan computed by the balancer and expires the plans still waiting for approval,
+// which were computed on outdated metrics.
+func (man *SRebalancePlanManager) CreatePlan(ctx context.Context, userCred mcclient.TokenCredential, input *monitor.RebalancePlanCreateInput) (*SRebalancePlan, error) {
+	if err := man.ExpirePendingPlans(ctx, userCred, string(input.MetricType)); err != nil {
+		return nil, errors.Wrap(err, "ExpirePendingPlans")
+	}
+	name, err := db.GenerateName(ctx, man, nil, fmt.Sprintf("rebalance-%s", input.MetricType))
+	if err != nil {
+		return nil, errors.Wrap(err, "GenerateName")
+	}
+	for _, step := range input.Steps {
+		step.Status = monitor.REBALANCE_STEP_STATUS_PENDING
+	}
+	plan := &SRebalancePlan{
+		MetricType:      string(input.MetricType),
+		AutoExecute:     input.AutoExecute,
+		MaxConcurrency:  input.MaxConcurrency,
+		ImbalanceBefore: input.ImbalanceBefore,
+		ImbalanceAfter:  input.ImbalanceAfter,
+		Steps:           jsonutils.Marshal(input.Steps),
+	}
+	if plan.MaxConcurrency <= 0 {
+		plan.MaxConcurrency = 1
+	}
+	plan.Name = name
+	plan.Status = monitor.REBALANCE_PLAN_STATUS_PENDING_APPROVAL
+	plan.SetModelManager(man, plan)
+	if err := man.TableSpec().Insert(ctx, plan); err != nil {
+		return nil, errors.Wrap(err, "Insert")
+	}
+	db.OpsLog.LogEvent(plan, db.ACT_CREATE, plan.GetShortDesc(ctx), userCred)
+	return plan, nil
+}
+
+func (man *SRebalancePlanManager) ExpirePendingPlans(ctx context.Context, userCred mcclient.TokenCredential, metricType string) error {
+	plans := make([]SRebalancePlan, 0)
+	q := man.Query().Equals("metric_type", metricType).Equals("status", monitor.REBALANCE_PLAN_STATUS_PENDING_APPROVAL)
+	if err := db.FetchModelObjects(man, q, &plans); err != nil {
+		return errors.Wrap(err, "FetchModelObjects")
+	}
+	for i := range plans {
+		if err := plans[i].SetStatus(userCred, monitor.REBALANCE_PLAN_STATUS_EXPIRED, "superseded by a new plan"); err != nil {
+			return errors.Wrapf(err, "expire plan %s", plans[i].GetName())
+		}
+	}
+	return nil
+}
+
+func (man *SRebalancePlanManager) GetExecutingPlans(metricType string) ([]SRebalancePlan, error) {
+	plans := make([]SRebalancePlan, 0)
+	q := man.Query().Equals("metric_type", metricType).Equals("status", monitor.REBALANCE_PLAN_STATUS_EXECUTING)
+	if err := db.FetchModelObjects(man, q, &plans); err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return plans, nil
+}
+
+func (plan *SRebalancePlan) GetSteps() ([]*monitor.RebalancePlanStep, error) {
+	steps := make([]*monitor.RebalancePlanStep, 0)
+	if plan.Steps == nil {
+		return steps, nil
+	}
+	if err := plan.Steps.Unmarshal(&steps); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal steps")
+	}
+	return steps, nil
+}
+
+// SetStep persists the progress of the step at index idx
+func (plan *SRebalancePlan) SetStep(ctx context.Context, idx int, step *monitor.RebalancePlanStep) error {
+	_, err := db.UpdateWithLock(ctx, plan, func() error {
+		steps, err := plan.GetSteps()
+		if err != nil {
+			return errors.Wrap(err, "GetSteps")
+		}
+		if idx < 0 || idx >= len(steps) {
+			return errors.Errorf("step index %d out of range %d", idx, len(steps))
+		}
+		steps[idx] = step
+		plan.Steps = jsonutils.Marshal(steps)
+		return nil
+	})
+	return err
+}
+
+func (plan *SRebalancePlan) PerformApprove(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *monitor.RebalancePlanApproveInput) (jsonutils.JSONObject, error) {
+	if plan.Status != monitor.REBALANCE_PLAN_STATUS_PENDING_APPROVAL {
+		return nil, httperrors.NewInvalidStatusError("can't approve plan in status %s", plan.Status)
+	}
+	if input.MaxConcurrency > 0 {
+		if _, err := db.Update(plan, func() error {
+			plan.MaxConcurrency = input.MaxConcurrency
+			return nil
+		}); err != nil {
+			return nil, errors.Wrap(err, "update max_concurrency")
+		}
+	}
+	db.OpsLog.LogEvent(plan, "approve", input, userCred)
+	return nil, plan.StartExecuteTask(ctx, userCred, "")
+}
+
+func (plan *SRebalancePlan) PerformReject(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *monitor.RebalancePlanRejectInput) (jsonutils.JSONObject, error) {
+	if plan.Status != monitor.REBALANCE_PLAN_STATUS_PENDING_APPROVAL {
+		return nil, httperrors.NewInvalidStatusError("can't reject plan in status %s", plan.Status)
+	}
+	return nil, plan.SetStatus(userCred, monitor.REBALANCE_PLAN_STATUS_REJECTED, input.Reason)
+}
+
+func (plan *SRebalancePlan) StartExecuteTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	plan.SetStatus(userCred, monitor.REBALANCE_PLAN_STATUS_EXECUTING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "RebalancePlanExecuteTask", plan, userCred, jsonutils.NewDict(), parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
@@ -37,6 +37,16 @@ type AlerterOptions struct {
 	WorkerCheckInterval int `default:"180"`
 
 	AutoMigrationMustPair bool `default:"false" help:"result of auto migration source guests and target hosts must be paired"`
+
+	RebalanceIntervalMinutes int     `default:"0" help:"interval of cluster rebalance planning, 0 means disabled"`
+	RebalanceMetricType      string  `default:"cpu.usage_active" choices:"cpu.usage_active|mem.available" help:"metric used to measure hosts utilization when rebalancing"`
+	RebalanceThreshold       float64 `default:"10" help:"max standard deviation of hosts utilization percentage tolerated by rebalancer"`
+	RebalanceMaxMoves        int     `default:"10" help:"max number of migrations of a rebalance plan"`
+	RebalanceMaxConcurrency  int     `default:"2" help:"max number of migrations running at the same time when executing a rebalance plan"`
+	RebalanceAutoExecute     bool    `default:"false" help:"execute rebalance plan without approval"`
+
+	TsdbDriver    string `default:"influxdb" choices:"influxdb|prometheus" help:"driver of the default datasource"`
+	PrometheusUrl string `help:"url of prometheus server, use the prometheus service endpoint in catalog if not set"`
 }
 
 var (
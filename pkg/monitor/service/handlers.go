@@ -67,6 +67,7 @@ func InitHandlers(app *appsrv.Application) {
 		models.MonitorResourceManager,
 		models.AlertRecordShieldManager,
 		models.GetMigrationAlertManager(),
+		models.RebalancePlanManager,
 	} {
 		db.RegisterModelManager(manager)
 		handler := db.NewModelHandler(manager)
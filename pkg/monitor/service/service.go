@@ -24,6 +24,7 @@ import (
 	"yunion.io/x/log"
 	_ "yunion.io/x/sqlchemy/backends"
 
+	"yunion.io/x/onecloud/pkg/apis/monitor"
 	"yunion.io/x/onecloud/pkg/cloudcommon"
 	common_app "yunion.io/x/onecloud/pkg/cloudcommon/app"
 	"yunion.io/x/onecloud/pkg/cloudcommon/cronman"
@@ -42,6 +43,7 @@ import (
 	"yunion.io/x/onecloud/pkg/monitor/subscriptionmodel"
 	_ "yunion.io/x/onecloud/pkg/monitor/tasks"
 	_ "yunion.io/x/onecloud/pkg/monitor/tsdb/driver/influxdb"
+	_ "yunion.io/x/onecloud/pkg/monitor/tsdb/driver/prometheus"
 	"yunion.io/x/onecloud/pkg/monitor/worker"
 )
 
@@ -72,6 +74,15 @@ func StartService() {
 	cron.AddJobAtIntervalsWithStartRun("InitAlertResourceAdminRoleUsers", time.Duration(opts.InitAlertResourceAdminRoleUsersIntervalSeconds)*time.Second, models.GetAlertResourceManager().GetAdminRoleUsers, true)
 	cron.AddJobEveryFewDays("DeleteRecordsOfThirtyDaysAgoRecords", 1, 0, 0, 0,
 		models.AlertRecordManager.DeleteRecordsOfThirtyDaysAgo, false)
+	if opts.RebalanceIntervalMinutes > 0 {
+		cron.AddJobAtIntervals("ClusterRebalance", time.Duration(opts.RebalanceIntervalMinutes)*time.Minute, balancer.NewRebalanceCronJob(&balancer.RebalanceOptions{
+			MetricType:     monitor.MigrationAlertMetricType(opts.RebalanceMetricType),
+			Threshold:      opts.RebalanceThreshold,
+			MaxMoves:       opts.RebalanceMaxMoves,
+			MaxConcurrency: opts.RebalanceMaxConcurrency,
+			AutoExecute:    opts.RebalanceAutoExecute,
+		}, opts.Region))
+	}
 	//cron.AddJobAtIntervalsWithStartRun("MonitorResourceSync", time.Duration(opts.MonitorResourceSyncIntervalSeconds)*time.Minute*60, models.MonitorResourceManager.SyncResources, true)
 	cron.Start()
 	defer cron.Stop()
@@ -209,7 +209,8 @@ func (self *SSubscriptionManager) Eval(details monitor.CommonAlertMetricDetails,
 	*monitor.EvalMatch, error) {
 	serie := self.getPointsByAlertDetail(details, alert, points)
 	reduceCondition := monitor.Condition{
-		Type: details.Reduce,
+		Type:   details.Reduce,
+		Params: details.ReduceParams,
 	}
 	if len(details.FieldOpt) != 0 {
 		reduceCondition.Operators = []string{details.FieldOpt}
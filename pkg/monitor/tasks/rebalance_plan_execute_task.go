@@ -0,0 +1,59 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/monitor/controller/balancer"
+	"yunion.io/x/onecloud/pkg/monitor/models"
+	"yunion.io/x/onecloud/pkg/monitor/options"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type RebalancePlanExecuteTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(&RebalancePlanExecuteTask{})
+}
+
+func (self *RebalancePlanExecuteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	plan := obj.(*models.SRebalancePlan)
+	self.SetStage("OnExecuteComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		s := auth.GetAdminSession(ctx, options.Options.Region, "")
+		return nil, balancer.ExecuteRebalancePlan(ctx, s, plan)
+	})
+}
+
+func (self *RebalancePlanExecuteTask) OnExecuteComplete(ctx context.Context, plan *models.SRebalancePlan, data jsonutils.JSONObject) {
+	plan.SetStatus(self.UserCred, monitor.REBALANCE_PLAN_STATUS_COMPLETED, "")
+	logclient.AddActionLogWithStartable(self, plan, logclient.ACT_MIGRATE, nil, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *RebalancePlanExecuteTask) OnExecuteCompleteFailed(ctx context.Context, plan *models.SRebalancePlan, data jsonutils.JSONObject) {
+	plan.SetStatus(self.UserCred, monitor.REBALANCE_PLAN_STATUS_FAILED, data.String())
+	logclient.AddActionLogWithStartable(self, plan, logclient.ACT_MIGRATE, data, self.UserCred, false)
+	self.SetStageFailed(ctx, data)
+}
@@ -12,8 +12,4 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package conditions
-
-func NewCommonAlertReducer(t string) *queryReducer {
-	return &queryReducer{Type: t}
-}
+package prometheus // import "yunion.io/x/onecloud/pkg/monitor/tsdb/driver/prometheus"
@@ -0,0 +1,164 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context/ctxhttp"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+// MetricNameLabel is the label holding the metric name of a series
+const MetricNameLabel = "__name__"
+
+type metadataResponse struct {
+	Status    string          `json:"status"`
+	Data      json.RawMessage `json:"data"`
+	ErrorType string          `json:"errorType"`
+	Error     string          `json:"error"`
+}
+
+// SMetadataClient queries series metadata through the prometheus HTTP API,
+// it serves the measurement and tag pickers which use SHOW queries on
+// influxdb.
+type SMetadataClient struct {
+	dsInfo     *tsdb.DataSource
+	httpClient *http.Client
+}
+
+func NewMetadataClient(dsInfo *tsdb.DataSource) (*SMetadataClient, error) {
+	httpClient, err := dsInfo.GetHttpClient()
+	if err != nil {
+		return nil, err
+	}
+	return &SMetadataClient{
+		dsInfo:     dsInfo,
+		httpClient: httpClient,
+	}, nil
+}
+
+// LabelNames returns the label names of the series matching matches
+func (c *SMetadataClient) LabelNames(ctx context.Context, matches []string, start, end time.Time) ([]string, error) {
+	ret := make([]string, 0)
+	err := c.get(ctx, "api/v1/labels", matches, start, end, &ret)
+	if err != nil {
+		return nil, errors.Wrap(err, "labels")
+	}
+	return ret, nil
+}
+
+// LabelValues returns the values of label name of the series matching matches
+func (c *SMetadataClient) LabelValues(ctx context.Context, name string, matches []string, start, end time.Time) ([]string, error) {
+	ret := make([]string, 0)
+	err := c.get(ctx, path.Join("api/v1/label", url.PathEscape(name), "values"), matches, start, end, &ret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "label %s values", name)
+	}
+	return ret, nil
+}
+
+// Series returns the label sets of the series matching matches
+func (c *SMetadataClient) Series(ctx context.Context, matches []string, start, end time.Time) ([]map[string]string, error) {
+	ret := make([]map[string]string, 0)
+	err := c.get(ctx, "api/v1/series", matches, start, end, &ret)
+	if err != nil {
+		return nil, errors.Wrap(err, "series")
+	}
+	return ret, nil
+}
+
+func (c *SMetadataClient) get(ctx context.Context, apiPath string, matches []string, start, end time.Time, data interface{}) error {
+	u, err := url.Parse(c.dsInfo.Url)
+	if err != nil {
+		return errors.Wrapf(err, "invalid datasource url %s", c.dsInfo.Url)
+	}
+	u.Path = path.Join(u.Path, apiPath)
+	params := url.Values{}
+	for _, m := range matches {
+		params.Add("match[]", m)
+	}
+	if !start.IsZero() {
+		params.Set("start", formatUnixSeconds(start.UnixNano()))
+	}
+	if !end.IsZero() {
+		params.Set("end", formatUnixSeconds(end.UnixNano()))
+	}
+	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "OneCloud Monitor")
+	req.Header.Set("Content-type", "application/x-www-form-urlencoded")
+	if c.dsInfo.BasicAuth {
+		req.SetBasicAuth(c.dsInfo.BasicAuthUser, c.dsInfo.BasicAuthPassword)
+	} else if c.dsInfo.User != "" {
+		req.SetBasicAuth(c.dsInfo.User, c.dsInfo.Password)
+	}
+	resp, err := ctxhttp.Do(ctx, c.httpClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response metadataResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		if resp.StatusCode/100 != 2 {
+			return errors.Wrapf(ErrPrometheusInvalidResponse, "status code: %v", resp.Status)
+		}
+		return errors.Wrap(err, "decode response")
+	}
+	if response.Status != "success" {
+		return errors.Wrapf(ErrPrometheusQueryFailed, "%s: %s", response.ErrorType, response.Error)
+	}
+	if err := json.Unmarshal(response.Data, data); err != nil {
+		return errors.Wrap(err, "decode response data")
+	}
+	return nil
+}
+
+// MeasurementFields splits metric names into the fields of measurement,
+// reversing MetricName. Names of a longer measurement sharing the prefix,
+// listed in others, are skipped.
+func MeasurementFields(measurement string, others []string, names []string) []string {
+	prefix := MetricName(measurement, "")
+	ret := make([]string, 0)
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+		owned := true
+		for _, other := range others {
+			otherPrefix := MetricName(other, "")
+			if len(otherPrefix) > len(prefix) && strings.HasPrefix(name, otherPrefix) {
+				owned = false
+				break
+			}
+		}
+		if owned {
+			ret = append(ret, name[len(prefix):])
+		}
+	}
+	return ret
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/monitor"
+)
+
+type Query struct {
+	Measurement string
+	Alias       string
+	Tags        []api.MetricQueryTag
+	// GroupByTags are the label names of tag group by parts
+	GroupByTags []string
+	// GroupByAll keeps every series apart like influxdb GROUP BY *
+	GroupByAll bool
+	Selects    []*Select
+	// Step is the resolution of the range query, also used as the range
+	// window of *_over_time functions
+	Step time.Duration
+}
+
+// Select is a field followed by the functions applied to it, e.g.
+// field(usage_active), mean(), math(/ 100)
+type Select struct {
+	Field string
+	Parts []api.MetricQueryPart
+}
+
+// Response is the prometheus HTTP API response of query_range
+type Response struct {
+	Status    string       `json:"status"`
+	Data      ResponseData `json:"data"`
+	ErrorType string       `json:"errorType"`
+	Error     string       `json:"error"`
+}
+
+type ResponseData struct {
+	ResultType string         `json:"resultType"`
+	Result     []SampleStream `json:"result"`
+}
+
+// SampleStream is one series of a matrix result, values are pairs of
+// [unix seconds, "value"]
+type SampleStream struct {
+	Metric map[string]string `json:"metric"`
+	Values [][]interface{}   `json:"values"`
+}
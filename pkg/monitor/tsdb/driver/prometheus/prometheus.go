@@ -0,0 +1,163 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context/ctxhttp"
+	"moul.io/http2curl/v2"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+const (
+	ErrPrometheusInvalidResponse = errors.Error("Prometheus invalid status")
+	ErrPrometheusQueryFailed     = errors.Error("Prometheus query failed")
+)
+
+func init() {
+	tsdb.RegisterTsdbQueryEndpoint(monitor.DataSourceTypePrometheus, NewPrometheusExecutor)
+}
+
+type PrometheusExecutor struct {
+	QueryParser    *PrometheusQueryParser
+	ResponseParser *ResponseParser
+}
+
+func NewPrometheusExecutor(datasource *tsdb.DataSource) (tsdb.TsdbQueryEndpoint, error) {
+	return &PrometheusExecutor{
+		QueryParser:    &PrometheusQueryParser{},
+		ResponseParser: &ResponseParser{},
+	}, nil
+}
+
+func (e *PrometheusExecutor) Query(ctx context.Context, dsInfo *tsdb.DataSource, tsdbQuery *tsdb.TsdbQuery) (*tsdb.Response, error) {
+	if len(tsdbQuery.Queries) == 0 {
+		return nil, errors.Error("query request contains no queries")
+	}
+	httpClient, err := dsInfo.GetHttpClient()
+	if err != nil {
+		return nil, err
+	}
+	start := tsdbQuery.TimeRange.MustGetFrom()
+	end := tsdbQuery.TimeRange.MustGetTo()
+
+	result := &tsdb.Response{
+		Results: make(map[string]*tsdb.QueryResult),
+	}
+	for _, q := range tsdbQuery.Queries {
+		query, err := e.QueryParser.Parse(q, dsInfo, tsdbQuery)
+		if err != nil {
+			return nil, err
+		}
+		exprs, err := query.Build()
+		if err != nil {
+			return nil, err
+		}
+		responses := make([]*Response, 0, len(exprs))
+		for _, expr := range exprs {
+			params := url.Values{}
+			params.Set("query", expr)
+			params.Set("start", formatUnixSeconds(start.UnixNano()))
+			params.Set("end", formatUnixSeconds(end.UnixNano()))
+			params.Set("step", formatUnixSeconds(query.Step.Nanoseconds()))
+			resp, err := e.doRequest(ctx, httpClient, dsInfo, params)
+			if err != nil {
+				return nil, errors.Wrapf(err, "query %q", expr)
+			}
+			responses = append(responses, resp)
+		}
+		ret, err := e.ResponseParser.Parse(responses, query)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse response")
+		}
+		ret.RefId = q.RefId
+		ret.Meta = tsdb.QueryResultMeta{
+			RawQuery: strings.Join(exprs, "; "),
+		}
+		result.Results[q.RefId] = ret
+	}
+	return result, nil
+}
+
+func formatUnixSeconds(nano int64) string {
+	return strconv.FormatFloat(float64(nano)/1e9, 'f', -1, 64)
+}
+
+func (e *PrometheusExecutor) doRequest(ctx context.Context, httpClient *http.Client, dsInfo *tsdb.DataSource, params url.Values) (*Response, error) {
+	req, err := e.createRequest(dsInfo, params)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ctxhttp.Do(ctx, httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response Response
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&response); err != nil {
+		if resp.StatusCode/100 != 2 {
+			return nil, errors.Wrapf(ErrPrometheusInvalidResponse, "status code: %v", resp.Status)
+		}
+		return nil, errors.Wrap(err, "decode response")
+	}
+	if response.Status != "success" {
+		return nil, errors.Wrapf(ErrPrometheusQueryFailed, "%s: %s", response.ErrorType, response.Error)
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, errors.Wrapf(ErrPrometheusInvalidResponse, "status code: %v", resp.Status)
+	}
+	if response.Data.ResultType != "matrix" {
+		return nil, errors.Wrapf(ErrPrometheusInvalidResponse, "unexpected result type %s", response.Data.ResultType)
+	}
+	return &response, nil
+}
+
+func (e *PrometheusExecutor) createRequest(dsInfo *tsdb.DataSource, params url.Values) (*http.Request, error) {
+	u, err := url.Parse(dsInfo.Url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid datasource url %s", dsInfo.Url)
+	}
+	u.Path = path.Join(u.Path, "api/v1/query_range")
+	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "OneCloud Monitor")
+	req.Header.Set("Content-type", "application/x-www-form-urlencoded")
+	if dsInfo.BasicAuth {
+		req.SetBasicAuth(dsInfo.BasicAuthUser, dsInfo.BasicAuthPassword)
+	} else if dsInfo.User != "" {
+		req.SetBasicAuth(dsInfo.User, dsInfo.Password)
+	}
+
+	curlCmd, _ := http2curl.GetCurlCommand(req)
+	log.Debugf("Prometheus raw query: %q, curl: %s", params.Get("query"), curlCmd)
+	return req, nil
+}
@@ -0,0 +1,386 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"fmt"
+	gomath "math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+const (
+	ErrUnsupportedQuery = errors.Error("Query not supported by prometheus driver")
+)
+
+var (
+	regexpOperatorPattern = regexp.MustCompile(`^\/.*\/$`)
+	invalidMetricChars    = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	invalidLabelChars     = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+// sRangeFunction describes how an influxdb selector function is
+// translated: the *_over_time function applied on the range vector of each
+// step and the aggregation operator merging series of the same group.
+type sRangeFunction struct {
+	function   string
+	aggregator string
+}
+
+var rangeFunctions = map[string]sRangeFunction{
+	"mean":   {function: "avg_over_time", aggregator: "avg"},
+	"max":    {function: "max_over_time", aggregator: "max"},
+	"min":    {function: "min_over_time", aggregator: "min"},
+	"sum":    {function: "sum_over_time", aggregator: "sum"},
+	"count":  {function: "count_over_time", aggregator: "sum"},
+	"last":   {function: "last_over_time", aggregator: "avg"},
+	"stddev": {function: "stddev_over_time", aggregator: "avg"},
+	"median": {function: "quantile_over_time", aggregator: "avg"},
+	// percentile(nth) is rendered as quantile_over_time(nth/100, ...)
+	"percentile": {function: "quantile_over_time", aggregator: "avg"},
+	"spread":     {aggregator: "max"},
+	// rate like functions on counters
+	"derivative":              {function: "deriv", aggregator: "sum"},
+	"non_negative_derivative": {function: "rate", aggregator: "sum"},
+	"difference":              {function: "delta", aggregator: "sum"},
+	"non_negative_difference": {function: "increase", aggregator: "sum"},
+}
+
+type PrometheusQueryParser struct{}
+
+func (qp *PrometheusQueryParser) Parse(model *tsdb.Query, dsInfo *tsdb.DataSource, queryCtx *tsdb.TsdbQuery) (*Query, error) {
+	if len(model.Measurement) == 0 {
+		return nil, errors.Wrap(ErrUnsupportedQuery, "empty measurement")
+	}
+	query := &Query{
+		Measurement: model.Measurement,
+		Alias:       model.Alias,
+		Tags:        model.Tags,
+	}
+	minInterval, err := tsdb.GetIntervalFrom(dsInfo, model, time.Millisecond*1)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetIntervalFrom")
+	}
+	calculator := tsdb.NewIntervalCalculator(&tsdb.IntervalOptions{})
+	query.Step = calculator.Calculate(queryCtx.TimeRange, minInterval).Value
+	for _, gb := range model.GroupBy {
+		switch gb.Type {
+		case "tag":
+			if len(gb.Params) == 0 {
+				return nil, errors.Wrap(ErrUnsupportedQuery, "tag group by without tag name")
+			}
+			if gb.Params[0] == "*" {
+				query.GroupByAll = true
+				continue
+			}
+			query.GroupByTags = append(query.GroupByTags, LabelName(gb.Params[0]))
+		case "time":
+			if len(gb.Params) == 0 || isAutoInterval(gb.Params[0]) {
+				continue
+			}
+			step, err := time.ParseDuration(gb.Params[0])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid group by time %s", gb.Params[0])
+			}
+			query.Step = step
+		case "fill":
+			// prometheus never fills missing steps, null fill is the only
+			// behaviour supported
+		default:
+			return nil, errors.Wrapf(ErrUnsupportedQuery, "group by %s", gb.Type)
+		}
+	}
+	for _, sel := range model.Selects {
+		if len(sel) == 0 {
+			continue
+		}
+		if sel[0].Type != "field" || len(sel[0].Params) == 0 || sel[0].Params[0] == "*" {
+			return nil, errors.Wrapf(ErrUnsupportedQuery, "select must start with a single field, got %s", sel[0].Type)
+		}
+		query.Selects = append(query.Selects, &Select{
+			Field: sel[0].Params[0],
+			Parts: sel[1:],
+		})
+	}
+	if len(query.Selects) == 0 {
+		return nil, errors.Wrap(ErrUnsupportedQuery, "no field selected")
+	}
+	return query, nil
+}
+
+func isAutoInterval(interval string) bool {
+	switch interval {
+	case "auto", "$interval", "$__interval":
+		return true
+	}
+	return false
+}
+
+// MetricName returns the prometheus metric name of a measurement field,
+// following the telegraf prometheus output naming of measurement_field.
+func MetricName(measurement, field string) string {
+	return invalidMetricChars.ReplaceAllString(measurement+"_"+field, "_")
+}
+
+// LabelName maps an influxdb tag key to a valid prometheus label name
+func LabelName(key string) string {
+	name := invalidLabelChars.ReplaceAllString(key, "_")
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// unanchoredRegex converts an influxdb regex, which matches anywhere in the
+// value, to a prometheus one, which must match the whole value. Anchors
+// inside the pattern keep their meaning.
+func unanchoredRegex(pattern string) string {
+	return ".*(?:" + pattern + ").*"
+}
+
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		d = time.Second
+	}
+	if d%time.Second == 0 {
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+	return fmt.Sprintf("%dms", d/time.Millisecond)
+}
+
+func quoteLabelValue(val string) string {
+	return strconv.Quote(val)
+}
+
+// renderMatchers translates tag filters into prometheus label matchers.
+// Label matchers are always ANDed, so OR conditions are only accepted
+// between equality filters of the same tag, which are merged into one regex
+// matcher.
+func (query *Query) renderMatchers() ([]string, error) {
+	type sMatcher struct {
+		key    string
+		op     string
+		values []string
+	}
+	matchers := make([]*sMatcher, 0)
+	for i, tag := range query.Tags {
+		op := tag.Operator
+		value := tag.Value
+		if op == "" {
+			if regexpOperatorPattern.MatchString(value) {
+				op = "=~"
+			} else {
+				op = "="
+			}
+		}
+		switch op {
+		case "=~", "!~":
+			value = unanchoredRegex(strings.TrimSuffix(strings.TrimPrefix(value, "/"), "/"))
+		case "=", "!=":
+		case "<>":
+			op = "!="
+		default:
+			return nil, errors.Wrapf(ErrUnsupportedQuery, "tag operator %s", op)
+		}
+		if i > 0 && strings.EqualFold(tag.Condition, "OR") {
+			prev := matchers[len(matchers)-1]
+			if prev.key != LabelName(tag.Key) || prev.op != "=" || op != "=" {
+				return nil, errors.Wrapf(ErrUnsupportedQuery, "OR condition on tag %s", tag.Key)
+			}
+			prev.values = append(prev.values, value)
+			continue
+		}
+		matchers = append(matchers, &sMatcher{key: LabelName(tag.Key), op: op, values: []string{value}})
+	}
+	ret := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		if len(m.values) == 1 {
+			ret = append(ret, fmt.Sprintf("%s%s%s", m.key, m.op, quoteLabelValue(m.values[0])))
+			continue
+		}
+		alts := make([]string, len(m.values))
+		for i := range m.values {
+			alts[i] = regexp.QuoteMeta(m.values[i])
+		}
+		ret = append(ret, fmt.Sprintf("%s=~%s", m.key, quoteLabelValue(strings.Join(alts, "|"))))
+	}
+	return ret, nil
+}
+
+func (query *Query) renderAggregator(aggregator string, expr string) string {
+	if query.GroupByAll {
+		return expr
+	}
+	by := ""
+	if len(query.GroupByTags) > 0 {
+		tags := make([]string, len(query.GroupByTags))
+		copy(tags, query.GroupByTags)
+		sort.Strings(tags)
+		by = fmt.Sprintf(" by (%s)", strings.Join(tags, ", "))
+	}
+	return fmt.Sprintf("%s%s (%s)", aggregator, by, expr)
+}
+
+// Build renders one PromQL expression per select
+func (query *Query) Build() ([]string, error) {
+	matchers, err := query.renderMatchers()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0, len(query.Selects))
+	for _, sel := range query.Selects {
+		expr, err := query.renderSelect(sel, matchers)
+		if err != nil {
+			return nil, errors.Wrapf(err, "render select %s", sel.Field)
+		}
+		ret = append(ret, expr)
+	}
+	return ret, nil
+}
+
+func (query *Query) renderSelect(sel *Select, matchers []string) (string, error) {
+	expr := fmt.Sprintf("%s{%s}", MetricName(query.Measurement, sel.Field), strings.Join(matchers, ","))
+	step := formatDuration(query.Step)
+	// rangeOf returns the range vector of the current expression, raw
+	// selectors take a plain range, computed expressions a subquery
+	isSelector := true
+	rangeOf := func(window string) string {
+		if isSelector {
+			return fmt.Sprintf("%s[%s]", expr, window)
+		}
+		return fmt.Sprintf("(%s)[%s:%s]", expr, window, step)
+	}
+	aggregated := false
+	for _, part := range sel.Parts {
+		fn, isRangeFunc := rangeFunctions[part.Type]
+		switch {
+		case isRangeFunc:
+			switch part.Type {
+			case "median":
+				expr = fmt.Sprintf("%s(0.5, %s)", fn.function, rangeOf(step))
+			case "percentile":
+				if len(part.Params) == 0 {
+					return "", errors.Wrap(ErrUnsupportedQuery, "percentile without nth")
+				}
+				nth, err := strconv.ParseFloat(part.Params[0], 64)
+				if err != nil {
+					return "", errors.Wrapf(err, "invalid percentile %s", part.Params[0])
+				}
+				expr = fmt.Sprintf("%s(%s, %s)", fn.function, strconv.FormatFloat(nth/100, 'f', -1, 64), rangeOf(step))
+			case "spread":
+				expr = fmt.Sprintf("max_over_time(%s) - min_over_time(%s)", rangeOf(step), rangeOf(step))
+			case "derivative", "non_negative_derivative":
+				// derivatives need at least two samples in the window
+				expr = fmt.Sprintf("%s(%s)", fn.function, rangeOf(formatDuration(2*query.Step)))
+				unit := time.Second
+				if len(part.Params) > 0 && len(part.Params[0]) > 0 {
+					var err error
+					unit, err = time.ParseDuration(part.Params[0])
+					if err != nil {
+						return "", errors.Wrapf(err, "invalid %s unit %s", part.Type, part.Params[0])
+					}
+				}
+				if unit != time.Second {
+					expr = fmt.Sprintf("%s * %s", expr, strconv.FormatFloat(unit.Seconds(), 'f', -1, 64))
+				}
+			case "difference", "non_negative_difference":
+				expr = fmt.Sprintf("%s(%s)", fn.function, rangeOf(formatDuration(2*query.Step)))
+			default:
+				expr = fmt.Sprintf("%s(%s)", fn.function, rangeOf(step))
+			}
+			isSelector = false
+			if !aggregated {
+				expr = query.renderAggregator(fn.aggregator, expr)
+				aggregated = true
+			}
+		case part.Type == "math":
+			if len(part.Params) == 0 {
+				continue
+			}
+			math, err := parseMathPart(part.Params[0], query.Step)
+			if err != nil {
+				return "", err
+			}
+			expr = fmt.Sprintf("(%s) %s", expr, math)
+			isSelector = false
+		case part.Type == "abs":
+			expr = fmt.Sprintf("abs(%s)", expr)
+			isSelector = false
+		case part.Type == "top" || part.Type == "bottom":
+			if len(part.Params) == 0 {
+				return "", errors.Wrapf(ErrUnsupportedQuery, "%s without count", part.Type)
+			}
+			op := "topk"
+			if part.Type == "bottom" {
+				op = "bottomk"
+			}
+			if !aggregated {
+				// keep every series, top/bottom picks among them
+				expr = fmt.Sprintf("last_over_time(%s)", rangeOf(step))
+				aggregated = true
+			}
+			count, err := strconv.Atoi(strings.TrimSpace(part.Params[0]))
+			if err != nil || count <= 0 {
+				return "", errors.Wrapf(ErrUnsupportedQuery, "invalid %s count %q", part.Type, part.Params[0])
+			}
+			expr = fmt.Sprintf("%s(%d, %s)", op, count, expr)
+			isSelector = false
+		case part.Type == "alias":
+			// handled by the response parser
+		default:
+			return "", errors.Wrapf(ErrUnsupportedQuery, "function %s", part.Type)
+		}
+	}
+	if !aggregated && len(query.GroupByTags) > 0 {
+		expr = query.renderAggregator("avg", expr)
+	}
+	return expr, nil
+}
+
+// parseMathPart validates math part of `<op> <number>` format, e.g. `/ 100`,
+// the number can be $__interval_ms which is replaced by the query step
+func parseMathPart(math string, step time.Duration) (string, error) {
+	math = strings.TrimSpace(math)
+	if len(math) < 2 || !strings.ContainsRune("+-*/%^", rune(math[0])) {
+		return "", errors.Wrapf(ErrUnsupportedQuery, "invalid math %q", math)
+	}
+	operand := strings.TrimSpace(math[1:])
+	if operand == "$__interval_ms" {
+		operand = strconv.FormatInt(step.Milliseconds(), 10)
+	}
+	num, err := strconv.ParseFloat(operand, 64)
+	if err != nil || gomath.IsNaN(num) || gomath.IsInf(num, 0) {
+		return "", errors.Wrapf(ErrUnsupportedQuery, "invalid math %q", math)
+	}
+	return fmt.Sprintf("%c %s", math[0], strconv.FormatFloat(num, 'f', -1, 64)), nil
+}
+
+// columnName returns the name of the select in the result columns, the
+// alias if given otherwise the field name.
+func (sel *Select) columnName() string {
+	for _, part := range sel.Parts {
+		if part.Type == "alias" && len(part.Params) > 0 {
+			return part.Params[0]
+		}
+	}
+	return sel.Field
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "yunion.io/x/onecloud/pkg/apis/monitor"
+)
+
+func TestPrometheusQueryBuilder(t *testing.T) {
+
+	Convey("Prometheus query builder", t, func() {
+
+		mean := api.MetricQueryPart{Type: "mean"}
+		derivative := api.MetricQueryPart{Type: "non_negative_derivative", Params: []string{"1s"}}
+		divideBy100 := api.MetricQueryPart{Type: "math", Params: []string{"/ 100"}}
+
+		tag1 := api.MetricQueryTag{Key: "hostname", Value: "server1", Operator: "="}
+		tag2 := api.MetricQueryTag{Key: "hostname", Value: "server2", Operator: "=", Condition: "OR"}
+		tag3 := api.MetricQueryTag{Key: "brand", Value: "OneCloud", Operator: "!="}
+
+		Convey("can build simple query", func() {
+			query := &Query{
+				Measurement: "cpu",
+				Selects:     []*Select{{Field: "usage_active", Parts: []api.MetricQueryPart{mean}}},
+				Step:        time.Second * 10,
+			}
+			exprs, err := query.Build()
+			So(err, ShouldBeNil)
+			So(exprs, ShouldResemble, []string{`avg (avg_over_time(cpu_usage_active{}[10s]))`})
+		})
+
+		Convey("can build query with tags and group by", func() {
+			query := &Query{
+				Measurement: "cpu",
+				Tags:        []api.MetricQueryTag{tag1, tag2, tag3},
+				GroupByTags: []string{"hostname"},
+				Selects:     []*Select{{Field: "usage_active", Parts: []api.MetricQueryPart{mean, divideBy100}}},
+				Step:        time.Minute,
+			}
+			exprs, err := query.Build()
+			So(err, ShouldBeNil)
+			So(exprs, ShouldResemble, []string{`(avg by (hostname) (avg_over_time(cpu_usage_active{hostname=~"server1|server2",brand!="OneCloud"}[60s]))) / 100`})
+		})
+
+		Convey("can build derivative query", func() {
+			query := &Query{
+				Measurement: "net",
+				GroupByAll:  true,
+				Selects:     []*Select{{Field: "bytes_recv", Parts: []api.MetricQueryPart{derivative}}},
+				Step:        time.Second * 30,
+			}
+			exprs, err := query.Build()
+			So(err, ShouldBeNil)
+			So(exprs, ShouldResemble, []string{`rate(net_bytes_recv{}[60s])`})
+		})
+
+		Convey("can build regex query with unanchored patterns and sanitized labels", func() {
+			query := &Query{
+				Measurement: "vm_cpu",
+				Tags: []api.MetricQueryTag{
+					{Key: "vm_name", Value: "/^vm-/"},
+					{Key: "host.ip-addr", Value: "/web/", Operator: "!~"},
+				},
+				GroupByTags: []string{LabelName("zone-id")},
+				Selects:     []*Select{{Field: "usage_active", Parts: []api.MetricQueryPart{mean}}},
+				Step:        time.Second * 10,
+			}
+			exprs, err := query.Build()
+			So(err, ShouldBeNil)
+			So(exprs, ShouldResemble, []string{`avg by (zone_id) (avg_over_time(vm_cpu_usage_active{vm_name=~".*(?:^vm-).*",host_ip_addr!~".*(?:web).*"}[10s]))`})
+		})
+
+		Convey("can build top query and reject injected params", func() {
+			build := func(parts ...api.MetricQueryPart) ([]string, error) {
+				query := &Query{
+					Measurement: "cpu",
+					Selects:     []*Select{{Field: "usage_active", Parts: parts}},
+					Step:        time.Second * 10,
+				}
+				return query.Build()
+			}
+			exprs, err := build(api.MetricQueryPart{Type: "top", Params: []string{"3"}})
+			So(err, ShouldBeNil)
+			So(exprs, ShouldResemble, []string{`topk(3, last_over_time(cpu_usage_active{}[10s]))`})
+
+			exprs, err = build(mean, api.MetricQueryPart{Type: "math", Params: []string{"*$__interval_ms"}})
+			So(err, ShouldBeNil)
+			So(exprs, ShouldResemble, []string{`(avg (avg_over_time(cpu_usage_active{}[10s]))) * 10000`})
+
+			for _, part := range []api.MetricQueryPart{
+				{Type: "top", Params: []string{"0"}},
+				{Type: "bottom", Params: []string{"1, up) or vector(1"}},
+				{Type: "math", Params: []string{"/ 100) or up"}},
+				{Type: "math", Params: []string{"100"}},
+			} {
+				_, err := build(mean, part)
+				So(err, ShouldNotBeNil)
+			}
+		})
+
+		Convey("can not build comparison on tags", func() {
+			query := &Query{
+				Measurement: "cpu",
+				Tags:        []api.MetricQueryTag{{Key: "hostname", Value: "a", Operator: ">"}},
+				Selects:     []*Select{{Field: "usage_active"}},
+				Step:        time.Second * 10,
+			}
+			_, err := query.Build()
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestPrometheusResponseParser(t *testing.T) {
+
+	Convey("Prometheus response parser", t, func() {
+		parse := func(body string) *Response {
+			resp := new(Response)
+			dec := json.NewDecoder(strings.NewReader(body))
+			dec.UseNumber()
+			So(dec.Decode(resp), ShouldBeNil)
+			return resp
+		}
+		query := &Query{
+			Measurement: "cpu",
+			Alias:       "$tag_hostname $col",
+			Selects: []*Select{
+				{Field: "usage_active"},
+				{Field: "usage_idle"},
+			},
+		}
+		resp1 := parse(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"__name__":"cpu_usage_active","hostname":"server1"},"values":[[1600000000,"10"],[1600000010,"NaN"]]}]}}`)
+		resp2 := parse(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"__name__":"cpu_usage_idle","hostname":"server1"},"values":[[1600000010,"90"]]}]}}`)
+
+		ret, err := (&ResponseParser{}).Parse([]*Response{resp1, resp2}, query)
+		So(err, ShouldBeNil)
+		So(len(ret.Series), ShouldEqual, 1)
+		series := ret.Series[0]
+		So(series.Name, ShouldEqual, "server1 usage_active-usage_idle")
+		So(series.Columns, ShouldResemble, []string{"usage_active", "usage_idle", "time"})
+		So(series.Tags, ShouldResemble, map[string]string{"hostname": "server1"})
+		So(len(series.Points), ShouldEqual, 2)
+		So(*series.Points[0][0].(*float64), ShouldEqual, 10)
+		So(series.Points[0][1], ShouldBeNil)
+		So(series.Points[0][2], ShouldEqual, float64(1600000000000))
+		So(series.Points[1][0], ShouldBeNil)
+		So(*series.Points[1][1].(*float64), ShouldEqual, 90)
+	})
+}
+
+func TestMeasurementFields(t *testing.T) {
+	Convey("Split metric names into measurement fields", t, func() {
+		names := []string{"vm_cpu_usage_active", "vm_cpu_usage_idle", "vm_cpu_steal_usage", "vm_mem_used_percent", "vm_cpu"}
+		So(MeasurementFields("vm_cpu", []string{"vm_cpu", "vm_cpu_steal", "vm_mem"}, names), ShouldResemble, []string{"usage_active", "usage_idle"})
+		So(MeasurementFields("vm_mem", []string{"vm_cpu", "vm_mem"}, names), ShouldResemble, []string{"used_percent"})
+	})
+}
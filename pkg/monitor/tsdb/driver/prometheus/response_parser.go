@@ -0,0 +1,191 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+const metricNameLabel = "__name__"
+
+var legendFormat = regexp.MustCompile(`\[\[(\w+)(\.\w+)*\]\]*|\$\s*(\w+?)*`)
+
+type ResponseParser struct{}
+
+type sMergedSeries struct {
+	tags   map[string]string
+	points map[int64][]interface{}
+}
+
+// Parse merges the matrix results of every select of the query into time
+// series whose points carry one value per select followed by the
+// timestamp in milliseconds, the same layout the influxdb driver returns.
+func (rp *ResponseParser) Parse(responses []*Response, query *Query) (*tsdb.QueryResult, error) {
+	queryRes := tsdb.NewQueryResult()
+	merged := make(map[string]*sMergedSeries)
+	keys := make([]string, 0)
+	for selIdx, resp := range responses {
+		for _, stream := range resp.Data.Result {
+			tags := make(map[string]string)
+			for k, v := range stream.Metric {
+				if k != metricNameLabel {
+					tags[k] = v
+				}
+			}
+			key := seriesKey(tags)
+			series, ok := merged[key]
+			if !ok {
+				series = &sMergedSeries{
+					tags:   tags,
+					points: make(map[int64][]interface{}),
+				}
+				merged[key] = series
+				keys = append(keys, key)
+			}
+			for _, pair := range stream.Values {
+				ts, val, err := parseSamplePair(pair)
+				if err != nil {
+					return nil, err
+				}
+				values, ok := series.points[ts]
+				if !ok {
+					values = make([]interface{}, len(responses))
+					series.points[ts] = values
+				}
+				if val != nil {
+					values[selIdx] = val
+				}
+			}
+		}
+	}
+	sort.Strings(keys)
+	columns := make([]string, 0, len(query.Selects)+1)
+	for _, sel := range query.Selects {
+		columns = append(columns, sel.columnName())
+	}
+	col := strings.Join(columns, "-")
+	columns = append(columns, "time")
+	for _, key := range keys {
+		series := merged[key]
+		timestamps := make([]int64, 0, len(series.points))
+		for ts := range series.points {
+			timestamps = append(timestamps, ts)
+		}
+		sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+		points := make(tsdb.TimeSeriesPoints, 0, len(timestamps))
+		for _, ts := range timestamps {
+			point := make(tsdb.TimePoint, 0, len(responses)+1)
+			point = append(point, series.points[ts]...)
+			point = append(point, float64(ts))
+			points = append(points, point)
+		}
+		queryRes.Series = append(queryRes.Series, &tsdb.TimeSeries{
+			RawName: query.Measurement,
+			Name:    rp.formatSerieName(series.tags, col, query),
+			Columns: columns,
+			Points:  points,
+			Tags:    series.tags,
+		})
+	}
+	return queryRes, nil
+}
+
+func seriesKey(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(tags[k]))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+// parseSamplePair parses [unix seconds, "value"] into the timestamp in
+// milliseconds and the value, nil for NaN.
+func parseSamplePair(pair []interface{}) (int64, *float64, error) {
+	if len(pair) != 2 {
+		return 0, nil, errors.Errorf("invalid sample %v", pair)
+	}
+	var seconds float64
+	switch ts := pair[0].(type) {
+	case json.Number:
+		var err error
+		seconds, err = ts.Float64()
+		if err != nil {
+			return 0, nil, errors.Wrapf(err, "invalid timestamp %s", ts)
+		}
+	case float64:
+		seconds = ts
+	default:
+		return 0, nil, errors.Errorf("invalid timestamp %v", pair[0])
+	}
+	valStr, ok := pair[1].(string)
+	if !ok {
+		return 0, nil, errors.Errorf("invalid sample value %v", pair[1])
+	}
+	val, err := strconv.ParseFloat(valStr, 64)
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "invalid sample value %s", valStr)
+	}
+	ts := int64(math.Round(seconds * 1000))
+	if math.IsNaN(val) {
+		return ts, nil, nil
+	}
+	return ts, &val, nil
+}
+
+func (rp *ResponseParser) formatSerieName(tags map[string]string, column string, query *Query) string {
+	if query.Alias == "" {
+		return fmt.Sprintf("%s.%s", query.Measurement, column)
+	}
+	result := legendFormat.ReplaceAllFunc([]byte(query.Alias), func(in []byte) []byte {
+		aliasFormat := string(in)
+		aliasFormat = strings.Replace(aliasFormat, "[[", "", 1)
+		aliasFormat = strings.Replace(aliasFormat, "]]", "", 1)
+		aliasFormat = strings.Replace(aliasFormat, "$", "", 1)
+
+		if aliasFormat == "m" || aliasFormat == "measurement" {
+			return []byte(query.Measurement)
+		}
+		if aliasFormat == "col" {
+			return []byte(column)
+		}
+		if !strings.HasPrefix(aliasFormat, "tag_") {
+			return in
+		}
+		tagValue, exist := tags[strings.Replace(aliasFormat, "tag_", "", 1)]
+		if exist {
+			return []byte(tagValue)
+		}
+		return in
+	})
+	return string(result)
+}
@@ -15,6 +15,7 @@
 package validators
 
 import (
+	"math"
 	"strings"
 	"time"
 
@@ -43,6 +44,15 @@ var (
 	CommonAlertNotifyTypes      = []string{"email", "mobile", "dingtalk", "webconsole", "feishu"}
 
 	ConditionTypes = []string{"query", "nodata_query"}
+
+	// ReducerParamsCount is the max count of params of the reducers
+	// taking params, other reducers take none
+	ReducerParamsCount = map[string]int{
+		"percentile":       1,
+		"stddev_deviation": 1,
+		"percent_change":   1,
+		"predict_linear":   1,
+	}
 )
 
 func ValidateAlertCreateInput(input monitor.AlertCreateInput) error {
@@ -149,6 +159,17 @@ func ValidateFromAndToValue(input monitor.AlertQuery) error {
 }
 
 func ValidateAlertConditionReducer(input monitor.Condition) error {
+	if len(input.Params) > ReducerParamsCount[input.Type] {
+		return httperrors.NewInputParameterError("reducer %s takes at most %d params, got %d", input.Type, ReducerParamsCount[input.Type], len(input.Params))
+	}
+	for _, param := range input.Params {
+		if param < 0 || math.IsNaN(param) || math.IsInf(param, 0) {
+			return httperrors.NewInputParameterError("reducer %s param %v is illegal", input.Type, param)
+		}
+	}
+	if input.Type == "percentile" && len(input.Params) > 0 && input.Params[0] > 100 {
+		return httperrors.NewInputParameterError("reducer percentile param %v is out of [0, 100]", input.Params[0])
+	}
 	return nil
 }
 
@@ -147,6 +147,12 @@ func (rds *SDBInstance) GetStatus() string {
 		return api.DBINSTANCE_DELETING
 	case "rebooting":
 		return api.DBINSTANCE_REBOOTING
+	case "starting":
+		return api.DBINSTANCE_STARTING
+	case "stopping":
+		return api.DBINSTANCE_STOPPING
+	case "stopped":
+		return api.DBINSTANCE_STOPPED
 	default:
 		log.Errorf("Unknown db instance status: %s", rds.DBInstanceStatus)
 		return api.DBINSTANCE_UNKNOWN
@@ -169,6 +175,14 @@ func (rds *SDBInstance) Reboot() error {
 	return rds.region.RebootDBInstance(rds.DBInstanceIdentifier)
 }
 
+func (rds *SDBInstance) Start() error {
+	return rds.region.StartDBInstance(rds.DBInstanceIdentifier)
+}
+
+func (rds *SDBInstance) Stop() error {
+	return rds.region.StopDBInstance(rds.DBInstanceIdentifier)
+}
+
 func (self *SDBInstance) GetCategory() string {
 	switch self.Engine {
 	case "aurora", "aurora-mysql":
@@ -575,6 +589,22 @@ func (self *SRegion) RebootDBInstance(id string) error {
 	return self.rdsRequest("RebootDBInstance", params, nil)
 }
 
+// https://docs.aws.amazon.com/AmazonRDS/latest/APIReference/API_StartDBInstance.html
+func (self *SRegion) StartDBInstance(id string) error {
+	params := map[string]string{
+		"DBInstanceIdentifier": id,
+	}
+	return self.rdsRequest("StartDBInstance", params, nil)
+}
+
+// https://docs.aws.amazon.com/AmazonRDS/latest/APIReference/API_StopDBInstance.html
+func (self *SRegion) StopDBInstance(id string) error {
+	params := map[string]string{
+		"DBInstanceIdentifier": id,
+	}
+	return self.rdsRequest("StopDBInstance", params, nil)
+}
+
 func (self *SDBInstance) SetTags(tags map[string]string, replace bool) error {
 	oldTags, err := self.region.ListRdsResourceTags(self.DBInstanceArn)
 	if err != nil {
@@ -64,6 +64,14 @@ func (instance *SDBInstanceBase) Reboot() error {
 	return errors.Wrapf(cloudprovider.ErrNotImplemented, "Reboot")
 }
 
+func (instance *SDBInstanceBase) Start() error {
+	return errors.Wrapf(cloudprovider.ErrNotImplemented, "Start")
+}
+
+func (instance *SDBInstanceBase) Stop() error {
+	return errors.Wrapf(cloudprovider.ErrNotImplemented, "Stop")
+}
+
 func (instance *SDBInstanceBase) Delete() error {
 	return errors.Wrapf(cloudprovider.ErrNotImplemented, "Delete")
 }
@@ -14,11 +14,25 @@
 
 package multicloud
 
+import (
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
 type SElasticcacheBase struct {
 	SVirtualResourceBase
 	SBillingBase
 }
 
+func (self *SElasticcacheBase) Start() error {
+	return errors.Wrapf(cloudprovider.ErrNotImplemented, "Start")
+}
+
+func (self *SElasticcacheBase) Stop() error {
+	return errors.Wrapf(cloudprovider.ErrNotImplemented, "Stop")
+}
+
 func (self *SElasticcacheBase) GetBandwidth() int {
 	return 0
 }
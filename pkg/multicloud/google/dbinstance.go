@@ -202,6 +202,10 @@ func (rds *SDBInstance) IsEmulated() bool {
 func (rds *SDBInstance) GetStatus() string {
 	switch rds.State {
 	case "RUNNABLE":
+		// a stopped instance keeps RUNNABLE with activation policy NEVER
+		if rds.Settings.ActivationPolicy == "NEVER" {
+			return api.DBINSTANCE_STOPPED
+		}
 		return api.DBINSTANCE_RUNNING
 	case "PENDING_CREATE":
 		return api.DBINSTANCE_DEPLOYING
@@ -536,6 +540,24 @@ func (rds *SDBInstance) Reboot() error {
 	return rds.region.rdsDo(rds.SelfLink, "restart", nil, nil)
 }
 
+func (rds *SDBInstance) Start() error {
+	return rds.region.SetDBInstanceActivationPolicy(rds.SelfLink, "ALWAYS")
+}
+
+func (rds *SDBInstance) Stop() error {
+	return rds.region.SetDBInstanceActivationPolicy(rds.SelfLink, "NEVER")
+}
+
+// https://cloud.google.com/sql/docs/mysql/start-stop-restart-instance
+func (region *SRegion) SetDBInstanceActivationPolicy(id string, policy string) error {
+	body := map[string]interface{}{
+		"settings": map[string]interface{}{
+			"activationPolicy": policy,
+		},
+	}
+	return region.rdsPatch(id, jsonutils.Marshal(body))
+}
+
 func (rds *SDBInstance) Delete() error {
 	return rds.region.DeleteDBInstance(rds.SelfLink)
 }
@@ -31,7 +31,8 @@ import (
 	_ "yunion.io/x/onecloud/pkg/multicloud/huawei/provider"
 	_ "yunion.io/x/onecloud/pkg/multicloud/incloudsphere/provider" // private clouds
 	_ "yunion.io/x/onecloud/pkg/multicloud/jdcloud/provider"       // public clouds
-	_ "yunion.io/x/onecloud/pkg/multicloud/nutanix/provider"       // private clouds
+	_ "yunion.io/x/onecloud/pkg/multicloud/mockcloud/provider"
+	_ "yunion.io/x/onecloud/pkg/multicloud/nutanix/provider" // private clouds
 	_ "yunion.io/x/onecloud/pkg/multicloud/objectstore/ceph/provider"
 	_ "yunion.io/x/onecloud/pkg/multicloud/objectstore/provider"
 	_ "yunion.io/x/onecloud/pkg/multicloud/objectstore/xsky/provider"
@@ -0,0 +1,469 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type sObject struct {
+	cloudprovider.SBaseCloudObject
+
+	bucket *SBucket
+	acl    cloudprovider.TBucketACLType
+	data   []byte
+}
+
+type SBucket struct {
+	multicloud.SBaseBucket
+	multicloud.STagBase
+
+	region *SRegion
+
+	objects map[string]*sObject
+
+	BucketName   string
+	RegionId     string
+	StorageClass string
+	Acl          string
+	CreatedAt    time.Time
+}
+
+func (self *SRegion) GetBuckets() ([]SBucket, error) {
+	err := self.client.invoke("ListBuckets")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	ret := []SBucket{}
+	for _, bucket := range self.client.buckets {
+		if bucket.RegionId == self.RegionId {
+			ret = append(ret, *bucket)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].BucketName < ret[j].BucketName })
+	for i := range ret {
+		ret[i].region = self
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetBucket(name string) (*SBucket, error) {
+	buckets, err := self.GetBuckets()
+	if err != nil {
+		return nil, err
+	}
+	for i := range buckets {
+		if buckets[i].BucketName == name {
+			return &buckets[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, name)
+}
+
+func (self *SRegion) GetIBuckets() ([]cloudprovider.ICloudBucket, error) {
+	buckets, err := self.GetBuckets()
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudBucket{}
+	for i := range buckets {
+		ret = append(ret, &buckets[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIBucketById(name string) (cloudprovider.ICloudBucket, error) {
+	bucket, err := self.GetBucket(name)
+	if err != nil {
+		return nil, err
+	}
+	return bucket, nil
+}
+
+func (self *SRegion) GetIBucketByName(name string) (cloudprovider.ICloudBucket, error) {
+	return self.GetIBucketById(name)
+}
+
+func (self *SRegion) IBucketExist(name string) (bool, error) {
+	err := self.client.invoke("HeadBucket")
+	if err != nil {
+		return false, err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	_, ok := self.client.buckets[name]
+	return ok, nil
+}
+
+func (self *SRegion) CreateIBucket(name string, storageClassStr string, acl string) error {
+	err := self.client.invoke("CreateBucket")
+	if err != nil {
+		return err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	if _, ok := self.client.buckets[name]; ok {
+		return errors.Wrapf(cloudprovider.ErrDuplicateId, "bucket %s", name)
+	}
+	if len(acl) == 0 {
+		acl = string(cloudprovider.ACLPrivate)
+	}
+	self.client.buckets[name] = &SBucket{
+		objects:      map[string]*sObject{},
+		BucketName:   name,
+		RegionId:     self.RegionId,
+		StorageClass: storageClassStr,
+		Acl:          acl,
+		CreatedAt:    time.Now(),
+	}
+	return nil
+}
+
+func (self *SRegion) DeleteIBucket(name string) error {
+	err := self.client.invoke("DeleteBucket")
+	if err != nil {
+		return err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	bucket, ok := self.client.buckets[name]
+	if !ok {
+		return nil
+	}
+	if len(bucket.objects) > 0 {
+		return errors.Wrapf(cloudprovider.ErrInvalidStatus, "bucket %s is not empty", name)
+	}
+	delete(self.client.buckets, name)
+	return nil
+}
+
+func (self *SBucket) GetId() string {
+	return self.BucketName
+}
+
+func (self *SBucket) GetName() string {
+	return self.BucketName
+}
+
+func (self *SBucket) GetGlobalId() string {
+	return self.BucketName
+}
+
+func (self *SBucket) Refresh() error {
+	bucket, err := self.region.GetBucket(self.BucketName)
+	if err != nil {
+		return err
+	}
+	*self = *bucket
+	return nil
+}
+
+func (self *SBucket) GetProjectId() string {
+	return ""
+}
+
+func (self *SBucket) GetAcl() cloudprovider.TBucketACLType {
+	return cloudprovider.TBucketACLType(self.Acl)
+}
+
+func (self *SBucket) SetAcl(acl cloudprovider.TBucketACLType) error {
+	client := self.region.client
+	err := client.invoke("PutBucketAcl")
+	if err != nil {
+		return err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	bucket, ok := client.buckets[self.BucketName]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.BucketName)
+	}
+	bucket.Acl = string(acl)
+	self.Acl = string(acl)
+	return nil
+}
+
+func (self *SBucket) GetLocation() string {
+	return self.RegionId
+}
+
+func (self *SBucket) GetIRegion() cloudprovider.ICloudRegion {
+	return self.region
+}
+
+func (self *SBucket) GetCreatedAt() time.Time {
+	return self.CreatedAt
+}
+
+func (self *SBucket) GetCreateAt() time.Time {
+	return self.CreatedAt
+}
+
+func (self *SBucket) GetStorageClass() string {
+	return self.StorageClass
+}
+
+func (self *SBucket) GetAccessUrls() []cloudprovider.SBucketAccessUrl {
+	return []cloudprovider.SBucketAccessUrl{
+		{
+			Url:         fmt.Sprintf("https://%s.%s.oss.mockcloud.local", self.BucketName, self.RegionId),
+			Description: "bucket url",
+			Primary:     true,
+		},
+	}
+}
+
+func (self *SBucket) GetStats() cloudprovider.SBucketStats {
+	client := self.region.client
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	stats := cloudprovider.SBucketStats{}
+	for _, obj := range self.objects {
+		stats.SizeBytes += obj.SizeBytes
+		stats.ObjectCount += 1
+	}
+	return stats
+}
+
+func (self *SBucket) ListObjects(prefix string, marker string, delimiter string, maxCount int) (cloudprovider.SListObjectResult, error) {
+	result := cloudprovider.SListObjectResult{}
+	client := self.region.client
+	err := client.invoke("ListObjects")
+	if err != nil {
+		return result, err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	keys := []string{}
+	for key := range self.objects {
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	prefixes := map[string]bool{}
+	for _, key := range keys {
+		if maxCount > 0 && len(result.Objects)+len(result.CommonPrefixes) >= maxCount {
+			result.IsTruncated = true
+			break
+		}
+		result.NextMarker = key
+		if len(delimiter) > 0 {
+			if pos := strings.Index(key[len(prefix):], delimiter); pos >= 0 {
+				commonPrefix := key[:len(prefix)+pos+len(delimiter)]
+				if !prefixes[commonPrefix] {
+					prefixes[commonPrefix] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, &sObject{
+						bucket:           self,
+						SBaseCloudObject: cloudprovider.SBaseCloudObject{Key: commonPrefix},
+					})
+				}
+				continue
+			}
+		}
+		obj := *self.objects[key]
+		obj.bucket = self
+		result.Objects = append(result.Objects, &obj)
+	}
+	if !result.IsTruncated {
+		result.NextMarker = ""
+	}
+	return result, nil
+}
+
+func (self *SBucket) PutObject(ctx context.Context, key string, input io.Reader, sizeBytes int64, cannedAcl cloudprovider.TBucketACLType, storageClassStr string, meta http.Header) error {
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return errors.Wrap(err, "ReadAll")
+	}
+	client := self.region.client
+	err = client.invoke("PutObject")
+	if err != nil {
+		return err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if len(storageClassStr) == 0 {
+		storageClassStr = self.StorageClass
+	}
+	self.objects[key] = &sObject{
+		SBaseCloudObject: cloudprovider.SBaseCloudObject{
+			Key:          key,
+			SizeBytes:    int64(len(data)),
+			StorageClass: storageClassStr,
+			ETag:         fmt.Sprintf("%x", md5.Sum(data)),
+			LastModified: time.Now(),
+			Meta:         meta,
+		},
+		acl:  cannedAcl,
+		data: data,
+	}
+	return nil
+}
+
+func (self *SBucket) GetObject(ctx context.Context, key string, rangeOpt *cloudprovider.SGetObjectRange) (io.ReadCloser, error) {
+	client := self.region.client
+	err := client.invoke("GetObject")
+	if err != nil {
+		return nil, err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	obj, ok := self.objects[key]
+	if !ok {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, key)
+	}
+	data := obj.data
+	if rangeOpt != nil {
+		end := rangeOpt.End + 1
+		if end <= 0 || end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		if rangeOpt.Start > end {
+			return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "invalid range %d-%d", rangeOpt.Start, rangeOpt.End)
+		}
+		data = data[rangeOpt.Start:end]
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (self *SBucket) CopyObject(ctx context.Context, destKey string, srcBucket, srcKey string, cannedAcl cloudprovider.TBucketACLType, storageClassStr string, meta http.Header) error {
+	client := self.region.client
+	err := client.invoke("CopyObject")
+	if err != nil {
+		return err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	src, ok := client.buckets[srcBucket]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, "bucket %s", srcBucket)
+	}
+	obj, ok := src.objects[srcKey]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, "object %s", srcKey)
+	}
+	newObj := *obj
+	newObj.Key = destKey
+	newObj.LastModified = time.Now()
+	newObj.acl = cannedAcl
+	if len(storageClassStr) > 0 {
+		newObj.StorageClass = storageClassStr
+	}
+	if meta != nil {
+		newObj.Meta = meta
+	}
+	self.objects[destKey] = &newObj
+	return nil
+}
+
+func (self *SBucket) DeleteObject(ctx context.Context, key string) error {
+	client := self.region.client
+	err := client.invoke("DeleteObject")
+	if err != nil {
+		return err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	delete(self.objects, key)
+	return nil
+}
+
+func (self *SBucket) GetTempUrl(method string, key string, expire time.Duration) (string, error) {
+	return "", cloudprovider.ErrNotSupported
+}
+
+func (self *SBucket) NewMultipartUpload(ctx context.Context, key string, cannedAcl cloudprovider.TBucketACLType, storageClassStr string, meta http.Header) (string, error) {
+	return "", cloudprovider.ErrNotSupported
+}
+
+func (self *SBucket) UploadPart(ctx context.Context, key string, uploadId string, partIndex int, input io.Reader, partSize int64, offset, totalSize int64) (string, error) {
+	return "", cloudprovider.ErrNotSupported
+}
+
+func (self *SBucket) CopyPart(ctx context.Context, key string, uploadId string, partIndex int, srcBucketName string, srcKey string, srcOffset int64, srcLength int64) (string, error) {
+	return "", cloudprovider.ErrNotSupported
+}
+
+func (self *SBucket) CompleteMultipartUpload(ctx context.Context, key string, uploadId string, partEtags []string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SBucket) AbortMultipartUpload(ctx context.Context, key string, uploadId string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *sObject) GetIBucket() cloudprovider.ICloudBucket {
+	return self.bucket
+}
+
+func (self *sObject) GetAcl() cloudprovider.TBucketACLType {
+	return self.acl
+}
+
+func (self *sObject) SetAcl(acl cloudprovider.TBucketACLType) error {
+	client := self.bucket.region.client
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	obj, ok := self.bucket.objects[self.Key]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.Key)
+	}
+	obj.acl = acl
+	self.acl = acl
+	return nil
+}
+
+func (self *sObject) SetMeta(ctx context.Context, meta http.Header) error {
+	client := self.bucket.region.client
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	obj, ok := self.bucket.objects[self.Key]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.Key)
+	}
+	obj.Meta = meta
+	self.Meta = meta
+	return nil
+}
@@ -0,0 +1,355 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type SDBInstance struct {
+	multicloud.SDBInstanceBase
+	multicloud.STagBase
+
+	region *SRegion
+
+	DBInstanceId   string
+	DBInstanceName string
+	RegionId       string
+	ZoneId         string
+	VpcId          string
+	NetworkId      string
+	Address        string
+	Engine         string
+	EngineVersion  string
+	InstanceType   string
+	StorageType    string
+	VcpuCount      int
+	VmemSizeMB     int
+	DiskSizeGB     int
+	Port           int
+	Status         string
+	CreatedAt      time.Time
+}
+
+func (self *SRegion) GetDBInstances() ([]SDBInstance, error) {
+	err := self.client.invoke("DescribeDBInstances")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	ret := []SDBInstance{}
+	for _, rds := range self.client.dbinstances {
+		if rds.RegionId == self.RegionId {
+			ret = append(ret, *rds)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].DBInstanceId < ret[j].DBInstanceId })
+	for i := range ret {
+		ret[i].region = self
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetDBInstance(id string) (*SDBInstance, error) {
+	instances, err := self.GetDBInstances()
+	if err != nil {
+		return nil, err
+	}
+	for i := range instances {
+		if instances[i].DBInstanceId == id {
+			return &instances[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) GetIDBInstances() ([]cloudprovider.ICloudDBInstance, error) {
+	instances, err := self.GetDBInstances()
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudDBInstance{}
+	for i := range instances {
+		ret = append(ret, &instances[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIDBInstanceById(id string) (cloudprovider.ICloudDBInstance, error) {
+	rds, err := self.GetDBInstance(id)
+	if err != nil {
+		return nil, err
+	}
+	return rds, nil
+}
+
+func (self *SRegion) GetIDBInstanceBackups() ([]cloudprovider.ICloudDBInstanceBackup, error) {
+	return []cloudprovider.ICloudDBInstanceBackup{}, nil
+}
+
+func (self *SRegion) GetIDBInstanceSkus() ([]cloudprovider.ICloudDBInstanceSku, error) {
+	return []cloudprovider.ICloudDBInstanceSku{}, nil
+}
+
+func (self *SRegion) CreateIDBInstance(desc *cloudprovider.SManagedDBInstanceCreateConfig) (cloudprovider.ICloudDBInstance, error) {
+	network, err := self.GetNetwork(desc.NetworkId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetNetwork(%s)", desc.NetworkId)
+	}
+	err = self.client.invoke("CreateDBInstance")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	address, err := self.client.allocateIp(network.NetworkId, desc.Address)
+	if err != nil {
+		self.client.lock.Unlock()
+		return nil, errors.Wrapf(err, "allocateIp")
+	}
+	port := desc.Port
+	if port == 0 {
+		port = 3306
+	}
+	rds := &SDBInstance{
+		DBInstanceId:   newId("rds"),
+		DBInstanceName: desc.Name,
+		RegionId:       self.RegionId,
+		ZoneId:         network.ZoneId,
+		VpcId:          network.VpcId,
+		NetworkId:      network.NetworkId,
+		Address:        address,
+		Engine:         desc.Engine,
+		EngineVersion:  desc.EngineVersion,
+		InstanceType:   desc.InstanceType,
+		StorageType:    desc.StorageType,
+		VcpuCount:      desc.VcpuCount,
+		VmemSizeMB:     desc.VmemSizeMb,
+		DiskSizeGB:     desc.DiskSizeGB,
+		Port:           port,
+		Status:         api.DBINSTANCE_DEPLOYING,
+		CreatedAt:      time.Now(),
+	}
+	self.client.dbinstances[rds.DBInstanceId] = rds
+	self.client.transit(func() {
+		rds.Status = api.DBINSTANCE_RUNNING
+	})
+	self.client.lock.Unlock()
+	return self.GetDBInstance(rds.DBInstanceId)
+}
+
+func (self *SDBInstance) GetId() string {
+	return self.DBInstanceId
+}
+
+func (self *SDBInstance) GetName() string {
+	return self.DBInstanceName
+}
+
+func (self *SDBInstance) GetGlobalId() string {
+	return self.DBInstanceId
+}
+
+func (self *SDBInstance) GetStatus() string {
+	return self.Status
+}
+
+func (self *SDBInstance) GetCreatedAt() time.Time {
+	return self.CreatedAt
+}
+
+func (self *SDBInstance) Refresh() error {
+	rds, err := self.region.GetDBInstance(self.DBInstanceId)
+	if err != nil {
+		return err
+	}
+	*self = *rds
+	return nil
+}
+
+func (self *SDBInstance) GetPort() int {
+	return self.Port
+}
+
+func (self *SDBInstance) GetEngine() string {
+	return self.Engine
+}
+
+func (self *SDBInstance) GetEngineVersion() string {
+	return self.EngineVersion
+}
+
+func (self *SDBInstance) GetInstanceType() string {
+	return self.InstanceType
+}
+
+func (self *SDBInstance) GetVcpuCount() int {
+	return self.VcpuCount
+}
+
+func (self *SDBInstance) GetVmemSizeMB() int {
+	return self.VmemSizeMB
+}
+
+func (self *SDBInstance) GetDiskSizeGB() int {
+	return self.DiskSizeGB
+}
+
+func (self *SDBInstance) GetStorageType() string {
+	return self.StorageType
+}
+
+func (self *SDBInstance) GetMaintainTime() string {
+	return ""
+}
+
+func (self *SDBInstance) GetZone1Id() string {
+	return fmt.Sprintf("%s/%s", self.region.GetGlobalId(), self.ZoneId)
+}
+
+func (self *SDBInstance) GetZone2Id() string {
+	return ""
+}
+
+func (self *SDBInstance) GetZone3Id() string {
+	return ""
+}
+
+func (self *SDBInstance) GetIVpcId() string {
+	return self.VpcId
+}
+
+func (self *SDBInstance) GetInternalConnectionStr() string {
+	return fmt.Sprintf("%s:%d", self.Address, self.Port)
+}
+
+func (self *SDBInstance) GetDBNetworks() ([]cloudprovider.SDBInstanceNetwork, error) {
+	return []cloudprovider.SDBInstanceNetwork{
+		{IP: self.Address, NetworkId: self.NetworkId},
+	}, nil
+}
+
+func (self *SDBInstance) GetSecurityGroupIds() ([]string, error) {
+	return []string{}, nil
+}
+
+func (self *SDBInstance) GetIDBInstanceParameters() ([]cloudprovider.ICloudDBInstanceParameter, error) {
+	return []cloudprovider.ICloudDBInstanceParameter{}, nil
+}
+
+func (self *SDBInstance) GetIDBInstanceDatabases() ([]cloudprovider.ICloudDBInstanceDatabase, error) {
+	return []cloudprovider.ICloudDBInstanceDatabase{}, nil
+}
+
+func (self *SDBInstance) GetIDBInstanceAccounts() ([]cloudprovider.ICloudDBInstanceAccount, error) {
+	return []cloudprovider.ICloudDBInstanceAccount{}, nil
+}
+
+func (self *SDBInstance) GetIDBInstanceBackups() ([]cloudprovider.ICloudDBInstanceBackup, error) {
+	return []cloudprovider.ICloudDBInstanceBackup{}, nil
+}
+
+func (self *SDBInstance) update(action string, apply func(rds *SDBInstance) error) error {
+	client := self.region.client
+	err := client.invoke(action)
+	if err != nil {
+		return err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	rds, ok := client.dbinstances[self.DBInstanceId]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.DBInstanceId)
+	}
+	return apply(rds)
+}
+
+func (self *SDBInstance) Reboot() error {
+	client := self.region.client
+	return self.update("RebootDBInstance", func(rds *SDBInstance) error {
+		rds.Status = api.DBINSTANCE_REBOOTING
+		client.transit(func() {
+			rds.Status = api.DBINSTANCE_RUNNING
+		})
+		return nil
+	})
+}
+
+func (self *SDBInstance) Start() error {
+	client := self.region.client
+	return self.update("StartDBInstance", func(rds *SDBInstance) error {
+		if rds.Status != api.DBINSTANCE_STOPPED {
+			return errors.Errorf("dbinstance %s in status %s can not be started", rds.DBInstanceId, rds.Status)
+		}
+		rds.Status = api.DBINSTANCE_STARTING
+		client.transit(func() {
+			rds.Status = api.DBINSTANCE_RUNNING
+		})
+		return nil
+	})
+}
+
+func (self *SDBInstance) Stop() error {
+	client := self.region.client
+	return self.update("StopDBInstance", func(rds *SDBInstance) error {
+		if rds.Status != api.DBINSTANCE_RUNNING {
+			return errors.Errorf("dbinstance %s in status %s can not be stopped", rds.DBInstanceId, rds.Status)
+		}
+		rds.Status = api.DBINSTANCE_STOPPING
+		client.transit(func() {
+			rds.Status = api.DBINSTANCE_STOPPED
+		})
+		return nil
+	})
+}
+
+func (self *SDBInstance) ChangeConfig(ctx context.Context, config *cloudprovider.SManagedDBInstanceChangeConfig) error {
+	client := self.region.client
+	return self.update("ModifyDBInstanceSpec", func(rds *SDBInstance) error {
+		if config.DiskSizeGB > 0 {
+			rds.DiskSizeGB = config.DiskSizeGB
+		}
+		if len(config.InstanceType) > 0 {
+			rds.InstanceType = config.InstanceType
+		}
+		rds.Status = api.DBINSTANCE_CHANGE_CONFIG
+		client.transit(func() {
+			rds.Status = api.DBINSTANCE_RUNNING
+		})
+		return nil
+	})
+}
+
+func (self *SDBInstance) Delete() error {
+	client := self.region.client
+	return self.update("DeleteDBInstance", func(rds *SDBInstance) error {
+		rds.Status = api.DBINSTANCE_DELETING
+		client.transit(func() {
+			delete(client.dbinstances, rds.DBInstanceId)
+		})
+		return nil
+	})
+}
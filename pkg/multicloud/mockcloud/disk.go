@@ -0,0 +1,266 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type SDisk struct {
+	multicloud.SDisk
+	multicloud.STagBase
+
+	storage *SStorage
+
+	DiskId     string
+	DiskName   string
+	StorageId  string
+	InstanceId string
+	DiskType   string
+	SizeMB     int
+	Status     string
+	AutoDelete bool
+	CreatedAt  time.Time
+}
+
+// GetDisks lists the disks of the region filtered by storageId and instanceId
+// when they are not empty
+func (self *SRegion) GetDisks(storageId, instanceId string) ([]SDisk, error) {
+	storages, err := self.GetStorages("")
+	if err != nil {
+		return nil, err
+	}
+	err = self.client.invoke("DescribeDisks")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	ret := []SDisk{}
+	for i := range storages {
+		if len(storageId) > 0 && storages[i].StorageId != storageId {
+			continue
+		}
+		part := []SDisk{}
+		for _, disk := range self.client.disks {
+			if disk.StorageId != storages[i].StorageId {
+				continue
+			}
+			if len(instanceId) > 0 && disk.InstanceId != instanceId {
+				continue
+			}
+			part = append(part, *disk)
+		}
+		sort.Slice(part, func(i, j int) bool { return part[i].DiskId < part[j].DiskId })
+		for j := range part {
+			part[j].storage = &storages[i]
+		}
+		ret = append(ret, part...)
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetDisk(id string) (*SDisk, error) {
+	disks, err := self.GetDisks("", "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range disks {
+		if disks[i].DiskId == id {
+			return &disks[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) CreateDisk(storageId, name string, sizeMb int, instanceId string) (*SDisk, error) {
+	err := self.client.invoke("CreateDisk")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	if _, ok := self.client.storages[storageId]; !ok {
+		self.client.lock.Unlock()
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "storage %s", storageId)
+	}
+	disk := &SDisk{
+		DiskId:     newId("disk"),
+		DiskName:   name,
+		StorageId:  storageId,
+		InstanceId: instanceId,
+		DiskType:   api.DISK_TYPE_DATA,
+		SizeMB:     sizeMb,
+		Status:     api.DISK_ALLOCATING,
+		CreatedAt:  time.Now(),
+	}
+	self.client.disks[disk.DiskId] = disk
+	self.client.transit(func() {
+		disk.Status = api.DISK_READY
+	})
+	self.client.lock.Unlock()
+	return self.GetDisk(disk.DiskId)
+}
+
+func (self *SRegion) DeleteDisk(id string) error {
+	err := self.client.invoke("DeleteDisk")
+	if err != nil {
+		return err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	disk, ok := self.client.disks[id]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, id)
+	}
+	if len(disk.InstanceId) > 0 {
+		return errors.Wrapf(cloudprovider.ErrInvalidStatus, "disk %s is attached to %s", id, disk.InstanceId)
+	}
+	disk.Status = api.DISK_DEALLOC
+	self.client.transit(func() {
+		delete(self.client.disks, id)
+	})
+	return nil
+}
+
+func (self *SDisk) GetId() string {
+	return self.DiskId
+}
+
+func (self *SDisk) GetName() string {
+	return self.DiskName
+}
+
+func (self *SDisk) GetGlobalId() string {
+	return self.DiskId
+}
+
+func (self *SDisk) GetStatus() string {
+	return self.Status
+}
+
+func (self *SDisk) GetCreatedAt() time.Time {
+	return self.CreatedAt
+}
+
+func (self *SDisk) Refresh() error {
+	disk, err := self.storage.zone.region.GetDisk(self.DiskId)
+	if err != nil {
+		return err
+	}
+	*self = *disk
+	return nil
+}
+
+func (self *SDisk) GetIStorage() (cloudprovider.ICloudStorage, error) {
+	return self.storage, nil
+}
+
+func (self *SDisk) GetIStorageId() string {
+	return self.StorageId
+}
+
+func (self *SDisk) GetDiskFormat() string {
+	return "vhd"
+}
+
+func (self *SDisk) GetDiskSizeMB() int {
+	return self.SizeMB
+}
+
+func (self *SDisk) GetIsAutoDelete() bool {
+	return self.AutoDelete
+}
+
+func (self *SDisk) GetTemplateId() string {
+	return ""
+}
+
+func (self *SDisk) GetDiskType() string {
+	return self.DiskType
+}
+
+func (self *SDisk) GetFsFormat() string {
+	return ""
+}
+
+func (self *SDisk) GetIsNonPersistent() bool {
+	return false
+}
+
+func (self *SDisk) GetDriver() string {
+	return "virtio"
+}
+
+func (self *SDisk) GetCacheMode() string {
+	return "none"
+}
+
+func (self *SDisk) GetMountpoint() string {
+	return ""
+}
+
+func (self *SDisk) GetAccessPath() string {
+	return ""
+}
+
+func (self *SDisk) Delete(ctx context.Context) error {
+	return self.storage.zone.region.DeleteDisk(self.DiskId)
+}
+
+func (self *SDisk) CreateISnapshot(ctx context.Context, name string, desc string) (cloudprovider.ICloudSnapshot, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SDisk) GetISnapshots() ([]cloudprovider.ICloudSnapshot, error) {
+	return []cloudprovider.ICloudSnapshot{}, nil
+}
+
+func (self *SDisk) Resize(ctx context.Context, newSizeMB int64) error {
+	client := self.storage.zone.region.client
+	err := client.invoke("ResizeDisk")
+	if err != nil {
+		return err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	disk, ok := client.disks[self.DiskId]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.DiskId)
+	}
+	if int(newSizeMB) < disk.SizeMB {
+		return errors.Wrapf(cloudprovider.ErrNotSupported, "shrink disk from %dMB to %dMB", disk.SizeMB, newSizeMB)
+	}
+	disk.SizeMB = int(newSizeMB)
+	return nil
+}
+
+func (self *SDisk) Reset(ctx context.Context, snapshotId string) (string, error) {
+	return "", cloudprovider.ErrNotSupported
+}
+
+func (self *SDisk) Rebuild(ctx context.Context) error {
+	return cloudprovider.ErrNotSupported
+}
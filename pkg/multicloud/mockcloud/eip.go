@@ -0,0 +1,287 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"sort"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/netutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+// EIP_IP_START is the first address handed out to eips
+const EIP_IP_START = "100.64.0.1"
+
+type SEip struct {
+	multicloud.SEipBase
+	multicloud.STagBase
+
+	region *SRegion
+
+	EipId         string
+	EipName       string
+	RegionId      string
+	IpAddr        string
+	Bandwidth     int
+	Status        string
+	AssociateType string
+	AssociateId   string
+	CreatedAt     time.Time
+}
+
+func (self *SRegion) GetEips() ([]SEip, error) {
+	err := self.client.invoke("DescribeEips")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	ret := []SEip{}
+	for _, eip := range self.client.eips {
+		if eip.RegionId == self.RegionId {
+			ret = append(ret, *eip)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].EipId < ret[j].EipId })
+	for i := range ret {
+		ret[i].region = self
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetEip(id string) (*SEip, error) {
+	eips, err := self.GetEips()
+	if err != nil {
+		return nil, err
+	}
+	for i := range eips {
+		if eips[i].EipId == id {
+			return &eips[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) GetIEips() ([]cloudprovider.ICloudEIP, error) {
+	eips, err := self.GetEips()
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudEIP{}
+	for i := range eips {
+		ret = append(ret, &eips[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIEipById(id string) (cloudprovider.ICloudEIP, error) {
+	eip, err := self.GetEip(id)
+	if err != nil {
+		return nil, err
+	}
+	return eip, nil
+}
+
+func (self *SRegion) CreateEIP(opts *cloudprovider.SEip) (cloudprovider.ICloudEIP, error) {
+	err := self.client.invoke("AllocateEip")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	used := map[string]bool{}
+	for _, eip := range self.client.eips {
+		used[eip.IpAddr] = true
+	}
+	addr, _ := netutils.NewIPV4Addr(EIP_IP_START)
+	for used[addr.String()] {
+		addr = addr.StepUp()
+	}
+	if len(opts.IP) > 0 {
+		if used[opts.IP] {
+			self.client.lock.Unlock()
+			return nil, errors.Wrapf(ErrAddressInUse, "%s", opts.IP)
+		}
+		addr, err = netutils.NewIPV4Addr(opts.IP)
+		if err != nil {
+			self.client.lock.Unlock()
+			return nil, errors.Wrapf(err, "invalid address %s", opts.IP)
+		}
+	}
+	eip := &SEip{
+		EipId:     newId("eip"),
+		EipName:   opts.Name,
+		RegionId:  self.RegionId,
+		IpAddr:    addr.String(),
+		Bandwidth: opts.BandwidthMbps,
+		Status:    api.EIP_STATUS_ALLOCATE,
+		CreatedAt: time.Now(),
+	}
+	self.client.eips[eip.EipId] = eip
+	self.client.transit(func() {
+		eip.Status = api.EIP_STATUS_READY
+	})
+	self.client.lock.Unlock()
+	return self.GetEip(eip.EipId)
+}
+
+func (self *SRegion) DeleteEip(id string) error {
+	err := self.client.invoke("ReleaseEip")
+	if err != nil {
+		return err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	eip, ok := self.client.eips[id]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, id)
+	}
+	if len(eip.AssociateId) > 0 {
+		return errors.Wrapf(cloudprovider.ErrInvalidStatus, "eip %s is associated with %s", id, eip.AssociateId)
+	}
+	eip.Status = api.EIP_STATUS_DEALLOCATE
+	self.client.transit(func() {
+		delete(self.client.eips, id)
+	})
+	return nil
+}
+
+func (self *SEip) GetId() string {
+	return self.EipId
+}
+
+func (self *SEip) GetName() string {
+	return self.EipName
+}
+
+func (self *SEip) GetGlobalId() string {
+	return self.EipId
+}
+
+func (self *SEip) GetStatus() string {
+	return self.Status
+}
+
+func (self *SEip) GetCreatedAt() time.Time {
+	return self.CreatedAt
+}
+
+func (self *SEip) Refresh() error {
+	eip, err := self.region.GetEip(self.EipId)
+	if err != nil {
+		return err
+	}
+	*self = *eip
+	return nil
+}
+
+func (self *SEip) GetProjectId() string {
+	return ""
+}
+
+func (self *SEip) GetIpAddr() string {
+	return self.IpAddr
+}
+
+func (self *SEip) GetMode() string {
+	return api.EIP_MODE_STANDALONE_EIP
+}
+
+func (self *SEip) GetINetworkId() string {
+	return ""
+}
+
+func (self *SEip) GetAssociationType() string {
+	return self.AssociateType
+}
+
+func (self *SEip) GetAssociationExternalId() string {
+	return self.AssociateId
+}
+
+func (self *SEip) GetBandwidth() int {
+	return self.Bandwidth
+}
+
+func (self *SEip) GetInternetChargeType() string {
+	return api.EIP_CHARGE_TYPE_BY_TRAFFIC
+}
+
+func (self *SEip) Delete() error {
+	return self.region.DeleteEip(self.EipId)
+}
+
+func (self *SEip) update(action string, apply func(eip *SEip) error) error {
+	client := self.region.client
+	err := client.invoke(action)
+	if err != nil {
+		return err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	eip, ok := client.eips[self.EipId]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.EipId)
+	}
+	return apply(eip)
+}
+
+func (self *SEip) Associate(conf *cloudprovider.AssociateConfig) error {
+	client := self.region.client
+	return self.update("AssociateEip", func(eip *SEip) error {
+		if len(eip.AssociateId) > 0 && eip.AssociateId != conf.InstanceId {
+			return errors.Wrapf(cloudprovider.ErrInvalidStatus, "eip %s is associated with %s", eip.EipId, eip.AssociateId)
+		}
+		associateType := conf.AssociateType
+		if len(associateType) == 0 {
+			associateType = api.EIP_ASSOCIATE_TYPE_SERVER
+		}
+		switch associateType {
+		case api.EIP_ASSOCIATE_TYPE_SERVER:
+			if _, ok := client.instances[conf.InstanceId]; !ok {
+				return errors.Wrapf(cloudprovider.ErrNotFound, "instance %s", conf.InstanceId)
+			}
+		case api.EIP_ASSOCIATE_TYPE_LOADBALANCER:
+			if _, ok := client.loadbalancers[conf.InstanceId]; !ok {
+				return errors.Wrapf(cloudprovider.ErrNotFound, "loadbalancer %s", conf.InstanceId)
+			}
+		default:
+			return errors.Wrapf(cloudprovider.ErrNotSupported, "associate type %s", associateType)
+		}
+		eip.AssociateType, eip.AssociateId = associateType, conf.InstanceId
+		return nil
+	})
+}
+
+func (self *SEip) Dissociate() error {
+	return self.update("DissociateEip", func(eip *SEip) error {
+		eip.AssociateType, eip.AssociateId = "", ""
+		return nil
+	})
+}
+
+func (self *SEip) ChangeBandwidth(bw int) error {
+	return self.update("ModifyEipBandwidth", func(eip *SEip) error {
+		eip.Bandwidth = bw
+		return nil
+	})
+}
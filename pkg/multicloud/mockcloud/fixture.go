@@ -0,0 +1,148 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"io/ioutil"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+// SMockCloudFixture is the json document used to seed the state store, the
+// Failures and LatencyMs maps are keyed by action name or ACTION_ANY
+type SMockCloudFixture struct {
+	Regions       []SRegion
+	Zones         []SZone
+	Storages      []SStorage
+	Vpcs          []SVpc
+	Networks      []SNetwork
+	Instances     []SInstance
+	Disks         []SDisk
+	Eips          []SEip
+	Loadbalancers []SLoadbalancer
+	Buckets       []SBucket
+	DBInstances   []SDBInstance
+
+	Failures          map[string]string
+	LatencyMs         map[string]int
+	TransitionDelayMs int
+}
+
+func loadFixture(path string) (*SMockCloudFixture, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "ReadFile")
+	}
+	obj, err := jsonutils.Parse(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "jsonutils.Parse")
+	}
+	fixture := &SMockCloudFixture{}
+	err = obj.Unmarshal(fixture)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	if len(fixture.Regions) == 0 {
+		return nil, errors.Error("no region in fixture")
+	}
+	return fixture, nil
+}
+
+// defaultFixture is a single region with two zones, each with a storage and a
+// network of the default vpc
+func defaultFixture() *SMockCloudFixture {
+	return &SMockCloudFixture{
+		Regions: []SRegion{
+			{RegionId: "mock-region-1", RegionName: "Mock Region 1"},
+		},
+		Zones: []SZone{
+			{ZoneId: "mock-zone-1a", ZoneName: "Mock Zone 1a", RegionId: "mock-region-1"},
+			{ZoneId: "mock-zone-1b", ZoneName: "Mock Zone 1b", RegionId: "mock-region-1"},
+		},
+		Storages: []SStorage{
+			{StorageId: "mock-storage-1a", StorageName: "cloud_ssd", ZoneId: "mock-zone-1a", StorageType: STORAGE_TYPE_CLOUD_SSD, CapacityMB: 10 * 1024 * 1024},
+			{StorageId: "mock-storage-1b", StorageName: "cloud_ssd", ZoneId: "mock-zone-1b", StorageType: STORAGE_TYPE_CLOUD_SSD, CapacityMB: 10 * 1024 * 1024},
+		},
+		Vpcs: []SVpc{
+			{VpcId: "mock-vpc-default", VpcName: "default", RegionId: "mock-region-1", CidrBlock: "10.0.0.0/16", IsDefault: true, Status: api.VPC_STATUS_AVAILABLE},
+		},
+		Networks: []SNetwork{
+			{NetworkId: "mock-network-1a", NetworkName: "default-1a", VpcId: "mock-vpc-default", ZoneId: "mock-zone-1a", CidrBlock: "10.0.1.0/24", Status: api.NETWORK_STATUS_AVAILABLE},
+			{NetworkId: "mock-network-1b", NetworkName: "default-1b", VpcId: "mock-vpc-default", ZoneId: "mock-zone-1b", CidrBlock: "10.0.2.0/24", Status: api.NETWORK_STATUS_AVAILABLE},
+		},
+	}
+}
+
+func (self *SMockCloudClient) load(fixture *SMockCloudFixture) {
+	self.regions = fixture.Regions
+	for i := range self.regions {
+		self.regions[i].client = self
+	}
+	self.zones = map[string]*SZone{}
+	for i := range fixture.Zones {
+		self.zones[fixture.Zones[i].ZoneId] = &fixture.Zones[i]
+	}
+	self.storages = map[string]*SStorage{}
+	for i := range fixture.Storages {
+		self.storages[fixture.Storages[i].StorageId] = &fixture.Storages[i]
+	}
+	self.vpcs = map[string]*SVpc{}
+	for i := range fixture.Vpcs {
+		self.vpcs[fixture.Vpcs[i].VpcId] = &fixture.Vpcs[i]
+	}
+	self.networks = map[string]*SNetwork{}
+	for i := range fixture.Networks {
+		self.networks[fixture.Networks[i].NetworkId] = &fixture.Networks[i]
+	}
+	self.instances = map[string]*SInstance{}
+	for i := range fixture.Instances {
+		self.instances[fixture.Instances[i].InstanceId] = &fixture.Instances[i]
+	}
+	self.disks = map[string]*SDisk{}
+	for i := range fixture.Disks {
+		self.disks[fixture.Disks[i].DiskId] = &fixture.Disks[i]
+	}
+	self.eips = map[string]*SEip{}
+	for i := range fixture.Eips {
+		self.eips[fixture.Eips[i].EipId] = &fixture.Eips[i]
+	}
+	self.loadbalancers = map[string]*SLoadbalancer{}
+	for i := range fixture.Loadbalancers {
+		self.loadbalancers[fixture.Loadbalancers[i].LoadbalancerId] = &fixture.Loadbalancers[i]
+	}
+	self.buckets = map[string]*SBucket{}
+	for i := range fixture.Buckets {
+		fixture.Buckets[i].objects = map[string]*sObject{}
+		self.buckets[fixture.Buckets[i].BucketName] = &fixture.Buckets[i]
+	}
+	self.dbinstances = map[string]*SDBInstance{}
+	for i := range fixture.DBInstances {
+		self.dbinstances[fixture.DBInstances[i].DBInstanceId] = &fixture.DBInstances[i]
+	}
+
+	self.failures = map[string]*sFailure{}
+	for action, msg := range fixture.Failures {
+		self.failures[action] = &sFailure{err: errors.Error(msg)}
+	}
+	self.latency = map[string]time.Duration{}
+	for action, ms := range fixture.LatencyMs {
+		self.latency[action] = time.Duration(ms) * time.Millisecond
+	}
+	self.transitionDelay = time.Duration(fixture.TransitionDelayMs) * time.Millisecond
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+// SHost is the emulated host of a zone, every instance of the zone runs on it
+type SHost struct {
+	multicloud.SHostBase
+
+	zone *SZone
+}
+
+func (self *SHost) GetId() string {
+	return fmt.Sprintf("%s-%s", CLOUD_PROVIDER_MOCKCLOUD, self.zone.ZoneId)
+}
+
+func (self *SHost) GetName() string {
+	return fmt.Sprintf("%s-%s", CLOUD_PROVIDER_MOCKCLOUD, self.zone.ZoneName)
+}
+
+func (self *SHost) GetGlobalId() string {
+	return self.GetId()
+}
+
+func (self *SHost) IsEmulated() bool {
+	return true
+}
+
+func (self *SHost) GetStatus() string {
+	return api.HOST_STATUS_RUNNING
+}
+
+func (self *SHost) GetIVMs() ([]cloudprovider.ICloudVM, error) {
+	vms, err := self.zone.region.GetInstances(self.zone.ZoneId)
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudVM{}
+	for i := range vms {
+		vms[i].host = self
+		ret = append(ret, &vms[i])
+	}
+	return ret, nil
+}
+
+func (self *SHost) GetIVMById(id string) (cloudprovider.ICloudVM, error) {
+	vm, err := self.zone.region.GetInstance(id)
+	if err != nil {
+		return nil, err
+	}
+	if vm.ZoneId != self.zone.ZoneId {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+	}
+	vm.host = self
+	return vm, nil
+}
+
+func (self *SHost) GetIWires() ([]cloudprovider.ICloudWire, error) {
+	vpcs, err := self.zone.region.GetVpcs()
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudWire{}
+	for i := range vpcs {
+		ret = append(ret, vpcs[i].getWire(self.zone))
+	}
+	return ret, nil
+}
+
+func (self *SHost) GetIStorages() ([]cloudprovider.ICloudStorage, error) {
+	return self.zone.GetIStorages()
+}
+
+func (self *SHost) GetIStorageById(id string) (cloudprovider.ICloudStorage, error) {
+	return self.zone.GetIStorageById(id)
+}
+
+func (self *SHost) GetEnabled() bool {
+	return true
+}
+
+func (self *SHost) GetHostStatus() string {
+	return api.HOST_ONLINE
+}
+
+func (self *SHost) GetAccessIp() string {
+	return ""
+}
+
+func (self *SHost) GetAccessMac() string {
+	return ""
+}
+
+func (self *SHost) GetSysInfo() jsonutils.JSONObject {
+	info := jsonutils.NewDict()
+	info.Add(jsonutils.NewString(CLOUD_PROVIDER_MOCKCLOUD), "manufacture")
+	return info
+}
+
+func (self *SHost) GetSN() string {
+	return ""
+}
+
+func (self *SHost) GetCpuCount() int {
+	return 0
+}
+
+func (self *SHost) GetNodeCount() int8 {
+	return 0
+}
+
+func (self *SHost) GetCpuDesc() string {
+	return ""
+}
+
+func (self *SHost) GetCpuMhz() int {
+	return 0
+}
+
+func (self *SHost) GetMemSizeMB() int {
+	return 0
+}
+
+func (self *SHost) GetStorageSizeMB() int {
+	return 0
+}
+
+func (self *SHost) GetStorageType() string {
+	return api.DISK_TYPE_HYBRID
+}
+
+func (self *SHost) GetHostType() string {
+	return api.HOST_TYPE_MOCKCLOUD
+}
+
+func (self *SHost) GetIsMaintenance() bool {
+	return false
+}
+
+func (self *SHost) GetVersion() string {
+	return MOCKCLOUD_API_VERSION
+}
+
+func (self *SHost) GetIHostNics() ([]cloudprovider.ICloudHostNetInterface, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SHost) CreateVM(desc *cloudprovider.SManagedVMCreateConfig) (cloudprovider.ICloudVM, error) {
+	vm, err := self.zone.region.CreateInstance(self.zone.ZoneId, desc)
+	if err != nil {
+		return nil, err
+	}
+	vm.host = self
+	return vm, nil
+}
@@ -0,0 +1,624 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type SInstanceNic struct {
+	instance *SInstance
+
+	NetworkId string
+	IpAddr    string
+	MacAddr   string
+}
+
+type SInstance struct {
+	multicloud.SInstanceBase
+
+	host *SHost
+
+	InstanceId   string
+	InstanceName string
+	ZoneId       string
+	Hostname     string
+	Status       string
+	Cpu          int
+	MemoryMB     int
+	InstanceType string
+	ImageId      string
+	OsType       string
+	OsName       string
+	UserData     string
+	Nics         []SInstanceNic
+	Tags         map[string]string
+	CreatedAt    time.Time
+}
+
+// GetInstances lists the instances of the region, or of the zone if zoneId is
+// not empty
+func (self *SRegion) GetInstances(zoneId string) ([]SInstance, error) {
+	zones, err := self.GetZones()
+	if err != nil {
+		return nil, err
+	}
+	err = self.client.invoke("DescribeInstances")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	ret := []SInstance{}
+	for i := range zones {
+		if len(zoneId) > 0 && zones[i].ZoneId != zoneId {
+			continue
+		}
+		host := zones[i].getHost()
+		part := []SInstance{}
+		for _, instance := range self.client.instances {
+			if instance.ZoneId == zones[i].ZoneId {
+				part = append(part, *instance)
+			}
+		}
+		sort.Slice(part, func(i, j int) bool { return part[i].InstanceId < part[j].InstanceId })
+		for j := range part {
+			part[j].host = host
+			part[j].Nics = append([]SInstanceNic{}, part[j].Nics...)
+		}
+		ret = append(ret, part...)
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetInstance(id string) (*SInstance, error) {
+	instances, err := self.GetInstances("")
+	if err != nil {
+		return nil, err
+	}
+	for i := range instances {
+		if instances[i].InstanceId == id {
+			return &instances[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) CreateInstance(zoneId string, desc *cloudprovider.SManagedVMCreateConfig) (*SInstance, error) {
+	network, err := self.GetNetwork(desc.ExternalNetworkId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetNetwork(%s)", desc.ExternalNetworkId)
+	}
+	if network.ZoneId != zoneId {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "network %s not in zone %s", network.NetworkId, zoneId)
+	}
+	storages, err := self.GetStorages(zoneId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetStorages")
+	}
+	if len(storages) == 0 {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "no storage in zone %s", zoneId)
+	}
+	findStorage := func(id string) (string, error) {
+		if len(id) == 0 {
+			return storages[0].StorageId, nil
+		}
+		for i := range storages {
+			if storages[i].StorageId == id {
+				return id, nil
+			}
+		}
+		return "", errors.Wrapf(cloudprovider.ErrNotFound, "storage %s", id)
+	}
+	err = self.client.invoke("CreateInstance")
+	if err != nil {
+		return nil, err
+	}
+
+	self.client.lock.Lock()
+	ipAddr, err := self.client.allocateIp(network.NetworkId, desc.IpAddr)
+	if err != nil {
+		self.client.lock.Unlock()
+		return nil, errors.Wrapf(err, "allocateIp")
+	}
+	hostname := desc.Hostname
+	if len(hostname) == 0 {
+		hostname = desc.Name
+	}
+	instance := &SInstance{
+		InstanceId:   newId("ins"),
+		InstanceName: desc.Name,
+		ZoneId:       zoneId,
+		Hostname:     hostname,
+		Status:       api.VM_DEPLOYING,
+		Cpu:          desc.Cpu,
+		MemoryMB:     desc.MemoryMB,
+		InstanceType: desc.InstanceType,
+		ImageId:      desc.ExternalImageId,
+		OsType:       desc.OsType,
+		OsName:       desc.OsDistribution,
+		UserData:     desc.UserData,
+		Tags:         desc.Tags,
+		CreatedAt:    time.Now(),
+	}
+	instance.Nics = []SInstanceNic{
+		{NetworkId: network.NetworkId, IpAddr: ipAddr, MacAddr: newMac()},
+	}
+	disks := append([]cloudprovider.SDiskInfo{desc.SysDisk}, desc.DataDisks...)
+	for i := range disks {
+		storageId, err := findStorage(disks[i].StorageExternalId)
+		if err != nil {
+			self.client.lock.Unlock()
+			return nil, err
+		}
+		disk := &SDisk{
+			DiskId:     newId("disk"),
+			DiskName:   disks[i].Name,
+			StorageId:  storageId,
+			InstanceId: instance.InstanceId,
+			DiskType:   api.DISK_TYPE_DATA,
+			SizeMB:     disks[i].SizeGB * 1024,
+			Status:     api.DISK_READY,
+			CreatedAt:  instance.CreatedAt,
+		}
+		if i == 0 {
+			disk.DiskType = api.DISK_TYPE_SYS
+			disk.AutoDelete = true
+		}
+		self.client.disks[disk.DiskId] = disk
+	}
+	self.client.instances[instance.InstanceId] = instance
+	self.client.transit(func() {
+		instance.Status = api.VM_RUNNING
+	})
+	self.client.lock.Unlock()
+	return self.GetInstance(instance.InstanceId)
+}
+
+// setInstanceStatus changes the status of the instance to status and then to
+// target after the transition delay
+func (self *SRegion) setInstanceStatus(id string, status, target string) error {
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	instance, ok := self.client.instances[id]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, id)
+	}
+	if instance.Status == target {
+		return nil
+	}
+	instance.Status = status
+	self.client.transit(func() {
+		instance.Status = target
+	})
+	return nil
+}
+
+func (self *SRegion) DeleteInstance(id string) error {
+	err := self.client.invoke("DeleteInstance")
+	if err != nil {
+		return err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	instance, ok := self.client.instances[id]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, id)
+	}
+	instance.Status = api.VM_DELETING
+	self.client.transit(func() {
+		for diskId, disk := range self.client.disks {
+			if disk.InstanceId != id {
+				continue
+			}
+			if disk.AutoDelete {
+				delete(self.client.disks, diskId)
+				continue
+			}
+			disk.InstanceId = ""
+		}
+		for _, eip := range self.client.eips {
+			if eip.AssociateId == id {
+				eip.AssociateType, eip.AssociateId = "", ""
+			}
+		}
+		delete(self.client.instances, id)
+	})
+	return nil
+}
+
+func (self *SInstance) GetId() string {
+	return self.InstanceId
+}
+
+func (self *SInstance) GetName() string {
+	return self.InstanceName
+}
+
+func (self *SInstance) GetGlobalId() string {
+	return self.InstanceId
+}
+
+func (self *SInstance) GetStatus() string {
+	return self.Status
+}
+
+func (self *SInstance) GetCreatedAt() time.Time {
+	return self.CreatedAt
+}
+
+func (self *SInstance) Refresh() error {
+	instance, err := self.getRegion().GetInstance(self.InstanceId)
+	if err != nil {
+		return err
+	}
+	*self = *instance
+	return nil
+}
+
+func (self *SInstance) getRegion() *SRegion {
+	return self.host.zone.region
+}
+
+func (self *SInstance) GetProjectId() string {
+	return ""
+}
+
+func (self *SInstance) GetSysTags() map[string]string {
+	return nil
+}
+
+func (self *SInstance) GetTags() (map[string]string, error) {
+	return self.Tags, nil
+}
+
+func (self *SInstance) SetTags(tags map[string]string, replace bool) error {
+	client := self.getRegion().client
+	err := client.invoke("SetInstanceTags")
+	if err != nil {
+		return err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	instance, ok := client.instances[self.InstanceId]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.InstanceId)
+	}
+	newTags := map[string]string{}
+	if !replace {
+		for k, v := range instance.Tags {
+			newTags[k] = v
+		}
+	}
+	for k, v := range tags {
+		newTags[k] = v
+	}
+	instance.Tags = newTags
+	self.Tags = newTags
+	return nil
+}
+
+func (self *SInstance) GetHostname() string {
+	return self.Hostname
+}
+
+func (self *SInstance) GetIHost() cloudprovider.ICloudHost {
+	return self.host
+}
+
+func (self *SInstance) GetIHostId() string {
+	return self.host.GetGlobalId()
+}
+
+func (self *SInstance) GetIDisks() ([]cloudprovider.ICloudDisk, error) {
+	disks, err := self.getRegion().GetDisks("", self.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	// system disk first
+	sort.SliceStable(disks, func(i, j int) bool {
+		return disks[i].DiskType == api.DISK_TYPE_SYS && disks[j].DiskType != api.DISK_TYPE_SYS
+	})
+	ret := []cloudprovider.ICloudDisk{}
+	for i := range disks {
+		ret = append(ret, &disks[i])
+	}
+	return ret, nil
+}
+
+func (self *SInstance) GetINics() ([]cloudprovider.ICloudNic, error) {
+	ret := []cloudprovider.ICloudNic{}
+	for i := range self.Nics {
+		self.Nics[i].instance = self
+		ret = append(ret, &self.Nics[i])
+	}
+	return ret, nil
+}
+
+func (self *SInstance) GetIEIP() (cloudprovider.ICloudEIP, error) {
+	eips, err := self.getRegion().GetEips()
+	if err != nil {
+		return nil, err
+	}
+	for i := range eips {
+		if eips[i].AssociateId == self.InstanceId {
+			return &eips[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (self *SInstance) GetVcpuCount() int {
+	return self.Cpu
+}
+
+func (self *SInstance) GetVmemSizeMB() int {
+	return self.MemoryMB
+}
+
+func (self *SInstance) GetBootOrder() string {
+	return "dcn"
+}
+
+func (self *SInstance) GetVga() string {
+	return "std"
+}
+
+func (self *SInstance) GetVdi() string {
+	return "vnc"
+}
+
+func (self *SInstance) GetOsType() cloudprovider.TOsType {
+	if self.OsType == string(cloudprovider.OsTypeWindows) {
+		return cloudprovider.OsTypeWindows
+	}
+	return cloudprovider.OsTypeLinux
+}
+
+func (self *SInstance) GetOSName() string {
+	return self.OsName
+}
+
+func (self *SInstance) GetBios() string {
+	return "BIOS"
+}
+
+func (self *SInstance) GetMachine() string {
+	return "pc"
+}
+
+func (self *SInstance) GetInstanceType() string {
+	return self.InstanceType
+}
+
+func (self *SInstance) GetHypervisor() string {
+	return api.HYPERVISOR_MOCKCLOUD
+}
+
+func (self *SInstance) GetSecurityGroupIds() ([]string, error) {
+	return []string{}, nil
+}
+
+func (self *SInstance) AssignSecurityGroup(secgroupId string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) SetSecurityGroups(secgroupIds []string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) StartVM(ctx context.Context) error {
+	err := self.getRegion().client.invoke("StartInstance")
+	if err != nil {
+		return err
+	}
+	return self.getRegion().setInstanceStatus(self.InstanceId, api.VM_STARTING, api.VM_RUNNING)
+}
+
+func (self *SInstance) StopVM(ctx context.Context, opts *cloudprovider.ServerStopOptions) error {
+	err := self.getRegion().client.invoke("StopInstance")
+	if err != nil {
+		return err
+	}
+	return self.getRegion().setInstanceStatus(self.InstanceId, api.VM_STOPPING, api.VM_READY)
+}
+
+func (self *SInstance) DeleteVM(ctx context.Context) error {
+	return self.getRegion().DeleteInstance(self.InstanceId)
+}
+
+func (self *SInstance) update(action string, apply func(instance *SInstance) error) error {
+	client := self.getRegion().client
+	err := client.invoke(action)
+	if err != nil {
+		return err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	instance, ok := client.instances[self.InstanceId]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.InstanceId)
+	}
+	return apply(instance)
+}
+
+func (self *SInstance) UpdateVM(ctx context.Context, name string) error {
+	return self.update("ModifyInstanceAttribute", func(instance *SInstance) error {
+		instance.InstanceName = name
+		return nil
+	})
+}
+
+func (self *SInstance) UpdateUserData(userData string) error {
+	return self.update("ModifyInstanceUserData", func(instance *SInstance) error {
+		instance.UserData = userData
+		return nil
+	})
+}
+
+func (self *SInstance) DeployVM(ctx context.Context, name string, username string, password string, publicKey string, deleteKeypair bool, description string) error {
+	return self.update("DeployInstance", func(instance *SInstance) error {
+		if len(name) > 0 {
+			instance.InstanceName = name
+		}
+		return nil
+	})
+}
+
+func (self *SInstance) ChangeConfig(ctx context.Context, config *cloudprovider.SManagedVMChangeConfig) error {
+	return self.update("ModifyInstanceSpec", func(instance *SInstance) error {
+		if instance.Status != api.VM_READY {
+			return errors.Wrapf(cloudprovider.ErrInvalidStatus, "instance %s status %s", instance.InstanceId, instance.Status)
+		}
+		if config.Cpu > 0 {
+			instance.Cpu = config.Cpu
+		}
+		if config.MemoryMB > 0 {
+			instance.MemoryMB = config.MemoryMB
+		}
+		if len(config.InstanceType) > 0 {
+			instance.InstanceType = config.InstanceType
+		}
+		return nil
+	})
+}
+
+func (self *SInstance) RebuildRoot(ctx context.Context, config *cloudprovider.SManagedVMRebuildRootConfig) (string, error) {
+	client := self.getRegion().client
+	diskId := ""
+	err := self.update("RebuildInstance", func(instance *SInstance) error {
+		for id, disk := range client.disks {
+			if disk.InstanceId != instance.InstanceId || disk.DiskType != api.DISK_TYPE_SYS {
+				continue
+			}
+			newDisk := *disk
+			newDisk.DiskId = newId("disk")
+			newDisk.CreatedAt = time.Now()
+			if config.SysSizeGB > 0 {
+				newDisk.SizeMB = config.SysSizeGB * 1024
+			}
+			delete(client.disks, id)
+			client.disks[newDisk.DiskId] = &newDisk
+			diskId = newDisk.DiskId
+			break
+		}
+		if len(config.ImageId) > 0 {
+			instance.ImageId = config.ImageId
+		}
+		if len(config.OsType) > 0 {
+			instance.OsType = config.OsType
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return diskId, nil
+}
+
+func (self *SInstance) GetVNCInfo(input *cloudprovider.ServerVncInput) (*cloudprovider.ServerVncOutput, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) AttachDisk(ctx context.Context, diskId string) error {
+	client := self.getRegion().client
+	return self.update("AttachDisk", func(instance *SInstance) error {
+		disk, ok := client.disks[diskId]
+		if !ok {
+			return errors.Wrapf(cloudprovider.ErrNotFound, "disk %s", diskId)
+		}
+		if len(disk.InstanceId) > 0 {
+			return errors.Wrapf(cloudprovider.ErrInvalidStatus, "disk %s is attached to %s", diskId, disk.InstanceId)
+		}
+		storage, ok := client.storages[disk.StorageId]
+		if !ok || storage.ZoneId != instance.ZoneId {
+			return errors.Wrapf(cloudprovider.ErrInvalidStatus, "disk %s not in zone %s", diskId, instance.ZoneId)
+		}
+		disk.InstanceId = instance.InstanceId
+		return nil
+	})
+}
+
+func (self *SInstance) DetachDisk(ctx context.Context, diskId string) error {
+	client := self.getRegion().client
+	return self.update("DetachDisk", func(instance *SInstance) error {
+		disk, ok := client.disks[diskId]
+		if !ok || disk.InstanceId != instance.InstanceId {
+			return nil
+		}
+		if disk.DiskType == api.DISK_TYPE_SYS {
+			return errors.Wrapf(cloudprovider.ErrNotSupported, "detach system disk %s", diskId)
+		}
+		disk.InstanceId = ""
+		return nil
+	})
+}
+
+func (self *SInstance) GetError() error {
+	return nil
+}
+
+func (self *SInstanceNic) GetId() string {
+	return ""
+}
+
+func (self *SInstanceNic) GetIP() string {
+	return self.IpAddr
+}
+
+func (self *SInstanceNic) GetMAC() string {
+	return self.MacAddr
+}
+
+func (self *SInstanceNic) InClassicNetwork() bool {
+	return false
+}
+
+func (self *SInstanceNic) GetDriver() string {
+	return "virtio"
+}
+
+func (self *SInstanceNic) GetINetworkId() string {
+	return self.NetworkId
+}
+
+func (self *SInstanceNic) GetSubAddress() ([]string, error) {
+	return []string{}, nil
+}
+
+func (self *SInstanceNic) AssignNAddress(count int) ([]string, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SInstanceNic) AssignAddress(ipAddrs []string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SInstanceNic) UnassignAddress(ipAddrs []string) error {
+	return nil
+}
@@ -0,0 +1,312 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type SLoadbalancer struct {
+	multicloud.SLoadbalancerBase
+	multicloud.STagBase
+
+	region *SRegion
+
+	LoadbalancerId   string
+	LoadbalancerName string
+	RegionId         string
+	ZoneId           string
+	VpcId            string
+	NetworkId        string
+	Address          string
+	Spec             string
+	EgressMbps       int
+	Status           string
+	CreatedAt        time.Time
+}
+
+func (self *SRegion) GetLoadbalancers() ([]SLoadbalancer, error) {
+	err := self.client.invoke("DescribeLoadbalancers")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	ret := []SLoadbalancer{}
+	for _, lb := range self.client.loadbalancers {
+		if lb.RegionId == self.RegionId {
+			ret = append(ret, *lb)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].LoadbalancerId < ret[j].LoadbalancerId })
+	for i := range ret {
+		ret[i].region = self
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetLoadbalancer(id string) (*SLoadbalancer, error) {
+	lbs, err := self.GetLoadbalancers()
+	if err != nil {
+		return nil, err
+	}
+	for i := range lbs {
+		if lbs[i].LoadbalancerId == id {
+			return &lbs[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) GetILoadBalancers() ([]cloudprovider.ICloudLoadbalancer, error) {
+	lbs, err := self.GetLoadbalancers()
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudLoadbalancer{}
+	for i := range lbs {
+		ret = append(ret, &lbs[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetILoadBalancerById(id string) (cloudprovider.ICloudLoadbalancer, error) {
+	lb, err := self.GetLoadbalancer(id)
+	if err != nil {
+		return nil, err
+	}
+	return lb, nil
+}
+
+func (self *SRegion) CreateILoadBalancer(opts *cloudprovider.SLoadbalancer) (cloudprovider.ICloudLoadbalancer, error) {
+	if len(opts.NetworkIDs) == 0 {
+		return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "loadbalancer without network")
+	}
+	network, err := self.GetNetwork(opts.NetworkIDs[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetNetwork(%s)", opts.NetworkIDs[0])
+	}
+	err = self.client.invoke("CreateLoadbalancer")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	address, err := self.client.allocateIp(network.NetworkId, opts.Address)
+	if err != nil {
+		self.client.lock.Unlock()
+		return nil, errors.Wrapf(err, "allocateIp")
+	}
+	lb := &SLoadbalancer{
+		LoadbalancerId:   newId("lb"),
+		LoadbalancerName: opts.Name,
+		RegionId:         self.RegionId,
+		ZoneId:           network.ZoneId,
+		VpcId:            network.VpcId,
+		NetworkId:        network.NetworkId,
+		Address:          address,
+		Spec:             opts.LoadbalancerSpec,
+		EgressMbps:       opts.EgressMbps,
+		Status:           api.LB_CREATING,
+		CreatedAt:        time.Now(),
+	}
+	self.client.loadbalancers[lb.LoadbalancerId] = lb
+	self.client.transit(func() {
+		lb.Status = api.LB_STATUS_ENABLED
+	})
+	self.client.lock.Unlock()
+	return self.GetLoadbalancer(lb.LoadbalancerId)
+}
+
+func (self *SLoadbalancer) GetId() string {
+	return self.LoadbalancerId
+}
+
+func (self *SLoadbalancer) GetName() string {
+	return self.LoadbalancerName
+}
+
+func (self *SLoadbalancer) GetGlobalId() string {
+	return self.LoadbalancerId
+}
+
+func (self *SLoadbalancer) GetStatus() string {
+	return self.Status
+}
+
+func (self *SLoadbalancer) GetCreatedAt() time.Time {
+	return self.CreatedAt
+}
+
+func (self *SLoadbalancer) Refresh() error {
+	lb, err := self.region.GetLoadbalancer(self.LoadbalancerId)
+	if err != nil {
+		return err
+	}
+	*self = *lb
+	return nil
+}
+
+func (self *SLoadbalancer) GetProjectId() string {
+	return ""
+}
+
+func (self *SLoadbalancer) GetAddress() string {
+	return self.Address
+}
+
+func (self *SLoadbalancer) GetAddressType() string {
+	return api.LB_ADDR_TYPE_INTRANET
+}
+
+func (self *SLoadbalancer) GetNetworkType() string {
+	return api.LB_NETWORK_TYPE_VPC
+}
+
+func (self *SLoadbalancer) GetNetworkIds() []string {
+	return []string{self.NetworkId}
+}
+
+func (self *SLoadbalancer) GetVpcId() string {
+	return self.VpcId
+}
+
+func (self *SLoadbalancer) GetZoneId() string {
+	return fmt.Sprintf("%s/%s", self.region.GetGlobalId(), self.ZoneId)
+}
+
+func (self *SLoadbalancer) GetZone1Id() string {
+	return ""
+}
+
+func (self *SLoadbalancer) GetLoadbalancerSpec() string {
+	return self.Spec
+}
+
+func (self *SLoadbalancer) GetChargeType() string {
+	return api.LB_CHARGE_TYPE_BY_TRAFFIC
+}
+
+func (self *SLoadbalancer) GetEgressMbps() int {
+	return self.EgressMbps
+}
+
+func (self *SLoadbalancer) GetIEIP() (cloudprovider.ICloudEIP, error) {
+	eips, err := self.region.GetEips()
+	if err != nil {
+		return nil, err
+	}
+	for i := range eips {
+		if eips[i].AssociateId == self.LoadbalancerId {
+			return &eips[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (self *SLoadbalancer) setStatus(action, status string) error {
+	client := self.region.client
+	err := client.invoke(action)
+	if err != nil {
+		return err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	lb, ok := client.loadbalancers[self.LoadbalancerId]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.LoadbalancerId)
+	}
+	lb.Status = status
+	return nil
+}
+
+func (self *SLoadbalancer) Start() error {
+	return self.setStatus("StartLoadbalancer", api.LB_STATUS_ENABLED)
+}
+
+func (self *SLoadbalancer) Stop() error {
+	return self.setStatus("StopLoadbalancer", api.LB_STATUS_DISABLED)
+}
+
+func (self *SLoadbalancer) Delete(ctx context.Context) error {
+	client := self.region.client
+	err := client.invoke("DeleteLoadbalancer")
+	if err != nil {
+		return err
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	lb, ok := client.loadbalancers[self.LoadbalancerId]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, self.LoadbalancerId)
+	}
+	lb.Status = api.LB_STATUS_DELETING
+	client.transit(func() {
+		for _, eip := range client.eips {
+			if eip.AssociateId == lb.LoadbalancerId {
+				eip.AssociateType, eip.AssociateId = "", ""
+			}
+		}
+		delete(client.loadbalancers, lb.LoadbalancerId)
+	})
+	return nil
+}
+
+func (self *SLoadbalancer) GetILoadBalancerListeners() ([]cloudprovider.ICloudLoadbalancerListener, error) {
+	return []cloudprovider.ICloudLoadbalancerListener{}, nil
+}
+
+func (self *SLoadbalancer) GetILoadBalancerBackendGroups() ([]cloudprovider.ICloudLoadbalancerBackendGroup, error) {
+	return []cloudprovider.ICloudLoadbalancerBackendGroup{}, nil
+}
+
+func (self *SLoadbalancer) CreateILoadBalancerBackendGroup(group *cloudprovider.SLoadbalancerBackendGroup) (cloudprovider.ICloudLoadbalancerBackendGroup, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SLoadbalancer) GetILoadBalancerBackendGroupById(groupId string) (cloudprovider.ICloudLoadbalancerBackendGroup, error) {
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, groupId)
+}
+
+func (self *SLoadbalancer) CreateILoadBalancerListener(ctx context.Context, listener *cloudprovider.SLoadbalancerListener) (cloudprovider.ICloudLoadbalancerListener, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SLoadbalancer) GetILoadBalancerListenerById(listenerId string) (cloudprovider.ICloudLoadbalancerListener, error) {
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, listenerId)
+}
+
+func (self *SRegion) GetILoadBalancerAcls() ([]cloudprovider.ICloudLoadbalancerAcl, error) {
+	return []cloudprovider.ICloudLoadbalancerAcl{}, nil
+}
+
+func (self *SRegion) GetILoadBalancerCertificates() ([]cloudprovider.ICloudLoadbalancerCertificate, error) {
+	return []cloudprovider.ICloudLoadbalancerCertificate{}, nil
+}
+
+func (self *SRegion) GetILoadBalancerBackendGroups() ([]cloudprovider.ICloudLoadbalancerBackendGroup, error) {
+	return []cloudprovider.ICloudLoadbalancerBackendGroup{}, nil
+}
@@ -0,0 +1,300 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/stringutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+const (
+	CLOUD_PROVIDER_MOCKCLOUD = api.CLOUD_PROVIDER_MOCKCLOUD
+	MOCKCLOUD_API_VERSION    = "2022-01-01"
+
+	STORAGE_TYPE_CLOUD_SSD = "cloud_ssd"
+
+	// ACTION_ANY matches every action when injecting failures or latency
+	ACTION_ANY = "*"
+)
+
+var (
+	ErrInvalidCidr  = errors.Error("InvalidCidrError")
+	ErrAddressInUse = errors.Error("AddressInUseError")
+)
+
+type MockCloudConfig struct {
+	cpcfg cloudprovider.ProviderConfig
+
+	account string
+	fixture string
+}
+
+// NewMockCloudClientConfig creates a config for the account, fixture is the
+// path of an optional json file used to seed the state store
+func NewMockCloudClientConfig(account, fixture string) *MockCloudConfig {
+	cfg := &MockCloudConfig{
+		account: account,
+		fixture: fixture,
+	}
+	return cfg
+}
+
+func (cfg *MockCloudConfig) CloudproviderConfig(cpcfg cloudprovider.ProviderConfig) *MockCloudConfig {
+	cfg.cpcfg = cpcfg
+	return cfg
+}
+
+type sFailure struct {
+	err   error
+	count int
+}
+
+type sTransition struct {
+	at    time.Time
+	apply func()
+}
+
+type SMockCloudClient struct {
+	*MockCloudConfig
+
+	lock sync.Mutex
+
+	regions       []SRegion
+	zones         map[string]*SZone
+	storages      map[string]*SStorage
+	vpcs          map[string]*SVpc
+	networks      map[string]*SNetwork
+	instances     map[string]*SInstance
+	disks         map[string]*SDisk
+	eips          map[string]*SEip
+	loadbalancers map[string]*SLoadbalancer
+	buckets       map[string]*SBucket
+	dbinstances   map[string]*SDBInstance
+
+	failures        map[string]*sFailure
+	latency         map[string]time.Duration
+	transitionDelay time.Duration
+	transitions     []sTransition
+}
+
+var (
+	clientsLock sync.Mutex
+	clients     = map[string]*SMockCloudClient{}
+)
+
+// NewMockCloudClient returns the client of the account, the state store is
+// shared by all clients of the same account so that resources created through
+// one provider instance are visible to the following syncs
+func NewMockCloudClient(cfg *MockCloudConfig) (*SMockCloudClient, error) {
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+
+	key := cfg.account + "@" + cfg.fixture
+	if client, ok := clients[key]; ok {
+		return client, nil
+	}
+	client := &SMockCloudClient{MockCloudConfig: cfg}
+	fixture := defaultFixture()
+	if len(cfg.fixture) > 0 {
+		var err error
+		fixture, err = loadFixture(cfg.fixture)
+		if err != nil {
+			return nil, errors.Wrapf(err, "loadFixture %s", cfg.fixture)
+		}
+	}
+	client.load(fixture)
+	clients[key] = client
+	return client, nil
+}
+
+// ResetMockCloudClients drops the state of all accounts
+func ResetMockCloudClients() {
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+
+	clients = map[string]*SMockCloudClient{}
+}
+
+// InjectFailure makes the next count calls of action fail with err, a count
+// not greater than zero makes every call fail until ClearFailures is called
+func (self *SMockCloudClient) InjectFailure(action string, err error, count int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.failures[action] = &sFailure{err: err, count: count}
+}
+
+func (self *SMockCloudClient) ClearFailures() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.failures = map[string]*sFailure{}
+}
+
+// SetLatency delays every call of action by latency
+func (self *SMockCloudClient) SetLatency(action string, latency time.Duration) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if latency <= 0 {
+		delete(self.latency, action)
+		return
+	}
+	self.latency[action] = latency
+}
+
+// SetTransitionDelay sets how long resources stay in intermediate status such
+// as starting or deleting, zero makes all transitions immediate
+func (self *SMockCloudClient) SetTransitionDelay(delay time.Duration) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.transitionDelay = delay
+}
+
+func (self *SMockCloudClient) invoke(action string) error {
+	self.lock.Lock()
+	latency, ok := self.latency[action]
+	if !ok {
+		latency = self.latency[ACTION_ANY]
+	}
+	self.lock.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for _, key := range []string{action, ACTION_ANY} {
+		failure, ok := self.failures[key]
+		if !ok {
+			continue
+		}
+		if failure.count > 0 {
+			failure.count--
+			if failure.count == 0 {
+				delete(self.failures, key)
+			}
+		}
+		return errors.Wrapf(failure.err, "%s", action)
+	}
+	self.settle()
+	return nil
+}
+
+// transit applies the change after the transition delay, must be called with
+// the lock held
+func (self *SMockCloudClient) transit(apply func()) {
+	if self.transitionDelay <= 0 {
+		apply()
+		return
+	}
+	self.transitions = append(self.transitions, sTransition{
+		at:    time.Now().Add(self.transitionDelay),
+		apply: apply,
+	})
+}
+
+// settle applies the due transitions, must be called with the lock held
+func (self *SMockCloudClient) settle() {
+	now := time.Now()
+	pending := []sTransition{}
+	for i := range self.transitions {
+		if self.transitions[i].at.After(now) {
+			pending = append(pending, self.transitions[i])
+			continue
+		}
+		self.transitions[i].apply()
+	}
+	self.transitions = pending
+}
+
+func (self *SMockCloudClient) GetAccountId() string {
+	return self.account
+}
+
+func (self *SMockCloudClient) GetSubAccounts() ([]cloudprovider.SSubAccount, error) {
+	err := self.invoke("GetSubAccounts")
+	if err != nil {
+		return nil, err
+	}
+	subAccount := cloudprovider.SSubAccount{
+		Account: self.account,
+		Name:    self.cpcfg.Name,
+
+		HealthStatus: api.CLOUD_PROVIDER_HEALTH_NORMAL,
+	}
+	return []cloudprovider.SSubAccount{subAccount}, nil
+}
+
+func (self *SMockCloudClient) GetIRegions() []cloudprovider.ICloudRegion {
+	ret := []cloudprovider.ICloudRegion{}
+	for i := range self.regions {
+		self.regions[i].client = self
+		ret = append(ret, &self.regions[i])
+	}
+	return ret
+}
+
+func (self *SMockCloudClient) GetIRegionById(id string) (cloudprovider.ICloudRegion, error) {
+	iregions := self.GetIRegions()
+	for i := range iregions {
+		if iregions[i].GetGlobalId() == id {
+			return iregions[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SMockCloudClient) GetRegion(id string) (*SRegion, error) {
+	for i := range self.regions {
+		if self.regions[i].RegionId == id {
+			return &self.regions[i], nil
+		}
+	}
+	if len(id) == 0 && len(self.regions) > 0 {
+		return &self.regions[0], nil
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SMockCloudClient) GetCapabilities() []string {
+	return []string{
+		cloudprovider.CLOUD_CAPABILITY_COMPUTE,
+		cloudprovider.CLOUD_CAPABILITY_NETWORK,
+		cloudprovider.CLOUD_CAPABILITY_EIP,
+		cloudprovider.CLOUD_CAPABILITY_LOADBALANCER,
+		cloudprovider.CLOUD_CAPABILITY_OBJECTSTORE,
+		cloudprovider.CLOUD_CAPABILITY_RDS,
+	}
+}
+
+func newId(prefix string) string {
+	return fmt.Sprintf("%s-%s", prefix, stringutils.UUID4()[:8])
+}
+
+func newMac() string {
+	return fmt.Sprintf("00:16:3e:%02x:%02x:%02x", rand.Intn(256), rand.Intn(256), rand.Intn(256))
+}
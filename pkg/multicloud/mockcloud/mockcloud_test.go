@@ -0,0 +1,169 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+var (
+	_ cloudprovider.ICloudRegion       = (*SRegion)(nil)
+	_ cloudprovider.ICloudZone         = (*SZone)(nil)
+	_ cloudprovider.ICloudHost         = (*SHost)(nil)
+	_ cloudprovider.ICloudStorage      = (*SStorage)(nil)
+	_ cloudprovider.ICloudStoragecache = (*SStoragecache)(nil)
+	_ cloudprovider.ICloudVpc          = (*SVpc)(nil)
+	_ cloudprovider.ICloudWire         = (*SWire)(nil)
+	_ cloudprovider.ICloudNetwork      = (*SNetwork)(nil)
+	_ cloudprovider.ICloudVM           = (*SInstance)(nil)
+	_ cloudprovider.ICloudNic          = (*SInstanceNic)(nil)
+	_ cloudprovider.ICloudDisk         = (*SDisk)(nil)
+	_ cloudprovider.ICloudEIP          = (*SEip)(nil)
+	_ cloudprovider.ICloudLoadbalancer = (*SLoadbalancer)(nil)
+	_ cloudprovider.ICloudBucket       = (*SBucket)(nil)
+	_ cloudprovider.ICloudDBInstance   = (*SDBInstance)(nil)
+)
+
+func newTestRegion(t *testing.T) *SRegion {
+	ResetMockCloudClients()
+	client, err := NewMockCloudClient(NewMockCloudClientConfig(t.Name(), ""))
+	if err != nil {
+		t.Fatalf("NewMockCloudClient: %s", err)
+	}
+	region, err := client.GetRegion("")
+	if err != nil {
+		t.Fatalf("GetRegion: %s", err)
+	}
+	return region
+}
+
+func TestSharedState(t *testing.T) {
+	ResetMockCloudClients()
+	cfg := NewMockCloudClientConfig("account", "")
+	c1, _ := NewMockCloudClient(cfg)
+	c2, _ := NewMockCloudClient(cfg)
+	if c1 != c2 {
+		t.Errorf("clients of the same account should share the state store")
+	}
+}
+
+func TestInstanceLifecycle(t *testing.T) {
+	region := newTestRegion(t)
+	desc := &cloudprovider.SManagedVMCreateConfig{
+		Name:              "vm1",
+		ExternalNetworkId: "mock-network-1a",
+		Cpu:               2,
+		MemoryMB:          2048,
+		SysDisk:           cloudprovider.SDiskInfo{SizeGB: 30},
+		DataDisks:         []cloudprovider.SDiskInfo{{SizeGB: 100}},
+	}
+	vm, err := region.CreateInstance("mock-zone-1a", desc)
+	if err != nil {
+		t.Fatalf("CreateInstance: %s", err)
+	}
+	if vm.GetStatus() != api.VM_RUNNING {
+		t.Errorf("status want %s got %s", api.VM_RUNNING, vm.GetStatus())
+	}
+	if ip := vm.Nics[0].IpAddr; ip != "10.0.1.2" {
+		t.Errorf("ip want 10.0.1.2 got %s", ip)
+	}
+	disks, _ := vm.GetIDisks()
+	if len(disks) != 2 || disks[0].GetDiskType() != api.DISK_TYPE_SYS {
+		t.Fatalf("unexpected disks %v", disks)
+	}
+	if _, err := region.CreateInstance("mock-zone-1b", desc); errors.Cause(err) != cloudprovider.ErrNotFound {
+		t.Errorf("network of another zone should be rejected, got %v", err)
+	}
+
+	err = vm.DeleteVM(context.Background())
+	if err != nil {
+		t.Fatalf("DeleteVM: %s", err)
+	}
+	if _, err := region.GetInstance(vm.InstanceId); errors.Cause(err) != cloudprovider.ErrNotFound {
+		t.Errorf("instance should be deleted, got %v", err)
+	}
+	remains, _ := region.GetDisks("", "")
+	if len(remains) != 1 || len(remains[0].InstanceId) > 0 {
+		t.Errorf("data disk should be detached and kept, got %v", remains)
+	}
+}
+
+func TestTransitionDelay(t *testing.T) {
+	region := newTestRegion(t)
+	region.client.SetTransitionDelay(50 * time.Millisecond)
+	eip, err := region.CreateEIP(&cloudprovider.SEip{Name: "eip1", BandwidthMbps: 10})
+	if err != nil {
+		t.Fatalf("CreateEIP: %s", err)
+	}
+	if eip.GetStatus() != api.EIP_STATUS_ALLOCATE {
+		t.Errorf("status want %s got %s", api.EIP_STATUS_ALLOCATE, eip.GetStatus())
+	}
+	time.Sleep(60 * time.Millisecond)
+	eip.Refresh()
+	if eip.GetStatus() != api.EIP_STATUS_READY {
+		t.Errorf("status want %s got %s", api.EIP_STATUS_READY, eip.GetStatus())
+	}
+}
+
+func TestInjectFailure(t *testing.T) {
+	region := newTestRegion(t)
+	errThrottled := errors.Error("Throttled")
+	region.client.InjectFailure("DescribeVpcs", errThrottled, 1)
+	if _, err := region.GetVpcs(); errors.Cause(err) != errThrottled {
+		t.Errorf("want injected failure, got %v", err)
+	}
+	if _, err := region.GetVpcs(); err != nil {
+		t.Errorf("failure should be consumed, got %v", err)
+	}
+}
+
+func TestAllocateIp(t *testing.T) {
+	region := newTestRegion(t)
+	desc := &cloudprovider.SManagedVMCreateConfig{
+		Name:              "vm1",
+		ExternalNetworkId: "mock-network-1a",
+		IpAddr:            "10.0.1.10",
+	}
+	_, err := region.CreateInstance("mock-zone-1a", desc)
+	if err != nil {
+		t.Fatalf("CreateInstance: %s", err)
+	}
+	client := region.client
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	cases := []struct {
+		ipAddr string
+		want   error
+	}{
+		{"10.0.1.11", nil},
+		{"10.0.1.10", ErrAddressInUse},
+		{"10.0.2.10", ErrInvalidCidr},
+		{"10.0.1.1", ErrInvalidCidr},
+	}
+	for _, c := range cases {
+		_, err := client.allocateIp("mock-network-1a", c.ipAddr)
+		if errors.Cause(err) != c.want {
+			t.Errorf("allocateIp(%s) want %v got %v", c.ipAddr, c.want, err)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"sort"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/netutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+)
+
+type SNetwork struct {
+	multicloud.SResourceBase
+	multicloud.STagBase
+
+	wire *SWire
+
+	NetworkId   string
+	NetworkName string
+	VpcId       string
+	ZoneId      string
+	CidrBlock   string
+	Status      string
+}
+
+// GetNetworks lists the networks of the region filtered by vpcId and zoneId
+// when they are not empty
+func (self *SRegion) GetNetworks(vpcId, zoneId string) ([]SNetwork, error) {
+	vpcs, err := self.GetVpcs()
+	if err != nil {
+		return nil, err
+	}
+	zones, err := self.GetZones()
+	if err != nil {
+		return nil, err
+	}
+	err = self.client.invoke("DescribeNetworks")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	ret := []SNetwork{}
+	for i := range vpcs {
+		if len(vpcId) > 0 && vpcs[i].VpcId != vpcId {
+			continue
+		}
+		for j := range zones {
+			if len(zoneId) > 0 && zones[j].ZoneId != zoneId {
+				continue
+			}
+			wire := vpcs[i].getWire(&zones[j])
+			part := []SNetwork{}
+			for _, network := range self.client.networks {
+				if network.VpcId == vpcs[i].VpcId && network.ZoneId == zones[j].ZoneId {
+					part = append(part, *network)
+				}
+			}
+			sort.Slice(part, func(i, j int) bool { return part[i].NetworkId < part[j].NetworkId })
+			for k := range part {
+				part[k].wire = wire
+			}
+			ret = append(ret, part...)
+		}
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetNetwork(id string) (*SNetwork, error) {
+	networks, err := self.GetNetworks("", "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range networks {
+		if networks[i].NetworkId == id {
+			return &networks[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) CreateNetwork(vpcId, zoneId, name, cidr string) (*SNetwork, error) {
+	prefix, err := netutils.NewIPV4Prefix(cidr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid cidr %s", cidr)
+	}
+	vpc, err := self.GetVpc(vpcId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetVpc")
+	}
+	vpcPrefix, err := netutils.NewIPV4Prefix(vpc.CidrBlock)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid vpc cidr %s", vpc.CidrBlock)
+	}
+	if !vpcPrefix.ToIPRange().ContainsRange(prefix.ToIPRange()) {
+		return nil, errors.Wrapf(ErrInvalidCidr, "%s not in vpc cidr %s", cidr, vpc.CidrBlock)
+	}
+	err = self.client.invoke("CreateNetwork")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	for _, network := range self.client.networks {
+		if network.VpcId != vpcId {
+			continue
+		}
+		exist, _ := netutils.NewIPV4Prefix(network.CidrBlock)
+		if exist.ToIPRange().IsOverlap(prefix.ToIPRange()) {
+			self.client.lock.Unlock()
+			return nil, errors.Wrapf(ErrInvalidCidr, "%s overlaps with network %s", cidr, network.NetworkId)
+		}
+	}
+	network := &SNetwork{
+		NetworkId:   newId("network"),
+		NetworkName: name,
+		VpcId:       vpcId,
+		ZoneId:      zoneId,
+		CidrBlock:   cidr,
+		Status:      api.NETWORK_STATUS_PENDING,
+	}
+	self.client.networks[network.NetworkId] = network
+	self.client.transit(func() {
+		network.Status = api.NETWORK_STATUS_AVAILABLE
+	})
+	self.client.lock.Unlock()
+	return self.GetNetwork(network.NetworkId)
+}
+
+func (self *SRegion) DeleteNetwork(id string) error {
+	err := self.client.invoke("DeleteNetwork")
+	if err != nil {
+		return err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	network, ok := self.client.networks[id]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, id)
+	}
+	if ips := self.client.usedIps(id); len(ips) > 0 {
+		return errors.Wrapf(errors.ErrInvalidStatus, "network %s has %d addresses in use", id, len(ips))
+	}
+	network.Status = api.NETWORK_STATUS_DELETING
+	self.client.transit(func() {
+		delete(self.client.networks, id)
+	})
+	return nil
+}
+
+// usedIps returns the addresses allocated from the network, must be called
+// with the lock held
+func (self *SMockCloudClient) usedIps(networkId string) map[string]bool {
+	ret := map[string]bool{}
+	for _, instance := range self.instances {
+		for _, nic := range instance.Nics {
+			if nic.NetworkId == networkId {
+				ret[nic.IpAddr] = true
+			}
+		}
+	}
+	for _, lb := range self.loadbalancers {
+		if lb.NetworkId == networkId {
+			ret[lb.Address] = true
+		}
+	}
+	for _, rds := range self.dbinstances {
+		if rds.NetworkId == networkId {
+			ret[rds.Address] = true
+		}
+	}
+	return ret
+}
+
+// allocateIp allocates ipAddr, or the first free address if ipAddr is empty,
+// from the network, must be called with the lock held
+func (self *SMockCloudClient) allocateIp(networkId, ipAddr string) (string, error) {
+	network, ok := self.networks[networkId]
+	if !ok {
+		return "", errors.Wrapf(cloudprovider.ErrNotFound, "network %s", networkId)
+	}
+	used := self.usedIps(networkId)
+	start, _ := netutils.NewIPV4Addr(network.GetIpStart())
+	end, _ := netutils.NewIPV4Addr(network.GetIpEnd())
+	ipRange := netutils.NewIPV4AddrRange(start, end)
+	if len(ipAddr) > 0 {
+		addr, err := netutils.NewIPV4Addr(ipAddr)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid address %s", ipAddr)
+		}
+		if !ipRange.Contains(addr) {
+			return "", errors.Wrapf(ErrInvalidCidr, "%s not in network %s", ipAddr, network.CidrBlock)
+		}
+		if used[ipAddr] {
+			return "", errors.Wrapf(ErrAddressInUse, "%s", ipAddr)
+		}
+		return ipAddr, nil
+	}
+	for addr := start; ipRange.Contains(addr); addr = addr.StepUp() {
+		if !used[addr.String()] {
+			return addr.String(), nil
+		}
+	}
+	return "", errors.Wrapf(cloudprovider.ErrAddressCountExceed, "network %s", networkId)
+}
+
+func (self *SNetwork) GetId() string {
+	return self.NetworkId
+}
+
+func (self *SNetwork) GetName() string {
+	return self.NetworkName
+}
+
+func (self *SNetwork) GetGlobalId() string {
+	return self.NetworkId
+}
+
+func (self *SNetwork) GetStatus() string {
+	return self.Status
+}
+
+func (self *SNetwork) Refresh() error {
+	network, err := self.wire.vpc.region.GetNetwork(self.NetworkId)
+	if err != nil {
+		return err
+	}
+	*self = *network
+	return nil
+}
+
+func (self *SNetwork) GetProjectId() string {
+	return ""
+}
+
+func (self *SNetwork) GetIWire() cloudprovider.ICloudWire {
+	return self.wire
+}
+
+func (self *SNetwork) GetGateway() string {
+	pref, _ := netutils.NewIPV4Prefix(self.CidrBlock)
+	return pref.Address.NetAddr(pref.MaskLen).StepUp().String()
+}
+
+func (self *SNetwork) GetIpStart() string {
+	pref, _ := netutils.NewIPV4Prefix(self.CidrBlock)
+	return pref.Address.NetAddr(pref.MaskLen).StepUp().StepUp().String()
+}
+
+func (self *SNetwork) GetIpEnd() string {
+	pref, _ := netutils.NewIPV4Prefix(self.CidrBlock)
+	return pref.Address.BroadcastAddr(pref.MaskLen).StepDown().String()
+}
+
+func (self *SNetwork) GetIpMask() int8 {
+	pref, _ := netutils.NewIPV4Prefix(self.CidrBlock)
+	return pref.MaskLen
+}
+
+func (self *SNetwork) GetServerType() string {
+	return api.NETWORK_TYPE_GUEST
+}
+
+func (self *SNetwork) GetPublicScope() rbacutils.TRbacScope {
+	return rbacutils.ScopeDomain
+}
+
+func (self *SNetwork) GetAllocTimeoutSeconds() int {
+	return 120
+}
+
+func (self *SNetwork) Delete() error {
+	return self.wire.vpc.region.DeleteNetwork(self.NetworkId)
+}
@@ -0,0 +1,148 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/multicloud/mockcloud"
+)
+
+type SMockCloudProviderFactory struct {
+	cloudprovider.SPrivateCloudBaseProviderFactory
+}
+
+func (self *SMockCloudProviderFactory) GetId() string {
+	return mockcloud.CLOUD_PROVIDER_MOCKCLOUD
+}
+
+func (self *SMockCloudProviderFactory) GetName() string {
+	return mockcloud.CLOUD_PROVIDER_MOCKCLOUD
+}
+
+func (self *SMockCloudProviderFactory) ValidateChangeBandwidth(instanceId string, bandwidth int64) error {
+	return nil
+}
+
+func (self *SMockCloudProviderFactory) ValidateCreateCloudaccountData(ctx context.Context, userCred mcclient.TokenCredential, input cloudprovider.SCloudaccountCredential) (cloudprovider.SCloudaccount, error) {
+	output := cloudprovider.SCloudaccount{}
+	if len(input.AccessKeyId) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "access_key_id")
+	}
+	// endpoint is the optional path of the fixture seeding the state store
+	output.AccessUrl = input.Endpoint
+	output.Account = input.AccessKeyId
+	output.Secret = input.AccessKeySecret
+	return output, nil
+}
+
+func (self *SMockCloudProviderFactory) ValidateUpdateCloudaccountCredential(ctx context.Context, userCred mcclient.TokenCredential, input cloudprovider.SCloudaccountCredential, cloudaccount string) (cloudprovider.SCloudaccount, error) {
+	output := cloudprovider.SCloudaccount{}
+	if len(input.AccessKeyId) == 0 {
+		return output, errors.Wrap(httperrors.ErrMissingParameter, "access_key_id")
+	}
+	output = cloudprovider.SCloudaccount{
+		Account: input.AccessKeyId,
+		Secret:  input.AccessKeySecret,
+	}
+	return output, nil
+}
+
+func (self *SMockCloudProviderFactory) GetProvider(cfg cloudprovider.ProviderConfig) (cloudprovider.ICloudProvider, error) {
+	client, err := mockcloud.NewMockCloudClient(
+		mockcloud.NewMockCloudClientConfig(
+			cfg.Account, cfg.URL,
+		).CloudproviderConfig(cfg),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &SMockCloudProvider{
+		SBaseProvider: cloudprovider.NewBaseProvider(self),
+		client:        client,
+	}, nil
+}
+
+func (self *SMockCloudProviderFactory) GetClientRC(info cloudprovider.SProviderInfo) (map[string]string, error) {
+	return map[string]string{
+		"MOCKCLOUD_ACCOUNT": info.Account,
+		"MOCKCLOUD_FIXTURE": info.Url,
+	}, nil
+}
+
+func init() {
+	factory := SMockCloudProviderFactory{}
+	cloudprovider.RegisterFactory(&factory)
+}
+
+type SMockCloudProvider struct {
+	cloudprovider.SBaseProvider
+	client *mockcloud.SMockCloudClient
+}
+
+func (self *SMockCloudProvider) GetSysInfo() (jsonutils.JSONObject, error) {
+	return jsonutils.NewDict(), nil
+}
+
+func (self *SMockCloudProvider) GetVersion() string {
+	return mockcloud.MOCKCLOUD_API_VERSION
+}
+
+func (self *SMockCloudProvider) GetSubAccounts() ([]cloudprovider.SSubAccount, error) {
+	return self.client.GetSubAccounts()
+}
+
+func (self *SMockCloudProvider) GetAccountId() string {
+	return self.client.GetAccountId()
+}
+
+func (self *SMockCloudProvider) GetIRegions() []cloudprovider.ICloudRegion {
+	return self.client.GetIRegions()
+}
+
+func (self *SMockCloudProvider) GetIRegionById(id string) (cloudprovider.ICloudRegion, error) {
+	return self.client.GetIRegionById(id)
+}
+
+func (self *SMockCloudProvider) GetBalance() (float64, string, error) {
+	return 0.0, api.CLOUD_PROVIDER_HEALTH_NORMAL, cloudprovider.ErrNotSupported
+}
+
+func (self *SMockCloudProvider) GetIProjects() ([]cloudprovider.ICloudProject, error) {
+	return []cloudprovider.ICloudProject{}, nil
+}
+
+func (self *SMockCloudProvider) GetStorageClasses(regionId string) []string {
+	return nil
+}
+
+func (self *SMockCloudProvider) GetBucketCannedAcls(regionId string) []string {
+	return nil
+}
+
+func (self *SMockCloudProvider) GetObjectCannedAcls(regionId string) []string {
+	return nil
+}
+
+func (self *SMockCloudProvider) GetCapabilities() []string {
+	return self.client.GetCapabilities()
+}
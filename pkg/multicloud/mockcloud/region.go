@@ -0,0 +1,162 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"fmt"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type SRegion struct {
+	multicloud.SRegion
+	multicloud.SRegionEipBase
+	multicloud.SRegionLbBase
+	multicloud.SRegionOssBase
+	multicloud.SRegionSecurityGroupBase
+	multicloud.SRegionVpcBase
+	multicloud.SRegionZoneBase
+
+	client *SMockCloudClient
+
+	RegionId   string
+	RegionName string
+	Latitude   float32
+	Longitude  float32
+}
+
+func (self *SRegion) GetId() string {
+	return self.RegionId
+}
+
+func (self *SRegion) GetName() string {
+	return self.RegionName
+}
+
+func (self *SRegion) GetGlobalId() string {
+	return fmt.Sprintf("%s/%s", CLOUD_PROVIDER_MOCKCLOUD, self.RegionId)
+}
+
+func (self *SRegion) GetClient() *SMockCloudClient {
+	return self.client
+}
+
+func (self *SRegion) GetCapabilities() []string {
+	return self.client.GetCapabilities()
+}
+
+func (self *SRegion) GetI18n() cloudprovider.SModelI18nTable {
+	return cloudprovider.SModelI18nTable{}
+}
+
+func (self *SRegion) GetProvider() string {
+	return api.CLOUD_PROVIDER_MOCKCLOUD
+}
+
+func (self *SRegion) GetStatus() string {
+	return api.CLOUD_REGION_STATUS_INSERVER
+}
+
+func (self *SRegion) GetCloudEnv() string {
+	return ""
+}
+
+func (self *SRegion) GetGeographicInfo() cloudprovider.SGeographicInfo {
+	return cloudprovider.SGeographicInfo{
+		Latitude:  self.Latitude,
+		Longitude: self.Longitude,
+	}
+}
+
+func (self *SRegion) GetIHosts() ([]cloudprovider.ICloudHost, error) {
+	zones, err := self.GetZones()
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudHost{}
+	for i := range zones {
+		ret = append(ret, zones[i].getHost())
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIHostById(id string) (cloudprovider.ICloudHost, error) {
+	hosts, err := self.GetIHosts()
+	if err != nil {
+		return nil, err
+	}
+	for i := range hosts {
+		if hosts[i].GetGlobalId() == id {
+			return hosts[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) GetIStorages() ([]cloudprovider.ICloudStorage, error) {
+	storages, err := self.GetStorages("")
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudStorage{}
+	for i := range storages {
+		ret = append(ret, &storages[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIStorageById(id string) (cloudprovider.ICloudStorage, error) {
+	storage, err := self.GetStorage(id)
+	if err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+func (self *SRegion) getStoragecache() *SStoragecache {
+	return &SStoragecache{region: self}
+}
+
+func (self *SRegion) GetIStoragecaches() ([]cloudprovider.ICloudStoragecache, error) {
+	return []cloudprovider.ICloudStoragecache{self.getStoragecache()}, nil
+}
+
+func (self *SRegion) GetIStoragecacheById(id string) (cloudprovider.ICloudStoragecache, error) {
+	cache := self.getStoragecache()
+	if cache.GetGlobalId() == id {
+		return cache, nil
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) GetIVMById(id string) (cloudprovider.ICloudVM, error) {
+	vm, err := self.GetInstance(id)
+	if err != nil {
+		return nil, err
+	}
+	return vm, nil
+}
+
+func (self *SRegion) GetIDiskById(id string) (cloudprovider.ICloudDisk, error) {
+	disk, err := self.GetDisk(id)
+	if err != nil {
+		return nil, err
+	}
+	return disk, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"sort"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type SStorage struct {
+	multicloud.SStorageBase
+
+	zone *SZone
+
+	StorageId   string
+	StorageName string
+	ZoneId      string
+	StorageType string
+	MediumType  string
+	CapacityMB  int64
+}
+
+// GetStorages lists the storages of the region, or of the zone if zoneId is
+// not empty
+func (self *SRegion) GetStorages(zoneId string) ([]SStorage, error) {
+	zones, err := self.GetZones()
+	if err != nil {
+		return nil, err
+	}
+	err = self.client.invoke("DescribeStorages")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	ret := []SStorage{}
+	for i := range zones {
+		if len(zoneId) > 0 && zones[i].ZoneId != zoneId {
+			continue
+		}
+		part := []SStorage{}
+		for _, storage := range self.client.storages {
+			if storage.ZoneId == zones[i].ZoneId {
+				part = append(part, *storage)
+			}
+		}
+		sort.Slice(part, func(i, j int) bool { return part[i].StorageId < part[j].StorageId })
+		for j := range part {
+			part[j].zone = &zones[i]
+		}
+		ret = append(ret, part...)
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetStorage(id string) (*SStorage, error) {
+	storages, err := self.GetStorages("")
+	if err != nil {
+		return nil, err
+	}
+	for i := range storages {
+		if storages[i].StorageId == id {
+			return &storages[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SStorage) GetId() string {
+	return self.StorageId
+}
+
+func (self *SStorage) GetName() string {
+	return self.StorageName
+}
+
+func (self *SStorage) GetGlobalId() string {
+	return self.StorageId
+}
+
+func (self *SStorage) GetStatus() string {
+	return api.STORAGE_ONLINE
+}
+
+func (self *SStorage) Refresh() error {
+	storage, err := self.zone.region.GetStorage(self.StorageId)
+	if err != nil {
+		return err
+	}
+	*self = *storage
+	return nil
+}
+
+func (self *SStorage) GetIStoragecache() cloudprovider.ICloudStoragecache {
+	return self.zone.region.getStoragecache()
+}
+
+func (self *SStorage) GetIZone() cloudprovider.ICloudZone {
+	return self.zone
+}
+
+func (self *SStorage) GetIDisks() ([]cloudprovider.ICloudDisk, error) {
+	disks, err := self.zone.region.GetDisks(self.StorageId, "")
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudDisk{}
+	for i := range disks {
+		disks[i].storage = self
+		ret = append(ret, &disks[i])
+	}
+	return ret, nil
+}
+
+func (self *SStorage) GetIDiskById(id string) (cloudprovider.ICloudDisk, error) {
+	disk, err := self.zone.region.GetDisk(id)
+	if err != nil {
+		return nil, err
+	}
+	if disk.StorageId != self.StorageId {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+	}
+	disk.storage = self
+	return disk, nil
+}
+
+func (self *SStorage) CreateIDisk(conf *cloudprovider.DiskCreateConfig) (cloudprovider.ICloudDisk, error) {
+	disk, err := self.zone.region.CreateDisk(self.StorageId, conf.Name, conf.SizeGb*1024, "")
+	if err != nil {
+		return nil, err
+	}
+	disk.storage = self
+	return disk, nil
+}
+
+func (self *SStorage) GetStorageType() string {
+	return self.StorageType
+}
+
+func (self *SStorage) GetMediumType() string {
+	if len(self.MediumType) > 0 {
+		return self.MediumType
+	}
+	return api.DISK_TYPE_SSD
+}
+
+func (self *SStorage) GetCapacityMB() int64 {
+	return self.CapacityMB
+}
+
+func (self *SStorage) GetCapacityUsedMB() int64 {
+	return 0
+}
+
+func (self *SStorage) GetStorageConf() jsonutils.JSONObject {
+	return jsonutils.NewDict()
+}
+
+func (self *SStorage) GetEnabled() bool {
+	return true
+}
+
+func (self *SStorage) GetMountPoint() string {
+	return ""
+}
+
+func (self *SStorage) IsSysDiskStore() bool {
+	return true
+}
@@ -0,0 +1,225 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"sort"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/netutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type SVpc struct {
+	multicloud.SVpc
+	multicloud.STagBase
+
+	region *SRegion
+
+	VpcId     string
+	VpcName   string
+	RegionId  string
+	CidrBlock string
+	IsDefault bool
+	Status    string
+}
+
+func (self *SRegion) GetVpcs() ([]SVpc, error) {
+	err := self.client.invoke("DescribeVpcs")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	ret := []SVpc{}
+	for _, vpc := range self.client.vpcs {
+		if vpc.RegionId == self.RegionId {
+			ret = append(ret, *vpc)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].VpcId < ret[j].VpcId })
+	for i := range ret {
+		ret[i].region = self
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetVpc(id string) (*SVpc, error) {
+	vpcs, err := self.GetVpcs()
+	if err != nil {
+		return nil, err
+	}
+	for i := range vpcs {
+		if vpcs[i].VpcId == id {
+			return &vpcs[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) GetIVpcs() ([]cloudprovider.ICloudVpc, error) {
+	vpcs, err := self.GetVpcs()
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudVpc{}
+	for i := range vpcs {
+		ret = append(ret, &vpcs[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIVpcById(id string) (cloudprovider.ICloudVpc, error) {
+	vpc, err := self.GetVpc(id)
+	if err != nil {
+		return nil, err
+	}
+	return vpc, nil
+}
+
+func (self *SRegion) CreateIVpc(opts *cloudprovider.VpcCreateOptions) (cloudprovider.ICloudVpc, error) {
+	_, err := netutils.NewIPV4Prefix(opts.CIDR)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid cidr %s", opts.CIDR)
+	}
+	err = self.client.invoke("CreateVpc")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	vpc := &SVpc{
+		VpcId:     newId("vpc"),
+		VpcName:   opts.NAME,
+		RegionId:  self.RegionId,
+		CidrBlock: opts.CIDR,
+		Status:    api.VPC_STATUS_PENDING,
+	}
+	self.client.vpcs[vpc.VpcId] = vpc
+	self.client.transit(func() {
+		vpc.Status = api.VPC_STATUS_AVAILABLE
+	})
+	self.client.lock.Unlock()
+	return self.GetVpc(vpc.VpcId)
+}
+
+func (self *SRegion) DeleteVpc(id string) error {
+	err := self.client.invoke("DeleteVpc")
+	if err != nil {
+		return err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	vpc, ok := self.client.vpcs[id]
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotFound, id)
+	}
+	for _, network := range self.client.networks {
+		if network.VpcId == id {
+			return errors.Wrapf(errors.ErrInvalidStatus, "vpc %s has network %s", id, network.NetworkId)
+		}
+	}
+	vpc.Status = api.VPC_STATUS_DELETING
+	self.client.transit(func() {
+		delete(self.client.vpcs, id)
+	})
+	return nil
+}
+
+func (self *SVpc) GetId() string {
+	return self.VpcId
+}
+
+func (self *SVpc) GetName() string {
+	return self.VpcName
+}
+
+func (self *SVpc) GetGlobalId() string {
+	return self.VpcId
+}
+
+func (self *SVpc) GetStatus() string {
+	return self.Status
+}
+
+func (self *SVpc) Refresh() error {
+	vpc, err := self.region.GetVpc(self.VpcId)
+	if err != nil {
+		return err
+	}
+	*self = *vpc
+	return nil
+}
+
+func (self *SVpc) GetRegion() cloudprovider.ICloudRegion {
+	return self.region
+}
+
+func (self *SVpc) GetIsDefault() bool {
+	return self.IsDefault
+}
+
+func (self *SVpc) GetCidrBlock() string {
+	return self.CidrBlock
+}
+
+func (self *SVpc) Delete() error {
+	return self.region.DeleteVpc(self.VpcId)
+}
+
+func (self *SVpc) GetISecurityGroups() ([]cloudprovider.ICloudSecurityGroup, error) {
+	return []cloudprovider.ICloudSecurityGroup{}, nil
+}
+
+func (self *SVpc) GetIRouteTables() ([]cloudprovider.ICloudRouteTable, error) {
+	return []cloudprovider.ICloudRouteTable{}, nil
+}
+
+func (self *SVpc) GetIRouteTableById(id string) (cloudprovider.ICloudRouteTable, error) {
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SVpc) getWire(zone *SZone) *SWire {
+	return &SWire{vpc: self, zone: zone}
+}
+
+func (self *SVpc) GetIWires() ([]cloudprovider.ICloudWire, error) {
+	zones, err := self.region.GetZones()
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudWire{}
+	for i := range zones {
+		ret = append(ret, self.getWire(&zones[i]))
+	}
+	return ret, nil
+}
+
+func (self *SVpc) GetIWireById(id string) (cloudprovider.ICloudWire, error) {
+	wires, err := self.GetIWires()
+	if err != nil {
+		return nil, err
+	}
+	for i := range wires {
+		if wires[i].GetGlobalId() == id {
+			return wires[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"fmt"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+// SWire is the emulated wire of a vpc in a zone
+type SWire struct {
+	multicloud.SResourceBase
+	multicloud.STagBase
+
+	vpc  *SVpc
+	zone *SZone
+}
+
+func (self *SWire) GetId() string {
+	return fmt.Sprintf("%s/%s", self.vpc.GetGlobalId(), self.zone.ZoneId)
+}
+
+func (self *SWire) GetGlobalId() string {
+	return self.GetId()
+}
+
+func (self *SWire) GetName() string {
+	return fmt.Sprintf("%s-%s", self.vpc.GetName(), self.zone.GetName())
+}
+
+func (self *SWire) IsEmulated() bool {
+	return true
+}
+
+func (self *SWire) GetStatus() string {
+	return api.WIRE_STATUS_AVAILABLE
+}
+
+func (self *SWire) GetBandwidth() int {
+	return 10000
+}
+
+func (self *SWire) GetIVpc() cloudprovider.ICloudVpc {
+	return self.vpc
+}
+
+func (self *SWire) GetIZone() cloudprovider.ICloudZone {
+	return self.zone
+}
+
+func (self *SWire) GetINetworks() ([]cloudprovider.ICloudNetwork, error) {
+	networks, err := self.vpc.region.GetNetworks(self.vpc.VpcId, self.zone.ZoneId)
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudNetwork{}
+	for i := range networks {
+		networks[i].wire = self
+		ret = append(ret, &networks[i])
+	}
+	return ret, nil
+}
+
+func (self *SWire) GetINetworkById(id string) (cloudprovider.ICloudNetwork, error) {
+	networks, err := self.GetINetworks()
+	if err != nil {
+		return nil, err
+	}
+	for i := range networks {
+		if networks[i].GetGlobalId() == id {
+			return networks[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SWire) CreateINetwork(opts *cloudprovider.SNetworkCreateOptions) (cloudprovider.ICloudNetwork, error) {
+	network, err := self.vpc.region.CreateNetwork(self.vpc.VpcId, self.zone.ZoneId, opts.Name, opts.Cidr)
+	if err != nil {
+		return nil, err
+	}
+	network.wire = self
+	return network, nil
+}
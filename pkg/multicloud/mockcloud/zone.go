@@ -0,0 +1,161 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"fmt"
+	"sort"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/multicloud"
+)
+
+type SZone struct {
+	multicloud.SResourceBase
+	multicloud.STagBase
+
+	region *SRegion
+
+	ZoneId   string
+	ZoneName string
+	RegionId string
+}
+
+func (self *SRegion) GetZones() ([]SZone, error) {
+	err := self.client.invoke("DescribeZones")
+	if err != nil {
+		return nil, err
+	}
+	self.client.lock.Lock()
+	defer self.client.lock.Unlock()
+
+	ret := []SZone{}
+	for _, zone := range self.client.zones {
+		if zone.RegionId == self.RegionId {
+			ret = append(ret, *zone)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ZoneId < ret[j].ZoneId })
+	for i := range ret {
+		ret[i].region = self
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetZone(id string) (*SZone, error) {
+	zones, err := self.GetZones()
+	if err != nil {
+		return nil, err
+	}
+	for i := range zones {
+		if zones[i].ZoneId == id {
+			return &zones[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SRegion) GetIZones() ([]cloudprovider.ICloudZone, error) {
+	zones, err := self.GetZones()
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudZone{}
+	for i := range zones {
+		ret = append(ret, &zones[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIZoneById(id string) (cloudprovider.ICloudZone, error) {
+	zones, err := self.GetZones()
+	if err != nil {
+		return nil, err
+	}
+	for i := range zones {
+		if zones[i].GetGlobalId() == id {
+			return &zones[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SZone) GetId() string {
+	return self.ZoneId
+}
+
+func (self *SZone) GetName() string {
+	return self.ZoneName
+}
+
+func (self *SZone) GetGlobalId() string {
+	return fmt.Sprintf("%s/%s", self.region.GetGlobalId(), self.ZoneId)
+}
+
+func (self *SZone) GetStatus() string {
+	return api.ZONE_ENABLE
+}
+
+func (self *SZone) GetI18n() cloudprovider.SModelI18nTable {
+	return cloudprovider.SModelI18nTable{}
+}
+
+func (self *SZone) GetIRegion() cloudprovider.ICloudRegion {
+	return self.region
+}
+
+func (self *SZone) getHost() *SHost {
+	return &SHost{zone: self}
+}
+
+func (self *SZone) GetIHosts() ([]cloudprovider.ICloudHost, error) {
+	return []cloudprovider.ICloudHost{self.getHost()}, nil
+}
+
+func (self *SZone) GetIHostById(id string) (cloudprovider.ICloudHost, error) {
+	host := self.getHost()
+	if host.GetGlobalId() == id {
+		return host, nil
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
+
+func (self *SZone) GetIStorages() ([]cloudprovider.ICloudStorage, error) {
+	storages, err := self.region.GetStorages(self.ZoneId)
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.ICloudStorage{}
+	for i := range storages {
+		ret = append(ret, &storages[i])
+	}
+	return ret, nil
+}
+
+func (self *SZone) GetIStorageById(id string) (cloudprovider.ICloudStorage, error) {
+	storages, err := self.region.GetStorages(self.ZoneId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range storages {
+		if storages[i].GetGlobalId() == id {
+			return &storages[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, id)
+}
@@ -517,6 +517,16 @@ func init() {
 			"cloudpods component",
 			"cloudpods服务组件",
 		},
+		sI18nElme{
+			api.TOPIC_RESOURCE_QUOTA,
+			"quota",
+			"配额",
+		},
+		sI18nElme{
+			api.TOPIC_RESOURCE_QUOTA_CHANGE_REQUEST,
+			"quota change request",
+			"配额变更申请",
+		},
 		sI18nElme{
 			string(api.ActionCreate),
 			"created",
@@ -562,6 +572,21 @@ func init() {
 			"added to the recycle bin",
 			"加入回收站",
 		},
+		sI18nElme{
+			string(api.ActionExceedThreshold),
+			"exceeded the warning threshold",
+			"超过告警阈值",
+		},
+		sI18nElme{
+			string(api.ActionApprove),
+			"approved",
+			"审批通过",
+		},
+		sI18nElme{
+			string(api.ActionReject),
+			"rejected",
+			"审批拒绝",
+		},
 		sI18nElme{
 			string(api.ResultFailed),
 			"failed",
@@ -34,6 +34,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/notify/robot"
 	rpcapi "yunion.io/x/onecloud/pkg/notify/rpc/apis"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
@@ -205,9 +206,13 @@ func (rm *SRobotManager) ValidateCreateData(ctx context.Context, userCred mcclie
 		return input, errors.Wrap(err, "SSharableVirtualResourceBaseManager.ValidateCreateData")
 	}
 	// check type
-	if !utils.IsInStringArray(input.Type, []string{api.ROBOT_TYPE_FEISHU, api.ROBOT_TYPE_WORKWX, api.ROBOT_TYPE_DINGTALK, api.ROBOT_TYPE_WEBHOOK}) {
+	if !utils.IsInStringArray(input.Type, []string{api.ROBOT_TYPE_FEISHU, api.ROBOT_TYPE_WORKWX, api.ROBOT_TYPE_DINGTALK, api.ROBOT_TYPE_WEBHOOK, api.ROBOT_TYPE_SLACK, api.ROBOT_TYPE_TEAMS, api.ROBOT_TYPE_TELEGRAM}) {
 		return input, httperrors.NewInputParameterError("unkown type %q", input.Type)
 	}
+	err = validateRobotAddress(input.Type, input.Address)
+	if err != nil {
+		return input, err
+	}
 	// check lang
 	if input.Lang == "" {
 		input.Lang = "zh_CN"
@@ -233,6 +238,20 @@ func (rm *SRobotManager) ValidateCreateData(ctx context.Context, userCred mcclie
 	return input, nil
 }
 
+// validateRobotAddress checks the address format of the robots sent by notify
+// service itself before sending the verification message
+func validateRobotAddress(rType, address string) error {
+	sender, ok := robot.GetSender(rType)
+	if !ok {
+		return nil
+	}
+	err := sender.ValidateAddress(address)
+	if err != nil {
+		return httperrors.NewInputParameterError("invalid address: %v", err)
+	}
+	return nil
+}
+
 func (r *SRobot) Receiver() *rpcapi.SReceiver {
 	return &rpcapi.SReceiver{
 		Contact:  r.Address,
@@ -281,6 +300,10 @@ func (r *SRobot) ValidateUpdateData(ctx context.Context, userCred mcclient.Token
 		}
 	}
 	if len(input.Address) > 0 {
+		err = validateRobotAddress(r.Type, input.Address)
+		if err != nil {
+			return input, err
+		}
 		// check Address
 		records, err := NotifyService.SendRobotMessage(ctx, r.Type, []*rpcapi.SReceiver{
 			{
@@ -95,6 +95,8 @@ const (
 	DefaultChecksumTestFailed      = "checksum test failed"
 	DefaultUserLock                = "user lock"
 	DefaultActionLogExceedCount    = "action log exceed count"
+	DefaultQuotaExceedThreshold    = "quota exceed threshold"
+	DefaultQuotaChangeRequest      = "quota change request"
 )
 
 func (sm *STopicManager) InitializeData() error {
@@ -114,6 +116,8 @@ func (sm *STopicManager) InitializeData() error {
 		DefaultChecksumTestFailed,
 		DefaultUserLock,
 		DefaultActionLogExceedCount,
+		DefaultQuotaExceedThreshold,
+		DefaultQuotaChangeRequest,
 	)
 	q := sm.Query()
 	topics := make([]STopic, 0, initSNames.Len())
@@ -327,6 +331,24 @@ func (sm *STopicManager) InitializeData() error {
 				notify.ActionExceedCount,
 			)
 			t.Type = notify.TOPIC_TYPE_RESOURCE
+		case DefaultQuotaExceedThreshold:
+			t.addResources(
+				notify.TOPIC_RESOURCE_QUOTA,
+			)
+			t.addAction(
+				notify.ActionExceedThreshold,
+			)
+			t.Type = notify.TOPIC_TYPE_RESOURCE
+		case DefaultQuotaChangeRequest:
+			t.addResources(
+				notify.TOPIC_RESOURCE_QUOTA_CHANGE_REQUEST,
+			)
+			t.addAction(
+				notify.ActionCreate,
+				notify.ActionApprove,
+				notify.ActionReject,
+			)
+			t.Type = notify.TOPIC_TYPE_AUTOMATED_PROCESS
 		}
 		if topic == nil {
 			err := sm.TableSpec().Insert(ctx, t)
@@ -556,6 +578,8 @@ func init() {
 			notify.TOPIC_RESOURCE_DB_TABLE_RECORD:          35,
 			notify.TOPIC_RESOURCE_USER:                     36,
 			notify.TOPIC_RESOURCE_ACTION_LOG:               37,
+			notify.TOPIC_RESOURCE_QUOTA:                    38,
+			notify.TOPIC_RESOURCE_QUOTA_CHANGE_REQUEST:     39,
 		},
 	)
 	converter.registerAction(
@@ -584,6 +608,9 @@ func init() {
 			notify.ActionChecksumTest:       21,
 			notify.ActionLock:               22,
 			notify.ActionExceedCount:        23,
+			notify.ActionExceedThreshold:    24,
+			notify.ActionApprove:            25,
+			notify.ActionReject:             26,
 		},
 	)
 }
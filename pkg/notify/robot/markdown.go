@@ -0,0 +1,177 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package robot
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The notification templates are written in markdown, iMarkdownFormatter
+// converts the markdown elements to the rich format of each platform.
+type iMarkdownFormatter interface {
+	escape(text string) string
+	bold(text string) string
+	italic(text string) string
+	code(text string) string
+	link(text, url string) string
+	heading(text string) string
+	listItem(text string) string
+	codeBlock(lines []string) string
+	lineSep() string
+}
+
+var (
+	headingReg  = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+	listItemReg = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	inlineReg   = regexp.MustCompile("`([^`]+)`" + `|\[([^\]]+)\]\(([^)\s]+)\)|\*\*(.+?)\*\*|__(.+?)__|\*([^*\s][^*]*?)\*`)
+)
+
+func convertMarkdown(md string, f iMarkdownFormatter) string {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+	var (
+		inCodeBlock bool
+		codeLines   []string
+	)
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inCodeBlock {
+				out = append(out, f.codeBlock(codeLines))
+				codeLines = nil
+			}
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock {
+			codeLines = append(codeLines, line)
+			continue
+		}
+		if m := headingReg.FindStringSubmatch(line); m != nil {
+			out = append(out, f.heading(convertInline(m[1], f)))
+			continue
+		}
+		if m := listItemReg.FindStringSubmatch(line); m != nil {
+			out = append(out, f.listItem(convertInline(m[1], f)))
+			continue
+		}
+		out = append(out, convertInline(line, f))
+	}
+	if inCodeBlock {
+		out = append(out, f.codeBlock(codeLines))
+	}
+	return strings.Join(out, f.lineSep())
+}
+
+func convertInline(text string, f iMarkdownFormatter) string {
+	var (
+		buf  strings.Builder
+		last int
+	)
+	for _, m := range inlineReg.FindAllStringSubmatchIndex(text, -1) {
+		buf.WriteString(f.escape(text[last:m[0]]))
+		sub := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return text[m[2*i]:m[2*i+1]]
+		}
+		switch {
+		case m[2] >= 0:
+			buf.WriteString(f.code(sub(1)))
+		case m[4] >= 0:
+			buf.WriteString(f.link(convertInline(sub(2), f), sub(3)))
+		case m[8] >= 0:
+			buf.WriteString(f.bold(convertInline(sub(4), f)))
+		case m[10] >= 0:
+			buf.WriteString(f.bold(convertInline(sub(5), f)))
+		case m[12] >= 0:
+			buf.WriteString(f.italic(convertInline(sub(6), f)))
+		}
+		last = m[1]
+	}
+	buf.WriteString(f.escape(text[last:]))
+	return buf.String()
+}
+
+// sSlackFormatter converts markdown to slack mrkdwn
+type sSlackFormatter struct{}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (sSlackFormatter) escape(text string) string    { return slackEscaper.Replace(text) }
+func (sSlackFormatter) bold(text string) string      { return "*" + text + "*" }
+func (sSlackFormatter) italic(text string) string    { return "_" + text + "_" }
+func (sSlackFormatter) code(text string) string      { return "`" + text + "`" }
+func (sSlackFormatter) heading(text string) string   { return "*" + text + "*" }
+func (sSlackFormatter) listItem(text string) string  { return "• " + text }
+func (sSlackFormatter) lineSep() string              { return "\n" }
+func (sSlackFormatter) link(text, url string) string { return "<" + url + "|" + text + ">" }
+func (f sSlackFormatter) codeBlock(lines []string) string {
+	return "```\n" + f.escape(strings.Join(lines, "\n")) + "\n```"
+}
+
+// MarkdownToSlack converts markdown to slack mrkdwn
+func MarkdownToSlack(md string) string {
+	return convertMarkdown(md, sSlackFormatter{})
+}
+
+// sTelegramFormatter converts markdown to the html subset supported by telegram
+type sTelegramFormatter struct{}
+
+var (
+	telegramEscaper     = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	telegramAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+func (sTelegramFormatter) escape(text string) string   { return telegramEscaper.Replace(text) }
+func (sTelegramFormatter) bold(text string) string     { return "<b>" + text + "</b>" }
+func (sTelegramFormatter) italic(text string) string   { return "<i>" + text + "</i>" }
+func (f sTelegramFormatter) code(text string) string   { return "<code>" + f.escape(text) + "</code>" }
+func (sTelegramFormatter) heading(text string) string  { return "<b>" + text + "</b>" }
+func (sTelegramFormatter) listItem(text string) string { return "• " + text }
+func (sTelegramFormatter) lineSep() string             { return "\n" }
+func (sTelegramFormatter) link(text, url string) string {
+	return `<a href="` + telegramAttrEscaper.Replace(url) + `">` + text + "</a>"
+}
+func (f sTelegramFormatter) codeBlock(lines []string) string {
+	return "<pre>" + f.escape(strings.Join(lines, "\n")) + "</pre>"
+}
+
+// MarkdownToTelegramHTML converts markdown to the html subset supported by telegram
+func MarkdownToTelegramHTML(md string) string {
+	return convertMarkdown(md, sTelegramFormatter{})
+}
+
+// sTeamsFormatter normalizes markdown for the connector card of teams, which
+// needs blank lines to separate lines and doesn't support headings
+type sTeamsFormatter struct{}
+
+func (sTeamsFormatter) escape(text string) string    { return text }
+func (sTeamsFormatter) bold(text string) string      { return "**" + text + "**" }
+func (sTeamsFormatter) italic(text string) string    { return "_" + text + "_" }
+func (sTeamsFormatter) code(text string) string      { return "`" + text + "`" }
+func (sTeamsFormatter) heading(text string) string   { return "**" + text + "**" }
+func (sTeamsFormatter) listItem(text string) string  { return "- " + text }
+func (sTeamsFormatter) lineSep() string              { return "\n\n" }
+func (sTeamsFormatter) link(text, url string) string { return "[" + text + "](" + url + ")" }
+func (sTeamsFormatter) codeBlock(lines []string) string {
+	return "<pre>" + telegramEscaper.Replace(strings.Join(lines, "\n")) + "</pre>"
+}
+
+// MarkdownToTeams converts markdown to the format of teams connector card
+func MarkdownToTeams(md string) string {
+	return convertMarkdown(md, sTeamsFormatter{})
+}
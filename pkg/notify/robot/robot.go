@@ -0,0 +1,190 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package robot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	ErrInvalidAddress = errors.Error("invalid robot address")
+	ErrRateLimited    = errors.Error("rate limited")
+)
+
+// IRobotSender sends message to the chat platform directly instead of
+// through the notify plugins
+type IRobotSender interface {
+	// ValidateAddress checks the format of the robot address
+	ValidateAddress(address string) error
+	// Send renders the markdown message to the rich format of the platform
+	// and sends it to address
+	Send(ctx context.Context, address, title, message string) error
+}
+
+var senders = map[string]IRobotSender{}
+
+func Register(robotType string, sender IRobotSender) {
+	senders[robotType] = sender
+}
+
+// GetSender returns the sender of robotType, false if the robot type is
+// served by the notify plugins
+func GetSender(robotType string) (IRobotSender, bool) {
+	sender, ok := senders[robotType]
+	return sender, ok
+}
+
+var (
+	cli = &http.Client{
+		Transport: httputils.GetTransport(false),
+		Timeout:   30 * time.Second,
+	}
+
+	// MaxRetries is the max retry times when the platform is rate limited or unavailable
+	MaxRetries = 3
+	// MaxRetryAfter limits the waiting time requested by the platform
+	MaxRetryAfter = time.Minute
+
+	retryBaseDelay = time.Second
+)
+
+type sResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (r *sResponse) json() (jsonutils.JSONObject, error) {
+	if len(r.Body) == 0 {
+		return jsonutils.NewDict(), nil
+	}
+	return jsonutils.Parse(r.Body)
+}
+
+// sRetryError means the request could be retried after Delay
+type sRetryError struct {
+	err   error
+	Delay time.Duration
+	// Body is the response body if any
+	Body []byte
+}
+
+func (e *sRetryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sRetryError) Cause() error {
+	return errors.Cause(e.err)
+}
+
+func retryAfter(header http.Header) time.Duration {
+	ra := header.Get("Retry-After")
+	if len(ra) == 0 {
+		return 0
+	}
+	if sec, err := strconv.Atoi(ra); err == nil {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(ra); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func postJSON(ctx context.Context, url string, header http.Header, body jsonutils.JSONObject) (*sResponse, error) {
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := httputils.Request(cli, ctx, httputils.POST, url, header, bytes.NewReader([]byte(body.String())), false)
+	if err != nil {
+		return nil, &sRetryError{err: err}
+	}
+	defer httputils.CloseResponse(resp)
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &sRetryError{err: errors.Wrap(err, "read response")}
+	}
+	ret := &sResponse{StatusCode: resp.StatusCode, Header: resp.Header, Body: data}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, &sRetryError{
+			err:   errors.Wrapf(ErrRateLimited, "%s", data),
+			Delay: retryAfter(resp.Header),
+			Body:  data,
+		}
+	case resp.StatusCode >= 500:
+		return nil, &sRetryError{
+			err:   errors.Errorf("status code %d: %s", resp.StatusCode, data),
+			Delay: retryAfter(resp.Header),
+			Body:  data,
+		}
+	}
+	return ret, nil
+}
+
+// withRetry calls send until it succeeds, returns an error which can't be
+// retried or the retry times run out. The delay requested by the platform is
+// preferred and exponential backoff is used otherwise.
+func withRetry(ctx context.Context, send func() error) error {
+	var err error
+	for i := 0; i <= MaxRetries; i++ {
+		err = send()
+		if err == nil {
+			return nil
+		}
+		re, ok := err.(*sRetryError)
+		if !ok || i == MaxRetries {
+			break
+		}
+		delay := re.Delay
+		if delay <= 0 {
+			delay = retryBaseDelay << uint(i)
+		}
+		if delay > MaxRetryAfter {
+			delay = MaxRetryAfter
+		}
+		log.Warningf("robot message sending failed: %v, retry after %s", re, delay)
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), err.Error())
+		case <-time.After(delay):
+		}
+	}
+	if re, ok := err.(*sRetryError); ok {
+		return re.err
+	}
+	return err
+}
+
+func truncate(s string, limit int) string {
+	r := []rune(s)
+	if len(r) <= limit {
+		return s
+	}
+	return fmt.Sprintf("%s...", string(r[:limit-3]))
+}
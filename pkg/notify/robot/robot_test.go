@@ -0,0 +1,193 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package robot
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+)
+
+func TestMarkdownConvert(t *testing.T) {
+	md := strings.Join([]string{
+		"# Server created",
+		"**name**: vm-1 & *test*",
+		"- see [details](https://example.com/a?b=1&c=\"2\")",
+		"`a<b>`",
+		"```",
+		"x < y",
+		"```",
+	}, "\n")
+	cases := []struct {
+		name    string
+		convert func(string) string
+		want    string
+	}{
+		{
+			name:    "slack",
+			convert: MarkdownToSlack,
+			want: strings.Join([]string{
+				"*Server created*",
+				"*name*: vm-1 &amp; _test_",
+				"• see <https://example.com/a?b=1&c=\"2\"|details>",
+				"`a<b>`",
+				"```\nx &lt; y\n```",
+			}, "\n"),
+		},
+		{
+			name:    "telegram",
+			convert: MarkdownToTelegramHTML,
+			want: strings.Join([]string{
+				"<b>Server created</b>",
+				"<b>name</b>: vm-1 &amp; <i>test</i>",
+				"• see <a href=\"https://example.com/a?b=1&amp;c=&quot;2&quot;\">details</a>",
+				"<code>a&lt;b&gt;</code>",
+				"<pre>x &lt; y</pre>",
+			}, "\n"),
+		},
+		{
+			name:    "teams",
+			convert: MarkdownToTeams,
+			want: strings.Join([]string{
+				"**Server created**",
+				"**name**: vm-1 & _test_",
+				"- see [details](https://example.com/a?b=1&c=\"2\")",
+				"`a<b>`",
+				"<pre>x &lt; y</pre>",
+			}, "\n\n"),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.convert(md)
+			if got != c.want {
+				t.Errorf("want:\n%s\ngot:\n%s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	oldBase, oldApi := retryBaseDelay, telegramApiBase
+	defer func() {
+		retryBaseDelay, telegramApiBase = oldBase, oldApi
+	}()
+	retryBaseDelay = time.Millisecond
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests","parameters":{"retry_after":0}}`))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		obj, _ := jsonutils.Parse(body)
+		if mode, _ := obj.GetString("parse_mode"); mode != "HTML" {
+			t.Errorf("parse_mode should be HTML, got %q", mode)
+		}
+		if r.URL.Path != "/bot123:abc/sendMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+	telegramApiBase = srv.URL
+
+	sender := &STelegramSender{}
+	err := sender.Send(context.Background(), "123:abc/-1001", "title", "**content**")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expect 2 calls, got %d", calls)
+	}
+}
+
+func TestTelegramTokenRedacted(t *testing.T) {
+	oldBase, oldRetries, oldApi := retryBaseDelay, MaxRetries, telegramApiBase
+	defer func() {
+		retryBaseDelay, MaxRetries, telegramApiBase = oldBase, oldRetries, oldApi
+	}()
+	retryBaseDelay = time.Millisecond
+	MaxRetries = 1
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	// nothing listens on the address after close
+	srv.Close()
+	telegramApiBase = srv.URL
+
+	err := (&STelegramSender{}).Send(context.Background(), "123:secret/-1001", "title", "content")
+	if err == nil {
+		t.Fatal("expect connection error")
+	}
+	if strings.Contains(err.Error(), "123:secret") {
+		t.Errorf("bot token leaks in error: %v", err)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	oldBase, oldRetries := retryBaseDelay, MaxRetries
+	defer func() {
+		retryBaseDelay, MaxRetries = oldBase, oldRetries
+	}()
+	retryBaseDelay = time.Millisecond
+	MaxRetries = 2
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	err := (&SSlackSender{}).Send(context.Background(), srv.URL, "title", "content")
+	if errors.Cause(err) != ErrRateLimited {
+		t.Errorf("expect rate limited error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expect 3 calls, got %d", calls)
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	cases := []struct {
+		sender  IRobotSender
+		address string
+		valid   bool
+	}{
+		{&SSlackSender{}, "https://hooks.slack.com/services/T/B/X", true},
+		{&SSlackSender{}, "xoxb-123/#ops", true},
+		{&SSlackSender{}, "ops", false},
+		{&STeamsSender{}, "https://example.webhook.office.com/webhookb2/x", true},
+		{&STeamsSender{}, "http://example.com", false},
+		{&STelegramSender{}, "123:abc/@ops", true},
+		{&STelegramSender{}, "123abc", false},
+	}
+	for _, c := range cases {
+		err := c.sender.ValidateAddress(c.address)
+		if (err == nil) != c.valid {
+			t.Errorf("address %q: expect valid %v, got %v", c.address, c.valid, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package robot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/notify"
+)
+
+var slackApiBase = "https://slack.com/api"
+
+const slackTextLimit = 3000
+
+func init() {
+	Register(api.ROBOT_TYPE_SLACK, &SSlackSender{})
+}
+
+// SSlackSender sends message through slack incoming webhook or Bot API.
+// The address is the incoming webhook url, or '<bot token>/<channel>' for Bot API.
+type SSlackSender struct{}
+
+func (s *SSlackSender) parseBotAddress(address string) (token, channel string, err error) {
+	parts := strings.SplitN(address, "/", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "xox") || len(parts[1]) == 0 {
+		return "", "", errors.Wrapf(ErrInvalidAddress, "slack address should be a webhook url or '<bot token>/<channel>'")
+	}
+	return parts[0], parts[1], nil
+}
+
+func (s *SSlackSender) ValidateAddress(address string) error {
+	if isHttpUrl(address) {
+		return nil
+	}
+	_, _, err := s.parseBotAddress(address)
+	return err
+}
+
+func (s *SSlackSender) body(title, message string) *jsonutils.JSONDict {
+	text := truncate(MarkdownToSlack(message), slackTextLimit)
+	blocks := []jsonutils.JSONObject{}
+	if len(title) > 0 {
+		blocks = append(blocks, jsonutils.Marshal(map[string]interface{}{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": truncate(title, 150)},
+		}))
+	}
+	blocks = append(blocks, jsonutils.Marshal(map[string]interface{}{
+		"type": "section",
+		"text": map[string]interface{}{"type": "mrkdwn", "text": text},
+	}))
+	body := jsonutils.NewDict()
+	// text is the fallback of notifications
+	body.Set("text", jsonutils.NewString(fmt.Sprintf("%s\n%s", title, text)))
+	body.Set("blocks", jsonutils.NewArray(blocks...))
+	return body
+}
+
+func (s *SSlackSender) Send(ctx context.Context, address, title, message string) error {
+	body := s.body(title, message)
+	if isHttpUrl(address) {
+		return withRetry(ctx, func() error {
+			resp, err := postJSON(ctx, address, nil, body)
+			if err != nil {
+				return err
+			}
+			// incoming webhook responds plain text 'ok' or the error code
+			if resp.StatusCode != http.StatusOK {
+				return errors.Errorf("slack webhook status code %d: %s", resp.StatusCode, resp.Body)
+			}
+			return nil
+		})
+	}
+	token, channel, err := s.parseBotAddress(address)
+	if err != nil {
+		return err
+	}
+	body.Set("channel", jsonutils.NewString(channel))
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	return withRetry(ctx, func() error {
+		resp, err := postJSON(ctx, slackApiBase+"/chat.postMessage", header, body)
+		if err != nil {
+			return err
+		}
+		ret, err := resp.json()
+		if err != nil {
+			return errors.Wrapf(err, "parse slack response %s", resp.Body)
+		}
+		if ok, _ := ret.Bool("ok"); !ok {
+			reason, _ := ret.GetString("error")
+			if reason == "ratelimited" {
+				return &sRetryError{err: errors.Wrap(ErrRateLimited, reason), Delay: retryAfter(resp.Header)}
+			}
+			return errors.Errorf("slack chat.postMessage: %s", reason)
+		}
+		return nil
+	})
+}
+
+func isHttpUrl(address string) bool {
+	return strings.HasPrefix(address, "https://") || strings.HasPrefix(address, "http://")
+}
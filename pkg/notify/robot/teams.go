@@ -0,0 +1,77 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package robot
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/notify"
+)
+
+// teams limits the size of the card to 28KB
+const teamsTextLimit = 20000
+
+func init() {
+	Register(api.ROBOT_TYPE_TEAMS, &STeamsSender{})
+}
+
+// STeamsSender sends message card through the incoming webhook connector of
+// Microsoft Teams. The address is the webhook url.
+type STeamsSender struct{}
+
+func (s *STeamsSender) ValidateAddress(address string) error {
+	if !strings.HasPrefix(address, "https://") {
+		return errors.Wrapf(ErrInvalidAddress, "teams address should be a https webhook url")
+	}
+	return nil
+}
+
+func (s *STeamsSender) Send(ctx context.Context, address, title, message string) error {
+	err := s.ValidateAddress(address)
+	if err != nil {
+		return err
+	}
+	body := jsonutils.Marshal(map[string]interface{}{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  title,
+		"title":    title,
+		"text":     truncate(MarkdownToTeams(message), teamsTextLimit),
+	})
+	return withRetry(ctx, func() error {
+		resp, err := postJSON(ctx, address, nil, body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+			return errors.Errorf("teams webhook status code %d: %s", resp.StatusCode, resp.Body)
+		}
+		// the legacy connector responds 200 with the error message in body
+		// when the request is throttled, and '1' for success
+		text := strings.TrimSpace(string(resp.Body))
+		if strings.Contains(text, "429") {
+			return &sRetryError{err: errors.Wrap(ErrRateLimited, text), Delay: retryAfter(resp.Header)}
+		}
+		if len(text) > 0 && text != "1" {
+			return errors.Errorf("teams webhook: %s", text)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package robot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/notify"
+)
+
+var telegramApiBase = "https://api.telegram.org"
+
+const telegramTextLimit = 4096
+
+func init() {
+	Register(api.ROBOT_TYPE_TELEGRAM, &STelegramSender{})
+}
+
+// STelegramSender sends message through telegram Bot API.
+// The address is '<bot token>/<chat id>', the chat id could be the numeric id
+// of the chat or the username of the channel like '@mychannel'.
+type STelegramSender struct{}
+
+func (s *STelegramSender) parseAddress(address string) (token, chatId string, err error) {
+	parts := strings.SplitN(address, "/", 2)
+	if len(parts) != 2 || !strings.Contains(parts[0], ":") || len(parts[1]) == 0 {
+		return "", "", errors.Wrapf(ErrInvalidAddress, "telegram address should be '<bot token>/<chat id>'")
+	}
+	return parts[0], parts[1], nil
+}
+
+func (s *STelegramSender) ValidateAddress(address string) error {
+	_, _, err := s.parseAddress(address)
+	return err
+}
+
+func (s *STelegramSender) Send(ctx context.Context, address, title, message string) error {
+	token, chatId, err := s.parseAddress(address)
+	if err != nil {
+		return err
+	}
+	body := jsonutils.NewDict()
+	body.Set("chat_id", jsonutils.NewString(chatId))
+	body.Set("disable_web_page_preview", jsonutils.JSONTrue)
+	text := MarkdownToTelegramHTML(message)
+	if len(title) > 0 {
+		text = fmt.Sprintf("<b>%s</b>\n%s", html.EscapeString(title), text)
+	}
+	if len([]rune(text)) <= telegramTextLimit {
+		body.Set("parse_mode", jsonutils.NewString("HTML"))
+	} else {
+		// the truncated html may be broken, fall back to plain text
+		text = truncate(fmt.Sprintf("%s\n%s", title, message), telegramTextLimit)
+	}
+	body.Set("text", jsonutils.NewString(text))
+	url := fmt.Sprintf("%s/bot%s/sendMessage", telegramApiBase, token)
+	return withRetry(ctx, func() error {
+		resp, err := postJSON(ctx, url, nil, body)
+		if err != nil {
+			if re, ok := err.(*sRetryError); ok && re.Delay == 0 {
+				// telegram returns the delay in body instead of header
+				re.Delay = telegramRetryAfter(re.Body)
+			}
+			return redactToken(err, token)
+		}
+		ret, err := resp.json()
+		if err != nil {
+			return errors.Wrapf(err, "parse telegram response %s", resp.Body)
+		}
+		if ok, _ := ret.Bool("ok"); !ok {
+			desc, _ := ret.GetString("description")
+			return errors.Errorf("telegram sendMessage: %s", desc)
+		}
+		return nil
+	})
+}
+
+// redactToken hides the bot token in the request url the transport errors
+// carry, the errors are logged and returned to the caller
+func redactToken(err error, token string) error {
+	if re, ok := err.(*sRetryError); ok {
+		re.err = redactToken(re.err, token)
+		return re
+	}
+	if !strings.Contains(err.Error(), token) {
+		return err
+	}
+	return errors.Error(strings.ReplaceAll(err.Error(), token, "<token>"))
+}
+
+// telegramRetryAfter extracts parameters.retry_after from the response body
+func telegramRetryAfter(body []byte) time.Duration {
+	if len(body) == 0 {
+		return 0
+	}
+	ret, err := jsonutils.Parse(body)
+	if err != nil {
+		return 0
+	}
+	sec, _ := ret.Int("parameters", "retry_after")
+	return time.Duration(sec) * time.Second
+}
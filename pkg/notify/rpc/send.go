@@ -34,6 +34,7 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/notify"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	notifyv2 "yunion.io/x/onecloud/pkg/notify"
+	"yunion.io/x/onecloud/pkg/notify/robot"
 	"yunion.io/x/onecloud/pkg/notify/rpc/apis"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
 )
@@ -443,12 +444,37 @@ func robotType2ContactType(rType string) string {
 		return api.WORKWX_ROBOT
 	case api.ROBOT_TYPE_WEBHOOK:
 		return api.WEBHOOK
+	case api.ROBOT_TYPE_SLACK:
+		return api.SLACK_ROBOT
+	case api.ROBOT_TYPE_TEAMS:
+		return api.TEAMS_ROBOT
+	case api.ROBOT_TYPE_TELEGRAM:
+		return api.TELEGRAM_ROBOT
 	}
 	return rType
 }
 
+// sendRobotMessageLocal sends message by the robot sender in notify service
+// instead of the send service
+func (self *SRpcService) sendRobotMessageLocal(ctx context.Context, sender robot.IRobotSender, receivers []*apis.SReceiver, title string, message string) []*apis.FailedRecord {
+	records := make([]*apis.FailedRecord, 0)
+	for _, receiver := range receivers {
+		err := sender.Send(ctx, receiver.Contact, title, message)
+		if err != nil {
+			records = append(records, &apis.FailedRecord{
+				Receiver: receiver,
+				Reason:   err.Error(),
+			})
+		}
+	}
+	return records
+}
+
 func (self *SRpcService) SendRobotMessage(ctx context.Context, rType string, receivers []*apis.SReceiver, title string, message string) ([]*apis.FailedRecord, error) {
 	log.Infof("rType: %s", rType)
+	if sender, ok := robot.GetSender(rType); ok {
+		return self.sendRobotMessageLocal(ctx, sender, receivers, title, message), nil
+	}
 	contactType := robotType2ContactType(rType)
 	args := apis.BatchSendParams{
 		Receivers: receivers,
@@ -58,6 +58,8 @@ func s3HandlerTimeoutInfo(info *appsrv.SHandlerInfo, r *http.Request) time.Durat
 			return 2 * time.Hour
 		} else if r.Method == http.MethodPut && (len(r.URL.RawQuery) == 0 || strings.Contains(r.URL.RawQuery, "partNumber=")) {
 			return 2 * time.Hour
+		} else if r.Method == http.MethodPost && strings.Contains(r.URL.RawQuery, "select") {
+			return 2 * time.Hour
 		}
 	}
 	return time.Duration(0)
@@ -372,9 +374,6 @@ func postObject(ctx context.Context, userCred mcclient.TokenCredential, bucket s
 			return nil, nil, errors.Wrap(httperrors.ErrBadRequest, "FetchXml")
 		}
 		return completeMultipartUpload(ctx, userCred, r.Header, bucket, key, uploadId, &request)
-	} else if query.Contains("select") {
-		// select object
-		return selectObject(ctx, userCred, r.Header, bucket, key)
 	} else {
 		// upload object by form POST
 	}
@@ -397,6 +396,14 @@ func postHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 			SendError(ctx, w, BadRequest(ctx, err.Error()))
 			return
 		}
+		if query.Contains("select") {
+			// select object, results are streamed as event stream
+			err := selectObject(ctx, userCred, o.Bucket, o.Key, r, w)
+			if err != nil {
+				SendGeneralError(ctx, w, err)
+			}
+			return
+		}
 		resp, respHdr, err := postObject(ctx, userCred, o.Bucket, o.Key, query, r)
 		if err != nil {
 			SendGeneralError(ctx, w, err)
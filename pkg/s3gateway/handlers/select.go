@@ -18,11 +18,56 @@ import (
 	"context"
 	"net/http"
 
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
 	"yunion.io/x/s3cli"
 
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/s3gateway/models"
+	"yunion.io/x/onecloud/pkg/s3gateway/s3select"
 )
 
-func selectObject(ctx context.Context, userCred mcclient.TokenCredential, hdr http.Header, bucket string, key string) (*s3cli.InitiateMultipartUploadResult, http.Header, error) {
-	return nil, nil, NotImplemented(ctx, "")
+func selectError(ctx context.Context, err error) s3cli.ErrorResponse {
+	return generalError(ctx, 400, s3select.ErrorCode(err), err.Error())
+}
+
+func selectObject(ctx context.Context, userCred mcclient.TokenCredential, bucketName string, key string, r *http.Request, w http.ResponseWriter) error {
+	request := s3cli.SelectObjectOptions{}
+	err := appsrv.FetchXml(r, &request)
+	if err != nil {
+		return errors.Wrap(httperrors.ErrBadRequest, "FetchXml")
+	}
+	selector, err := s3select.NewSelector(&request)
+	if err != nil {
+		return selectError(ctx, err)
+	}
+	bucket, err := models.BucketManager.GetByName(ctx, userCred, bucketName)
+	if err != nil {
+		return errors.Wrap(err, "models.BucketManager.GetByName")
+	}
+	iBucket, err := bucket.GetIBucket(ctx, userCred)
+	if err != nil {
+		return errors.Wrap(err, "bucket.GetIBucket")
+	}
+	stream, err := iBucket.GetObject(ctx, key, nil)
+	if err != nil {
+		return errors.Wrap(err, "iBucket.GetObject")
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	// from now on errors can only be reported inside the event stream
+	sw := s3select.NewEventStreamWriter(w)
+	err = selector.Run(ctx, stream, sw)
+	if err != nil {
+		log.Errorf("select %s/%s %q: %s", bucketName, key, request.Expression, err)
+		err = sw.WriteError(s3select.ErrorCode(err), err.Error())
+		if err != nil {
+			log.Errorf("send select error event: %s", err)
+		}
+	}
+	return nil
 }
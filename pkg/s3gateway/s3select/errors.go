@@ -0,0 +1,40 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	ErrParse            = errors.Error("ParseSelectFailure")
+	ErrEvaluation       = errors.Error("EvaluatorInvalidArguments")
+	ErrUnsupported      = errors.Error("UnsupportedSyntax")
+	ErrInvalidDataType  = errors.Error("InvalidDataType")
+	ErrInvalidInput     = errors.Error("InvalidRequestParameter")
+	ErrInvalidCSVRecord = errors.Error("CSVParsingError")
+	ErrInvalidJSON      = errors.Error("JSONParsingError")
+)
+
+// ErrorCode returns the S3 error code to report for an error raised while
+// parsing or evaluating a select request.
+func ErrorCode(err error) string {
+	switch cause := errors.Cause(err); cause {
+	case ErrParse, ErrEvaluation, ErrUnsupported, ErrInvalidDataType, ErrInvalidInput, ErrInvalidCSVRecord, ErrInvalidJSON:
+		return string(cause.(errors.Error))
+	default:
+		return "InternalError"
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/s3cli"
+)
+
+const (
+	headerMessageType = ":message-type"
+	headerEventType   = ":event-type"
+	headerContentType = ":content-type"
+	headerErrorCode   = ":error-code"
+	headerErrorMsg    = ":error-message"
+
+	// value type of a string header in the event stream encoding
+	headerValueTypeString = 7
+
+	eventRecords  = "Records"
+	eventStats    = "Stats"
+	eventProgress = "Progress"
+	eventCont     = "Cont"
+	eventEnd      = "End"
+)
+
+type sEventHeader struct {
+	name  string
+	value string
+}
+
+// SEventStreamWriter encodes select results into the AWS event stream
+// binary framing:
+//
+//	| total len (4) | headers len (4) | prelude crc (4) | headers | payload | message crc (4) |
+type SEventStreamWriter struct {
+	writer  io.Writer
+	flusher http.Flusher
+
+	// lock serializes messages written by the select loop and the
+	// keep-alive ticker
+	lock      sync.Mutex
+	lastWrite time.Time
+}
+
+func NewEventStreamWriter(w io.Writer) *SEventStreamWriter {
+	sw := &SEventStreamWriter{writer: w, lastWrite: time.Now()}
+	if f, ok := w.(http.Flusher); ok {
+		sw.flusher = f
+	}
+	return sw
+}
+
+func encodeMessage(headers []sEventHeader, payload []byte) []byte {
+	var hdrBuf bytes.Buffer
+	for _, h := range headers {
+		hdrBuf.WriteByte(byte(len(h.name)))
+		hdrBuf.WriteString(h.name)
+		hdrBuf.WriteByte(headerValueTypeString)
+		binary.Write(&hdrBuf, binary.BigEndian, uint16(len(h.value)))
+		hdrBuf.WriteString(h.value)
+	}
+	totalLen := 4 + 4 + 4 + hdrBuf.Len() + len(payload) + 4
+	msg := bytes.NewBuffer(make([]byte, 0, totalLen))
+	binary.Write(msg, binary.BigEndian, uint32(totalLen))
+	binary.Write(msg, binary.BigEndian, uint32(hdrBuf.Len()))
+	binary.Write(msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(hdrBuf.Bytes())
+	msg.Write(payload)
+	binary.Write(msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
+
+func (w *SEventStreamWriter) writeMessage(headers []sEventHeader, payload []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.lastWrite = time.Now()
+	_, err := w.writer.Write(encodeMessage(headers, payload))
+	if err != nil {
+		return errors.Wrap(err, "write event")
+	}
+	if w.flusher != nil {
+		w.flusher.Flush()
+	}
+	return nil
+}
+
+func (w *SEventStreamWriter) writeEvent(event string, contentType string, payload []byte) error {
+	headers := []sEventHeader{
+		{name: headerEventType, value: event},
+	}
+	if len(contentType) > 0 {
+		headers = append(headers, sEventHeader{name: headerContentType, value: contentType})
+	}
+	headers = append(headers, sEventHeader{name: headerMessageType, value: "event"})
+	return w.writeMessage(headers, payload)
+}
+
+func (w *SEventStreamWriter) WriteRecords(payload []byte) error {
+	return w.writeEvent(eventRecords, "application/octet-stream", payload)
+}
+
+func (w *SEventStreamWriter) WriteStats(stats s3cli.StatsMessage) error {
+	payload, err := xml.Marshal(stats)
+	if err != nil {
+		return errors.Wrap(err, "xml.Marshal")
+	}
+	return w.writeEvent(eventStats, "text/xml", payload)
+}
+
+func (w *SEventStreamWriter) WriteProgress(stats s3cli.StatsMessage) error {
+	progress := s3cli.ProgressMessage{StatsMessage: stats}
+	payload, err := xml.Marshal(progress)
+	if err != nil {
+		return errors.Wrap(err, "xml.Marshal")
+	}
+	return w.writeEvent(eventProgress, "text/xml", payload)
+}
+
+func (w *SEventStreamWriter) WriteCont() error {
+	return w.writeEvent(eventCont, "", nil)
+}
+
+// idleSince returns how long no message has been written
+func (w *SEventStreamWriter) idleSince() time.Duration {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return time.Since(w.lastWrite)
+}
+
+func (w *SEventStreamWriter) WriteEnd() error {
+	return w.writeEvent(eventEnd, "", nil)
+}
+
+func (w *SEventStreamWriter) WriteError(code string, msg string) error {
+	return w.writeMessage([]sEventHeader{
+		{name: headerErrorCode, value: code},
+		{name: headerErrorMsg, value: msg},
+		{name: headerMessageType, value: "error"},
+	}, nil)
+}
@@ -0,0 +1,792 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"yunion.io/x/pkg/errors"
+)
+
+type iExpr interface {
+	eval(rec *SRecord) (interface{}, error)
+}
+
+type sLiteral struct {
+	val interface{}
+}
+
+func (e *sLiteral) eval(rec *SRecord) (interface{}, error) {
+	return e.val, nil
+}
+
+type sPathSeg struct {
+	name    string
+	quoted  bool
+	index   int
+	isIndex bool
+}
+
+type sColumnRef struct {
+	path []sPathSeg
+}
+
+// name returns the output column name of the reference, empty if it ends
+// with an array index
+func (e *sColumnRef) name() string {
+	last := e.path[len(e.path)-1]
+	if last.isIndex {
+		return ""
+	}
+	return last.name
+}
+
+func (e *sColumnRef) eval(rec *SRecord) (interface{}, error) {
+	var cur interface{} = rec
+	for _, seg := range e.path {
+		switch v := cur.(type) {
+		case *SRecord:
+			if seg.isIndex {
+				return nil, nil
+			}
+			val, ok := v.Get(seg.name, seg.quoted)
+			if !ok {
+				idx, isPos := parsePositional(seg.name)
+				if !isPos || idx >= len(v.Values) {
+					return nil, nil
+				}
+				val = v.Values[idx]
+			}
+			cur = val
+		case []interface{}:
+			if !seg.isIndex || seg.index < 0 || seg.index >= len(v) {
+				return nil, nil
+			}
+			cur = v[seg.index]
+		default:
+			return nil, nil
+		}
+	}
+	return cur, nil
+}
+
+type sUnaryExpr struct {
+	op string
+	x  iExpr
+}
+
+func (e *sUnaryExpr) eval(rec *SRecord) (interface{}, error) {
+	val, err := e.x.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, nil
+	}
+	switch e.op {
+	case "NOT":
+		b, err := toBool(val)
+		if err != nil {
+			return nil, err
+		}
+		return !b, nil
+	case "-":
+		num, err := toNumber(val)
+		if err != nil {
+			return nil, err
+		}
+		switch n := num.(type) {
+		case int64:
+			return -n, nil
+		case float64:
+			return -n, nil
+		}
+	}
+	return nil, errors.Wrapf(ErrUnsupported, "unary operator %s", e.op)
+}
+
+type sBinaryExpr struct {
+	op string
+	l  iExpr
+	r  iExpr
+}
+
+func (e *sBinaryExpr) eval(rec *SRecord) (interface{}, error) {
+	switch e.op {
+	case "AND", "OR":
+		return e.evalLogical(rec)
+	}
+	lv, err := e.l.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	rv, err := e.r.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	if lv == nil || rv == nil {
+		return nil, nil
+	}
+	switch e.op {
+	case "=", "!=", "<>", "<", "<=", ">", ">=":
+		cmp, err := compareValues(lv, rv)
+		if err != nil {
+			return unknownOnTypeMismatch(err)
+		}
+		switch e.op {
+		case "=":
+			return cmp == 0, nil
+		case "!=", "<>":
+			return cmp != 0, nil
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	case "||":
+		return valueToString(lv) + valueToString(rv), nil
+	case "+", "-", "*", "/", "%":
+		return arithmetic(e.op, lv, rv)
+	}
+	return nil, errors.Wrapf(ErrUnsupported, "binary operator %s", e.op)
+}
+
+func (e *sBinaryExpr) evalLogical(rec *SRecord) (interface{}, error) {
+	lv, err := evalBool(e.l, rec)
+	if err != nil {
+		return nil, err
+	}
+	if e.op == "AND" && lv != nil && !*lv {
+		return false, nil
+	}
+	if e.op == "OR" && lv != nil && *lv {
+		return true, nil
+	}
+	rv, err := evalBool(e.r, rec)
+	if err != nil {
+		return nil, err
+	}
+	if e.op == "AND" {
+		if rv != nil && !*rv {
+			return false, nil
+		}
+	} else {
+		if rv != nil && *rv {
+			return true, nil
+		}
+	}
+	if lv == nil || rv == nil {
+		return nil, nil
+	}
+	return *rv, nil
+}
+
+// evalBool evaluates a predicate with three-valued logic, nil meaning
+// unknown.
+func evalBool(x iExpr, rec *SRecord) (*bool, error) {
+	val, err := x.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, nil
+	}
+	b, err := toBool(val)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+type sLikeExpr struct {
+	x       iExpr
+	pattern iExpr
+	escape  iExpr
+	not     bool
+
+	cached *regexp.Regexp
+}
+
+func (e *sLikeExpr) eval(rec *SRecord) (interface{}, error) {
+	val, err := e.x.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, nil
+	}
+	re := e.cached
+	if re == nil {
+		pattern, err := e.pattern.eval(rec)
+		if err != nil {
+			return nil, err
+		}
+		if pattern == nil {
+			return nil, nil
+		}
+		escape := ""
+		if e.escape != nil {
+			escVal, err := e.escape.eval(rec)
+			if err != nil {
+				return nil, err
+			}
+			escape = valueToString(escVal)
+		}
+		re, err = likeToRegexp(valueToString(pattern), escape)
+		if err != nil {
+			return nil, err
+		}
+		_, patIsLit := e.pattern.(*sLiteral)
+		if patIsLit && (e.escape == nil || isLiteral(e.escape)) {
+			e.cached = re
+		}
+	}
+	match := re.MatchString(valueToString(val))
+	return match != e.not, nil
+}
+
+func isLiteral(x iExpr) bool {
+	_, ok := x.(*sLiteral)
+	return ok
+}
+
+func likeToRegexp(pattern string, escape string) (*regexp.Regexp, error) {
+	if utf8.RuneCountInString(escape) > 1 {
+		return nil, errors.Wrap(ErrEvaluation, "LIKE escape must be a single character")
+	}
+	escRune, _ := utf8.DecodeRuneInString(escape)
+	var sb strings.Builder
+	sb.WriteString("(?s)^")
+	escaped := false
+	for _, c := range pattern {
+		if escaped {
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+			escaped = false
+			continue
+		}
+		switch {
+		case len(escape) > 0 && c == escRune:
+			escaped = true
+		case c == '%':
+			sb.WriteString(".*")
+		case c == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+type sInExpr struct {
+	x    iExpr
+	list []iExpr
+	not  bool
+}
+
+func (e *sInExpr) eval(rec *SRecord) (interface{}, error) {
+	val, err := e.x.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, nil
+	}
+	for _, item := range e.list {
+		iv, err := item.eval(rec)
+		if err != nil {
+			return nil, err
+		}
+		if iv == nil {
+			continue
+		}
+		cmp, err := compareValues(val, iv)
+		if err != nil {
+			continue
+		}
+		if cmp == 0 {
+			return !e.not, nil
+		}
+	}
+	return e.not, nil
+}
+
+type sBetweenExpr struct {
+	x   iExpr
+	lo  iExpr
+	hi  iExpr
+	not bool
+}
+
+func (e *sBetweenExpr) eval(rec *SRecord) (interface{}, error) {
+	val, err := e.x.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	lo, err := e.lo.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := e.hi.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil || lo == nil || hi == nil {
+		return nil, nil
+	}
+	c1, err := compareValues(val, lo)
+	if err != nil {
+		return unknownOnTypeMismatch(err)
+	}
+	c2, err := compareValues(val, hi)
+	if err != nil {
+		return unknownOnTypeMismatch(err)
+	}
+	return (c1 >= 0 && c2 <= 0) != e.not, nil
+}
+
+type sIsNullExpr struct {
+	x   iExpr
+	not bool
+}
+
+func (e *sIsNullExpr) eval(rec *SRecord) (interface{}, error) {
+	val, err := e.x.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	return (val == nil) != e.not, nil
+}
+
+type sCastExpr struct {
+	x   iExpr
+	typ string
+}
+
+func (e *sCastExpr) eval(rec *SRecord) (interface{}, error) {
+	val, err := e.x.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, nil
+	}
+	switch e.typ {
+	case "INT", "INTEGER", "BIGINT", "SMALLINT":
+		num, err := toNumber(val)
+		if err != nil {
+			return nil, err
+		}
+		switch n := num.(type) {
+		case float64:
+			return int64(n), nil
+		default:
+			return n, nil
+		}
+	case "FLOAT", "REAL", "DOUBLE", "DECIMAL", "NUMERIC":
+		num, err := toNumber(val)
+		if err != nil {
+			return nil, err
+		}
+		switch n := num.(type) {
+		case int64:
+			return float64(n), nil
+		default:
+			return n, nil
+		}
+	case "STRING", "VARCHAR", "CHAR":
+		return valueToString(val), nil
+	case "BOOL", "BOOLEAN":
+		return toBool(val)
+	}
+	return nil, errors.Wrapf(ErrUnsupported, "cast to %s", e.typ)
+}
+
+type sFuncExpr struct {
+	name string
+	args []iExpr
+}
+
+func (e *sFuncExpr) eval(rec *SRecord) (interface{}, error) {
+	vals := make([]interface{}, len(e.args))
+	for i := range e.args {
+		v, err := e.args[i].eval(rec)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	switch e.name {
+	case "COALESCE":
+		for _, v := range vals {
+			if v != nil {
+				return v, nil
+			}
+		}
+		return nil, nil
+	case "NULLIF":
+		if len(vals) != 2 {
+			return nil, errors.Wrap(ErrEvaluation, "NULLIF requires 2 arguments")
+		}
+		if vals[0] == nil || vals[1] == nil {
+			return vals[0], nil
+		}
+		cmp, err := compareValues(vals[0], vals[1])
+		if err == nil && cmp == 0 {
+			return nil, nil
+		}
+		return vals[0], nil
+	}
+	if len(vals) == 0 {
+		return nil, errors.Wrapf(ErrEvaluation, "%s requires arguments", e.name)
+	}
+	if vals[0] == nil {
+		return nil, nil
+	}
+	switch e.name {
+	case "LOWER":
+		return strings.ToLower(valueToString(vals[0])), nil
+	case "UPPER":
+		return strings.ToUpper(valueToString(vals[0])), nil
+	case "TRIM":
+		return strings.TrimSpace(valueToString(vals[0])), nil
+	case "CHAR_LENGTH", "CHARACTER_LENGTH":
+		return int64(utf8.RuneCountInString(valueToString(vals[0]))), nil
+	case "ABS":
+		num, err := toNumber(vals[0])
+		if err != nil {
+			return nil, err
+		}
+		switch n := num.(type) {
+		case int64:
+			if n < 0 {
+				return -n, nil
+			}
+			return n, nil
+		case float64:
+			return math.Abs(n), nil
+		}
+	case "SUBSTRING":
+		return substring(vals)
+	}
+	return nil, errors.Wrapf(ErrUnsupported, "function %s", e.name)
+}
+
+func substring(vals []interface{}) (interface{}, error) {
+	if len(vals) < 2 || len(vals) > 3 {
+		return nil, errors.Wrap(ErrEvaluation, "SUBSTRING requires 2 or 3 arguments")
+	}
+	// like SQL, any NULL argument makes the result NULL
+	for _, val := range vals {
+		if val == nil {
+			return nil, nil
+		}
+	}
+	runes := []rune(valueToString(vals[0]))
+	startNum, err := toNumber(vals[1])
+	if err != nil {
+		return nil, err
+	}
+	start := int(toFloat(startNum))
+	end := len(runes) + 1
+	if len(vals) == 3 {
+		lenNum, err := toNumber(vals[2])
+		if err != nil {
+			return nil, err
+		}
+		length := int(toFloat(lenNum))
+		if length < 0 {
+			return nil, errors.Wrap(ErrEvaluation, "negative SUBSTRING length")
+		}
+		end = start + length
+	}
+	// SQL positions are 1-based and positions before 1 are clipped
+	if start < 1 {
+		start = 1
+	}
+	if end > len(runes)+1 {
+		end = len(runes) + 1
+	}
+	if start >= end {
+		return "", nil
+	}
+	return string(runes[start-1 : end-1]), nil
+}
+
+const (
+	aggCount = "COUNT"
+	aggSum   = "SUM"
+	aggAvg   = "AVG"
+	aggMin   = "MIN"
+	aggMax   = "MAX"
+)
+
+// sAggregateExpr accumulates its argument over all matching records and
+// evaluates to the accumulated result once the scan is finished.
+type sAggregateExpr struct {
+	fn  string
+	arg iExpr // nil for COUNT(*)
+
+	count int64
+	sum   interface{}
+	best  interface{}
+}
+
+func (e *sAggregateExpr) accumulate(rec *SRecord) error {
+	if e.arg == nil {
+		e.count++
+		return nil
+	}
+	val, err := e.arg.eval(rec)
+	if err != nil {
+		return err
+	}
+	if val == nil {
+		return nil
+	}
+	e.count++
+	switch e.fn {
+	case aggSum, aggAvg:
+		num, err := toNumber(val)
+		if err != nil {
+			return err
+		}
+		if e.sum == nil {
+			e.sum = num
+		} else {
+			e.sum, err = arithmetic("+", e.sum, num)
+			if err != nil {
+				return err
+			}
+		}
+	case aggMin, aggMax:
+		if e.best == nil {
+			e.best = val
+			return nil
+		}
+		cmp, err := compareValues(val, e.best)
+		if err != nil {
+			return err
+		}
+		if (e.fn == aggMin && cmp < 0) || (e.fn == aggMax && cmp > 0) {
+			e.best = val
+		}
+	}
+	return nil
+}
+
+func (e *sAggregateExpr) eval(rec *SRecord) (interface{}, error) {
+	switch e.fn {
+	case aggCount:
+		return e.count, nil
+	case aggSum:
+		return e.sum, nil
+	case aggAvg:
+		if e.count == 0 || e.sum == nil {
+			return nil, nil
+		}
+		return toFloat(e.sum) / float64(e.count), nil
+	default:
+		return e.best, nil
+	}
+}
+
+func toBool(val interface{}) (bool, error) {
+	switch v := val.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, errors.Wrapf(ErrInvalidDataType, "%q is not a boolean", v)
+		}
+		return b, nil
+	}
+	return false, errors.Wrapf(ErrInvalidDataType, "%v is not a boolean", val)
+}
+
+// toNumber converts a value into int64 or float64. Strings are parsed so
+// that CSV fields can be used in arithmetic and numeric comparisons.
+func toNumber(val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case int64, float64:
+		return v, nil
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+		return nil, errors.Wrapf(ErrInvalidDataType, "%q is not a number", v)
+	}
+	return nil, errors.Wrapf(ErrInvalidDataType, "%v is not a number", val)
+}
+
+func toFloat(num interface{}) float64 {
+	switch n := num.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func isNumber(val interface{}) bool {
+	switch val.(type) {
+	case int64, float64:
+		return true
+	}
+	return false
+}
+
+func arithmetic(op string, lv, rv interface{}) (interface{}, error) {
+	ln, err := toNumber(lv)
+	if err != nil {
+		return nil, err
+	}
+	rn, err := toNumber(rv)
+	if err != nil {
+		return nil, err
+	}
+	li, lInt := ln.(int64)
+	ri, rInt := rn.(int64)
+	if lInt && rInt {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "/":
+			if ri == 0 {
+				return nil, errors.Wrap(ErrEvaluation, "division by zero")
+			}
+			return li / ri, nil
+		case "%":
+			if ri == 0 {
+				return nil, errors.Wrap(ErrEvaluation, "division by zero")
+			}
+			return li % ri, nil
+		}
+	}
+	lf, rf := toFloat(ln), toFloat(rn)
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, errors.Wrap(ErrEvaluation, "division by zero")
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, errors.Wrap(ErrEvaluation, "division by zero")
+		}
+		return math.Mod(lf, rf), nil
+	}
+	return nil, errors.Wrapf(ErrUnsupported, "operator %s", op)
+}
+
+// compareValues compares two non-null values. When either side is numeric
+// the other side is converted to a number, so that untyped CSV fields
+// compare naturally against numeric literals.
+func compareValues(lv, rv interface{}) (int, error) {
+	if isNumber(lv) || isNumber(rv) {
+		ln, err := toNumber(lv)
+		if err != nil {
+			return 0, err
+		}
+		rn, err := toNumber(rv)
+		if err != nil {
+			return 0, err
+		}
+		li, lInt := ln.(int64)
+		ri, rInt := rn.(int64)
+		if lInt && rInt {
+			return compareInt(li, ri), nil
+		}
+		lf, rf := toFloat(ln), toFloat(rn)
+		switch {
+		case lf < rf:
+			return -1, nil
+		case lf > rf:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	lb, lBool := lv.(bool)
+	rb, rBool := rv.(bool)
+	if lBool || rBool {
+		var err error
+		if !lBool {
+			lb, err = toBool(lv)
+		} else if !rBool {
+			rb, err = toBool(rv)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if lb == rb {
+			return 0, nil
+		}
+		if !lb {
+			return -1, nil
+		}
+		return 1, nil
+	}
+	return strings.Compare(valueToString(lv), valueToString(rv)), nil
+}
+
+// unknownOnTypeMismatch turns a comparison between incompatible values,
+// e.g. an empty CSV field against a number, into an unknown result instead
+// of failing the whole query.
+func unknownOnTypeMismatch(err error) (interface{}, error) {
+	if errors.Cause(err) == ErrInvalidDataType {
+		return nil, nil
+	}
+	return nil, err
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
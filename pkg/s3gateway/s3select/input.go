@@ -0,0 +1,310 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/s3cli"
+)
+
+type iRecordReader interface {
+	// Read returns the next input record or io.EOF
+	Read() (*SRecord, error)
+}
+
+type sCountingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *sCountingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+func decompressReader(compression s3cli.SelectCompressionType, r io.Reader) (io.Reader, error) {
+	switch strings.ToUpper(string(compression)) {
+	case "", string(s3cli.SelectCompressionNONE):
+		return r, nil
+	case s3cli.SelectCompressionGZIP:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidInput, "gzip.NewReader: %s", err)
+		}
+		return gz, nil
+	case s3cli.SelectCompressionBZIP:
+		return bzip2.NewReader(r), nil
+	}
+	return nil, errors.Wrapf(ErrInvalidInput, "unsupported compression type %s", compression)
+}
+
+func validateInput(input *s3cli.SelectObjectInputSerialization) error {
+	if input.Parquet != nil {
+		return errors.Wrap(ErrUnsupported, "Parquet input is not supported")
+	}
+	switch {
+	case input.CSV != nil && input.JSON != nil:
+		return errors.Wrap(ErrInvalidInput, "only one of CSV and JSON input serialization is allowed")
+	case input.CSV != nil:
+		opts := input.CSV
+		for _, s := range []string{opts.FieldDelimiter, opts.QuoteCharacter, opts.Comments} {
+			if utf8.RuneCountInString(s) > 1 {
+				return errors.Wrapf(ErrInvalidInput, "%q must be a single character", s)
+			}
+		}
+		if len(opts.QuoteCharacter) > 0 && opts.QuoteCharacter != `"` {
+			return errors.Wrapf(ErrUnsupported, "quote character %q", opts.QuoteCharacter)
+		}
+		switch strings.ToUpper(string(opts.FileHeaderInfo)) {
+		case "", string(s3cli.CSVFileHeaderInfoNone), s3cli.CSVFileHeaderInfoIgnore, s3cli.CSVFileHeaderInfoUse:
+		default:
+			return errors.Wrapf(ErrInvalidInput, "invalid FileHeaderInfo %s", opts.FileHeaderInfo)
+		}
+	case input.JSON != nil:
+		switch strings.ToUpper(string(input.JSON.Type)) {
+		case "", string(s3cli.JSONDocumentType), s3cli.JSONLinesType:
+		default:
+			return errors.Wrapf(ErrInvalidInput, "invalid JSON type %s", input.JSON.Type)
+		}
+	default:
+		return errors.Wrap(ErrInvalidInput, "missing input serialization")
+	}
+	return nil
+}
+
+type sCSVReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func newCSVReader(opts *s3cli.CSVInputOptions, r io.Reader) (*sCSVReader, error) {
+	if len(opts.RecordDelimiter) > 0 && opts.RecordDelimiter != "\n" && opts.RecordDelimiter != "\r\n" {
+		r = newDelimiterReader(r, opts.RecordDelimiter)
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if len(opts.FieldDelimiter) > 0 {
+		reader.Comma, _ = utf8.DecodeRuneInString(opts.FieldDelimiter)
+	}
+	if len(opts.Comments) > 0 {
+		reader.Comment, _ = utf8.DecodeRuneInString(opts.Comments)
+	}
+	cr := &sCSVReader{reader: reader}
+	switch strings.ToUpper(string(opts.FileHeaderInfo)) {
+	case s3cli.CSVFileHeaderInfoUse, s3cli.CSVFileHeaderInfoIgnore:
+		header, err := cr.readLine()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if strings.ToUpper(string(opts.FileHeaderInfo)) == s3cli.CSVFileHeaderInfoUse {
+			cr.header = header
+		}
+	}
+	return cr, nil
+}
+
+func (r *sCSVReader) readLine() ([]string, error) {
+	line, err := r.reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, errors.Wrapf(ErrInvalidCSVRecord, "%s", err)
+	}
+	return line, nil
+}
+
+func (r *sCSVReader) Read() (*SRecord, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	rec := &SRecord{
+		Keys:   make([]string, len(line)),
+		Values: make([]interface{}, len(line)),
+	}
+	for i := range line {
+		if i < len(r.header) {
+			rec.Keys[i] = r.header[i]
+		} else {
+			rec.Keys[i] = positionalName(i)
+		}
+		rec.Values[i] = line[i]
+	}
+	return rec, nil
+}
+
+// maximal length of a single input record, same as AWS
+const maxRecordSize = 1024 * 1024
+
+// sDelimiterReader rewrites a custom record delimiter into newlines so
+// that encoding/csv can split the records.
+type sDelimiterReader struct {
+	scanner *bufio.Scanner
+	buf     bytes.Buffer
+}
+
+func newDelimiterReader(r io.Reader, delim string) *sDelimiterReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	d := []byte(delim)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, d); i >= 0 {
+			return i + len(d), data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+	return &sDelimiterReader{scanner: scanner}
+}
+
+func (r *sDelimiterReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if !r.scanner.Scan() {
+			if err := r.scanner.Err(); err != nil {
+				return 0, errors.Wrapf(ErrInvalidCSVRecord, "%s", err)
+			}
+			return 0, io.EOF
+		}
+		r.buf.Write(r.scanner.Bytes())
+		r.buf.WriteByte('\n')
+	}
+	return r.buf.Read(p)
+}
+
+type sJSONReader struct {
+	decoder *json.Decoder
+	// pending elements of a top level array
+	pending []interface{}
+}
+
+func newJSONReader(r io.Reader) *sJSONReader {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return &sJSONReader{decoder: decoder}
+}
+
+func (r *sJSONReader) Read() (*SRecord, error) {
+	for {
+		var val interface{}
+		if len(r.pending) > 0 {
+			val = r.pending[0]
+			r.pending = r.pending[1:]
+		} else {
+			var err error
+			val, err = decodeJSONValue(r.decoder)
+			if err != nil {
+				return nil, err
+			}
+			if arr, ok := val.([]interface{}); ok {
+				r.pending = arr
+				continue
+			}
+		}
+		switch v := val.(type) {
+		case *SRecord:
+			return v, nil
+		default:
+			rec := NewRecord()
+			rec.Set(positionalName(0), v)
+			return rec, nil
+		}
+	}
+}
+
+// decodeJSONValue decodes the next JSON value keeping the key order of
+// objects, which are returned as *SRecord.
+func decodeJSONValue(decoder *json.Decoder) (interface{}, error) {
+	tok, err := decoder.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, errors.Wrapf(ErrInvalidJSON, "%s", err)
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			rec := NewRecord()
+			for decoder.More() {
+				keyTok, err := decoder.Token()
+				if err != nil {
+					return nil, errors.Wrapf(ErrInvalidJSON, "%s", err)
+				}
+				key, ok := keyTok.(string)
+				if !ok {
+					return nil, errors.Wrapf(ErrInvalidJSON, "invalid object key %v", keyTok)
+				}
+				val, err := decodeJSONValue(decoder)
+				if err != nil {
+					return nil, unexpectedEOF(err)
+				}
+				rec.Set(key, val)
+			}
+			if _, err := decoder.Token(); err != nil {
+				return nil, errors.Wrapf(ErrInvalidJSON, "%s", err)
+			}
+			return rec, nil
+		case '[':
+			arr := make([]interface{}, 0)
+			for decoder.More() {
+				val, err := decodeJSONValue(decoder)
+				if err != nil {
+					return nil, unexpectedEOF(err)
+				}
+				arr = append(arr, val)
+			}
+			if _, err := decoder.Token(); err != nil {
+				return nil, errors.Wrapf(ErrInvalidJSON, "%s", err)
+			}
+			return arr, nil
+		}
+		return nil, errors.Wrapf(ErrInvalidJSON, "unexpected delimiter %s", v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidJSON, "invalid number %s", v)
+		}
+		return f, nil
+	default:
+		// string, bool or nil
+		return v, nil
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return errors.Wrap(ErrInvalidJSON, "unexpected end of input")
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"strings"
+	"unicode"
+
+	"yunion.io/x/pkg/errors"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenIdent
+	tokenQuotedIdent
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+type sToken struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t sToken) isKeyword(kw string) bool {
+	return t.typ == tokenIdent && strings.EqualFold(t.val, kw)
+}
+
+func (t sToken) isOp(op string) bool {
+	return t.typ == tokenOp && t.val == op
+}
+
+var twoCharOps = []string{"<=", ">=", "<>", "!=", "||"}
+
+func tokenize(sql string) ([]sToken, error) {
+	tokens := make([]sToken, 0)
+	runes := []rune(sql)
+	i := 0
+	for i < len(runes) {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			closed := false
+			for i < len(runes) {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						sb.WriteRune('\'')
+						i += 2
+						continue
+					}
+					closed = true
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				return nil, errors.Wrapf(ErrParse, "unterminated string literal at %d", start)
+			}
+			tokens = append(tokens, sToken{typ: tokenString, val: sb.String(), pos: start})
+		case c == '"' || c == '`':
+			start := i
+			quote := c
+			i++
+			var sb strings.Builder
+			closed := false
+			for i < len(runes) {
+				if runes[i] == quote {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				return nil, errors.Wrapf(ErrParse, "unterminated quoted identifier at %d", start)
+			}
+			tokens = append(tokens, sToken{typ: tokenQuotedIdent, val: sb.String(), pos: start})
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				j := i + 1
+				if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+					j++
+				}
+				if j < len(runes) && unicode.IsDigit(runes[j]) {
+					i = j
+					for i < len(runes) && unicode.IsDigit(runes[i]) {
+						i++
+					}
+				}
+			}
+			tokens = append(tokens, sToken{typ: tokenNumber, val: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, sToken{typ: tokenIdent, val: string(runes[start:i]), pos: start})
+		default:
+			start := i
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				matched := false
+				for _, op := range twoCharOps {
+					if two == op {
+						matched = true
+						break
+					}
+				}
+				if matched {
+					tokens = append(tokens, sToken{typ: tokenOp, val: two, pos: start})
+					i += 2
+					continue
+				}
+			}
+			if strings.ContainsRune("()[],.*+-/%=<>", c) {
+				tokens = append(tokens, sToken{typ: tokenOp, val: string(c), pos: start})
+				i++
+				continue
+			}
+			return nil, errors.Wrapf(ErrParse, "unexpected character %q at %d", c, start)
+		}
+	}
+	tokens = append(tokens, sToken{typ: tokenEOF, pos: len(runes)})
+	return tokens, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/s3cli"
+)
+
+type iRecordWriter interface {
+	write(buf *bytes.Buffer, rec *SRecord) error
+}
+
+func newRecordWriter(output *s3cli.SelectObjectOutputSerialization) (iRecordWriter, error) {
+	switch {
+	case output.CSV != nil && output.JSON != nil:
+		return nil, errors.Wrap(ErrInvalidInput, "only one of CSV and JSON output serialization is allowed")
+	case output.CSV != nil:
+		return newCSVWriter(output.CSV)
+	case output.JSON != nil:
+		w := &sJSONWriter{recordDelimiter: output.JSON.RecordDelimiter}
+		if len(w.recordDelimiter) == 0 {
+			w.recordDelimiter = "\n"
+		}
+		return w, nil
+	}
+	return nil, errors.Wrap(ErrInvalidInput, "missing output serialization")
+}
+
+type sCSVWriter struct {
+	fieldDelimiter  string
+	recordDelimiter string
+	quote           string
+	quoteEscape     string
+	alwaysQuote     bool
+}
+
+func newCSVWriter(opts *s3cli.CSVOutputOptions) (*sCSVWriter, error) {
+	w := &sCSVWriter{
+		fieldDelimiter:  opts.FieldDelimiter,
+		recordDelimiter: opts.RecordDelimiter,
+		quote:           opts.QuoteCharacter,
+		quoteEscape:     opts.QuoteEscapeCharacter,
+	}
+	if len(w.fieldDelimiter) == 0 {
+		w.fieldDelimiter = ","
+	}
+	if len(w.recordDelimiter) == 0 {
+		w.recordDelimiter = "\n"
+	}
+	if len(w.quote) == 0 {
+		w.quote = `"`
+	}
+	if len(w.quoteEscape) == 0 {
+		w.quoteEscape = w.quote
+	}
+	for _, s := range []string{w.fieldDelimiter, w.quote, w.quoteEscape} {
+		if utf8.RuneCountInString(s) != 1 {
+			return nil, errors.Wrapf(ErrInvalidInput, "%q must be a single character", s)
+		}
+	}
+	switch strings.ToUpper(string(opts.QuoteFields)) {
+	case "", strings.ToUpper(string(s3cli.CSVQuoteFieldsAsNeeded)):
+	case strings.ToUpper(string(s3cli.CSVQuoteFieldsAlways)):
+		w.alwaysQuote = true
+	default:
+		return nil, errors.Wrapf(ErrInvalidInput, "invalid QuoteFields %s", opts.QuoteFields)
+	}
+	return w, nil
+}
+
+func (w *sCSVWriter) write(buf *bytes.Buffer, rec *SRecord) error {
+	for i, val := range rec.Values {
+		if i > 0 {
+			buf.WriteString(w.fieldDelimiter)
+		}
+		field := valueToString(val)
+		if w.alwaysQuote || w.needQuote(field) {
+			buf.WriteString(w.quote)
+			buf.WriteString(strings.ReplaceAll(field, w.quote, w.quoteEscape+w.quote))
+			buf.WriteString(w.quote)
+		} else {
+			buf.WriteString(field)
+		}
+	}
+	buf.WriteString(w.recordDelimiter)
+	return nil
+}
+
+func (w *sCSVWriter) needQuote(field string) bool {
+	return strings.Contains(field, w.fieldDelimiter) ||
+		strings.Contains(field, w.quote) ||
+		strings.Contains(field, w.recordDelimiter) ||
+		strings.ContainsAny(field, "\r\n")
+}
+
+type sJSONWriter struct {
+	recordDelimiter string
+}
+
+func (w *sJSONWriter) write(buf *bytes.Buffer, rec *SRecord) error {
+	js, err := json.Marshal(rec)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal")
+	}
+	buf.Write(js)
+	buf.WriteString(w.recordDelimiter)
+	return nil
+}
@@ -0,0 +1,640 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const tableName = "S3Object"
+
+var reservedWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "LIMIT": true, "AS": true,
+	"AND": true, "OR": true, "NOT": true, "LIKE": true, "ESCAPE": true,
+	"IN": true, "BETWEEN": true, "IS": true, "NULL": true, "MISSING": true,
+	"TRUE": true, "FALSE": true, "CAST": true,
+}
+
+var aggregateFuncs = map[string]bool{
+	aggCount: true, aggSum: true, aggAvg: true, aggMin: true, aggMax: true,
+}
+
+type sParser struct {
+	tokens []sToken
+	pos    int
+	alias  string
+
+	aggregates []*sAggregateExpr
+}
+
+func (p *sParser) peek() sToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sParser) next() sToken {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *sParser) acceptKeyword(kw string) bool {
+	if p.peek().isKeyword(kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sParser) acceptOp(op string) bool {
+	if p.peek().isOp(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sParser) expectKeyword(kw string) error {
+	if !p.acceptKeyword(kw) {
+		return p.errorf("expect %s", kw)
+	}
+	return nil
+}
+
+func (p *sParser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		return p.errorf("expect %q", op)
+	}
+	return nil
+}
+
+func (p *sParser) errorf(msg string, params ...interface{}) error {
+	t := p.peek()
+	if t.typ == tokenEOF {
+		return errors.Wrapf(ErrParse, msg+" at end of expression", params...)
+	}
+	return errors.Wrapf(ErrParse, msg+" near %q at %d", append(params, t.val, t.pos)...)
+}
+
+// ParseQuery parses an S3 select SQL expression of the form
+//
+//	SELECT <projections> FROM S3Object[[*]] [[AS] alias] [WHERE <cond>] [LIMIT n]
+func ParseQuery(sql string) (*SQuery, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &sParser{tokens: tokens}
+	return p.parseQuery()
+}
+
+func (p *sParser) parseQuery() (*SQuery, error) {
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	// projections reference the table alias which is only known after
+	// FROM is parsed, so locate FROM first and come back
+	projStart := p.pos
+	depth := 0
+	for {
+		t := p.peek()
+		if t.typ == tokenEOF {
+			return nil, p.errorf("missing FROM clause")
+		}
+		if t.isOp("(") {
+			depth++
+		} else if t.isOp(")") {
+			depth--
+		} else if depth == 0 && t.isKeyword("FROM") {
+			break
+		}
+		p.pos++
+	}
+	fromPos := p.pos
+	p.pos++
+	err := p.parseFrom()
+	if err != nil {
+		return nil, err
+	}
+	query := &SQuery{limit: -1}
+	if p.acceptKeyword("WHERE") {
+		query.where, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if len(p.aggregates) > 0 {
+			return nil, errors.Wrap(ErrParse, "aggregate functions are not allowed in WHERE clause")
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		t := p.next()
+		if t.typ != tokenNumber {
+			return nil, p.errorf("expect number after LIMIT")
+		}
+		query.limit, err = strconv.ParseInt(t.val, 10, 64)
+		if err != nil || query.limit < 0 {
+			return nil, errors.Wrapf(ErrParse, "invalid LIMIT %s", t.val)
+		}
+	}
+	if p.peek().typ != tokenEOF {
+		return nil, p.errorf("unexpected token")
+	}
+
+	p.tokens = append(append([]sToken{}, p.tokens[projStart:fromPos]...), sToken{typ: tokenEOF, pos: p.tokens[fromPos].pos})
+	p.pos = 0
+	err = p.parseProjections(query)
+	if err != nil {
+		return nil, err
+	}
+	query.aggregates = p.aggregates
+	if len(query.aggregates) > 0 {
+		for _, proj := range query.projections {
+			if !isAggregateOnly(proj.expr) {
+				return nil, errors.Wrap(ErrParse, "cannot mix aggregate and non-aggregate projections")
+			}
+		}
+	}
+	return query, nil
+}
+
+func (p *sParser) parseFrom() error {
+	t := p.next()
+	if t.typ != tokenIdent || !strings.EqualFold(t.val, tableName) {
+		return errors.Wrapf(ErrParse, "FROM must be %s", tableName)
+	}
+	if p.acceptOp("[") {
+		if err := p.expectOp("*"); err != nil {
+			return err
+		}
+		if err := p.expectOp("]"); err != nil {
+			return err
+		}
+	}
+	p.acceptKeyword("AS")
+	t = p.peek()
+	if (t.typ == tokenIdent && !reservedWords[strings.ToUpper(t.val)]) || t.typ == tokenQuotedIdent {
+		p.alias = t.val
+		p.pos++
+	}
+	return nil
+}
+
+func (p *sParser) parseProjections(query *SQuery) error {
+	if p.acceptOp("*") {
+		if p.peek().typ != tokenEOF {
+			return p.errorf("unexpected token after *")
+		}
+		return nil
+	}
+	for {
+		expr, err := p.parseExpr()
+		if err != nil {
+			return err
+		}
+		proj := sProjection{expr: expr}
+		if p.acceptKeyword("AS") {
+			t := p.next()
+			if t.typ != tokenIdent && t.typ != tokenQuotedIdent {
+				return p.errorf("expect alias after AS")
+			}
+			proj.name = t.val
+		} else if t := p.peek(); t.typ == tokenQuotedIdent || (t.typ == tokenIdent && !reservedWords[strings.ToUpper(t.val)]) {
+			proj.name = t.val
+			p.pos++
+		}
+		if len(proj.name) == 0 {
+			if col, ok := expr.(*sColumnRef); ok && len(col.name()) > 0 {
+				proj.name = col.name()
+			} else {
+				proj.name = positionalName(len(query.projections))
+			}
+		}
+		query.projections = append(query.projections, proj)
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	if p.peek().typ != tokenEOF {
+		return p.errorf("unexpected token")
+	}
+	return nil
+}
+
+func (p *sParser) parseExpr() (iExpr, error) {
+	return p.parseOr()
+}
+
+func (p *sParser) parseOr() (iExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &sBinaryExpr{op: "OR", l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *sParser) parseAnd() (iExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &sBinaryExpr{op: "AND", l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *sParser) parseNot() (iExpr, error) {
+	if p.acceptKeyword("NOT") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &sUnaryExpr{op: "NOT", x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *sParser) parseComparison() (iExpr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.typ == tokenOp {
+		switch t.val {
+		case "=", "!=", "<>", "<", "<=", ">", ">=":
+			p.pos++
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return &sBinaryExpr{op: t.val, l: left, r: right}, nil
+		}
+		return left, nil
+	}
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		if !p.acceptKeyword("NULL") && !p.acceptKeyword("MISSING") {
+			return nil, p.errorf("expect NULL or MISSING after IS")
+		}
+		return &sIsNullExpr{x: left, not: not}, nil
+	}
+	not := p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("LIKE"):
+		pattern, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		like := &sLikeExpr{x: left, pattern: pattern, not: not}
+		if p.acceptKeyword("ESCAPE") {
+			like.escape, err = p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return like, nil
+	case p.acceptKeyword("IN"):
+		if err := p.expectOp("("); err != nil {
+			return nil, err
+		}
+		list, err := p.parseExprList()
+		if err != nil {
+			return nil, err
+		}
+		return &sInExpr{x: left, list: list, not: not}, nil
+	case p.acceptKeyword("BETWEEN"):
+		lo, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		hi, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &sBetweenExpr{x: left, lo: lo, hi: hi, not: not}, nil
+	}
+	if not {
+		return nil, p.errorf("expect LIKE, IN or BETWEEN after NOT")
+	}
+	return left, nil
+}
+
+// parseExprList parses a comma separated expression list up to and
+// including the closing parenthesis.
+func (p *sParser) parseExprList() ([]iExpr, error) {
+	list := make([]iExpr, 0)
+	if p.acceptOp(")") {
+		return list, nil
+	}
+	for {
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, x)
+		if p.acceptOp(")") {
+			return list, nil
+		}
+		if err := p.expectOp(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *sParser) parseAdditive() (iExpr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !t.isOp("+") && !t.isOp("-") && !t.isOp("||") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &sBinaryExpr{op: t.val, l: left, r: right}
+	}
+}
+
+func (p *sParser) parseMultiplicative() (iExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !t.isOp("*") && !t.isOp("/") && !t.isOp("%") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &sBinaryExpr{op: t.val, l: left, r: right}
+	}
+}
+
+func (p *sParser) parseUnary() (iExpr, error) {
+	if p.acceptOp("-") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if lit, ok := x.(*sLiteral); ok && isNumber(lit.val) {
+			switch v := lit.val.(type) {
+			case int64:
+				return &sLiteral{val: -v}, nil
+			case float64:
+				return &sLiteral{val: -v}, nil
+			}
+		}
+		return &sUnaryExpr{op: "-", x: x}, nil
+	}
+	p.acceptOp("+")
+	return p.parsePrimary()
+}
+
+func (p *sParser) parsePrimary() (iExpr, error) {
+	t := p.peek()
+	switch t.typ {
+	case tokenString:
+		p.pos++
+		return &sLiteral{val: t.val}, nil
+	case tokenNumber:
+		p.pos++
+		if i, err := strconv.ParseInt(t.val, 10, 64); err == nil {
+			return &sLiteral{val: i}, nil
+		}
+		f, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, errors.Wrapf(ErrParse, "invalid number %s", t.val)
+		}
+		return &sLiteral{val: f}, nil
+	case tokenOp:
+		if p.acceptOp("(") {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+		return nil, p.errorf("unexpected operator")
+	case tokenQuotedIdent:
+		return p.parseColumnRef()
+	case tokenIdent:
+		upper := strings.ToUpper(t.val)
+		switch upper {
+		case "TRUE", "FALSE":
+			p.pos++
+			return &sLiteral{val: upper == "TRUE"}, nil
+		case "NULL", "MISSING":
+			p.pos++
+			return &sLiteral{val: nil}, nil
+		case "CAST":
+			p.pos++
+			return p.parseCast()
+		}
+		if p.tokens[p.pos+1].isOp("(") {
+			p.pos += 2
+			return p.parseFunc(upper)
+		}
+		if reservedWords[upper] {
+			return nil, p.errorf("unexpected keyword")
+		}
+		return p.parseColumnRef()
+	}
+	return nil, p.errorf("unexpected end of expression")
+}
+
+func (p *sParser) parseCast() (iExpr, error) {
+	if err := p.expectOp("("); err != nil {
+		return nil, err
+	}
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("AS"); err != nil {
+		return nil, err
+	}
+	t := p.next()
+	if t.typ != tokenIdent {
+		return nil, p.errorf("expect type name")
+	}
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+	return &sCastExpr{x: x, typ: strings.ToUpper(t.val)}, nil
+}
+
+func (p *sParser) parseFunc(name string) (iExpr, error) {
+	if aggregateFuncs[name] {
+		agg := &sAggregateExpr{fn: name}
+		if name == aggCount && p.acceptOp("*") {
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+		} else {
+			nAggs := len(p.aggregates)
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if len(p.aggregates) > nAggs {
+				return nil, errors.Wrap(ErrParse, "nested aggregate functions")
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			agg.arg = arg
+		}
+		p.aggregates = append(p.aggregates, agg)
+		return agg, nil
+	}
+	if name == "SUBSTRING" {
+		return p.parseSubstring()
+	}
+	args, err := p.parseExprList()
+	if err != nil {
+		return nil, err
+	}
+	return &sFuncExpr{name: name, args: args}, nil
+}
+
+// parseSubstring accepts both SUBSTRING(s, start[, len]) and
+// SUBSTRING(s FROM start [FOR len])
+func (p *sParser) parseSubstring() (iExpr, error) {
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	args := []iExpr{x}
+	if p.acceptKeyword("FROM") {
+		start, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, start)
+		if p.acceptKeyword("FOR") {
+			length, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, length)
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return &sFuncExpr{name: "SUBSTRING", args: args}, nil
+	}
+	if err := p.expectOp(","); err != nil {
+		return nil, err
+	}
+	rest, err := p.parseExprList()
+	if err != nil {
+		return nil, err
+	}
+	return &sFuncExpr{name: "SUBSTRING", args: append(args, rest...)}, nil
+}
+
+func (p *sParser) parseColumnRef() (iExpr, error) {
+	col := &sColumnRef{}
+	t := p.next()
+	col.path = append(col.path, sPathSeg{name: t.val, quoted: t.typ == tokenQuotedIdent})
+	for {
+		if p.acceptOp(".") {
+			t := p.next()
+			if t.typ != tokenIdent && t.typ != tokenQuotedIdent {
+				return nil, p.errorf("expect field name after .")
+			}
+			col.path = append(col.path, sPathSeg{name: t.val, quoted: t.typ == tokenQuotedIdent})
+		} else if p.acceptOp("[") {
+			t := p.next()
+			if t.typ != tokenNumber {
+				return nil, p.errorf("expect array index")
+			}
+			idx, err := strconv.Atoi(t.val)
+			if err != nil {
+				return nil, errors.Wrapf(ErrParse, "invalid array index %s", t.val)
+			}
+			if err := p.expectOp("]"); err != nil {
+				return nil, err
+			}
+			col.path = append(col.path, sPathSeg{index: idx, isIndex: true})
+		} else {
+			break
+		}
+	}
+	// strip the table name or alias qualifier
+	if len(col.path) > 1 && !col.path[0].isIndex {
+		head := col.path[0].name
+		if strings.EqualFold(head, tableName) || (len(p.alias) > 0 && strings.EqualFold(head, p.alias)) {
+			col.path = col.path[1:]
+		}
+	}
+	return col, nil
+}
+
+// isAggregateOnly reports whether x is computed from aggregates and
+// constants only, i.e. can be evaluated once the scan is finished
+func isAggregateOnly(x iExpr) bool {
+	switch e := x.(type) {
+	case *sAggregateExpr:
+		return true
+	case *sLiteral:
+		return true
+	case *sUnaryExpr:
+		return isAggregateOnly(e.x)
+	case *sBinaryExpr:
+		return isAggregateOnly(e.l) && isAggregateOnly(e.r)
+	case *sCastExpr:
+		return isAggregateOnly(e.x)
+	case *sFuncExpr:
+		for _, arg := range e.args {
+			if !isAggregateOnly(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
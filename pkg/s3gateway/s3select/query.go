@@ -0,0 +1,91 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+type sProjection struct {
+	expr iExpr
+	name string
+}
+
+// SQuery is a parsed S3 select expression
+type SQuery struct {
+	// empty projections means SELECT *
+	projections []sProjection
+	where       iExpr
+	limit       int64
+
+	aggregates []*sAggregateExpr
+}
+
+// IsAggregate reports whether the query yields a single aggregated record
+// instead of one record per matching input record.
+func (q *SQuery) IsAggregate() bool {
+	return len(q.aggregates) > 0
+}
+
+// Limit returns the maximal number of output records, -1 if unlimited.
+func (q *SQuery) Limit() int64 {
+	return q.limit
+}
+
+// Match reports whether the record satisfies the WHERE clause.
+func (q *SQuery) Match(rec *SRecord) (bool, error) {
+	if q.where == nil {
+		return true, nil
+	}
+	b, err := evalBool(q.where, rec)
+	if err != nil {
+		return false, err
+	}
+	return b != nil && *b, nil
+}
+
+// Project evaluates the projections of a non-aggregate query against a
+// matching record.
+func (q *SQuery) Project(rec *SRecord) (*SRecord, error) {
+	if len(q.projections) == 0 {
+		return rec, nil
+	}
+	return q.project(rec)
+}
+
+func (q *SQuery) project(rec *SRecord) (*SRecord, error) {
+	out := NewRecord()
+	for _, proj := range q.projections {
+		val, err := proj.expr.eval(rec)
+		if err != nil {
+			return nil, err
+		}
+		out.Keys = append(out.Keys, proj.name)
+		out.Values = append(out.Values, val)
+	}
+	return out, nil
+}
+
+// Accumulate feeds a matching record into the aggregate functions.
+func (q *SQuery) Accumulate(rec *SRecord) error {
+	for _, agg := range q.aggregates {
+		err := agg.accumulate(rec)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AggregateResult returns the single output record of an aggregate query.
+func (q *SQuery) AggregateResult() (*SRecord, error) {
+	return q.project(NewRecord())
+}
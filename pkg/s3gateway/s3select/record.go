@@ -0,0 +1,129 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SRecord is an ordered list of named values. A CSV row, a JSON object and
+// a projected output row are all represented as SRecord, nested JSON
+// objects are *SRecord values and JSON arrays are []interface{} values.
+type SRecord struct {
+	Keys   []string
+	Values []interface{}
+}
+
+func NewRecord() *SRecord {
+	return &SRecord{
+		Keys:   make([]string, 0),
+		Values: make([]interface{}, 0),
+	}
+}
+
+func (r *SRecord) Set(key string, val interface{}) {
+	for i := range r.Keys {
+		if r.Keys[i] == key {
+			r.Values[i] = val
+			return
+		}
+	}
+	r.Keys = append(r.Keys, key)
+	r.Values = append(r.Values, val)
+}
+
+func (r *SRecord) Get(key string, caseSensitive bool) (interface{}, bool) {
+	for i := range r.Keys {
+		if r.Keys[i] == key {
+			return r.Values[i], true
+		}
+	}
+	if !caseSensitive {
+		for i := range r.Keys {
+			if strings.EqualFold(r.Keys[i], key) {
+				return r.Values[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (r *SRecord) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := range r.Keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(r.Keys[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		val, err := json.Marshal(r.Values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// positionalName returns the column name S3 select uses for the idx-th
+// (0-based) column of a headerless CSV record.
+func positionalName(idx int) string {
+	return fmt.Sprintf("_%d", idx+1)
+}
+
+// parsePositional reports whether name is a positional column reference
+// such as _1 and returns its 0-based index.
+func parsePositional(name string) (int, bool) {
+	if len(name) < 2 || name[0] != '_' {
+		return -1, false
+	}
+	idx, err := strconv.Atoi(name[1:])
+	if err != nil || idx <= 0 {
+		return -1, false
+	}
+	return idx - 1, true
+}
+
+// valueToString formats a value the way it is emitted in CSV output.
+func valueToString(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		js, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(js)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/s3cli"
+)
+
+const (
+	// flush buffered records once the payload reaches this size
+	recordsPayloadSize = 128 * 1024
+	// send a Cont event when no message was sent for this long, so that
+	// clients and proxies do not time out on selective queries
+	keepAliveInterval = 10 * time.Second
+)
+
+// SSelector runs a SelectObjectContent request against an object stream
+type SSelector struct {
+	options *s3cli.SelectObjectOptions
+	query   *SQuery
+	writer  iRecordWriter
+
+	scanned   *sCountingReader
+	processed *sCountingReader
+	returned  int64
+}
+
+// NewSelector validates the request and parses its SQL expression, so
+// that malformed requests can be rejected before any response is sent.
+func NewSelector(options *s3cli.SelectObjectOptions) (*SSelector, error) {
+	if len(options.ExpressionType) > 0 && !strings.EqualFold(string(options.ExpressionType), string(s3cli.QueryExpressionTypeSQL)) {
+		return nil, errors.Wrapf(ErrInvalidInput, "unsupported expression type %s", options.ExpressionType)
+	}
+	err := validateInput(&options.InputSerialization)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := newRecordWriter(&options.OutputSerialization)
+	if err != nil {
+		return nil, err
+	}
+	query, err := ParseQuery(options.Expression)
+	if err != nil {
+		return nil, err
+	}
+	return &SSelector{
+		options: options,
+		query:   query,
+		writer:  writer,
+	}, nil
+}
+
+func (s *SSelector) stats() s3cli.StatsMessage {
+	stats := s3cli.StatsMessage{
+		BytesReturned: s.returned,
+	}
+	if s.scanned != nil {
+		stats.BytesScanned = s.scanned.count
+	}
+	if s.processed != nil {
+		stats.BytesProcessed = s.processed.count
+	}
+	return stats
+}
+
+func (s *SSelector) newRecordReader(input io.Reader) (iRecordReader, error) {
+	s.scanned = &sCountingReader{reader: input}
+	decompressed, err := decompressReader(s.options.InputSerialization.CompressionType, s.scanned)
+	if err != nil {
+		return nil, err
+	}
+	s.processed = &sCountingReader{reader: decompressed}
+	if s.options.InputSerialization.CSV != nil {
+		return newCSVReader(s.options.InputSerialization.CSV, s.processed)
+	}
+	return newJSONReader(s.processed), nil
+}
+
+// keepAlive writes Cont events while the stream stays idle for interval,
+// the returned function stops it
+func (s *SSelector) keepAlive(w *SEventStreamWriter, interval time.Duration) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if w.idleSince() < interval {
+					continue
+				}
+				if err := w.WriteCont(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// Run scans input and writes the matching records followed by the Stats
+// and End events. Errors are returned to the caller, which is expected to
+// report them with an error event as the response status is already sent.
+func (s *SSelector) Run(ctx context.Context, input io.Reader, w *SEventStreamWriter) error {
+	reader, err := s.newRecordReader(input)
+	if err != nil {
+		return err
+	}
+	stopKeepAlive := s.keepAlive(w, keepAliveInterval)
+	defer stopKeepAlive()
+
+	var buf bytes.Buffer
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		s.returned += int64(buf.Len())
+		err := w.WriteRecords(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		buf.Reset()
+		if s.options.RequestProgress.Enabled {
+			return w.WriteProgress(s.stats())
+		}
+		return nil
+	}
+	var count int64
+	limit := s.query.Limit()
+	for s.query.IsAggregate() || limit < 0 || count < limit {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "select canceled")
+		default:
+		}
+		rec, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		match, err := s.query.Match(rec)
+		if err != nil {
+			return err
+		}
+		if !match {
+			continue
+		}
+		count++
+		if s.query.IsAggregate() {
+			err = s.query.Accumulate(rec)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		out, err := s.query.Project(rec)
+		if err != nil {
+			return err
+		}
+		err = s.writer.write(&buf, out)
+		if err != nil {
+			return err
+		}
+		if buf.Len() >= recordsPayloadSize {
+			err = flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if s.query.IsAggregate() {
+		out, err := s.query.AggregateResult()
+		if err != nil {
+			return err
+		}
+		err = s.writer.write(&buf, out)
+		if err != nil {
+			return err
+		}
+	}
+	err = flush()
+	if err != nil {
+		return err
+	}
+	err = w.WriteStats(s.stats())
+	if err != nil {
+		return err
+	}
+	return w.WriteEnd()
+}
@@ -0,0 +1,219 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"yunion.io/x/s3cli"
+)
+
+type sTestEvent struct {
+	headers map[string]string
+	payload []byte
+}
+
+func decodeEvents(t *testing.T, data []byte) []sTestEvent {
+	events := make([]sTestEvent, 0)
+	for len(data) > 0 {
+		totalLen := binary.BigEndian.Uint32(data[0:4])
+		hdrLen := binary.BigEndian.Uint32(data[4:8])
+		if crc32.ChecksumIEEE(data[0:8]) != binary.BigEndian.Uint32(data[8:12]) {
+			t.Fatalf("prelude crc mismatch")
+		}
+		msg := data[:totalLen]
+		if crc32.ChecksumIEEE(msg[:totalLen-4]) != binary.BigEndian.Uint32(msg[totalLen-4:]) {
+			t.Fatalf("message crc mismatch")
+		}
+		event := sTestEvent{headers: map[string]string{}}
+		hdrs := msg[12 : 12+hdrLen]
+		for len(hdrs) > 0 {
+			nameLen := int(hdrs[0])
+			name := string(hdrs[1 : 1+nameLen])
+			hdrs = hdrs[1+nameLen:]
+			if hdrs[0] != headerValueTypeString {
+				t.Fatalf("unexpected header type %d", hdrs[0])
+			}
+			valLen := int(binary.BigEndian.Uint16(hdrs[1:3]))
+			event.headers[name] = string(hdrs[3 : 3+valLen])
+			hdrs = hdrs[3+valLen:]
+		}
+		event.payload = msg[12+hdrLen : totalLen-4]
+		events = append(events, event)
+		data = data[totalLen:]
+	}
+	return events
+}
+
+func runSelect(t *testing.T, opts *s3cli.SelectObjectOptions, input io.Reader) (string, []sTestEvent) {
+	selector, err := NewSelector(opts)
+	if err != nil {
+		t.Fatalf("NewSelector %q: %s", opts.Expression, err)
+	}
+	var out bytes.Buffer
+	err = selector.Run(context.Background(), input, NewEventStreamWriter(&out))
+	if err != nil {
+		t.Fatalf("Run %q: %s", opts.Expression, err)
+	}
+	events := decodeEvents(t, out.Bytes())
+	var records strings.Builder
+	for _, ev := range events {
+		if ev.headers[headerEventType] == eventRecords {
+			records.Write(ev.payload)
+		}
+	}
+	if len(events) < 2 || events[len(events)-1].headers[headerEventType] != eventEnd || events[len(events)-2].headers[headerEventType] != eventStats {
+		t.Fatalf("%q: stream not terminated by Stats and End", opts.Expression)
+	}
+	return records.String(), events
+}
+
+const testCSV = `name,age,city
+alice,30,beijing
+bob,25,"shanghai, pudong"
+carol,41,beijing
+dave,,hangzhou
+`
+
+func TestSelectCSV(t *testing.T) {
+	cases := []struct {
+		sql  string
+		want string
+	}{
+		{
+			sql:  "SELECT * FROM S3Object",
+			want: "alice,30,beijing\nbob,25,\"shanghai, pudong\"\ncarol,41,beijing\ndave,,hangzhou\n",
+		},
+		{
+			sql:  "SELECT s.name FROM S3Object s WHERE s.age > 28",
+			want: "alice\ncarol\n",
+		},
+		{
+			sql:  "SELECT name, CAST(age AS INT) + 1 FROM S3Object WHERE city = 'beijing' AND name LIKE 'c%'",
+			want: "carol,42\n",
+		},
+		{
+			sql:  "SELECT UPPER(name) FROM S3Object WHERE age IS NULL OR age = ''",
+			want: "DAVE\n",
+		},
+		{
+			sql:  "SELECT COUNT(*), SUM(age), MAX(age) FROM S3Object WHERE age <> ''",
+			want: "3,96,41\n",
+		},
+		{
+			sql:  "SELECT _1 FROM S3Object WHERE city IN ('hangzhou', 'shanghai') OR age BETWEEN 40 AND 50 LIMIT 1",
+			want: "carol\n",
+		},
+		{
+			sql:  "SELECT SUBSTRING(name, 2, 2), SUBSTRING(city, zz) FROM S3Object s WHERE s.name = 'bob'",
+			want: "ob,\n",
+		},
+	}
+	for _, c := range cases {
+		opts := &s3cli.SelectObjectOptions{
+			Expression: c.sql,
+			InputSerialization: s3cli.SelectObjectInputSerialization{
+				CSV: &s3cli.CSVInputOptions{FileHeaderInfo: s3cli.CSVFileHeaderInfoUse},
+			},
+			OutputSerialization: s3cli.SelectObjectOutputSerialization{
+				CSV: &s3cli.CSVOutputOptions{},
+			},
+		}
+		got, _ := runSelect(t, opts, strings.NewReader(testCSV))
+		if got != c.want {
+			t.Errorf("%q: want %q got %q", c.sql, c.want, got)
+		}
+	}
+}
+
+func TestSelectGzipJSONLines(t *testing.T) {
+	var input bytes.Buffer
+	gz := gzip.NewWriter(&input)
+	gz.Write([]byte(`{"level":"error","msg":"disk full","host":{"name":"h1"},"tags":["a","b"]}
+{"level":"info","msg":"ok","host":{"name":"h2"},"tags":[]}
+{"level":"error","msg":"oom","host":{"name":"h2"},"tags":["c"]}
+`))
+	gz.Close()
+
+	opts := &s3cli.SelectObjectOptions{
+		Expression: "SELECT s.host.name AS host, s.msg, s.tags[0] FROM S3Object[*] s WHERE s.level = 'error'",
+		InputSerialization: s3cli.SelectObjectInputSerialization{
+			CompressionType: s3cli.SelectCompressionGZIP,
+			JSON:            &s3cli.JSONInputOptions{Type: s3cli.JSONLinesType},
+		},
+		OutputSerialization: s3cli.SelectObjectOutputSerialization{
+			JSON: &s3cli.JSONOutputOptions{},
+		},
+		RequestProgress: struct{ Enabled bool }{Enabled: true},
+	}
+	got, events := runSelect(t, opts, &input)
+	want := `{"host":"h1","msg":"disk full","_3":"a"}` + "\n" + `{"host":"h2","msg":"oom","_3":"c"}` + "\n"
+	if got != want {
+		t.Errorf("want %q got %q", want, got)
+	}
+	hasProgress := false
+	for _, ev := range events {
+		if ev.headers[headerEventType] == eventProgress {
+			hasProgress = true
+			if !bytes.HasPrefix(ev.payload, []byte("<Progress>")) {
+				t.Errorf("invalid progress payload %s", ev.payload)
+			}
+		}
+	}
+	if !hasProgress {
+		t.Errorf("missing progress event")
+	}
+}
+
+func TestParseQueryError(t *testing.T) {
+	for _, sql := range []string{
+		"SELECT",
+		"SELECT * FROM table",
+		"SELECT a FROM S3Object WHERE",
+		"SELECT a, COUNT(*) FROM S3Object",
+		"SELECT a FROM S3Object WHERE COUNT(*) > 1",
+		"SELECT 'abc FROM S3Object",
+	} {
+		_, err := ParseQuery(sql)
+		if err == nil {
+			t.Errorf("%q: expect parse error", sql)
+		}
+	}
+}
+
+func TestSelectKeepAlive(t *testing.T) {
+	var out bytes.Buffer
+	w := NewEventStreamWriter(&out)
+	stop := (&SSelector{}).keepAlive(w, 20*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	stop()
+	events := decodeEvents(t, out.Bytes())
+	if len(events) == 0 {
+		t.Fatalf("missing keep-alive event")
+	}
+	for _, ev := range events {
+		if ev.headers[headerEventType] != eventCont {
+			t.Errorf("unexpected event %s", ev.headers[headerEventType])
+		}
+	}
+}
@@ -76,7 +76,9 @@ type SScheduledTask struct {
 
 	ResourceType string `width:"32" charset:"ascii" create:"required" list:"user" get:"user"`
 	Operation    string `width:"32" charset:"ascii" create:"required" list:"user" get:"user"`
-	LabelType    string `width:"4" charset:"ascii" create:"required" list:"user" get:"user"`
+	// OperationParams is the marshaled api.ScheduledTaskOperationParams
+	OperationParams jsonutils.JSONObject `nullable:"true" create:"optional" list:"user" get:"user"`
+	LabelType       string               `width:"4" charset:"ascii" create:"required" list:"user" get:"user"`
 }
 
 func (stm *SScheduledTaskManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, input api.ScheduledTaskListInput) (*sqlchemy.SQuery, error) {
@@ -166,15 +168,26 @@ func (stm *SScheduledTaskManager) ValidateCreateData(ctx context.Context, userCr
 	if !utils.IsInStringArray(input.ScheduledType, []string{api.ST_TYPE_TIMING, api.ST_TYPE_CYCLE}) {
 		return input, httperrors.NewInputParameterError("unkown scheduled type '%s'", input.ScheduledType)
 	}
-	if !utils.IsInStringArray(input.ResourceType, []string{api.ST_RESOURCE_SERVER, api.ST_RESOURCE_CLOUDACCOUNT}) {
+	if _, ok := Modules[Resource(input.ResourceType)]; !ok {
 		return input, httperrors.NewInputParameterError("unkown resource type '%s'", input.ResourceType)
 	}
-	if !utils.IsInStringArray(input.Operation, []string{api.ST_RESOURCE_OPERATION_RESTART, api.ST_RESOURCE_OPERATION_STOP, api.ST_RESOURCE_OPERATION_START, api.ST_RESOURCE_OPERATION_SYNC}) {
-		return input, httperrors.NewInputParameterError("unkown resource operation '%s'", input.Operation)
+	oper, ok := ResourceOperationMap[fmt.Sprintf("%s.%s", input.ResourceType, input.Operation)]
+	if !ok {
+		return input, httperrors.NewInputParameterError("unkown resource operation '%s' for %s", input.Operation, input.ResourceType)
+	}
+	if oper.Validate != nil {
+		err = oper.Validate(&input.OperationParams)
+		if err != nil {
+			return input, httperrors.NewInputParameterError("invalid operation params: %v", err)
+		}
 	}
 	if !utils.IsInStringArray(input.LabelType, []string{api.ST_LABEL_ID, api.ST_LABEL_TAG}) {
 		return input, httperrors.NewInputParameterError("unkown label type '%s'", input.LabelType)
 	}
+	err = oper.validateLabels(ctx, userCred, input.LabelType, input.Labels)
+	if err != nil {
+		return input, err
+	}
 	// check timer or cycletimer
 	if input.ScheduledType == api.ST_TYPE_TIMING {
 		input.Timer, err = checkTimerCreateInput(input.Timer)
@@ -244,6 +257,11 @@ func (st *SScheduledTask) PostCreate(ctx context.Context, userCred mcclient.Toke
 			StartTime: input.CycleTimer.StartTime,
 			EndTime:   input.CycleTimer.EndTime,
 			NextTime:  time.Time{},
+
+			CronExpr:        input.CycleTimer.CronExpr,
+			TimeZone:        input.CycleTimer.TimeZone,
+			JitterSeconds:   input.CycleTimer.JitterSeconds,
+			MissedRunPolicy: input.CycleTimer.MissedRunPolicy,
 		}
 		st.SetWeekDays(input.CycleTimer.WeekDays)
 		st.SetMonthDays(input.CycleTimer.MonthDays)
@@ -304,6 +322,10 @@ func (st *SScheduledTask) STLabels() ([]SScheduledTaskLabel, error) {
 }
 
 func (st *SScheduledTask) PerformSetLabels(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ScheduledTaskSetLabelsInput) (jsonutils.JSONObject, error) {
+	err := st.ResourceOperation().validateLabels(ctx, userCred, st.LabelType, input.Labels)
+	if err != nil {
+		return nil, err
+	}
 	nowLabels, err := st.STLabels()
 	if err != nil {
 		return nil, err
@@ -343,7 +365,15 @@ func (st *SScheduledTask) PerformSetLabels(ctx context.Context, userCred mcclien
 }
 
 func (st *SScheduledTask) PerformTrigger(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ScheduledTaskTriggerInput) (jsonutils.JSONObject, error) {
+	ok, err := st.acquireExecution()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	if !ok {
+		return nil, httperrors.NewConflictError("scheduled task %s is still running", st.Name)
+	}
 	go func() {
+		defer st.releaseExecution()
 		log.Infof("start to execute scheduled task '%s'", st.Id)
 		err := st.Execute(ctx, userCred)
 		if err != nil {
@@ -373,9 +403,17 @@ func (st *SScheduledTask) CustomizeDelete(ctx context.Context, userCred mcclient
 	return nil
 }
 
+func (st *SScheduledTask) GetOperationParams() api.ScheduledTaskOperationParams {
+	params := api.ScheduledTaskOperationParams{}
+	if st.OperationParams != nil {
+		st.OperationParams.Unmarshal(&params)
+	}
+	return params
+}
+
 func (st *SScheduledTask) Action(ctx context.Context, userCred mcclient.TokenCredential) SAction {
 	session := auth.GetSession(ctx, userCred, "", "")
-	return Action.ResourceOperation(st.ResourceOperation()).Session(session)
+	return Action.ResourceOperation(st.ResourceOperation()).Session(session).OperationParams(st.GetOperationParams())
 }
 
 func (st *SScheduledTask) ExecuteNotify(ctx context.Context, userCred mcclient.TokenCredential, name string) {
@@ -473,6 +511,19 @@ func (st *SScheduledTask) Execute(ctx context.Context, userCred mcclient.TokenCr
 	failedReasons := make([]string, 0, 1)
 	succeedIds := make([]string, 0, 1)
 	displayStrs := res
+	activityResults := make([]api.ScheduledTaskActivityResult, len(results))
+	for i, ret := range results {
+		activityResults[i] = api.ScheduledTaskActivityResult{
+			Id:      ret.id,
+			Name:    displayStrs[ret.id],
+			Succeed: ret.succeed,
+			Reason:  ret.reason,
+		}
+	}
+	err = sa.SetResults(activityResults)
+	if err != nil {
+		log.Errorf("unable to save results of scheduled task activity %s: %v", sa.Id, err)
+	}
 	for _, ret := range results {
 		if ret.succeed {
 			succeedIds = append(succeedIds, displayStrs[ret.id])
@@ -535,11 +586,61 @@ func (stm *SScheduledTaskManager) timeScope(median time.Time, interval time.Dura
 
 var timerQueue chan struct{}
 
+// the execution lease of a scheduled task, the task left executing longer,
+// e.g. by a crashed replica, is ready to execute again
+const scheduledTaskExecutionLease = 6 * time.Hour
+
+// acquireExecution marks the ready task executing in database, so that the
+// task executed by any replica is not executed again until released
+func (st *SScheduledTask) acquireExecution() (bool, error) {
+	result, err := sqlchemy.GetDB().Exec(
+		fmt.Sprintf(
+			"update %s set status = ?, updated_at = ? where id = ? and status = ?",
+			ScheduledTaskManager.TableSpec().Name(),
+		), api.ST_STATUS_EXECUTING, time.Now().UTC(), st.Id, api.ST_STATUS_READY,
+	)
+	if err != nil {
+		return false, errors.Wrap(err, "mark scheduled task executing")
+	}
+	cnt, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "RowsAffected")
+	}
+	return cnt == 1, nil
+}
+
+func (st *SScheduledTask) releaseExecution() {
+	_, err := sqlchemy.GetDB().Exec(
+		fmt.Sprintf(
+			"update %s set status = ? where id = ? and status = ?",
+			ScheduledTaskManager.TableSpec().Name(),
+		), api.ST_STATUS_READY, st.Id, api.ST_STATUS_EXECUTING,
+	)
+	if err != nil {
+		log.Errorf("release execution of scheduled task %s: %v", st.Id, err)
+	}
+}
+
+// releaseExpiredExecutions makes the tasks whose execution lease expires
+// ready again
+func (stm *SScheduledTaskManager) releaseExpiredExecutions() {
+	_, err := sqlchemy.GetDB().Exec(
+		fmt.Sprintf(
+			"update %s set status = ? where status = ? and updated_at < ?",
+			stm.TableSpec().Name(),
+		), api.ST_STATUS_READY, api.ST_STATUS_EXECUTING, time.Now().UTC().Add(-scheduledTaskExecutionLease),
+	)
+	if err != nil {
+		log.Errorf("release expired scheduled task executions: %v", err)
+	}
+}
+
 func (stm *SScheduledTaskManager) Timer(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
 	if timerQueue == nil {
 		timerQueue = make(chan struct{}, sop.Options.ScheduledTaskQueueSize)
 	}
 	log.Infof("queueSize: %d", sop.Options.ScheduledTaskQueueSize)
+	stm.releaseExpiredExecutions()
 	// 60 is for fault tolerance
 	interval := 60 + 30
 	timeScope := stm.timeScope(time.Now(), time.Duration(interval)*time.Second)
@@ -562,7 +663,17 @@ func (stm *SScheduledTaskManager) Timer(ctx context.Context, userCred mcclient.T
 				<-timerQueue
 				<-waitQueue
 			}()
-			if st.NextTime.Before(timeScope.Start) {
+			ok, err := st.acquireExecution()
+			if err != nil {
+				log.Errorf("unable to acquire execution of scheduled task '%s': %v", st.Id, err)
+				return
+			}
+			if !ok {
+				log.Warningf("scheduled task '%s' is executed by others, skip", st.Id)
+				return
+			}
+			defer st.releaseExecution()
+			if st.NextTime.Before(timeScope.Start) && st.MissedRunPolicy != api.TIMER_MISSED_RUN_CATCH_UP_ONCE {
 				// For unknown reasons, the scalingTimer did not execute at the specified time
 				st.Update(timeScope.Start)
 				// scalingTimer should not exec for now.
@@ -574,7 +685,7 @@ func (stm *SScheduledTaskManager) Timer(ctx context.Context, userCred mcclient.T
 					return
 				}
 			}
-			err := st.Execute(ctx, userCred)
+			err = st.Execute(ctx, userCred)
 			if err != nil {
 				log.Errorf("unable to execute scheduled task '%s'", st.Id)
 			}
@@ -594,6 +705,10 @@ func (stm *SScheduledTaskManager) Timer(ctx context.Context, userCred mcclient.T
 func init() {
 	Register(ResourceServer, compute.Servers.ResourceManager)
 	Register(ResourceCloudAccount, compute.Cloudaccounts)
+	Register(ResourceDisk, compute.Disks)
+	Register(ResourceScalingGroup, compute.ScalingGroup)
+	Register(ResourceDBInstance, compute.DBInstance)
+	Register(ResourceElasticcache, compute.ElasticCache.ResourceManager)
 }
 
 // Modules describe the correspondence between Resource and modulebase.ResourceManager,
@@ -611,6 +726,10 @@ type Resource string
 const (
 	ResourceServer       Resource = api.ST_RESOURCE_SERVER
 	ResourceCloudAccount Resource = api.ST_RESOURCE_CLOUDACCOUNT
+	ResourceDisk         Resource = api.ST_RESOURCE_DISK
+	ResourceScalingGroup Resource = api.ST_RESOURCE_SCALINGGROUP
+	ResourceDBInstance   Resource = api.ST_RESOURCE_DBINSTANCE
+	ResourceElasticcache Resource = api.ST_RESOURCE_ELASTICCACHE
 )
 
 // ResourceOperation describe the operation for onecloud resource like create, update, delete and so on.
@@ -620,8 +739,70 @@ type ResourceOperation struct {
 	StatusSuccess []string
 	Fail          []ResourceOperationFail
 	Params        *jsonutils.JSONDict
+
+	// Validate checks and normalizes the operation params when the scheduled task is created
+	Validate func(params *api.ScheduledTaskOperationParams) error
+	// Request sends the request for the resource instead of performing the operation on it,
+	// it returns the id of the object created by the request if StatusManager is set
+	Request func(session *mcclient.ClientSession, id string, params api.ScheduledTaskOperationParams) (string, error)
+	// StatusManager is the manager of the object created by Request, the status of
+	// the created object instead of the resource is waited for
+	StatusManager IResourceClient
+	// Timeout overrides the timeout of waiting for the status if it is not zero
+	Timeout time.Duration
+	// Providers are the providers whose resources support the operation,
+	// the resources of all providers are supported if it is nil
+	Providers []string
+}
+
+// IResourceClient is the part of modulebase.Manager used to apply the operation
+type IResourceClient interface {
+	Get(session *mcclient.ClientSession, id string, params jsonutils.JSONObject) (jsonutils.JSONObject, error)
+	GetSpecific(session *mcclient.ClientSession, id string, spec string, params jsonutils.JSONObject) (jsonutils.JSONObject, error)
+	PerformAction(session *mcclient.ClientSession, id string, action string, params jsonutils.JSONObject) (jsonutils.JSONObject, error)
 }
 
+func (oper ResourceOperation) checkProvider(provider string) error {
+	if oper.Providers == nil || utils.IsInStringArray(provider, oper.Providers) {
+		return nil
+	}
+	return errors.Errorf("operation %s of %s is not supported by provider %q", oper.Operation, oper.Resource, provider)
+}
+
+// validateLabels rejects the operation if the providers of resources with
+// the labels don't support it, resources with tag labels are checked
+// when the operation is applied
+func (oper ResourceOperation) validateLabels(ctx context.Context, userCred mcclient.TokenCredential, labelType string, labels []string) error {
+	if oper.Providers == nil {
+		return nil
+	}
+	if len(oper.Providers) == 0 {
+		return httperrors.NewNotSupportedError("operation %s of %s is not supported by any provider", oper.Operation, oper.Resource)
+	}
+	if labelType != api.ST_LABEL_ID {
+		return nil
+	}
+	resourceManager, ok := Modules[oper.Resource]
+	if !ok {
+		return httperrors.NewInputParameterError("unkown resource type '%s'", oper.Resource)
+	}
+	session := auth.GetSession(ctx, userCred, "", "")
+	for _, label := range labels {
+		obj, err := resourceManager.Get(session, label, nil)
+		if err != nil {
+			return httperrors.NewInputParameterError("unable to fetch %s %s: %v", oper.Resource, label, err)
+		}
+		provider, _ := obj.GetString("provider")
+		if err := oper.checkProvider(provider); err != nil {
+			return httperrors.NewNotSupportedError("%s %s: %v", oper.Resource, label, err)
+		}
+	}
+	return nil
+}
+
+// ResourceOperationFail describes a failed status of the resource. The reason
+// is fetched from the opslog whose action is LogEvent, if LogEvent is empty,
+// the status itself is used as the reason.
 type ResourceOperationFail struct {
 	Status   string
 	LogEvent string
@@ -662,40 +843,221 @@ func init() {
 		Operation: api.ST_RESOURCE_OPERATION_SYNC,
 		Params:    paramsAccoutSync,
 	}
-	ResourceOperationMap = map[string]ResourceOperation{
-		fmt.Sprintf("%s.%s", ResourceServer, api.ST_RESOURCE_OPERATION_START):      ServerStart,
-		fmt.Sprintf("%s.%s", ResourceServer, api.ST_RESOURCE_OPERATION_STOP):       ServerStop,
-		fmt.Sprintf("%s.%s", ResourceServer, api.ST_RESOURCE_OPERATION_RESTART):    ServerRestart,
-		fmt.Sprintf("%s.%s", ResourceCloudAccount, api.ST_RESOURCE_OPERATION_SYNC): CloudAccountSync,
+	ServerInstanceSnapshot = ResourceOperation{
+		Resource:      ResourceServer,
+		Operation:     api.ST_RESOURCE_OPERATION_INSTANCE_SNAPSHOT,
+		StatusSuccess: []string{comapi.INSTANCE_SNAPSHOT_READY},
+		Fail: []ResourceOperationFail{
+			{comapi.INSTANCE_SNAPSHOT_FAILED, ""},
+		},
+		Request: func(session *mcclient.ClientSession, id string, params api.ScheduledTaskOperationParams) (string, error) {
+			input := comapi.ServerInstanceSnapshot{WithMemory: params.WithMemory}
+			input.GenerateName = snapshotName(params.NamePrefix)
+			ret, err := compute.Servers.PerformAction(session, id, "instance-snapshot", jsonutils.Marshal(input))
+			if err != nil {
+				return "", err
+			}
+			return ret.GetString("instance_snapshot_id")
+		},
+		StatusManager: &compute.InstanceSnapshots,
+		Timeout:       30 * time.Minute,
+	}
+	ServerChangeConfig = ResourceOperation{
+		Resource:      ResourceServer,
+		Operation:     api.ST_RESOURCE_OPERATION_CHANGE_CONFIG,
+		StatusSuccess: []string{comapi.VM_READY, comapi.VM_RUNNING},
+		Fail: []ResourceOperationFail{
+			{comapi.VM_CHANGE_FLAVOR_FAIL, db.ACT_CHANGE_FLAVOR_FAIL},
+		},
+		Validate: func(params *api.ScheduledTaskOperationParams) error {
+			if len(params.InstanceType) == 0 && params.VcpuCount <= 0 && len(params.VmemSize) == 0 {
+				return errors.Error("one of instance_type, vcpu_count and vmem_size is required")
+			}
+			return nil
+		},
+		Request: func(session *mcclient.ClientSession, id string, params api.ScheduledTaskOperationParams) (string, error) {
+			input := comapi.ServerChangeConfigInput{
+				InstanceType: params.InstanceType,
+				VcpuCount:    params.VcpuCount,
+				VmemSize:     params.VmemSize,
+				AutoStart:    params.AutoStart,
+			}
+			_, err := compute.Servers.PerformAction(session, id, "change-config", jsonutils.Marshal(input))
+			return "", err
+		},
+	}
+	DiskSnapshot = ResourceOperation{
+		Resource:      ResourceDisk,
+		Operation:     api.ST_RESOURCE_OPERATION_SNAPSHOT,
+		StatusSuccess: []string{comapi.SNAPSHOT_READY},
+		Fail: []ResourceOperationFail{
+			{comapi.SNAPSHOT_FAILED, ""},
+		},
+		Request: func(session *mcclient.ClientSession, id string, params api.ScheduledTaskOperationParams) (string, error) {
+			input := jsonutils.NewDict()
+			input.Add(jsonutils.NewString(id), "disk_id")
+			input.Add(jsonutils.NewString(snapshotName(params.NamePrefix)), "generate_name")
+			ret, err := compute.Snapshots.Create(session, input)
+			if err != nil {
+				return "", err
+			}
+			return ret.GetString("id")
+		},
+		StatusManager: &compute.Snapshots,
+		Timeout:       30 * time.Minute,
+	}
+	DiskBackup = ResourceOperation{
+		Resource:      ResourceDisk,
+		Operation:     api.ST_RESOURCE_OPERATION_BACKUP,
+		StatusSuccess: []string{comapi.BACKUP_STATUS_READY},
+		Fail: []ResourceOperationFail{
+			{comapi.BACKUP_STATUS_CREATE_FAILED, ""},
+			{comapi.BACKUP_STATUS_SNAPSHOT_FAILED, ""},
+			{comapi.BACKUP_STATUS_SAVE_FAILED, ""},
+			{comapi.BACKUP_STATUS_CLEANUP_SNAPSHOT_FAILED, ""},
+		},
+		Validate: func(params *api.ScheduledTaskOperationParams) error {
+			if len(params.BackupStorageId) == 0 {
+				return errors.Error("backup_storage_id is required")
+			}
+			return nil
+		},
+		Request: func(session *mcclient.ClientSession, id string, params api.ScheduledTaskOperationParams) (string, error) {
+			input := comapi.DiskBackupCreateInput{
+				DiskId:          id,
+				BackupStorageId: params.BackupStorageId,
+				Incremental:     params.Incremental,
+			}
+			input.GenerateName = snapshotName(params.NamePrefix)
+			ret, err := compute.DiskBackups.Create(session, jsonutils.Marshal(input))
+			if err != nil {
+				return "", err
+			}
+			return ret.GetString("id")
+		},
+		StatusManager: &compute.DiskBackups,
+		Timeout:       2 * time.Hour,
+	}
+	ScalingGroupSetDesiredCapacity = ResourceOperation{
+		Resource:  ResourceScalingGroup,
+		Operation: api.ST_RESOURCE_OPERATION_SET_DESIRED_CAPACITY,
+		Validate: func(params *api.ScheduledTaskOperationParams) error {
+			if params.DesireInstanceNumber < 0 {
+				return errors.Error("desire_instance_number should not be negative")
+			}
+			return nil
+		},
+		Request: func(session *mcclient.ClientSession, id string, params api.ScheduledTaskOperationParams) (string, error) {
+			input := jsonutils.NewDict()
+			input.Add(jsonutils.NewInt(int64(params.DesireInstanceNumber)), "desire_instance_number")
+			_, err := compute.ScalingGroup.Update(session, id, input)
+			return "", err
+		},
+	}
+	DBInstanceStart = ResourceOperation{
+		Resource:      ResourceDBInstance,
+		Operation:     api.ST_RESOURCE_OPERATION_START,
+		StatusSuccess: []string{comapi.DBINSTANCE_RUNNING},
+		Fail: []ResourceOperationFail{
+			{comapi.DBINSTANCE_START_FAILED, db.ACT_START_FAIL},
+		},
+		Providers: comapi.DBINSTANCE_START_STOP_PROVIDERS,
+	}
+	DBInstanceStop = ResourceOperation{
+		Resource:      ResourceDBInstance,
+		Operation:     api.ST_RESOURCE_OPERATION_STOP,
+		StatusSuccess: []string{comapi.DBINSTANCE_STOPPED},
+		Fail: []ResourceOperationFail{
+			{comapi.DBINSTANCE_STOP_FAILED, db.ACT_STOP_FAIL},
+		},
+		Providers: comapi.DBINSTANCE_START_STOP_PROVIDERS,
+	}
+	ElasticcacheStart = ResourceOperation{
+		Resource:      ResourceElasticcache,
+		Operation:     api.ST_RESOURCE_OPERATION_START,
+		StatusSuccess: []string{comapi.ELASTIC_CACHE_STATUS_RUNNING},
+		Fail: []ResourceOperationFail{
+			{comapi.ELASTIC_CACHE_STATUS_START_FAILED, db.ACT_START_FAIL},
+		},
+		Providers: comapi.ELASTIC_CACHE_START_STOP_PROVIDERS,
+	}
+	ElasticcacheStop = ResourceOperation{
+		Resource:      ResourceElasticcache,
+		Operation:     api.ST_RESOURCE_OPERATION_STOP,
+		StatusSuccess: []string{comapi.ELASTIC_CACHE_STATUS_STOPPED},
+		Fail: []ResourceOperationFail{
+			{comapi.ELASTIC_CACHE_STATUS_STOP_FAILED, db.ACT_STOP_FAIL},
+		},
+		Providers: comapi.ELASTIC_CACHE_START_STOP_PROVIDERS,
+	}
+	ResourceOperationMap = map[string]ResourceOperation{}
+	for _, oper := range []ResourceOperation{
+		ServerStart, ServerStop, ServerRestart, ServerInstanceSnapshot, ServerChangeConfig,
+		CloudAccountSync,
+		DiskSnapshot, DiskBackup,
+		ScalingGroupSetDesiredCapacity,
+		DBInstanceStart, DBInstanceStop,
+		ElasticcacheStart, ElasticcacheStop,
+	} {
+		ResourceOperationMap[fmt.Sprintf("%s.%s", oper.Resource, oper.Operation)] = oper
 	}
 }
 
+// snapshotName returns the generate name of the snapshot or backup created by scheduled task
+func snapshotName(prefix string) string {
+	if len(prefix) == 0 {
+		prefix = "scheduled"
+	}
+	return fmt.Sprintf("%s-%s", prefix, time.Now().Format("20060102150405"))
+}
+
 var (
-	ServerStart          ResourceOperation
-	ServerStop           ResourceOperation
-	ServerRestart        ResourceOperation
-	CloudAccountSync     ResourceOperation
-	ResourceOperationMap map[string]ResourceOperation
+	ServerStart                    ResourceOperation
+	ServerStop                     ResourceOperation
+	ServerRestart                  ResourceOperation
+	ServerInstanceSnapshot         ResourceOperation
+	ServerChangeConfig             ResourceOperation
+	CloudAccountSync               ResourceOperation
+	DiskSnapshot                   ResourceOperation
+	DiskBackup                     ResourceOperation
+	ScalingGroupSetDesiredCapacity ResourceOperation
+	DBInstanceStart                ResourceOperation
+	DBInstanceStop                 ResourceOperation
+	ElasticcacheStart              ResourceOperation
+	ElasticcacheStop               ResourceOperation
+	ResourceOperationMap           map[string]ResourceOperation
 )
 
 // Action itself is meaningless, a meaningful Action is generated by
 // calling Resource, Operation, Session and DefaultParams.
 // A example:
 // 	Action.ResourceOperation(ServerStart).Session(...).Apply(...)
-var Action = SAction{timeout: 5 * time.Minute}
+var Action = SAction{timeout: 5 * time.Minute, interval: 10 * time.Second}
 
 // SAction encapsulates action to for onecloud resources
 type SAction struct {
 	operation ResourceOperation
+	params    api.ScheduledTaskOperationParams
 	session   *mcclient.ClientSession
 	timeout   time.Duration
+	interval  time.Duration
 }
 
+// opsLogs lists the opslogs to find the reason of a failed operation
+var opsLogs interface {
+	List(session *mcclient.ClientSession, params jsonutils.JSONObject) (*modulebase.ListResult, error)
+} = &compute.Logs
+
 func (r SAction) ResourceOperation(oper ResourceOperation) SAction {
 	r.operation = oper
 	return r
 }
 
+func (r SAction) OperationParams(params api.ScheduledTaskOperationParams) SAction {
+	r.params = params
+	return r
+}
+
 func (r SAction) Session(session *mcclient.ClientSession) SAction {
 	r.session = session
 	return r
@@ -706,6 +1068,11 @@ func (r SAction) Timeout(time time.Duration) SAction {
 	return r
 }
 
+func (r SAction) Interval(interval time.Duration) SAction {
+	r.interval = interval
+	return r
+}
+
 type WrapperListOptions struct {
 	options.BaseListOptions
 }
@@ -733,75 +1100,118 @@ func (r SAction) List(opts *WrapperListOptions) (map[string]string, error) {
 }
 
 func (r SAction) Apply(id string) (success bool, failReason string) {
-	success = true
 	resourceManager, ok := Modules[r.operation.Resource]
 	if !ok {
 		return false, fmt.Sprintf("no such resource '%s' in Modules", r.operation.Resource)
 	}
-	var requestFunc func(session *mcclient.ClientSession, id string, params *jsonutils.JSONDict) error
+	return r.apply(&resourceManager, id)
+}
 
-	action := utils.CamelSplit(r.operation.Operation, "-")
-	requestFunc = func(session *mcclient.ClientSession, id string, params *jsonutils.JSONDict) error {
+func (r SAction) apply(manager IResourceClient, id string) (success bool, failReason string) {
+	if r.operation.Providers != nil {
+		obj, err := manager.Get(r.session, id, nil)
+		if err != nil {
+			return false, errorReason(err)
+		}
+		provider, _ := obj.GetString("provider")
+		if err := r.operation.checkProvider(provider); err != nil {
+			return false, err.Error()
+		}
+	}
+	statusManager, statusId := manager, id
+	if r.operation.Request != nil {
+		createdId, err := r.operation.Request(r.session, id, r.params)
+		if err != nil {
+			return false, errorReason(err)
+		}
+		if r.operation.StatusManager != nil {
+			if len(createdId) == 0 {
+				return false, fmt.Sprintf("no object created by %s of %s %s", r.operation.Operation, r.operation.Resource, id)
+			}
+			statusManager, statusId = r.operation.StatusManager, createdId
+		}
+	} else {
+		params := r.operation.Params
 		if params == nil {
 			params = jsonutils.NewDict()
 		}
-		_, err := resourceManager.PerformAction(session, id, action, params)
-		return err
-	}
-	err := requestFunc(r.session, id, r.operation.Params)
-	if err != nil {
-		clientErr, _ := err.(*httputils.JSONClientError)
-		return false, clientErr.Details
+		_, err := manager.PerformAction(r.session, id, utils.CamelSplit(r.operation.Operation, "-"), params)
+		if err != nil {
+			return false, errorReason(err)
+		}
 	}
 	if len(r.operation.StatusSuccess) == 0 {
 		return true, ""
 	}
-	// wait for status
-	timer := time.NewTimer(r.timeout)
-	ticker := time.NewTicker(10 * time.Second)
+	return r.waitStatus(statusManager, statusId)
+}
+
+func errorReason(err error) string {
+	if clientErr, ok := err.(*httputils.JSONClientError); ok {
+		return clientErr.Details
+	}
+	return err.Error()
+}
+
+// waitStatus waits for the object to turn into one of the success or fail
+// status of the operation
+func (r SAction) waitStatus(manager IResourceClient, id string) (success bool, failReason string) {
+	timeout := r.timeout
+	if r.operation.Timeout > 0 {
+		timeout = r.operation.Timeout
+	}
+	timer := time.NewTimer(timeout)
+	ticker := time.NewTicker(r.interval)
 	defer func() {
 		ticker.Stop()
 		timer.Stop()
 	}()
 	for {
+		done, success, failReason := r.checkStatus(manager, id)
+		if done {
+			return success, failReason
+		}
 		select {
-		default:
-			ret, e := resourceManager.GetSpecific(r.session, id, "status", nil)
-			if e != nil {
-				log.Errorf("fail to exec resouce(%s.%s).GetStatus: %s", r.operation.Resource, id, e.Error())
-				<-ticker.C
-				continue
-			}
-			status, _ := ret.GetString("status")
-			if utils.IsInStringArray(status, r.operation.StatusSuccess) {
-				return
-			}
-			for _, fail := range r.operation.Fail {
-				if status != fail.Status {
-					continue
-				}
-				params := jsonutils.NewDict()
-				params.Add(jsonutils.NewString(id), "obj_id")
-				params.Add(jsonutils.NewStringArray([]string{fail.LogEvent}), "action")
-				params.Add(jsonutils.NewInt(1), "limit")
-				events, err := compute.Logs.List(r.session, params)
-				if err != nil {
-					log.Errorf("Logs.List failed: %s", err.Error())
-					<-ticker.C
-					continue
-				}
-				if len(events.Data) == 0 {
-					log.Errorf("These is no opslog about action '%s' for %s.%s: %s", fail.LogEvent, r.operation.Resource, id, err.Error())
-					<-ticker.C
-					continue
-				}
-				reason, _ := events.Data[0].GetString("notes")
-				return false, reason
-			}
-			<-ticker.C
+		case <-ticker.C:
 		case <-timer.C:
-			log.Errorf("timeout(%s) to exec resource(%s.%s).%s", r.timeout.String(), r.operation.Resource, id, r.operation.Operation)
+			log.Errorf("timeout(%s) to exec resource(%s.%s).%s", timeout.String(), r.operation.Resource, id, r.operation.Operation)
 			return false, "timeout"
 		}
 	}
 }
+
+func (r SAction) checkStatus(manager IResourceClient, id string) (done bool, success bool, failReason string) {
+	ret, err := manager.GetSpecific(r.session, id, "status", nil)
+	if err != nil {
+		log.Errorf("fail to exec resouce(%s.%s).GetStatus: %s", r.operation.Resource, id, err.Error())
+		return false, false, ""
+	}
+	status, _ := ret.GetString("status")
+	if utils.IsInStringArray(status, r.operation.StatusSuccess) {
+		return true, true, ""
+	}
+	for _, fail := range r.operation.Fail {
+		if status != fail.Status {
+			continue
+		}
+		if len(fail.LogEvent) == 0 {
+			return true, false, status
+		}
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(id), "obj_id")
+		params.Add(jsonutils.NewStringArray([]string{fail.LogEvent}), "action")
+		params.Add(jsonutils.NewInt(1), "limit")
+		events, err := opsLogs.List(r.session, params)
+		if err != nil {
+			log.Errorf("Logs.List failed: %s", err.Error())
+			return false, false, ""
+		}
+		if len(events.Data) == 0 {
+			log.Errorf("These is no opslog about action '%s' for %s.%s", fail.LogEvent, r.operation.Resource, id)
+			return false, false, ""
+		}
+		reason, _ := events.Data[0].GetString("notes")
+		return true, false, reason
+	}
+	return false, false, ""
+}
@@ -0,0 +1,185 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	"yunion.io/x/jsonutils"
+
+	comapi "yunion.io/x/onecloud/pkg/apis/compute"
+	api "yunion.io/x/onecloud/pkg/apis/scheduledtask"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+)
+
+type fakeResourceClient struct {
+	provider string
+	// statuses returned by successive status requests, the last one is kept
+	statuses  []string
+	statusIds []string
+	performed []string
+}
+
+func (c *fakeResourceClient) Get(session *mcclient.ClientSession, id string, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	ret := jsonutils.NewDict()
+	ret.Set("id", jsonutils.NewString(id))
+	ret.Set("provider", jsonutils.NewString(c.provider))
+	return ret, nil
+}
+
+func (c *fakeResourceClient) GetSpecific(session *mcclient.ClientSession, id string, spec string, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	c.statusIds = append(c.statusIds, id)
+	status := c.statuses[0]
+	if len(c.statuses) > 1 {
+		c.statuses = c.statuses[1:]
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("status", jsonutils.NewString(status))
+	return ret, nil
+}
+
+func (c *fakeResourceClient) PerformAction(session *mcclient.ClientSession, id string, action string, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	c.performed = append(c.performed, action)
+	return jsonutils.NewDict(), nil
+}
+
+type fakeOpsLogs struct {
+	notes string
+}
+
+func (l fakeOpsLogs) List(session *mcclient.ClientSession, params jsonutils.JSONObject) (*modulebase.ListResult, error) {
+	ret := &modulebase.ListResult{}
+	if len(l.notes) > 0 {
+		event := jsonutils.NewDict()
+		event.Set("notes", jsonutils.NewString(l.notes))
+		ret.Data = append(ret.Data, event)
+	}
+	return ret, nil
+}
+
+func TestActionApply(t *testing.T) {
+	opsLogs = fakeOpsLogs{notes: "no capacity"}
+	start := ResourceOperation{
+		Resource:      ResourceDBInstance,
+		Operation:     api.ST_RESOURCE_OPERATION_START,
+		StatusSuccess: []string{comapi.DBINSTANCE_RUNNING},
+		Fail: []ResourceOperationFail{
+			{comapi.DBINSTANCE_START_FAILED, "start_fail"},
+		},
+		Providers: []string{comapi.CLOUD_PROVIDER_AWS},
+	}
+	snapshot := func(created *fakeResourceClient, createdId string) ResourceOperation {
+		return ResourceOperation{
+			Resource:      ResourceDisk,
+			Operation:     api.ST_RESOURCE_OPERATION_SNAPSHOT,
+			StatusSuccess: []string{comapi.SNAPSHOT_READY},
+			Fail: []ResourceOperationFail{
+				{comapi.SNAPSHOT_FAILED, ""},
+			},
+			Request: func(session *mcclient.ClientSession, id string, params api.ScheduledTaskOperationParams) (string, error) {
+				return createdId, nil
+			},
+			StatusManager: created,
+		}
+	}
+	cases := []struct {
+		name      string
+		oper      func(created *fakeResourceClient) ResourceOperation
+		resource  *fakeResourceClient
+		created   *fakeResourceClient
+		success   bool
+		reason    string
+		performed int
+		statusIds []string
+	}{
+		{
+			name:      "perform and wait for success",
+			oper:      func(*fakeResourceClient) ResourceOperation { return start },
+			resource:  &fakeResourceClient{provider: comapi.CLOUD_PROVIDER_AWS, statuses: []string{comapi.DBINSTANCE_STARTING, comapi.DBINSTANCE_RUNNING}},
+			success:   true,
+			performed: 1,
+			statusIds: []string{"res", "res"},
+		},
+		{
+			name:      "fail reason from opslog",
+			oper:      func(*fakeResourceClient) ResourceOperation { return start },
+			resource:  &fakeResourceClient{provider: comapi.CLOUD_PROVIDER_AWS, statuses: []string{comapi.DBINSTANCE_START_FAILED}},
+			reason:    "no capacity",
+			performed: 1,
+			statusIds: []string{"res"},
+		},
+		{
+			name:     "provider not supported",
+			oper:     func(*fakeResourceClient) ResourceOperation { return start },
+			resource: &fakeResourceClient{provider: comapi.CLOUD_PROVIDER_ALIYUN},
+			reason:   `operation start of dbinstance is not supported by provider "Aliyun"`,
+		},
+		{
+			name:      "timeout",
+			oper:      func(*fakeResourceClient) ResourceOperation { return start },
+			resource:  &fakeResourceClient{provider: comapi.CLOUD_PROVIDER_AWS, statuses: []string{comapi.DBINSTANCE_STARTING}},
+			reason:    "timeout",
+			performed: 1,
+		},
+		{
+			name:      "wait for created object instead of resource",
+			oper:      func(created *fakeResourceClient) ResourceOperation { return snapshot(created, "snap") },
+			resource:  &fakeResourceClient{statuses: []string{comapi.DISK_READY}},
+			created:   &fakeResourceClient{statuses: []string{comapi.SNAPSHOT_CREATING, comapi.SNAPSHOT_READY}},
+			success:   true,
+			statusIds: []string{"snap", "snap"},
+		},
+		{
+			name:      "created object failed",
+			oper:      func(created *fakeResourceClient) ResourceOperation { return snapshot(created, "snap") },
+			resource:  &fakeResourceClient{statuses: []string{comapi.DISK_READY}},
+			created:   &fakeResourceClient{statuses: []string{comapi.SNAPSHOT_CREATING, comapi.SNAPSHOT_FAILED}},
+			reason:    comapi.SNAPSHOT_FAILED,
+			statusIds: []string{"snap", "snap"},
+		},
+		{
+			name:     "no object created",
+			oper:     func(created *fakeResourceClient) ResourceOperation { return snapshot(created, "") },
+			resource: &fakeResourceClient{statuses: []string{comapi.DISK_READY}},
+			created:  &fakeResourceClient{statuses: []string{comapi.SNAPSHOT_READY}},
+			reason:   "no object created by snapshot of disk res",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			created := c.created
+			if created == nil {
+				created = &fakeResourceClient{}
+			}
+			action := Action.ResourceOperation(c.oper(created)).Timeout(50 * time.Millisecond).Interval(time.Millisecond)
+			success, reason := action.apply(c.resource, "res")
+			if success != c.success || reason != c.reason {
+				t.Fatalf("want (%t, %q), got (%t, %q)", c.success, c.reason, success, reason)
+			}
+			if len(c.resource.performed) != c.performed {
+				t.Errorf("want %d performed actions, got %v", c.performed, c.resource.performed)
+			}
+			if c.statusIds == nil {
+				return
+			}
+			statusIds := append(c.resource.statusIds, created.statusIds...)
+			if jsonutils.Marshal(statusIds).String() != jsonutils.Marshal(c.statusIds).String() {
+				t.Errorf("want status of %v, got %v", c.statusIds, statusIds)
+			}
+		})
+	}
+}
@@ -55,6 +55,8 @@ type SScheduledTaskActivity struct {
 	StartTime       time.Time `list:"user"`
 	EndTime         time.Time `list:"user"`
 	Reason          string    `charset:"utf8" list:"user"`
+	// Results records the operation result of every resource
+	Results jsonutils.JSONObject `nullable:"true" list:"user" get:"user"`
 }
 
 func (sam *SScheduledTaskActivityManager) InitializeData() error {
@@ -156,6 +158,15 @@ func (sa *SScheduledTaskActivity) SetResult(status, reason string) error {
 	return err
 }
 
+// SetResults saves the operation result of every resource
+func (sa *SScheduledTaskActivity) SetResults(results []api.ScheduledTaskActivityResult) error {
+	_, err := db.Update(sa, func() error {
+		sa.Results = jsonutils.Marshal(results)
+		return nil
+	})
+	return err
+}
+
 func (sa *SScheduledTaskActivity) Fail(reason string) error {
 	return sa.SetResult(api.ST_ACTIVITY_STATUS_FAILED, reason)
 }
@@ -17,6 +17,7 @@ package models
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"sort"
 	"strconv"
 	"strings"
@@ -27,6 +28,7 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/scheduledtask"
 	"yunion.io/x/onecloud/pkg/i18n"
 	"yunion.io/x/onecloud/pkg/util/bitmap"
+	"yunion.io/x/onecloud/pkg/util/cronexpr"
 )
 
 type STimer struct {
@@ -40,6 +42,14 @@ type STimer struct {
 	WeekDays uint8 `nullable:"false"`
 	// 0-31 0 is unlimited
 	MonthDays uint32 `nullable:"false"`
+	// CronExpr is the cron expression of cron type timer
+	CronExpr string `width:"128" charset:"ascii"`
+	// TimeZone the cron expression is evaluated in
+	TimeZone string `width:"64" charset:"ascii"`
+	// JitterSeconds delays every bell by a random duration less than it
+	JitterSeconds int `nullable:"false" default:"0"`
+	// MissedRunPolicy decides whether to run once for the missed bells
+	MissedRunPolicy string `width:"16" charset:"ascii"`
 
 	// StartTime represent the start time of this timer
 	StartTime time.Time
@@ -66,6 +76,14 @@ func (st *STimer) Update(now time.Time) {
 		return
 	}
 
+	if st.Type == api.TIMER_TYPE_CRON {
+		st.NextTime = st.cronNextTime(now)
+		if st.NextTime.IsZero() || st.NextTime.After(st.EndTime) {
+			st.IsExpired = true
+		}
+		return
+	}
+
 	newNextTime := time.Date(now.Year(), now.Month(), now.Day(), st.Hour, st.Minute, 0, 0, time.UTC).In(now.Location())
 	if now.After(newNextTime) {
 		newNextTime = newNextTime.AddDate(0, 0, 1)
@@ -109,6 +127,28 @@ func (st *STimer) Update(now time.Time) {
 	}
 }
 
+func (st *STimer) location() *time.Location {
+	loc, err := cronexpr.LoadLocation(st.TimeZone)
+	if err != nil {
+		log.Errorf("invalid time zone %s: %v", st.TimeZone, err)
+		return time.Local
+	}
+	return loc
+}
+
+func (st *STimer) cronNextTime(now time.Time) time.Time {
+	schedule, err := cronexpr.ParseInLocation(st.CronExpr, st.location())
+	if err != nil {
+		log.Errorf("invalid cron expression %s: %v", st.CronExpr, err)
+		return time.Time{}
+	}
+	next := schedule.Next(now)
+	if next.IsZero() || st.JitterSeconds <= 0 {
+		return next
+	}
+	return next.Add(time.Duration(rand.Intn(st.JitterSeconds)) * time.Second)
+}
+
 // MonthDaySum calculate the number of month's days
 func (st *STimer) MonthDaySum(t time.Time) int {
 	year, month := t.Year(), t.Month()
@@ -151,6 +191,11 @@ func (st *STimer) CycleTimerDetails() api.CycleTimerDetails {
 		StartTime: st.StartTime,
 		EndTime:   st.EndTime,
 		CycleType: st.Type,
+
+		CronExpr:        st.CronExpr,
+		TimeZone:        st.TimeZone,
+		JitterSeconds:   st.JitterSeconds,
+		MissedRunPolicy: st.MissedRunPolicy,
 	}
 	return out
 }
@@ -203,6 +248,8 @@ func (st *STimer) descChinese(zone *time.Location) string {
 			weekDays[i] = fmt.Sprintf("星期%s", wdsCN[wds[i]])
 		}
 		prefix = fmt.Sprintf("每周 【%s】", strings.Join(weekDays, "｜"))
+	case api.TIMER_TYPE_CRON:
+		return fmt.Sprintf("按cron表达式【%s】触发 有效时间为%s至%s", st.cronDesc(), st.StartTime.In(zone).Format(format), st.EndTime.In(zone).Format(format))
 	case api.TIMER_TYPE_MONTH:
 		mns := st.GetMonthDays()
 		monthDays := make([]string, len(mns))
@@ -232,6 +279,8 @@ func (st *STimer) descEnglish(zone *time.Location) string {
 		detail = st.weekDaysDesc(zone)
 	case api.TIMER_TYPE_MONTH:
 		detail = st.monthDaysDesc(zone)
+	case api.TIMER_TYPE_CRON:
+		detail = fmt.Sprintf("cron %s", st.cronDesc())
 	}
 	if st.EndTime.IsZero() {
 		return detail
@@ -239,6 +288,13 @@ func (st *STimer) descEnglish(zone *time.Location) string {
 	return fmt.Sprintf("%s, from %s to %s", detail, st.StartTime.In(zone).Format(format), st.EndTime.In(zone).Format(format))
 }
 
+func (st *STimer) cronDesc() string {
+	if len(st.TimeZone) == 0 {
+		return st.CronExpr
+	}
+	return fmt.Sprintf("%s (%s)", st.CronExpr, st.TimeZone)
+}
+
 func (st *STimer) weekDaysDesc(zone *time.Location) string {
 	if st.WeekDays == 0 {
 		return ""
@@ -317,9 +373,41 @@ func checkCycleTimerCreateInput(in api.CycleTimerCreateInput) (api.CycleTimerCre
 			return in, fmt.Errorf("month_days should not be empty")
 		}
 		in.WeekDays = []int{}
+	case api.TIMER_TYPE_CRON:
+		if len(in.CronExpr) == 0 {
+			return in, fmt.Errorf("cron_expr should not be empty")
+		}
+		loc, err := cronexpr.LoadLocation(in.TimeZone)
+		if err != nil {
+			return in, fmt.Errorf("invalid time_zone %s", in.TimeZone)
+		}
+		schedule, err := cronexpr.ParseInLocation(in.CronExpr, loc)
+		if err != nil {
+			return in, err
+		}
+		// scheduled tasks are checked once a minute
+		interval, err := schedule.MinInterval(now, 60)
+		if err != nil {
+			return in, err
+		}
+		if interval > 0 && interval < time.Minute {
+			return in, fmt.Errorf("cron_expr should not fire more often than once a minute")
+		}
+		in.WeekDays = []int{}
+		in.MonthDays = []int{}
 	default:
 		return in, fmt.Errorf("unkown cycle type %s", in.CycleType)
 	}
+	if in.JitterSeconds < 0 {
+		return in, fmt.Errorf("jitter_seconds should not be negative")
+	}
+	switch in.MissedRunPolicy {
+	case "":
+		in.MissedRunPolicy = api.TIMER_MISSED_RUN_SKIP
+	case api.TIMER_MISSED_RUN_SKIP, api.TIMER_MISSED_RUN_CATCH_UP_ONCE:
+	default:
+		return in, fmt.Errorf("unkown missed_run_policy %s", in.MissedRunPolicy)
+	}
 	if now.After(in.EndTime) {
 		return in, fmt.Errorf("end_time is earlier than now")
 	}
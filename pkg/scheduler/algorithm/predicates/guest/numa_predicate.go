@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"context"
+	"fmt"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/plugin"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// NumaPredicate filter hosts where the guest can not be placed entirely
+// inside one NUMA node when numa_strict is requested, the selected node
+// is passed back to the host to bind the guest.
+type NumaPredicate struct {
+	predicates.BasePredicate
+	plugin.BasePlugin
+}
+
+func (p *NumaPredicate) Name() string {
+	return "host_numa"
+}
+
+func (p *NumaPredicate) Clone() core.FitPredicate {
+	return &NumaPredicate{}
+}
+
+func (p *NumaPredicate) PreExecute(ctx context.Context, u *core.Unit, cs []core.Candidater) (bool, error) {
+	data := u.SchedData()
+	if !data.NumaStrict || data.Memory <= 0 {
+		return false, nil
+	}
+	if u.GetHypervisor() != computeapi.HYPERVISOR_KVM {
+		return false, nil
+	}
+	u.AppendSelectPlugin(p)
+	return true, nil
+}
+
+func (p *NumaPredicate) Execute(ctx context.Context, u *core.Unit, c core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	h := predicates.NewPredicateHelper(p, u, c)
+	d := u.SchedData()
+
+	nodes := c.Getter().NumaNodes()
+	if len(nodes) == 0 {
+		h.Exclude("host not report numa nodes")
+		return h.GetResult()
+	}
+
+	vcpu, mem := int64(d.Ncpu), int64(d.Memory)
+	capacity := int64(0)
+	for _, node := range nodes {
+		capacity += node.Capacity(vcpu, mem)
+	}
+	if capacity <= 0 {
+		h.Exclude(fmt.Sprintf("no numa node fits %d vcpus and %dMB memory", vcpu, mem))
+		return h.GetResult()
+	}
+	h.SetCapacity(capacity)
+	return h.GetResult()
+}
+
+func (p *NumaPredicate) OnSelectEnd(u *core.Unit, c core.Candidater, count int64) {
+	d := u.SchedData()
+	output := u.GetAllocatedResource(c.IndexKey())
+	output.NumaNodes = core.SelectNumaNodes(c.Getter().NumaNodes(), int64(d.Ncpu), int64(d.Memory), count)
+}
@@ -17,7 +17,9 @@ package predicates
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/scheduler/core"
 )
 
@@ -67,7 +69,7 @@ func (f *IsolatedDevicePredicate) Execute(ctx context.Context, u *core.Unit, c c
 	}
 
 	reqCount := len(reqIsoDevs)
-	freeCount := len(getter.UnusedIsolatedDevices()) - getter.GetPendingUsage().IsolatedDevice
+	freeCount := len(availableIsolatedDevices(getter, getter.UnusedIsolatedDevices())) - getter.GetPendingUsage().IsolatedDevice
 	totalCount := len(getter.GetIsolatedDevices())
 
 	// check host isolated device count
@@ -87,7 +89,7 @@ func (f *IsolatedDevicePredicate) Execute(ctx context.Context, u *core.Unit, c c
 		}
 	}
 	for devType, reqCount := range devTypeRequest {
-		freeCount := len(getter.UnusedIsolatedDevicesByType(devType))
+		freeCount := len(availableIsolatedDevices(getter, getter.UnusedIsolatedDevicesByType(devType)))
 		if freeCount < reqCount {
 			h.Exclude(fmt.Sprintf("IsolatedDevice type %q not enough, request: %d, hostFree: %d", devType, reqCount, freeCount))
 			return h.GetResult()
@@ -106,7 +108,7 @@ func (f *IsolatedDevicePredicate) Execute(ctx context.Context, u *core.Unit, c c
 		}
 	}
 	for vendorModel, reqCount := range devVendorModelRequest {
-		freeCount := len(getter.UnusedIsolatedDevicesByVendorModel(vendorModel))
+		freeCount := len(availableIsolatedDevices(getter, getter.UnusedIsolatedDevicesByVendorModel(vendorModel)))
 		if freeCount < reqCount {
 			h.Exclude(fmt.Sprintf("IsolatedDevice vendor:model %q not enough, request: %d, hostFree: %d", vendorModel, reqCount, freeCount))
 			return h.GetResult()
@@ -120,3 +122,32 @@ func (f *IsolatedDevicePredicate) Execute(ctx context.Context, u *core.Unit, c c
 	h.SetCapacity(minCapacity)
 	return h.GetResult()
 }
+
+// mdevParentAddr returns parent device address of mdev slot `<parent_addr>/<index>`
+func mdevParentAddr(addr string) string {
+	return strings.Split(addr, "/")[0]
+}
+
+// availableIsolatedDevices filters out mdev slots that can't be created,
+// a parent device is partitioned by instances of one mdev type at a time.
+func availableIsolatedDevices(getter core.CandidatePropertyGetter, devs []*core.IsolatedDeviceDesc) []*core.IsolatedDeviceDesc {
+	usedParentModels := make(map[string]string)
+	for _, dev := range getter.GetIsolatedDevices() {
+		if dev.DevType == computeapi.MDEV_TYPE && len(dev.GuestID) != 0 {
+			usedParentModels[mdevParentAddr(dev.Addr)] = dev.Model
+		}
+	}
+	if len(usedParentModels) == 0 {
+		return devs
+	}
+	ret := make([]*core.IsolatedDeviceDesc, 0, len(devs))
+	for _, dev := range devs {
+		if dev.DevType == computeapi.MDEV_TYPE {
+			if model, ok := usedParentModels[mdevParentAddr(dev.Addr)]; ok && model != dev.Model {
+				continue
+			}
+		}
+		ret = append(ret, dev)
+	}
+	return ret
+}
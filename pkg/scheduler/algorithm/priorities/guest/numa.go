@@ -0,0 +1,62 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/priorities"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	"yunion.io/x/onecloud/pkg/scheduler/core/score"
+)
+
+// NumaPriority prefers hosts having a NUMA node the guest fits in, the
+// tighter the best fitting node the higher the score
+type NumaPriority struct {
+	priorities.BasePriority
+}
+
+func (p *NumaPriority) Name() string {
+	return "host_numa"
+}
+
+func (p *NumaPriority) Clone() core.Priority {
+	return &NumaPriority{}
+}
+
+func (p *NumaPriority) Map(u *core.Unit, c core.Candidater) (core.HostPriority, error) {
+	h := priorities.NewPriorityHelper(p, u, c)
+
+	d := u.SchedData()
+	vcpu, mem := int64(d.Ncpu), int64(d.Memory)
+	if mem <= 0 {
+		return h.GetResult()
+	}
+	var best *core.NumaNodeDesc
+	for _, node := range c.Getter().NumaNodes() {
+		if !node.Fits(vcpu, mem) {
+			continue
+		}
+		if best == nil || node.FreeMemSize < best.FreeMemSize {
+			best = node
+		}
+	}
+	if best != nil {
+		h.SetScore(5 + int(5*mem/best.FreeMemSize))
+	}
+	return h.GetResult()
+}
+
+func (p *NumaPriority) ScoreIntervals() score.Intervals {
+	return score.NewIntervals(0, 1, 6)
+}
@@ -17,6 +17,7 @@ package api
 import (
 	"net/http" //"yunion.io/x/jsonutils"
 
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
@@ -51,6 +52,27 @@ type SchedInfo struct {
 	InstanceGroupsDetail map[string]*models.SGroup
 
 	UserCred mcclient.TokenCredential
+
+	// SimulatedUsages is the usage placed on hosts by earlier specs of a
+	// what-if simulation, keyed by host id
+	SimulatedUsages map[string]*SimulatedUsage `json:"-"`
+}
+
+// SimulatedUsage is the resources a what-if simulation has placed on a host
+type SimulatedUsage struct {
+	Cpu    int
+	Memory int
+	// storage backend => size in MB
+	Disks map[string]int
+	// network id => port count
+	Nets map[string]int
+}
+
+func NewSimulatedUsage() *SimulatedUsage {
+	return &SimulatedUsage{
+		Disks: make(map[string]int),
+		Nets:  make(map[string]int),
+	}
 }
 
 func FetchAuthToken(req *http.Request) (mcclient.TokenCredential, error) {
@@ -85,6 +107,37 @@ func FetchSchedInfo(req *http.Request) (*SchedInfo, error) {
 		return nil, err
 	}
 
+	return newSchedInfoByJSON(userCred, body)
+}
+
+// FetchSimulateSchedInfos parses the specs of a what-if simulation request
+func FetchSimulateSchedInfos(req *http.Request) ([]*SchedInfo, error) {
+	userCred, err := FetchUserCred(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch user cred")
+	}
+
+	body, err := appsrv.FetchJSON(req)
+	if err != nil {
+		return nil, err
+	}
+
+	specs, err := body.GetArray("specs")
+	if err != nil {
+		return nil, errors.Wrap(httperrors.ErrMissingParameter, "specs")
+	}
+	infos := make([]*SchedInfo, 0, len(specs))
+	for i, spec := range specs {
+		info, err := newSchedInfoByJSON(userCred, spec)
+		if err != nil {
+			return nil, errors.Wrapf(err, "spec %d", i)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func newSchedInfoByJSON(userCred mcclient.TokenCredential, body jsonutils.JSONObject) (*SchedInfo, error) {
 	input, err := cmdline.FetchScheduleInputByJSON(body)
 	if err != nil {
 		return nil, err
@@ -293,3 +346,39 @@ type SchedForecastResult struct {
 	NotAllowReasons    []string                 `json:"not_allow_reasons"`
 	FilteredCandidates []FilteredCandidate      `json:"filtered_candidates"`
 }
+
+type SchedSimulateSpecResult struct {
+	Index              int                 `json:"index"`
+	Name               string              `json:"name"`
+	ReqCount           int64               `json:"req_count"`
+	AllowCount         int64               `json:"allow_count"`
+	Hosts              map[string]int64    `json:"hosts"`
+	Zones              map[string]int64    `json:"zones"`
+	NotAllowReasons    []string            `json:"not_allow_reasons"`
+	FilteredCandidates []FilteredCandidate `json:"filtered_candidates"`
+}
+
+type SchedSimulateHostUsage struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	ZoneId string `json:"zone_id"`
+
+	CpuTotal int64 `json:"cpu_total"`
+	CpuFree  int64 `json:"cpu_free"`
+	// cpu placed by the simulation
+	CpuSimulated int64 `json:"cpu_simulated"`
+
+	MemTotal     int64 `json:"mem_total"`
+	MemFree      int64 `json:"mem_free"`
+	MemSimulated int64 `json:"mem_simulated"`
+
+	// utilization percent after the simulation
+	CpuUtilization float64 `json:"cpu_utilization"`
+	MemUtilization float64 `json:"mem_utilization"`
+}
+
+type SchedSimulateResult struct {
+	CanCreate bool                       `json:"can_create"`
+	Specs     []*SchedSimulateSpecResult `json:"specs"`
+	Hosts     []*SchedSimulateHostUsage  `json:"hosts"`
+}
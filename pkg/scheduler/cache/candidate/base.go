@@ -283,6 +283,10 @@ func (b baseHostGetter) GetIsolatedDevices() []*core.IsolatedDeviceDesc {
 	return b.h.GetIsolatedDevices()
 }
 
+func (b baseHostGetter) NumaNodes() []*core.NumaNodeDesc {
+	return nil
+}
+
 func reviseResourceType(resType string) string {
 	if resType == "" {
 		return computeapi.HostResourceTypeDefault
@@ -17,6 +17,7 @@ package candidate
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	gosync "sync"
 	"sync/atomic"
@@ -97,6 +98,10 @@ func (h *hostGetter) OvnCapable() bool {
 	return len(h.h.OvnVersion) > 0
 }
 
+func (h *hostGetter) NumaNodes() []*core.NumaNodeDesc {
+	return h.h.NumaNodes
+}
+
 type HostDesc struct {
 	*BaseHostDesc
 
@@ -137,6 +142,9 @@ type HostDesc struct {
 	IsMaintenance             bool              `json:"is_maintenance"`
 	GuestReservedResource     *ReservedResource `json:"guest_reserved_resource"`
 	GuestReservedResourceUsed *ReservedResource `json:"guest_reserved_used"`
+
+	// numa
+	NumaNodes []*core.NumaNodeDesc `json:"numa_nodes"`
 }
 
 type ReservedResource struct {
@@ -230,6 +238,8 @@ type HostBuilder struct {
 
 	hostGuests       map[string][]interface{}
 	hostBackupGuests map[string][]interface{}
+	// guest id => metadata key => value of numa related metadata
+	guestNumaMetadata map[string]map[string]string
 
 	//groupGuests        []interface{}
 	//groups             []interface{}
@@ -452,6 +462,7 @@ func (b *HostBuilder) init(ids []string) error {
 		func() { b.setSchedtags(ids, errMessageChannel) },
 		func() {
 			b.setGuests(ids, errMessageChannel)
+			b.setGuestNumaMetadata(ids, errMessageChannel)
 			b.setIsolatedDevs(ids, errMessageChannel)
 		},
 	}
@@ -569,6 +580,26 @@ func (b *HostBuilder) setGuests(ids []string, errMessageChannel chan error) {
 	return
 }
 
+func (b *HostBuilder) setGuestNumaMetadata(ids []string, errMessageChannel chan error) {
+	guests := computemodels.GuestManager.Query("id").In("host_id", ids).SubQuery()
+	q := computedb.Metadata.Query().Equals("obj_type", computemodels.GuestManager.Keyword())
+	q = q.In("key", []string{computeapi.VM_METADATA_NUMA_NODE, computeapi.VM_METADATA_CGROUP_CPUSET})
+	q = q.In("obj_id", guests)
+	metadatas := make([]computedb.SMetadata, 0)
+	if err := q.All(&metadatas); err != nil {
+		errMessageChannel <- err
+		return
+	}
+	ret := make(map[string]map[string]string)
+	for _, m := range metadatas {
+		if _, ok := ret[m.ObjId]; !ok {
+			ret[m.ObjId] = make(map[string]string)
+		}
+		ret[m.ObjId][m.Key] = m.Value
+	}
+	b.guestNumaMetadata = ret
+}
+
 //func (b *HostBuilder) setGroupInfo(errMessageChannel chan error) {
 //groupGuests, err := models.FetchByGuestIDs(models.GroupGuests, b.guestIDs)
 //if err != nil {
@@ -815,6 +846,7 @@ func (b *HostBuilder) buildOne(host *computemodels.SHost) (interface{}, error) {
 		b.fillGuestsResourceInfo,
 		//b.fillResidentGroups,
 		b.fillMetadata,
+		b.fillNumaNodes,
 		b.fillCPUIOLoads,
 	}
 
@@ -1004,6 +1036,63 @@ func (b *HostBuilder) fillMetadata(desc *HostDesc, host *computemodels.SHost) er
 	return nil
 }
 
+// fillNumaNodes builds the schedulable NUMA nodes from the topology the
+// host reported, memory of guests bound to a node and cpus pinned by any
+// guest are not available to new guests, free memory is further capped by
+// what the host last reported as free on the node
+func (b *HostBuilder) fillNumaNodes(desc *HostDesc, host *computemodels.SHost) error {
+	nodesStr, ok := desc.Metadata[computeapi.HOSTMETA_NUMA_NODES]
+	if !ok || len(nodesStr) == 0 {
+		return nil
+	}
+	nodes := make([]computeapi.HostNumaNode, 0)
+	if err := json.Unmarshal([]byte(nodesStr), &nodes); err != nil {
+		log.Errorf("Unmarshal host %s numa nodes %q: %v", desc.GetId(), nodesStr, err)
+		return nil
+	}
+	boundMem := make(map[int]int64)
+	pinned := sets.NewInt()
+	for _, gst := range b.hostGuests[host.Id] {
+		guest := gst.(computemodels.SGuest)
+		meta, ok := b.guestNumaMetadata[guest.Id]
+		if !ok {
+			continue
+		}
+		if nodeStr, ok := meta[computeapi.VM_METADATA_NUMA_NODE]; ok {
+			nodeId, err := strconv.Atoi(nodeStr)
+			if err == nil {
+				boundMem[nodeId] += int64(guest.VmemSize)
+			}
+		}
+		if cpusetStr, ok := meta[computeapi.VM_METADATA_CGROUP_CPUSET]; ok {
+			cpuset := new(computeapi.ServerCPUSetInput)
+			if err := json.Unmarshal([]byte(cpusetStr), cpuset); err == nil {
+				pinned.Insert(cpuset.CPUS...)
+			}
+		}
+	}
+	desc.NumaNodes = make([]*core.NumaNodeDesc, 0, len(nodes))
+	for _, node := range nodes {
+		cpuCount := int64(0)
+		for _, cpu := range node.Cpus {
+			if !pinned.Has(cpu) {
+				cpuCount++
+			}
+		}
+		freeMem := int64(node.MemSizeMb) - boundMem[node.NodeId]
+		if node.MemFreeMb > 0 && int64(node.MemFreeMb) < freeMem {
+			freeMem = int64(node.MemFreeMb)
+		}
+		desc.NumaNodes = append(desc.NumaNodes, &core.NumaNodeDesc{
+			NodeId:       node.NodeId,
+			CpuCount:     cpuCount,
+			TotalMemSize: int64(node.MemSizeMb),
+			FreeMemSize:  freeMem,
+		})
+	}
+	return nil
+}
+
 func (b *HostBuilder) getUsedIsolatedDevices(hostID string) (devs []computemodels.SIsolatedDevice) {
 	devs = make([]computemodels.SIsolatedDevice, 0)
 	for _, dev := range b.getIsolatedDevices(hostID) {
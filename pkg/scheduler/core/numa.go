@@ -0,0 +1,78 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"sort"
+)
+
+// NumaNodeDesc describes the schedulable resources of one host NUMA node
+type NumaNodeDesc struct {
+	NodeId int
+	// CpuCount is the count of node cpus not pinned by other guests
+	CpuCount     int64
+	TotalMemSize int64
+	FreeMemSize  int64
+}
+
+// Fits reports whether a guest with vcpu and mem can be placed entirely
+// inside this node
+func (n *NumaNodeDesc) Fits(vcpu int64, mem int64) bool {
+	return vcpu <= n.CpuCount && mem <= n.FreeMemSize
+}
+
+// Capacity returns how many guests of the given size this node can hold,
+// cpus are shared so only memory bounds the count
+func (n *NumaNodeDesc) Capacity(vcpu int64, mem int64) int64 {
+	if !n.Fits(vcpu, mem) {
+		return 0
+	}
+	if mem <= 0 {
+		return EmptyCapacity
+	}
+	return n.FreeMemSize / mem
+}
+
+// SelectNumaNodes picks a node for each of count guests, preferring the
+// node with the least free memory that still fits to keep large nodes
+// available for large guests. Nil is returned if not all guests fit.
+func SelectNumaNodes(nodes []*NumaNodeDesc, vcpu int64, mem int64, count int64) []int {
+	free := make([]NumaNodeDesc, len(nodes))
+	for i := range nodes {
+		free[i] = *nodes[i]
+	}
+	sort.SliceStable(free, func(i, j int) bool {
+		return free[i].FreeMemSize < free[j].FreeMemSize
+	})
+	ret := make([]int, 0, count)
+	for i := int64(0); i < count; i++ {
+		found := false
+		for j := range free {
+			if free[j].Fits(vcpu, mem) {
+				free[j].FreeMemSize -= mem
+				ret = append(ret, free[j].NodeId)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+		sort.SliceStable(free, func(i, j int) bool {
+			return free[i].FreeMemSize < free[j].FreeMemSize
+		})
+	}
+	return ret
+}
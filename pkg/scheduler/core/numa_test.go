@@ -0,0 +1,62 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectNumaNodes(t *testing.T) {
+	nodes := []*NumaNodeDesc{
+		{NodeId: 0, CpuCount: 8, TotalMemSize: 16384, FreeMemSize: 8192},
+		{NodeId: 1, CpuCount: 8, TotalMemSize: 16384, FreeMemSize: 4096},
+		{NodeId: 2, CpuCount: 2, TotalMemSize: 16384, FreeMemSize: 16384},
+	}
+	cases := []struct {
+		name  string
+		vcpu  int64
+		mem   int64
+		count int64
+		want  []int
+	}{
+		{"best fit", 4, 4096, 1, []int{1}},
+		{"spill to next node", 4, 4096, 3, []int{1, 0, 0}},
+		{"cpus bound", 4, 1024, 1, []int{1}},
+		{"few cpus node", 2, 12288, 1, []int{2}},
+		{"not all fit", 4, 8192, 2, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := SelectNumaNodes(nodes, c.vcpu, c.mem, c.count)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+	if nodes[1].FreeMemSize != 4096 {
+		t.Errorf("input nodes should not be changed")
+	}
+}
+
+func TestNumaNodeCapacity(t *testing.T) {
+	node := &NumaNodeDesc{NodeId: 0, CpuCount: 4, FreeMemSize: 8192}
+	if got := node.Capacity(4, 2048); got != 4 {
+		t.Errorf("want 4, got %d", got)
+	}
+	if got := node.Capacity(8, 2048); got != 0 {
+		t.Errorf("want 0, got %d", got)
+	}
+}
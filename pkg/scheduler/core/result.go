@@ -77,13 +77,23 @@ func (its SchedResultItems) Less(i, j int) bool {
 
 func (item *SchedResultItem) ToCandidateResource(storageUsed *StorageUsed) *schedapi.CandidateResource {
 	return &schedapi.CandidateResource{
-		HostId: item.ID,
-		Name:   item.Name,
-		Disks:  item.getDisks(storageUsed),
-		Nets:   item.Nets,
+		HostId:   item.ID,
+		Name:     item.Name,
+		Disks:    item.getDisks(storageUsed),
+		Nets:     item.Nets,
+		NumaNode: item.popNumaNode(),
 	}
 }
 
+func (item *SchedResultItem) popNumaNode() *int {
+	if item.AllocatedResource == nil || len(item.NumaNodes) == 0 {
+		return nil
+	}
+	node := item.NumaNodes[0]
+	item.NumaNodes = item.NumaNodes[1:]
+	return &node
+}
+
 func (item *SchedResultItem) getDisks(used *StorageUsed) []*schedapi.CandidateDisk {
 	inputs := item.SchedData.Disks
 	ret := make([]*schedapi.CandidateDisk, 0)
@@ -126,6 +126,8 @@ type CandidatePropertyGetter interface {
 	UnusedGpuDevices() []*IsolatedDeviceDesc
 	GetIsolatedDevices() []*IsolatedDeviceDesc
 
+	NumaNodes() []*NumaNodeDesc
+
 	db.IResource
 }
 
@@ -225,6 +227,8 @@ type Priority interface {
 type AllocatedResource struct {
 	Disks []*schedapi.CandidateDiskV2 `json:"disks"`
 	Nets  []*schedapi.CandidateNet    `json:"nets"`
+	// NumaNodes is the host NUMA node selected for each guest
+	NumaNodes []int `json:"numa_nodes"`
 }
 
 func NewAllocatedResource() *AllocatedResource {
@@ -59,6 +59,9 @@ func candidatesByProvider(provider CandidatesProvider, schedData *api.SchedInfo)
 	if err != nil {
 		return nil, err
 	}
+	if len(schedData.SimulatedUsages) > 0 {
+		hosts = simulateCandidates(hosts, schedData.SimulatedUsages)
+	}
 	return hosts, nil
 }
 
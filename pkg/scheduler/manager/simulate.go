@@ -0,0 +1,190 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"sort"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// simulatedCandidate hides the resources placed on the host by earlier
+// specs of a what-if simulation
+type simulatedCandidate struct {
+	core.Candidater
+	usage *api.SimulatedUsage
+}
+
+func (c *simulatedCandidate) Getter() core.CandidatePropertyGetter {
+	return &simulatedGetter{
+		CandidatePropertyGetter: c.Candidater.Getter(),
+		usage:                   c.usage,
+	}
+}
+
+type simulatedGetter struct {
+	core.CandidatePropertyGetter
+	usage *api.SimulatedUsage
+}
+
+func (g *simulatedGetter) FreeCPUCount(useRsvd bool) int64 {
+	return g.CandidatePropertyGetter.FreeCPUCount(useRsvd) - int64(g.usage.Cpu)
+}
+
+func (g *simulatedGetter) FreeMemorySize(useRsvd bool) int64 {
+	return g.CandidatePropertyGetter.FreeMemorySize(useRsvd) - int64(g.usage.Memory)
+}
+
+func (g *simulatedGetter) GetFreeStorageSizeOfType(storageType string, useRsvd bool) (int64, int64) {
+	free, actualFree := g.CandidatePropertyGetter.GetFreeStorageSizeOfType(storageType, useRsvd)
+	used := int64(g.usage.Disks[storageType])
+	return free - used, actualFree - used
+}
+
+func (g *simulatedGetter) GetFreePort(netId string) int {
+	return g.CandidatePropertyGetter.GetFreePort(netId) - g.usage.Nets[netId]
+}
+
+func simulateCandidates(cs []core.Candidater, usages map[string]*api.SimulatedUsage) []core.Candidater {
+	ret := make([]core.Candidater, len(cs))
+	for i, c := range cs {
+		if usage, ok := usages[c.IndexKey()]; ok {
+			ret[i] = &simulatedCandidate{Candidater: c, usage: usage}
+		} else {
+			ret[i] = c
+		}
+	}
+	return ret
+}
+
+// Simulate schedules the specs one after another as forecasts, the guests
+// placed by a spec are deducted from the hosts seen by the following
+// specs. Nothing is reserved in the candidate cache or pending usage.
+func Simulate(infos []*api.SchedInfo) (*api.SchedSimulateResult, error) {
+	usages := make(map[string]*api.SimulatedUsage)
+	ret := &api.SchedSimulateResult{
+		CanCreate: true,
+		Specs:     make([]*api.SchedSimulateSpecResult, 0, len(infos)),
+	}
+	for idx, info := range infos {
+		info.IsSuggestion = true
+		info.ShowSuggestionDetails = true
+		info.SuggestionAll = true
+		info.SimulatedUsages = usages
+		result, err := Schedule(info)
+		if err != nil {
+			return nil, errors.Wrapf(err, "simulate spec %d", idx)
+		}
+		forecast := result.ForecastResult
+		spec := &api.SchedSimulateSpecResult{
+			Index:              idx,
+			Name:               info.Name,
+			ReqCount:           forecast.ReqCount,
+			AllowCount:         forecast.AllowCount,
+			Hosts:              make(map[string]int64),
+			Zones:              make(map[string]int64),
+			NotAllowReasons:    forecast.NotAllowReasons,
+			FilteredCandidates: forecast.FilteredCandidates,
+		}
+		for _, candi := range forecast.Candidates {
+			spec.Hosts[candi.HostId]++
+			usage, ok := usages[candi.HostId]
+			if !ok {
+				usage = api.NewSimulatedUsage()
+				usages[candi.HostId] = usage
+			}
+			usage.Cpu += info.Ncpu
+			usage.Memory += info.Memory
+			for _, disk := range info.Disks {
+				usage.Disks[disk.Backend] += disk.SizeMb
+			}
+			for _, net := range candi.Nets {
+				if len(net.NetworkIds) > 0 {
+					usage.Nets[net.NetworkIds[0]]++
+				}
+			}
+		}
+		if !forecast.CanCreate {
+			ret.CanCreate = false
+		}
+		ret.Specs = append(ret.Specs, spec)
+	}
+
+	hosts, err := simulateHostUsages(usages)
+	if err != nil {
+		return nil, err
+	}
+	ret.Hosts = hosts
+	zones := make(map[string]string)
+	for _, host := range hosts {
+		zones[host.Id] = host.ZoneId
+	}
+	for _, spec := range ret.Specs {
+		for hostId, count := range spec.Hosts {
+			spec.Zones[zones[hostId]] += count
+		}
+	}
+	return ret, nil
+}
+
+func simulateHostUsages(usages map[string]*api.SimulatedUsage) ([]*api.SchedSimulateHostUsage, error) {
+	ret := make([]*api.SchedSimulateHostUsage, 0, len(usages))
+	if len(usages) == 0 {
+		return ret, nil
+	}
+	ids := make([]string, 0, len(usages))
+	for id := range usages {
+		ids = append(ids, id)
+	}
+	cs, err := schedManager.CandidateManager.GetCandidatesByIds(api.HostTypeHost, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetCandidatesByIds")
+	}
+	for _, c := range cs {
+		getter := c.Getter()
+		usage := usages[c.IndexKey()]
+		host := &api.SchedSimulateHostUsage{
+			Id:           getter.Id(),
+			Name:         getter.Name(),
+			CpuTotal:     getter.TotalCPUCount(false),
+			CpuFree:      getter.FreeCPUCount(false),
+			CpuSimulated: int64(usage.Cpu),
+			MemTotal:     getter.TotalMemorySize(false),
+			MemFree:      getter.FreeMemorySize(false),
+			MemSimulated: int64(usage.Memory),
+		}
+		if zone := getter.Zone(); zone != nil {
+			host.ZoneId = zone.Id
+		}
+		host.CpuUtilization = utilization(host.CpuTotal, host.CpuFree, host.CpuSimulated)
+		host.MemUtilization = utilization(host.MemTotal, host.MemFree, host.MemSimulated)
+		ret = append(ret, host)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+// utilization returns the used percent of a resource after the simulation
+func utilization(total, free, simulated int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(total-free+simulated) * 100 / float64(total)
+}
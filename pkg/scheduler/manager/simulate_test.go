@@ -0,0 +1,78 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	"yunion.io/x/onecloud/pkg/scheduler/test/mock"
+)
+
+func TestSimulateCandidates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	newCandidate := func(id string) core.Candidater {
+		getter := mock.NewMockCandidatePropertyGetter(ctrl)
+		getter.EXPECT().FreeCPUCount(gomock.Any()).AnyTimes().Return(int64(16))
+		getter.EXPECT().FreeMemorySize(gomock.Any()).AnyTimes().Return(int64(32768))
+		getter.EXPECT().GetFreeStorageSizeOfType(gomock.Any(), gomock.Any()).AnyTimes().Return(int64(102400), int64(0))
+		getter.EXPECT().GetFreePort(gomock.Any()).AnyTimes().Return(10)
+		c := mock.NewMockCandidater(ctrl)
+		c.EXPECT().IndexKey().AnyTimes().Return(id)
+		c.EXPECT().Getter().AnyTimes().Return(getter)
+		return c
+	}
+
+	usage := api.NewSimulatedUsage()
+	usage.Cpu = 4
+	usage.Memory = 8192
+	usage.Disks["local"] = 20480
+	usage.Nets["net1"] = 2
+	cs := simulateCandidates(
+		[]core.Candidater{newCandidate("host1"), newCandidate("host2")},
+		map[string]*api.SimulatedUsage{"host1": usage},
+	)
+
+	getter := cs[0].Getter()
+	if got := getter.FreeCPUCount(false); got != 12 {
+		t.Errorf("cpu want 12, got %d", got)
+	}
+	if got := getter.FreeMemorySize(false); got != 24576 {
+		t.Errorf("memory want 24576, got %d", got)
+	}
+	if free, _ := getter.GetFreeStorageSizeOfType("local", false); free != 81920 {
+		t.Errorf("storage want 81920, got %d", free)
+	}
+	if got := getter.GetFreePort("net1"); got != 8 {
+		t.Errorf("port want 8, got %d", got)
+	}
+	if got := cs[1].Getter().FreeCPUCount(false); got != 16 {
+		t.Errorf("host without usage want 16, got %d", got)
+	}
+}
+
+func TestUtilization(t *testing.T) {
+	if got := utilization(100, 60, 20); got != 60 {
+		t.Errorf("want 60, got %f", got)
+	}
+	if got := utilization(0, 0, 20); got != 0 {
+		t.Errorf("want 0, got %f", got)
+	}
+}
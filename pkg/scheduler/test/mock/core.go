@@ -249,6 +249,20 @@ func (mr *MockCandidatePropertyGetterMockRecorder) GetIsolatedDevices() *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIsolatedDevices", reflect.TypeOf((*MockCandidatePropertyGetter)(nil).GetIsolatedDevices))
 }
 
+// NumaNodes mocks base method
+func (m *MockCandidatePropertyGetter) NumaNodes() []*core.NumaNodeDesc {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NumaNodes")
+	ret0, _ := ret[0].([]*core.NumaNodeDesc)
+	return ret0
+}
+
+// NumaNodes indicates an expected call of NumaNodes
+func (mr *MockCandidatePropertyGetterMockRecorder) NumaNodes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumaNodes", reflect.TypeOf((*MockCandidatePropertyGetter)(nil).NumaNodes))
+}
+
 // GetPendingUsage mocks base method
 func (m *MockCandidatePropertyGetter) GetPendingUsage() *models0.SPendingUsage {
 	m.ctrl.T.Helper()
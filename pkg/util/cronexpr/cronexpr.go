@@ -0,0 +1,322 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cronexpr
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	ErrInvalidExpr = errors.Error("invalid cron expression")
+
+	// searchYears bounds the search of Next for expressions that never match,
+	// such as 0 0 30 2 *
+	searchYears = 5
+)
+
+type sField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	secondField = sField{name: "second", min: 0, max: 59}
+	minuteField = sField{name: "minute", min: 0, max: 59}
+	hourField   = sField{name: "hour", min: 0, max: 23}
+	domField    = sField{name: "day of month", min: 1, max: 31}
+	monthField  = sField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is accepted as an alias of Sunday
+	dowField = sField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	descriptors = map[string]string{
+		"@yearly":   "0 0 0 1 1 *",
+		"@annually": "0 0 0 1 1 *",
+		"@monthly":  "0 0 0 1 * *",
+		"@weekly":   "0 0 0 * * 0",
+		"@daily":    "0 0 0 * * *",
+		"@midnight": "0 0 0 * * *",
+		"@hourly":   "0 0 * * * *",
+	}
+)
+
+// SSchedule is a parsed cron expression
+type SSchedule struct {
+	expr string
+	loc  *time.Location
+
+	second uint64
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// day of month and day of week match any day when they are * or ?,
+	// otherwise a day matching either of them matches
+	domAny bool
+	dowAny bool
+}
+
+// Parse parses a standard 5-field (minute hour dom month dow) or 6-field
+// (second minute hour dom month dow) cron expression evaluated in UTC. The
+// expression may be prefixed by CRON_TZ=<zone> or TZ=<zone> to evaluate it in
+// the zone, and descriptors such as @daily are accepted.
+func Parse(expr string) (*SSchedule, error) {
+	return ParseInLocation(expr, time.UTC)
+}
+
+// LoadLocation returns the time zone of name, the cron expressions without
+// time zone are evaluated in the local time zone of the process
+func LoadLocation(name string) (*time.Location, error) {
+	if len(name) == 0 {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// ParseInLocation is like Parse but evaluates the expression in loc unless
+// the expression specifies its own time zone
+func ParseInLocation(expr string, loc *time.Location) (*SSchedule, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	spec := strings.TrimSpace(expr)
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if !strings.HasPrefix(spec, prefix) {
+			continue
+		}
+		i := strings.IndexAny(spec, " \t")
+		if i < 0 {
+			return nil, errors.Wrapf(ErrInvalidExpr, "missing fields after %s", spec)
+		}
+		var err error
+		loc, err = time.LoadLocation(spec[len(prefix):i])
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidExpr, "time zone %s: %v", spec[len(prefix):i], err)
+		}
+		spec = strings.TrimSpace(spec[i:])
+		break
+	}
+	if strings.HasPrefix(spec, "@") {
+		d, ok := descriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, errors.Wrapf(ErrInvalidExpr, "unknown descriptor %s", spec)
+		}
+		spec = d
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, errors.Wrapf(ErrInvalidExpr, "%q: expect 5 or 6 fields, got %d", expr, len(fields))
+	}
+
+	s := &SSchedule{expr: expr, loc: loc}
+	var err error
+	for _, p := range []struct {
+		bits  *uint64
+		field sField
+		value string
+	}{
+		{&s.second, secondField, fields[0]},
+		{&s.minute, minuteField, fields[1]},
+		{&s.hour, hourField, fields[2]},
+		{&s.dom, domField, fields[3]},
+		{&s.month, monthField, fields[4]},
+		{&s.dow, dowField, fields[5]},
+	} {
+		*p.bits, err = parseField(p.value, p.field)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%q", expr)
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = isAny(fields[3])
+	s.dowAny = isAny(fields[5])
+	return s, nil
+}
+
+func isAny(value string) bool {
+	return value == "*" || value == "?"
+}
+
+// parseField parses a comma separated list of values, ranges and steps such as
+// 1,5-10,*/15 into a bitset
+func parseField(value string, field sField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		start, end, step := field.min, field.max, 1
+		rangeStr := part
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, errors.Wrapf(ErrInvalidExpr, "%s: invalid step %q", field.name, part)
+			}
+			rangeStr = part[:i]
+		}
+		switch {
+		case isAny(rangeStr):
+		case strings.Contains(rangeStr, "-"):
+			bounds := strings.SplitN(rangeStr, "-", 2)
+			var err error
+			start, err = field.parseValue(bounds[0])
+			if err != nil {
+				return 0, err
+			}
+			end, err = field.parseValue(bounds[1])
+			if err != nil {
+				return 0, err
+			}
+		default:
+			v, err := field.parseValue(rangeStr)
+			if err != nil {
+				return 0, err
+			}
+			start = v
+			// a single value with a step like 5/15 runs from the value to max
+			if !strings.Contains(part, "/") {
+				end = v
+			}
+		}
+		if start > end {
+			return 0, errors.Wrapf(ErrInvalidExpr, "%s: invalid range %q", field.name, part)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (field sField) parseValue(value string) (int, error) {
+	if v, ok := field.names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.Wrapf(ErrInvalidExpr, "%s: invalid value %q", field.name, value)
+	}
+	if v < field.min || v > field.max {
+		return 0, errors.Wrapf(ErrInvalidExpr, "%s: %d out of range [%d, %d]", field.name, v, field.min, field.max)
+	}
+	return v, nil
+}
+
+func (s *SSchedule) String() string {
+	return s.expr
+}
+
+func (s *SSchedule) Location() *time.Location {
+	return s.loc
+}
+
+func (s *SSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next returns the first time matching the schedule strictly after t, in the
+// location of t. A zero time is returned if nothing matches in the following
+// years.
+func (s *SSchedule) Next(t time.Time) time.Time {
+	origLoc := t.Location()
+	t = t.In(s.loc)
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + searchYears
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, s.loc).AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.loc).AddDate(0, 0, 1)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, s.loc).Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, s.loc).Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+	for s.second&(1<<uint(t.Second())) == 0 {
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+	return t.In(origLoc)
+}
+
+// MinInterval returns the shortest interval between the next count runs
+// after t, it is used to reject expressions firing too often for a caller
+func (s *SSchedule) MinInterval(t time.Time, count int) (time.Duration, error) {
+	prev := s.Next(t)
+	if prev.IsZero() {
+		return 0, errors.Wrapf(ErrInvalidExpr, "%s never fires", s.expr)
+	}
+	min := time.Duration(0)
+	for i := 0; i < count; i++ {
+		next := s.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if d := next.Sub(prev); min == 0 || d < min {
+			min = d
+		}
+		prev = next
+	}
+	return min, nil
+}
+
+// Validate checks whether expr is a valid cron expression
+func Validate(expr string) error {
+	_, err := Parse(expr)
+	return err
+}
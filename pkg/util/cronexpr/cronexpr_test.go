@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cronexpr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	base := time.Date(2022, 3, 15, 10, 30, 20, 500, time.UTC)
+	cases := []struct {
+		expr string
+		want string
+	}{
+		{"* * * * *", "2022-03-15T10:31:00Z"},
+		{"*/15 * * * * *", "2022-03-15T10:30:30Z"},
+		{"0 2 * * *", "2022-03-16T02:00:00Z"},
+		{"30 10 * * *", "2022-03-16T10:30:00Z"},
+		{"0 0 1 * *", "2022-04-01T00:00:00Z"},
+		{"0 9 * * mon-fri", "2022-03-16T09:00:00Z"},
+		{"0 9 * * 7", "2022-03-20T09:00:00Z"},
+		{"0 0 13 * fri", "2022-03-18T00:00:00Z"},
+		{"0 0 29 2 *", "2024-02-29T00:00:00Z"},
+		{"5/20 * * * *", "2022-03-15T10:45:00Z"},
+		{"@monthly", "2022-04-01T00:00:00Z"},
+		{"CRON_TZ=Asia/Shanghai 0 8 * * *", "2022-03-16T00:00:00Z"},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", c.expr, err)
+			continue
+		}
+		got := s.Next(base).Format(time.RFC3339)
+		if got != c.want {
+			t.Errorf("Next(%q) want %s got %s", c.expr, c.want, got)
+		}
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if next := s.Next(time.Now()); !next.IsZero() {
+		t.Errorf("want zero time, got %s", next)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"10-5 * * * *",
+		"*/0 * * * *",
+		"@never",
+		"TZ=Mars/Olympus * * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) should fail", expr)
+		}
+	}
+}
+
+func TestMinInterval(t *testing.T) {
+	s, _ := Parse("0,10 * * * *")
+	d, err := s.MinInterval(time.Now(), 10)
+	if err != nil {
+		t.Fatalf("MinInterval: %v", err)
+	}
+	if d != 10*time.Minute {
+		t.Errorf("want 10m got %s", d)
+	}
+}
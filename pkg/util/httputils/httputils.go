@@ -43,6 +43,7 @@ import (
 	"yunion.io/x/pkg/utils"
 
 	"yunion.io/x/onecloud/pkg/appctx"
+	"yunion.io/x/onecloud/pkg/util/tracing"
 )
 
 type THttpMethod string
@@ -503,7 +504,7 @@ func requestInternal(client sClient, ctx context.Context, method THttpMethod, ur
 			return nil, nil, err
 		}
 		clientTrace = trace.StartClientTrace(clientTrace, addr, port, ctxData.ServiceName)
-		clientTrace.AddClientRequestHeader(header)
+		tracing.AddClientRequestHeader(clientTrace, header)
 	}
 
 	if len(ctxData.RequestId) > 0 {
@@ -569,6 +570,7 @@ func requestInternal(client sClient, ctx context.Context, method THttpMethod, ur
 	}
 	if clientTrace != nil {
 		clientTrace.EndClientTraceHeader(resp.Header)
+		tracing.SubmitTrace(clientTrace)
 	}
 
 	return req, resp, nil
@@ -0,0 +1,188 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/trace"
+)
+
+// span kinds of OTLP protocol
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindServer   = 2
+	otlpSpanKindClient   = 3
+)
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTracesRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+// sOtlpExporter sends spans to OTLP/HTTP endpoint in JSON encoding
+type sOtlpExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewOtlpExporter(endpoint string) ISpanExporter {
+	return &sOtlpExporter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func stringAttr(key, val string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpValue{StringValue: val}}
+}
+
+func toOtlpSpan(tr *trace.STrace) otlpSpan {
+	spanId, parentId := OtlpSpanIds(tr)
+	kind := otlpSpanKindInternal
+	switch tr.Kind {
+	case trace.TRACE_KIND_SERVER:
+		kind = otlpSpanKindServer
+	case trace.TRACE_KIND_CLIENT:
+		kind = otlpSpanKindClient
+	}
+	name := tr.Name
+	if len(name) == 0 {
+		name = string(tr.Kind)
+	}
+	attrs := make([]otlpKeyValue, 0)
+	if len(tr.RemoteEndpoint.ServiceName) > 0 {
+		attrs = append(attrs, stringAttr("peer.service", tr.RemoteEndpoint.ServiceName))
+	}
+	if len(tr.RemoteEndpoint.Addr) > 0 {
+		attrs = append(attrs, stringAttr("net.peer.name", tr.RemoteEndpoint.Addr))
+		attrs = append(attrs, stringAttr("net.peer.port", strconv.Itoa(tr.RemoteEndpoint.Port)))
+	}
+	attrs = append(attrs, stringAttr("yunion.trace_id", tr.TraceId))
+	attrs = append(attrs, stringAttr("yunion.span_id", tr.Id))
+	keys := make([]string, 0, len(tr.Tags))
+	for k := range tr.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attrs = append(attrs, stringAttr(k, tr.Tags[k]))
+	}
+	return otlpSpan{
+		TraceId:           OtlpTraceId(tr.TraceId),
+		SpanId:            spanId,
+		ParentSpanId:      parentId,
+		Name:              name,
+		Kind:              kind,
+		StartTimeUnixNano: strconv.FormatInt(tr.Timestamp.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(tr.Timestamp.Add(tr.Duration).UnixNano(), 10),
+		Attributes:        attrs,
+	}
+}
+
+func newOtlpTracesRequest(spans []*trace.STrace) *otlpTracesRequest {
+	services := make([]string, 0)
+	svcSpans := make(map[string][]otlpSpan)
+	for _, span := range spans {
+		svc := span.LocalEndpoint.ServiceName
+		if _, ok := svcSpans[svc]; !ok {
+			services = append(services, svc)
+		}
+		svcSpans[svc] = append(svcSpans[svc], toOtlpSpan(span))
+	}
+	req := &otlpTracesRequest{
+		ResourceSpans: make([]otlpResourceSpans, 0, len(services)),
+	}
+	for _, svc := range services {
+		req.ResourceSpans = append(req.ResourceSpans, otlpResourceSpans{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{stringAttr("service.name", svc)},
+			},
+			ScopeSpans: []otlpScopeSpans{
+				{
+					Scope: otlpScope{Name: "yunion.io/x/onecloud"},
+					Spans: svcSpans[svc],
+				},
+			},
+		})
+	}
+	return req
+}
+
+func (e *sOtlpExporter) ExportSpans(spans []*trace.STrace) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(newOtlpTracesRequest(spans))
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal")
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "post %s", e.endpoint)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("otlp endpoint %s response %d: %s", e.endpoint, resp.StatusCode, msg)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/trace"
+)
+
+const (
+	EXPORTER_NONE = "none"
+	EXPORTER_OTLP = "otlp"
+
+	// TRACE_KIND_INTERNAL is the kind of spans not crossing process boundary, e.g. task stages
+	TRACE_KIND_INTERNAL = trace.TraceKind("INTERNAL")
+
+	batchSize     = 512
+	queueSize     = 4096
+	flushInterval = 5 * time.Second
+)
+
+// ISpanExporter sends finished spans to a tracing backend
+type ISpanExporter interface {
+	ExportSpans(spans []*trace.STrace) error
+}
+
+type sBatchProcessor struct {
+	exporter ISpanExporter
+	queue    chan *trace.STrace
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+var (
+	processor     *sBatchProcessor
+	processorLock sync.RWMutex
+)
+
+// Init setups the exporter of finished spans, spans are dropped by default
+func Init(exporter string, otlpEndpoint string) {
+	switch exporter {
+	case EXPORTER_OTLP:
+		if len(otlpEndpoint) == 0 {
+			log.Errorf("tracing exporter otlp requires otlp endpoint, tracing disabled")
+			SetExporter(nil)
+			return
+		}
+		SetExporter(NewOtlpExporter(otlpEndpoint))
+	default:
+		SetExporter(nil)
+	}
+}
+
+// SetExporter replaces the current exporter, nil means no-op
+func SetExporter(exporter ISpanExporter) {
+	processorLock.Lock()
+	defer processorLock.Unlock()
+	if processor != nil {
+		processor.shutdown()
+		processor = nil
+	}
+	if exporter != nil {
+		processor = newBatchProcessor(exporter)
+	}
+}
+
+// Enabled returns whether finished spans are exported
+func Enabled() bool {
+	processorLock.RLock()
+	defer processorLock.RUnlock()
+	return processor != nil
+}
+
+// SubmitTrace queues a finished span to export, it never blocks and drops spans when queue is full
+func SubmitTrace(tr *trace.STrace) {
+	if tr == nil || tr.IsZero() {
+		return
+	}
+	processorLock.RLock()
+	defer processorLock.RUnlock()
+	if processor == nil {
+		return
+	}
+	span := *tr
+	select {
+	case processor.queue <- &span:
+	default:
+		log.Debugf("tracing queue full, drop span %s", tr.String())
+	}
+}
+
+// StartInternalTrace starts a span inside current process as child of parent,
+// e.g. a stage of async task resumed from the request context saved in task params
+func StartInternalTrace(parent *trace.STrace, spanId string, name string, serviceName string) *trace.STrace {
+	if parent == nil || parent.IsZero() {
+		return nil
+	}
+	return &trace.STrace{
+		TraceId:       parent.TraceId,
+		Name:          name,
+		ParentId:      parent.Id,
+		Id:            spanId,
+		Kind:          TRACE_KIND_INTERNAL,
+		Timestamp:     time.Now(),
+		Debug:         parent.Debug,
+		Shared:        parent.Shared,
+		LocalEndpoint: trace.STraceEndpoint{ServiceName: serviceName},
+	}
+}
+
+// EndTrace finishes span and submits it
+func EndTrace(tr *trace.STrace) {
+	if tr == nil {
+		return
+	}
+	tr.Duration = time.Now().Sub(tr.Timestamp)
+	SubmitTrace(tr)
+}
+
+// Shutdown flushes the queued spans
+func Shutdown() {
+	SetExporter(nil)
+}
+
+func newBatchProcessor(exporter ISpanExporter) *sBatchProcessor {
+	p := &sBatchProcessor{
+		exporter: exporter,
+		queue:    make(chan *trace.STrace, queueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *sBatchProcessor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	batch := make([]*trace.STrace, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.exporter.ExportSpans(batch); err != nil {
+			log.Warningf("export %d tracing spans: %v", len(batch), err)
+		}
+		batch = make([]*trace.STrace, 0, batchSize)
+	}
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.stop:
+			for {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *sBatchProcessor) shutdown() {
+	close(p.stop)
+	<-p.done
+}
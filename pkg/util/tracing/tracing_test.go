@@ -0,0 +1,127 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"yunion.io/x/pkg/trace"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	cases := []struct {
+		in      string
+		traceId string
+		spanId  string
+		sampled bool
+		ok      bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", false, true},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", "", "", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "", "", false, false},
+		{"00-4bf92f35-00f067aa0ba902b7-01", "", "", false, false},
+		{"", "", "", false, false},
+	}
+	for _, c := range cases {
+		traceId, spanId, sampled, ok := ParseTraceparent(c.in)
+		if traceId != c.traceId || spanId != c.spanId || sampled != c.sampled || ok != c.ok {
+			t.Errorf("ParseTraceparent(%q) = %q %q %v %v", c.in, traceId, spanId, sampled, ok)
+		}
+	}
+}
+
+func TestSpanIds(t *testing.T) {
+	server := &trace.STrace{TraceId: "abcd", Id: "0", Kind: trace.TRACE_KIND_SERVER,
+		RemoteEndpoint: trace.STraceEndpoint{ServiceName: trace.UNKNOWN_SERVICE_NAME}}
+	serverId, parent := OtlpSpanIds(server)
+	if parent != "" {
+		t.Errorf("root server span has parent %s", parent)
+	}
+
+	client := trace.StartClientTrace(server, "127.0.0.1", 8888, "region")
+	clientId, clientParent := OtlpSpanIds(client)
+	if clientParent != serverId {
+		t.Errorf("client span parent %s != server span %s", clientParent, serverId)
+	}
+
+	header := http.Header{}
+	AddClientRequestHeader(client, header)
+	traceId, spanId, _, ok := ParseTraceparent(header.Get(TRACEPARENT))
+	if !ok || traceId != OtlpTraceId("abcd") || spanId != clientId {
+		t.Errorf("traceparent %s mismatch client span %s", header.Get(TRACEPARENT), clientId)
+	}
+
+	remote := &trace.STrace{TraceId: client.TraceId, Id: client.Id, ParentId: client.ParentId, Kind: trace.TRACE_KIND_SERVER,
+		RemoteEndpoint: trace.STraceEndpoint{ServiceName: "region"}}
+	remoteId, remoteParent := OtlpSpanIds(remote)
+	if remoteParent != clientId || remoteId == clientId {
+		t.Errorf("remote server span %s parent %s, client %s", remoteId, remoteParent, clientId)
+	}
+
+	stage := StartInternalTrace(server, "0.task-OnInit", "Task.OnInit", "region")
+	_, stageParent := OtlpSpanIds(stage)
+	if stageParent != serverId {
+		t.Errorf("stage span parent %s != server span %s", stageParent, serverId)
+	}
+}
+
+func TestInjectServerRequestHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set(TRACEPARENT, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	InjectServerRequestHeader(header)
+	if header.Get(trace.X_YUNION_TRACE_ID) != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace id not injected: %v", header)
+	}
+	server := &trace.STrace{TraceId: header.Get(trace.X_YUNION_TRACE_ID), Id: header.Get(trace.X_YUNION_SPAN_ID), Kind: trace.TRACE_KIND_SERVER}
+	_, parent := OtlpSpanIds(server)
+	if parent != "00f067aa0ba902b7" {
+		t.Errorf("server span parent %s, want foreign span", parent)
+	}
+}
+
+func TestOtlpExporter(t *testing.T) {
+	reqs := make(chan otlpTracesRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		req := otlpTracesRequest{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("unmarshal %s: %v", body, err)
+		}
+		reqs <- req
+	}))
+	defer srv.Close()
+
+	SetExporter(NewOtlpExporter(srv.URL))
+	SubmitTrace(&trace.STrace{TraceId: "abcd", Id: "0", Name: "GET /servers", Kind: trace.TRACE_KIND_SERVER,
+		LocalEndpoint: trace.STraceEndpoint{ServiceName: "region"}})
+	Shutdown()
+
+	req := <-reqs
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans[0].Spans) != 1 {
+		t.Fatalf("unexpected request %#v", req)
+	}
+	span := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if span.Name != "GET /servers" || span.Kind != otlpSpanKindServer || span.TraceId != OtlpTraceId("abcd") {
+		t.Errorf("unexpected span %#v", span)
+	}
+	if Enabled() {
+		t.Errorf("exporter should be disabled after shutdown")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/trace"
+)
+
+// W3C trace context header, https://www.w3.org/TR/trace-context/
+const TRACEPARENT = "traceparent"
+
+var (
+	hex32Reg = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	hex16Reg = regexp.MustCompile(`^[0-9a-f]{16}$`)
+)
+
+func hashId(parts ...string) string {
+	sum := md5.Sum([]byte(strings.Join(parts, "/")))
+	return hex.EncodeToString(sum[:])
+}
+
+// OtlpTraceId converts trace id to the 16 bytes hex form of W3C trace context
+func OtlpTraceId(traceId string) string {
+	if hex32Reg.MatchString(traceId) {
+		return traceId
+	}
+	return hashId(traceId)
+}
+
+// span ids of yunion trace are hierarchical, e.g. 0.1.2, and shared by the client span
+// and the server span of a request, so they are hashed with trace id and span side.
+func clientSpanId(traceId, id string) string {
+	if hex16Reg.MatchString(id) {
+		// span from foreign w3c trace context
+		return id
+	}
+	return hashId(traceId, id, "client")[:16]
+}
+
+func localSpanId(traceId, id string) string {
+	return hashId(traceId, id, "local")[:16]
+}
+
+// OtlpSpanIds returns the 8 bytes hex span id and parent span id of a span
+func OtlpSpanIds(tr *trace.STrace) (string, string) {
+	switch tr.Kind {
+	case trace.TRACE_KIND_CLIENT:
+		parent := ""
+		if len(tr.ParentId) > 0 {
+			parent = localSpanId(tr.TraceId, tr.ParentId)
+		}
+		return clientSpanId(tr.TraceId, tr.Id), parent
+	case trace.TRACE_KIND_SERVER:
+		parent := ""
+		if tr.RemoteEndpoint.ServiceName != trace.UNKNOWN_SERVICE_NAME {
+			parent = clientSpanId(tr.TraceId, tr.Id)
+		}
+		return localSpanId(tr.TraceId, tr.Id), parent
+	default:
+		parent := ""
+		if len(tr.ParentId) > 0 {
+			parent = localSpanId(tr.TraceId, tr.ParentId)
+		}
+		return localSpanId(tr.TraceId, tr.Id), parent
+	}
+}
+
+// Traceparent returns W3C traceparent header value of client span
+func Traceparent(tr *trace.STrace) string {
+	flags := "00"
+	if tr.Debug {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", OtlpTraceId(tr.TraceId), clientSpanId(tr.TraceId, tr.Id), flags)
+}
+
+// AddClientRequestHeader adds both yunion and W3C trace headers of client span
+func AddClientRequestHeader(tr *trace.STrace, header http.Header) {
+	tr.AddClientRequestHeader(header)
+	header.Set(TRACEPARENT, Traceparent(tr))
+}
+
+// ParseTraceparent returns the trace id, parent span id and sampled flag of W3C traceparent header value
+func ParseTraceparent(val string) (string, string, bool, bool) {
+	parts := strings.Split(strings.TrimSpace(val), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return "", "", false, false
+	}
+	traceId, spanId, flags := parts[1], parts[2], parts[3]
+	if !hex32Reg.MatchString(traceId) || !hex16Reg.MatchString(spanId) || len(flags) != 2 {
+		return "", "", false, false
+	}
+	if traceId == strings.Repeat("0", 32) || spanId == strings.Repeat("0", 16) {
+		return "", "", false, false
+	}
+	flagsVal, err := strconv.ParseUint(flags, 16, 8)
+	if err != nil {
+		return "", "", false, false
+	}
+	return traceId, spanId, flagsVal&0x01 != 0, true
+}
+
+// InjectServerRequestHeader converts W3C traceparent of request from foreign callers
+// to yunion trace headers, so the server span joins the caller's trace
+func InjectServerRequestHeader(header http.Header) {
+	if len(header.Get(trace.X_YUNION_TRACE_ID)) > 0 {
+		return
+	}
+	traceId, spanId, sampled, ok := ParseTraceparent(header.Get(TRACEPARENT))
+	if !ok {
+		return
+	}
+	header.Set(trace.X_YUNION_TRACE_ID, traceId)
+	header.Set(trace.X_YUNION_SPAN_ID, spanId)
+	if sampled {
+		header.Set(trace.X_YUNION_TRACE_DEBUG, "true")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/ugorji/go/codec"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// COSE algorithm identifiers, https://www.iana.org/assignments/cose/cose.xhtml
+const (
+	COSE_ALG_ES256 = -7
+	COSE_ALG_EDDSA = -8
+	COSE_ALG_RS256 = -257
+
+	coseKeyKty = 1
+	coseKeyAlg = 3
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+// SupportedAlgorithms is the preference list of pubKeyCredParams
+var SupportedAlgorithms = []int{COSE_ALG_ES256, COSE_ALG_EDDSA, COSE_ALG_RS256}
+
+func cborHandle() *codec.CborHandle {
+	h := &codec.CborHandle{}
+	h.SignedInteger = true
+	return h
+}
+
+// decodeCBOR decodes the first CBOR item of data and returns the number of bytes consumed
+func decodeCBOR(data []byte, v interface{}) (int, error) {
+	dec := codec.NewDecoderBytes(data, cborHandle())
+	err := dec.Decode(v)
+	if err != nil {
+		return 0, errors.Wrap(ErrInvalidCBOR, err.Error())
+	}
+	return dec.NumBytesRead(), nil
+}
+
+type coseKey map[interface{}]interface{}
+
+func (k coseKey) int(label int64) (int64, bool) {
+	switch v := k[label].(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func (k coseKey) bytes(label int64) []byte {
+	v, _ := k[label].([]byte)
+	return v
+}
+
+// SPublicKey is the credential public key parsed from COSE_Key
+type SPublicKey struct {
+	Alg int64
+	Key crypto.PublicKey
+}
+
+// ParsePublicKey parses the COSE_Key encoded credential public key
+func ParsePublicKey(data []byte) (*SPublicKey, error) {
+	key := coseKey{}
+	_, err := decodeCBOR(data, &key)
+	if err != nil {
+		return nil, err
+	}
+	kty, _ := key.int(coseKeyKty)
+	alg, ok := key.int(coseKeyAlg)
+	if !ok {
+		return nil, errors.Wrap(ErrInvalidPublicKey, "missing alg")
+	}
+	ret := &SPublicKey{Alg: alg}
+	switch {
+	case kty == coseKtyEC2 && alg == COSE_ALG_ES256:
+		crv, _ := key.int(-1)
+		x, y := key.bytes(-2), key.bytes(-3)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.Wrap(ErrInvalidPublicKey, "invalid EC2 key")
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.Wrap(ErrInvalidPublicKey, "point not on curve")
+		}
+		ret.Key = pub
+	case kty == coseKtyRSA && alg == COSE_ALG_RS256:
+		n, e := key.bytes(-1), key.bytes(-2)
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.Wrap(ErrInvalidPublicKey, "invalid RSA key")
+		}
+		ret.Key = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	case kty == coseKtyOKP && alg == COSE_ALG_EDDSA:
+		crv, _ := key.int(-1)
+		x := key.bytes(-2)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.Wrap(ErrInvalidPublicKey, "invalid OKP key")
+		}
+		ret.Key = ed25519.PublicKey(x)
+	default:
+		return nil, errors.Wrapf(ErrUnsupportedAlgorithm, "kty %d alg %d", kty, alg)
+	}
+	return ret, nil
+}
+
+// Verify checks the signature of data
+func (k *SPublicKey) Verify(data, sig []byte) error {
+	valid := false
+	switch pub := k.Key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		valid = ecdsa.VerifyASN1(pub, digest[:], sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		valid = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(pub, data, sig)
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+)
+
+const (
+	ErrInvalidCBOR          = errors.Error("invalid cbor")
+	ErrInvalidPublicKey     = errors.Error("invalid public key")
+	ErrUnsupportedAlgorithm = errors.Error("unsupported algorithm")
+	ErrInvalidSignature     = errors.Error("invalid signature")
+	ErrInvalidClientData    = errors.Error("invalid client data")
+	ErrInvalidAuthData      = errors.Error("invalid authenticator data")
+	ErrChallengeMismatch    = errors.Error("challenge mismatch")
+	ErrOriginMismatch       = errors.Error("origin mismatch")
+	ErrRPIdMismatch         = errors.Error("rp id hash mismatch")
+	ErrUserNotPresent       = errors.Error("user not present")
+	ErrUserNotVerified      = errors.Error("user not verified")
+	ErrSignCountRollback    = errors.Error("sign count rollback, the authenticator may be cloned")
+)
+
+const (
+	CLIENT_DATA_TYPE_CREATE = "webauthn.create"
+	CLIENT_DATA_TYPE_GET    = "webauthn.get"
+
+	FLAG_USER_PRESENT  = 0x01
+	FLAG_USER_VERIFIED = 0x04
+	FLAG_ATTESTED_DATA = 0x40
+	FLAG_EXTENSION     = 0x80
+
+	challengeSize = 32
+)
+
+// NewChallenge returns a random challenge for a ceremony
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeSize)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return nil, errors.Wrap(err, "rand.Read")
+	}
+	return challenge, nil
+}
+
+// EncodeBase64URL encodes data as the unpadded base64url used by WebAuthn JSON
+func EncodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeBase64URL decodes base64url data with or without padding
+func DecodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// SRelyingParty describes the server which the credentials are scoped to
+type SRelyingParty struct {
+	// Id is the effective domain of the relying party, e.g. cloud.example.com
+	Id   string
+	Name string
+	// Origins are the allowed origins of client data, e.g. https://cloud.example.com
+	Origins []string
+}
+
+type SCollectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type SAuthenticatorData struct {
+	RPIdHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// attested credential data, only exists in registration
+	AAGUID              []byte
+	CredentialId        []byte
+	CredentialPublicKey []byte
+}
+
+func (d SAuthenticatorData) UserPresent() bool {
+	return d.Flags&FLAG_USER_PRESENT != 0
+}
+
+func (d SAuthenticatorData) UserVerified() bool {
+	return d.Flags&FLAG_USER_VERIFIED != 0
+}
+
+// ParseAuthenticatorData parses the authenticator data,
+// https://www.w3.org/TR/webauthn-2/#sctn-authenticator-data
+func ParseAuthenticatorData(data []byte) (*SAuthenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.Wrap(ErrInvalidAuthData, "too short")
+	}
+	ret := &SAuthenticatorData{
+		RPIdHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if ret.Flags&FLAG_ATTESTED_DATA == 0 {
+		return ret, nil
+	}
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, errors.Wrap(ErrInvalidAuthData, "attested credential data too short")
+	}
+	ret.AAGUID = rest[:16]
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLen {
+		return nil, errors.Wrap(ErrInvalidAuthData, "credential id too short")
+	}
+	ret.CredentialId = rest[:idLen]
+	rest = rest[idLen:]
+	var key interface{}
+	n, err := decodeCBOR(rest, &key)
+	if err != nil {
+		return nil, errors.Wrap(err, "credential public key")
+	}
+	ret.CredentialPublicKey = rest[:n]
+	return ret, nil
+}
+
+func (rp SRelyingParty) verifyClientData(clientDataJSON []byte, typ string, challenge []byte) error {
+	cd := SCollectedClientData{}
+	err := json.Unmarshal(clientDataJSON, &cd)
+	if err != nil {
+		return errors.Wrap(ErrInvalidClientData, err.Error())
+	}
+	if cd.Type != typ {
+		return errors.Wrapf(ErrInvalidClientData, "type %q", cd.Type)
+	}
+	got, err := DecodeBase64URL(cd.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+	if !utils.IsInStringArray(cd.Origin, rp.Origins) {
+		return errors.Wrapf(ErrOriginMismatch, "origin %q", cd.Origin)
+	}
+	return nil
+}
+
+func (rp SRelyingParty) verifyAuthData(ad *SAuthenticatorData, requireUV bool) error {
+	rpIdHash := sha256.Sum256([]byte(rp.Id))
+	if !bytes.Equal(ad.RPIdHash, rpIdHash[:]) {
+		return ErrRPIdMismatch
+	}
+	if !ad.UserPresent() {
+		return ErrUserNotPresent
+	}
+	if requireUV && !ad.UserVerified() {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+// SCredential is the registered public key credential of a user
+type SCredential struct {
+	Id        []byte
+	PublicKey []byte
+	SignCount uint32
+	AAGUID    []byte
+}
+
+type sAttestationObject struct {
+	Fmt      string                 `codec:"fmt"`
+	AttStmt  map[string]interface{} `codec:"attStmt"`
+	AuthData []byte                 `codec:"authData"`
+}
+
+// VerifyRegistration verifies the response of navigator.credentials.create()
+// and returns the new credential
+func (rp SRelyingParty) VerifyRegistration(clientDataJSON, attestationObject, challenge []byte, requireUV bool) (*SCredential, error) {
+	err := rp.verifyClientData(clientDataJSON, CLIENT_DATA_TYPE_CREATE, challenge)
+	if err != nil {
+		return nil, err
+	}
+	att := sAttestationObject{}
+	_, err = decodeCBOR(attestationObject, &att)
+	if err != nil {
+		return nil, errors.Wrap(err, "attestation object")
+	}
+	ad, err := ParseAuthenticatorData(att.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	err = rp.verifyAuthData(ad, requireUV)
+	if err != nil {
+		return nil, err
+	}
+	if len(ad.CredentialId) == 0 {
+		return nil, errors.Wrap(ErrInvalidAuthData, "missing attested credential data")
+	}
+	// make sure the public key is usable
+	_, err = ParsePublicKey(ad.CredentialPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &SCredential{
+		Id:        ad.CredentialId,
+		PublicKey: ad.CredentialPublicKey,
+		SignCount: ad.SignCount,
+		AAGUID:    ad.AAGUID,
+	}, nil
+}
+
+// VerifyAssertion verifies the response of navigator.credentials.get() with
+// the registered credential and returns the new sign count
+func (rp SRelyingParty) VerifyAssertion(cred SCredential, clientDataJSON, authenticatorData, signature, challenge []byte, requireUV bool) (uint32, error) {
+	err := rp.verifyClientData(clientDataJSON, CLIENT_DATA_TYPE_GET, challenge)
+	if err != nil {
+		return 0, err
+	}
+	ad, err := ParseAuthenticatorData(authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	err = rp.verifyAuthData(ad, requireUV)
+	if err != nil {
+		return 0, err
+	}
+	pub, err := ParsePublicKey(cred.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authenticatorData...), clientDataHash[:]...)
+	err = pub.Verify(signed, signature)
+	if err != nil {
+		return 0, err
+	}
+	// authenticators without counter always return 0
+	if (ad.SignCount != 0 || cred.SignCount != 0) && ad.SignCount <= cred.SignCount {
+		return 0, ErrSignCountRollback
+	}
+	return ad.SignCount, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+
+	"yunion.io/x/pkg/errors"
+)
+
+var testRP = SRelyingParty{
+	Id:      "cloud.example.com",
+	Name:    "Cloud",
+	Origins: []string{"https://cloud.example.com"},
+}
+
+func encodeCBOR(t *testing.T, v interface{}) []byte {
+	var out []byte
+	err := codec.NewEncoderBytes(&out, cborHandle()).Encode(v)
+	if err != nil {
+		t.Fatalf("encode cbor: %v", err)
+	}
+	return out
+}
+
+func fixedBytes(b []byte, size int) []byte {
+	ret := make([]byte, size)
+	copy(ret[size-len(b):], b)
+	return ret
+}
+
+func makeAuthData(rpId string, flags byte, count uint32, credId, pubKey []byte) []byte {
+	rpIdHash := sha256.Sum256([]byte(rpId))
+	data := append([]byte{}, rpIdHash[:]...)
+	data = append(data, flags)
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], count)
+	if credId != nil {
+		data = append(data, make([]byte, 16)...)
+		data = append(data, 0, 0)
+		binary.BigEndian.PutUint16(data[len(data)-2:], uint16(len(credId)))
+		data = append(data, credId...)
+		data = append(data, pubKey...)
+	}
+	return data
+}
+
+func makeClientData(t *testing.T, typ string, challenge []byte, origin string) []byte {
+	data, err := json.Marshal(SCollectedClientData{
+		Type:      typ,
+		Challenge: EncodeBase64URL(challenge),
+		Origin:    origin,
+	})
+	if err != nil {
+		t.Fatalf("marshal client data: %v", err)
+	}
+	return data
+}
+
+func TestRegisterAndAssert(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	pubKey := encodeCBOR(t, map[int]interface{}{
+		coseKeyKty: coseKtyEC2,
+		coseKeyAlg: COSE_ALG_ES256,
+		-1:         coseCrvP256,
+		-2:         fixedBytes(priv.X.Bytes(), 32),
+		-3:         fixedBytes(priv.Y.Bytes(), 32),
+	})
+	credId := []byte("credential-1")
+
+	challenge, _ := NewChallenge()
+	attObj := encodeCBOR(t, map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": makeAuthData(testRP.Id, FLAG_USER_PRESENT|FLAG_ATTESTED_DATA, 0, credId, pubKey),
+	})
+	clientData := makeClientData(t, CLIENT_DATA_TYPE_CREATE, challenge, testRP.Origins[0])
+	cred, err := testRP.VerifyRegistration(clientData, attObj, challenge, false)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	if string(cred.Id) != string(credId) {
+		t.Errorf("credential id mismatch: %q", cred.Id)
+	}
+	_, err = testRP.VerifyRegistration(clientData, attObj, challenge, true)
+	if errors.Cause(err) != ErrUserNotVerified {
+		t.Errorf("expect user not verified, got %v", err)
+	}
+	_, err = SRelyingParty{Id: "evil.com", Origins: testRP.Origins}.VerifyRegistration(clientData, attObj, challenge, false)
+	if errors.Cause(err) != ErrRPIdMismatch {
+		t.Errorf("expect rp id mismatch, got %v", err)
+	}
+
+	assert := func(count uint32, challenge []byte, origin string) (uint32, error) {
+		authData := makeAuthData(testRP.Id, FLAG_USER_PRESENT|FLAG_USER_VERIFIED, count, nil, nil)
+		clientData := makeClientData(t, CLIENT_DATA_TYPE_GET, challenge, origin)
+		hash := sha256.Sum256(clientData)
+		digest := sha256.Sum256(append(append([]byte{}, authData...), hash[:]...))
+		sig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return testRP.VerifyAssertion(*cred, clientData, authData, sig, challenge, true)
+	}
+
+	challenge, _ = NewChallenge()
+	count, err := assert(5, challenge, testRP.Origins[0])
+	if err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+	if count != 5 {
+		t.Errorf("expect sign count 5, got %d", count)
+	}
+	cred.SignCount = count
+	_, err = assert(5, challenge, testRP.Origins[0])
+	if errors.Cause(err) != ErrSignCountRollback {
+		t.Errorf("expect sign count rollback, got %v", err)
+	}
+	_, err = assert(6, challenge, "https://evil.com")
+	if errors.Cause(err) != ErrOriginMismatch {
+		t.Errorf("expect origin mismatch, got %v", err)
+	}
+	other, _ := NewChallenge()
+	authData := makeAuthData(testRP.Id, FLAG_USER_PRESENT, 7, nil, nil)
+	_, err = testRP.VerifyAssertion(*cred, makeClientData(t, CLIENT_DATA_TYPE_GET, other, testRP.Origins[0]), authData, []byte("bad"), challenge, false)
+	if errors.Cause(err) != ErrChallengeMismatch {
+		t.Errorf("expect challenge mismatch, got %v", err)
+	}
+	_, err = testRP.VerifyAssertion(*cred, makeClientData(t, CLIENT_DATA_TYPE_GET, challenge, testRP.Origins[0]), authData, []byte("bad"), challenge, false)
+	if errors.Cause(err) != ErrInvalidSignature {
+		t.Errorf("expect invalid signature, got %v", err)
+	}
+}
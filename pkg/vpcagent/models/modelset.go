@@ -51,6 +51,12 @@ type (
 	LoadbalancerNetworks  map[string]*LoadbalancerNetwork // key: networkId/loadbalancerId
 	LoadbalancerListeners map[string]*LoadbalancerListener
 	LoadbalancerAcls      map[string]*LoadbalancerAcl
+
+	NatGateways map[string]*NatGateway
+	NatSEntries map[string]*NatSEntry
+	NatDEntries map[string]*NatDEntry
+
+	VpcPeeringConnections map[string]*VpcPeeringConnection
 )
 
 func (set Vpcs) ModelManager() mcclient_modulebase.IBaseManager {
@@ -149,6 +155,24 @@ func (ms Vpcs) joinNetworks(subEntries Networks) bool {
 	return correct
 }
 
+func (ms Vpcs) joinNatGateways(subEntries NatGateways) bool {
+	for _, m := range ms {
+		m.NatGateways = NatGateways{}
+	}
+	for subId, subEntry := range subEntries {
+		m, ok := ms[subEntry.VpcId]
+		if !ok {
+			log.Warningf("natgateway %s(%s): vpc id %s not found",
+				subEntry.Name, subEntry.Id, subEntry.VpcId)
+			delete(subEntries, subId)
+			continue
+		}
+		subEntry.Vpc = m
+		m.NatGateways[subId] = subEntry
+	}
+	return true
+}
+
 func (set Wires) ModelManager() mcclient_modulebase.IBaseManager {
 	return &mcclient_modules.Wires
 }
@@ -981,3 +1005,157 @@ func (set LoadbalancerAcls) Copy() apihelper.IModelSet {
 	}
 	return setCopy
 }
+
+func (set NatGateways) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatGateways
+}
+
+func (set NatGateways) NewModel() db.IModel {
+	return &NatGateway{}
+}
+
+func (set NatGateways) AddModel(i db.IModel) {
+	m := i.(*NatGateway)
+	set[m.Id] = m
+}
+
+func (set NatGateways) Copy() apihelper.IModelSet {
+	setCopy := NatGateways{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set NatGateways) joinNatSEntries(subEntries NatSEntries, networks Networks) bool {
+	for _, m := range set {
+		m.NatSEntries = NatSEntries{}
+	}
+	correct := true
+	for subId, subEntry := range subEntries {
+		m, ok := set[subEntry.NatgatewayId]
+		if !ok {
+			log.Warningf("natsentry %s(%s): natgateway id %s not found",
+				subEntry.Name, subEntry.Id, subEntry.NatgatewayId)
+			correct = false
+			continue
+		}
+		subEntry.NatGateway = m
+		if subEntry.NetworkId != "" {
+			network, ok := networks[subEntry.NetworkId]
+			if !ok {
+				log.Warningf("natsentry %s(%s): network id %s not found",
+					subEntry.Name, subEntry.Id, subEntry.NetworkId)
+				correct = false
+				continue
+			}
+			subEntry.Network = network
+		}
+		m.NatSEntries[subId] = subEntry
+	}
+	return correct
+}
+
+func (set NatGateways) joinNatDEntries(subEntries NatDEntries) bool {
+	for _, m := range set {
+		m.NatDEntries = NatDEntries{}
+	}
+	correct := true
+	for subId, subEntry := range subEntries {
+		m, ok := set[subEntry.NatgatewayId]
+		if !ok {
+			log.Warningf("natdentry %s(%s): natgateway id %s not found",
+				subEntry.Name, subEntry.Id, subEntry.NatgatewayId)
+			correct = false
+			continue
+		}
+		subEntry.NatGateway = m
+		m.NatDEntries[subId] = subEntry
+	}
+	return correct
+}
+
+func (set NatSEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatSTable
+}
+
+func (set NatSEntries) NewModel() db.IModel {
+	return &NatSEntry{}
+}
+
+func (set NatSEntries) AddModel(i db.IModel) {
+	m := i.(*NatSEntry)
+	set[m.Id] = m
+}
+
+func (set NatSEntries) Copy() apihelper.IModelSet {
+	setCopy := NatSEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set NatDEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatDTable
+}
+
+func (set NatDEntries) NewModel() db.IModel {
+	return &NatDEntry{}
+}
+
+func (set NatDEntries) AddModel(i db.IModel) {
+	m := i.(*NatDEntry)
+	set[m.Id] = m
+}
+
+func (set NatDEntries) Copy() apihelper.IModelSet {
+	setCopy := NatDEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set VpcPeeringConnections) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.VpcPeeringConnections
+}
+
+func (set VpcPeeringConnections) NewModel() db.IModel {
+	return &VpcPeeringConnection{}
+}
+
+func (set VpcPeeringConnections) AddModel(i db.IModel) {
+	m := i.(*VpcPeeringConnection)
+	set[m.Id] = m
+}
+
+func (set VpcPeeringConnections) Copy() apihelper.IModelSet {
+	setCopy := VpcPeeringConnections{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set VpcPeeringConnections) joinVpcs(vpcs Vpcs) bool {
+	for id, m := range set {
+		vpc, ok := vpcs[m.VpcId]
+		if !ok {
+			log.Warningf("vpc peering connection %s(%s): vpc id %s not found",
+				m.Name, m.Id, m.VpcId)
+			delete(set, id)
+			continue
+		}
+		peerVpc, ok := vpcs[m.PeerVpcId]
+		if !ok {
+			log.Warningf("vpc peering connection %s(%s): peer vpc id %s not found",
+				m.Name, m.Id, m.PeerVpcId)
+			delete(set, id)
+			continue
+		}
+		m.Vpc = vpc
+		m.PeerVpc = peerVpc
+	}
+	return true
+}
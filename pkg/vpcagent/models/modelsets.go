@@ -46,6 +46,12 @@ type ModelSetsMaxUpdatedAt struct {
 	LoadbalancerNetworks  time.Time
 	LoadbalancerListeners time.Time
 	LoadbalancerAcls      time.Time
+
+	NatGateways time.Time
+	NatSEntries time.Time
+	NatDEntries time.Time
+
+	VpcPeeringConnections time.Time
 }
 
 func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
@@ -72,6 +78,12 @@ func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
 		LoadbalancerNetworks:  apihelper.PseudoZeroTime,
 		LoadbalancerListeners: apihelper.PseudoZeroTime,
 		LoadbalancerAcls:      apihelper.PseudoZeroTime,
+
+		NatGateways: apihelper.PseudoZeroTime,
+		NatSEntries: apihelper.PseudoZeroTime,
+		NatDEntries: apihelper.PseudoZeroTime,
+
+		VpcPeeringConnections: apihelper.PseudoZeroTime,
 	}
 }
 
@@ -99,6 +111,12 @@ type ModelSets struct {
 	LoadbalancerNetworks  LoadbalancerNetworks
 	LoadbalancerListeners LoadbalancerListeners
 	LoadbalancerAcls      LoadbalancerAcls
+
+	NatGateways NatGateways
+	NatSEntries NatSEntries
+	NatDEntries NatDEntries
+
+	VpcPeeringConnections VpcPeeringConnections
 }
 
 func NewModelSets() *ModelSets {
@@ -126,6 +144,12 @@ func NewModelSets() *ModelSets {
 		LoadbalancerNetworks:  LoadbalancerNetworks{},
 		LoadbalancerListeners: LoadbalancerListeners{},
 		LoadbalancerAcls:      LoadbalancerAcls{},
+
+		NatGateways: NatGateways{},
+		NatSEntries: NatSEntries{},
+		NatDEntries: NatDEntries{},
+
+		VpcPeeringConnections: VpcPeeringConnections{},
 	}
 }
 
@@ -154,6 +178,12 @@ func (mss *ModelSets) ModelSetList() []apihelper.IModelSet {
 		mss.LoadbalancerNetworks,
 		mss.LoadbalancerListeners,
 		mss.LoadbalancerAcls,
+
+		mss.NatGateways,
+		mss.NatSEntries,
+		mss.NatDEntries,
+
+		mss.VpcPeeringConnections,
 	}
 }
 
@@ -185,6 +215,12 @@ func (mss *ModelSets) copy_() *ModelSets {
 		LoadbalancerNetworks:  mss.LoadbalancerNetworks.Copy().(LoadbalancerNetworks),
 		LoadbalancerListeners: mss.LoadbalancerListeners.Copy().(LoadbalancerListeners),
 		LoadbalancerAcls:      mss.LoadbalancerAcls.Copy().(LoadbalancerAcls),
+
+		NatGateways: mss.NatGateways.Copy().(NatGateways),
+		NatSEntries: mss.NatSEntries.Copy().(NatSEntries),
+		NatDEntries: mss.NatDEntries.Copy().(NatDEntries),
+
+		VpcPeeringConnections: mss.VpcPeeringConnections.Copy().(VpcPeeringConnections),
 	}
 	return mssCopy
 }
@@ -266,6 +302,14 @@ func (mss *ModelSets) join() bool {
 	msg = append(msg, "mss.LoadbalancerNetworks.joinLoadbalancerListeners(mss.LoadbalancerListeners)")
 	p = append(p, mss.LoadbalancerListeners.joinLoadbalancerAcls(mss.LoadbalancerAcls))
 	msg = append(msg, "mss.LoadbalancerListeners.joinLoadbalancerAcls(mss.LoadbalancerAcls)")
+	p = append(p, mss.Vpcs.joinNatGateways(mss.NatGateways))
+	msg = append(msg, "mss.Vpcs.joinNatGateways(mss.NatGateways)")
+	p = append(p, mss.NatGateways.joinNatSEntries(mss.NatSEntries, mss.Networks))
+	msg = append(msg, "mss.NatGateways.joinNatSEntries(mss.NatSEntries, mss.Networks)")
+	p = append(p, mss.NatGateways.joinNatDEntries(mss.NatDEntries))
+	msg = append(msg, "mss.NatGateways.joinNatDEntries(mss.NatDEntries)")
+	p = append(p, mss.VpcPeeringConnections.joinVpcs(mss.Vpcs))
+	msg = append(msg, "mss.VpcPeeringConnections.joinVpcs(mss.Vpcs)")
 	ret := true
 	var failMsg []string
 	for i, b := range p {
@@ -57,6 +57,8 @@ func DumpOVNNorthbound(ctx context.Context, cli *ovnutil.OvnNbCtl) (*OVNNorthbou
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.NAT,
+		&db.LoadBalancer,
 	}
 	args := []string{"--format=json", "list", "<tbl>"}
 	for _, itbl := range itbls {
@@ -686,11 +688,15 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 func (keeper *OVNNorthboundKeeper) ClaimRoutes(ctx context.Context, vpc *agentmodels.Vpc, routes resolvedRoutes) error {
 	var irows []types.IRow
 	for _, route := range routes {
-		irows = append(irows, &ovn_nb.LogicalRouterStaticRoute{
+		irow := &ovn_nb.LogicalRouterStaticRoute{
 			Policy:   ptr("dst-ip"),
 			IpPrefix: route.Cidr,
 			Nexthop:  route.NextHop,
-		})
+		}
+		if route.OutputPort != "" {
+			irow.OutputPort = ptr(route.OutputPort)
+		}
+		irows = append(irows, irow)
 	}
 	ocVersion := fmt.Sprintf("%s.%d", vpc.UpdatedAt, vpc.UpdateVersion)
 	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
@@ -1089,6 +1095,8 @@ func (keeper *OVNNorthboundKeeper) Mark(ctx context.Context) {
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.NAT,
+		&db.LoadBalancer,
 	}
 	for _, itbl := range itbls {
 		for _, irow := range itbl.Rows() {
@@ -1107,6 +1115,7 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 		&db.LogicalRouter,
 		&db.DHCPOptions,
 		&db.DNS,
+		&db.LoadBalancer,
 	}
 	var irows []types.IRow
 	for _, itbl := range itbls {
@@ -1164,5 +1173,19 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 			keeper.cli.Must(ctx, "Sweep qos", args)
 		}
 	}
+	{ //  remove unused NAT rows
+		var args []string
+		for _, irow := range db.NAT.Rows() {
+			_, ok := irow.GetExternalId(externalKeyOcVersion)
+			if !ok {
+				for _, lr := range db.LogicalRouter.FindNATReferrer_nat(irow.OvsdbUuid()) {
+					args = append(args, "--", "--if-exists", "remove", "Logical_Router", lr.Name, "nat", irow.OvsdbUuid())
+				}
+			}
+		}
+		if len(args) > 0 {
+			keeper.cli.Must(ctx, "Sweep nat", args)
+		}
+	}
 	return nil
 }
@@ -114,3 +114,21 @@ func vipName(netId string, groupId string, ipaddr string) string {
 func lbpName(lbId string) string {
 	return fmt.Sprintf("iface/lb/%s", lbId)
 }
+
+// natDnatLbName returns Load_Balancer name for nat gateway dnat entry
+func natDnatLbName(entryId string) string {
+	return fmt.Sprintf("nat-d/%s", entryId)
+}
+
+// vpc peering
+func vpcPeeringLsName(peeringId string) string {
+	return fmt.Sprintf("vpc-peer/%s", peeringId)
+}
+
+func vpcPeeringRpName(peeringId string, vpcId string) string {
+	return fmt.Sprintf("vpc-rpeer/%s/%s", peeringId, vpcId)
+}
+
+func vpcPeeringPrName(peeringId string, vpcId string) string {
+	return fmt.Sprintf("vpc-peerr/%s/%s", peeringId, vpcId)
+}
@@ -0,0 +1,207 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/ovsdb/types"
+	"yunion.io/x/pkg/util/netutils"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func networkCidr(network *agentmodels.Network) (string, error) {
+	ipAddr, err := netutils.NewIPV4Addr(network.GuestGateway)
+	if err != nil {
+		return "", err
+	}
+	netAddr := ipAddr.NetAddr(network.GuestIpMask)
+	return fmt.Sprintf("%s/%d", netAddr, network.GuestIpMask), nil
+}
+
+func natSEntryToNAT(natgw *agentmodels.NatGateway, snat *agentmodels.NatSEntry) (*ovn_nb.NAT, error) {
+	logicalIp := snat.SourceCIDR
+	if logicalIp == "" {
+		if snat.Network == nil {
+			return nil, fmt.Errorf("neither source cidr nor network is set")
+		}
+		cidr, err := networkCidr(snat.Network)
+		if err != nil {
+			return nil, err
+		}
+		logicalIp = cidr
+	}
+	return &ovn_nb.NAT{
+		Type:       "snat",
+		ExternalIp: snat.IP,
+		LogicalIp:  logicalIp,
+		ExternalIds: map[string]string{
+			externalKeyOcRef: fmt.Sprintf("snat/%s/%s", natgw.Id, snat.Id),
+		},
+	}, nil
+}
+
+// natDEntryToLoadBalancer renders port forwarding rule as router load
+// balancer, for NAT rows of type dnat has no notion of port
+func natDEntryToLoadBalancer(natgw *agentmodels.NatGateway, dnat *agentmodels.NatDEntry) (*ovn_nb.LoadBalancer, error) {
+	proto := strings.ToLower(dnat.IpProtocol)
+	switch proto {
+	case "tcp", "udp":
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", dnat.IpProtocol)
+	}
+	return &ovn_nb.LoadBalancer{
+		Name:     natDnatLbName(dnat.Id),
+		Protocol: ptr(proto),
+		Vips: map[string]string{
+			fmt.Sprintf("%s:%d", dnat.ExternalIP, dnat.ExternalPort): fmt.Sprintf("%s:%d", dnat.InternalIP, dnat.InternalPort),
+		},
+		ExternalIds: map[string]string{
+			externalKeyOcRef: fmt.Sprintf("dnat/%s/%s", natgw.Id, dnat.Id),
+		},
+	}, nil
+}
+
+// ClaimNatgateway renders snat entries as NAT rows and dnat entries as load
+// balancers on the vpc router.  Translated traffic of the external addresses
+// is routed through eip gateway by vpc ext router
+func (keeper *OVNNorthboundKeeper) ClaimNatgateway(ctx context.Context, natgw *agentmodels.NatGateway) error {
+	var (
+		vpc       = natgw.Vpc
+		ocVersion = fmt.Sprintf("%s.%d", natgw.UpdatedAt, natgw.UpdateVersion)
+	)
+	if natgw.Status != apis.NAT_STAUTS_AVAILABLE {
+		return nil
+	}
+
+	var (
+		nats   []*ovn_nb.NAT
+		lbs    []*ovn_nb.LoadBalancer
+		extIps []string
+	)
+	extIpSet := map[string]struct{}{}
+	addExtIp := func(ip string) {
+		if _, ok := extIpSet[ip]; !ok {
+			extIpSet[ip] = struct{}{}
+			extIps = append(extIps, ip)
+		}
+	}
+	{
+		snats := make([]*agentmodels.NatSEntry, 0, len(natgw.NatSEntries))
+		for _, snat := range natgw.NatSEntries {
+			if snat.Status == apis.NAT_STAUTS_AVAILABLE {
+				snats = append(snats, snat)
+			}
+		}
+		sort.Slice(snats, func(i, j int) bool {
+			return snats[i].Id < snats[j].Id
+		})
+		for _, snat := range snats {
+			nat, err := natSEntryToNAT(natgw, snat)
+			if err != nil {
+				log.Errorf("converting natsentry %s(%s): %v", snat.Name, snat.Id, err)
+				continue
+			}
+			nats = append(nats, nat)
+			addExtIp(snat.IP)
+		}
+	}
+	{
+		dnats := make([]*agentmodels.NatDEntry, 0, len(natgw.NatDEntries))
+		for _, dnat := range natgw.NatDEntries {
+			if dnat.Status == apis.NAT_STAUTS_AVAILABLE {
+				dnats = append(dnats, dnat)
+			}
+		}
+		sort.Slice(dnats, func(i, j int) bool {
+			return dnats[i].Id < dnats[j].Id
+		})
+		for _, dnat := range dnats {
+			lb, err := natDEntryToLoadBalancer(natgw, dnat)
+			if err != nil {
+				log.Errorf("converting natdentry %s(%s): %v", dnat.Name, dnat.Id, err)
+				continue
+			}
+			lbs = append(lbs, lb)
+			addExtIp(dnat.ExternalIP)
+		}
+	}
+
+	var routes []*ovn_nb.LogicalRouterStaticRoute
+	for _, extIp := range extIps {
+		ocRef := fmt.Sprintf("nat-eip/%s/%s", vpc.Id, extIp)
+		routes = append(routes,
+			&ovn_nb.LogicalRouterStaticRoute{
+				Policy:     ptr("src-ip"),
+				IpPrefix:   extIp + "/32",
+				Nexthop:    apis.VpcEipGatewayIP3().String(),
+				OutputPort: ptr(vpcRepName(vpc.Id)),
+				ExternalIds: map[string]string{
+					externalKeyOcRef: ocRef,
+				},
+			},
+			&ovn_nb.LogicalRouterStaticRoute{
+				Policy:     ptr("dst-ip"),
+				IpPrefix:   extIp + "/32",
+				Nexthop:    apis.VpcInterExtIP1().String(),
+				OutputPort: ptr(vpcR2extpName(vpc.Id)),
+				ExternalIds: map[string]string{
+					externalKeyOcRef: ocRef,
+				},
+			},
+		)
+	}
+
+	var irows []types.IRow
+	for _, nat := range nats {
+		irows = append(irows, nat)
+	}
+	for _, lb := range lbs {
+		irows = append(irows, lb)
+	}
+	for _, route := range routes {
+		irows = append(irows, route)
+	}
+	if len(irows) == 0 {
+		return nil
+	}
+	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
+	if allFound {
+		return nil
+	}
+	for i, nat := range nats {
+		ref := fmt.Sprintf("nat%d", i)
+		args = append(args, ovnCreateArgs(nat, ref)...)
+		args = append(args, "--", "add", "Logical_Router", vpcLrName(vpc.Id), "nat", "@"+ref)
+	}
+	for i, lb := range lbs {
+		ref := fmt.Sprintf("lb%d", i)
+		args = append(args, ovnCreateArgs(lb, ref)...)
+		args = append(args, "--", "add", "Logical_Router", vpcLrName(vpc.Id), "load_balancer", "@"+ref)
+	}
+	for i, route := range routes {
+		ref := fmt.Sprintf("natRoute%d", i)
+		args = append(args, ovnCreateArgs(route, ref)...)
+		args = append(args, "--", "add", "Logical_Router", vpcExtLrName(vpc.Id), "static_routes", "@"+ref)
+	}
+	return keeper.cli.Must(ctx, "ClaimNatgateway", args)
+}
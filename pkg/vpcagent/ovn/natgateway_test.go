@@ -0,0 +1,83 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"testing"
+
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestNatEntries(t *testing.T) {
+	natgw := &agentmodels.NatGateway{}
+	natgw.Id = "natgw0"
+
+	network := &agentmodels.Network{}
+	network.GuestGateway = "192.168.10.1"
+	network.GuestIpMask = 24
+
+	t.Run("snat network", func(t *testing.T) {
+		snat := &agentmodels.NatSEntry{Network: network}
+		snat.Id = "snat0"
+		snat.IP = "10.168.1.10"
+		nat, err := natSEntryToNAT(natgw, snat)
+		if err != nil {
+			t.Fatalf("natSEntryToNAT: %v", err)
+		}
+		if nat.Type != "snat" || nat.LogicalIp != "192.168.10.0/24" || nat.ExternalIp != "10.168.1.10" {
+			t.Errorf("unexpected nat %#v", nat)
+		}
+	})
+	t.Run("snat source cidr", func(t *testing.T) {
+		snat := &agentmodels.NatSEntry{}
+		snat.Id = "snat1"
+		snat.IP = "10.168.1.10"
+		snat.SourceCIDR = "192.168.10.128/25"
+		nat, err := natSEntryToNAT(natgw, snat)
+		if err != nil {
+			t.Fatalf("natSEntryToNAT: %v", err)
+		}
+		if nat.LogicalIp != "192.168.10.128/25" {
+			t.Errorf("unexpected logical ip %s", nat.LogicalIp)
+		}
+	})
+	t.Run("snat no source", func(t *testing.T) {
+		snat := &agentmodels.NatSEntry{}
+		snat.Id = "snat2"
+		if _, err := natSEntryToNAT(natgw, snat); err == nil {
+			t.Errorf("expect error for snat without source")
+		}
+	})
+	t.Run("dnat", func(t *testing.T) {
+		dnat := &agentmodels.NatDEntry{}
+		dnat.Id = "dnat0"
+		dnat.IpProtocol = "TCP"
+		dnat.ExternalIP = "10.168.1.10"
+		dnat.ExternalPort = 2222
+		dnat.InternalIP = "192.168.10.2"
+		dnat.InternalPort = 22
+		lb, err := natDEntryToLoadBalancer(natgw, dnat)
+		if err != nil {
+			t.Fatalf("natDEntryToLoadBalancer: %v", err)
+		}
+		if *lb.Protocol != "tcp" || lb.Vips["10.168.1.10:2222"] != "192.168.10.2:22" {
+			t.Errorf("unexpected lb %#v", lb)
+		}
+		dnat.IpProtocol = "icmp"
+		if _, err := natDEntryToLoadBalancer(natgw, dnat); err == nil {
+			t.Errorf("expect error for protocol icmp")
+		}
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/ovsdb/types"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+	"yunion.io/x/onecloud/pkg/vpcagent/ovn/mac"
+)
+
+// vpcPeeringNextHop returns the next hop address and the output port on vpc
+// side of the peering link.  ok is false if vpc is not part of the peering
+func vpcPeeringNextHop(vpc *agentmodels.Vpc, peering *agentmodels.VpcPeeringConnection) (nextHop, outputPort string, ok bool) {
+	ip1, ip2 := apis.VpcPeeringLinkIPs(peering.Id)
+	switch vpc.Id {
+	case peering.VpcId:
+		return ip2.String(), vpcPeeringRpName(peering.Id, vpc.Id), true
+	case peering.PeerVpcId:
+		return ip1.String(), vpcPeeringRpName(peering.Id, vpc.Id), true
+	}
+	return "", "", false
+}
+
+func (keeper *OVNNorthboundKeeper) ClaimVpcPeering(ctx context.Context, peering *agentmodels.VpcPeeringConnection) error {
+	var (
+		peeringId = peering.Id
+		vpcId     = peering.VpcId
+		peerVpcId = peering.PeerVpcId
+		ip1, ip2  = apis.VpcPeeringLinkIPs(peeringId)
+		ocVersion = fmt.Sprintf("%s.%d", peering.UpdatedAt, peering.UpdateVersion)
+	)
+
+	peerLs := &ovn_nb.LogicalSwitch{
+		Name: vpcPeeringLsName(peeringId),
+	}
+	vpcRp := &ovn_nb.LogicalRouterPort{
+		Name:     vpcPeeringRpName(peeringId, vpcId),
+		Mac:      mac.HashVpcPeeringRouterPortMac(peeringId, vpcId),
+		Networks: []string{fmt.Sprintf("%s/%d", ip1, apis.VpcPeeringLinkMask)},
+	}
+	vpcPr := &ovn_nb.LogicalSwitchPort{
+		Name:      vpcPeeringPrName(peeringId, vpcId),
+		Type:      "router",
+		Addresses: []string{"router"},
+		Options: map[string]string{
+			"router-port": vpcRp.Name,
+		},
+	}
+	peerVpcRp := &ovn_nb.LogicalRouterPort{
+		Name:     vpcPeeringRpName(peeringId, peerVpcId),
+		Mac:      mac.HashVpcPeeringRouterPortMac(peeringId, peerVpcId),
+		Networks: []string{fmt.Sprintf("%s/%d", ip2, apis.VpcPeeringLinkMask)},
+	}
+	peerVpcPr := &ovn_nb.LogicalSwitchPort{
+		Name:      vpcPeeringPrName(peeringId, peerVpcId),
+		Type:      "router",
+		Addresses: []string{"router"},
+		Options: map[string]string{
+			"router-port": peerVpcRp.Name,
+		},
+	}
+	irows := []types.IRow{
+		peerLs,
+		vpcRp,
+		vpcPr,
+		peerVpcRp,
+		peerVpcPr,
+	}
+	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
+	if allFound {
+		return nil
+	}
+	args = append(args, ovnCreateArgs(peerLs, peerLs.Name)...)
+	args = append(args, ovnCreateArgs(vpcRp, vpcRp.Name)...)
+	args = append(args, ovnCreateArgs(vpcPr, vpcPr.Name)...)
+	args = append(args, ovnCreateArgs(peerVpcRp, peerVpcRp.Name)...)
+	args = append(args, ovnCreateArgs(peerVpcPr, peerVpcPr.Name)...)
+	args = append(args, "--", "add", "Logical_Switch", peerLs.Name, "ports", "@"+vpcPr.Name)
+	args = append(args, "--", "add", "Logical_Router", vpcLrName(vpcId), "ports", "@"+vpcRp.Name)
+	args = append(args, "--", "add", "Logical_Switch", peerLs.Name, "ports", "@"+peerVpcPr.Name)
+	args = append(args, "--", "add", "Logical_Router", vpcLrName(peerVpcId), "ports", "@"+peerVpcRp.Name)
+	return keeper.cli.Must(ctx, "ClaimVpcPeering", args)
+}
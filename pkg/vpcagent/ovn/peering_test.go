@@ -0,0 +1,76 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"testing"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestResolveVpcPeeringRoutes(t *testing.T) {
+	vpc := &agentmodels.Vpc{}
+	vpc.Id = "vpc0"
+	peerVpc := &agentmodels.Vpc{}
+	peerVpc.Id = "vpc1"
+
+	peering := &agentmodels.VpcPeeringConnection{Vpc: vpc, PeerVpc: peerVpc}
+	peering.Id = "peer0"
+	peering.VpcId = vpc.Id
+	peering.PeerVpcId = peerVpc.Id
+	peering.Status = apis.VPC_PEERING_CONNECTION_STATUS_ACTIVE
+
+	mss := agentmodels.NewModelSets()
+	mss.VpcPeeringConnections[peering.Id] = peering
+
+	routeTable := func(cidr string) *agentmodels.RouteTable {
+		rt := &agentmodels.RouteTable{}
+		rt.Routes = &apis.SRoutes{
+			{
+				Type:        apis.ROUTE_ENTRY_TYPE_PROPAGATE,
+				Cidr:        cidr,
+				NextHopType: apis.NEXT_HOP_TYPE_VPCPEERING,
+				NextHopId:   peering.Id,
+			},
+		}
+		return rt
+	}
+	vpc.RouteTable = routeTable("192.168.20.0/24")
+	peerVpc.RouteTable = routeTable("192.168.10.0/24")
+
+	ip1, ip2 := apis.VpcPeeringLinkIPs(peering.Id)
+	for _, c := range []struct {
+		vpc        *agentmodels.Vpc
+		nextHop    string
+		outputPort string
+	}{
+		{vpc, ip2.String(), vpcPeeringRpName(peering.Id, vpc.Id)},
+		{peerVpc, ip1.String(), vpcPeeringRpName(peering.Id, peerVpc.Id)},
+	} {
+		routes := resolveRoutes(c.vpc, mss)
+		if len(routes) != 1 {
+			t.Fatalf("vpc %s: want 1 route, got %d", c.vpc.Id, len(routes))
+		}
+		if routes[0].NextHop != c.nextHop || routes[0].OutputPort != c.outputPort {
+			t.Errorf("vpc %s: unexpected route %#v", c.vpc.Id, routes[0])
+		}
+	}
+
+	peering.Status = apis.VPC_PEERING_CONNECTION_STATUS_DELETING
+	if routes := resolveRoutes(vpc, mss); len(routes) != 0 {
+		t.Errorf("inactive peering: want no routes, got %d", len(routes))
+	}
+}
@@ -24,6 +24,7 @@ type resolvedRoutes []resolvedRoute
 type resolvedRoute struct {
 	Cidr         string
 	NextHop      string
+	OutputPort   string
 	Network      *agentmodels.Network
 	Guestnetwork *agentmodels.Guestnetwork
 }
@@ -61,6 +62,20 @@ func resolveRoutes(vpc *agentmodels.Vpc, mss *agentmodels.ModelSets) resolvedRou
 					Guestnetwork: gn,
 				})
 			}
+		case computeapis.NEXT_HOP_TYPE_VPCPEERING:
+			peering, ok := mss.VpcPeeringConnections[routeModel.NextHopId]
+			if !ok || peering.Status != computeapis.VPC_PEERING_CONNECTION_STATUS_ACTIVE {
+				break
+			}
+			nextHop, outputPort, ok := vpcPeeringNextHop(vpc, peering)
+			if !ok {
+				break
+			}
+			r = append(r, resolvedRoute{
+				Cidr:       routeModel.Cidr,
+				NextHop:    nextHop,
+				OutputPort: outputPort,
+			})
 		default:
 			return nil
 		}
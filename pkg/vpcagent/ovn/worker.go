@@ -173,9 +173,20 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 				ovndb.ClaimLoadbalancerNetwork(ctx, loadbalancerNetwork)
 			}
 		}
+		if vpcHasEipgw(vpc) {
+			for _, natgw := range vpc.NatGateways {
+				ovndb.ClaimNatgateway(ctx, natgw)
+			}
+		}
 		routes := resolveRoutes(vpc, mss)
 		ovndb.ClaimRoutes(ctx, vpc, routes)
 	}
+	for _, peering := range mss.VpcPeeringConnections {
+		if peering.Status != apis.VPC_PEERING_CONNECTION_STATUS_ACTIVE {
+			continue
+		}
+		ovndb.ClaimVpcPeering(ctx, peering)
+	}
 	for _, vpc := range mss.Vpcs {
 		if vpc.Id == apis.DEFAULT_VPC_ID {
 			continue
@@ -162,6 +162,9 @@ func OvnNbctlArgsDestroy(irows []types.IRow) []string {
 		case *ovn_nb.LogicalRouterStaticRoute:
 		case *ovn_nb.ACL:
 		case *ovn_nb.QoS:
+		case *ovn_nb.NAT:
+		case *ovn_nb.LoadBalancer:
+			newArgs = []string{"--", "--if-exists", "lb-del", irow.OvsdbUuid()}
 		default:
 			if !irow.OvsdbIsRoot() {
 				panic(irow.OvsdbTableName())
@@ -44,6 +44,8 @@ type BaseCommand struct {
 	s    *mcclient.ClientSession
 	name string
 	args []string
+
+	recordObject *recorder.Object
 }
 
 func NewBaseCommand(s *mcclient.ClientSession, name string, args ...string) *BaseCommand {
@@ -89,5 +91,11 @@ func (c BaseCommand) Cleanup() error {
 }
 
 func (c BaseCommand) GetRecordObject() *recorder.Object {
-	return nil
+	return c.recordObject
+}
+
+// SetRecordObject sets the object the session accesses, commands and
+// terminal stream of the session are recorded with it
+func (c *BaseCommand) SetRecordObject(obj *recorder.Object) {
+	c.recordObject = obj
 }
@@ -26,6 +26,7 @@ import (
 	webconsole_api "yunion.io/x/onecloud/pkg/apis/webconsole"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	o "yunion.io/x/onecloud/pkg/webconsole/options"
+	"yunion.io/x/onecloud/pkg/webconsole/recorder"
 )
 
 type K8sEnv struct {
@@ -134,12 +135,20 @@ func NewPodBashCommand(env *K8sEnv) ICommand {
 		shellRequest.Command = "env"
 	}
 
-	return NewKubectlCommand(env.Session, env.Kubeconfig, env.Namespace).Exec().
+	cmd := NewKubectlCommand(env.Session, env.Kubeconfig, env.Namespace).Exec().
 		Stdin().
 		TTY().
 		Pod(env.Pod).
 		Container(env.Container).
 		Command(shellRequest.Command, args...)
+	notes := map[string]string{
+		"cluster":   env.Cluster,
+		"namespace": env.Namespace,
+		"container": env.Container,
+	}
+	obj := recorder.NewObject(env.Pod, env.Pod, "pod", env.Container, jsonutils.Marshal(notes))
+	cmd.SetRecordObject(obj.SetSessionType(webconsole_api.SESSION_TYPE_KUBE))
+	return cmd
 }
 
 type KubectlLog struct {
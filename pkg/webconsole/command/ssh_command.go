@@ -27,6 +27,7 @@ import (
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
+	webconsole_api "yunion.io/x/onecloud/pkg/apis/webconsole"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
@@ -282,5 +283,5 @@ func (c *SSHtoolSol) GetRecordObject() *recorder.Object {
 	if user == "" {
 		user = ansible.PUBLIC_CLOUD_ANSIBLE_USER
 	}
-	return recorder.NewObject(id, name, c.objectType, user, jsonutils.Marshal(notes))
+	return recorder.NewObject(id, name, c.objectType, user, jsonutils.Marshal(notes)).SetSessionType(webconsole_api.SESSION_TYPE_SSH)
 }
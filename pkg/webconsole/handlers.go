@@ -16,13 +16,16 @@ package webconsole
 
 import (
 	"context"
+	"database/sql"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
 	webconsole_api "yunion.io/x/onecloud/pkg/apis/webconsole"
@@ -38,6 +41,7 @@ import (
 	"yunion.io/x/onecloud/pkg/webconsole/command"
 	"yunion.io/x/onecloud/pkg/webconsole/models"
 	o "yunion.io/x/onecloud/pkg/webconsole/options"
+	"yunion.io/x/onecloud/pkg/webconsole/recorder"
 	"yunion.io/x/onecloud/pkg/webconsole/session"
 )
 
@@ -55,8 +59,12 @@ func InitHandlers(app *appsrv.Application) {
 	app.AddHandler("POST", ApiPathPrefix+"ssh/<ip>", auth.Authenticate(handleSshShell))
 	app.AddHandler("POST", ApiPathPrefix+"server/<id>", auth.Authenticate(handleServerRemoteConsole))
 
+	// registered ahead of the model dispatcher to stream the raw recording
+	app.AddHandler("GET", ApiPathPrefix+"session_recordings/<id>/download", auth.Authenticate(handleSessionRecordingDownload))
+
 	for _, man := range []db.IModelManager{
 		models.GetCommandLogManager(),
+		models.GetSessionRecordingManager(),
 	} {
 		db.RegisterModelManager(man)
 		handler := db.NewModelHandler(man)
@@ -205,6 +213,15 @@ func handleBaremetalShell(ctx context.Context, w http.ResponseWriter, r *http.Re
 		httperrors.GeneralServerError(ctx, w, err)
 		return
 	}
+	hostName, _ := ret.GetString("name")
+	if len(hostName) == 0 {
+		hostName = hostId
+	}
+	notes := map[string]string{
+		"ip": info.IpAddr,
+	}
+	obj := recorder.NewObject(hostId, hostName, "host", info.Username, jsonutils.Marshal(notes))
+	cmd.SetRecordObject(obj.SetSessionType(webconsole_api.SESSION_TYPE_IPMI))
 	handleCommandSession(ctx, cmd, w)
 }
 
@@ -267,6 +284,37 @@ func handleCommandSession(ctx context.Context, cmd command.ICommand, w http.Resp
 	handleDataSession(ctx, session.WrapCommandSession(cmd), w, nil, false)
 }
 
+func handleSessionRecordingDownload(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	params, _, _ := appsrv.FetchEnv(ctx, w, r)
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+	obj, err := db.FetchByIdOrName(models.GetSessionRecordingManager(), userCred, params["<id>"])
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			httperrors.NotFoundError(ctx, w, "session recording %s not found", params["<id>"])
+			return
+		}
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	err = db.IsObjectRbacAllowed(ctx, obj, userCred, policy.PolicyActionGet, "cast")
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	recording := obj.(*models.SSessionRecording)
+	reader, err := recording.OpenCast(ctx)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	defer reader.Close()
+	w.Header().Set("Content-Type", webconsole_api.SESSION_RECORDING_CONTENT_TYPE)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", recording.Id+".cast"))
+	if _, err := io.Copy(w, reader); err != nil {
+		log.Errorf("send session recording %s: %v", recording.Id, err)
+	}
+}
+
 func sendJSON(w http.ResponseWriter, body jsonutils.JSONObject) {
 	ret := jsonutils.NewDict()
 	if body != nil {
@@ -33,7 +33,9 @@ var commandLogManager *SCommandLogManager
 type CommandType string
 
 const (
-	CommandTypeSSH = "ssh"
+	CommandTypeSSH  = "ssh"
+	CommandTypeKube = "kube"
+	CommandTypeIPMI = "ipmi"
 )
 
 func InitCommandLog() {
@@ -76,6 +78,8 @@ type SCommandLog struct {
 	StartTime  time.Time   `list:"user" create:"required"`
 	Ps1        string      `charset:"utf8" list:"user" create:"optional" json:"ps1"`
 	Command    CommandType `charset:"utf8" list:"user" create:"required"`
+	// 会话录像Id
+	RecordingId string `width:"128" charset:"ascii" list:"user" create:"optional"`
 }
 
 type CommandLogCreateInput struct {
@@ -99,6 +103,7 @@ type CommandLogCreateInput struct {
 	StartTime       time.Time
 	Ps1             string `json:"ps1"`
 	Command         string
+	RecordingId     string
 	Notes           jsonutils.JSONObject
 }
 
@@ -0,0 +1,180 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/s3cli"
+
+	api "yunion.io/x/onecloud/pkg/apis/webconsole"
+	o "yunion.io/x/onecloud/pkg/webconsole/options"
+)
+
+// IRecordingStorage keeps the finished session recordings
+type IRecordingStorage interface {
+	GetType() string
+	// Save moves the finished recording file into the storage and returns
+	// its location in the storage
+	Save(ctx context.Context, filename string) (string, error)
+	Open(ctx context.Context, location string) (io.ReadCloser, error)
+	Remove(ctx context.Context, location string) error
+}
+
+var (
+	objectStorage     *sObjectRecordingStorage
+	objectStorageLock = &sync.Mutex{}
+)
+
+// GetRecordingStorage returns the recording storage of the type
+func GetRecordingStorage(storageType string) (IRecordingStorage, error) {
+	switch storageType {
+	case api.SESSION_RECORDING_STORAGE_LOCAL, "":
+		return &sLocalRecordingStorage{dir: o.Options.SessionRecordingLocalPath}, nil
+	case api.SESSION_RECORDING_STORAGE_OBJECT:
+		objectStorageLock.Lock()
+		defer objectStorageLock.Unlock()
+		if objectStorage == nil {
+			storage, err := newObjectRecordingStorage(
+				o.Options.SessionRecordingObjectEndpoint,
+				o.Options.SessionRecordingObjectBucket,
+				o.Options.SessionRecordingObjectAccessKey,
+				o.Options.SessionRecordingObjectSecret,
+			)
+			if err != nil {
+				return nil, err
+			}
+			objectStorage = storage
+		}
+		return objectStorage, nil
+	}
+	return nil, errors.Wrapf(errors.ErrNotSupported, "recording storage %s", storageType)
+}
+
+type sLocalRecordingStorage struct {
+	dir string
+}
+
+func (s *sLocalRecordingStorage) GetType() string {
+	return api.SESSION_RECORDING_STORAGE_LOCAL
+}
+
+func (s *sLocalRecordingStorage) Save(ctx context.Context, filename string) (string, error) {
+	location := path.Join(s.dir, filepath.Base(filename))
+	if location == filename {
+		// recorded in place
+		return location, nil
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", errors.Wrapf(err, "mkdir %s", s.dir)
+	}
+	if err := os.Rename(filename, location); err != nil {
+		return "", errors.Wrapf(err, "rename %s to %s", filename, location)
+	}
+	return location, nil
+}
+
+func (s *sLocalRecordingStorage) Open(ctx context.Context, location string) (io.ReadCloser, error) {
+	f, err := os.Open(location)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Wrapf(errors.ErrNotFound, "recording %s", location)
+		}
+		return nil, errors.Wrapf(err, "open %s", location)
+	}
+	return f, nil
+}
+
+func (s *sLocalRecordingStorage) Remove(ctx context.Context, location string) error {
+	if err := os.Remove(location); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "remove %s", location)
+	}
+	return nil
+}
+
+// sObjectRecordingStorage uploads the recordings staged in
+// SessionRecordingLocalPath to a bucket of an S3 compatible object storage
+type sObjectRecordingStorage struct {
+	bucket string
+	client *s3cli.Client
+}
+
+func newObjectRecordingStorage(endpoint, bucket, accessKey, secret string) (*sObjectRecordingStorage, error) {
+	if len(endpoint) == 0 || len(bucket) == 0 {
+		return nil, errors.Wrap(errors.ErrInvalidStatus, "session recording object storage endpoint or bucket is not configured")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid object storage endpoint %s", endpoint)
+	}
+	host, secure := u.Host, u.Scheme == "https"
+	if len(host) == 0 {
+		// endpoint without scheme
+		host, secure = endpoint, false
+	}
+	cli, err := s3cli.New(host, accessKey, secret, secure, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3cli.New")
+	}
+	return &sObjectRecordingStorage{
+		bucket: bucket,
+		client: cli,
+	}, nil
+}
+
+func (s *sObjectRecordingStorage) GetType() string {
+	return api.SESSION_RECORDING_STORAGE_OBJECT
+}
+
+func (s *sObjectRecordingStorage) Save(ctx context.Context, filename string) (string, error) {
+	key := path.Join("recordings", filepath.Base(filename))
+	opts := s3cli.PutObjectOptions{
+		ContentType: api.SESSION_RECORDING_CONTENT_TYPE,
+	}
+	if _, err := s.client.FPutObjectWithContext(ctx, s.bucket, key, filename, opts); err != nil {
+		return "", errors.Wrapf(err, "upload %s to %s", filename, key)
+	}
+	if err := os.Remove(filename); err != nil {
+		return "", errors.Wrapf(err, "remove uploaded %s", filename)
+	}
+	return key, nil
+}
+
+func (s *sObjectRecordingStorage) Open(ctx context.Context, location string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObjectWithContext(ctx, s.bucket, location, s3cli.GetObjectOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetObject %s", location)
+	}
+	return obj, nil
+}
+
+func (s *sObjectRecordingStorage) Remove(ctx context.Context, location string) error {
+	err := s.client.RemoveObject(s.bucket, location)
+	if err != nil {
+		switch s3cli.ToErrorResponse(err).Code {
+		case "NoSuchKey", "NotFound":
+			return nil
+		}
+		return errors.Wrapf(err, "RemoveObject %s", location)
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/webconsole"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+	o "yunion.io/x/onecloud/pkg/webconsole/options"
+)
+
+var sessionRecordingManager *SSessionRecordingManager
+
+func GetSessionRecordingManager() *SSessionRecordingManager {
+	if sessionRecordingManager != nil {
+		return sessionRecordingManager
+	}
+	sessionRecordingManager = &SSessionRecordingManager{
+		SUserResourceBaseManager: db.NewUserResourceBaseManager(
+			SSessionRecording{},
+			"session_recordings_tbl",
+			"session_recording",
+			"session_recordings",
+		),
+	}
+	sessionRecordingManager.SetVirtualObject(sessionRecordingManager)
+	return sessionRecordingManager
+}
+
+type SSessionRecordingManager struct {
+	db.SUserResourceBaseManager
+	db.SStatusResourceBaseManager
+}
+
+// SSessionRecording is an asciicast v2 recording of the raw terminal stream
+// of a webconsole session
+type SSessionRecording struct {
+	db.SUserResourceBase
+	db.SStatusResourceBase
+
+	// webconsole 会话Id
+	SessionId string `width:"128" charset:"ascii" index:"true" list:"user"`
+	// 被访问资源的Id
+	ObjId string `width:"128" charset:"ascii" list:"user"`
+	// 被访问资源的名称
+	ObjName string `width:"128" charset:"utf8" list:"user"`
+	// 被访问资源的类型, 例如: server, host, pod
+	ObjType string `width:"40" charset:"ascii" list:"user"`
+	// 会话类型
+	// enum: ssh, kube, ipmi
+	SessionType string `width:"16" charset:"ascii" list:"user"`
+	// 登录用户
+	LoginUser string `width:"128" charset:"utf8" list:"user"`
+	// 会话所属项目
+	TenantId string `width:"128" charset:"ascii" list:"user"`
+	Tenant   string `width:"128" charset:"utf8" list:"user"`
+
+	// 终端列数
+	Width int `list:"user"`
+	// 终端行数
+	Height int `list:"user"`
+
+	StartTime time.Time `list:"user"`
+	EndTime   time.Time `nullable:"true" list:"user"`
+
+	// 录像大小(字节)
+	SizeBytes int64 `list:"user"`
+	// 录像存储类型
+	// enum: local, object
+	StorageType string `width:"16" charset:"ascii" list:"user"`
+	// 录像在存储中的位置
+	Location string `width:"512" charset:"utf8" list:"admin"`
+}
+
+type SessionRecordingCreateInput struct {
+	SessionId   string
+	ObjId       string
+	ObjName     string
+	ObjType     string
+	SessionType string
+	LoginUser   string
+	Width       int
+	Height      int
+	StartTime   time.Time
+	StorageType string
+}
+
+func (m *SSessionRecordingManager) InitializeData() error {
+	// recordings of sessions interrupted by restart will never be finished
+	q := m.Query().Equals("status", api.SESSION_RECORDING_STATUS_RECORDING)
+	recordings := make([]SSessionRecording, 0)
+	err := db.FetchModelObjects(m, q, &recordings)
+	if err != nil {
+		return errors.Wrap(err, "FetchModelObjects")
+	}
+	for i := range recordings {
+		_, err := db.Update(&recordings[i], func() error {
+			recordings[i].Status = api.SESSION_RECORDING_STATUS_FAILED
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "update recording %s", recordings[i].Id)
+		}
+	}
+	return nil
+}
+
+// Create inserts the recording of a session which starts to be recorded
+func (m *SSessionRecordingManager) Create(ctx context.Context, userCred mcclient.TokenCredential, input *SessionRecordingCreateInput) (*SSessionRecording, error) {
+	recording := &SSessionRecording{}
+	recording.SetModelManager(m, recording)
+	recording.Name = input.SessionId
+	if len(input.ObjName) > 0 {
+		recording.Name = input.ObjName + "-" + input.StartTime.Format("20060102150405")
+	}
+	recording.OwnerId = userCred.GetUserId()
+	recording.Status = api.SESSION_RECORDING_STATUS_RECORDING
+	recording.SessionId = input.SessionId
+	recording.ObjId = input.ObjId
+	recording.ObjName = input.ObjName
+	recording.ObjType = input.ObjType
+	recording.SessionType = input.SessionType
+	recording.LoginUser = input.LoginUser
+	recording.TenantId = userCred.GetProjectId()
+	recording.Tenant = userCred.GetProjectName()
+	recording.Width = input.Width
+	recording.Height = input.Height
+	recording.StartTime = input.StartTime
+	recording.StorageType = input.StorageType
+	err := m.TableSpec().Insert(ctx, recording)
+	if err != nil {
+		return nil, errors.Wrap(err, "Insert")
+	}
+	return recording, nil
+}
+
+func (m *SSessionRecordingManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, httperrors.NewUnsupportOperationError("session recordings are created by webconsole sessions")
+}
+
+func (m *SSessionRecordingManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.SessionRecordingListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := m.SUserResourceBaseManager.ListItemFilter(ctx, q, userCred, query.UserResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SUserResourceBaseManager.ListItemFilter")
+	}
+	q, err = m.SStatusResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusResourceBaseManager.ListItemFilter")
+	}
+	if len(query.SessionId) > 0 {
+		q = q.In("session_id", query.SessionId)
+	}
+	if len(query.ObjId) > 0 {
+		q = q.In("obj_id", query.ObjId)
+	}
+	if len(query.ObjType) > 0 {
+		q = q.In("obj_type", query.ObjType)
+	}
+	if len(query.SessionType) > 0 {
+		q = q.In("session_type", query.SessionType)
+	}
+	return q, nil
+}
+
+func (m *SSessionRecordingManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.SessionRecordingListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := m.SUserResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.UserResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SUserResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (m *SSessionRecordingManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := m.SUserResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (m *SSessionRecordingManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.SessionRecordingDetails {
+	rows := make([]api.SessionRecordingDetails, len(objs))
+	userRows := m.SUserResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		recording := objs[i].(*SSessionRecording)
+		rows[i] = api.SessionRecordingDetails{
+			UserResourceDetails: userRows[i],
+		}
+		if !recording.EndTime.IsZero() {
+			rows[i].Duration = recording.EndTime.Sub(recording.StartTime).Seconds()
+		}
+	}
+	return rows
+}
+
+// Finish saves the recorded file into the recording storage
+func (r *SSessionRecording) Finish(ctx context.Context, filename string, sizeBytes int64) error {
+	status := api.SESSION_RECORDING_STATUS_READY
+	storage, err := GetRecordingStorage(r.StorageType)
+	var location string
+	if err == nil {
+		location, err = storage.Save(ctx, filename)
+	}
+	if err != nil {
+		log.Errorf("save recording %s of session %s: %v", filename, r.SessionId, err)
+		status = api.SESSION_RECORDING_STATUS_FAILED
+	}
+	_, uerr := db.Update(r, func() error {
+		r.Status = status
+		r.EndTime = time.Now()
+		r.SizeBytes = sizeBytes
+		r.Location = location
+		return nil
+	})
+	if uerr != nil {
+		return errors.Wrap(uerr, "update recording")
+	}
+	return err
+}
+
+// MarkFailed marks the recording failed when the session can't be recorded
+func (r *SSessionRecording) MarkFailed() error {
+	_, err := db.Update(r, func() error {
+		r.Status = api.SESSION_RECORDING_STATUS_FAILED
+		r.EndTime = time.Now()
+		return nil
+	})
+	return err
+}
+
+// OpenCast opens the asciicast v2 content of the recording
+func (r *SSessionRecording) OpenCast(ctx context.Context) (io.ReadCloser, error) {
+	if r.Status != api.SESSION_RECORDING_STATUS_READY {
+		return nil, httperrors.NewInvalidStatusError("recording is %s", r.Status)
+	}
+	storage, err := GetRecordingStorage(r.StorageType)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Open(ctx, r.Location)
+}
+
+const (
+	castPageDefaultLimit = 1000
+	castPageMaxLimit     = 10000
+)
+
+// GetDetailsCast returns a page of the recording events for the web
+// player, the whole file is streamed by the download handler
+func (r *SSessionRecording) GetDetailsCast(ctx context.Context, userCred mcclient.TokenCredential, query api.SessionRecordingCastInput) (api.SessionRecordingCastOutput, error) {
+	output := api.SessionRecordingCastOutput{
+		StartTime: r.StartTime,
+	}
+	reader, err := r.OpenCast(ctx)
+	if err != nil {
+		return output, err
+	}
+	defer reader.Close()
+	return readCastPage(reader, query.Offset, query.Limit, output)
+}
+
+func readCastPage(reader io.Reader, offset, limit int, output api.SessionRecordingCastOutput) (api.SessionRecordingCastOutput, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = castPageDefaultLimit
+	} else if limit > castPageMaxLimit {
+		limit = castPageMaxLimit
+	}
+	output.Events = make([]string, 0)
+	output.NextOffset = -1
+	br := bufio.NewReader(reader)
+	// the first line is the header, events are indexed from 0
+	index := -1
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return output, errors.Wrap(err, "read recording")
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if len(line) > 0 {
+			switch {
+			case index < 0:
+				output.Header = line
+			case index >= offset+limit:
+				output.NextOffset = offset + limit
+				return output, nil
+			case index >= offset:
+				output.Events = append(output.Events, line)
+			}
+			index++
+		}
+		if err == io.EOF {
+			return output, nil
+		}
+	}
+}
+
+func (r *SSessionRecording) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if r.Status == api.SESSION_RECORDING_STATUS_RECORDING {
+		return httperrors.NewInvalidStatusError("session %s is still being recorded", r.SessionId)
+	}
+	return r.SUserResourceBase.ValidateDeleteCondition(ctx, info)
+}
+
+func (r *SSessionRecording) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	if err := r.removeCast(ctx); err != nil {
+		return err
+	}
+	return r.SUserResourceBase.CustomizeDelete(ctx, userCred, query, data)
+}
+
+func (r *SSessionRecording) removeCast(ctx context.Context) error {
+	if len(r.Location) == 0 {
+		return nil
+	}
+	storage, err := GetRecordingStorage(r.StorageType)
+	if err != nil {
+		return err
+	}
+	return storage.Remove(ctx, r.Location)
+}
+
+// CleanExpiredRecordings removes the recordings older than the retention
+func (m *SSessionRecordingManager) CleanExpiredRecordings(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	days := o.Options.SessionRecordingRetentionDays
+	if days <= 0 {
+		return
+	}
+	q := m.Query().LT("start_time", time.Now().AddDate(0, 0, -days))
+	q = q.NotEquals("status", api.SESSION_RECORDING_STATUS_RECORDING)
+	recordings := make([]SSessionRecording, 0)
+	err := db.FetchModelObjects(m, q, &recordings)
+	if err != nil {
+		log.Errorf("fetch expired session recordings: %v", err)
+		return
+	}
+	for i := range recordings {
+		recording := &recordings[i]
+		if err := recording.removeCast(ctx); err != nil {
+			log.Errorf("remove expired session recording %s: %v", recording.Id, err)
+			continue
+		}
+		if err := recording.Delete(ctx, userCred); err != nil {
+			log.Errorf("delete expired session recording %s: %v", recording.Id, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/webconsole"
+)
+
+func TestReadCastPage(t *testing.T) {
+	cast := `{"version":2}
+[0.1,"o","a"]
+[0.2,"o","b"]
+
+[0.3,"o","c"]
+`
+	cases := []struct {
+		offset int
+		limit  int
+		events []string
+		next   int
+	}{
+		{0, 0, []string{`[0.1,"o","a"]`, `[0.2,"o","b"]`, `[0.3,"o","c"]`}, -1},
+		{0, 2, []string{`[0.1,"o","a"]`, `[0.2,"o","b"]`}, 2},
+		{2, 2, []string{`[0.3,"o","c"]`}, -1},
+		{3, 2, []string{}, -1},
+	}
+	for _, c := range cases {
+		page, err := readCastPage(strings.NewReader(cast), c.offset, c.limit, api.SessionRecordingCastOutput{})
+		if err != nil {
+			t.Fatalf("readCastPage: %v", err)
+		}
+		if page.Header != `{"version":2}` {
+			t.Errorf("offset %d: header %q", c.offset, page.Header)
+		}
+		if !reflect.DeepEqual(page.Events, c.events) || page.NextOffset != c.next {
+			t.Errorf("offset %d limit %d: got %v next %d, want %v next %d", c.offset, c.limit, page.Events, page.NextOffset, c.events, c.next)
+		}
+	}
+}
@@ -34,6 +34,16 @@ type WebConsoleOptions struct {
 	EnableAutoLogin   bool   `help:"allow webconsole to log in directly with the cloudroot public key" default:"false"`
 	ApsaraConsoleAddr string `help:"Apsara console addr" default:"https://xxxx.com.cn/module/ecs/vnc/index.html"`
 	AliyunVncVersion  string `help:"Aliyun vnc version" default:"0.0.8"`
+
+	EnableSessionRecording          bool   `help:"record the raw terminal stream of ssh, kube and ipmi sessions as asciicast v2 files" default:"false"`
+	SessionRecordingStorage         string `help:"where session recordings are stored" choices:"local|object" default:"local"`
+	SessionRecordingLocalPath       string `help:"directory session recordings are written to, recordings are staged here before uploaded to object storage" default:"/opt/cloud/workspace/webconsole/recordings"`
+	SessionRecordingObjectEndpoint  string `help:"object storage endpoint of session recordings, e.g. https://s3.example.com"`
+	SessionRecordingObjectBucket    string `help:"object storage bucket of session recordings"`
+	SessionRecordingObjectAccessKey string `help:"object storage access key of session recordings"`
+	SessionRecordingObjectSecret    string `help:"object storage secret of session recordings"`
+	SessionRecordingRetentionDays   int    `help:"days session recordings are kept, 0 keeps them forever" default:"180"`
+	SessionRecordingMaxSizeMb       int    `help:"maximal size of a single session recording, the recording stops when it is exceeded" default:"100"`
 }
 
 func OnOptionsChange(oldO, newO interface{}) bool {
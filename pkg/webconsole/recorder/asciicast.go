@@ -0,0 +1,282 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recorder
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/webconsole/models"
+	o "yunion.io/x/onecloud/pkg/webconsole/options"
+)
+
+const (
+	castVersion = 2
+
+	castEventOutput = "o"
+	castEventResize = "r"
+
+	defaultCastWidth  = 80
+	defaultCastHeight = 24
+)
+
+// castHeader is the first line of an asciicast v2 file
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// castWriter writes the terminal stream as asciicast v2, the header is
+// written with the latest terminal size before the first event
+type castWriter struct {
+	w       io.Writer
+	header  castHeader
+	start   time.Time
+	started bool
+	// pending holds the bytes of an utf-8 character split across outputs
+	pending []byte
+	size    int64
+}
+
+func newCastWriter(w io.Writer, title string, start time.Time) *castWriter {
+	return &castWriter{
+		w: w,
+		header: castHeader{
+			Version:   castVersion,
+			Width:     defaultCastWidth,
+			Height:    defaultCastHeight,
+			Timestamp: start.Unix(),
+			Title:     title,
+			Env:       map[string]string{"TERM": "xterm"},
+		},
+		start: start,
+	}
+}
+
+func (w *castWriter) writeLine(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal")
+	}
+	line = append(line, '\n')
+	n, err := w.w.Write(line)
+	w.size += int64(n)
+	return err
+}
+
+func (w *castWriter) ensureHeader() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	return w.writeLine(w.header)
+}
+
+func (w *castWriter) writeEvent(at time.Time, code string, data string) error {
+	if err := w.ensureHeader(); err != nil {
+		return err
+	}
+	elapsed := float64(at.Sub(w.start).Microseconds()) / 1e6
+	return w.writeLine([]interface{}{elapsed, code, data})
+}
+
+func (w *castWriter) Output(at time.Time, data []byte) error {
+	data = append(w.pending, data...)
+	data, w.pending = splitIncompleteUTF8(data)
+	if len(data) == 0 {
+		return nil
+	}
+	return w.writeEvent(at, castEventOutput, string(data))
+}
+
+func (w *castWriter) Resize(at time.Time, cols, rows int) error {
+	if !w.started {
+		w.header.Width, w.header.Height = cols, rows
+		return nil
+	}
+	return w.writeEvent(at, castEventResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+func (w *castWriter) Size() int64 {
+	return w.size
+}
+
+// splitIncompleteUTF8 splits the trailing bytes of an utf-8 character that
+// is not complete yet off data
+func splitIncompleteUTF8(data []byte) ([]byte, []byte) {
+	// an utf-8 character is 4 bytes at most
+	for i := 1; i <= utf8.UTFMax && i <= len(data); i++ {
+		c := data[len(data)-i]
+		if c < utf8.RuneSelf {
+			// ascii
+			break
+		}
+		if utf8.RuneStart(c) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				rest := make([]byte, i)
+				copy(rest, data[len(data)-i:])
+				return data[:len(data)-i], rest
+			}
+			break
+		}
+	}
+	return data, nil
+}
+
+// castRecorder records the raw terminal output of a session into an
+// asciicast v2 file, the file is saved into the recording storage once the
+// session is closed
+type castRecorder struct {
+	ctx       context.Context
+	recording *models.SSessionRecording
+	filename  string
+	file      *os.File
+	buf       *bufio.Writer
+	writer    *castWriter
+	maxSize   int64
+	stopped   bool
+	lock      *sync.Mutex
+}
+
+func newCastRecorder(s *mcclient.ClientSession, obj *Object, sessionId string) (*castRecorder, error) {
+	ctx := s.GetContext()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	start := time.Now()
+	recording, err := models.GetSessionRecordingManager().Create(ctx, s.GetToken(), &models.SessionRecordingCreateInput{
+		SessionId:   sessionId,
+		ObjId:       obj.Id,
+		ObjName:     obj.Name,
+		ObjType:     obj.Type,
+		SessionType: obj.GetSessionType(),
+		LoginUser:   obj.LoginUser,
+		Width:       defaultCastWidth,
+		Height:      defaultCastHeight,
+		StartTime:   start,
+		StorageType: o.Options.SessionRecordingStorage,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create session recording")
+	}
+	dir := o.Options.SessionRecordingLocalPath
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		recording.MarkFailed()
+		return nil, errors.Wrapf(err, "mkdir %s", dir)
+	}
+	filename := path.Join(dir, recording.Id+".cast")
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		recording.MarkFailed()
+		return nil, errors.Wrapf(err, "open %s", filename)
+	}
+	buf := bufio.NewWriter(file)
+	return &castRecorder{
+		ctx:       ctx,
+		recording: recording,
+		filename:  filename,
+		file:      file,
+		buf:       buf,
+		writer:    newCastWriter(buf, obj.Name, start),
+		maxSize:   int64(o.Options.SessionRecordingMaxSizeMb) * 1024 * 1024,
+		lock:      &sync.Mutex{},
+	}, nil
+}
+
+func (r *castRecorder) Start() {
+}
+
+func (r *castRecorder) stop(reason string, err error) {
+	if err != nil {
+		log.Errorf("stop recording session %s: %s %v", r.recording.SessionId, reason, err)
+	} else {
+		log.Infof("stop recording session %s: %s", r.recording.SessionId, reason)
+	}
+	r.stopped = true
+}
+
+func (r *castRecorder) Write(userInput string, ptyOutput string) {
+	// only the output is recorded, the input is echoed by the terminal
+	// except secrets like passwords
+	if len(ptyOutput) == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.stopped {
+		return
+	}
+	if r.maxSize > 0 && r.writer.Size() >= r.maxSize {
+		r.stop("recording exceeds max size", nil)
+		return
+	}
+	if err := r.writer.Output(time.Now(), []byte(ptyOutput)); err != nil {
+		r.stop("write output", err)
+	}
+}
+
+func (r *castRecorder) Resize(cols, rows int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.stopped {
+		return
+	}
+	if err := r.writer.Resize(time.Now(), cols, rows); err != nil {
+		r.stop("write resize", err)
+	}
+}
+
+func (r *castRecorder) Close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == nil {
+		return
+	}
+	// sessions without any output still get a playable recording
+	err := r.writer.ensureHeader()
+	if err == nil {
+		err = r.buf.Flush()
+	}
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	r.file = nil
+	r.stopped = true
+	if err != nil {
+		log.Errorf("close recording %s: %v", r.filename, err)
+	}
+	if err := r.recording.Finish(r.ctx, r.filename, r.writer.Size()); err != nil {
+		log.Errorf("finish recording of session %s: %v", r.recording.SessionId, err)
+	}
+}
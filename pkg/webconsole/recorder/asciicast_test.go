@@ -0,0 +1,75 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recorder
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitIncompleteUTF8(t *testing.T) {
+	cases := []struct {
+		in       []byte
+		complete string
+		rest     []byte
+	}{
+		{[]byte("ls -l\r\n"), "ls -l\r\n", nil},
+		{[]byte("中文"), "中文", nil},
+		{[]byte("中文")[:4], "中", []byte("文")[:1]},
+		{[]byte("a中")[:3], "a", []byte("中")[:2]},
+		{[]byte{}, "", nil},
+	}
+	for _, c := range cases {
+		complete, rest := splitIncompleteUTF8(c.in)
+		if string(complete) != c.complete || !bytes.Equal(rest, c.rest) {
+			t.Errorf("split %q: got %q %q, want %q %q", c.in, complete, rest, c.complete, c.rest)
+		}
+	}
+}
+
+func TestCastWriter(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	buf := &bytes.Buffer{}
+	w := newCastWriter(buf, "vm1", start)
+	// resize before any output goes to the header
+	if err := w.Resize(start, 120, 40); err != nil {
+		t.Fatalf("resize: %v", err)
+	}
+	chars := []byte("中")
+	if err := w.Output(start.Add(500*time.Millisecond), append([]byte("$ "), chars[:1]...)); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	if err := w.Output(start.Add(time.Second), chars[1:]); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	if err := w.Resize(start.Add(2*time.Second), 80, 24); err != nil {
+		t.Fatalf("resize: %v", err)
+	}
+	want := strings.Join([]string{
+		`{"version":2,"width":120,"height":40,"timestamp":1600000000,"title":"vm1","env":{"TERM":"xterm"}}`,
+		`[0.5,"o","$ "]`,
+		`[1,"o","中"]`,
+		`[2,"r","80x24"]`,
+		``,
+	}, "\n")
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if w.Size() != int64(buf.Len()) {
+		t.Errorf("size %d != %d", w.Size(), buf.Len())
+	}
+}
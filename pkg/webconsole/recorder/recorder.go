@@ -25,13 +25,17 @@ import (
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
+	api "yunion.io/x/onecloud/pkg/apis/webconsole"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/webconsole/models"
+	o "yunion.io/x/onecloud/pkg/webconsole/options"
 )
 
 type Recoder interface {
 	Start()
 	Write(userInput string, ptyOutput string)
+	Resize(cols, rows int)
+	Close()
 }
 
 type Object struct {
@@ -40,6 +44,8 @@ type Object struct {
 	Type      string
 	LoginUser string
 	Notes     jsonutils.JSONObject
+	// SessionType is ssh, kube or ipmi, ssh if empty
+	SessionType string
 }
 
 func NewObject(id, name, oType, loginUser string, notes jsonutils.JSONObject) *Object {
@@ -52,6 +58,68 @@ func NewObject(id, name, oType, loginUser string, notes jsonutils.JSONObject) *O
 	}
 }
 
+func (obj *Object) SetSessionType(sessionType string) *Object {
+	obj.SessionType = sessionType
+	return obj
+}
+
+func (obj *Object) GetSessionType() string {
+	if len(obj.SessionType) == 0 {
+		return api.SESSION_TYPE_SSH
+	}
+	return obj.SessionType
+}
+
+// NewRecorder returns the recorder of a session, besides the typed
+// commands the raw terminal stream is recorded as asciicast when session
+// recording is enabled
+func NewRecorder(s *mcclient.ClientSession, obj *Object, sessionId string, accessedAt time.Time) Recoder {
+	rec := &sessionRecorder{
+		cmd: newCmdRecorder(s, obj, sessionId, accessedAt),
+	}
+	if !o.Options.EnableSessionRecording || obj == nil {
+		return rec
+	}
+	castRec, err := newCastRecorder(s, obj, sessionId)
+	if err != nil {
+		log.Errorf("start recording session %s: %v", sessionId, err)
+		return rec
+	}
+	rec.cmd.recordingId = castRec.recording.Id
+	rec.cast = castRec
+	return rec
+}
+
+type sessionRecorder struct {
+	cmd  *cmdRecoder
+	cast *castRecorder
+}
+
+func (r *sessionRecorder) Start() {
+	r.cmd.Start()
+}
+
+func (r *sessionRecorder) Write(userInput string, ptyOutput string) {
+	// the asciicast is written in place to keep the events in order, the
+	// command recorder may block on saving commands
+	if r.cast != nil {
+		r.cast.Write(userInput, ptyOutput)
+	}
+	go r.cmd.Write(userInput, ptyOutput)
+}
+
+func (r *sessionRecorder) Resize(cols, rows int) {
+	if r.cast != nil {
+		r.cast.Resize(cols, rows)
+	}
+}
+
+func (r *sessionRecorder) Close() {
+	if r.cast != nil {
+		r.cast.Close()
+	}
+}
+
 type cmdRecoder struct {
 	cs               *mcclient.ClientSession
 	sessionId        string
@@ -65,9 +133,14 @@ type cmdRecoder struct {
 	cmdCh            chan string
 	object           *Object
 	wLock            *sync.Mutex
+	recordingId      string
 }
 
 func NewCmdRecorder(s *mcclient.ClientSession, obj *Object, sessionId string, accessedAt time.Time) Recoder {
+	return newCmdRecorder(s, obj, sessionId, accessedAt)
+}
+
+func newCmdRecorder(s *mcclient.ClientSession, obj *Object, sessionId string, accessedAt time.Time) *cmdRecoder {
 	return &cmdRecoder{
 		cs:             s,
 		sessionId:      sessionId,
@@ -111,6 +184,12 @@ func (r *cmdRecoder) Write(userInput string, ptyOutput string) {
 	r.ptyOutputBuff += ptyOutput
 }
 
+func (r *cmdRecoder) Resize(cols, rows int) {
+}
+
+func (r *cmdRecoder) Close() {
+}
+
 func (r *cmdRecoder) cleanCmd() *cmdRecoder {
 	r.userInputBuff = ""
 	r.ptyOutputBuff = ""
@@ -185,9 +264,10 @@ func (r *cmdRecoder) newModelInput(userCred mcclient.TokenCredential, command st
 		SessionId:       r.sessionId,
 		AccessedAt:      r.accessedAt,
 		LoginUser:       r.object.LoginUser,
-		Type:            models.CommandTypeSSH,
+		Type:            models.CommandType(r.object.GetSessionType()),
 		StartTime:       time.Now(),
 		Ps1:             r.ps1,
 		Command:         command,
+		RecordingId:     r.recordingId,
 	}
 }
@@ -84,7 +84,7 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 				} else {
 					// log.Errorf("--p.Pty.output data: %q", data)
 					so.Emit(OUTPUT_EVENT, string(data))
-					go p.Session.GetRecorder().Write("", string(data))
+					p.Session.GetRecorder().Write("", string(data))
 				}
 				continue
 			}
@@ -122,7 +122,7 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 			}
 		} else {
 			p.Pty.Write([]byte(data))
-			go p.Session.GetRecorder().Write(data, "")
+			p.Session.GetRecorder().Write(data, "")
 		}
 	})
 
@@ -143,6 +143,7 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 			Rows: colRow[1],
 		}
 		p.Resize(&newSize)
+		p.Session.GetRecorder().Resize(int(newSize.Cols), int(newSize.Rows))
 	})
 
 	// handle disconnection
@@ -22,6 +22,7 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -33,6 +34,7 @@ import (
 	"yunion.io/x/onecloud/pkg/appsrv"
 	"yunion.io/x/onecloud/pkg/cloudcommon"
 	app_common "yunion.io/x/onecloud/pkg/cloudcommon/app"
+	"yunion.io/x/onecloud/pkg/cloudcommon/cronman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
 	"yunion.io/x/onecloud/pkg/webconsole"
@@ -93,6 +95,10 @@ func start() {
 
 	db.EnsureAppSyncDB(app, dbOpts, models.InitDB)
 
+	cron := cronman.InitCronJobManager(true, o.Options.CronJobWorkerCount)
+	cron.AddJobAtIntervalsWithStartRun("CleanExpiredSessionRecordings", time.Hour, models.GetSessionRecordingManager().CleanExpiredRecordings, true)
+	cron.Start()
+
 	root := mux.NewRouter()
 	root.UseEncodedPath()
 
@@ -73,6 +73,7 @@ func (man *SSessionManager) Save(data ISessionData) (*SSession, error) {
 		Id:           idStr,
 		ISessionData: data,
 		AccessToken:  token,
+		recorderOnce: &sync.Once{},
 	}
 	man.Store(idStr, session)
 	return session, nil
@@ -126,6 +127,7 @@ type SSession struct {
 	AccessedAt    time.Time
 	duplicateHook func()
 	recorder      recorder.Recoder
+	recorderOnce  *sync.Once
 }
 
 func (s SSession) GetConnectParams(params url.Values) (string, error) {
@@ -153,6 +155,12 @@ func (s SSession) GetConnectParams(params url.Values) (string, error) {
 }
 
 func (s *SSession) Close() error {
+	// recorderOnce returns after the recorder of GetRecorder is set, or
+	// marks it done so that no recorder is created for the closed session
+	s.recorderOnce.Do(func() {})
+	if s.recorder != nil {
+		s.recorder.Close()
+	}
 	if err := s.ISessionData.Cleanup(); err != nil {
 		log.Errorf("Clean up command error: %v", err)
 	}
@@ -169,9 +177,20 @@ func (s *SSession) RegisterDuplicateHook(f func()) {
 }
 
 func (s *SSession) GetRecorder() recorder.Recoder {
-	if s.recorder == nil {
-		s.recorder = recorder.NewCmdRecorder(s.GetClientSession(), s.GetRecordObject(), s.GetId(), s.AccessedAt)
+	s.recorderOnce.Do(func() {
+		s.recorder = recorder.NewRecorder(s.GetClientSession(), s.GetRecordObject(), s.GetId(), s.AccessedAt)
 		go s.recorder.Start()
+	})
+	if s.recorder == nil {
+		// the session is closed
+		return sNopRecorder{}
 	}
 	return s.recorder
 }
+
+type sNopRecorder struct{}
+
+func (r sNopRecorder) Start()                                   {}
+func (r sNopRecorder) Write(userInput string, ptyOutput string) {}
+func (r sNopRecorder) Resize(cols, rows int)                    {}
+func (r sNopRecorder) Close()                                   {}